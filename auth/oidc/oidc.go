@@ -0,0 +1,491 @@
+// Package oidc provides OpenID Connect login for Helix applications: provider
+// discovery, the authorization code flow with PKCE, ID token validation,
+// token refresh and sessions.
+//
+// Mount the login, callback and logout handlers, then load sessions with
+// Middleware or protect routes with RequireLogin:
+//
+//	p, err := oidc.New(ctx, oidc.Config{
+//	    Issuer:        "https://accounts.example.com",
+//	    ClientID:      os.Getenv("OIDC_CLIENT_ID"),
+//	    ClientSecret:  os.Getenv("OIDC_CLIENT_SECRET"),
+//	    RedirectURL:   "https://app.example.com/callback",
+//	    SessionSecret: []byte(os.Getenv("SESSION_SECRET")),
+//	})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//
+//	s.GET("/login", p.LoginHandler())
+//	s.GET("/callback", p.CallbackHandler())
+//	s.POST("/logout", p.LogoutHandler())
+//
+//	app := s.Group("/app", p.RequireLogin())
+//	app.GET("/me", func(w http.ResponseWriter, r *http.Request) {
+//	    session := oidc.SessionFrom(r.Context())
+//	    fmt.Fprintf(w, "hello %s", session.Claim("email"))
+//	})
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/kolosys/helix/middleware"
+)
+
+// Errors reported to Config.ErrorHandler by the callback handler.
+var (
+	// ErrInvalidState is returned when the callback's state does not match
+	// the login flow, e.g. because the flow cookie expired or was forged.
+	ErrInvalidState = errors.New("helix/oidc: invalid state")
+
+	// ErrInvalidToken is returned when an ID token fails validation.
+	ErrInvalidToken = errors.New("helix/oidc: invalid token")
+)
+
+// Config configures a Provider.
+type Config struct {
+	// Issuer is the provider's issuer URL. Its discovery document is read
+	// from Issuer + "/.well-known/openid-configuration".
+	Issuer string
+
+	// ClientID is the client identifier registered with the provider.
+	ClientID string
+
+	// ClientSecret authenticates the client at the token endpoint. Leave
+	// empty for public clients, which rely on PKCE alone.
+	// Default: ""
+	ClientSecret string
+
+	// RedirectURL is the absolute URL of the callback handler, as
+	// registered with the provider.
+	RedirectURL string
+
+	// Scopes are requested during login. "openid" is always included.
+	// Default: ["openid", "profile", "email"]
+	Scopes []string
+
+	// SessionSecret encrypts the login flow cookie and, with the default
+	// store, the session cookie. Use at least 32 random bytes.
+	SessionSecret []byte
+
+	// Sessions stores sessions between requests.
+	// Default: NewCookieStore(SessionSecret, CookieOptions{})
+	Sessions SessionStore
+
+	// LoginPath is where RequireLogin redirects unauthenticated requests.
+	// Default: "/login"
+	LoginPath string
+
+	// AfterLoginURL is where the callback redirects when the login did not
+	// name a return path.
+	// Default: "/"
+	AfterLoginURL string
+
+	// AfterLogoutURL is where logout redirects. When absolute, it is also
+	// sent to the provider's end session endpoint.
+	// Default: "/"
+	AfterLogoutURL string
+
+	// ErrorHandler writes the response when login or the callback fails.
+	// If nil, a 401 Unauthorized response is sent.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+	// HTTPClient performs discovery, key and token requests.
+	// Default: http.DefaultClient
+	HTTPClient *http.Client
+
+	// ClockSkew is the tolerance when checking token expiry.
+	// Default: 1 minute
+	ClockSkew time.Duration
+}
+
+// Metadata is the subset of the provider's discovery document used by the
+// package.
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint,omitempty"`
+	EndSessionEndpoint    string `json:"end_session_endpoint,omitempty"`
+}
+
+// Provider runs the login flow against an OpenID Connect provider.
+type Provider struct {
+	config   Config
+	metadata Metadata
+	keys     *keySet
+	flow     *sealer
+}
+
+// New discovers the provider at config.Issuer and returns a Provider.
+func New(ctx context.Context, config Config) (*Provider, error) {
+	switch {
+	case config.Issuer == "":
+		return nil, errors.New("helix/oidc: Issuer is required")
+	case config.ClientID == "":
+		return nil, errors.New("helix/oidc: ClientID is required")
+	case config.RedirectURL == "":
+		return nil, errors.New("helix/oidc: RedirectURL is required")
+	case len(config.SessionSecret) == 0:
+		return nil, errors.New("helix/oidc: SessionSecret is required")
+	}
+	config.Issuer = strings.TrimSuffix(config.Issuer, "/")
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "profile", "email"}
+	}
+	if !containsString(config.Scopes, "openid") {
+		config.Scopes = append([]string{"openid"}, config.Scopes...)
+	}
+	if config.Sessions == nil {
+		config.Sessions = NewCookieStore(config.SessionSecret, CookieOptions{})
+	}
+	if config.LoginPath == "" {
+		config.LoginPath = "/login"
+	}
+	if config.AfterLoginURL == "" {
+		config.AfterLoginURL = "/"
+	}
+	if config.AfterLogoutURL == "" {
+		config.AfterLogoutURL = "/"
+	}
+	if config.ErrorHandler == nil {
+		config.ErrorHandler = defaultErrorHandler
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	if config.ClockSkew <= 0 {
+		config.ClockSkew = time.Minute
+	}
+
+	p := &Provider{config: config, flow: newSealer(config.SessionSecret, "flow")}
+	if err := p.discover(ctx); err != nil {
+		return nil, err
+	}
+	p.keys = newKeySet(p.metadata.JWKSURI, config.HTTPClient)
+	return p, nil
+}
+
+// Metadata returns the provider's discovery document.
+func (p *Provider) Metadata() Metadata {
+	return p.metadata
+}
+
+// discover fetches and checks the discovery document.
+func (p *Provider) discover(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return fmt.Errorf("helix/oidc: discovery: %w", err)
+	}
+	if err := getJSON(p.config.HTTPClient, req, &p.metadata); err != nil {
+		return fmt.Errorf("helix/oidc: discovery: %w", err)
+	}
+
+	m := p.metadata
+	if strings.TrimSuffix(m.Issuer, "/") != p.config.Issuer {
+		return fmt.Errorf("helix/oidc: discovery: issuer %q does not match %q", m.Issuer, p.config.Issuer)
+	}
+	if m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" || m.JWKSURI == "" {
+		return errors.New("helix/oidc: discovery: document is missing required endpoints")
+	}
+	return nil
+}
+
+// flowState is kept in the flow cookie between login and callback.
+type flowState struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	ReturnTo string `json:"return_to,omitempty"`
+}
+
+// flowCookie is the name of the login flow cookie.
+const flowCookie = "helix_oidc_flow"
+
+// LoginHandler returns a handler that starts the login flow by redirecting
+// to the provider. A relative return_to query parameter is honored after
+// the callback.
+func (p *Provider) LoginHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flow := flowState{
+			State:    randomString(32),
+			Nonce:    randomString(32),
+			Verifier: randomString(32),
+			ReturnTo: safeReturnTo(r.URL.Query().Get("return_to")),
+		}
+		sealed, err := p.flow.seal(flow)
+		if err != nil {
+			p.config.ErrorHandler(w, r, err)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     flowCookie,
+			Value:    sealed,
+			Path:     "/",
+			MaxAge:   int((10 * time.Minute).Seconds()),
+			HttpOnly: true,
+			Secure:   strings.HasPrefix(p.config.RedirectURL, "https://"),
+			SameSite: http.SameSiteLaxMode,
+		})
+
+		challenge := sha256.Sum256([]byte(flow.Verifier))
+		q := url.Values{
+			"response_type":         {"code"},
+			"client_id":             {p.config.ClientID},
+			"redirect_uri":          {p.config.RedirectURL},
+			"scope":                 {strings.Join(p.config.Scopes, " ")},
+			"state":                 {flow.State},
+			"nonce":                 {flow.Nonce},
+			"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+			"code_challenge_method": {"S256"},
+		}
+		http.Redirect(w, r, withQuery(p.metadata.AuthorizationEndpoint, q), http.StatusFound)
+	}
+}
+
+// CallbackHandler returns the handler for RedirectURL. It checks the flow
+// state, exchanges the code for tokens, validates the ID token, saves the
+// session and redirects to the return path.
+func (p *Provider) CallbackHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if e := q.Get("error"); e != "" {
+			p.config.ErrorHandler(w, r, fmt.Errorf("helix/oidc: provider returned %s: %s", e, q.Get("error_description")))
+			return
+		}
+
+		var flow flowState
+		cookie, err := r.Cookie(flowCookie)
+		if err != nil || p.flow.open(cookie.Value, &flow) != nil ||
+			subtle.ConstantTimeCompare([]byte(flow.State), []byte(q.Get("state"))) != 1 {
+			p.config.ErrorHandler(w, r, ErrInvalidState)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: flowCookie, Path: "/", MaxAge: -1})
+
+		tokens, err := p.exchange(r.Context(), url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {q.Get("code")},
+			"redirect_uri":  {p.config.RedirectURL},
+			"code_verifier": {flow.Verifier},
+		})
+		if err != nil {
+			p.config.ErrorHandler(w, r, err)
+			return
+		}
+		if tokens.IDToken == "" {
+			p.config.ErrorHandler(w, r, fmt.Errorf("%w: token response has no id_token", ErrInvalidToken))
+			return
+		}
+		claims, err := p.verify(r.Context(), tokens.IDToken, flow.Nonce)
+		if err != nil {
+			p.config.ErrorHandler(w, r, err)
+			return
+		}
+
+		session := &Session{}
+		session.update(tokens, claims)
+		if err := p.config.Sessions.Save(w, r, session); err != nil {
+			p.config.ErrorHandler(w, r, err)
+			return
+		}
+
+		returnTo := flow.ReturnTo
+		if returnTo == "" {
+			returnTo = p.config.AfterLoginURL
+		}
+		http.Redirect(w, r, returnTo, http.StatusFound)
+	}
+}
+
+// LogoutHandler returns a handler that clears the session and redirects to
+// the provider's end session endpoint, if it has one, or AfterLogoutURL.
+func (p *Provider) LogoutHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, _ := p.config.Sessions.Load(r)
+		p.config.Sessions.Clear(w, r)
+
+		target := p.config.AfterLogoutURL
+		if p.metadata.EndSessionEndpoint != "" {
+			q := url.Values{"client_id": {p.config.ClientID}}
+			if session != nil && session.IDToken != "" {
+				q.Set("id_token_hint", session.IDToken)
+			}
+			if u, err := url.Parse(target); err == nil && u.IsAbs() {
+				q.Set("post_logout_redirect_uri", target)
+			}
+			target = withQuery(p.metadata.EndSessionEndpoint, q)
+		}
+		http.Redirect(w, r, target, http.StatusFound)
+	}
+}
+
+// Middleware returns a middleware that loads the session, refreshing its
+// tokens when they are about to expire, and stores it in the request
+// context for SessionFrom, along with a middleware.Principal of the user
+// and their ID token claims. Requests without a session pass through.
+func (p *Provider) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r, _ = p.loadSession(w, r)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireLogin returns a middleware like Middleware that also rejects
+// requests without a session. GET and HEAD requests are redirected to
+// LoginPath with a return_to parameter; others get 401 Unauthorized.
+func (p *Provider) RequireLogin() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r, session := p.loadSession(w, r)
+			if session != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				q := url.Values{"return_to": {r.URL.RequestURI()}}
+				http.Redirect(w, r, withQuery(p.config.LoginPath, q), http.StatusFound)
+				return
+			}
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		})
+	}
+}
+
+// refreshWindow is how long before expiry Middleware refreshes tokens.
+const refreshWindow = 30 * time.Second
+
+// loadSession loads and, if needed, refreshes the session. Sessions that
+// can no longer be refreshed are cleared.
+func (p *Provider) loadSession(w http.ResponseWriter, r *http.Request) (*http.Request, *Session) {
+	if session := SessionFrom(r.Context()); session != nil {
+		return r, session
+	}
+
+	session, err := p.config.Sessions.Load(r)
+	if err != nil || session == nil {
+		return r, nil
+	}
+
+	if !session.Expiry.IsZero() && time.Now().Add(refreshWindow).After(session.Expiry) {
+		if session.RefreshToken == "" || p.Refresh(r.Context(), session) != nil {
+			p.config.Sessions.Clear(w, r)
+			return r, nil
+		}
+		if err := p.config.Sessions.Save(w, r, session); err != nil {
+			return r, nil
+		}
+	}
+
+	ctx := context.WithValue(r.Context(), sessionKey{}, session)
+	return r.WithContext(middleware.WithPrincipal(ctx, principal(session))), session
+}
+
+// principal returns the principal of session's user, from its ID token
+// claims.
+func principal(session *Session) *middleware.Principal {
+	p := middleware.PrincipalFromClaims(session.Claims)
+	p.ID = session.Subject
+	return p
+}
+
+// Refresh exchanges the session's refresh token for new tokens, updating it
+// in place. The caller is responsible for saving the session.
+func (p *Provider) Refresh(ctx context.Context, session *Session) error {
+	if session.RefreshToken == "" {
+		return errors.New("helix/oidc: session has no refresh token")
+	}
+
+	tokens, err := p.exchange(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {session.RefreshToken},
+	})
+	if err != nil {
+		return err
+	}
+
+	var claims map[string]any
+	if tokens.IDToken != "" {
+		if claims, err = p.verify(ctx, tokens.IDToken, ""); err != nil {
+			return err
+		}
+		if sub, _ := claims["sub"].(string); sub != session.Subject {
+			return fmt.Errorf("%w: refreshed token is for another subject", ErrInvalidToken)
+		}
+	}
+	session.update(tokens, claims)
+	return nil
+}
+
+// Verify validates a raw ID token issued to this client, such as one sent
+// as a bearer token, and returns its claims.
+func (p *Provider) Verify(ctx context.Context, rawIDToken string) (map[string]any, error) {
+	return p.verify(ctx, rawIDToken, "")
+}
+
+// defaultErrorHandler is the default Config.ErrorHandler.
+func defaultErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
+
+// safeReturnTo accepts only local paths, preventing open redirects.
+// Backslashes and control characters are rejected anywhere, since browsers
+// strip tabs and newlines and read backslashes as slashes, turning
+// "/\t/evil.example" into "//evil.example".
+func safeReturnTo(s string) string {
+	if !strings.HasPrefix(s, "/") || strings.HasPrefix(s, "//") ||
+		strings.ContainsFunc(s, func(r rune) bool { return r == '\\' || unicode.IsControl(r) }) {
+		return ""
+	}
+	return s
+}
+
+// withQuery appends q to the query of rawURL.
+func withQuery(rawURL string, q url.Values) string {
+	sep := "?"
+	if strings.Contains(rawURL, "?") {
+		sep = "&"
+	}
+	return rawURL + sep + q.Encode()
+}
+
+// getJSON performs req and decodes a 200 JSON response into v.
+func getJSON(client *http.Client, req *http.Request, v any) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// containsString reports whether list contains s.
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
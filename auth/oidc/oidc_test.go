@@ -0,0 +1,344 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/helix/auth/oidc"
+	"github.com/kolosys/helix/cache"
+	"github.com/kolosys/helix/middleware"
+)
+
+// fakeProvider is a minimal OpenID Connect provider.
+type fakeProvider struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu        sync.Mutex
+	challenge string
+	nonce     string
+	expiresIn int
+	refreshes int
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp := &fakeProvider{key: key, expiresIn: 3600}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 fp.URL,
+			"authorization_endpoint": fp.URL + "/authorize",
+			"token_endpoint":         fp.URL + "/token",
+			"jwks_uri":               fp.URL + "/jwks",
+			"end_session_endpoint":   fp.URL + "/logout",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if user, pass, _ := r.BasicAuth(); user != "client" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+
+		fp.mu.Lock()
+		defer fp.mu.Unlock()
+		switch r.Form.Get("grant_type") {
+		case "authorization_code":
+			sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+			if r.Form.Get("code") != "code-1" || base64.RawURLEncoding.EncodeToString(sum[:]) != fp.challenge {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+		case "refresh_token":
+			if r.Form.Get("refresh_token") != "refresh-1" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+			fp.refreshes++
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  "access-" + r.Form.Get("grant_type"),
+			"token_type":    "Bearer",
+			"refresh_token": "refresh-1",
+			"expires_in":    fp.expiresIn,
+			"id_token":      fp.sign(t, fp.claims(map[string]any{"nonce": fp.nonce})),
+		})
+	})
+	fp.Server = httptest.NewServer(mux)
+	t.Cleanup(fp.Close)
+	return fp
+}
+
+// claims returns valid ID token claims with overrides applied.
+func (fp *fakeProvider) claims(overrides map[string]any) map[string]any {
+	claims := map[string]any{
+		"iss":   fp.URL,
+		"sub":   "user-1",
+		"aud":   "client",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"email": "ann@example.com",
+	}
+	for k, v := range overrides {
+		claims[k] = v
+	}
+	return claims
+}
+
+// sign returns an RS256 JWT for claims.
+func (fp *fakeProvider) sign(t *testing.T, claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "k1", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, fp.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func newTestProvider(t *testing.T, fp *fakeProvider, config Config) *Provider {
+	t.Helper()
+	config.Issuer = fp.URL
+	config.ClientID = "client"
+	config.ClientSecret = "secret"
+	config.RedirectURL = "https://app.example.com/callback"
+	config.SessionSecret = []byte("0123456789abcdef0123456789abcdef")
+	p, err := New(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// login runs the login flow and returns the session cookies.
+func login(t *testing.T, fp *fakeProvider, p *Provider, returnTo string) (*httptest.ResponseRecorder, []*http.Cookie) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	p.LoginHandler()(rec, httptest.NewRequest(http.MethodGet, "/login?return_to="+url.QueryEscape(returnTo), nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("login: expected 302, got %d", rec.Code)
+	}
+	authURL, _ := url.Parse(rec.Header().Get("Location"))
+	q := authURL.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("client_id") != "client" || !strings.Contains(q.Get("scope"), "openid") {
+		t.Fatalf("login: unexpected authorization request %s", authURL)
+	}
+	fp.mu.Lock()
+	fp.challenge, fp.nonce = q.Get("code_challenge"), q.Get("nonce")
+	fp.mu.Unlock()
+
+	req := httptest.NewRequest(http.MethodGet, "/callback?code=code-1&state="+q.Get("state"), nil)
+	for _, c := range rec.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	rec = httptest.NewRecorder()
+	p.CallbackHandler()(rec, req)
+
+	var session []*http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.MaxAge > 0 {
+			session = append(session, c)
+		}
+	}
+	return rec, session
+}
+
+func TestLoginFlow(t *testing.T) {
+	fp := newFakeProvider(t)
+	p := newTestProvider(t, fp, Config{})
+
+	rec, cookies := login(t, fp, p, "/app/me")
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/app/me" {
+		t.Fatalf("callback: expected redirect to /app/me, got %d %q: %s", rec.Code, rec.Header().Get("Location"), rec.Body.String())
+	}
+
+	var got *Session
+	var principal *middleware.Principal
+	protected := p.RequireLogin()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = SessionFrom(r.Context())
+		principal = middleware.PrincipalFrom(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/app/me", nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	protected.ServeHTTP(httptest.NewRecorder(), req)
+	if got == nil || got.Subject != "user-1" || got.Claim("email") != "ann@example.com" || got.AccessToken != "access-authorization_code" {
+		t.Fatalf("unexpected session %+v", got)
+	}
+	if principal == nil || principal.ID != "user-1" || principal.Attr("email") != "ann@example.com" {
+		t.Errorf("expected the session's principal, got %+v", principal)
+	}
+
+	// Unauthenticated requests are sent to the login page
+	rec = httptest.NewRecorder()
+	protected.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/app/me?tab=1", nil))
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/login?return_to=%2Fapp%2Fme%3Ftab%3D1" {
+		t.Errorf("expected login redirect, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	rec = httptest.NewRecorder()
+	protected.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/app/me", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for POST, got %d", rec.Code)
+	}
+
+	// Logout clears the session and ends it at the provider
+	req = httptest.NewRequest(http.MethodPost, "/logout", nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	rec = httptest.NewRecorder()
+	p.LogoutHandler()(rec, req)
+	logoutURL, _ := url.Parse(rec.Header().Get("Location"))
+	if !strings.HasPrefix(logoutURL.String(), fp.URL+"/logout") || logoutURL.Query().Get("id_token_hint") == "" {
+		t.Errorf("expected end session redirect, got %q", logoutURL)
+	}
+	if c := rec.Result().Cookies(); len(c) != 1 || c[0].MaxAge >= 0 {
+		t.Errorf("expected session cookie to be cleared, got %v", c)
+	}
+}
+
+func TestCallbackRejectsInvalidState(t *testing.T) {
+	fp := newFakeProvider(t)
+
+	var gotErr error
+	p := newTestProvider(t, fp, Config{
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			gotErr = err
+			w.WriteHeader(http.StatusForbidden)
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	p.LoginHandler()(rec, httptest.NewRequest(http.MethodGet, "/login?return_to=//evil.example.com", nil))
+	req := httptest.NewRequest(http.MethodGet, "/callback?code=code-1&state=forged", nil)
+	for _, c := range rec.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	rec = httptest.NewRecorder()
+	p.CallbackHandler()(rec, req)
+
+	if rec.Code != http.StatusForbidden || !errors.Is(gotErr, ErrInvalidState) {
+		t.Errorf("expected invalid state, got %d %v", rec.Code, gotErr)
+	}
+
+	// Absolute return paths are dropped to prevent open redirects
+	for _, returnTo := range []string{"//evil.example.com", "/\\evil.example.com", "/\t/evil.example.com", "/a\\b", "/\n/evil.example.com", "/app\x7f"} {
+		rec, _ = login(t, fp, p, returnTo)
+		if rec.Header().Get("Location") != "/" {
+			t.Errorf("%q: expected redirect to /, got %q", returnTo, rec.Header().Get("Location"))
+		}
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	fp := newFakeProvider(t)
+	fp.expiresIn = 10 // inside the refresh window
+	store := NewCacheStore(cache.New(cache.Config{}), CookieOptions{})
+	p := newTestProvider(t, fp, Config{Sessions: store})
+
+	_, cookies := login(t, fp, p, "/")
+
+	var got *Session
+	handler := p.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = SessionFrom(r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if fp.refreshes != 1 || got == nil || got.AccessToken != "access-refresh_token" {
+		t.Fatalf("expected tokens to be refreshed, got %d refreshes and %+v", fp.refreshes, got)
+	}
+	if len(rec.Result().Cookies()) != 1 {
+		t.Error("expected the refreshed session to be saved")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	fp := newFakeProvider(t)
+	p := newTestProvider(t, fp, Config{})
+	ctx := context.Background()
+
+	claims, err := p.Verify(ctx, fp.sign(t, fp.claims(nil)))
+	if err != nil || claims["sub"] != "user-1" {
+		t.Fatalf("expected valid token, got %v %v", claims, err)
+	}
+
+	tests := map[string]string{
+		"wrong audience": fp.sign(t, fp.claims(map[string]any{"aud": "other"})),
+		"wrong issuer":   fp.sign(t, fp.claims(map[string]any{"iss": "https://evil.example.com"})),
+		"expired":        fp.sign(t, fp.claims(map[string]any{"exp": time.Now().Add(-time.Hour).Unix()})),
+		"tampered":       tamper(fp.sign(t, fp.claims(nil))),
+		"malformed":      "not-a-jwt",
+	}
+	for name, token := range tests {
+		if _, err := p.Verify(ctx, token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: expected ErrInvalidToken, got %v", name, err)
+		}
+	}
+}
+
+// tamper replaces the payload of a JWT, keeping the signature.
+func tamper(token string) string {
+	parts := strings.Split(token, ".")
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	payload = []byte(strings.Replace(string(payload), "user-1", "admin", 1))
+	return parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2]
+}
+
+func TestNewValidatesConfig(t *testing.T) {
+	if _, err := New(context.Background(), Config{ClientID: "client"}); err == nil {
+		t.Error("expected error without Issuer")
+	}
+
+	fp := newFakeProvider(t)
+	_, err := New(context.Background(), Config{
+		Issuer:        fp.URL + "/other",
+		ClientID:      "client",
+		RedirectURL:   "https://app.example.com/callback",
+		SessionSecret: []byte("secret"),
+	})
+	if err == nil {
+		t.Error("expected discovery to fail for a mismatched issuer")
+	}
+}
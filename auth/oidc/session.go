@@ -0,0 +1,296 @@
+package oidc
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kolosys/helix/cache"
+)
+
+// Session holds the tokens and identity of a logged-in user.
+type Session struct {
+	// Subject is the user's identifier at the provider (the "sub" claim).
+	Subject string `json:"sub"`
+
+	// Claims are the claims of the latest ID token.
+	Claims map[string]any `json:"claims"`
+
+	// IDToken is the latest raw ID token.
+	IDToken string `json:"id_token,omitempty"`
+
+	// AccessToken authorizes calls to APIs on the user's behalf.
+	AccessToken string `json:"access_token"`
+
+	// RefreshToken renews AccessToken, if the provider issued one.
+	RefreshToken string `json:"refresh_token,omitempty"`
+
+	// Expiry is when AccessToken expires. Zero means unknown.
+	Expiry time.Time `json:"expiry,omitzero"`
+}
+
+// Claim returns the string claim name of the ID token, such as "email" or
+// "name", or "" if it is missing.
+func (s *Session) Claim(name string) string {
+	v, _ := s.Claims[name].(string)
+	return v
+}
+
+// update applies a token response and, if set, new ID token claims.
+func (s *Session) update(tokens *tokenResponse, claims map[string]any) {
+	s.AccessToken = tokens.AccessToken
+	if tokens.RefreshToken != "" {
+		s.RefreshToken = tokens.RefreshToken
+	}
+	s.Expiry = time.Time{}
+	if tokens.ExpiresIn > 0 {
+		s.Expiry = time.Now().Add(time.Duration(tokens.ExpiresIn) * time.Second)
+	}
+	if claims != nil {
+		s.Claims = claims
+		s.Subject, _ = claims["sub"].(string)
+		s.IDToken = tokens.IDToken
+	}
+}
+
+// sessionKey is the context key for the session.
+type sessionKey struct{}
+
+// SessionFrom returns the session loaded by Middleware or RequireLogin, or
+// nil if the request is not authenticated.
+func SessionFrom(ctx context.Context) *Session {
+	s, _ := ctx.Value(sessionKey{}).(*Session)
+	return s
+}
+
+// SessionStore stores sessions between requests.
+type SessionStore interface {
+	// Load returns the request's session, or nil if it has none.
+	Load(r *http.Request) (*Session, error)
+
+	// Save stores the session and sets any cookies identifying it.
+	Save(w http.ResponseWriter, r *http.Request, s *Session) error
+
+	// Clear removes the request's session.
+	Clear(w http.ResponseWriter, r *http.Request) error
+}
+
+// CookieOptions configures the session cookie.
+type CookieOptions struct {
+	// Name is the cookie name.
+	// Default: "helix_session"
+	Name string
+
+	// Path is the cookie path.
+	// Default: "/"
+	Path string
+
+	// Domain is the cookie domain.
+	// Default: "" (the request host)
+	Domain string
+
+	// MaxAge bounds the session lifetime.
+	// Default: 24 hours
+	MaxAge time.Duration
+
+	// Insecure allows the cookie over plain HTTP, for local development.
+	// Default: false
+	Insecure bool
+}
+
+// withDefaults fills zero-valued fields.
+func (o CookieOptions) withDefaults() CookieOptions {
+	if o.Name == "" {
+		o.Name = "helix_session"
+	}
+	if o.Path == "" {
+		o.Path = "/"
+	}
+	if o.MaxAge <= 0 {
+		o.MaxAge = 24 * time.Hour
+	}
+	return o
+}
+
+// cookie returns the session cookie with value.
+func (o CookieOptions) cookie(value string) *http.Cookie {
+	return &http.Cookie{
+		Name:     o.Name,
+		Value:    value,
+		Path:     o.Path,
+		Domain:   o.Domain,
+		MaxAge:   int(o.MaxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   !o.Insecure,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// clearCookie returns a cookie deleting the session cookie.
+func (o CookieOptions) clearCookie() *http.Cookie {
+	c := o.cookie("")
+	c.MaxAge = -1
+	return c
+}
+
+// CookieStore keeps the whole session in an encrypted cookie. Sessions with
+// large tokens may exceed browser cookie limits; use CacheStore for those.
+type CookieStore struct {
+	opts   CookieOptions
+	sealer *sealer
+}
+
+// NewCookieStore creates a CookieStore encrypting sessions with a key
+// derived from secret.
+func NewCookieStore(secret []byte, opts CookieOptions) *CookieStore {
+	return &CookieStore{opts: opts.withDefaults(), sealer: newSealer(secret, "session")}
+}
+
+// sealedSession adds the lifetime bound to a stored session.
+type sealedSession struct {
+	Session
+	Until time.Time `json:"until"`
+}
+
+// Load implements SessionStore.
+func (cs *CookieStore) Load(r *http.Request) (*Session, error) {
+	c, err := r.Cookie(cs.opts.Name)
+	if err != nil {
+		return nil, nil
+	}
+	var s sealedSession
+	if err := cs.sealer.open(c.Value, &s); err != nil || time.Now().After(s.Until) {
+		return nil, nil
+	}
+	return &s.Session, nil
+}
+
+// Save implements SessionStore.
+func (cs *CookieStore) Save(w http.ResponseWriter, r *http.Request, s *Session) error {
+	value, err := cs.sealer.seal(sealedSession{Session: *s, Until: time.Now().Add(cs.opts.MaxAge)})
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, cs.opts.cookie(value))
+	return nil
+}
+
+// Clear implements SessionStore.
+func (cs *CookieStore) Clear(w http.ResponseWriter, r *http.Request) error {
+	http.SetCookie(w, cs.opts.clearCookie())
+	return nil
+}
+
+// CacheStore keeps sessions in a cache, such as a Redis-backed one shared
+// by all instances, with only a random session ID in the cookie.
+type CacheStore struct {
+	opts  CookieOptions
+	cache *cache.Cache
+}
+
+// NewCacheStore creates a CacheStore keeping sessions in c.
+func NewCacheStore(c *cache.Cache, opts CookieOptions) *CacheStore {
+	return &CacheStore{opts: opts.withDefaults(), cache: c}
+}
+
+// Load implements SessionStore.
+func (cs *CacheStore) Load(r *http.Request) (*Session, error) {
+	c, err := r.Cookie(cs.opts.Name)
+	if err != nil || c.Value == "" {
+		return nil, nil
+	}
+	s, ok, err := cache.Get[Session](r.Context(), cs.cache, c.Value)
+	if err != nil {
+		return nil, fmt.Errorf("helix/oidc: load session: %w", err)
+	}
+	if !ok {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+// Save implements SessionStore. Each save issues a new session ID.
+func (cs *CacheStore) Save(w http.ResponseWriter, r *http.Request, s *Session) error {
+	id := randomString(32)
+	if err := cache.Set(r.Context(), cs.cache, id, *s, cs.opts.MaxAge); err != nil {
+		return fmt.Errorf("helix/oidc: save session: %w", err)
+	}
+	if c, err := r.Cookie(cs.opts.Name); err == nil && c.Value != "" {
+		cs.cache.Delete(r.Context(), c.Value)
+	}
+	http.SetCookie(w, cs.opts.cookie(id))
+	return nil
+}
+
+// Clear implements SessionStore.
+func (cs *CacheStore) Clear(w http.ResponseWriter, r *http.Request) error {
+	http.SetCookie(w, cs.opts.clearCookie())
+	if c, err := r.Cookie(cs.opts.Name); err == nil && c.Value != "" {
+		return cs.cache.Delete(r.Context(), c.Value)
+	}
+	return nil
+}
+
+// sealer encrypts values into cookie-safe strings with AES-GCM.
+type sealer struct {
+	aead cipher.AEAD
+}
+
+// newSealer derives a key for purpose from secret, so the flow and session
+// cookies never share a key.
+func newSealer(secret []byte, purpose string) *sealer {
+	key := sha256.Sum256(append([]byte("helix/oidc "+purpose+"\x00"), secret...))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		panic(err) // unreachable: the key is always 32 bytes
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return &sealer{aead: aead}
+}
+
+// seal encodes v as JSON and encrypts it.
+func (s *sealer) seal(v any) (string, error) {
+	plain, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("helix/oidc: seal: %w", err)
+	}
+	nonce := make([]byte, s.aead.NonceSize(), s.aead.NonceSize()+len(plain)+s.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("helix/oidc: seal: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(s.aead.Seal(nonce, nonce, plain, nil)), nil
+}
+
+// errSealed is returned for values that fail to decrypt.
+var errSealed = errors.New("helix/oidc: invalid sealed value")
+
+// open decrypts a value produced by seal into v.
+func (s *sealer) open(value string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(b) < s.aead.NonceSize() {
+		return errSealed
+	}
+	plain, err := s.aead.Open(nil, b[:s.aead.NonceSize()], b[s.aead.NonceSize():], nil)
+	if err != nil {
+		return errSealed
+	}
+	return json.Unmarshal(plain, v)
+}
+
+// randomString returns n random bytes encoded as base64url.
+func randomString(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
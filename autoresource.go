@@ -0,0 +1,213 @@
+package helix
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// ErrEntityNotFound is returned by a Repository when the requested entity
+// does not exist. Resources served from a repository answer it with a 404.
+var ErrEntityNotFound = errors.New("helix: entity not found")
+
+// Repository is the storage interface used by AutoResource.
+// IDs are passed as the raw path parameter; implementations parse them as
+// needed and return ErrEntityNotFound for unknown IDs.
+type Repository[Entity any] interface {
+	// List returns a page of entities and the total number of entities
+	// matching opts.Filters.
+	List(ctx context.Context, opts ListOptions) ([]Entity, int, error)
+
+	// Get returns the entity with the given ID.
+	Get(ctx context.Context, id string) (Entity, error)
+
+	// Create stores a new entity and returns it as stored, e.g. with its ID set.
+	Create(ctx context.Context, entity Entity) (Entity, error)
+
+	// Update replaces the entity with the given ID and returns it as stored.
+	Update(ctx context.Context, id string, entity Entity) (Entity, error)
+
+	// Delete removes the entity with the given ID.
+	Delete(ctx context.Context, id string) error
+}
+
+// ListOptions describes the page of entities requested from Repository.List.
+type ListOptions struct {
+	Pagination
+
+	// Offset is the number of entities to skip, derived from Page and Limit.
+	Offset int
+
+	// Filters holds the values of the query parameters listed in
+	// AutoResourceConfig.Filters that are present in the request.
+	Filters map[string]string
+}
+
+// AutoResourceConfig configures resources served from a Repository.
+type AutoResourceConfig struct {
+	// DefaultLimit is the page size when the request has no limit.
+	// Default: 20
+	DefaultLimit int
+
+	// MaxLimit caps the requested page size.
+	// Default: 100
+	MaxLimit int
+
+	// SortFields lists the fields clients may sort by. Other values of the
+	// sort query parameter are replaced by DefaultSort.
+	// Default: nil (no sorting)
+	SortFields []string
+
+	// DefaultSort is the sort field used when none or a disallowed one is requested.
+	// Default: ""
+	DefaultSort string
+
+	// Filters lists the query parameters passed to Repository.List as filters.
+	// Default: nil (no filters)
+	Filters []string
+}
+
+// DefaultAutoResourceConfig returns the default AutoResource configuration.
+func DefaultAutoResourceConfig() AutoResourceConfig {
+	return AutoResourceConfig{
+		DefaultLimit: 20,
+		MaxLimit:     100,
+	}
+}
+
+// AutoResource registers list, create, get, update and delete routes for an
+// entity backed by repo, with the default configuration. See
+// AutoResourceWithConfig.
+//
+// Example:
+//
+//	helix.AutoResource[User](s, "/users", userRepo)
+func AutoResource[Entity any](s *Server, pattern string, repo Repository[Entity], mw ...any) *TypedResourceBuilder[Entity] {
+	return AutoResourceWithConfig(s, pattern, repo, DefaultAutoResourceConfig(), mw...)
+}
+
+// AutoResourceWithConfig registers list, create, get, update and delete
+// routes for an entity backed by repo:
+//
+//   - GET pattern returns a PaginatedResponse built from Repository.List
+//   - POST pattern binds and validates the entity and returns 201 Created
+//   - GET pattern/{id} returns the entity
+//   - PUT pattern/{id} binds and validates the entity and replaces it
+//   - DELETE pattern/{id} removes the entity and returns 204 No Content
+//
+// ErrEntityNotFound from the repository is answered with a 404 Not Found.
+// The returned builder can be used to add custom routes.
+func AutoResourceWithConfig[Entity any](s *Server, pattern string, repo Repository[Entity], config AutoResourceConfig, mw ...any) *TypedResourceBuilder[Entity] {
+	return TypedResource[Entity](s, pattern, mw...).Repository(repo, config)
+}
+
+// Repository registers the standard routes of rb backed by repo. Routes
+// excluded with Only or Except are skipped and UseFor middleware applies.
+// See AutoResourceWithConfig.
+//
+// Example:
+//
+//	helix.TypedResource[User](s, "/users").
+//	    Except("delete").
+//	    UseFor("create", "update", requireAuth).
+//	    Repository(userRepo, helix.DefaultAutoResourceConfig())
+func (rb *TypedResourceBuilder[Entity]) Repository(repo Repository[Entity], config AutoResourceConfig) *TypedResourceBuilder[Entity] {
+	if config.DefaultLimit <= 0 {
+		config.DefaultLimit = 20
+	}
+	if config.MaxLimit <= 0 {
+		config.MaxLimit = 100
+	}
+
+	rb.handleAction(ActionList, http.MethodGet, rb.pattern, func(w http.ResponseWriter, r *http.Request) {
+		p := BindPagination(r, config.DefaultLimit, config.MaxLimit)
+		p.Sort = p.GetSort(config.DefaultSort, config.SortFields)
+		opts := ListOptions{Pagination: p, Offset: p.GetOffset(p.Limit)}
+		for _, name := range config.Filters {
+			if r.URL.Query().Has(name) {
+				if opts.Filters == nil {
+					opts.Filters = make(map[string]string, len(config.Filters))
+				}
+				opts.Filters[name] = Query(r, name)
+			}
+		}
+
+		items, total, err := repo.List(r.Context(), opts)
+		if err != nil {
+			handleRepositoryError(w, r, err)
+			return
+		}
+		if items == nil {
+			items = []Entity{}
+		}
+		respondOrError(w, r, http.StatusOK, NewPaginatedResponse(items, total, p.Page, p.Limit))
+	})
+
+	rb.handleAction(ActionCreate, http.MethodPost, rb.pattern, func(w http.ResponseWriter, r *http.Request) {
+		entity, err := BindAndValidate[Entity](r)
+		if err != nil {
+			handleError(w, r, err)
+			return
+		}
+		created, err := repo.Create(r.Context(), entity)
+		if err != nil {
+			handleRepositoryError(w, r, err)
+			return
+		}
+		respondOrError(w, r, http.StatusCreated, created)
+	})
+
+	rb.handleAction(ActionGet, http.MethodGet, rb.itemPattern(), func(w http.ResponseWriter, r *http.Request) {
+		entity, err := repo.Get(r.Context(), Param(r, "id"))
+		if err != nil {
+			handleRepositoryError(w, r, err)
+			return
+		}
+		respondOrError(w, r, http.StatusOK, entity)
+	})
+
+	rb.handleAction(ActionUpdate, http.MethodPut, rb.itemPattern(), func(w http.ResponseWriter, r *http.Request) {
+		entity, err := BindAndValidate[Entity](r)
+		if err != nil {
+			handleError(w, r, err)
+			return
+		}
+		updated, err := repo.Update(r.Context(), Param(r, "id"), entity)
+		if err != nil {
+			handleRepositoryError(w, r, err)
+			return
+		}
+		respondOrError(w, r, http.StatusOK, updated)
+	})
+
+	rb.handleAction(ActionDelete, http.MethodDelete, rb.itemPattern(), func(w http.ResponseWriter, r *http.Request) {
+		if err := repo.Delete(r.Context(), Param(r, "id")); err != nil {
+			handleRepositoryError(w, r, err)
+			return
+		}
+		NoContent(w)
+	})
+
+	return rb
+}
+
+// handleRepositoryError handles a repository error, answering
+// ErrEntityNotFound with a 404.
+func handleRepositoryError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, ErrEntityNotFound) {
+		if id := Param(r, "id"); id != "" {
+			err = ErrNotFound.WithDetailf("no entity with id %q", id)
+		} else {
+			err = ErrNotFound
+		}
+	}
+	handleError(w, r, err)
+}
+
+// respondOrError writes v like a typed handler response, handling encoding
+// errors.
+func respondOrError(w http.ResponseWriter, r *http.Request, status int, v any) {
+	if err := writeResponse(w, r, status, v); err != nil {
+		handleError(w, r, err)
+	}
+}
@@ -0,0 +1,161 @@
+package helix_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/kolosys/helix"
+)
+
+type autoUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func (u *autoUser) Validate() error {
+	if u.Name == "" {
+		v := NewValidationErrors()
+		v.Add("name", "is required")
+		return v.Err()
+	}
+	return nil
+}
+
+type memoryRepo struct {
+	users  map[int]autoUser
+	nextID int
+	opts   ListOptions
+}
+
+func newMemoryRepo() *memoryRepo {
+	return &memoryRepo{users: map[int]autoUser{1: {ID: 1, Name: "alice"}}, nextID: 2}
+}
+
+func (m *memoryRepo) List(ctx context.Context, opts ListOptions) ([]autoUser, int, error) {
+	m.opts = opts
+	var items []autoUser
+	for _, u := range m.users {
+		items = append(items, u)
+	}
+	return items, len(items), nil
+}
+
+func (m *memoryRepo) Get(ctx context.Context, id string) (autoUser, error) {
+	n, _ := strconv.Atoi(id)
+	u, ok := m.users[n]
+	if !ok {
+		return autoUser{}, ErrEntityNotFound
+	}
+	return u, nil
+}
+
+func (m *memoryRepo) Create(ctx context.Context, u autoUser) (autoUser, error) {
+	u.ID = m.nextID
+	m.nextID++
+	m.users[u.ID] = u
+	return u, nil
+}
+
+func (m *memoryRepo) Update(ctx context.Context, id string, u autoUser) (autoUser, error) {
+	n, _ := strconv.Atoi(id)
+	if _, ok := m.users[n]; !ok {
+		return autoUser{}, ErrEntityNotFound
+	}
+	u.ID = n
+	m.users[n] = u
+	return u, nil
+}
+
+func (m *memoryRepo) Delete(ctx context.Context, id string) error {
+	n, _ := strconv.Atoi(id)
+	if _, ok := m.users[n]; !ok {
+		return ErrEntityNotFound
+	}
+	delete(m.users, n)
+	return nil
+}
+
+func TestAutoResource(t *testing.T) {
+	s := New(nil)
+	repo := newMemoryRepo()
+	AutoResource[autoUser](s, "/users", repo)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodGet, "/users/1", ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"alice"`) {
+		t.Errorf("get: expected alice, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodGet, "/users/9", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("get missing: expected 404, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/users", `{"name":"bob"}`); rec.Code != http.StatusCreated || !strings.Contains(rec.Body.String(), `"id":2`) {
+		t.Errorf("create: expected 201 with id 2, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodPost, "/users", `{}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("create invalid: expected 422, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/users/1", `{"name":"carol"}`); rec.Code != http.StatusOK || repo.users[1].Name != "carol" {
+		t.Errorf("update: expected 200 and renamed user, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodDelete, "/users/2", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("delete: expected 204, got %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/users/2", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("delete missing: expected 404, got %d", rec.Code)
+	}
+
+	rec := do(http.MethodGet, "/users?page=1&limit=500", "")
+	var page PaginatedResponse[autoUser]
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("list: invalid JSON: %v", err)
+	}
+	if page.Total != 1 || page.Limit != 100 {
+		t.Errorf("list: expected total 1 and limit capped at 100, got %+v", page)
+	}
+}
+
+func TestAutoResource_FiltersAndSort(t *testing.T) {
+	s := New(nil)
+	repo := newMemoryRepo()
+	config := DefaultAutoResourceConfig()
+	config.SortFields = []string{"name"}
+	config.DefaultSort = "id"
+	config.Filters = []string{"role"}
+	AutoResourceWithConfig[autoUser](s, "/users", repo, config)
+
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users?role=admin&sort=password&page=3&limit=10", nil))
+
+	if repo.opts.Filters["role"] != "admin" {
+		t.Errorf("expected role filter, got %v", repo.opts.Filters)
+	}
+	if repo.opts.Sort != "id" {
+		t.Errorf("expected disallowed sort to fall back to id, got %q", repo.opts.Sort)
+	}
+	if repo.opts.Offset != 20 {
+		t.Errorf("expected offset 20, got %d", repo.opts.Offset)
+	}
+}
+
+func TestTypedResource_RepositoryRespectsExcept(t *testing.T) {
+	s := New(nil)
+	TypedResource[autoUser](s, "/users").Except(ActionDelete).Repository(newMemoryRepo(), DefaultAutoResourceConfig())
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/users/1", nil))
+	if rec.Code == http.StatusNoContent {
+		t.Error("expected delete route to be excluded")
+	}
+}
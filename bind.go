@@ -30,6 +30,7 @@ const (
 	tagHeader = "header"
 	tagJSON   = "json"
 	tagForm   = "form"
+	tagCtx    = "ctx"
 )
 
 // bindingCache caches reflected struct information for performance.
@@ -38,7 +39,7 @@ var bindingCache sync.Map
 type fieldInfo struct {
 	index     int
 	name      string
-	source    string // path, query, header, json, form
+	source    string // path, query, header, json, form, ctx
 	required  bool
 	omitEmpty bool
 	fieldType reflect.Type
@@ -53,8 +54,13 @@ type structInfo struct {
 //   - `path:"name"` - binds from URL path parameters
 //   - `query:"name"` - binds from URL query parameters
 //   - `header:"name"` - binds from HTTP headers
-//   - `json:"name"` - binds from JSON body
+//   - `json:"name"` - binds from JSON body, or a body in the format of a
+//     registered Codec
 //   - `form:"name"` - binds from form data
+//   - `ctx:"name"` - binds from a request context value; see ContextValue
+//
+// Context values are bound after the body, so a JSON body can never set a
+// `ctx` field, such as the authenticated user's ID.
 func Bind[T any](r *http.Request) (T, error) {
 	var result T
 
@@ -66,8 +72,8 @@ func Bind[T any](r *http.Request) (T, error) {
 
 	// First bind non-body fields
 	for _, field := range info.fields {
-		if field.source == tagJSON {
-			continue // Handle JSON separately
+		if field.source == tagJSON || field.source == tagCtx {
+			continue // Handle JSON and context values separately
 		}
 
 		var value string
@@ -107,15 +113,65 @@ func Bind[T any](r *http.Request) (T, error) {
 
 	// Bind JSON body if there are JSON fields
 	if hasJSONFields && r.Body != nil && r.ContentLength != 0 {
-		decoder := json.NewDecoder(r.Body)
-		if err := decoder.Decode(&result); err != nil && err != io.EOF {
-			return result, fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+		if c := codecFor(ContentType(r), &result); c != nil {
+			if err := decodeBody(r, c, &result); err != nil {
+				return result, err
+			}
+		} else {
+			if err := checkJSONContentType(r); err != nil {
+				return result, err
+			}
+			decoder := json.NewDecoder(r.Body)
+			if err := decoder.Decode(&result); err != nil && err != io.EOF {
+				return result, fmt.Errorf("%w: %w", ErrInvalidJSON, err)
+			}
 		}
 	}
 
+	if err := bindContext(r, resultVal, info); err != nil {
+		return result, err
+	}
+
 	return result, nil
 }
 
+// bindContext sets the `ctx` fields of v from the request context.
+func bindContext(r *http.Request, v reflect.Value, info *structInfo) error {
+	for _, field := range info.fields {
+		if field.source != tagCtx {
+			continue
+		}
+
+		value, ok := ContextValue(r.Context(), field.name)
+		if !ok {
+			// Clear anything the body decoded into the field
+			v.Field(field.index).SetZero()
+			if field.required {
+				return fmt.Errorf("%w: %s", ErrRequiredField, field.name)
+			}
+			continue
+		}
+
+		if err := setContextFieldValue(v.Field(field.index), value); err != nil {
+			return fmt.Errorf("%w: field %s: %v", ErrInvalidFieldValue, field.name, err)
+		}
+	}
+	return nil
+}
+
+// setContextFieldValue sets field to a context value, directly if its type
+// fits and otherwise by parsing its string form.
+func setContextFieldValue(field reflect.Value, value any) error {
+	if rv := reflect.ValueOf(value); rv.Type().AssignableTo(field.Type()) {
+		field.Set(rv)
+		return nil
+	}
+	if s, ok := value.(fmt.Stringer); ok {
+		return setFieldValue(field, s.String())
+	}
+	return setFieldValue(field, fmt.Sprint(value))
+}
+
 // BindJSON binds the JSON request body to a struct.
 func BindJSON[T any](r *http.Request) (T, error) {
 	var result T
@@ -123,15 +179,30 @@ func BindJSON[T any](r *http.Request) (T, error) {
 	if r.Body == nil {
 		return result, ErrInvalidJSON
 	}
+	if err := checkJSONContentType(r); err != nil {
+		return result, err
+	}
 
 	decoder := json.NewDecoder(r.Body)
 	if err := decoder.Decode(&result); err != nil {
-		return result, fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+		return result, fmt.Errorf("%w: %w", ErrInvalidJSON, err)
 	}
 
 	return result, nil
 }
 
+// checkJSONContentType returns ErrUnsupportedMediaType, listing JSON and
+// the registered codec types, if the request declares a body type other than
+// JSON, rather than letting the decoder fail with a confusing error. Bodies
+// without a Content-Type are decoded as JSON.
+func checkJSONContentType(r *http.Request) error {
+	ct := ContentType(r)
+	if ct == "" || ct == MIMEApplicationJSON || strings.HasSuffix(ct, "+json") {
+		return nil
+	}
+	return ErrUnsupportedMediaType.WithDetailf("unsupported content type %q; supported: %s", ct, supportedBodyTypes())
+}
+
 // BindQuery binds URL query parameters to a struct.
 // Uses the `query` struct tag to determine field names.
 func BindQuery[T any](r *http.Request) (T, error) {
@@ -225,6 +296,43 @@ func BindHeader[T any](r *http.Request) (T, error) {
 	return result, nil
 }
 
+// BindForm binds URL-encoded or multipart form values to a struct.
+// Uses the `form` struct tag to determine field names. Unlike the other
+// binders it reports every missing or malformed field at once, as a
+// *ValidationErrors keyed by form field name, so HTML forms can show all
+// errors together.
+func BindForm[T any](r *http.Request) (T, error) {
+	var result T
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return result, fmt.Errorf("%w: %v", ErrBindingFailed, err)
+	}
+
+	resultVal := reflect.ValueOf(&result).Elem()
+	info := getStructInfo(resultVal.Type())
+	verrs := NewValidationErrors()
+
+	for _, field := range info.fields {
+		if field.source != tagForm {
+			continue
+		}
+
+		value := r.FormValue(field.name)
+		if value == "" {
+			if field.required {
+				verrs.Add(field.name, "is required")
+			}
+			continue
+		}
+
+		if err := setFieldValue(resultVal.Field(field.index), value); err != nil {
+			verrs.Add(field.name, "is invalid")
+		}
+	}
+
+	return result, verrs.Err()
+}
+
 // getStructInfo gets or creates cached struct information.
 func getStructInfo(t reflect.Type) *structInfo {
 	if cached, ok := bindingCache.Load(t); ok {
@@ -242,7 +350,7 @@ func getStructInfo(t reflect.Type) *structInfo {
 		}
 
 		// Check each tag type
-		for _, tagName := range []string{tagPath, tagQuery, tagHeader, tagJSON, tagForm} {
+		for _, tagName := range []string{tagPath, tagQuery, tagHeader, tagJSON, tagForm, tagCtx} {
 			tag := field.Tag.Get(tagName)
 			if tag == "" {
 				continue
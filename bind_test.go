@@ -1,11 +1,15 @@
 package helix_test
 
 import (
+	"context"
+	"errors"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 
 	. "github.com/kolosys/helix"
+	"github.com/kolosys/helix/middleware"
 )
 
 func TestBindRequired(t *testing.T) {
@@ -339,3 +343,67 @@ func BenchmarkBindComplex(b *testing.B) {
 		Bind[Request](req)
 	}
 }
+
+func TestBindForm(t *testing.T) {
+	type Request struct {
+		Name  string `form:"name,required"`
+		Email string `form:"email,required"`
+		Age   int    `form:"age"`
+	}
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader("name=John&age=abc"))
+	req.Header.Set("Content-Type", MIMEApplicationForm)
+
+	result, err := BindForm[Request](req)
+	var verrs *ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	if result.Name != "John" {
+		t.Errorf("expected valid fields to be bound, got %+v", result)
+	}
+	errs := verrs.Errors()
+	if len(errs) != 2 || errs[0].Field != "email" || errs[1].Field != "age" {
+		t.Errorf("expected email and age errors, got %+v", errs)
+	}
+}
+
+type tenantKey struct{}
+
+func TestBindContext(t *testing.T) {
+	RegisterContextKey("tenant", tenantKey{})
+
+	type Request struct {
+		UserID  string   `ctx:"principal_id,required"`
+		Scopes  []string `ctx:"scopes"`
+		Tenant  string   `ctx:"tenant"`
+		OrgID   int      `ctx:"org_id"`
+		Comment string   `json:"comment"`
+	}
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"comment":"hi","UserID":"mallory"}`))
+	ctx := middleware.WithPrincipal(req.Context(), &middleware.Principal{ID: "u1", Scopes: []string{"read"}})
+	ctx = context.WithValue(ctx, tenantKey{}, "acme")
+	ctx = WithContextValue(ctx, "org_id", "42")
+
+	result, err := Bind[Request](req.WithContext(ctx))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := Request{UserID: "u1", Scopes: []string{"read"}, Tenant: "acme", OrgID: 42, Comment: "hi"}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("expected %+v, got %+v", want, result)
+	}
+
+	// The body must not fill context fields the request lacks
+	req = httptest.NewRequest("POST", "/", strings.NewReader(`{"Tenant":"evil"}`))
+	req = req.WithContext(middleware.WithPrincipal(req.Context(), &middleware.Principal{ID: "u1"}))
+	if result, _ := Bind[Request](req); result.Tenant != "" {
+		t.Errorf("expected the body's Tenant to be ignored, got %q", result.Tenant)
+	}
+
+	_, err = Bind[Request](httptest.NewRequest("GET", "/", nil))
+	if !errors.Is(err, ErrRequiredField) {
+		t.Errorf("expected ErrRequiredField without a principal, got %v", err)
+	}
+}
@@ -0,0 +1,249 @@
+// Package cache provides a cache abstraction for the Helix framework with
+// in-memory and Redis stores, TTLs, namespacing, singleflight loading and
+// typed helpers.
+//
+// Stores hold raw bytes, so they can also back middleware such as response
+// caches. The typed helpers encode values as JSON.
+//
+// Example:
+//
+//	c := cache.New(cache.Config{Store: cache.NewMemoryStore(), DefaultTTL: time.Minute})
+//	users := c.Namespace("users")
+//
+//	user, err := cache.GetOrLoad(ctx, users, id, 5*time.Minute, func(ctx context.Context) (User, error) {
+//	    return db.FindUser(ctx, id)
+//	})
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Store is a cache backend holding raw values.
+type Store interface {
+	// Get returns the value for key, or false if it is missing or expired.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+
+	// Set stores value for key. A ttl of zero or less means no expiry.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Config configures a Cache.
+type Config struct {
+	// Store holds the cached values.
+	// Default: NewMemoryStore()
+	Store Store
+
+	// Namespace prefixes every key, separated by a colon.
+	// Default: ""
+	Namespace string
+
+	// DefaultTTL is used when a TTL of zero is passed to Set or GetOrLoad.
+	// Default: 0 (no expiry)
+	DefaultTTL time.Duration
+
+	// LoadTimeout bounds the loads of GetOrLoad. A load shared by
+	// concurrent callers runs detached from their contexts, so that one
+	// caller giving up does not fail the others.
+	// Default: 30 seconds
+	LoadTimeout time.Duration
+}
+
+// Cache reads and writes a Store under a namespace.
+type Cache struct {
+	store       Store
+	prefix      string
+	defaultTTL  time.Duration
+	loadTimeout time.Duration
+	group       *flightGroup
+}
+
+// New creates a Cache.
+func New(config Config) *Cache {
+	if config.Store == nil {
+		config.Store = NewMemoryStore()
+	}
+	if config.LoadTimeout == 0 {
+		config.LoadTimeout = 30 * time.Second
+	}
+	c := &Cache{
+		store:       config.Store,
+		defaultTTL:  config.DefaultTTL,
+		loadTimeout: config.LoadTimeout,
+		group:       &flightGroup{},
+	}
+	if config.Namespace != "" {
+		c.prefix = config.Namespace + ":"
+	}
+	return c
+}
+
+// Namespace returns a Cache whose keys are prefixed with ns, sharing the
+// store of c. Namespaces nest, e.g. "app:users:42".
+func (c *Cache) Namespace(ns string) *Cache {
+	child := *c
+	child.prefix = c.prefix + ns + ":"
+	return &child
+}
+
+// Store returns the underlying store.
+func (c *Cache) Store() Store {
+	return c.store
+}
+
+// Get returns the raw value for key.
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return c.store.Get(ctx, c.prefix+key)
+}
+
+// Set stores a raw value for key. A ttl of zero uses the default TTL.
+func (c *Cache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.store.Set(ctx, c.prefix+key, value, c.ttl(ttl))
+}
+
+// Delete removes key.
+func (c *Cache) Delete(ctx context.Context, key string) error {
+	return c.store.Delete(ctx, c.prefix+key)
+}
+
+// GetOrLoad returns the value for key, calling load and storing its result
+// on a miss. Concurrent misses for the same key share a single load, whose
+// context keeps the values of the first caller's but not its cancellation;
+// it is bounded by Config.LoadTimeout instead. Each caller stops waiting
+// when its own ctx is done. A ttl of zero uses the default TTL. Errors from
+// load are not cached.
+func (c *Cache) GetOrLoad(ctx context.Context, key string, ttl time.Duration, load func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	if value, ok, err := c.Get(ctx, key); err != nil {
+		return nil, err
+	} else if ok {
+		return value, nil
+	}
+
+	return c.group.do(ctx, c.prefix+key, func() ([]byte, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.loadTimeout)
+		defer cancel()
+		value, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.Set(ctx, key, value, ttl); err != nil {
+			return nil, err
+		}
+		return value, nil
+	})
+}
+
+// ttl applies the default TTL.
+func (c *Cache) ttl(ttl time.Duration) time.Duration {
+	if ttl == 0 {
+		return c.defaultTTL
+	}
+	return ttl
+}
+
+// Get returns the JSON-decoded value for key.
+func Get[T any](ctx context.Context, c *Cache, key string) (T, bool, error) {
+	var v T
+	data, ok, err := c.Get(ctx, key)
+	if err != nil || !ok {
+		return v, false, err
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return v, false, err
+	}
+	return v, true, nil
+}
+
+// Set stores v for key as JSON.
+func Set[T any](ctx context.Context, c *Cache, key string, v T, ttl time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Set(ctx, key, data, ttl)
+}
+
+// GetOrLoad is the typed form of Cache.GetOrLoad.
+func GetOrLoad[T any](ctx context.Context, c *Cache, key string, ttl time.Duration, load func(ctx context.Context) (T, error)) (T, error) {
+	var v T
+	data, err := c.GetOrLoad(ctx, key, ttl, func(ctx context.Context) ([]byte, error) {
+		loaded, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(loaded)
+	})
+	if err != nil {
+		return v, err
+	}
+	err = json.Unmarshal(data, &v)
+	return v, err
+}
+
+// errPanicked is returned to callers sharing a load that panicked.
+var errPanicked = errors.New("helix/cache: loader panicked")
+
+// flightGroup deduplicates concurrent loads of the same key.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// flightCall is a load in progress.
+type flightCall struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+// do runs fn once for concurrent callers with the same key, in the
+// background, and returns each caller its own copy of the value. A caller
+// whose ctx is done stops waiting, but the load goes on for the others.
+func (g *flightGroup) do(ctx context.Context, key string, fn func() ([]byte, error)) ([]byte, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	call, ok := g.calls[key]
+	if !ok {
+		call = &flightCall{done: make(chan struct{}), err: errPanicked}
+		g.calls[key] = call
+		go g.run(key, call, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		if call.err != nil {
+			return nil, call.err
+		}
+		return append([]byte(nil), call.value...), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// run runs the load of call. A panic in fn is reported to the callers as
+// errPanicked, with the panic value and stack, rather than crashing the
+// process from the background.
+func (g *flightGroup) run(key string, call *flightCall, fn func() ([]byte, error)) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			call.value, call.err = nil, fmt.Errorf("%w: %v\n%s", errPanicked, rec, debug.Stack())
+		}
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+	call.value, call.err = fn()
+}
@@ -0,0 +1,214 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/helix/cache"
+)
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestCache_SetGetDelete(t *testing.T) {
+	ctx := context.Background()
+	c := New(Config{})
+
+	if _, ok, err := c.Get(ctx, "k"); ok || err != nil {
+		t.Fatalf("expected miss, got ok=%v err=%v", ok, err)
+	}
+	if err := c.Set(ctx, "k", []byte("v"), 0); err != nil {
+		t.Fatal(err)
+	}
+	value, ok, err := c.Get(ctx, "k")
+	if !ok || err != nil || string(value) != "v" {
+		t.Fatalf("expected v, got %q ok=%v err=%v", value, ok, err)
+	}
+	if err := c.Delete(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := c.Get(ctx, "k"); ok {
+		t.Error("expected miss after delete")
+	}
+}
+
+func TestCache_TTL(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	c := New(Config{Store: store, DefaultTTL: 20 * time.Millisecond})
+
+	c.Set(ctx, "default", []byte("v"), 0)
+	c.Set(ctx, "long", []byte("v"), time.Hour)
+
+	time.Sleep(40 * time.Millisecond)
+
+	if _, ok, _ := c.Get(ctx, "default"); ok {
+		t.Error("expected entry with default TTL to expire")
+	}
+	if _, ok, _ := c.Get(ctx, "long"); !ok {
+		t.Error("expected entry with explicit TTL to remain")
+	}
+	if store.Len() != 1 {
+		t.Errorf("expected expired entry to be removed, got %d entries", store.Len())
+	}
+}
+
+func TestCache_Namespace(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	c := New(Config{Store: store, Namespace: "app"})
+	users := c.Namespace("users")
+
+	users.Set(ctx, "42", []byte("alice"), 0)
+
+	if _, ok, _ := store.Get(ctx, "app:users:42"); !ok {
+		t.Error("expected key to be stored under app:users:42")
+	}
+	if _, ok, _ := c.Get(ctx, "42"); ok {
+		t.Error("expected parent namespace not to see child keys")
+	}
+	if value, ok, _ := users.Get(ctx, "42"); !ok || string(value) != "alice" {
+		t.Errorf("expected alice, got %q", value)
+	}
+}
+
+func TestCache_TypedHelpers(t *testing.T) {
+	ctx := context.Background()
+	c := New(Config{})
+
+	if err := Set(ctx, c, "u", user{ID: 1, Name: "alice"}, 0); err != nil {
+		t.Fatal(err)
+	}
+	got, ok, err := Get[user](ctx, c, "u")
+	if !ok || err != nil || got.Name != "alice" {
+		t.Errorf("expected alice, got %+v ok=%v err=%v", got, ok, err)
+	}
+
+	if _, ok, err := Get[user](ctx, c, "missing"); ok || err != nil {
+		t.Errorf("expected miss, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestCache_GetOrLoad(t *testing.T) {
+	ctx := context.Background()
+	c := New(Config{})
+
+	var loads atomic.Int32
+	load := func(ctx context.Context) (user, error) {
+		loads.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		return user{ID: 7, Name: "bob"}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u, err := GetOrLoad(ctx, c, "u7", time.Minute, load)
+			if err != nil || u.Name != "bob" {
+				t.Errorf("expected bob, got %+v err=%v", u, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("expected concurrent misses to share one load, got %d", n)
+	}
+
+	if _, err := GetOrLoad(ctx, c, "u7", time.Minute, load); err != nil {
+		t.Fatal(err)
+	}
+	if n := loads.Load(); n != 1 {
+		t.Errorf("expected cached value to be served, got %d loads", n)
+	}
+}
+
+func TestCache_GetOrLoadError(t *testing.T) {
+	ctx := context.Background()
+	c := New(Config{})
+	errDown := errors.New("db down")
+
+	_, err := GetOrLoad(ctx, c, "k", 0, func(ctx context.Context) (int, error) {
+		return 0, errDown
+	})
+	if !errors.Is(err, errDown) {
+		t.Errorf("expected loader error, got %v", err)
+	}
+	if _, ok, _ := c.Get(ctx, "k"); ok {
+		t.Error("expected errors not to be cached")
+	}
+}
+
+func TestCache_GetOrLoadPanic(t *testing.T) {
+	c := New(Config{})
+
+	_, err := c.GetOrLoad(context.Background(), "k", 0, func(ctx context.Context) ([]byte, error) {
+		panic("boom")
+	})
+	if err == nil {
+		t.Fatal("expected an error from a panicking loader")
+	}
+	if msg := err.Error(); !strings.Contains(msg, "loader panicked: boom") || !strings.Contains(msg, "goroutine") {
+		t.Errorf("expected the panic value and stack in the error, got %q", msg)
+	}
+}
+
+func TestCache_GetOrLoadCanceled(t *testing.T) {
+	c := New(Config{})
+	started, release := make(chan struct{}), make(chan struct{})
+	load := func(ctx context.Context) ([]byte, error) {
+		close(started)
+		<-release
+		return []byte("v"), ctx.Err()
+	}
+
+	first, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrLoad(first, "k", 0, load)
+		firstErr <- err
+	}()
+	<-started
+
+	second := make(chan []byte, 1)
+	go func() {
+		value, err := c.GetOrLoad(context.Background(), "k", 0, load)
+		if err != nil {
+			t.Errorf("expected the shared load to survive the first caller, got %v", err)
+		}
+		second <- value
+	}()
+
+	cancel()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the canceled caller to stop waiting, got %v", err)
+	}
+	close(release)
+	if value := <-second; string(value) != "v" {
+		t.Errorf("expected v, got %q", value)
+	}
+	if value, ok, _ := c.Get(context.Background(), "k"); !ok || string(value) != "v" {
+		t.Errorf("expected the shared load to be cached, got %q ok=%v", value, ok)
+	}
+}
+
+func TestMemoryStore_GetCopies(t *testing.T) {
+	ctx := context.Background()
+	c := New(Config{})
+	c.Set(ctx, "k", []byte("value"), 0)
+
+	value, _, _ := c.Get(ctx, "k")
+	value[0] = 'X'
+	if again, _, _ := c.Get(ctx, "k"); string(again) != "value" {
+		t.Errorf("expected the cached value to be unaffected by callers, got %q", again)
+	}
+}
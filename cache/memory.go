@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery is the number of writes between sweeps of expired entries.
+const sweepEvery = 1024
+
+// MemoryStore is an in-process Store. Expired entries are removed lazily
+// on access and periodically on writes.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	entries map[string]memoryEntry
+	writes  int
+}
+
+// memoryEntry is a stored value with its expiry.
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// expired reports whether the entry has expired at now.
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// NewMemoryStore creates an empty in-process store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]memoryEntry)}
+}
+
+// Get implements Store. The value returned is a copy.
+func (m *MemoryStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	m.mu.RLock()
+	e, ok := m.entries[key]
+	m.mu.RUnlock()
+
+	if !ok {
+		return nil, false, nil
+	}
+	if e.expired(time.Now()) {
+		m.mu.Lock()
+		if cur, ok := m.entries[key]; ok && cur.expired(time.Now()) {
+			delete(m.entries, key)
+		}
+		m.mu.Unlock()
+		return nil, false, nil
+	}
+	return append([]byte(nil), e.value...), true, nil
+}
+
+// Set implements Store. The value is copied.
+func (m *MemoryStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	e := memoryEntry{value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		e.expiresAt = time.Now().Add(ttl)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries[key] = e
+	m.writes++
+	if m.writes%sweepEvery == 0 {
+		now := time.Now()
+		for k, e := range m.entries {
+			if e.expired(now) {
+				delete(m.entries, k)
+			}
+		}
+	}
+	return nil
+}
+
+// Delete implements Store.
+func (m *MemoryStore) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
+
+// Len returns the number of entries, including expired entries not yet removed.
+func (m *MemoryStore) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.entries)
+}
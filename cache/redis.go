@@ -0,0 +1,273 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// RedisConfig configures a RedisStore.
+type RedisConfig struct {
+	// Addr is the Redis server address.
+	// Default: "localhost:6379"
+	Addr string
+
+	// Password authenticates with AUTH when set.
+	// Default: ""
+	Password string
+
+	// DB is the database selected with SELECT.
+	// Default: 0
+	DB int
+
+	// PoolSize is the maximum number of idle connections kept open.
+	// Default: 10
+	PoolSize int
+
+	// DialTimeout bounds connecting to the server.
+	// Default: 5 seconds
+	DialTimeout time.Duration
+}
+
+// DefaultRedisConfig returns the default Redis configuration.
+func DefaultRedisConfig() RedisConfig {
+	return RedisConfig{
+		Addr:        "localhost:6379",
+		PoolSize:    10,
+		DialTimeout: 5 * time.Second,
+	}
+}
+
+// RedisStore is a Store backed by a Redis server. It speaks the RESP
+// protocol directly and uses only GET, SET with PX, and DEL.
+type RedisStore struct {
+	config RedisConfig
+	idle   chan *redisConn
+}
+
+// NewRedisStore creates a Redis store. Connections are opened lazily.
+func NewRedisStore(config RedisConfig) *RedisStore {
+	if config.Addr == "" {
+		config.Addr = "localhost:6379"
+	}
+	if config.PoolSize <= 0 {
+		config.PoolSize = 10
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = 5 * time.Second
+	}
+	return &RedisStore{
+		config: config,
+		idle:   make(chan *redisConn, config.PoolSize),
+	}
+}
+
+// Get implements Store.
+func (r *RedisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := r.do(ctx, "GET", key)
+	if err != nil {
+		return nil, false, err
+	}
+	if reply == nil {
+		return nil, false, nil
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("helix/cache: unexpected GET reply %T", reply)
+	}
+	return value, true, nil
+}
+
+// Set implements Store.
+func (r *RedisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		ms := ttl.Milliseconds()
+		if ms < 1 {
+			ms = 1
+		}
+		args = append(args, "PX", strconv.FormatInt(ms, 10))
+	}
+	_, err := r.do(ctx, args...)
+	return err
+}
+
+// Delete implements Store.
+func (r *RedisStore) Delete(ctx context.Context, key string) error {
+	_, err := r.do(ctx, "DEL", key)
+	return err
+}
+
+// Close closes the idle connections.
+func (r *RedisStore) Close() error {
+	for {
+		select {
+		case c := <-r.idle:
+			c.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// redisError is an error reply from the server.
+type redisError string
+
+func (e redisError) Error() string {
+	return "helix/cache: redis: " + string(e)
+}
+
+// do sends a command and reads its reply. Connections that fail with an
+// I/O error are discarded; those that return an error reply are reused.
+func (r *RedisStore) do(ctx context.Context, args ...string) (any, error) {
+	c, err := r.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		c.conn.SetDeadline(deadline)
+	} else {
+		c.conn.SetDeadline(time.Time{})
+	}
+
+	reply, err := c.do(args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		c.conn.Close()
+		return nil, err
+	}
+	r.put(c)
+	return reply, err
+}
+
+// get returns an idle connection or dials a new one.
+func (r *RedisStore) get(ctx context.Context) (*redisConn, error) {
+	select {
+	case c := <-r.idle:
+		return c, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: r.config.DialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", r.config.Addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{conn: conn, rd: bufio.NewReader(conn)}
+
+	if r.config.Password != "" {
+		if _, err := c.do("AUTH", r.config.Password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if r.config.DB != 0 {
+		if _, err := c.do("SELECT", strconv.Itoa(r.config.DB)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// put returns c to the pool, closing it if the pool is full.
+func (r *RedisStore) put(c *redisConn) {
+	select {
+	case r.idle <- c:
+	default:
+		c.conn.Close()
+	}
+}
+
+// redisConn is a single connection to the server.
+type redisConn struct {
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// do writes a command as a RESP array of bulk strings and reads the reply.
+func (c *redisConn) do(args ...string) (any, error) {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	if _, err := c.conn.Write(buf); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+// readReply reads one RESP reply. Bulk strings are returned as []byte,
+// integers as int64, simple strings as string and null replies as nil.
+func (c *redisConn) readReply() (any, error) {
+	line, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errors.New("helix/cache: empty redis reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(c.rd, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = c.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("helix/cache: invalid redis reply %q", line)
+}
+
+// readLine reads a CRLF-terminated line without the terminator.
+func (c *redisConn) readLine() (string, error) {
+	line, err := c.rd.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("helix/cache: malformed redis line %q", line)
+	}
+	return line[:len(line)-2], nil
+}
@@ -0,0 +1,168 @@
+package cache_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/helix/cache"
+)
+
+// fakeRedis is a minimal RESP server supporting AUTH, GET, SET [PX] and DEL.
+type fakeRedis struct {
+	ln       net.Listener
+	password string
+
+	mu   sync.Mutex
+	data map[string]string
+	ttls map[string]string
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{ln: ln, password: password, data: map[string]string{}, ttls: map[string]string{}}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		args, err := readCommand(rd)
+		if err != nil {
+			return
+		}
+
+		cmd := strings.ToUpper(args[0])
+		if cmd == "AUTH" {
+			if args[1] != f.password {
+				fmt.Fprint(conn, "-WRONGPASS invalid password\r\n")
+				continue
+			}
+			authed = true
+			fmt.Fprint(conn, "+OK\r\n")
+			continue
+		}
+		if !authed {
+			fmt.Fprint(conn, "-NOAUTH Authentication required\r\n")
+			continue
+		}
+
+		f.mu.Lock()
+		switch cmd {
+		case "GET":
+			if v, ok := f.data[args[1]]; ok {
+				fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(v), v)
+			} else {
+				fmt.Fprint(conn, "$-1\r\n")
+			}
+		case "SET":
+			f.data[args[1]] = args[2]
+			if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+				f.ttls[args[1]] = args[4]
+			}
+			fmt.Fprint(conn, "+OK\r\n")
+		case "DEL":
+			_, ok := f.data[args[1]]
+			delete(f.data, args[1])
+			if ok {
+				fmt.Fprint(conn, ":1\r\n")
+			} else {
+				fmt.Fprint(conn, ":0\r\n")
+			}
+		default:
+			fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", args[0])
+		}
+		f.mu.Unlock()
+	}
+}
+
+func readCommand(rd *bufio.Reader) ([]string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+	args := make([]string, n)
+	for i := range args {
+		line, err := rd.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func TestRedisStore(t *testing.T) {
+	f := newFakeRedis(t, "secret")
+	store := NewRedisStore(RedisConfig{Addr: f.ln.Addr().String(), Password: "secret"})
+	defer store.Close()
+
+	ctx := context.Background()
+	c := New(Config{Store: store, Namespace: "app"})
+
+	if _, ok, err := c.Get(ctx, "k"); ok || err != nil {
+		t.Fatalf("expected miss, got ok=%v err=%v", ok, err)
+	}
+	if err := Set(ctx, c, "k", user{ID: 1, Name: "alice"}, 1500*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok, err := Get[user](ctx, c, "k")
+	if !ok || err != nil || got.Name != "alice" {
+		t.Fatalf("expected alice, got %+v ok=%v err=%v", got, ok, err)
+	}
+
+	f.mu.Lock()
+	ttl := f.ttls["app:k"]
+	f.mu.Unlock()
+	if ttl != "1500" {
+		t.Errorf("expected PX 1500, got %q", ttl)
+	}
+
+	if err := c.Delete(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := c.Get(ctx, "k"); ok {
+		t.Error("expected miss after delete")
+	}
+}
+
+func TestRedisStore_AuthError(t *testing.T) {
+	f := newFakeRedis(t, "secret")
+	store := NewRedisStore(RedisConfig{Addr: f.ln.Addr().String(), Password: "wrong"})
+	defer store.Close()
+
+	_, _, err := store.Get(context.Background(), "k")
+	if err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Errorf("expected auth error, got %v", err)
+	}
+}
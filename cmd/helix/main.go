@@ -0,0 +1,103 @@
+// Command helix provides tooling for Helix applications.
+//
+// Usage:
+//
+//	helix replay -target URL [flags] [file ...]
+//
+// The replay command re-issues requests recorded by replay.Record against
+// the target server, reading JSON lines from the files or standard input,
+// and prints a summary of the responses.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kolosys/helix/replay"
+)
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "replay" {
+		fmt.Fprintln(os.Stderr, "usage: helix replay -target URL [flags] [file ...]")
+		os.Exit(2)
+	}
+	if err := runReplay(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "helix replay:", err)
+		os.Exit(1)
+	}
+}
+
+// headerFlags collects repeated -H "Name: value" flags.
+type headerFlags http.Header
+
+func (h headerFlags) String() string { return "" }
+
+func (h headerFlags) Set(s string) error {
+	name, value, ok := strings.Cut(s, ":")
+	if !ok {
+		return fmt.Errorf("header %q is not \"Name: value\"", s)
+	}
+	http.Header(h).Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	return nil
+}
+
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	target := fs.String("target", "", "base URL of the server to replay against (required)")
+	concurrency := fs.Int("concurrency", 1, "number of requests sent at once")
+	speed := fs.Float64("speed", 0, "replay at the recorded pace sped up by this factor (0: as fast as possible)")
+	keepHost := fs.Bool("keep-host", false, "send the recorded Host header")
+	header := headerFlags{}
+	fs.Var(header, "H", `header set on every request, as "Name: value" (repeatable)`)
+	fs.Parse(args)
+	if *target == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var input io.Reader = os.Stdin
+	if fs.NArg() > 0 {
+		readers := make([]io.Reader, 0, fs.NArg())
+		for _, name := range fs.Args() {
+			f, err := os.Open(name)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			readers = append(readers, f)
+		}
+		input = io.MultiReader(readers...)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	res, err := replay.Replay(ctx, input, replay.ReplayConfig{
+		Target:      *target,
+		Concurrency: *concurrency,
+		Speed:       *speed,
+		Header:      http.Header(header),
+		KeepHost:    *keepHost,
+	})
+	if err != nil && res.Requests == 0 {
+		return err
+	}
+
+	fmt.Printf("%d requests in %s, %d errors\n", res.Requests, res.Elapsed.Round(time.Millisecond), res.Errors)
+	statuses := make([]int, 0, len(res.Statuses))
+	for status := range res.Statuses {
+		statuses = append(statuses, status)
+	}
+	slices.Sort(statuses)
+	for _, status := range statuses {
+		fmt.Printf("  %d %s: %d\n", status, http.StatusText(status), res.Statuses[status])
+	}
+	return err
+}
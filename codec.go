@@ -0,0 +1,185 @@
+package helix
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/kolosys/helix/middleware"
+)
+
+// Codec encodes and decodes typed-handler bodies in a format other than
+// JSON, such as MessagePack or protocol buffers. Register codecs with
+// RegisterCodec; JSON is always available and remains the default.
+type Codec interface {
+	// ContentTypes returns the media types the codec handles. Each is
+	// matched against Accept and Content-Type headers.
+	ContentTypes() []string
+
+	// Marshal encodes v.
+	Marshal(v any) ([]byte, error)
+
+	// Unmarshal decodes data into v, a pointer.
+	Unmarshal(data []byte, v any) error
+}
+
+// SelectiveCodec is a Codec that only handles some values, such as protocol
+// buffer messages. Responses it does not handle are encoded as JSON.
+type SelectiveCodec interface {
+	Codec
+
+	// Handles reports whether the codec can encode or decode v.
+	Handles(v any) bool
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   []Codec
+)
+
+// RegisterCodec makes typed handlers decode request bodies in the codec's
+// content types and encode responses in them when the Accept header
+// prefers one over JSON. Must be called before the server starts.
+//
+// Example:
+//
+//	helix.RegisterCodec(helix.MsgPackCodec)
+func RegisterCodec(c Codec) {
+	if c == nil || len(c.ContentTypes()) == 0 {
+		panic("helix: codec must have a content type")
+	}
+
+	codecsMu.Lock()
+	codecs = append(codecs, c)
+	codecsMu.Unlock()
+}
+
+// registeredCodecs returns the registered codecs.
+func registeredCodecs() []Codec {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	return codecs
+}
+
+// codecHandles reports whether c can encode or decode v.
+func codecHandles(c Codec, v any) bool {
+	s, ok := c.(SelectiveCodec)
+	return !ok || s.Handles(v)
+}
+
+// codecFor returns the registered codec for the media type of a request
+// body that handles v, or nil if none does.
+func codecFor(mediaType string, v any) Codec {
+	for _, c := range registeredCodecs() {
+		for _, t := range c.ContentTypes() {
+			if strings.EqualFold(t, mediaType) && codecHandles(c, v) {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// supportedBodyTypes lists the request body types Bind decodes.
+func supportedBodyTypes() string {
+	types := []string{MIMEApplicationJSON}
+	for _, c := range registeredCodecs() {
+		types = append(types, c.ContentTypes()...)
+	}
+	return strings.Join(types, ", ")
+}
+
+// decodeBody decodes the request body into v with c.
+func decodeBody(r *http.Request, c Codec, v any) error {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBindingFailed, err)
+	}
+	if err := c.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrBindingFailed, c.ContentTypes()[0], err)
+	}
+	return nil
+}
+
+// writeEncoded writes v as JSON, or in the registered codec format the
+// request's Accept header prefers.
+func writeEncoded(w http.ResponseWriter, r *http.Request, status int, v any) error {
+	registered := registeredCodecs()
+	if len(registered) == 0 {
+		return JSON(w, status, v)
+	}
+	middleware.AddVary(w.Header(), "Accept")
+
+	offers := []string{MIMEApplicationJSON}
+	byType := make(map[string]Codec)
+	for _, c := range registered {
+		if !codecHandles(c, v) {
+			continue
+		}
+		for _, t := range c.ContentTypes() {
+			if _, seen := byType[t]; !seen {
+				offers = append(offers, t)
+				byType[t] = c
+			}
+		}
+	}
+
+	best := Accepts(r, offers...)
+	c, ok := byType[best]
+	if !ok {
+		return JSON(w, status, v)
+	}
+	data, err := c.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return Blob(w, status, best, data)
+}
+
+// ProtobufCodec returns a codec for protocol buffer messages in the
+// application/x-protobuf and application/protobuf content types. helix does
+// not depend on the protobuf module, so pass its Marshal and Unmarshal. The
+// codec handles values with a ProtoReflect method, that is, proto.Message
+// implementations.
+//
+// Example:
+//
+//	helix.RegisterCodec(helix.ProtobufCodec(
+//	    func(v any) ([]byte, error) { return proto.Marshal(v.(proto.Message)) },
+//	    func(data []byte, v any) error { return proto.Unmarshal(data, v.(proto.Message)) },
+//	))
+func ProtobufCodec(marshal func(v any) ([]byte, error), unmarshal func(data []byte, v any) error) Codec {
+	if marshal == nil || unmarshal == nil {
+		panic("helix: ProtobufCodec requires marshal and unmarshal functions")
+	}
+	return protobufCodec{marshal: marshal, unmarshal: unmarshal}
+}
+
+// protobufCodec is the Codec returned by ProtobufCodec.
+type protobufCodec struct {
+	marshal   func(v any) ([]byte, error)
+	unmarshal func(data []byte, v any) error
+}
+
+// ContentTypes implements Codec.
+func (protobufCodec) ContentTypes() []string {
+	return []string{MIMEApplicationProtobuf, "application/protobuf"}
+}
+
+// Marshal implements Codec.
+func (c protobufCodec) Marshal(v any) ([]byte, error) { return c.marshal(v) }
+
+// Unmarshal implements Codec.
+func (c protobufCodec) Unmarshal(data []byte, v any) error { return c.unmarshal(data, v) }
+
+// Handles implements SelectiveCodec, detecting proto.Message values.
+func (protobufCodec) Handles(v any) bool {
+	if v == nil {
+		return false
+	}
+	_, ok := reflect.TypeOf(v).MethodByName("ProtoReflect")
+	return ok
+}
@@ -0,0 +1,160 @@
+package helix_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	. "github.com/kolosys/helix"
+)
+
+type codecItem struct {
+	ID    int      `json:"id"`
+	Name  string   `json:"name"`
+	Price float64  `json:"price"`
+	Tags  []string `json:"tags"`
+	Data  []byte   `json:"data,omitempty"`
+	Note  *string  `json:"note"`
+}
+
+func TestMsgPackCodec(t *testing.T) {
+	b, err := MsgPackCodec.Marshal(map[string]any{"a": 1, "b": -3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{0x82, 0xa1, 'a', 0x01, 0xa1, 'b', 0xfd}; !bytes.Equal(b, want) {
+		t.Errorf("expected % x, got % x", want, b)
+	}
+
+	in := codecItem{ID: 70000, Name: "widget", Price: 9.5, Tags: []string{"a", "b"}, Data: []byte{1, 2}}
+	b, err = MsgPackCodec.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out codecItem
+	if err := MsgPackCodec.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("expected %+v, got %+v", in, out)
+	}
+
+	// Binary data (bin 8) decodes into []byte fields
+	raw := []byte{0x81, 0xa4, 'd', 'a', 't', 'a', 0xc4, 0x02, 0xca, 0xfe}
+	if err := MsgPackCodec.Unmarshal(raw, &out); err != nil || !bytes.Equal(out.Data, []byte{0xca, 0xfe}) {
+		t.Errorf("expected bin data to decode, got %v (%v)", out.Data, err)
+	}
+
+	if err := MsgPackCodec.Unmarshal([]byte{0x92, 0x01}, &[]int{}); err == nil {
+		t.Error("expected an error for truncated data")
+	}
+	if err := MsgPackCodec.Unmarshal([]byte{0xdd, 0xff, 0xff, 0xff, 0xff}, &[]int{}); err == nil {
+		t.Error("expected an error for an oversized array header")
+	}
+}
+
+func TestHandle_Codecs(t *testing.T) {
+	RegisterCodec(MsgPackCodec)
+	defer ResetCodecs()
+
+	s := New(nil)
+	s.POST("/items", Handle(func(ctx context.Context, req codecItem) (codecItem, error) {
+		req.ID = 1
+		return req, nil
+	}))
+
+	body, _ := MsgPackCodec.Marshal(codecItem{Name: "widget", Tags: []string{}})
+	req := httptest.NewRequest(http.MethodPost, "/items", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-msgpack")
+	req.Header.Set("Accept", "application/x-msgpack")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-msgpack" {
+		t.Fatalf("expected a MessagePack response, got %q: %s", ct, rec.Body)
+	}
+	var got codecItem
+	if err := MsgPackCodec.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != 1 || got.Name != "widget" {
+		t.Errorf("unexpected response %+v", got)
+	}
+
+	// Without a preference JSON is still the default
+	req = httptest.NewRequest(http.MethodPost, "/items", bytes.NewReader(body))
+	req.Header.Set("Content-Type", MIMEApplicationMsgPack)
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if ct := rec.Header().Get("Content-Type"); ct != MIMEApplicationJSONCharsetUTF8 {
+		t.Errorf("expected a JSON response, got %q", ct)
+	}
+	if vary := rec.Header().Get("Vary"); vary != "Accept" {
+		t.Errorf("expected Vary: Accept, got %q", vary)
+	}
+}
+
+// fakeMessage stands in for a generated protocol buffer message.
+type fakeMessage struct {
+	Name string `json:"name"`
+}
+
+func (*fakeMessage) ProtoReflect() {}
+
+func TestProtobufCodec(t *testing.T) {
+	RegisterCodec(ProtobufCodec(
+		func(v any) ([]byte, error) { return []byte("pb:" + v.(*fakeMessage).Name), nil },
+		func(data []byte, v any) error {
+			if !bytes.HasPrefix(data, []byte("pb:")) {
+				return errors.New("bad message")
+			}
+			v.(*fakeMessage).Name = string(data[3:])
+			return nil
+		},
+	))
+	defer ResetCodecs()
+
+	s := New(nil)
+	s.POST("/echo", Handle(func(ctx context.Context, req fakeMessage) (*fakeMessage, error) {
+		return &req, nil
+	}))
+	s.GET("/plain", HandleNoRequest(func(ctx context.Context) (map[string]string, error) {
+		return map[string]string{"name": "plain"}, nil
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/echo", bytes.NewReader([]byte("pb:hello")))
+	req.Header.Set("Content-Type", MIMEApplicationProtobuf)
+	req.Header.Set("Accept", MIMEApplicationProtobuf)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Body.String() != "pb:hello" || rec.Header().Get("Content-Type") != MIMEApplicationProtobuf {
+		t.Errorf("expected a protobuf echo, got %q (%s)", rec.Body, rec.Header().Get("Content-Type"))
+	}
+
+	// Field selections leave messages to the codec
+	sparse := New(nil)
+	sparse.Use(WithSparseFields())
+	sparse.GET("/message", HandleNoRequest(func(ctx context.Context) (*fakeMessage, error) {
+		return &fakeMessage{Name: "hello"}, nil
+	}))
+	req = httptest.NewRequest(http.MethodGet, "/message?fields=id", nil)
+	req.Header.Set("Accept", MIMEApplicationProtobuf)
+	rec = httptest.NewRecorder()
+	sparse.ServeHTTP(rec, req)
+	if rec.Body.String() != "pb:hello" {
+		t.Errorf("expected the message to be encoded whole, got %q", rec.Body)
+	}
+
+	// Values that are no messages fall back to JSON
+	req = httptest.NewRequest(http.MethodGet, "/plain", nil)
+	req.Header.Set("Accept", MIMEApplicationProtobuf)
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if ct := rec.Header().Get("Content-Type"); ct != MIMEApplicationJSONCharsetUTF8 {
+		t.Errorf("expected JSON for a non-message response, got %q", ct)
+	}
+}
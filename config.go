@@ -0,0 +1,183 @@
+package helix
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kolosys/helix/config"
+	"github.com/kolosys/helix/middleware"
+)
+
+// Config is the configuration read by NewFromConfig. The Options
+// fields are configured by their snake_case names (addr, read_timeout,
+// tls_cert_file, ...); fields holding functions or TLS and HTTP/2
+// structures can only be set in code.
+type Config struct {
+	Options
+
+	// Middleware selects the built-in middleware installed on the server.
+	Middleware MiddlewareOptions
+}
+
+// MiddlewareOptions toggles the built-in middleware installed by
+// NewFromConfig, under the "middleware" key.
+type MiddlewareOptions struct {
+	// RequestID installs middleware.RequestID.
+	// Default: true
+	RequestID bool
+
+	// Logger installs the request logger.
+	// Default: true
+	Logger bool
+
+	// LogFormat is the logger format: dev, combined, common, short, tiny or json.
+	// Default: "dev"
+	LogFormat string
+
+	// Recover installs middleware.Recover.
+	// Default: true
+	Recover bool
+
+	// Compress installs middleware.Compress.
+	// Default: false
+	Compress bool
+
+	// CORSOrigins installs CORS middleware allowing these origins.
+	// Default: [] (no CORS middleware)
+	CORSOrigins []string
+
+	// RateLimit installs rate limiting at this many requests per second
+	// per client.
+	// Default: 0 (no rate limiting)
+	RateLimit float64
+
+	// RateLimitBurst is the rate limiter burst size.
+	// Default: RateLimit rounded up
+	RateLimitBurst int
+
+	// Timeout installs a request timeout.
+	// Default: 0 (no timeout)
+	Timeout time.Duration
+}
+
+// DefaultConfig returns the configuration NewFromConfig starts from:
+// the Options defaults with the request ID, logger and recover middleware
+// enabled, matching Default.
+func DefaultConfig() Config {
+	return Config{
+		Middleware: MiddlewareOptions{
+			RequestID: true,
+			Logger:    true,
+			LogFormat: string(middleware.LogFormatDev),
+			Recover:   true,
+		},
+	}
+}
+
+// Validate checks the configuration for inconsistent values.
+func (c *Config) Validate() error {
+	var errs []error
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		errs = append(errs, errors.New("tls_cert_file and tls_key_file must be set together"))
+	}
+	durations := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"read_timeout", c.ReadTimeout},
+		{"write_timeout", c.WriteTimeout},
+		{"idle_timeout", c.IdleTimeout},
+		{"grace_period", c.GracePeriod},
+		{"middleware.timeout", c.Middleware.Timeout},
+	}
+	for _, f := range durations {
+		if f.d < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative", f.name))
+		}
+	}
+	switch middleware.LogFormat(c.Middleware.LogFormat) {
+	case "", middleware.LogFormatDev, middleware.LogFormatCombined, middleware.LogFormatCommon,
+		middleware.LogFormatShort, middleware.LogFormatTiny, middleware.LogFormatJSON:
+	default:
+		errs = append(errs, fmt.Errorf("unknown middleware.log_format %q", c.Middleware.LogFormat))
+	}
+	if c.Middleware.RateLimit < 0 || c.Middleware.RateLimitBurst < 0 {
+		errs = append(errs, errors.New("middleware.rate_limit and middleware.rate_limit_burst must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// NewFromConfig creates a server configured from the file at path (JSON,
+// YAML or TOML), followed by HELIX_-prefixed environment variables such as
+// HELIX_ADDR or HELIX_MIDDLEWARE_COMPRESS. An empty path skips the file.
+// Additional sources, such as config.WithFlags(os.Args[1:]), take
+// precedence over both.
+//
+// Example config.yaml:
+//
+//	addr: ":9000"
+//	read_timeout: 10s
+//	tls_cert_file: /etc/tls/cert.pem
+//	tls_key_file: /etc/tls/key.pem
+//	middleware:
+//	  compress: true
+//	  cors_origins: [https://example.com]
+func NewFromConfig(path string, opts ...config.Option) (*Server, error) {
+	cfg := DefaultConfig()
+
+	sources := []config.Option{config.WithEnv("HELIX_")}
+	if path != "" {
+		sources = append([]config.Option{config.WithFile(path)}, sources...)
+	}
+	if err := config.Load(&cfg, append(sources, opts...)...); err != nil {
+		return nil, err
+	}
+	return NewWithConfig(cfg), nil
+}
+
+// NewWithConfig creates a server from a Config, installing the
+// middleware it enables.
+func NewWithConfig(cfg Config) *Server {
+	opts := cfg.Options
+	mw := cfg.Middleware
+	if opts.LogOutput == nil && mw.LogFormat != "" {
+		opts.LogOutput = middleware.TextOutput(os.Stdout, middleware.LogFormat(mw.LogFormat))
+	}
+
+	s := New(&opts)
+	if mw.RequestID {
+		s.Use(middleware.RequestID())
+	}
+	if mw.Logger {
+		s.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
+			Output: opts.LogOutput,
+		}))
+	}
+	if mw.Recover {
+		s.Use(middleware.Recover())
+	}
+	if len(mw.CORSOrigins) > 0 {
+		cors := middleware.DefaultCORSConfig()
+		cors.AllowOrigins = mw.CORSOrigins
+		s.Use(middleware.CORSWithConfig(cors))
+	}
+	if mw.RateLimit > 0 {
+		burst := mw.RateLimitBurst
+		if burst == 0 {
+			burst = int(mw.RateLimit)
+			if float64(burst) < mw.RateLimit {
+				burst++
+			}
+		}
+		s.Use(middleware.RateLimit(mw.RateLimit, burst))
+	}
+	if mw.Timeout > 0 {
+		s.Use(middleware.Timeout(mw.Timeout))
+	}
+	if mw.Compress {
+		s.Use(middleware.Compress())
+	}
+	return s
+}
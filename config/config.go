@@ -0,0 +1,219 @@
+// Package config loads configuration structs for the Helix framework from
+// files, environment variables and command-line flags.
+//
+// Sources are applied in order of increasing precedence: the values already
+// in the struct (defaults), then files in the order given, then environment
+// variables, then flags. JSON, YAML and TOML files are supported; YAML and
+// TOML are parsed with built-in subset parsers covering nested tables,
+// scalars and lists, so no third-party dependency is needed.
+//
+// Keys are derived from field names in snake_case (ReadTimeout becomes
+// "read_timeout") and may be overridden with a `config:"name"` tag. Nested
+// structs form dotted paths: the field TLS.CertFile has the file key
+// "tls.cert_file", the environment variable PREFIX_TLS_CERT_FILE and the
+// flag -tls.cert-file. A `config:"-"` tag skips a field and
+// `config:",required"` makes it required.
+//
+// Example:
+//
+//	type AppConfig struct {
+//	    DatabaseURL string        `config:"database_url,required"`
+//	    CacheTTL    time.Duration // cache_ttl
+//	}
+//
+//	cfg := AppConfig{CacheTTL: time.Minute}
+//	err := config.Load(&cfg,
+//	    config.WithFile("config.yaml"),
+//	    config.WithEnv("APP_"),
+//	    config.WithFlags(os.Args[1:]),
+//	)
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Option configures Load.
+type Option func(*loader)
+
+// file is a configuration file source.
+type file struct {
+	path     string
+	optional bool
+}
+
+// loader collects the sources for Load.
+type loader struct {
+	files        []file
+	envPrefix    string
+	useEnv       bool
+	args         []string
+	useFlags     bool
+	allowUnknown bool
+}
+
+// WithFile loads path. The format is chosen by its extension: .json, .yaml,
+// .yml or .toml. It is an error if the file does not exist.
+func WithFile(path string) Option {
+	return func(l *loader) { l.files = append(l.files, file{path: path}) }
+}
+
+// WithOptionalFile is like WithFile but a missing file is ignored.
+func WithOptionalFile(path string) Option {
+	return func(l *loader) { l.files = append(l.files, file{path: path, optional: true}) }
+}
+
+// WithEnv reads environment variables named prefix followed by the
+// upper-cased key path, e.g. "HELIX_READ_TIMEOUT" for prefix "HELIX_".
+// Lists are comma-separated.
+func WithEnv(prefix string) Option {
+	return func(l *loader) {
+		l.envPrefix = prefix
+		l.useEnv = true
+	}
+}
+
+// WithFlags parses args as command-line flags named after the key path,
+// e.g. -read-timeout=10s or -tls.cert-file=cert.pem. Lists are
+// comma-separated.
+func WithFlags(args []string) Option {
+	return func(l *loader) {
+		l.args = args
+		l.useFlags = true
+	}
+}
+
+// AllowUnknown ignores file keys that do not match a field. By default they
+// are an error, which catches typos.
+func AllowUnknown() Option {
+	return func(l *loader) { l.allowUnknown = true }
+}
+
+// Validatable is implemented by configuration structs that check their own
+// values. Load calls Validate after all sources are applied.
+type Validatable interface {
+	Validate() error
+}
+
+// Load fills the struct pointed to by dst from the configured sources.
+func Load(dst any, opts ...Option) error {
+	root := reflect.ValueOf(dst)
+	if root.Kind() != reflect.Pointer || root.Elem().Kind() != reflect.Struct {
+		return errors.New("helix/config: Load requires a pointer to a struct")
+	}
+	root = root.Elem()
+
+	l := &loader{}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	leaves := fieldsOf(root.Type(), nil, nil)
+
+	for _, f := range l.files {
+		values, err := readFile(f)
+		if err != nil {
+			return err
+		}
+		for _, kv := range flatten(values, "") {
+			if err := assign(root, kv.key, kv.value); err != nil {
+				if errors.Is(err, errUnknownKey) {
+					if l.allowUnknown {
+						continue
+					}
+					return fmt.Errorf("helix/config: %s: unknown key %q", f.path, kv.key)
+				}
+				return fmt.Errorf("helix/config: %s: %w", f.path, err)
+			}
+		}
+	}
+
+	if l.useEnv {
+		for _, leaf := range leaves {
+			name := l.envPrefix + leaf.envName()
+			if raw, ok := os.LookupEnv(name); ok {
+				if err := assign(root, leaf.key(), raw); err != nil {
+					return fmt.Errorf("helix/config: %s: %w", name, err)
+				}
+			}
+		}
+	}
+
+	if l.useFlags {
+		if err := parseFlags(root, leaves, l.args); err != nil {
+			return err
+		}
+	}
+
+	for _, leaf := range leaves {
+		if leaf.required && lookup(root, leaf.index).IsZero() {
+			return fmt.Errorf("helix/config: %s is required", leaf.key())
+		}
+	}
+
+	if v, ok := dst.(Validatable); ok {
+		if err := v.Validate(); err != nil {
+			return fmt.Errorf("helix/config: %w", err)
+		}
+	}
+	return nil
+}
+
+// readFile parses a configuration file into nested maps.
+func readFile(f file) (map[string]any, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		if f.optional && errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("helix/config: %w", err)
+	}
+
+	var values map[string]any
+	switch ext := strings.ToLower(filepath.Ext(f.path)); ext {
+	case ".json":
+		values, err = parseJSON(data)
+	case ".yaml", ".yml":
+		values, err = parseYAML(data)
+	case ".toml":
+		values, err = parseTOML(data)
+	default:
+		return nil, fmt.Errorf("helix/config: unsupported file format %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("helix/config: %s: %w", f.path, err)
+	}
+	return values, nil
+}
+
+// keyValue is a flattened file entry.
+type keyValue struct {
+	key   string
+	value any
+}
+
+// flatten turns nested maps into dotted keys, sorted for deterministic
+// errors. Lists are kept as values.
+func flatten(values map[string]any, prefix string) []keyValue {
+	var out []keyValue
+	for _, k := range slices.Sorted(maps.Keys(values)) {
+		v := values[k]
+		key := prefix + k
+		if m, ok := v.(map[string]any); ok {
+			out = append(out, flatten(m, key+".")...)
+			continue
+		}
+		if v == nil {
+			continue
+		}
+		out = append(out, keyValue{key: key, value: v})
+	}
+	return out
+}
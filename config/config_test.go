@@ -0,0 +1,268 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/helix/config"
+)
+
+type tlsConfig struct {
+	CertFile string
+	KeyFile  string
+}
+
+type appConfig struct {
+	Addr        string
+	ReadTimeout time.Duration
+	Debug       bool
+	Workers     int
+	Ratio       float64
+	Origins     []string
+	DatabaseURL string `config:"database_url"`
+	Secret      []byte
+	Internal    string `config:"-"`
+	TLS         tlsConfig
+}
+
+type requiredConfig struct {
+	DatabaseURL string `config:"database_url,required"`
+}
+
+type validatedConfig struct {
+	Port int
+}
+
+func (c *validatedConfig) Validate() error {
+	if c.Port > 65535 {
+		return errors.New("port out of range")
+	}
+	return nil
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func checkApp(t *testing.T, cfg appConfig) {
+	t.Helper()
+	if cfg.Addr != ":9000" {
+		t.Errorf("addr: got %q", cfg.Addr)
+	}
+	if cfg.ReadTimeout != 10*time.Second {
+		t.Errorf("read_timeout: got %v", cfg.ReadTimeout)
+	}
+	if !cfg.Debug {
+		t.Error("debug: expected true")
+	}
+	if cfg.Workers != 8 {
+		t.Errorf("workers: got %d", cfg.Workers)
+	}
+	if cfg.Ratio != 0.5 {
+		t.Errorf("ratio: got %v", cfg.Ratio)
+	}
+	if strings.Join(cfg.Origins, ",") != "https://a.example,https://b.example" {
+		t.Errorf("origins: got %v", cfg.Origins)
+	}
+	if cfg.DatabaseURL != "postgres://db/app" {
+		t.Errorf("database_url: got %q", cfg.DatabaseURL)
+	}
+	if cfg.TLS.CertFile != "cert.pem" || cfg.TLS.KeyFile != "key.pem" {
+		t.Errorf("tls: got %+v", cfg.TLS)
+	}
+}
+
+func TestLoad_Formats(t *testing.T) {
+	files := map[string]string{
+		"config.json": `{
+			"addr": ":9000",
+			"read_timeout": "10s",
+			"debug": true,
+			"workers": 8,
+			"ratio": 0.5,
+			"origins": ["https://a.example", "https://b.example"],
+			"database_url": "postgres://db/app",
+			"tls": {"cert_file": "cert.pem", "key_file": "key.pem"}
+		}`,
+		"config.yaml": `
+# server settings
+addr: ":9000"
+read_timeout: 10s
+debug: true
+workers: 8 # per CPU
+ratio: 0.5
+origins:
+  - https://a.example
+  - 'https://b.example'
+database_url: postgres://db/app
+tls:
+  cert_file: cert.pem
+  key_file: "key.pem"
+`,
+		"config.toml": `
+addr = ":9000"
+read_timeout = "10s"
+debug = true
+workers = 8
+ratio = 0.5
+origins = [
+  "https://a.example",
+  "https://b.example", # trailing comma
+]
+database_url = 'postgres://db/app'
+
+[tls]
+cert_file = "cert.pem"
+key_file = "key.pem"
+`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			var cfg appConfig
+			if err := Load(&cfg, WithFile(writeFile(t, name, content))); err != nil {
+				t.Fatal(err)
+			}
+			checkApp(t, cfg)
+		})
+	}
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeFile(t, "config.yaml", "addr: \":9000\"\nworkers: 2\ntls:\n  cert_file: file.pem\n")
+	t.Setenv("APP_WORKERS", "4")
+	t.Setenv("APP_TLS_CERT_FILE", "env.pem")
+	t.Setenv("APP_ORIGINS", "https://a.example, https://b.example")
+
+	cfg := appConfig{Addr: ":8080", Ratio: 0.25}
+	err := Load(&cfg,
+		WithFile(path),
+		WithEnv("APP_"),
+		WithFlags([]string{"-tls.cert-file=flag.pem", "-debug", "-read-timeout", "5s"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Addr != ":9000" {
+		t.Errorf("expected file to override default, got %q", cfg.Addr)
+	}
+	if cfg.Ratio != 0.25 {
+		t.Errorf("expected default to be kept, got %v", cfg.Ratio)
+	}
+	if cfg.Workers != 4 {
+		t.Errorf("expected env to override file, got %d", cfg.Workers)
+	}
+	if cfg.TLS.CertFile != "flag.pem" {
+		t.Errorf("expected flag to override env, got %q", cfg.TLS.CertFile)
+	}
+	if !cfg.Debug || cfg.ReadTimeout != 5*time.Second {
+		t.Errorf("expected flags to be applied, got debug=%v read_timeout=%v", cfg.Debug, cfg.ReadTimeout)
+	}
+	if len(cfg.Origins) != 2 || cfg.Origins[1] != "https://b.example" {
+		t.Errorf("expected comma-separated env list, got %v", cfg.Origins)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		load func() error
+		want string
+	}{
+		{
+			name: "unknown key",
+			load: func() error {
+				var cfg appConfig
+				return Load(&cfg, WithFile(writeFile(t, "c.yaml", "adr: x\n")))
+			},
+			want: `unknown key "adr"`,
+		},
+		{
+			name: "skipped field",
+			load: func() error {
+				var cfg appConfig
+				return Load(&cfg, WithFile(writeFile(t, "c.json", `{"internal": "x"}`)))
+			},
+			want: `unknown key "internal"`,
+		},
+		{
+			name: "invalid value",
+			load: func() error {
+				var cfg appConfig
+				return Load(&cfg, WithFile(writeFile(t, "c.toml", "read_timeout = \"soon\"\n")))
+			},
+			want: `read_timeout: invalid duration "soon"`,
+		},
+		{
+			name: "required",
+			load: func() error {
+				var cfg requiredConfig
+				return Load(&cfg)
+			},
+			want: "database_url is required",
+		},
+		{
+			name: "validate",
+			load: func() error {
+				var cfg validatedConfig
+				return Load(&cfg, WithFlags([]string{"-port=70000"}))
+			},
+			want: "port out of range",
+		},
+		{
+			name: "missing file",
+			load: func() error {
+				var cfg appConfig
+				return Load(&cfg, WithFile(filepath.Join(t.TempDir(), "missing.yaml")))
+			},
+			want: "no such file",
+		},
+		{
+			name: "unsupported format",
+			load: func() error {
+				var cfg appConfig
+				return Load(&cfg, WithFile(writeFile(t, "c.ini", "addr=x")))
+			},
+			want: `unsupported file format ".ini"`,
+		},
+		{
+			name: "not a pointer",
+			load: func() error { return Load(appConfig{}) },
+			want: "pointer to a struct",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.load()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoad_OptionalFileAndAllowUnknown(t *testing.T) {
+	var cfg appConfig
+	if err := Load(&cfg, WithOptionalFile(filepath.Join(t.TempDir(), "missing.yaml"))); err != nil {
+		t.Errorf("expected missing optional file to be ignored, got %v", err)
+	}
+
+	path := writeFile(t, "c.yaml", "addr: \":9000\"\nother_service:\n  url: x\n")
+	if err := Load(&cfg, WithFile(path), AllowUnknown()); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr != ":9000" {
+		t.Errorf("expected known keys to be applied, got %q", cfg.Addr)
+	}
+}
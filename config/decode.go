@@ -0,0 +1,321 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// errUnknownKey is returned by assign for keys without a matching field.
+var errUnknownKey = errors.New("unknown key")
+
+var (
+	durationType        = reflect.TypeFor[time.Duration]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// leaf is a configurable field.
+type leaf struct {
+	path     []string
+	index    []int
+	kind     reflect.Kind
+	required bool
+}
+
+// key returns the dotted file key.
+func (l leaf) key() string {
+	return strings.Join(l.path, ".")
+}
+
+// envName returns the environment variable name without prefix.
+func (l leaf) envName() string {
+	return strings.ToUpper(strings.Join(l.path, "_"))
+}
+
+// flagName returns the command-line flag name.
+func (l leaf) flagName() string {
+	return strings.ReplaceAll(l.key(), "_", "-")
+}
+
+// field is a struct field with its configuration key.
+type field struct {
+	reflect.StructField
+	name     string
+	required bool
+}
+
+// fieldsIn returns the configurable fields of t. Embedded structs without a
+// name tag are flattened into t.
+func fieldsIn(t reflect.Type, index []int) []field {
+	var out []field
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(sf.Tag.Get("config"), ",")
+		if name == "-" {
+			continue
+		}
+		sf.Index = append(append([]int(nil), index...), i)
+
+		if sf.Anonymous && name == "" && sf.Type.Kind() == reflect.Struct && !isLeaf(sf.Type) {
+			out = append(out, fieldsIn(sf.Type, sf.Index)...)
+			continue
+		}
+		if name == "" {
+			name = snakeCase(sf.Name)
+		}
+		out = append(out, field{StructField: sf, name: name, required: opts == "required"})
+	}
+	return out
+}
+
+// fieldsOf returns the leaves of t, recursing into nested structs.
+func fieldsOf(t reflect.Type, path []string, index []int) []leaf {
+	var out []leaf
+	for _, f := range fieldsIn(t, nil) {
+		p := append(append([]string(nil), path...), f.name)
+		idx := append(append([]int(nil), index...), f.Index...)
+		switch {
+		case isLeaf(f.Type):
+			out = append(out, leaf{path: p, index: idx, kind: f.Type.Kind(), required: f.required})
+		case f.Type.Kind() == reflect.Struct:
+			out = append(out, fieldsOf(f.Type, p, idx)...)
+		}
+	}
+	return out
+}
+
+// isLeaf reports whether values of t are set from a single value.
+func isLeaf(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() != reflect.Slice && isLeaf(t.Elem())
+	}
+	return false
+}
+
+// lookup returns the field of root at index.
+func lookup(root reflect.Value, index []int) reflect.Value {
+	return root.FieldByIndex(index)
+}
+
+// assign sets the field at the dotted key to raw.
+func assign(root reflect.Value, key string, raw any) error {
+	v := root
+	segments := strings.Split(key, ".")
+	for i, seg := range segments {
+		f, ok := fieldByName(v.Type(), seg)
+		if !ok {
+			return errUnknownKey
+		}
+		v = v.FieldByIndex(f.Index)
+
+		last := i == len(segments)-1
+		switch {
+		case isLeaf(f.Type) && last:
+			if err := setValue(v, raw); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			return nil
+		case isLeaf(f.Type) || last || f.Type.Kind() != reflect.Struct:
+			return errUnknownKey
+		}
+	}
+	return errUnknownKey
+}
+
+// fieldByName finds the configurable field of t named name.
+func fieldByName(t reflect.Type, name string) (field, bool) {
+	for _, f := range fieldsIn(t, nil) {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// setValue sets v from a file value, a list or a string.
+func setValue(v reflect.Value, raw any) error {
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		var items []any
+		switch raw := raw.(type) {
+		case []any:
+			items = raw
+		case string:
+			for _, s := range strings.Split(raw, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					items = append(items, s)
+				}
+			}
+		default:
+			items = []any{raw}
+		}
+
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setValue(slice.Index(i), item); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+
+	if _, ok := raw.([]any); ok {
+		return errors.New("expected a single value, got a list")
+	}
+	s, ok := raw.(string)
+	if !ok {
+		s = fmt.Sprint(raw)
+	}
+	return setString(v, s)
+}
+
+// setString parses s into v.
+func setString(v reflect.Value, s string) error {
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(s))
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid bool %q", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == durationType {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return fmt.Errorf("invalid duration %q", s)
+			}
+			v.SetInt(int64(d))
+			return nil
+		}
+		n, err := strconv.ParseInt(s, 0, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 0, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", s)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		v.SetBytes([]byte(s))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// snakeCase converts a Go field name to snake_case, keeping acronyms
+// together: "TLSCertFile" becomes "tls_cert_file".
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || ((unicode.IsUpper(prev) || unicode.IsDigit(prev)) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// flagValue records a flag's value for assignment after parsing.
+type flagValue struct {
+	isBool bool
+	value  *string
+}
+
+func (f flagValue) String() string {
+	if f.value == nil {
+		return ""
+	}
+	return *f.value
+}
+
+func (f flagValue) Set(s string) error {
+	*f.value = s
+	return nil
+}
+
+func (f flagValue) IsBoolFlag() bool {
+	return f.isBool
+}
+
+// parseFlags defines a flag per leaf, parses args and assigns the flags
+// that were set.
+func parseFlags(root reflect.Value, leaves []leaf, args []string) error {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	keys := make(map[string]string, len(leaves))
+	values := make(map[string]*string, len(leaves))
+	for _, l := range leaves {
+		value := new(string)
+		keys[l.flagName()] = l.key()
+		values[l.flagName()] = value
+		fs.Var(flagValue{isBool: l.kind == reflect.Bool, value: value}, l.flagName(), "sets "+l.key())
+	}
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("helix/config: %w", err)
+	}
+
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if err != nil {
+			return
+		}
+		if e := assign(root, keys[f.Name], *values[f.Name]); e != nil {
+			err = fmt.Errorf("helix/config: -%s: %w", f.Name, e)
+		}
+	})
+	return err
+}
+
+// parseJSON parses a JSON object, keeping numbers exact.
+func parseJSON(data []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var values map[string]any
+	if err := dec.Decode(&values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
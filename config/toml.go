@@ -0,0 +1,237 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseTOML parses the subset of TOML used for configuration files: tables,
+// dotted and quoted keys, basic and literal strings, numbers, booleans,
+// arrays (which may span lines) and inline tables. Arrays of tables and
+// multi-line strings are not supported. Dates are kept as strings.
+func parseTOML(data []byte) (map[string]any, error) {
+	root := map[string]any{}
+	current := root
+
+	lines := strings.Split(string(data), "\n")
+	for i := 0; i < len(lines); i++ {
+		num := i + 1
+		line := strings.TrimSpace(stripComment(strings.TrimRight(lines[i], "\r")))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[[") {
+			return nil, fmt.Errorf("line %d: arrays of tables are not supported", num)
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: invalid table header", num)
+			}
+			path, err := tomlKey(line[1 : len(line)-1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", num, err)
+			}
+			if current, err = tomlTable(root, path); err != nil {
+				return nil, fmt.Errorf("line %d: %w", num, err)
+			}
+			continue
+		}
+
+		keyText, valueText, ok := cutTOMLKey(line)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key = value\"", num)
+		}
+
+		// Arrays may continue over several lines until their brackets balance.
+		for strings.HasPrefix(valueText, "[") && !balanced(valueText) && i+1 < len(lines) {
+			i++
+			valueText += " " + strings.TrimSpace(stripComment(strings.TrimRight(lines[i], "\r")))
+		}
+
+		path, err := tomlKey(keyText)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", num, err)
+		}
+		value, err := tomlValue(valueText)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", num, err)
+		}
+		if err := tomlSet(current, path, value); err != nil {
+			return nil, fmt.Errorf("line %d: %w", num, err)
+		}
+	}
+	return root, nil
+}
+
+// cutTOMLKey splits "key = value" on the first "=" outside quotes.
+func cutTOMLKey(line string) (key, value string, ok bool) {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '=':
+			return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// tomlKey splits a dotted key into its parts, unquoting quoted parts.
+func tomlKey(text string) ([]string, error) {
+	var path []string
+	for _, part := range splitDotted(text) {
+		part = strings.TrimSpace(part)
+		if isQuoted(part) {
+			unquoted, err := yamlQuoted(part)
+			if err != nil {
+				return nil, err
+			}
+			part = unquoted
+		}
+		if part == "" {
+			return nil, fmt.Errorf("invalid key %q", text)
+		}
+		path = append(path, part)
+	}
+	return path, nil
+}
+
+// splitDotted splits on dots outside quotes.
+func splitDotted(text string) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '.':
+			parts = append(parts, text[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, text[start:])
+}
+
+// tomlTable returns the table at path, creating it as needed.
+func tomlTable(root map[string]any, path []string) (map[string]any, error) {
+	m := root
+	for _, key := range path {
+		switch v := m[key].(type) {
+		case nil:
+			next := map[string]any{}
+			m[key] = next
+			m = next
+		case map[string]any:
+			m = v
+		default:
+			return nil, fmt.Errorf("key %q is already defined", key)
+		}
+	}
+	return m, nil
+}
+
+// tomlSet sets the dotted key path in m.
+func tomlSet(m map[string]any, path []string, value any) error {
+	table, err := tomlTable(m, path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+	key := path[len(path)-1]
+	if _, dup := table[key]; dup {
+		return fmt.Errorf("duplicate key %q", strings.Join(path, "."))
+	}
+	table[key] = value
+	return nil
+}
+
+// tomlValue parses a value. Scalars other than strings are returned in
+// their textual form and converted when assigned to a field.
+func tomlValue(text string) (any, error) {
+	switch {
+	case text == "":
+		return nil, fmt.Errorf("missing value")
+	case strings.HasPrefix(text, `"""`) || strings.HasPrefix(text, "'''"):
+		return nil, fmt.Errorf("multi-line strings are not supported")
+	case isQuoted(text):
+		return yamlQuoted(text)
+	case strings.HasPrefix(text, "["):
+		if !strings.HasSuffix(text, "]") {
+			return nil, fmt.Errorf("unterminated array %q", text)
+		}
+		items := []any{}
+		for _, part := range splitTopLevel(text[1 : len(text)-1]) {
+			item, err := tomlValue(part)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case strings.HasPrefix(text, "{"):
+		if !strings.HasSuffix(text, "}") {
+			return nil, fmt.Errorf("unterminated inline table %q", text)
+		}
+		table := map[string]any{}
+		for _, part := range splitTopLevel(text[1 : len(text)-1]) {
+			keyText, valueText, ok := cutTOMLKey(part)
+			if !ok {
+				return nil, fmt.Errorf("invalid inline table entry %q", part)
+			}
+			path, err := tomlKey(keyText)
+			if err != nil {
+				return nil, err
+			}
+			value, err := tomlValue(valueText)
+			if err != nil {
+				return nil, err
+			}
+			if err := tomlSet(table, path, value); err != nil {
+				return nil, err
+			}
+		}
+		return table, nil
+	}
+
+	// Underscores are digit separators in numbers.
+	if c := text[0]; c == '+' || c == '-' || (c >= '0' && c <= '9') {
+		return strings.TrimPrefix(strings.ReplaceAll(text, "_", ""), "+"), nil
+	}
+	return text, nil
+}
+
+// balanced reports whether the brackets in text, outside quotes, are closed.
+func balanced(text string) bool {
+	var quote byte
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		}
+	}
+	return depth <= 0
+}
@@ -0,0 +1,296 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// yamlLine is a significant line of a YAML document.
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+// parseYAML parses the subset of YAML used for configuration files: nested
+// block mappings, block sequences of scalars, flow sequences ("[a, b]"),
+// plain, single- and double-quoted scalars, and comments. Anchors, flow
+// mappings, block scalars and multiple documents are not supported.
+func parseYAML(data []byte) (map[string]any, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(raw, "\r")
+		text := strings.TrimRight(stripComment(raw), " \t")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || (len(lines) == 0 && trimmed == "---") {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		lines = append(lines, yamlLine{num: i + 1, indent: len(text) - len(trimmed), text: trimmed})
+	}
+	if len(lines) == 0 {
+		return map[string]any{}, nil
+	}
+
+	p := &yamlParser{lines: lines}
+	value, err := p.block(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", lines[p.pos].num)
+	}
+	m, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("line %d: expected a mapping at the top level", lines[0].num)
+	}
+	return m, nil
+}
+
+// yamlParser walks the significant lines of a document.
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// block parses the mapping or sequence starting at the current line.
+func (p *yamlParser) block(indent int) (any, error) {
+	if isSeqItem(p.lines[p.pos].text) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+// mapping parses "key: value" lines at indent.
+func (p *yamlParser) mapping(indent int) (map[string]any, error) {
+	m := map[string]any{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		line := p.lines[p.pos]
+		if isSeqItem(line.text) {
+			return nil, fmt.Errorf("line %d: unexpected sequence item", line.num)
+		}
+		key, rest, ok := cutKey(line.text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", line.num)
+		}
+		if _, dup := m[key]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %q", line.num, key)
+		}
+		p.pos++
+
+		if rest != "" {
+			value, err := yamlScalar(rest)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line.num, err)
+			}
+			m[key] = value
+			continue
+		}
+
+		// A nested block is indented further, except that sequences may
+		// start at the same indentation as their key.
+		if p.pos < len(p.lines) {
+			next := p.lines[p.pos]
+			if next.indent > indent || (next.indent == indent && isSeqItem(next.text)) {
+				value, err := p.block(next.indent)
+				if err != nil {
+					return nil, err
+				}
+				m[key] = value
+				continue
+			}
+		}
+		m[key] = nil
+	}
+	return m, nil
+}
+
+// sequence parses "- item" lines at indent.
+func (p *yamlParser) sequence(indent int) ([]any, error) {
+	var items []any
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isSeqItem(p.lines[p.pos].text) {
+		line := p.lines[p.pos]
+		item := strings.TrimSpace(strings.TrimPrefix(line.text, "-"))
+		p.pos++
+
+		if item == "" {
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				value, err := p.block(p.lines[p.pos].indent)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, value)
+				continue
+			}
+			items = append(items, nil)
+			continue
+		}
+		if _, _, ok := cutKey(item); ok && !isQuoted(item) {
+			return nil, fmt.Errorf("line %d: mappings inside sequences are not supported", line.num)
+		}
+		value, err := yamlScalar(item)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line.num, err)
+		}
+		items = append(items, value)
+	}
+	return items, nil
+}
+
+// isSeqItem reports whether text starts a sequence item.
+func isSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// isQuoted reports whether text starts with a quote.
+func isQuoted(text string) bool {
+	return strings.HasPrefix(text, `"`) || strings.HasPrefix(text, "'")
+}
+
+// cutKey splits "key: value" or "key:". Keys may be quoted.
+func cutKey(text string) (key, rest string, ok bool) {
+	if isQuoted(text) {
+		end := closingQuote(text)
+		if end < 0 || !strings.HasPrefix(text[end+1:], ":") {
+			return "", "", false
+		}
+		key, err := yamlQuoted(text[:end+1])
+		if err != nil {
+			return "", "", false
+		}
+		return key, strings.TrimSpace(text[end+2:]), true
+	}
+
+	if key, rest, ok := strings.Cut(text, ": "); ok {
+		return strings.TrimSpace(key), strings.TrimSpace(rest), true
+	}
+	if key, ok := strings.CutSuffix(text, ":"); ok {
+		return strings.TrimSpace(key), "", true
+	}
+	return "", "", false
+}
+
+// yamlScalar parses a scalar or flow sequence. Null values are returned
+// as nil.
+func yamlScalar(text string) (any, error) {
+	switch {
+	case text == "~" || text == "null" || text == "Null" || text == "NULL":
+		return nil, nil
+	case isQuoted(text):
+		return yamlQuoted(text)
+	case strings.HasPrefix(text, "["):
+		if !strings.HasSuffix(text, "]") {
+			return nil, fmt.Errorf("unterminated flow sequence %q", text)
+		}
+		var items []any
+		for _, part := range splitTopLevel(text[1 : len(text)-1]) {
+			item, err := yamlScalar(part)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case strings.HasPrefix(text, "{"):
+		return nil, fmt.Errorf("flow mappings are not supported")
+	case strings.HasPrefix(text, "|") || strings.HasPrefix(text, ">"):
+		return nil, fmt.Errorf("block scalars are not supported")
+	case strings.HasPrefix(text, "&") || strings.HasPrefix(text, "*"):
+		return nil, fmt.Errorf("anchors and aliases are not supported")
+	}
+	return text, nil
+}
+
+// yamlQuoted unquotes a single- or double-quoted scalar.
+func yamlQuoted(text string) (string, error) {
+	if closingQuote(text) != len(text)-1 {
+		return "", fmt.Errorf("invalid quoted string %s", text)
+	}
+	if text[0] == '\'' {
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	}
+	s, err := strconv.Unquote(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid quoted string %s", text)
+	}
+	return s, nil
+}
+
+// closingQuote returns the index of the quote closing the one at text[0],
+// or -1.
+func closingQuote(text string) int {
+	q := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case q == '"' && text[i] == '\\':
+			i++
+		case q == '\'' && text[i] == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == q:
+			return i
+		}
+	}
+	return -1
+}
+
+// stripComment removes a "#" comment that is outside quotes and starts the
+// line or follows whitespace.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// splitTopLevel splits a list body on commas outside quotes and brackets.
+// Empty items are dropped, so trailing commas are allowed.
+func splitTopLevel(s string) []string {
+	var parts []string
+	var quote byte
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	parts = append(parts, s[start:])
+
+	out := parts[:0]
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
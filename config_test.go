@@ -0,0 +1,73 @@
+package helix_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/helix"
+	"github.com/kolosys/helix/config"
+)
+
+func TestNewFromConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+addr: ":9000"
+read_timeout: 10s
+middleware:
+  logger: false
+  cors_origins: [https://example.com]
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HELIX_WRITE_TIMEOUT", "20s")
+
+	s, err := NewFromConfig(path, config.WithFlags([]string{"-addr=:9100"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := s.GetConfig()
+	if cfg.Addr != ":9100" {
+		t.Errorf("expected flag to override file, got %s", cfg.Addr)
+	}
+	if cfg.ReadTimeout != int64(10*time.Second) {
+		t.Errorf("expected read timeout from file, got %v", time.Duration(cfg.ReadTimeout))
+	}
+	if cfg.WriteTimeout != int64(20*time.Second) {
+		t.Errorf("expected write timeout from env, got %v", time.Duration(cfg.WriteTimeout))
+	}
+	if cfg.IdleTimeout != int64(120*time.Second) {
+		t.Errorf("expected default idle timeout, got %v", time.Duration(cfg.IdleTimeout))
+	}
+	// request ID, recover and CORS
+	if cfg.MiddlewareLen != 3 {
+		t.Errorf("expected 3 middleware, got %d", cfg.MiddlewareLen)
+	}
+
+	s.GET("/", func(w http.ResponseWriter, r *http.Request) {})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://example.com")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://example.com" {
+		t.Errorf("expected CORS header, got %q", got)
+	}
+}
+
+func TestNewFromConfig_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"tls_cert_file": "cert.pem"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := NewFromConfig(path)
+	if err == nil || !strings.Contains(err.Error(), "tls_cert_file and tls_key_file must be set together") {
+		t.Errorf("expected validation error, got %v", err)
+	}
+}
@@ -33,6 +33,8 @@ const (
 
 	// Application types - no charset needed
 	MIMEApplicationProblemJSON = "application/problem+json"
+	MIMEApplicationJSONPatch   = "application/json-patch+json"
+	MIMEApplicationMergePatch  = "application/merge-patch+json"
 	MIMEApplicationForm        = "application/x-www-form-urlencoded"
 	MIMEApplicationProtobuf    = "application/x-protobuf"
 	MIMEApplicationMsgPack     = "application/msgpack"
@@ -0,0 +1,96 @@
+package helix
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kolosys/helix/middleware"
+)
+
+// namedValueKey is the context key of a value stored with WithContextValue.
+type namedValueKey string
+
+// contextLookup reads a named value from a context.
+type contextLookup func(ctx context.Context) (any, bool)
+
+var (
+	contextKeysMu sync.RWMutex
+	contextKeys   = map[string]contextLookup{
+		"request_id": func(ctx context.Context) (any, bool) {
+			id := middleware.GetRequestID(ctx)
+			return id, id != ""
+		},
+		"principal_id": func(ctx context.Context) (any, bool) {
+			if p := middleware.PrincipalFrom(ctx); p != nil && p.ID != "" {
+				return p.ID, true
+			}
+			return nil, false
+		},
+		"roles": func(ctx context.Context) (any, bool) {
+			if p := middleware.PrincipalFrom(ctx); p != nil && p.Roles != nil {
+				return p.Roles, true
+			}
+			return nil, false
+		},
+		"scopes": func(ctx context.Context) (any, bool) {
+			if p := middleware.PrincipalFrom(ctx); p != nil && p.Scopes != nil {
+				return p.Scopes, true
+			}
+			return nil, false
+		},
+	}
+)
+
+// WithContextValue returns a copy of ctx carrying value under name, for
+// binding into `ctx:"name"` struct fields and reading with ContextValue.
+//
+// Example:
+//
+//	func Tenant(next http.Handler) http.Handler {
+//	    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//	        ctx := helix.WithContextValue(r.Context(), "tenant", r.Header.Get("X-Tenant-ID"))
+//	        next.ServeHTTP(w, r.WithContext(ctx))
+//	    })
+//	}
+func WithContextValue(ctx context.Context, name string, value any) context.Context {
+	return context.WithValue(ctx, namedValueKey(name), value)
+}
+
+// RegisterContextKey makes the context value stored under key, such as a
+// third-party middleware's typed key, available under name to `ctx` binding
+// and ContextValue. Must be called before the server starts.
+//
+// Example:
+//
+//	helix.RegisterContextKey("user_id", auth.UserIDKey)
+func RegisterContextKey(name string, key any) {
+	if name == "" || key == nil {
+		panic("helix: context key name and key must not be empty")
+	}
+
+	contextKeysMu.Lock()
+	contextKeys[name] = func(ctx context.Context) (any, bool) {
+		v := ctx.Value(key)
+		return v, v != nil
+	}
+	contextKeysMu.Unlock()
+}
+
+// ContextValue returns the value named name in ctx: one set with
+// WithContextValue, else one under a key registered with
+// RegisterContextKey, else a built-in one:
+//   - "request_id" - the ID set by middleware.RequestID
+//   - "principal_id", "roles", "scopes" - from the middleware.Principal
+func ContextValue(ctx context.Context, name string) (any, bool) {
+	if v := ctx.Value(namedValueKey(name)); v != nil {
+		return v, true
+	}
+
+	contextKeysMu.RLock()
+	lookup, ok := contextKeys[name]
+	contextKeysMu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return lookup(ctx)
+}
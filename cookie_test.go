@@ -0,0 +1,196 @@
+package helix_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/kolosys/helix"
+)
+
+func TestCtx_Cookie(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+	c := NewCtx(httptest.NewRecorder(), req)
+
+	cookie, err := c.Cookie("theme")
+	if err != nil || cookie.Value != "dark" {
+		t.Errorf("expected theme cookie, got %v, %v", cookie, err)
+	}
+	if _, err := c.Cookie("missing"); !errors.Is(err, http.ErrNoCookie) {
+		t.Errorf("expected ErrNoCookie, got %v", err)
+	}
+	if got := c.CookieValue("theme", "light"); got != "dark" {
+		t.Errorf("expected dark, got %q", got)
+	}
+	if got := c.CookieValue("missing", "light"); got != "light" {
+		t.Errorf("expected default, got %q", got)
+	}
+}
+
+func TestCtx_DeleteCookie(t *testing.T) {
+	rec := httptest.NewRecorder()
+	c := NewCtx(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	c.DeleteCookie("session")
+
+	header := rec.Header().Get("Set-Cookie")
+	if !strings.Contains(header, "session=") || !strings.Contains(header, "Max-Age=0") {
+		t.Errorf("expected expired cookie, got %q", header)
+	}
+}
+
+func TestCtx_SignedCookie(t *testing.T) {
+	s := New(&Options{CookieSecret: []byte("0123456789abcdef0123456789abcdef")})
+	s.GET("/set", HandleCtx(func(c *Ctx) error {
+		c.SetSignedCookie(&http.Cookie{Name: "user", Value: "alice; admin=true", Path: "/"})
+		return c.NoContent()
+	}))
+
+	var got string
+	var gotErr error
+	s.GET("/get", HandleCtx(func(c *Ctx) error {
+		got, gotErr = c.GetSignedCookie("user")
+		return c.NoContent()
+	}))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/set", nil))
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("expected 1 cookie, got %d", len(cookies))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/get", nil)
+	req.AddCookie(cookies[0])
+	s.ServeHTTP(httptest.NewRecorder(), req)
+	if gotErr != nil || got != "alice; admin=true" {
+		t.Errorf("expected signed value, got %q, %v", got, gotErr)
+	}
+
+	// Tampered value
+	tampered := *cookies[0]
+	tampered.Value = "Ym9i" + tampered.Value[strings.Index(tampered.Value, "."):]
+	req = httptest.NewRequest(http.MethodGet, "/get", nil)
+	req.AddCookie(&tampered)
+	s.ServeHTTP(httptest.NewRecorder(), req)
+	if !errors.Is(gotErr, ErrInvalidCookieSignature) {
+		t.Errorf("expected ErrInvalidCookieSignature, got %v", gotErr)
+	}
+
+	// Missing cookie
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/get", nil))
+	if !errors.Is(gotErr, http.ErrNoCookie) {
+		t.Errorf("expected ErrNoCookie, got %v", gotErr)
+	}
+}
+
+func TestCtx_SignedCookieWithoutSecret(t *testing.T) {
+	c := NewCtx(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic without CookieSecret")
+		}
+	}()
+	c.SetSignedCookie(&http.Cookie{Name: "user", Value: "alice"})
+}
+
+func TestCtx_Flash(t *testing.T) {
+	s := New(&Options{CookieSecret: []byte("0123456789abcdef0123456789abcdef")})
+	s.POST("/profile", HandleCtx(func(c *Ctx) error {
+		c.Flash("success", "Profile saved")
+		c.Flash("info", "Email unchanged")
+		c.Redirect("/profile", http.StatusSeeOther)
+		return nil
+	}))
+
+	var got []Flash
+	s.GET("/profile", HandleCtx(func(c *Ctx) error {
+		got = c.Flashes()
+		return c.NoContent()
+	}))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/profile", nil))
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("expected 1 flash cookie, got %d", len(cookies))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/profile", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	want := []Flash{{Kind: "success", Message: "Profile saved"}, {Kind: "info", Message: "Email unchanged"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if cleared := rec.Result().Cookies(); len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+		t.Errorf("expected flash cookie to be cleared after reading, got %v", cleared)
+	}
+
+	// Forged flashes are discarded
+	forged := *cookies[0]
+	forged.Value = "W10" + forged.Value[strings.Index(forged.Value, "."):]
+	req = httptest.NewRequest(http.MethodGet, "/profile", nil)
+	req.AddCookie(&forged)
+	s.ServeHTTP(httptest.NewRecorder(), req)
+	if got != nil {
+		t.Errorf("expected forged flashes to be discarded, got %v", got)
+	}
+}
+
+func TestCtx_FlashUnread(t *testing.T) {
+	s := New(&Options{CookieSecret: []byte("0123456789abcdef0123456789abcdef")})
+	s.POST("/flash", HandleCtx(func(c *Ctx) error {
+		c.Flash("info", c.Query("msg"))
+		return c.NoContent()
+	}))
+	var got []Flash
+	s.GET("/flash", HandleCtx(func(c *Ctx) error {
+		got = c.Flashes()
+		return c.NoContent()
+	}))
+	post := func(cookie *http.Cookie, msg string) *http.Cookie {
+		req := httptest.NewRequest(http.MethodPost, "/flash?msg="+msg, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		cookies := rec.Result().Cookies()
+		if len(cookies) != 1 {
+			t.Fatalf("expected 1 flash cookie, got %d", len(cookies))
+		}
+		return cookies[0]
+	}
+
+	// Messages not read yet are kept by later requests
+	cookie := post(post(nil, "first"), "second")
+	req := httptest.NewRequest(http.MethodGet, "/flash", nil)
+	req.AddCookie(cookie)
+	s.ServeHTTP(httptest.NewRecorder(), req)
+	want := []Flash{{Kind: "info", Message: "first"}, {Kind: "info", Message: "second"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// The oldest messages are dropped to keep the cookie under 4KB
+	cookie = nil
+	for i := range 100 {
+		cookie = post(cookie, strings.Repeat("x", 90)+strconv.Itoa(i))
+	}
+	if len(cookie.Value) > 4096 {
+		t.Errorf("expected the flash cookie to stay under 4KB, got %d bytes", len(cookie.Value))
+	}
+	req = httptest.NewRequest(http.MethodGet, "/flash", nil)
+	req.AddCookie(cookie)
+	s.ServeHTTP(httptest.NewRecorder(), req)
+	if len(got) == 0 || len(got) == 100 || got[len(got)-1].Message != strings.Repeat("x", 90)+"99" {
+		t.Errorf("expected only the newest messages to be kept, got %d: %v", len(got), got)
+	}
+}
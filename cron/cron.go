@@ -0,0 +1,281 @@
+// Package cron runs scheduled tasks for the Helix framework, tied to the
+// server lifecycle.
+//
+// Jobs are scheduled with standard cron expressions or descriptors such as
+// "@hourly" and "@every 30s". Each job may have a timeout and jitter, runs
+// are never overlapped unless allowed, and running jobs are waited for
+// during graceful shutdown.
+//
+// Example:
+//
+//	c := cron.New(cron.DefaultConfig())
+//	c.MustAdd("*/5 * * * *", func(ctx context.Context) error {
+//	    return cache.Refresh(ctx)
+//	}, cron.WithName("refresh-cache"), cron.WithTimeout(time.Minute))
+//	cron.Attach(s, c)
+package cron
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kolosys/helix"
+)
+
+// Func is a scheduled task.
+type Func func(ctx context.Context) error
+
+// Config configures a Cron.
+type Config struct {
+	// Location is the time zone schedules are evaluated in.
+	// Default: time.Local
+	Location *time.Location
+
+	// OnError is called when a job returns an error or panics.
+	// Default: logs the error
+	OnError func(name string, err error)
+}
+
+// DefaultConfig returns the default cron configuration.
+func DefaultConfig() Config {
+	return Config{Location: time.Local}
+}
+
+// Option configures a job.
+type Option func(*job)
+
+// WithName names the job in error reports.
+// Default: the cron expression
+func WithName(name string) Option {
+	return func(j *job) { j.name = name }
+}
+
+// WithTimeout cancels the job's context after d.
+// Default: no timeout
+func WithTimeout(d time.Duration) Option {
+	return func(j *job) { j.timeout = d }
+}
+
+// WithJitter delays each run by a random duration up to d, which spreads
+// load when many instances run the same schedule.
+// Default: no jitter
+func WithJitter(d time.Duration) Option {
+	return func(j *job) { j.jitter = d }
+}
+
+// AllowOverlap lets a run start while the previous run is still in progress.
+// By default such runs are skipped.
+func AllowOverlap() Option {
+	return func(j *job) { j.overlap = true }
+}
+
+// job is a scheduled task.
+type job struct {
+	name     string
+	schedule Schedule
+	fn       Func
+	timeout  time.Duration
+	jitter   time.Duration
+	overlap  bool
+	running  atomic.Int32
+}
+
+// Cron runs jobs on their schedules.
+type Cron struct {
+	config Config
+
+	mu      sync.Mutex
+	jobs    []*job
+	started bool
+	stopped bool
+
+	stop    chan struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+	loops   sync.WaitGroup
+	running sync.WaitGroup
+}
+
+// New creates a Cron. Add jobs with Add and start it with Start, or attach
+// it to a server with Attach.
+func New(config Config) *Cron {
+	if config.Location == nil {
+		config.Location = time.Local
+	}
+	if config.OnError == nil {
+		config.OnError = func(name string, err error) {
+			log.Printf("helix/cron: job %s failed: %v", name, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Cron{
+		config: config,
+		stop:   make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Add schedules fn according to spec. See Parse for the expression syntax.
+// Jobs added after Start are scheduled immediately.
+func (c *Cron) Add(spec string, fn Func, opts ...Option) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+	c.AddSchedule(schedule, fn, append([]Option{WithName(spec)}, opts...)...)
+	return nil
+}
+
+// MustAdd is like Add but panics if spec is invalid.
+func (c *Cron) MustAdd(spec string, fn Func, opts ...Option) {
+	if err := c.Add(spec, fn, opts...); err != nil {
+		panic(err)
+	}
+}
+
+// AddSchedule schedules fn according to a custom Schedule.
+func (c *Cron) AddSchedule(schedule Schedule, fn Func, opts ...Option) {
+	j := &job{name: "job", schedule: schedule, fn: fn}
+	for _, opt := range opts {
+		opt(j)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.jobs = append(c.jobs, j)
+	if c.started && !c.stopped {
+		c.loops.Add(1)
+		go c.loop(j)
+	}
+}
+
+// Start starts running jobs on their schedules. Calling Start more than
+// once has no effect.
+func (c *Cron) Start() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.started || c.stopped {
+		return
+	}
+	c.started = true
+	for _, j := range c.jobs {
+		c.loops.Add(1)
+		go c.loop(j)
+	}
+}
+
+// Stop stops scheduling runs and waits for running jobs to finish. If ctx
+// expires first, the running jobs' contexts are canceled and ctx.Err() is
+// returned.
+func (c *Cron) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	if c.stopped {
+		c.mu.Unlock()
+		return nil
+	}
+	c.stopped = true
+	close(c.stop)
+	c.mu.Unlock()
+
+	c.loops.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		c.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		c.cancel()
+		return nil
+	case <-ctx.Done():
+		c.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Attach ties c to the server's lifecycle: jobs start running with the
+// server and stop during graceful shutdown, once the in-flight requests are
+// done. Shutdown errors are returned from Server.Shutdown.
+func Attach(s *helix.Server, c *Cron) {
+	s.OnStart(func(s *helix.Server) {
+		c.Start()
+	})
+	s.RegisterShutdownWaiter(c.Stop)
+}
+
+// loop waits for each activation of j and runs it.
+func (c *Cron) loop(j *job) {
+	defer c.loops.Done()
+
+	for {
+		now := time.Now().In(c.config.Location)
+		next := j.schedule.Next(now)
+		if next.IsZero() {
+			return
+		}
+		delay := next.Sub(now)
+		if j.jitter > 0 {
+			delay += rand.N(j.jitter)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+			c.fire(j)
+		case <-c.stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// fire runs j unless a previous run is still in progress and overlap is
+// not allowed.
+func (c *Cron) fire(j *job) {
+	if !j.overlap && !j.running.CompareAndSwap(0, 1) {
+		return
+	}
+	if j.overlap {
+		j.running.Add(1)
+	}
+
+	c.running.Add(1)
+	go func() {
+		defer c.running.Done()
+		defer j.running.Add(-1)
+		if err := c.run(j); err != nil {
+			c.config.OnError(j.name, err)
+		}
+	}()
+}
+
+// run calls j's function with panic recovery and its timeout.
+func (c *Cron) run(j *job) (err error) {
+	ctx := c.ctx
+	if j.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, j.timeout)
+		defer cancel()
+	}
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("helix/cron: panic: %v\n%s", rec, debug.Stack())
+		}
+	}()
+
+	return j.fn(ctx)
+}
@@ -0,0 +1,117 @@
+package cron_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/helix/cron"
+)
+
+func TestParse_Next(t *testing.T) {
+	base := time.Date(2026, time.March, 14, 10, 7, 30, 0, time.UTC) // Saturday
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 3, 14, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 3, 14, 10, 15, 0, 0, time.UTC)},
+		{"0 9-17 * * mon-fri", time.Date(2026, 3, 16, 9, 0, 0, 0, time.UTC)},
+		{"30 2 1 * *", time.Date(2026, 4, 1, 2, 30, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)},
+		{"0 0 13 * fri", time.Date(2026, 3, 20, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 3, 14, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", time.Date(2026, 3, 14, 10, 9, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.spec, func(t *testing.T) {
+			s, err := Parse(tc.spec)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := s.Next(base); !got.Equal(tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "@often", "@every -1s"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestCron_RunsAndStops(t *testing.T) {
+	c := New(DefaultConfig())
+
+	var runs atomic.Int32
+	c.AddSchedule(Every(time.Second), func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	})
+
+	c.Start()
+	time.Sleep(1100 * time.Millisecond)
+	if err := c.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	if runs.Load() < 1 {
+		t.Error("expected the job to run")
+	}
+}
+
+func TestCron_PreventsOverlapAndReportsErrors(t *testing.T) {
+	errs := make(chan error, 10)
+	config := DefaultConfig()
+	config.OnError = func(name string, err error) { errs <- err }
+	c := New(config)
+
+	var runs atomic.Int32
+	release := make(chan struct{})
+	c.AddSchedule(Every(time.Second), func(ctx context.Context) error {
+		runs.Add(1)
+		<-release
+		return errors.New("failed")
+	}, WithName("slow"))
+
+	c.Start()
+	time.Sleep(2100 * time.Millisecond)
+	close(release)
+	c.Stop(context.Background())
+
+	if runs.Load() != 1 {
+		t.Errorf("expected overlapping runs to be skipped, got %d runs", runs.Load())
+	}
+	if len(errs) != 1 {
+		t.Errorf("expected 1 reported error, got %d", len(errs))
+	}
+}
+
+func TestCron_StopCancelsAfterDeadline(t *testing.T) {
+	c := New(DefaultConfig())
+	started := make(chan struct{})
+	c.AddSchedule(Every(time.Second), func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return nil
+	})
+
+	c.Start()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := c.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
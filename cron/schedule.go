@@ -0,0 +1,226 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule computes the activation times of a job.
+type Schedule interface {
+	// Next returns the first activation time after t.
+	Next(t time.Time) time.Time
+}
+
+// Parse parses a standard five-field cron expression
+// ("minute hour day-of-month month day-of-week") or a descriptor.
+//
+// Fields support "*", single values, ranges ("1-5"), lists ("1,15") and
+// steps ("*/15", "0-30/10"). Months and weekdays accept three-letter names
+// ("jan", "mon"); weekday 0 and 7 are Sunday. If both day-of-month and
+// day-of-week are restricted, a day matching either runs the job.
+//
+// Descriptors: @yearly (or @annually), @monthly, @weekly, @daily (or
+// @midnight), @hourly and "@every <duration>", e.g. "@every 90s".
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@") {
+		return parseDescriptor(spec)
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("helix/cron: expected 5 fields in %q, got %d", spec, len(fields))
+	}
+
+	var s specSchedule
+	var err error
+	if s.minute, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseField(fields[2], 1, 31, nil); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, err
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1 << 0
+	}
+	s.domStar = fields[2] == "*" || fields[2] == "?"
+	s.dowStar = fields[4] == "*" || fields[4] == "?"
+	return &s, nil
+}
+
+// MustParse is like Parse but panics if the expression is invalid.
+func MustParse(spec string) Schedule {
+	s, err := Parse(spec)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Every returns a schedule that activates every d, which is rounded to at
+// least one second.
+func Every(d time.Duration) Schedule {
+	if d < time.Second {
+		d = time.Second
+	}
+	return everySchedule(d.Truncate(time.Second))
+}
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var dayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// parseDescriptor parses "@daily" style descriptors.
+func parseDescriptor(spec string) (Schedule, error) {
+	switch spec {
+	case "@yearly", "@annually":
+		return Parse("0 0 1 1 *")
+	case "@monthly":
+		return Parse("0 0 1 * *")
+	case "@weekly":
+		return Parse("0 0 * * 0")
+	case "@daily", "@midnight":
+		return Parse("0 0 * * *")
+	case "@hourly":
+		return Parse("0 * * * *")
+	}
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("helix/cron: invalid duration in %q", spec)
+		}
+		return Every(d), nil
+	}
+	return nil, fmt.Errorf("helix/cron: unknown descriptor %q", spec)
+}
+
+// parseField parses one field into a bit set of allowed values.
+func parseField(field string, lo, hi int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		expr, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("helix/cron: invalid step in %q", part)
+			}
+			step = n
+		}
+
+		start, end := lo, hi
+		switch {
+		case expr == "*" || expr == "?":
+		case strings.Contains(expr, "-"):
+			a, b, _ := strings.Cut(expr, "-")
+			var err error
+			if start, err = parseValue(a, lo, hi, names); err != nil {
+				return 0, err
+			}
+			if end, err = parseValue(b, lo, hi, names); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("helix/cron: invalid range %q", expr)
+			}
+		default:
+			v, err := parseValue(expr, lo, hi, names)
+			if err != nil {
+				return 0, err
+			}
+			start = v
+			if !hasStep {
+				end = v
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// parseValue parses a number or name within [lo, hi].
+func parseValue(s string, lo, hi int, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < lo || v > hi {
+		return 0, fmt.Errorf("helix/cron: value %q out of range [%d-%d]", s, lo, hi)
+	}
+	return v, nil
+}
+
+// specSchedule is a parsed five-field cron expression.
+type specSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+// Next implements Schedule.
+func (s *specSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches applies the day-of-month and day-of-week fields.
+func (s *specSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.domStar && s.dowStar:
+		return true
+	case s.domStar:
+		return dow
+	case s.dowStar:
+		return dom
+	}
+	return dom || dow
+}
+
+// everySchedule activates at a fixed interval.
+type everySchedule time.Duration
+
+// Next implements Schedule.
+func (e everySchedule) Next(t time.Time) time.Time {
+	return t.Truncate(time.Second).Add(time.Duration(e))
+}
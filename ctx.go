@@ -4,6 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kolosys/helix/flags"
+	"github.com/kolosys/helix/logs"
+	"github.com/kolosys/helix/middleware"
 )
 
 // Ctx provides a unified context for HTTP handlers with fluent accessors
@@ -17,22 +23,69 @@ type Ctx struct {
 
 	// store holds request-scoped values for dependency injection
 	store map[string]any
+
+	// next is the rest of the chain when the Ctx belongs to a CtxMiddleware
+	next http.Handler
+
+	// writer wraps the response to track what has been written
+	writer ctxWriter
+
+	// flashes holds the flash messages added during this request
+	flashes []Flash
+
+	// flashesRead reports whether Flashes consumed the incoming messages
+	flashesRead bool
+
+	// trailers holds the trailers registered with SetTrailer
+	trailers []trailer
+
+	// links holds the links added with WithLinks
+	links Links
+}
+
+// ctxPool reuses Ctx values across HandleCtx calls.
+var ctxPool = sync.Pool{
+	New: func() any {
+		return &Ctx{}
+	},
+}
+
+// acquireCtx returns a pooled Ctx reset for the request.
+func acquireCtx(w http.ResponseWriter, r *http.Request) *Ctx {
+	c := ctxPool.Get().(*Ctx)
+	c.Reset(w, r)
+	return c
+}
+
+// releaseCtx returns c to the pool, dropping its references.
+func releaseCtx(c *Ctx) {
+	c.Reset(nil, nil)
+	ctxPool.Put(c)
 }
 
 // NewCtx creates a new Ctx from an http.Request and http.ResponseWriter.
+// The Response field wraps w to track the status and bytes written.
 func NewCtx(w http.ResponseWriter, r *http.Request) *Ctx {
-	return &Ctx{
-		Request:  r,
-		Response: w,
-	}
+	c := &Ctx{}
+	c.Reset(w, r)
+	return c
 }
 
 // Reset resets the Ctx for reuse from a pool.
 func (c *Ctx) Reset(w http.ResponseWriter, r *http.Request) {
 	c.Request = r
-	c.Response = w
+	c.writer.reset(w)
+	c.Response = &c.writer
+	if w == nil {
+		c.Response = nil
+	}
 	c.status = 0
 	c.store = nil
+	c.next = nil
+	c.flashes = nil
+	c.flashesRead = false
+	c.trailers = nil
+	c.links = nil
 }
 
 // Context returns the request's context.Context.
@@ -40,6 +93,68 @@ func (c *Ctx) Context() context.Context {
 	return c.Request.Context()
 }
 
+// Logger returns the request-scoped logger set by middleware.ContextLogger.
+// Falls back to the default logger if none is set.
+func (c *Ctx) Logger() *logs.Logger {
+	return logs.FromContext(c.Request.Context())
+}
+
+// Can reports whether the request's principal holds permission under the
+// policy installed by middleware.Authorization, for checks that depend on
+// the resource being handled. Policy errors are logged and deny the
+// permission.
+//
+// Example:
+//
+//	if article.AuthorID != userID && !c.Can("articles:edit-any") {
+//	    return c.Forbidden("not your article")
+//	}
+func (c *Ctx) Can(permission string) bool {
+	ok, err := middleware.Can(c.Request.Context(), permission)
+	if err != nil {
+		c.Logger().Error("helix: authorization check failed", logs.Fields{"permission": permission, "error": err})
+	}
+	return ok
+}
+
+// Principal returns the request's authenticated principal, or nil if the
+// request is not authenticated. It is the same whichever middleware
+// authenticated the request.
+//
+// Example:
+//
+//	if p := c.Principal(); p != nil {
+//	    tenant := p.Attr("tenant")
+//	}
+func (c *Ctx) Principal() *Principal {
+	return middleware.PrincipalFrom(c.Request.Context())
+}
+
+// FeatureEnabled reports whether the feature flag named name is enabled
+// for the request, as loaded by flags.Middleware. It is false when the
+// middleware is not installed.
+//
+// Example:
+//
+//	if c.FeatureEnabled("new-search") {
+//	    return searchV2(c)
+//	}
+func (c *Ctx) FeatureEnabled(name string) bool {
+	return flags.Enabled(c.Request.Context(), name)
+}
+
+// UserAgentInfo classifies the request's User-Agent header by browser,
+// operating system, device and bot.
+func (c *Ctx) UserAgentInfo() middleware.UserAgent {
+	return middleware.ParseUserAgent(c.Request.UserAgent())
+}
+
+// Geo returns the client's location as resolved by middleware.GeoIP, or nil
+// if it is unknown.
+func (c *Ctx) Geo() *middleware.Geo {
+	return middleware.GeoFrom(c.Request.Context())
+}
+
 // -----------------------------------------------------------------------------
 // Request-Scoped Storage (Dependency Injection)
 // -----------------------------------------------------------------------------
@@ -162,6 +277,50 @@ func (c *Ctx) Header(name string) string {
 	return c.Request.Header.Get(name)
 }
 
+// ContentType returns the media type of the request body without parameters.
+func (c *Ctx) ContentType() string {
+	return ContentType(c.Request)
+}
+
+// -----------------------------------------------------------------------------
+// Content Negotiation
+// -----------------------------------------------------------------------------
+
+// Accepts returns the best of the offered content types for the Accept header.
+// Offers may be media types or shorthands such as "json" and "html".
+func (c *Ctx) Accepts(offers ...string) string {
+	return Accepts(c.Request, offers...)
+}
+
+// AcceptsEncodings returns the best of the offered content codings for the Accept-Encoding header.
+func (c *Ctx) AcceptsEncodings(offers ...string) string {
+	return AcceptsEncodings(c.Request, offers...)
+}
+
+// AcceptsLanguages returns the best of the offered language tags for the Accept-Language header.
+func (c *Ctx) AcceptsLanguages(offers ...string) string {
+	return AcceptsLanguages(c.Request, offers...)
+}
+
+// -----------------------------------------------------------------------------
+// Cookie Accessors
+// -----------------------------------------------------------------------------
+
+// Cookie returns the named request cookie.
+// Returns http.ErrNoCookie if not found.
+func (c *Ctx) Cookie(name string) (*http.Cookie, error) {
+	return c.Request.Cookie(name)
+}
+
+// CookieValue returns the value of the named request cookie or a default value.
+func (c *Ctx) CookieValue(name, defaultVal string) string {
+	cookie, err := c.Request.Cookie(name)
+	if err != nil {
+		return defaultVal
+	}
+	return cookie.Value
+}
+
 // -----------------------------------------------------------------------------
 // Request Body Binding
 // -----------------------------------------------------------------------------
@@ -201,35 +360,110 @@ func (c *Ctx) SetCookie(cookie *http.Cookie) *Ctx {
 	return c
 }
 
+// DeleteCookie expires the named cookie on the client and returns the Ctx for chaining.
+// The cookie is deleted at path "/"; use SetCookie with MaxAge -1 for other paths or domains.
+func (c *Ctx) DeleteCookie(name string) *Ctx {
+	return c.SetCookie(&http.Cookie{
+		Name:    name,
+		Value:   "",
+		Path:    "/",
+		MaxAge:  -1,
+		Expires: time.Unix(0, 0),
+	})
+}
+
+// EarlyHints sends a 103 Early Hints response with a preload Link header for
+// each of links, so the browser can fetch critical assets while the handler
+// is still working. Links are asset paths such as "/static/app.css" or full
+// Link values; see middleware.PreloadLink. Hints are only sent to HTTP/2 and
+// later clients, but the Link headers are kept on the final response.
+// Must be called before the response is written.
+func (c *Ctx) EarlyHints(links ...string) *Ctx {
+	middleware.WriteEarlyHints(c.Response, c.Request, links...)
+	return c
+}
+
 // Status sets the pending status code for the response and returns the Ctx for chaining.
-// The status is applied when a response body is written.
+// The status is applied when a response body is written: it replaces the
+// implied status of OK, Created and Accepted, and is used by JSON, Text,
+// HTML and Blob when they are called with a status of 0.
 func (c *Ctx) Status(code int) *Ctx {
 	c.status = code
 	return c
 }
 
+// StatusCode returns the status code written to the response, or the pending
+// status if nothing has been written yet. In a CtxMiddleware, it reports the
+// status written downstream once c.Next returns.
+func (c *Ctx) StatusCode() int {
+	if c.writer.status != 0 {
+		return c.writer.status
+	}
+	return c.status
+}
+
+// Written reports whether the response has been committed, i.e. the status
+// line and headers have been sent. Headers can no longer be changed then.
+func (c *Ctx) Written() bool {
+	return c.writer.status != 0
+}
+
+// BytesWritten returns the number of response body bytes written so far.
+func (c *Ctx) BytesWritten() int64 {
+	return c.writer.size
+}
+
+// resolveStatus returns status, or the pending status (default 200) if status is 0.
+func (c *Ctx) resolveStatus(status int) int {
+	if status == 0 {
+		status = c.status
+	}
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return status
+}
+
+// impliedStatus returns the pending status if set, otherwise status.
+func (c *Ctx) impliedStatus(status int) int {
+	if c.status != 0 {
+		return c.status
+	}
+	return status
+}
+
+// Push initiates an HTTP/2 server push for the given target.
+// Returns http.ErrNotSupported if the underlying connection does not support push.
+func (c *Ctx) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := c.Response.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
 // -----------------------------------------------------------------------------
 // Response Writers
 // -----------------------------------------------------------------------------
 
 // JSON writes a JSON response with the given status code.
+// A status of 0 uses the pending status set with Status, or 200.
 func (c *Ctx) JSON(status int, v any) error {
-	return JSON(c.Response, status, v)
+	return JSON(c.Response, c.resolveStatus(status), c.withLinks(v))
 }
 
-// OK writes a 200 OK JSON response.
+// OK writes a 200 OK JSON response, or uses the pending status if set.
 func (c *Ctx) OK(v any) error {
-	return OK(c.Response, v)
+	return JSON(c.Response, c.impliedStatus(http.StatusOK), c.withLinks(v))
 }
 
-// Created writes a 201 Created JSON response.
+// Created writes a 201 Created JSON response, or uses the pending status if set.
 func (c *Ctx) Created(v any) error {
-	return Created(c.Response, v)
+	return JSON(c.Response, c.impliedStatus(http.StatusCreated), c.withLinks(v))
 }
 
-// Accepted writes a 202 Accepted JSON response.
+// Accepted writes a 202 Accepted JSON response, or uses the pending status if set.
 func (c *Ctx) Accepted(v any) error {
-	return Accepted(c.Response, v)
+	return JSON(c.Response, c.impliedStatus(http.StatusAccepted), c.withLinks(v))
 }
 
 // NoContent writes a 204 No Content response.
@@ -238,22 +472,26 @@ func (c *Ctx) NoContent() error {
 }
 
 // Text writes a plain text response with the given status code.
+// A status of 0 uses the pending status set with Status, or 200.
 func (c *Ctx) Text(status int, text string) error {
-	return Text(c.Response, status, text)
+	return Text(c.Response, c.resolveStatus(status), text)
 }
 
 // HTML writes an HTML response with the given status code.
+// A status of 0 uses the pending status set with Status, or 200.
 func (c *Ctx) HTML(status int, html string) error {
-	return HTML(c.Response, status, html)
+	return HTML(c.Response, c.resolveStatus(status), html)
 }
 
 // Blob writes binary data with the given content type.
+// A status of 0 uses the pending status set with Status, or 200.
 func (c *Ctx) Blob(status int, contentType string, data []byte) error {
-	return Blob(c.Response, status, contentType, data)
+	return Blob(c.Response, c.resolveStatus(status), contentType, data)
 }
 
 // Problem writes an RFC 7807 Problem response.
 func (c *Ctx) Problem(p Problem) error {
+	p = prepareProblem(c.Response, c.Request, p)
 	if p.Instance == "" {
 		p.Instance = c.Request.URL.RequestURI()
 	}
@@ -317,11 +555,7 @@ func (c *Ctx) InternalServerError(message string) error {
 
 // SendJSON writes a JSON response with the pending status code (or 200 if not set).
 func (c *Ctx) SendJSON(v any) error {
-	status := c.status
-	if status == 0 {
-		status = 200
-	}
-	return JSON(c.Response, status, v)
+	return c.JSON(0, v)
 }
 
 // OKMessage writes a 200 OK response with a message.
@@ -339,13 +573,21 @@ func (c *Ctx) DeletedMessage(message string) error {
 	return c.OK(map[string]string{"message": message, "deleted": "true"})
 }
 
-// Paginated writes a paginated JSON response.
+// Paginated writes a paginated JSON response with self, first, prev, next
+// and last links, as a Link header and in "_links". Links set with
+// WithLinks take precedence.
 func (c *Ctx) Paginated(items any, total, page, limit int) error {
 	totalPages := total / limit
 	if total%limit > 0 {
 		totalPages++
 	}
 
+	links := PageLinks(c.Request, page, limit, totalPages)
+	for rel := range c.links {
+		delete(links, rel)
+	}
+	c.WithLinks(links)
+
 	return c.OK(map[string]any{
 		"items":       items,
 		"total":       total,
@@ -365,11 +607,107 @@ type CtxHandler func(c *Ctx) error
 
 // HandleCtx wraps a CtxHandler into an http.HandlerFunc.
 // Errors returned from the handler are automatically converted to RFC 7807 responses.
+// Values stored with Set by a CtxMiddleware are visible to the handler.
+//
+// The Ctx is pooled and reused once the handler returns, so it must not be
+// retained, e.g. by goroutines started from the handler. Copy the values
+// needed instead.
 func HandleCtx(h CtxHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		c := NewCtx(w, r)
+		c := acquireCtx(w, r)
+		if parent := ctxFromRequest(r); parent != nil {
+			c.store = parent.sharedStore()
+		}
 		if err := h(c); err != nil {
-			handleError(w, r, err)
+			c.handleError(err)
 		}
+		c.writeTrailers()
+		releaseCtx(c)
+	}
+}
+
+// -----------------------------------------------------------------------------
+// CtxMiddleware
+// -----------------------------------------------------------------------------
+
+// CtxMiddleware is a middleware that uses the unified Ctx type.
+// It calls c.Next to run the rest of the chain, or returns without calling it
+// to stop the request. A returned error is converted to an RFC 7807 response
+// like errors from handlers, so it must be returned before c.Next writes a response.
+//
+// Server.Use, Group.Use and route groups accept CtxMiddleware and
+// func(*Ctx) error alongside net/http middleware.
+//
+// Example:
+//
+//	s.Use(func(c *helix.Ctx) error {
+//	    user, err := auth.Authenticate(c.Header("Authorization"))
+//	    if err != nil {
+//	        return helix.Unauthorizedf("invalid credentials")
+//	    }
+//	    c.Set("user", user)
+//	    return c.Next()
+//	})
+type CtxMiddleware func(c *Ctx) error
+
+// ctxKey is the context key for the Ctx of the innermost CtxMiddleware.
+type ctxKey struct{}
+
+// AdaptCtx converts a CtxMiddleware into a net/http Middleware.
+func AdaptCtx(mw CtxMiddleware) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c := NewCtx(w, r)
+			c.next = next
+			if parent := ctxFromRequest(r); parent != nil {
+				c.store = parent.sharedStore()
+			}
+			c.Request = r.WithContext(context.WithValue(r.Context(), ctxKey{}, c))
+
+			if err := mw(c); err != nil {
+				c.handleError(err)
+			}
+			c.writeTrailers()
+		})
+	}
+}
+
+// Next runs the rest of the middleware chain and the handler with the Ctx's
+// current request and response, so changes made to c.Request and c.Response
+// are seen downstream. Errors from downstream handlers are written by those
+// handlers; Next returns nil so middleware can end with return c.Next().
+// Next does nothing outside of a CtxMiddleware.
+func (c *Ctx) Next() error {
+	if c.next == nil {
+		return nil
+	}
+	next := c.next
+	c.next = nil
+	next.ServeHTTP(c.Response, c.Request)
+	return nil
+}
+
+// handleError writes err as an RFC 7807 response. If the response has already
+// been written, the error can't be sent to the client, so it is passed to the
+// error hooks and logged as a warning instead.
+func (c *Ctx) handleError(err error) {
+	if !c.Written() {
+		handleError(c.Response, c.Request, err)
+		return
+	}
+	reportCommittedError(c.Request, err, c.StatusCode())
+}
+
+// ctxFromRequest returns the Ctx of the innermost CtxMiddleware, if any.
+func ctxFromRequest(r *http.Request) *Ctx {
+	c, _ := r.Context().Value(ctxKey{}).(*Ctx)
+	return c
+}
+
+// sharedStore returns the store, creating it so downstream contexts share it.
+func (c *Ctx) sharedStore() map[string]any {
+	if c.store == nil {
+		c.store = make(map[string]any)
 	}
+	return c.store
 }
@@ -1,13 +1,24 @@
 package helix_test
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/http/httptrace"
+	"net/netip"
+	"net/textproto"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
 
 	. "github.com/kolosys/helix"
+	"github.com/kolosys/helix/flags"
+	"github.com/kolosys/helix/logs"
+	"github.com/kolosys/helix/middleware"
 )
 
 func TestCtx_Param(t *testing.T) {
@@ -463,6 +474,274 @@ func TestCtx_ChainedSetHeader(t *testing.T) {
 	}
 }
 
+func TestCtx_StatusHonoredByWriters(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(c *Ctx) error
+		want  int
+	}{
+		{"OK", func(c *Ctx) error { return c.Status(http.StatusNotFound).OK("x") }, http.StatusNotFound},
+		{"Created", func(c *Ctx) error { return c.Status(http.StatusOK).Created("x") }, http.StatusOK},
+		{"JSON zero", func(c *Ctx) error { return c.Status(http.StatusAccepted).JSON(0, "x") }, http.StatusAccepted},
+		{"JSON explicit", func(c *Ctx) error { return c.Status(http.StatusAccepted).JSON(http.StatusTeapot, "x") }, http.StatusTeapot},
+		{"Text", func(c *Ctx) error { return c.Status(http.StatusConflict).Text(0, "x") }, http.StatusConflict},
+		{"HTML", func(c *Ctx) error { return c.Status(http.StatusGone).HTML(0, "x") }, http.StatusGone},
+		{"Blob", func(c *Ctx) error {
+			return c.Status(http.StatusPartialContent).Blob(0, "application/octet-stream", []byte("x"))
+		}, http.StatusPartialContent},
+		{"Text default", func(c *Ctx) error { return c.Text(0, "x") }, http.StatusOK},
+		{"OK default", func(c *Ctx) error { return c.OK("x") }, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			c := NewCtx(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if err := tt.write(c); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rec.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, rec.Code)
+			}
+			if c.StatusCode() != tt.want {
+				t.Errorf("expected StatusCode %d, got %d", tt.want, c.StatusCode())
+			}
+		})
+	}
+}
+
+func TestCtx_StatusCodeAfterNext(t *testing.T) {
+	s := New(nil)
+
+	var got int
+	s.Use(func(c *Ctx) error {
+		err := c.Next()
+		got = c.StatusCode()
+		return err
+	})
+	s.GET("/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	s.GET("/ok", HandleCtx(func(c *Ctx) error {
+		return c.Text(0, "ok")
+	}))
+
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if got != http.StatusNotFound {
+		t.Errorf("expected downstream status 404, got %d", got)
+	}
+
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+	if got != http.StatusOK {
+		t.Errorf("expected downstream status 200, got %d", got)
+	}
+}
+
+func TestCtx_Written(t *testing.T) {
+	rec := httptest.NewRecorder()
+	c := NewCtx(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if c.Written() || c.BytesWritten() != 0 {
+		t.Fatal("expected fresh Ctx to be unwritten")
+	}
+
+	c.Text(http.StatusOK, "hello")
+	if !c.Written() {
+		t.Error("expected Written after body write")
+	}
+	if c.BytesWritten() != 5 {
+		t.Errorf("expected 5 bytes written, got %d", c.BytesWritten())
+	}
+
+	// Informational responses don't commit the response
+	c = NewCtx(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	c.Response.WriteHeader(http.StatusEarlyHints)
+	if c.Written() {
+		t.Error("expected 1xx not to commit the response")
+	}
+}
+
+func TestCtx_WriterPassthrough(t *testing.T) {
+	rec := httptest.NewRecorder()
+	c := NewCtx(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	flusher, ok := c.Response.(http.Flusher)
+	if !ok {
+		t.Fatal("expected response to implement http.Flusher")
+	}
+	flusher.Flush()
+	if !rec.Flushed {
+		t.Error("expected flush to reach the underlying writer")
+	}
+
+	if _, _, err := http.NewResponseController(c.Response).Hijack(); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported from recorder hijack, got %v", err)
+	}
+	if err := c.Push("/app.js", nil); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported from push, got %v", err)
+	}
+
+	n, err := c.Response.(io.ReaderFrom).ReadFrom(strings.NewReader("body"))
+	if err != nil || n != 4 || c.BytesWritten() != 4 {
+		t.Errorf("expected ReadFrom to copy 4 bytes, got %d, %v", n, err)
+	}
+}
+
+func TestHandleCtx_ErrorAfterWrite(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logs.New(logs.WithOutput(&buf))
+
+	var hooked error
+	s := New(nil)
+	s.OnError(func(r *http.Request, err error) { hooked = err })
+	s.Use(middleware.ContextLogger(logger))
+	s.GET("/partial", HandleCtx(func(c *Ctx) error {
+		c.Text(http.StatusOK, "partial")
+		return errors.New("stream interrupted")
+	}))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/partial", nil))
+
+	if rec.Code != http.StatusOK || rec.Body.String() != "partial" {
+		t.Errorf("expected the original response to be kept, got %d %q", rec.Code, rec.Body.String())
+	}
+	if hooked == nil {
+		t.Error("expected error hooks to run")
+	}
+	if !strings.Contains(buf.String(), "WARN") || !strings.Contains(buf.String(), "stream interrupted") {
+		t.Errorf("expected warning log, got %q", buf.String())
+	}
+}
+
+func TestCtx_Logger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logs.New(logs.WithOutput(&buf), logs.WithFormatter(&logs.TextFormatter{DisableTimestamp: true}))
+
+	s := New(nil)
+	s.Use(middleware.ContextLogger(logger))
+	s.GET("/users/{id}", HandleCtx(func(c *Ctx) error {
+		c.Logger().Info("fetching user")
+		return c.NoContent()
+	}))
+
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/7", nil))
+
+	line := buf.String()
+	for _, want := range []string{"INFO fetching user", "method=GET", "path=/users/7", "route=/users/{id}"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("expected log line to contain %q, got %q", want, line)
+		}
+	}
+}
+
+func TestCtx_Can(t *testing.T) {
+	var canEdit, canDelete bool
+
+	s := New(nil)
+	s.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := &middleware.Principal{ID: "u1", Roles: []string{"editor"}}
+			next.ServeHTTP(w, r.WithContext(middleware.WithPrincipal(r.Context(), p)))
+		})
+	})
+	s.Use(middleware.Authorization(middleware.RolePolicy{"editor": {"articles:edit"}}))
+	s.GET("/articles/{id}", HandleCtx(func(c *Ctx) error {
+		canEdit = c.Can("articles:edit")
+		canDelete = c.Can("articles:delete")
+		return c.NoContent()
+	}))
+
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/articles/1", nil))
+
+	if !canEdit || canDelete {
+		t.Errorf("expected edit only, got edit=%v delete=%v", canEdit, canDelete)
+	}
+}
+
+func TestCtx_Principal(t *testing.T) {
+	var got *Principal
+
+	s := New(nil)
+	s.Use(middleware.APIKey(map[string]*Principal{"k1": {ID: "svc", Attrs: map[string]any{"tenant": "acme"}}}))
+	s.GET("/me", HandleCtx(func(c *Ctx) error {
+		got = c.Principal()
+		return c.NoContent()
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("X-API-Key", "k1")
+	s.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got == nil || got.ID != "svc" || got.Attr("tenant") != "acme" {
+		t.Errorf("expected the API key's principal, got %+v", got)
+	}
+}
+
+func TestCtx_FeatureEnabled(t *testing.T) {
+	var enabled, other bool
+
+	s := New(nil)
+	s.Use(flags.Middleware(flags.Rules{"new-search": {Tenants: []string{"acme"}}}))
+	s.Use(middleware.APIKey(map[string]*Principal{"k1": {ID: "svc", Attrs: map[string]any{"tenant": "acme"}}}))
+	s.GET("/search", HandleCtx(func(c *Ctx) error {
+		enabled, other = c.FeatureEnabled("new-search"), c.FeatureEnabled("other")
+		return c.NoContent()
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/search", nil)
+	req.Header.Set("X-API-Key", "k1")
+	s.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !enabled || other {
+		t.Errorf("expected only new-search for the tenant, got %v %v", enabled, other)
+	}
+}
+
+func TestCtx_Geo(t *testing.T) {
+	var got *middleware.Geo
+
+	s := New(nil)
+	s.Use(middleware.GeoIP(middleware.GeoResolverFunc(func(addr netip.Addr) (*middleware.Geo, error) {
+		if addr == netip.MustParseAddr("192.0.2.1") {
+			return &middleware.Geo{Country: "NL", City: "Amsterdam"}, nil
+		}
+		return nil, nil
+	})))
+	s.GET("/", HandleCtx(func(c *Ctx) error {
+		got = c.Geo()
+		return c.NoContent()
+	}))
+
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if got == nil || got.City != "Amsterdam" {
+		t.Errorf("expected Amsterdam, got %+v", got)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "[2001:db8::1]:1234"
+	s.ServeHTTP(httptest.NewRecorder(), req)
+	if got != nil {
+		t.Errorf("expected no location, got %+v", got)
+	}
+}
+
+func TestCtx_LoggerDefault(t *testing.T) {
+	var got *logs.Logger
+
+	s := New(nil)
+	s.GET("/", HandleCtx(func(c *Ctx) error {
+		got = c.Logger()
+		return c.NoContent()
+	}))
+
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got != logs.Default() {
+		t.Error("expected default logger without ContextLogger middleware")
+	}
+}
+
 func TestHandleCtx_ReturnsError(t *testing.T) {
 	s := New(nil)
 
@@ -483,6 +762,152 @@ func TestHandleCtx_ReturnsError(t *testing.T) {
 	}
 }
 
+func TestCtxMiddleware(t *testing.T) {
+	s := New(nil)
+
+	var order []string
+	s.Use(func(c *Ctx) error {
+		order = append(order, "before")
+		c.Set("user", "alice")
+		c.SetHeader("X-Ctx", "yes")
+		err := c.Next()
+		order = append(order, "after:"+c.GetString("seen"))
+		return err
+	})
+	s.GET("/me", HandleCtx(func(c *Ctx) error {
+		order = append(order, "handler")
+		c.Set("seen", "true")
+		return c.Text(http.StatusOK, c.GetString("user"))
+	}))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/me", nil))
+
+	if rec.Body.String() != "alice" {
+		t.Errorf("expected handler to see middleware value, got %q", rec.Body.String())
+	}
+	if rec.Header().Get("X-Ctx") != "yes" {
+		t.Error("expected middleware header")
+	}
+	if strings.Join(order, ",") != "before,handler,after:true" {
+		t.Errorf("unexpected order: %v", order)
+	}
+}
+
+func TestCtxMiddleware_Error(t *testing.T) {
+	s := New(nil)
+
+	var called bool
+	s.Group("/admin", CtxMiddleware(func(c *Ctx) error {
+		if c.Header("Authorization") == "" {
+			return Unauthorizedf("missing credentials")
+		}
+		return c.Next()
+	})).GET("/stats", func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/stats", nil))
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", rec.Code)
+	}
+	if rec.Header().Get("Content-Type") != MIMEApplicationProblemJSON {
+		t.Errorf("expected problem response, got %q", rec.Header().Get("Content-Type"))
+	}
+	if called {
+		t.Error("expected handler not to run")
+	}
+}
+
+func TestCtxMiddleware_ErrorHandler(t *testing.T) {
+	s := New(&Options{
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			w.WriteHeader(http.StatusTeapot)
+		},
+	})
+	s.Use(func(c *Ctx) error {
+		return ErrForbidden
+	})
+	s.GET("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusTeapot {
+		t.Errorf("expected custom error handler for middleware errors, got %d", rec.Code)
+	}
+}
+
+func TestCtxMiddleware_ReplacesRequest(t *testing.T) {
+	type key struct{}
+
+	s := New(nil)
+	s.Use(func(c *Ctx) error {
+		c.Request = c.Request.WithContext(context.WithValue(c.Context(), key{}, "value"))
+		return c.Next()
+	})
+
+	var got any
+	s.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		got = r.Context().Value(key{})
+	})
+
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got != "value" {
+		t.Errorf("expected downstream to see replaced request, got %v", got)
+	}
+}
+
+func TestCtx_NextWithoutChain(t *testing.T) {
+	c := NewCtx(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if err := c.Next(); err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+}
+
+func TestHandleCtx_NoAllocations(t *testing.T) {
+	h := HandleCtx(func(c *Ctx) error {
+		c.Status(http.StatusOK)
+		return nil
+	})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		h(w, req)
+	})
+	if allocs != 0 {
+		t.Errorf("expected pooled Ctx to avoid allocations, got %v allocs per request", allocs)
+	}
+}
+
+func TestHandleCtx_ResetsPooledCtx(t *testing.T) {
+	var first, second *Ctx
+	h := HandleCtx(func(c *Ctx) error {
+		if first == nil {
+			first = c
+			c.Set("key", "value")
+			c.Status(http.StatusTeapot)
+			return nil
+		}
+		second = c
+		if _, ok := c.Get("key"); ok {
+			t.Error("expected store to be reset")
+		}
+		return nil
+	})
+
+	for range 2 {
+		h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	if second == nil {
+		t.Fatal("expected handler to run twice")
+	}
+}
+
 func BenchmarkCtx_ParamAccess(b *testing.B) {
 	s := New(nil)
 	s.GET("/users/{id}", HandleCtx(func(c *Ctx) error {
@@ -520,3 +945,120 @@ func BenchmarkCtx_QueryAccess(b *testing.B) {
 		s.ServeHTTP(rec, req)
 	}
 }
+
+// BenchmarkHandleCtx measures the Ctx hot path without routing, so the
+// pooled Ctx shows up as zero allocations per request.
+func BenchmarkHandleCtx(b *testing.B) {
+	h := HandleCtx(func(c *Ctx) error {
+		c.Status(http.StatusOK)
+		return nil
+	})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		h(w, req)
+	}
+}
+
+// ctxSink keeps BenchmarkNewCtx's Ctx on the heap, as in a real handler.
+var ctxSink *Ctx
+
+// BenchmarkNewCtx is the unpooled baseline for BenchmarkHandleCtx.
+func BenchmarkNewCtx(b *testing.B) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		ctxSink = NewCtx(w, req)
+		ctxSink.Status(http.StatusOK)
+	}
+}
+
+func TestCtx_EarlyHints(t *testing.T) {
+	s := New(nil)
+	s.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{Output: func(middleware.LogValues) {}}), middleware.Compress())
+	s.GET("/", HandleCtx(func(c *Ctx) error {
+		c.EarlyHints("/static/app.css", "/static/font.woff2")
+		return c.HTML(http.StatusOK, "<html></html>")
+	}))
+
+	ts := httptest.NewUnstartedServer(s)
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	var hints []string
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			if code == http.StatusEarlyHints {
+				hints = header["Link"]
+			}
+			return nil
+		},
+	}
+	req, _ := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), http.MethodGet, ts.URL, nil)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	want := []string{
+		"</static/app.css>; rel=preload; as=style",
+		"</static/font.woff2>; rel=preload; as=font; crossorigin",
+	}
+	if !slices.Equal(hints, want) {
+		t.Errorf("expected early hints %q, got %q", want, hints)
+	}
+	if resp.StatusCode != http.StatusOK || !slices.Equal(resp.Header["Link"], want) {
+		t.Errorf("expected 200 with Link headers, got %d %q", resp.StatusCode, resp.Header["Link"])
+	}
+
+	// HTTP/1.1 clients only get the Link headers
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || len(rec.Header()["Link"]) != 2 {
+		t.Errorf("expected 200 with Link headers, got %d %q", rec.Code, rec.Header()["Link"])
+	}
+}
+
+func TestCtx_SetTrailer(t *testing.T) {
+	s := New(nil)
+	s.GET("/report", HandleCtx(func(c *Ctx) error {
+		var rows int
+		c.SetTrailer("x-row-count", func() string { return strconv.Itoa(rows) })
+		c.SetHeader("Content-Type", "text/csv")
+		for ; rows < 3; rows++ {
+			io.WriteString(c.Response, "row\n")
+		}
+		c.SetTrailer("X-Late", func() string { return "unannounced" })
+		return nil
+	}))
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Announced trailers are known before the body is read
+	if _, ok := resp.Trailer["X-Row-Count"]; !ok || len(resp.Trailer) != 1 {
+		t.Errorf("expected X-Row-Count to be announced, got %v", resp.Trailer)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if string(body) != "row\nrow\nrow\n" {
+		t.Errorf("unexpected body %q", body)
+	}
+	if got := resp.Trailer.Get("X-Row-Count"); got != "3" {
+		t.Errorf("expected X-Row-Count trailer 3, got %q", got)
+	}
+	if got := resp.Trailer.Get("X-Late"); got != "unannounced" {
+		t.Errorf("expected X-Late trailer, got %q", got)
+	}
+}
@@ -0,0 +1,96 @@
+package helix
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// ctxWriter wraps a Ctx's response to track the status code and the number
+// of body bytes written. It passes through http.Flusher, http.Hijacker,
+// http.Pusher and io.ReaderFrom, and supports http.ResponseController.
+type ctxWriter struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+// reset prepares the writer for a new response.
+func (w *ctxWriter) reset(rw http.ResponseWriter) {
+	*w = ctxWriter{ResponseWriter: rw}
+}
+
+// WriteHeader records the final status code and writes it.
+// Informational (1xx) responses don't commit the response.
+func (w *ctxWriter) WriteHeader(code int) {
+	if w.status == 0 && code >= http.StatusOK {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write records an implicit 200 status and writes the data.
+func (w *ctxWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// ReadFrom implements io.ReaderFrom, using the underlying implementation
+// if available so sendfile optimizations are preserved.
+func (w *ctxWriter) ReadFrom(r io.Reader) (int64, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	var n int64
+	var err error
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = io.Copy(writerOnly{w.ResponseWriter}, r)
+	}
+	w.size += n
+	return n, err
+}
+
+// Flush implements http.Flusher if the underlying writer supports it.
+func (w *ctxWriter) Flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker if the underlying writer supports it.
+func (w *ctxWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Push implements http.Pusher if the underlying writer supports it.
+func (w *ctxWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *ctxWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// writerOnly hides any io.ReaderFrom implementation so io.Copy doesn't
+// call back into ctxWriter.ReadFrom.
+type writerOnly struct {
+	io.Writer
+}
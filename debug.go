@@ -0,0 +1,115 @@
+package helix
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	"time"
+)
+
+// EnvKey is the environment variable that names the deployment environment.
+// When set to "production", safety guards such as EnableDebug's
+// authentication requirement are enforced.
+const EnvKey = "HELIX_ENV"
+
+// processStart is when the process started, for uptime reporting.
+var processStart = time.Now()
+
+// isProduction reports whether the process runs in the production environment.
+func isProduction() bool {
+	return os.Getenv(EnvKey) == "production"
+}
+
+// RuntimeStats is the response returned by the debug runtime endpoint.
+type RuntimeStats struct {
+	GoVersion    string        `json:"go_version"`
+	GOOS         string        `json:"goos"`
+	GOARCH       string        `json:"goarch"`
+	NumCPU       int           `json:"num_cpu"`
+	GOMAXPROCS   int           `json:"gomaxprocs"`
+	NumGoroutine int           `json:"num_goroutine"`
+	Uptime       time.Duration `json:"uptime_ns"`
+	HeapAlloc    uint64        `json:"heap_alloc"`
+	HeapInuse    uint64        `json:"heap_inuse"`
+	HeapObjects  uint64        `json:"heap_objects"`
+	TotalAlloc   uint64        `json:"total_alloc"`
+	Sys          uint64        `json:"sys"`
+	NumGC        uint32        `json:"num_gc"`
+	PauseTotal   time.Duration `json:"pause_total_ns"`
+}
+
+// EnableDebug registers debugging endpoints under the given prefix:
+//   - {prefix}/pprof/        - pprof index and profiles
+//   - {prefix}/vars          - expvar variables
+//   - {prefix}/runtime       - runtime and memory statistics
+//   - {prefix}/routes        - registered routes
+//   - {prefix}/stats         - per-route counters, see EnableProfiling
+//
+// The endpoints are never registered implicitly. Middleware, such as
+// authentication, is applied to every endpoint. When HELIX_ENV is
+// "production", EnableDebug panics if no middleware is provided so profiling
+// data is never exposed unauthenticated.
+func (s *Server) EnableDebug(prefix string, mw ...any) *Group {
+	if len(mw) == 0 && isProduction() {
+		panic("helix: EnableDebug requires middleware (e.g. authentication) in production")
+	}
+
+	g := s.Group(prefix, mw...)
+
+	g.GET("/pprof/", pprof.Index)
+	g.GET("/pprof/{profile}", func(w http.ResponseWriter, r *http.Request) {
+		switch name := Param(r, "profile"); name {
+		case "cmdline":
+			pprof.Cmdline(w, r)
+		case "profile":
+			pprof.Profile(w, r)
+		case "symbol":
+			pprof.Symbol(w, r)
+		case "trace":
+			pprof.Trace(w, r)
+		default:
+			pprof.Handler(name).ServeHTTP(w, r)
+		}
+	})
+	g.POST("/pprof/symbol", pprof.Symbol)
+
+	g.GET("/vars", expvar.Handler().ServeHTTP)
+
+	g.GET("/runtime", func(w http.ResponseWriter, r *http.Request) {
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+
+		JSON(w, http.StatusOK, RuntimeStats{
+			GoVersion:    runtime.Version(),
+			GOOS:         runtime.GOOS,
+			GOARCH:       runtime.GOARCH,
+			NumCPU:       runtime.NumCPU(),
+			GOMAXPROCS:   runtime.GOMAXPROCS(0),
+			NumGoroutine: runtime.NumGoroutine(),
+			Uptime:       time.Since(processStart),
+			HeapAlloc:    mem.HeapAlloc,
+			HeapInuse:    mem.HeapInuse,
+			HeapObjects:  mem.HeapObjects,
+			TotalAlloc:   mem.TotalAlloc,
+			Sys:          mem.Sys,
+			NumGC:        mem.NumGC,
+			PauseTotal:   time.Duration(mem.PauseTotalNs),
+		})
+	})
+
+	g.GET("/routes", func(w http.ResponseWriter, r *http.Request) {
+		JSON(w, http.StatusOK, s.Routes())
+	})
+
+	g.GET("/stats", func(w http.ResponseWriter, r *http.Request) {
+		stats := s.Stats()
+		if stats == nil {
+			stats = []RouteStats{}
+		}
+		JSON(w, http.StatusOK, stats)
+	})
+
+	return g
+}
@@ -0,0 +1,140 @@
+package helix_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/helix"
+	"github.com/kolosys/helix/middleware"
+)
+
+func TestEnableDebug_Endpoints(t *testing.T) {
+	s := New(nil)
+	s.EnableDebug("/debug")
+
+	tests := []struct {
+		path     string
+		contains string
+	}{
+		{"/debug/pprof/", "goroutine"},
+		{"/debug/pprof/goroutine?debug=1", "goroutine profile"},
+		{"/debug/pprof/cmdline", ""},
+		{"/debug/vars", "memstats"},
+		{"/debug/runtime", "num_goroutine"},
+		{"/debug/routes", "/debug/runtime"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			if rec.Code != http.StatusOK {
+				t.Errorf("expected status 200, got %d", rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tc.contains) {
+				t.Errorf("expected body to contain %q", tc.contains)
+			}
+		})
+	}
+}
+
+func TestEnableDebug_RuntimeStats(t *testing.T) {
+	s := New(nil)
+	s.EnableDebug("/debug")
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/runtime", nil))
+
+	var stats RuntimeStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("failed to decode runtime stats: %v", err)
+	}
+	if stats.NumGoroutine == 0 || stats.GoVersion == "" {
+		t.Errorf("unexpected runtime stats: %+v", stats)
+	}
+}
+
+func TestEnableDebug_Middleware(t *testing.T) {
+	s := New(nil)
+	s.EnableDebug("/debug", middleware.BasicAuth("admin", "secret"))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+	req.SetBasicAuth("admin", "secret")
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+}
+
+func TestEnableDebug_ProductionRequiresMiddleware(t *testing.T) {
+	t.Setenv(EnvKey, "production")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic without middleware in production")
+		}
+	}()
+
+	New(nil).EnableDebug("/debug")
+}
+
+func TestEnableDebug_ProductionWithMiddleware(t *testing.T) {
+	t.Setenv(EnvKey, "production")
+
+	s := New(nil)
+	s.EnableDebug("/debug", middleware.BasicAuth("admin", "secret"))
+
+	if len(s.Routes()) == 0 {
+		t.Error("expected debug routes to be registered")
+	}
+}
+
+func TestEnableProfiling(t *testing.T) {
+	s := New(nil)
+	if s.Stats() != nil {
+		t.Error("expected no stats before EnableProfiling")
+	}
+
+	s.GET("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		w.Write(make([]byte, 64<<10))
+	})
+	s.EnableProfiling()
+	s.EnableDebug("/debug")
+	s.GET("/fast/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, path := range []string{"/slow", "/fast/1", "/fast/2"} {
+		s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	stats := s.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("expected stats for 2 routes, got %+v", stats)
+	}
+	slow, fast := stats[0], stats[1]
+	if slow.Pattern != "/slow" || slow.Count != 1 || slow.Max < 5*time.Millisecond || slow.Bytes < 64<<10 {
+		t.Errorf("unexpected slow route stats %+v", slow)
+	}
+	if fast.Pattern != "/fast/{id}" || fast.Count != 2 || fast.Mean != fast.Total/2 {
+		t.Errorf("unexpected fast route stats %+v", fast)
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/stats", nil))
+	var served []RouteStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &served); err != nil || len(served) != 2 {
+		t.Errorf("expected the stats endpoint to serve the counters, got %s", rec.Body)
+	}
+}
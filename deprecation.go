@@ -0,0 +1,154 @@
+package helix
+
+import (
+	"cmp"
+	"expvar"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kolosys/helix/logs"
+)
+
+// deprecatedRequests counts the requests to deprecated routes by method and
+// route, published as the "helix_deprecated_requests" expvar (see the
+// {prefix}/vars endpoint of EnableDebug).
+var deprecatedRequests = expvar.NewMap("helix_deprecated_requests")
+
+// DeprecationConfig configures the Deprecated middleware.
+type DeprecationConfig struct {
+	// Route selects the deprecated routes, in the router's pattern syntax
+	// with an optional method: "/v1/users/{id}", "GET /v1/users" or
+	// "/v1/{path...}" for every route below /v1. A trailing "*" is the same
+	// as "{path...}".
+	// Default: every route the middleware is applied to
+	Route string
+
+	// Since is when the routes were deprecated, sent as the Deprecation
+	// header (RFC 9745). If zero, "Deprecation: true" is sent instead.
+	Since time.Time
+
+	// Sunset, if set, is when the routes stop working, sent as the Sunset
+	// header (RFC 8594).
+	Sunset time.Time
+
+	// Link, if set, is a page documenting the deprecation and the
+	// replacement, sent as a Link header with rel="deprecation".
+	Link string
+
+	// OnUse is called for each request to a deprecated route.
+	// If nil, the request is logged through the request's logger.
+	OnUse func(r *http.Request)
+}
+
+// Deprecated returns a middleware that marks the routes matching route as
+// deprecated, to be removed at sunset, with link documenting the
+// migration. See DeprecatedWithConfig.
+//
+// Example:
+//
+//	sunset := time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)
+//	s.Use(helix.Deprecated("/v1/*", sunset, "https://docs.example.com/migrate-to-v2"))
+func Deprecated(route string, sunset time.Time, link string) Middleware {
+	return DeprecatedWithConfig(DeprecationConfig{Route: route, Sunset: sunset, Link: link})
+}
+
+// DeprecatedWithConfig returns a Deprecated middleware with the given
+// configuration. Responses of the matching routes carry the Deprecation,
+// Sunset and Link headers, each use is logged, and the uses are counted in
+// the "helix_deprecated_requests" expvar by method and route. Apply it with
+// Use, to a group or with WithRouteMiddleware.
+func DeprecatedWithConfig(config DeprecationConfig) Middleware {
+	method, pattern := splitRoute(config.Route)
+	all, segments := pattern == "", routeSegments(pattern)
+	deprecation := "true"
+	if !config.Since.IsZero() {
+		deprecation = "@" + strconv.FormatInt(config.Since.Unix(), 10)
+	}
+	var sunset, link string
+	if !config.Sunset.IsZero() {
+		sunset = config.Sunset.UTC().Format(http.TimeFormat)
+	}
+	if config.Link != "" {
+		link = "<" + config.Link + `>; rel="deprecation"`
+	}
+	if config.OnUse == nil {
+		config.OnUse = func(r *http.Request) {
+			fields := logs.Fields{
+				"method":     r.Method,
+				"path":       r.URL.Path,
+				"route":      config.Route,
+				"user_agent": r.UserAgent(),
+			}
+			if sunset != "" {
+				fields["sunset"] = sunset
+			}
+			logs.FromContext(r.Context()).Info("deprecated route used", fields)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if (method != "" && r.Method != method) || (!all && !matchSegments(segments, routeSegments(r.URL.Path))) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("Deprecation", deprecation)
+			if sunset != "" {
+				h.Set("Sunset", sunset)
+			}
+			if link != "" {
+				h.Add("Link", link)
+			}
+			if method != "" {
+				deprecatedRequests.Add(method+" "+pattern, 1)
+			} else {
+				deprecatedRequests.Add(r.Method+" "+cmp.Or(pattern, "*"), 1)
+			}
+			config.OnUse(r)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// splitRoute splits a "METHOD /pattern" route into its method and pattern.
+func splitRoute(route string) (method, pattern string) {
+	route = strings.TrimSpace(route)
+	if m, p, ok := strings.Cut(route, " "); ok && !strings.HasPrefix(route, "/") {
+		return strings.ToUpper(m), strings.TrimSpace(p)
+	}
+	return "", route
+}
+
+// routeSegments splits a path or pattern into its segments.
+func routeSegments(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// matchSegments reports whether the segments of a path match those of a
+// pattern, where "{name}" matches one segment and a final "{name...}" or
+// "*" the rest of the path.
+func matchSegments(pattern, path []string) bool {
+	for i, seg := range pattern {
+		if i == len(pattern)-1 && (seg == "*" || (strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "...}"))) {
+			return true
+		}
+		if i >= len(path) {
+			return false
+		}
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			continue
+		}
+		if seg != path[i] {
+			return false
+		}
+	}
+	return len(path) == len(pattern)
+}
@@ -0,0 +1,68 @@
+package helix_test
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/helix"
+)
+
+// deprecatedUses returns the uses counted for route.
+func deprecatedUses(route string) int64 {
+	if v, ok := expvar.Get("helix_deprecated_requests").(*expvar.Map).Get(route).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func TestDeprecated(t *testing.T) {
+	v1Uses, deleteUses := deprecatedUses("GET /v1/*"), deprecatedUses("DELETE /v2/users/{id}")
+	sunset := time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)
+	s := New(nil)
+	s.Use(Deprecated("/v1/*", sunset, "https://docs.example.com/v2"))
+	v2 := s.Group("/v2")
+	v2.Use(DeprecatedWithConfig(DeprecationConfig{
+		Route: "DELETE /v2/users/{id}",
+		Since: time.Unix(1700000000, 0),
+	}))
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	s.GET("/v1/users/{id}", ok)
+	v2.GET("/users/{id}", ok)
+	v2.DELETE("/users/{id}", ok)
+
+	tests := []struct {
+		method, path string
+		deprecation  string
+		sunset       string
+	}{
+		{http.MethodGet, "/v1/users/1", "true", "Fri, 01 Jan 2027 00:00:00 GMT"},
+		{http.MethodGet, "/v2/users/1", "", ""},
+		{http.MethodDelete, "/v2/users/1", "@1700000000", ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if got := rec.Header().Get("Deprecation"); got != tt.deprecation {
+			t.Errorf("%s %s: expected Deprecation %q, got %q", tt.method, tt.path, tt.deprecation, got)
+		}
+		if got := rec.Header().Get("Sunset"); got != tt.sunset {
+			t.Errorf("%s %s: expected Sunset %q, got %q", tt.method, tt.path, tt.sunset, got)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/users/2", nil))
+	if got := rec.Header().Get("Link"); got != `<https://docs.example.com/v2>; rel="deprecation"` {
+		t.Errorf("unexpected Link %q", got)
+	}
+
+	if got := deprecatedUses("GET /v1/*") - v1Uses; got != 2 {
+		t.Errorf("expected 2 uses of GET /v1/*, got %d", got)
+	}
+	if got := deprecatedUses("DELETE /v2/users/{id}") - deleteUses; got != 1 {
+		t.Errorf("expected 1 use of DELETE /v2/users/{id}, got %d", got)
+	}
+}
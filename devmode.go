@@ -0,0 +1,208 @@
+package helix
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// debugProblem is a Problem extended with debugging details.
+type debugProblem struct {
+	Problem
+	Error  string       `json:"error,omitempty"`
+	Stack  []string     `json:"stack,omitempty"`
+	Frames []debugFrame `json:"frames,omitempty"`
+}
+
+// debugFrame is a stack frame with the surrounding source code.
+type debugFrame struct {
+	Function string       `json:"function"`
+	File     string       `json:"file"`
+	Line     int          `json:"line"`
+	Source   []sourceLine `json:"source,omitempty"`
+}
+
+// sourceLine is a line of source code around a frame.
+type sourceLine struct {
+	Number  int    `json:"number"`
+	Code    string `json:"code"`
+	Current bool   `json:"current,omitempty"`
+}
+
+// sourceContext is the number of lines shown around a frame.
+const sourceContext = 4
+
+// maxDebugFrames limits the frames rendered for a panic.
+const maxDebugFrames = 10
+
+// debugRecoverMiddleware recovers panics in debug mode and renders them with
+// source snippets. http.ErrAbortHandler is re-panicked so the server can abort
+// the response.
+func (s *Server) debugRecoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			err, ok := rec.(error)
+			if !ok {
+				err = fmt.Errorf("%v", rec)
+			}
+			err = fmt.Errorf("panic: %w", err)
+
+			frames := panicFrames()
+			stack := make([]byte, 64<<10)
+			stack = stack[:runtime.Stack(stack, false)]
+
+			reportError(r, s.errorConfig, err)
+
+			problem := ErrInternal.WithErr(err)
+			problem.Instance = r.URL.RequestURI()
+			writeDebugProblem(w, r, problem, err, stack, frames)
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// panicFrames returns the frames of the panicking goroutine, starting at the
+// function that panicked, with source snippets.
+func panicFrames() []debugFrame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(3, pcs)
+	iter := runtime.CallersFrames(pcs[:n])
+
+	// Skip frames up to and including runtime.gopanic
+	var frames []debugFrame
+	seenPanic := false
+	for {
+		frame, more := iter.Next()
+		if !seenPanic {
+			seenPanic = frame.Function == "runtime.gopanic"
+			if !more {
+				break
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			frames = append(frames, debugFrame{
+				Function: frame.Function,
+				File:     frame.File,
+				Line:     frame.Line,
+				Source:   readSource(frame.File, frame.Line),
+			})
+			if len(frames) == maxDebugFrames {
+				break
+			}
+		}
+		if !more {
+			break
+		}
+	}
+	return frames
+}
+
+// readSource returns the lines around line in file.
+// Returns nil if the file cannot be read.
+func readSource(file string, line int) []sourceLine {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+
+	lines := bytes.Split(data, []byte("\n"))
+	start := max(line-sourceContext, 1)
+	end := min(line+sourceContext, len(lines))
+
+	source := make([]sourceLine, 0, end-start+1)
+	for i := start; i <= end; i++ {
+		source = append(source, sourceLine{
+			Number:  i,
+			Code:    strings.ReplaceAll(string(lines[i-1]), "\t", "    "),
+			Current: i == line,
+		})
+	}
+	return source
+}
+
+// writeDebugProblem writes a problem with the error, stack, and frames,
+// as an HTML page for browsers or as problem JSON otherwise.
+func writeDebugProblem(w http.ResponseWriter, r *http.Request, p Problem, err error, stack []byte, frames []debugFrame) {
+	dp := debugProblem{
+		Problem: p,
+		Error:   err.Error(),
+		Frames:  frames,
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(stack)), "\n") {
+		dp.Stack = append(dp.Stack, strings.TrimSpace(line))
+	}
+
+	if wantsHTML(r) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(p.Status)
+		debugPageTemplate.Execute(w, dp)
+		return
+	}
+
+	w.Header().Set("Content-Type", MIMEApplicationProblemJSON)
+	w.WriteHeader(p.Status)
+	jsonEncode(w, dp)
+}
+
+var debugPageTemplate = template.Must(template.New("debug").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Status}} {{.Title}}</title>
+<style>
+body { font-family: -apple-system, system-ui, sans-serif; margin: 0; background: #1e1e2e; color: #cdd6f4; }
+header { background: {{if ge .Status 500}}#f38ba8{{else}}#f9e2af{{end}}; color: #11111b; padding: 1.5em 2em; }
+header h1 { margin: 0; font-size: 1.6em; }
+header p { margin: .4em 0 0; }
+main { padding: 1em 2em; }
+h2 { color: #89b4fa; font-size: 1.1em; margin-top: 1.5em; }
+pre, code { font-family: ui-monospace, monospace; font-size: 13px; }
+.error { background: #313244; padding: 1em; border-left: 4px solid #f38ba8; white-space: pre-wrap; }
+.frame { margin: 1em 0; background: #181825; border-radius: 4px; overflow: hidden; }
+.frame .loc { padding: .5em 1em; background: #313244; }
+.frame .fn { color: #a6e3a1; }
+.frame table { border-collapse: collapse; width: 100%; }
+.frame td { padding: 0 1em; white-space: pre; }
+.frame td.num { color: #6c7086; text-align: right; width: 3em; user-select: none; }
+.frame tr.current { background: #45475a; }
+.frame tr.current td.num { color: #f38ba8; }
+.stack { color: #a6adc8; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Status}} {{.Title}}</h1>
+{{if .Detail}}<p>{{.Detail}}</p>{{end}}
+<p><code>{{.Instance}}</code></p>
+</header>
+<main>
+<h2>Error</h2>
+<div class="error"><code>{{.Error}}</code></div>
+{{if .Frames}}<h2>Source</h2>
+{{range .Frames}}<div class="frame">
+<div class="loc"><span class="fn">{{.Function}}</span><br><code>{{.File}}:{{.Line}}</code></div>
+{{if .Source}}<table>{{range .Source}}<tr{{if .Current}} class="current"{{end}}><td class="num">{{.Number}}</td><td><code>{{.Code}}</code></td></tr>{{end}}</table>{{end}}
+</div>
+{{end}}{{end}}
+<h2>Stack</h2>
+<pre class="stack">{{range .Stack}}{{.}}
+{{end}}</pre>
+</main>
+</body>
+</html>
+`))
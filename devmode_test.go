@@ -0,0 +1,128 @@
+package helix_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/kolosys/helix"
+)
+
+func TestDebugMode_ProblemDetails(t *testing.T) {
+	s := New(&Options{Debug: true})
+	s.GET("/fail", HandleNoRequest(func(ctx context.Context) (any, error) {
+		return nil, errors.New("db: connection refused")
+	}))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", rec.Code)
+	}
+
+	var body struct {
+		Status int      `json:"status"`
+		Error  string   `json:"error"`
+		Stack  []string `json:"stack"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if body.Status != 500 || body.Error != "db: connection refused" {
+		t.Errorf("expected underlying error in response, got %+v", body)
+	}
+	if len(body.Stack) == 0 {
+		t.Error("expected stack trace in response")
+	}
+}
+
+func TestDebugMode_Disabled(t *testing.T) {
+	s := New(nil)
+	s.GET("/fail", HandleNoRequest(func(ctx context.Context) (any, error) {
+		return nil, errors.New("db: connection refused")
+	}))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	if strings.Contains(rec.Body.String(), "connection refused") {
+		t.Errorf("expected sanitized response without debug mode, got %s", rec.Body.String())
+	}
+}
+
+func TestDebugMode_HTMLPage(t *testing.T) {
+	s := New(&Options{Debug: true})
+	s.GET("/fail", HandleNoRequest(func(ctx context.Context) (any, error) {
+		return nil, ErrNotFound.WithDetail("<user> not found")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("expected HTML content type, got %q", rec.Header().Get("Content-Type"))
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "404 Not Found") || !strings.Contains(body, "&lt;user&gt; not found") {
+		t.Errorf("unexpected debug page: %s", body)
+	}
+}
+
+func TestDebugMode_PanicWithSource(t *testing.T) {
+	s := New(&Options{Debug: true})
+	s.GET("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("something exploded")
+	})
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", rec.Code)
+	}
+
+	var body struct {
+		Error  string `json:"error"`
+		Frames []struct {
+			Function string `json:"function"`
+			File     string `json:"file"`
+			Source   []struct {
+				Code    string `json:"code"`
+				Current bool   `json:"current"`
+			} `json:"source"`
+		} `json:"frames"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if body.Error != "panic: something exploded" {
+		t.Errorf("expected panic error, got %q", body.Error)
+	}
+	if len(body.Frames) == 0 {
+		t.Fatal("expected frames in response")
+	}
+
+	frame := body.Frames[0]
+	if !strings.HasSuffix(frame.File, "devmode_test.go") {
+		t.Errorf("expected first frame in the panicking handler, got %s", frame.File)
+	}
+	found := false
+	for _, line := range frame.Source {
+		if line.Current && strings.Contains(line.Code, `panic("something exploded")`) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected source snippet with the panicking line, got %+v", frame.Source)
+	}
+}
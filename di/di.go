@@ -0,0 +1,283 @@
+// Package di is a small dependency injection container. Constructors are
+// registered at startup with Provide; each is called once, on first use,
+// with its parameters resolved from the other constructors, and the value
+// it returns is shared from then on.
+//
+// Example:
+//
+//	c := di.New()
+//	c.Provide(func() (*sql.DB, error) { return sql.Open("pgx", os.Getenv("DATABASE_URL")) })
+//	c.Provide(NewUserRepo)      // func(*sql.DB) *UserRepo
+//	c.Provide(NewSignupService) // func(*UserRepo, *Mailer) (*SignupService, error)
+//
+//	if err := c.Validate(); err != nil {
+//	    log.Fatal(err) // a missing provider or a dependency cycle
+//	}
+//	svc, err := di.Resolve[*SignupService](c)
+//
+// Most applications use the container of the helix package through
+// helix.Provide, helix.Resolve and helix.HandleDeps.
+package di
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrNotProvided is returned when a type has no provider.
+var ErrNotProvided = errors.New("helix/di: no provider")
+
+// errorType is the type of the error interface.
+var errorType = reflect.TypeFor[error]()
+
+// Config configures a Container.
+type Config struct {
+	// Fallback, if set, is asked for the types without a provider, such as
+	// to use values registered elsewhere.
+	Fallback func(t reflect.Type) (reflect.Value, bool)
+}
+
+// Container holds constructors and the values they built.
+type Container struct {
+	mu        sync.RWMutex
+	providers map[reflect.Type]*provider
+	fallback  func(t reflect.Type) (reflect.Value, bool)
+}
+
+// provider is a registered constructor.
+type provider struct {
+	fn     reflect.Value
+	params []reflect.Type
+
+	mu    sync.Mutex                    // held while the value is built
+	value atomic.Pointer[reflect.Value] // set once built
+}
+
+// New creates an empty Container.
+func New() *Container {
+	return NewWithConfig(Config{})
+}
+
+// NewWithConfig creates an empty Container with the given configuration.
+func NewWithConfig(config Config) *Container {
+	return &Container{
+		providers: make(map[reflect.Type]*provider),
+		fallback:  config.Fallback,
+	}
+}
+
+// Provide registers constructor, a function returning a value, or a value
+// and an error. The value's type is what it provides: a constructor
+// returning *UserRepo provides *UserRepo, and one returning an interface
+// provides the interface. Its parameters are resolved from the container.
+// Provide panics if constructor is not such a function or if its type is
+// already provided.
+func (c *Container) Provide(constructor any) {
+	fn := reflect.ValueOf(constructor)
+	if fn.Kind() != reflect.Func {
+		panic(fmt.Sprintf("helix/di: constructor must be a function, got %T", constructor))
+	}
+	t := fn.Type()
+	if t.IsVariadic() || t.NumOut() < 1 || t.NumOut() > 2 ||
+		(t.NumOut() == 2 && t.Out(1) != errorType) || fn.IsNil() {
+		panic(fmt.Sprintf("helix/di: constructor must be a function returning a value and an optional error, got %T", constructor))
+	}
+
+	p := &provider{fn: fn}
+	for i := range t.NumIn() {
+		p.params = append(p.params, t.In(i))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.providers[t.Out(0)]; ok {
+		panic("helix/di: " + t.Out(0).String() + " is already provided")
+	}
+	c.providers[t.Out(0)] = p
+}
+
+// Has reports whether t has a provider.
+func (c *Container) Has(t reflect.Type) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.providers[t]
+	return ok
+}
+
+// Value returns the value of type t, building it and its dependencies on
+// first use. A constructor's error is returned, and the constructor is
+// called again on the next use. Concurrent uses build each value once,
+// and values already built are returned without waiting for the
+// constructors of other types. Constructors must not resolve from the
+// container themselves; take the dependencies as parameters instead.
+func (c *Container) Value(t reflect.Type) (reflect.Value, error) {
+	c.mu.RLock()
+	p := c.providers[t]
+	c.mu.RUnlock()
+	if p != nil {
+		if v := p.value.Load(); v != nil {
+			return *v, nil
+		}
+		if err := c.checkCycle(t); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+	return c.resolve(t, nil)
+}
+
+// Validate checks that the dependencies of every constructor are provided
+// and that none depends on itself, without calling any. Call it at
+// startup to fail early.
+func (c *Container) Validate() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var errs []error
+	checked := make(map[reflect.Type]bool)
+	visiting := make(map[reflect.Type]bool)
+	var visit func(t reflect.Type, path []reflect.Type) error
+	visit = func(t reflect.Type, path []reflect.Type) error {
+		p, ok := c.providers[t]
+		if !ok {
+			if c.fallback != nil {
+				if _, ok := c.fallback(t); ok {
+					return nil
+				}
+			}
+			return notProvided(t, path)
+		}
+		if visiting[t] {
+			return cycle(append(path, t))
+		}
+		if checked[t] {
+			return nil
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		for _, param := range p.params {
+			if err := visit(param, append(path, t)); err != nil {
+				return err
+			}
+		}
+		checked[t] = true
+		return nil
+	}
+	for t := range c.providers {
+		if err := visit(t, nil); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkCycle returns the error of a dependency cycle among the unbuilt
+// providers t depends on. Resolving such a cycle from several goroutines
+// would deadlock them on each other's construction, so it is rejected
+// before any is built.
+func (c *Container) checkCycle(t reflect.Type) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	checked := make(map[reflect.Type]bool)
+	var visit func(t reflect.Type, path []reflect.Type) error
+	visit = func(t reflect.Type, path []reflect.Type) error {
+		p, ok := c.providers[t]
+		if !ok || checked[t] || p.value.Load() != nil {
+			return nil
+		}
+		if slices.Contains(path, t) {
+			return cycle(append(path, t))
+		}
+		for _, param := range p.params {
+			if err := visit(param, append(path, t)); err != nil {
+				return err
+			}
+		}
+		checked[t] = true
+		return nil
+	}
+	return visit(t, nil)
+}
+
+// resolve returns the value of t, with path the types depending on it.
+// Each provider is built under its own lock, so constructors of unrelated
+// types run concurrently.
+func (c *Container) resolve(t reflect.Type, path []reflect.Type) (reflect.Value, error) {
+	c.mu.RLock()
+	p, ok := c.providers[t]
+	c.mu.RUnlock()
+	if !ok {
+		if c.fallback != nil {
+			if v, ok := c.fallback(t); ok {
+				return v, nil
+			}
+		}
+		return reflect.Value{}, notProvided(t, path)
+	}
+	if v := p.value.Load(); v != nil {
+		return *v, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if v := p.value.Load(); v != nil {
+		return *v, nil // built while waiting
+	}
+	args := make([]reflect.Value, len(p.params))
+	for i, param := range p.params {
+		v, err := c.resolve(param, append(path, t))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		args[i] = v
+	}
+
+	out := p.fn.Call(args)
+	if len(out) == 2 && !out[1].IsNil() {
+		return reflect.Value{}, fmt.Errorf("helix/di: constructing %s: %w", t, out[1].Interface().(error))
+	}
+	p.value.Store(&out[0])
+	return out[0], nil
+}
+
+// notProvided returns the ErrNotProvided error of t, needed by path.
+func notProvided(t reflect.Type, path []reflect.Type) error {
+	if len(path) == 0 {
+		return fmt.Errorf("%w for %s", ErrNotProvided, t)
+	}
+	return fmt.Errorf("%w for %s, needed by %s", ErrNotProvided, t, path[len(path)-1])
+}
+
+// cycle returns the error of a dependency cycle.
+func cycle(path []reflect.Type) error {
+	names := make([]string, len(path))
+	for i, t := range path {
+		names[i] = t.String()
+	}
+	return errors.New("helix/di: dependency cycle: " + strings.Join(names, " -> "))
+}
+
+// Resolve returns the value of type T from c. See Container.Value.
+func Resolve[T any](c *Container) (T, error) {
+	var zero T
+	v, err := c.Value(reflect.TypeFor[T]())
+	if err != nil {
+		return zero, err
+	}
+	t, _ := v.Interface().(T) // nil interfaces
+	return t, nil
+}
+
+// MustResolve is like Resolve but panics on error.
+func MustResolve[T any](c *Container) T {
+	v, err := Resolve[T](c)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
@@ -0,0 +1,180 @@
+package di_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/helix/di"
+)
+
+type (
+	config  struct{ dsn string }
+	db      struct{ config *config }
+	repo    interface{ DSN() string }
+	sqlRepo struct{ db *db }
+	service struct{ repo repo }
+)
+
+func (r *sqlRepo) DSN() string { return r.db.config.dsn }
+
+func TestContainer(t *testing.T) {
+	c := New()
+	calls := 0
+	c.Provide(func() *config { return &config{dsn: "postgres://"} })
+	c.Provide(func(cfg *config) (*db, error) {
+		calls++
+		return &db{config: cfg}, nil
+	})
+	c.Provide(func(d *db) repo { return &sqlRepo{db: d} })
+	c.Provide(func(r repo) *service { return &service{repo: r} })
+
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	svc, err := Resolve[*service](c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if svc.repo.DSN() != "postgres://" {
+		t.Fatalf("unexpected DSN %q", svc.repo.DSN())
+	}
+	if again := MustResolve[*service](c); again != svc || calls != 1 {
+		t.Fatalf("expected a shared instance built once, got %d calls", calls)
+	}
+
+	if _, err := Resolve[*strings.Builder](c); !errors.Is(err, ErrNotProvided) {
+		t.Fatalf("expected ErrNotProvided, got %v", err)
+	}
+}
+
+func TestContainerConcurrent(t *testing.T) {
+	c := New()
+	var builds atomic.Int32
+	release := make(chan struct{})
+	c.Provide(func() *config { return &config{dsn: "postgres://"} })
+	c.Provide(func(cfg *config) *db {
+		builds.Add(1)
+		<-release
+		return &db{config: cfg}
+	})
+	MustResolve[*config](c)
+
+	// Concurrent uses share one construction
+	var wg sync.WaitGroup
+	dbs := make([]*db, 10)
+	for i := range dbs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dbs[i] = MustResolve[*db](c)
+		}()
+	}
+
+	// Built values are served while another type is being constructed
+	done := make(chan struct{})
+	go func() {
+		MustResolve[*config](c)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected a built value not to wait for another constructor")
+	}
+
+	close(release)
+	wg.Wait()
+	if n := builds.Load(); n != 1 {
+		t.Errorf("expected 1 construction, got %d", n)
+	}
+	for _, d := range dbs {
+		if d != dbs[0] {
+			t.Fatal("expected every use to get the shared instance")
+		}
+	}
+}
+
+func TestContainerErrors(t *testing.T) {
+	t.Run("constructor error", func(t *testing.T) {
+		c := New()
+		fail := true
+		c.Provide(func() (*config, error) {
+			if fail {
+				return nil, errors.New("no config file")
+			}
+			return &config{}, nil
+		})
+		if _, err := Resolve[*config](c); err == nil || !strings.Contains(err.Error(), "no config file") {
+			t.Fatalf("expected constructor error, got %v", err)
+		}
+		fail = false
+		if _, err := Resolve[*config](c); err != nil {
+			t.Fatalf("expected retry to succeed, got %v", err)
+		}
+	})
+
+	t.Run("missing dependency", func(t *testing.T) {
+		c := New()
+		c.Provide(func(cfg *config) *db { return &db{config: cfg} })
+		err := c.Validate()
+		if !errors.Is(err, ErrNotProvided) || !strings.Contains(err.Error(), "needed by *di_test.db") {
+			t.Fatalf("unexpected error %v", err)
+		}
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		c := New()
+		c.Provide(func(*db) *config { return nil })
+		c.Provide(func(*config) *db { return nil })
+		if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "dependency cycle") {
+			t.Fatalf("expected cycle error, got %v", err)
+		}
+		if _, err := Resolve[*db](c); err == nil || !strings.Contains(err.Error(), "*di_test.db -> *di_test.config -> *di_test.db") {
+			t.Fatalf("expected cycle error, got %v", err)
+		}
+	})
+
+	t.Run("invalid constructors", func(t *testing.T) {
+		c := New()
+		c.Provide(func() *config { return nil })
+		for name, ctor := range map[string]any{
+			"value":     &config{},
+			"no result": func() {},
+			"not error": func() (*db, int) { return nil, 0 },
+			"duplicate": func() *config { return nil },
+		} {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Errorf("%s: expected panic", name)
+					}
+				}()
+				c.Provide(ctor)
+			}()
+		}
+	})
+}
+
+func TestContainerFallback(t *testing.T) {
+	cfg := &config{dsn: "mysql://"}
+	c := NewWithConfig(Config{
+		Fallback: func(t reflect.Type) (reflect.Value, bool) {
+			if t == reflect.TypeFor[*config]() {
+				return reflect.ValueOf(cfg), true
+			}
+			return reflect.Value{}, false
+		},
+	})
+	c.Provide(func(cfg *config) *db { return &db{config: cfg} })
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if d := MustResolve[*db](c); d.config != cfg {
+		t.Fatal("expected the fallback value")
+	}
+}
@@ -0,0 +1,71 @@
+package helix
+
+import (
+	"context"
+	"net/http"
+)
+
+// EnvelopeFunc wraps the value returned by a typed handler before it is
+// encoded. It receives the response status code.
+type EnvelopeFunc func(status int, v any) any
+
+// envelopeKey is the context key for the response envelope.
+type envelopeKey struct{}
+
+// envelopeSetting holds the envelope in the request context. A nil fn
+// disables an envelope set further out.
+type envelopeSetting struct {
+	fn EnvelopeFunc
+}
+
+// WithEnvelope returns middleware that sets the response envelope for typed
+// handlers, overriding the server's Options.ResponseEnvelope. A nil fn
+// disables enveloping.
+func WithEnvelope(fn EnvelopeFunc) Middleware {
+	setting := &envelopeSetting{fn: fn}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = r.WithContext(context.WithValue(r.Context(), envelopeKey{}, setting))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ResponseEnvelope sets the response envelope for routes registered on the
+// group after the call, overriding the server's. A nil fn disables
+// enveloping for the group.
+func (g *Group) ResponseEnvelope(fn EnvelopeFunc) {
+	g.Use(WithEnvelope(fn))
+}
+
+// envelopeFrom returns the response envelope for the request, if any.
+func envelopeFrom(r *http.Request) EnvelopeFunc {
+	setting, _ := r.Context().Value(envelopeKey{}).(*envelopeSetting)
+	if setting == nil {
+		return nil
+	}
+	return setting.fn
+}
+
+// Envelope is the standard response envelope produced by DataEnvelope.
+type Envelope struct {
+	Data any `json:"data"`
+	Meta any `json:"meta,omitempty"`
+}
+
+// EnvelopeMeta is implemented by response values that split into data and
+// metadata for DataEnvelope, such as PaginatedResponse.
+type EnvelopeMeta interface {
+	EnvelopeData() (data, meta any)
+}
+
+// DataEnvelope is an EnvelopeFunc that wraps responses in
+// {"data": ..., "meta": ...}. Values implementing EnvelopeMeta provide
+// their own metadata; others are placed in data without meta.
+func DataEnvelope(status int, v any) any {
+	if m, ok := v.(EnvelopeMeta); ok {
+		data, meta := m.EnvelopeData()
+		return Envelope{Data: data, Meta: meta}
+	}
+	return Envelope{Data: v}
+}
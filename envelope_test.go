@@ -0,0 +1,70 @@
+package helix_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/kolosys/helix"
+)
+
+func TestResponseEnvelope(t *testing.T) {
+	type User struct {
+		Name string `json:"name"`
+	}
+
+	s := New(&Options{ResponseEnvelope: DataEnvelope})
+	s.GET("/user", HandleNoRequest(func(ctx context.Context) (User, error) {
+		return User{Name: "ann"}, nil
+	}))
+	s.GET("/users", HandleNoRequest(func(ctx context.Context) (PaginatedResponse[User], error) {
+		return NewPaginatedResponse([]User{{Name: "ann"}}, 3, 1, 1), nil
+	}))
+	s.GET("/raw", HandleNoRequest(func(ctx context.Context) (Result[User], error) {
+		return Result[User]{Value: User{Name: "raw"}, Raw: true}, nil
+	}))
+	s.GET("/missing", HandleNoRequest(func(ctx context.Context) (User, error) {
+		return User{}, ErrNotFound
+	}))
+
+	v2 := s.Group("/v2")
+	v2.ResponseEnvelope(func(status int, v any) any {
+		return map[string]any{"status": status, "result": v}
+	})
+	v2.GET("/user", HandleNoRequest(func(ctx context.Context) (User, error) {
+		return User{Name: "ann"}, nil
+	}))
+
+	plain := s.Group("/plain")
+	plain.ResponseEnvelope(nil)
+	plain.GET("/user", HandleNoRequest(func(ctx context.Context) (User, error) {
+		return User{Name: "ann"}, nil
+	}))
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/user", `{"data":{"name":"ann"}}`},
+		{"/users", `{"data":[{"name":"ann"}],"meta":{"total":3,"page":1,"limit":1,"total_pages":3,"has_more":true,` +
+			`"_links":{"first":{"href":"/users?limit=1&page=1"},"last":{"href":"/users?limit=1&page=3"},"next":{"href":"/users?limit=1&page=2"},"self":{"href":"/users?limit=1&page=1"}}}}`},
+		{"/raw", `{"name":"raw"}`},
+		{"/v2/user", `{"result":{"name":"ann"},"status":200}`},
+		{"/plain/user", `{"name":"ann"}`},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if got := strings.TrimSpace(rec.Body.String()); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.path, tt.want, got)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if rec.Code != http.StatusNotFound || strings.Contains(rec.Body.String(), `"data"`) {
+		t.Errorf("expected errors not to be enveloped, got %d %s", rec.Code, rec.Body.String())
+	}
+}
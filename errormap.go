@@ -0,0 +1,126 @@
+package helix
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ErrorMapper translates an error into a Problem.
+// It returns false if it does not handle the error.
+type ErrorMapper func(err error) (Problem, bool)
+
+var (
+	errorMappersMu sync.RWMutex
+	errorMappers   []ErrorMapper
+)
+
+// RegisterErrorMapper registers a global mapper that translates domain errors
+// returned from handlers into Problems. Mappers run in registration order,
+// after any mappings registered with Server.MapError.
+//
+// Example:
+//
+//	helix.RegisterErrorMapper(func(err error) (helix.Problem, bool) {
+//	    var dup *store.DuplicateError
+//	    if errors.As(err, &dup) {
+//	        return helix.ErrConflict.WithDetailf("%s already exists", dup.Field), true
+//	    }
+//	    return helix.Problem{}, false
+//	})
+func RegisterErrorMapper(mapper ErrorMapper) {
+	if mapper == nil {
+		panic("helix: error mapper must not be nil")
+	}
+
+	errorMappersMu.Lock()
+	errorMappers = append(errorMappers, mapper)
+	errorMappersMu.Unlock()
+}
+
+// MapError maps a domain error to a Problem for this server's handlers.
+// target is either a sentinel error, matched with errors.Is, or a type given
+// as a typed nil pointer such as (*MyError)(nil) or a reflect.Type, matched
+// with errors.As. The original error is kept as the Problem's Err.
+// Must be called before the server starts.
+//
+// Example:
+//
+//	s.MapError(sql.ErrNoRows, helix.ErrNotFound)
+//	s.MapError((*store.DuplicateError)(nil), helix.ErrConflict)
+func (s *Server) MapError(target any, problem Problem) {
+	s.errorConfig.mappers = append(s.errorConfig.mappers, newErrorMapping(target, problem))
+}
+
+// newErrorMapping creates a mapper for a sentinel error or an error type.
+func newErrorMapping(target any, problem Problem) ErrorMapper {
+	var typ reflect.Type
+	switch t := target.(type) {
+	case nil:
+		panic("helix: MapError target must not be nil")
+	case reflect.Type:
+		typ = t
+	default:
+		v := reflect.ValueOf(target)
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			typ = v.Type()
+		} else if sentinel, ok := target.(error); ok {
+			return func(err error) (Problem, bool) {
+				if errors.Is(err, sentinel) {
+					return problem, true
+				}
+				return Problem{}, false
+			}
+		} else {
+			panic(fmt.Sprintf("helix: MapError target must be an error or error type, got %T", target))
+		}
+	}
+
+	errorType := reflect.TypeOf((*error)(nil)).Elem()
+	if !typ.Implements(errorType) {
+		panic(fmt.Sprintf("helix: MapError type %s does not implement error", typ))
+	}
+
+	return func(err error) (Problem, bool) {
+		ptr := reflect.New(typ)
+		if errors.As(err, ptr.Interface()) {
+			return problem, true
+		}
+		return Problem{}, false
+	}
+}
+
+// mapError translates err using the server's mappings and the global mappers.
+// Errors wrapping a Problem or validation errors are returned unchanged.
+func mapError(config *errorConfig, err error) error {
+	var verrs *ValidationErrors
+	if _, ok := ProblemFrom(err); ok || errors.As(err, &verrs) {
+		return err
+	}
+
+	if config != nil {
+		for _, mapper := range config.mappers {
+			if p, ok := mapper(err); ok {
+				return withCause(p, err)
+			}
+		}
+	}
+
+	errorMappersMu.RLock()
+	defer errorMappersMu.RUnlock()
+	for _, mapper := range errorMappers {
+		if p, ok := mapper(err); ok {
+			return withCause(p, err)
+		}
+	}
+	return err
+}
+
+// withCause records err as the Problem's cause unless one is already set.
+func withCause(p Problem, err error) Problem {
+	if p.Err == nil {
+		p.Err = err
+	}
+	return p
+}
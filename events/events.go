@@ -0,0 +1,292 @@
+// Package events provides an in-process, typed publish/subscribe event bus
+// for the Helix framework, so handlers can decouple side effects from the
+// request flow.
+//
+// Topics are Go types: subscribers receive every published value of their
+// type. Subscribers run synchronously by default, so Publish returns their
+// errors, or asynchronously with the Async option.
+//
+// Example:
+//
+//	type UserCreated struct{ ID, Email string }
+//
+//	events.Subscribe(func(ctx context.Context, e UserCreated) error {
+//	    return mailer.SendWelcome(ctx, e.Email)
+//	}, events.Async())
+//
+//	events.Publish(ctx, UserCreated{ID: id, Email: email})
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/kolosys/helix"
+)
+
+// ErrClosed is returned when publishing to a closed bus.
+var ErrClosed = errors.New("helix/events: bus closed")
+
+// ErrNilEvent is returned when publishing a nil event, which has no type to
+// deliver it by.
+var ErrNilEvent = errors.New("helix/events: nil event")
+
+// Next delivers an event to a subscriber.
+type Next func(ctx context.Context, event any) error
+
+// Middleware wraps event delivery, e.g. to log or retry.
+type Middleware func(next Next) Next
+
+// Config configures a Bus.
+type Config struct {
+	// OnError is called when an asynchronous subscriber fails.
+	// Default: logs the error
+	OnError func(topic string, err error)
+}
+
+// DefaultConfig returns the default bus configuration.
+func DefaultConfig() Config {
+	return Config{}
+}
+
+// Option configures a subscription.
+type Option func(*subscription)
+
+// Async delivers events to the subscriber on a separate goroutine.
+// Publish does not wait for it and its errors go to Config.OnError.
+func Async() Option {
+	return func(s *subscription) { s.async = true }
+}
+
+// subscription is a registered subscriber.
+type subscription struct {
+	id    uint64
+	fn    Next
+	async bool
+}
+
+// Bus dispatches events to subscribers by type.
+type Bus struct {
+	config Config
+
+	mu         sync.RWMutex
+	subs       map[reflect.Type][]*subscription
+	middleware []Middleware
+	nextID     uint64
+	closed     bool
+	inflight   sync.WaitGroup
+}
+
+// New creates an event bus.
+func New(config Config) *Bus {
+	if config.OnError == nil {
+		config.OnError = func(topic string, err error) {
+			log.Printf("helix/events: %s subscriber failed: %v", topic, err)
+		}
+	}
+	return &Bus{
+		config: config,
+		subs:   make(map[reflect.Type][]*subscription),
+	}
+}
+
+// Default is the bus used by the package-level functions.
+var Default = New(DefaultConfig())
+
+// Use adds middleware applied to every delivery, in the order added.
+// Must be called before events are published.
+func (b *Bus) Use(mw ...Middleware) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.middleware = append(b.middleware, mw...)
+}
+
+// SubscribeTo registers fn for events of type T on b and returns a function
+// that removes the subscription.
+func SubscribeTo[T any](b *Bus, fn func(ctx context.Context, event T) error, opts ...Option) (unsubscribe func()) {
+	typ := reflect.TypeFor[T]()
+	sub := &subscription{fn: func(ctx context.Context, event any) error {
+		return fn(ctx, event.(T))
+	}}
+	for _, opt := range opts {
+		opt(sub)
+	}
+
+	b.mu.Lock()
+	b.nextID++
+	sub.id = b.nextID
+	b.subs[typ] = append(b.subs[typ], sub)
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		subs := b.subs[typ]
+		for i, s := range subs {
+			if s.id == sub.id {
+				b.subs[typ] = append(subs[:i:i], subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Subscribe registers fn for events of type T on the Default bus.
+func Subscribe[T any](fn func(ctx context.Context, event T) error, opts ...Option) (unsubscribe func()) {
+	return SubscribeTo(Default, fn, opts...)
+}
+
+// Publish delivers event to the subscribers of its type. Synchronous
+// subscribers run in registration order and their errors are joined and
+// returned; asynchronous subscribers run in the background with a context
+// that is not canceled with ctx but keeps its values. Publishing a nil
+// event returns ErrNilEvent.
+func (b *Bus) Publish(ctx context.Context, event any) error {
+	typ := reflect.TypeOf(event)
+	if typ == nil {
+		return ErrNilEvent
+	}
+	topic := Topic(event)
+
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return ErrClosed
+	}
+	subs := b.subs[typ]
+	middleware := b.middleware
+	if len(subs) > 0 {
+		b.inflight.Add(1)
+	}
+	b.mu.RUnlock()
+
+	if len(subs) == 0 {
+		return nil
+	}
+	defer b.inflight.Done()
+
+	var errs []error
+	for _, sub := range subs {
+		next := chain(safe(sub.fn), middleware)
+		if !sub.async {
+			if err := next(ctx, event); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		b.inflight.Add(1)
+		go func() {
+			defer b.inflight.Done()
+			if err := next(context.WithoutCancel(ctx), event); err != nil {
+				b.config.OnError(topic, err)
+			}
+		}()
+	}
+	return errors.Join(errs...)
+}
+
+// Publish delivers event on the Default bus.
+func Publish(ctx context.Context, event any) error {
+	return Default.Publish(ctx, event)
+}
+
+// Close stops accepting events and waits for asynchronous deliveries to
+// finish or ctx to expire.
+func (b *Bus) Close(ctx context.Context) error {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Attach closes b during the server's graceful shutdown, once the in-flight
+// requests are done, waiting for asynchronous deliveries. Shutdown errors
+// are returned from Server.Shutdown.
+func Attach(s *helix.Server, b *Bus) {
+	s.RegisterShutdownWaiter(b.Close)
+}
+
+// Topic returns the topic name of event, its Go type name, or "<nil>" for
+// nil.
+func Topic(event any) string {
+	if event == nil {
+		return "<nil>"
+	}
+	return reflect.TypeOf(event).String()
+}
+
+// chain wraps next with middleware so the first middleware is outermost.
+func chain(next Next, middleware []Middleware) Next {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		next = middleware[i](next)
+	}
+	return next
+}
+
+// safe converts subscriber panics into errors.
+func safe(fn Next) Next {
+	return func(ctx context.Context, event any) (err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				err = fmt.Errorf("helix/events: panic: %v\n%s", rec, debug.Stack())
+			}
+		}()
+		return fn(ctx, event)
+	}
+}
+
+// Logger returns middleware that reports each delivery to fn with the
+// event's topic, duration and error.
+func Logger(fn func(topic string, duration time.Duration, err error)) Middleware {
+	return func(next Next) Next {
+		return func(ctx context.Context, event any) error {
+			start := time.Now()
+			err := next(ctx, event)
+			fn(Topic(event), time.Since(start), err)
+			return err
+		}
+	}
+}
+
+// Retry returns middleware that retries a failed delivery up to attempts
+// times in total, waiting backoff between attempts. It stops early if ctx
+// is canceled.
+func Retry(attempts int, backoff time.Duration) Middleware {
+	return func(next Next) Next {
+		return func(ctx context.Context, event any) error {
+			var err error
+			for i := 0; i < attempts; i++ {
+				if err = next(ctx, event); err == nil {
+					return nil
+				}
+				if i < attempts-1 {
+					select {
+					case <-time.After(backoff):
+					case <-ctx.Done():
+						return err
+					}
+				}
+			}
+			return err
+		}
+	}
+}
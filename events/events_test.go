@@ -0,0 +1,139 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/helix/events"
+)
+
+type userCreated struct{ ID string }
+
+type orderPlaced struct{ Total int }
+
+func TestBus_SyncDelivery(t *testing.T) {
+	b := New(DefaultConfig())
+
+	var got []string
+	SubscribeTo(b, func(ctx context.Context, e userCreated) error {
+		got = append(got, "first "+e.ID)
+		return nil
+	})
+	SubscribeTo(b, func(ctx context.Context, e userCreated) error {
+		got = append(got, "second "+e.ID)
+		return errors.New("mailer down")
+	})
+	SubscribeTo(b, func(ctx context.Context, e orderPlaced) error {
+		t.Error("orderPlaced subscriber received userCreated")
+		return nil
+	})
+
+	err := b.Publish(context.Background(), userCreated{ID: "42"})
+	if err == nil || err.Error() != "mailer down" {
+		t.Errorf("expected subscriber error, got %v", err)
+	}
+	if len(got) != 2 || got[0] != "first 42" || got[1] != "second 42" {
+		t.Errorf("unexpected deliveries %v", got)
+	}
+}
+
+func TestBus_AsyncDeliveryAndClose(t *testing.T) {
+	errs := make(chan error, 1)
+	b := New(Config{OnError: func(topic string, err error) { errs <- err }})
+
+	var delivered atomic.Bool
+	SubscribeTo(b, func(ctx context.Context, e userCreated) error {
+		time.Sleep(10 * time.Millisecond)
+		delivered.Store(true)
+		panic("boom")
+	}, Async())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := b.Publish(ctx, userCreated{}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	cancel()
+
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if !delivered.Load() {
+		t.Error("expected Close to wait for the async subscriber")
+	}
+	if err := <-errs; err == nil {
+		t.Error("expected recovered panic to be reported")
+	}
+	if err := b.Publish(context.Background(), userCreated{}); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestBus_PublishNil(t *testing.T) {
+	b := New(DefaultConfig())
+	if err := b.Publish(context.Background(), nil); !errors.Is(err, ErrNilEvent) {
+		t.Errorf("expected ErrNilEvent, got %v", err)
+	}
+}
+
+func TestBus_Unsubscribe(t *testing.T) {
+	b := New(DefaultConfig())
+	var calls int
+	unsubscribe := SubscribeTo(b, func(ctx context.Context, e userCreated) error {
+		calls++
+		return nil
+	})
+
+	b.Publish(context.Background(), userCreated{})
+	unsubscribe()
+	b.Publish(context.Background(), userCreated{})
+
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestBus_Middleware(t *testing.T) {
+	b := New(DefaultConfig())
+
+	var topics []string
+	b.Use(
+		Logger(func(topic string, d time.Duration, err error) { topics = append(topics, topic) }),
+		Retry(3, time.Millisecond),
+	)
+
+	var attempts int
+	SubscribeTo(b, func(ctx context.Context, e orderPlaced) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("flaky")
+		}
+		return nil
+	})
+
+	if err := b.Publish(context.Background(), orderPlaced{Total: 10}); err != nil {
+		t.Errorf("expected retry to succeed, got %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+	if len(topics) != 1 || topics[0] != "events_test.orderPlaced" {
+		t.Errorf("unexpected logged topics %v", topics)
+	}
+}
+
+func TestDefaultBus(t *testing.T) {
+	var got string
+	unsubscribe := Subscribe(func(ctx context.Context, e userCreated) error {
+		got = e.ID
+		return nil
+	})
+	defer unsubscribe()
+
+	Publish(context.Background(), userCreated{ID: "7"})
+	if got != "7" {
+		t.Errorf("expected 7, got %q", got)
+	}
+}
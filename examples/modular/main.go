@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/kolosys/helix"
+	"github.com/kolosys/helix/di"
 )
 
 // =============================================================================
@@ -155,7 +156,10 @@ type UserModule struct{}
 func (m *UserModule) Register(r helix.RouteRegistrar) {
 	// GET /users - List users with pagination
 	r.GET("/", helix.HandleCtx(func(c *helix.Ctx) error {
-		userSvc := helix.MustGet[*UserService]()
+		userSvc, err := helix.Resolve[*UserService](c)
+		if err != nil {
+			return err
+		}
 
 		// Use built-in pagination binding
 		p := c.BindPagination(20, 100)
@@ -170,11 +174,12 @@ func (m *UserModule) Register(r helix.RouteRegistrar) {
 	}))
 
 	// GET /users/{id} - Get user by ID
-	r.GET("/{id}", helix.Handle(func(ctx context.Context, req struct {
+	r.GET("/{id}", helix.HandleDeps(func(ctx context.Context, req struct {
 		ID int `path:"id"`
+	}, deps struct {
+		Users *UserService
 	}) (User, error) {
-		userSvc := helix.MustGet[*UserService]()
-		return userSvc.Get(ctx, req.ID)
+		return deps.Users.Get(ctx, req.ID)
 	}))
 
 	// POST /users - Create user
@@ -182,13 +187,19 @@ func (m *UserModule) Register(r helix.RouteRegistrar) {
 		Name  string `json:"name"`
 		Email string `json:"email"`
 	}) (User, error) {
-		userSvc := helix.MustGet[*UserService]()
+		userSvc, err := helix.ResolveContext[*UserService](ctx)
+		if err != nil {
+			return User{}, err
+		}
 		return userSvc.Create(ctx, req.Name, req.Email)
 	}))
 
 	// Mount posts sub-module
 	r.Group("/{userId}/posts").GET("/", helix.HandleCtx(func(c *helix.Ctx) error {
-		postSvc := helix.MustGet[*PostService]()
+		postSvc, err := helix.Resolve[*PostService](c)
+		if err != nil {
+			return err
+		}
 
 		userID, err := c.ParamInt("userId")
 		if err != nil {
@@ -237,13 +248,14 @@ func setupHealthChecks(userSvc *UserService) *helix.HealthBuilder {
 // =============================================================================
 
 func main() {
-	// Create services
-	userSvc := NewUserService()
-	postSvc := NewPostService()
-
-	// Register services in global registry (dependency injection)
-	helix.Register(userSvc)
-	helix.Register(postSvc)
+	// Register service constructors (dependency injection); each is built
+	// once, on first use
+	helix.Provide(NewUserService)
+	helix.Provide(NewPostService)
+	if err := helix.Container().Validate(); err != nil {
+		log.Fatal(err)
+	}
+	userSvc := di.MustResolve[*UserService](helix.Container())
 
 	// Create server
 	s := helix.Default(&helix.Options{
@@ -1,5 +1,10 @@
 package helix
 
+import (
+	"net"
+	"net/http"
+)
+
 // Export unexported symbols for testing.
 
 // NewRouter exports newRouter for testing.
@@ -50,3 +55,27 @@ func (s *Server) GetConfig() ServerConfig {
 		MaxHeaderBytes: s.maxHeaderBytes,
 	}
 }
+
+// NewHTTPServer exports newHTTPServer for testing.
+func (s *Server) NewHTTPServer() *http.Server {
+	return s.newHTTPServer()
+}
+
+// UpgradeEnvKey exports upgradeEnvKey for testing.
+const UpgradeEnvKey = upgradeEnvKey
+
+// UpgradeReadyEnvKey exports upgradeReadyEnvKey for testing.
+const UpgradeReadyEnvKey = upgradeReadyEnvKey
+
+// NewLimitListener exports newLimitListener for testing.
+func NewLimitListener(ln net.Listener, max, maxPerIP int, respond bool) (net.Listener, func() ConnStats) {
+	l := newLimitListener(ln, max, maxPerIP, respond)
+	return l, l.stats
+}
+
+// ResetCodecs removes the codecs registered with RegisterCodec.
+func ResetCodecs() {
+	codecsMu.Lock()
+	codecs = nil
+	codecsMu.Unlock()
+}
@@ -0,0 +1,6 @@
+//go:build !windows
+
+package helix
+
+// AwaitUpgradeReady exports awaitUpgradeReady for testing.
+var AwaitUpgradeReady = awaitUpgradeReady
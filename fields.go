@@ -0,0 +1,195 @@
+package helix
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// FieldSet is a sparse fieldset: the fields selected from a response
+// object, each mapped to the subfields selected from it. A nil FieldSet
+// selects a whole value.
+type FieldSet map[string]FieldSet
+
+// ParseFields parses a comma-separated field selection such as
+// "id,name,author.name", where dots select fields of nested objects.
+// Selecting a field whole, as "author", overrides selections within it.
+// Returns nil for an empty selection.
+func ParseFields(s string) FieldSet {
+	var fs FieldSet
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if fs == nil {
+			fs = FieldSet{}
+		}
+
+		node := fs
+		parts := strings.Split(field, ".")
+		for i, part := range parts {
+			child, seen := node[part]
+			if seen && child == nil {
+				break // already selected whole
+			}
+			if i == len(parts)-1 {
+				node[part] = nil
+				break
+			}
+			if child == nil {
+				child = FieldSet{}
+				node[part] = child
+			}
+			node = child
+		}
+	}
+	return fs
+}
+
+// FieldsFrom returns the selection in the request's "fields" query
+// parameter, or nil if it has none.
+func FieldsFrom(r *http.Request) FieldSet {
+	return ParseFields(r.URL.Query().Get("fields"))
+}
+
+// Has reports whether the dotted path, such as "author.name", is selected.
+// A nil FieldSet selects every path. Handlers can use it to skip loading
+// data the client did not ask for.
+func (fs FieldSet) Has(path string) bool {
+	node := fs
+	for _, part := range strings.Split(path, ".") {
+		if node == nil {
+			return true
+		}
+		child, ok := node[part]
+		if !ok {
+			return false
+		}
+		node = child
+	}
+	return true
+}
+
+// Filter returns v, encoded as JSON, with objects pruned to the selected
+// fields. Selections apply to each element of arrays, so "id,name" selects
+// from every item of a list. A nil FieldSet returns v unchanged.
+func (fs FieldSet) Filter(v any) (any, error) {
+	if fs == nil {
+		return v, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	generic, err := decodeJSONValue(b)
+	if err != nil {
+		return nil, err
+	}
+	return fs.prune(generic), nil
+}
+
+// prune removes the unselected members of the generic JSON value v.
+func (fs FieldSet) prune(v any) any {
+	if fs == nil {
+		return v
+	}
+	switch n := v.(type) {
+	case map[string]any:
+		for key, child := range n {
+			sub, ok := fs[key]
+			if !ok {
+				delete(n, key)
+				continue
+			}
+			n[key] = sub.prune(child)
+		}
+		return n
+	case []any:
+		for i, child := range n {
+			n[i] = fs.prune(child)
+		}
+		return n
+	}
+	return v
+}
+
+// sparseFieldser is implemented by collection responses, whose items rather
+// than the collection itself are pruned by a field selection.
+type sparseFieldser interface {
+	sparseFields(fs FieldSet) (any, error)
+}
+
+// sparseFieldsKey is the context key enabling sparse fieldsets.
+type sparseFieldsKey struct{}
+
+// WithSparseFields returns middleware that makes typed handlers honor the
+// "fields" query parameter of requests, pruning their JSON responses to the
+// selected fields. It is opt-in, as existing endpoints may use a "fields"
+// parameter of their own.
+//
+// Example:
+//
+//	api := s.Group("/api", helix.WithSparseFields())
+func WithSparseFields() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = r.WithContext(context.WithValue(r.Context(), sparseFieldsKey{}, true))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// sparseFieldsFrom returns the field selection applied to the typed
+// handler response of r, or nil if sparse fieldsets are not enabled.
+func sparseFieldsFrom(r *http.Request) FieldSet {
+	if on, _ := r.Context().Value(sparseFieldsKey{}).(bool); !on {
+		return nil
+	}
+	return FieldsFrom(r)
+}
+
+// applyFields prunes a typed handler's response to the selection. Values
+// a registered SelectiveCodec handles, such as protocol buffer messages,
+// are left as is so that the codec can still encode them.
+func applyFields(res any, fs FieldSet) (any, error) {
+	for _, c := range registeredCodecs() {
+		if s, ok := c.(SelectiveCodec); ok && s.Handles(res) {
+			return res, nil
+		}
+	}
+	if s, ok := res.(sparseFieldser); ok {
+		return s.sparseFields(fs)
+	}
+	return fs.Filter(res)
+}
+
+// sparseFields implements sparseFieldser.
+func (p PaginatedResponse[T]) sparseFields(fs FieldSet) (any, error) {
+	items, err := fs.Filter(p.Items)
+	if err != nil {
+		return nil, err
+	}
+	list, _ := items.([]any)
+	return PaginatedResponse[any]{
+		Items:      list,
+		Total:      p.Total,
+		Page:       p.Page,
+		Limit:      p.Limit,
+		TotalPages: p.TotalPages,
+		HasMore:    p.HasMore,
+		NextCursor: p.NextCursor,
+		Links:      p.Links,
+	}, nil
+}
+
+// sparseFields implements sparseFieldser.
+func (l ListResponse[Entity]) sparseFields(fs FieldSet) (any, error) {
+	items, err := fs.Filter(l.Items)
+	if err != nil {
+		return nil, err
+	}
+	list, _ := items.([]any)
+	return ListResponse[any]{Items: list, Total: l.Total, Page: l.Page, Limit: l.Limit, Links: l.Links}, nil
+}
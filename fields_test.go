@@ -0,0 +1,90 @@
+package helix_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/kolosys/helix"
+)
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		in   string
+		want FieldSet
+	}{
+		{"", nil},
+		{"id,name", FieldSet{"id": nil, "name": nil}},
+		{"id, author.name,author.id", FieldSet{"id": nil, "author": {"name": nil, "id": nil}}},
+		{"author.name,author", FieldSet{"author": nil}},
+		{"author,author.name", FieldSet{"author": nil}},
+	}
+	for _, tt := range tests {
+		if got := ParseFields(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseFields(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	fs := ParseFields("id,author.name")
+	if !fs.Has("author.name") || fs.Has("author.email") || fs.Has("title") {
+		t.Errorf("unexpected Has results for %v", fs)
+	}
+}
+
+type fieldsAuthor struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type fieldsPost struct {
+	ID     int          `json:"id"`
+	Title  string       `json:"title"`
+	Author fieldsAuthor `json:"author"`
+}
+
+func TestSparseFieldsets(t *testing.T) {
+	post := fieldsPost{ID: 1, Title: "Hello", Author: fieldsAuthor{Name: "Ann", Email: "ann@example.com"}}
+
+	s := New(nil)
+	s.Use(WithSparseFields())
+	s.GET("/posts/{id}", HandleNoRequest(func(ctx context.Context) (fieldsPost, error) {
+		return post, nil
+	}))
+	s.GET("/posts", HandleNoRequest(func(ctx context.Context) (PaginatedResponse[fieldsPost], error) {
+		return NewPaginatedResponse([]fieldsPost{post}, 1, 1, 10), nil
+	}))
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"/posts/1?fields=id,author.name", `{"author":{"name":"Ann"},"id":1}`},
+		{"/posts/1", `{"id":1,"title":"Hello","author":{"name":"Ann","email":"ann@example.com"}}`},
+		{"/posts?fields=title", `{"items":[{"title":"Hello"}],"total":1,"page":1,"limit":10,"total_pages":1,"has_more":false,` +
+			`"_links":{"first":{"href":"/posts?fields=title&limit=10&page=1"},"last":{"href":"/posts?fields=title&limit=10&page=1"},"self":{"href":"/posts?fields=title&limit=10&page=1"}}}`},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if got := strings.TrimSpace(rec.Body.String()); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.target, tt.want, got)
+		}
+	}
+}
+
+func TestSparseFieldsetsOptIn(t *testing.T) {
+	post := fieldsPost{ID: 1, Title: "Hello"}
+	s := New(nil)
+	s.GET("/search", HandleNoRequest(func(ctx context.Context) (fieldsPost, error) {
+		return post, nil
+	}))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?fields=title", nil))
+	if got := strings.TrimSpace(rec.Body.String()); got != `{"id":1,"title":"Hello","author":{"name":"","email":""}}` {
+		t.Errorf("expected no pruning without WithSparseFields, got %s", got)
+	}
+}
@@ -0,0 +1,417 @@
+// Package flags evaluates feature flags for the user or tenant of each
+// request, for gradual rollouts of route behavior. Flag rules come from a
+// Provider: a static set of Rules, a JSON file, the environment or a remote
+// service.
+//
+// Example:
+//
+//	rules, err := flags.LoadFile("flags.json")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	s.Use(flags.Middleware(rules))
+//
+//	s.GET("/search", helix.HandleCtx(func(c *helix.Ctx) error {
+//	    if c.FeatureEnabled("new-search") {
+//	        return searchV2(c)
+//	    }
+//	    return searchV1(c)
+//	}))
+//
+// with flags.json:
+//
+//	{
+//	    "new-search": {"percent": 10, "tenants": ["acme"]},
+//	    "dark-mode": true
+//	}
+package flags
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kolosys/helix/logs"
+	"github.com/kolosys/helix/middleware"
+)
+
+// Subject is who flags are evaluated for.
+type Subject struct {
+	// ID identifies the user. Percentage rollouts are sticky by ID.
+	ID string
+
+	// Tenant identifies the user's organization. Percentage rollouts are
+	// sticky by tenant for requests without an ID.
+	Tenant string
+
+	// Roles are the user's roles.
+	Roles []string
+}
+
+// Provider returns the flag state of subjects.
+type Provider interface {
+	// Flags returns the flags enabled for subject. Flags missing from the
+	// result are disabled.
+	Flags(ctx context.Context, subject Subject) (map[string]bool, error)
+}
+
+// ProviderFunc adapts a function to a Provider, such as to query a remote
+// flag service that evaluates flags itself.
+type ProviderFunc func(ctx context.Context, subject Subject) (map[string]bool, error)
+
+// Flags implements Provider.
+func (f ProviderFunc) Flags(ctx context.Context, subject Subject) (map[string]bool, error) {
+	return f(ctx, subject)
+}
+
+// Flag is the rule of a feature flag. A flag is enabled for a subject if
+// any of its conditions holds. In JSON, true and false are shorthands for
+// {"enabled": true} and {"enabled": false}.
+type Flag struct {
+	// Enabled turns the flag on for everyone.
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Percent turns the flag on for a stable share of subjects, from 0 to
+	// 100, bucketed by the flag name and the subject's ID or tenant.
+	Percent int `json:"percent,omitempty"`
+
+	// Users, Tenants and Roles turn the flag on for the subjects with one
+	// of these IDs, tenants or roles.
+	Users   []string `json:"users,omitempty"`
+	Tenants []string `json:"tenants,omitempty"`
+	Roles   []string `json:"roles,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting a boolean.
+func (f *Flag) UnmarshalJSON(data []byte) error {
+	var enabled bool
+	if err := json.Unmarshal(data, &enabled); err == nil {
+		*f = Flag{Enabled: enabled}
+		return nil
+	}
+	type flag Flag
+	return json.Unmarshal(data, (*flag)(f))
+}
+
+// On reports whether the flag named name is enabled for subject.
+func (f Flag) On(name string, subject Subject) bool {
+	if f.Enabled ||
+		(subject.ID != "" && slices.Contains(f.Users, subject.ID)) ||
+		(subject.Tenant != "" && slices.Contains(f.Tenants, subject.Tenant)) ||
+		slices.ContainsFunc(subject.Roles, func(role string) bool { return slices.Contains(f.Roles, role) }) {
+		return true
+	}
+	if f.Percent <= 0 {
+		return false
+	}
+	key := subject.ID
+	if key == "" {
+		key = subject.Tenant
+	}
+	if key == "" {
+		return false
+	}
+	return bucket(name, key) < f.Percent
+}
+
+// bucket returns the rollout bucket, from 0 to 99, of key for a flag.
+func bucket(name, key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	return int(h.Sum32() % 100)
+}
+
+// Rules is a Provider of flags by name.
+type Rules map[string]Flag
+
+// Flags implements Provider.
+func (rs Rules) Flags(_ context.Context, subject Subject) (map[string]bool, error) {
+	enabled := make(map[string]bool)
+	for name, f := range rs {
+		if f.On(name, subject) {
+			enabled[name] = true
+		}
+	}
+	return enabled, nil
+}
+
+// LoadFile reads Rules from a JSON file of flags by name.
+func LoadFile(path string) (Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("helix/flags: %w", err)
+	}
+	var rules Rules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("helix/flags: %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// FromEnv returns Rules from the environment variables starting with
+// prefix, such as FLAG_NEW_SEARCH=true for the "new-search" flag with the
+// prefix "FLAG_". Values are booleans, or percentages such as "25%".
+// Variables with other values are ignored.
+func FromEnv(prefix string) Rules {
+	rules := make(Rules)
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		name, ok := strings.CutPrefix(key, prefix)
+		if !ok || name == "" {
+			continue
+		}
+		name = strings.ReplaceAll(strings.ToLower(name), "_", "-")
+		if pct, ok := strings.CutSuffix(value, "%"); ok {
+			if n, err := strconv.Atoi(pct); err == nil {
+				rules[name] = Flag{Percent: n}
+			}
+		} else if enabled, err := strconv.ParseBool(value); err == nil {
+			rules[name] = Flag{Enabled: enabled}
+		}
+	}
+	return rules
+}
+
+// RemoteConfig configures a Remote provider.
+type RemoteConfig struct {
+	// URL serves the rules as a JSON document of flags by name, in the
+	// format of LoadFile. Required.
+	URL string
+
+	// Interval is how long fetched rules are used before they are fetched
+	// again. Until a fetch succeeds, the last rules are kept.
+	// Default: 30 seconds
+	Interval time.Duration
+
+	// Client fetches the rules.
+	// Default: a client with a 10 second timeout
+	Client *http.Client
+}
+
+// Remote is a Provider of rules fetched from a URL and refreshed
+// periodically, such as from a flag service or object storage.
+type Remote struct {
+	config RemoteConfig
+
+	mu       sync.Mutex
+	rules    Rules
+	loaded   bool
+	err      error         // the last fetch error, reported until loaded
+	next     time.Time     // when the rules are fetched again
+	backoff  time.Duration // the wait after the last failed first fetch
+	fetching chan struct{} // closed when the fetch in flight completes
+}
+
+// NewRemote returns a Remote provider. Rules are fetched on first use.
+func NewRemote(config RemoteConfig) *Remote {
+	if config.URL == "" {
+		panic("helix/flags: remote URL is required")
+	}
+	if config.Interval <= 0 {
+		config.Interval = 30 * time.Second
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Remote{config: config}
+}
+
+// Flags implements Provider. An error is returned until the rules are
+// fetched once, so the Middleware treats every flag as disabled; failed
+// first fetches are retried with a backoff of up to the Interval rather
+// than on every request.
+func (p *Remote) Flags(ctx context.Context, subject Subject) (map[string]bool, error) {
+	rules, err := p.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return rules.Flags(ctx, subject)
+}
+
+// current returns the rules, starting a fetch in the background when they
+// are due. Stale rules are served while refreshed; until the rules are
+// first loaded, callers wait for the fetch in flight, up to their ctx.
+func (p *Remote) current(ctx context.Context) (Rules, error) {
+	p.mu.Lock()
+	if p.fetching == nil && !time.Now().Before(p.next) {
+		p.fetching = make(chan struct{})
+		go p.refresh(logs.FromContext(ctx), p.fetching)
+	}
+	if p.loaded {
+		defer p.mu.Unlock()
+		return p.rules, nil
+	}
+	done := p.fetching
+	p.mu.Unlock()
+
+	if done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.loaded {
+		return nil, fmt.Errorf("helix/flags: rules not loaded: %w", p.err)
+	}
+	return p.rules, nil
+}
+
+// refresh fetches the rules, detached from the requests waiting for them.
+// On failure, loaded rules are kept until the next interval; before the
+// first success, the fetch is retried with a doubling backoff.
+func (p *Remote) refresh(logger *logs.Logger, done chan struct{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), cmp.Or(p.config.Client.Timeout, 10*time.Second))
+	defer cancel()
+	rules, err := p.fetch(ctx)
+	if err != nil {
+		logger.Warn("helix/flags: failed to fetch rules", logs.Fields{"url": p.config.URL, "error": err})
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	wait := p.config.Interval
+	switch {
+	case err == nil:
+		p.rules, p.loaded, p.err, p.backoff = rules, true, nil, 0
+	case !p.loaded:
+		p.err = err
+		p.backoff = min(max(2*p.backoff, time.Second), p.config.Interval)
+		wait = p.backoff
+	}
+	p.next = time.Now().Add(wait)
+	p.fetching = nil
+	close(done)
+}
+
+// fetch gets the rules from the URL.
+func (p *Remote) fetch(ctx context.Context) (Rules, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("helix/flags: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.config.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("helix/flags: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("helix/flags: fetching rules: %s", resp.Status)
+	}
+	var rules Rules
+	if err := json.NewDecoder(resp.Body).Decode(&rules); err != nil {
+		return nil, fmt.Errorf("helix/flags: decoding rules: %w", err)
+	}
+	return rules, nil
+}
+
+// Config configures the Middleware.
+type Config struct {
+	// Provider returns the flags of the request's subject. Required.
+	Provider Provider
+
+	// Subject returns who the flags of a request are evaluated for. It is
+	// called on the first flag check of the request, so the principal set
+	// by authentication middleware running later is seen.
+	// Default: SubjectFromPrincipal
+	Subject func(ctx context.Context) Subject
+}
+
+// SubjectFromPrincipal returns the subject of the request's principal (see
+// middleware.PrincipalFrom), with the tenant from its "tenant" attribute.
+func SubjectFromPrincipal(ctx context.Context) Subject {
+	p := middleware.PrincipalFrom(ctx)
+	if p == nil {
+		return Subject{}
+	}
+	return Subject{ID: p.ID, Tenant: p.Attr("tenant"), Roles: p.Roles}
+}
+
+// state holds the flags of a request, loaded on first use.
+type state struct {
+	config  Config
+	once    sync.Once
+	enabled map[string]bool
+}
+
+// load evaluates the flags once, with the context of the first check.
+func (s *state) load(ctx context.Context) map[string]bool {
+	s.once.Do(func() {
+		enabled, err := s.config.Provider.Flags(ctx, s.config.Subject(ctx))
+		if err != nil {
+			logs.FromContext(ctx).Error("helix/flags: failed to load flags", logs.Fields{"error": err})
+		}
+		s.enabled = enabled
+	})
+	return s.enabled
+}
+
+// stateKey is the context key of the request's flags.
+type stateKey struct{}
+
+// Middleware returns a middleware that makes the flags of each request's
+// subject available to Enabled and Ctx.FeatureEnabled. See
+// MiddlewareWithConfig.
+func Middleware(p Provider) func(http.Handler) http.Handler {
+	return MiddlewareWithConfig(Config{Provider: p})
+}
+
+// MiddlewareWithConfig returns a flags middleware with the given
+// configuration. Flags are loaded from the provider once per request, on
+// the first check; if the provider fails, the error is logged and every
+// flag is disabled for the request.
+func MiddlewareWithConfig(config Config) func(http.Handler) http.Handler {
+	if config.Provider == nil {
+		panic("helix/flags: provider is required")
+	}
+	if config.Subject == nil {
+		config.Subject = SubjectFromPrincipal
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), stateKey{}, &state{config: config})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Enabled reports whether the flag named name is enabled for the request
+// of ctx. It is false when no Middleware is installed.
+func Enabled(ctx context.Context, name string) bool {
+	s, ok := ctx.Value(stateKey{}).(*state)
+	return ok && s.load(ctx)[name]
+}
+
+// Require returns a middleware that answers 404 Not Found to requests for
+// which the flag named name is disabled, for routes that are only rolled
+// out to some users.
+//
+// Example:
+//
+//	s.GET("/search/v2", searchV2, helix.WithRouteMiddleware(flags.Require("new-search")))
+func Require(name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !Enabled(r.Context(), name) {
+				http.NotFound(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package flags_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/helix/flags"
+	"github.com/kolosys/helix/middleware"
+)
+
+func TestFlagOn(t *testing.T) {
+	f := Flag{Users: []string{"u1"}, Tenants: []string{"acme"}, Roles: []string{"staff"}}
+	tests := []struct {
+		subject Subject
+		want    bool
+	}{
+		{Subject{ID: "u1"}, true},
+		{Subject{ID: "u2", Tenant: "acme"}, true},
+		{Subject{ID: "u3", Roles: []string{"staff"}}, true},
+		{Subject{ID: "u4", Tenant: "globex"}, false},
+		{Subject{}, false},
+	}
+	for _, tt := range tests {
+		if got := f.On("beta", tt.subject); got != tt.want {
+			t.Errorf("%+v: expected %v, got %v", tt.subject, tt.want, got)
+		}
+	}
+
+	// Percentage rollouts are sticky and close to the requested share
+	rollout := Flag{Percent: 25}
+	on := 0
+	for i := range 10000 {
+		id := fmt.Sprintf("user-%d", i)
+		got := rollout.On("new-search", Subject{ID: id})
+		if got != rollout.On("new-search", Subject{ID: id}) {
+			t.Fatal("expected sticky rollout")
+		}
+		if got {
+			on++
+		}
+	}
+	if on < 2200 || on > 2800 {
+		t.Errorf("expected about 2500 of 10000 subjects, got %d", on)
+	}
+	if (Flag{Percent: 100}).On("x", Subject{}) {
+		t.Error("expected anonymous subjects to be left out of rollouts")
+	}
+}
+
+func TestProviders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.json")
+	os.WriteFile(path, []byte(`{"dark-mode": true, "beta": {"tenants": ["acme"]}, "off": false}`), 0o644)
+	rules, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := rules.Flags(context.Background(), Subject{Tenant: "acme"})
+	if !got["dark-mode"] || !got["beta"] || got["off"] {
+		t.Errorf("unexpected flags %v", got)
+	}
+
+	t.Setenv("FLAG_NEW_SEARCH", "true")
+	t.Setenv("FLAG_CHECKOUT_V2", "100%")
+	t.Setenv("FLAG_BROKEN", "maybe")
+	env := FromEnv("FLAG_")
+	if !env["new-search"].Enabled || env["checkout-v2"].Percent != 100 {
+		t.Errorf("unexpected env rules %+v", env)
+	}
+	if _, ok := env["broken"]; ok {
+		t.Error("expected invalid values to be ignored")
+	}
+}
+
+func TestRemote(t *testing.T) {
+	var fetches atomic.Int32
+	var fail atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if fail.Load() {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"beta": fetches.Load() > 1})
+	}))
+	defer srv.Close()
+
+	p := NewRemote(RemoteConfig{URL: srv.URL, Interval: 10 * time.Millisecond})
+	got, err := p.Flags(context.Background(), Subject{})
+	if err != nil || got["beta"] {
+		t.Fatalf("unexpected first fetch %v %v", got, err)
+	}
+
+	// Stale rules are served while refreshed in the background
+	time.Sleep(20 * time.Millisecond)
+	p.Flags(context.Background(), Subject{})
+	deadline := time.Now().Add(time.Second)
+	for !got["beta"] && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		got, _ = p.Flags(context.Background(), Subject{})
+	}
+	if !got["beta"] {
+		t.Fatal("expected refreshed rules")
+	}
+
+	// Failed refreshes keep the last rules
+	fail.Store(true)
+	time.Sleep(20 * time.Millisecond)
+	for range 5 {
+		got, err = p.Flags(context.Background(), Subject{})
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err != nil || !got["beta"] {
+		t.Fatalf("expected last rules, got %v %v", got, err)
+	}
+
+	if _, err := NewRemote(RemoteConfig{URL: srv.URL}).Flags(context.Background(), Subject{}); err == nil {
+		t.Fatal("expected error without rules")
+	}
+}
+
+func TestRemoteFirstFetch(t *testing.T) {
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) == 1 {
+			<-release
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"beta": true})
+	}))
+	defer srv.Close()
+	p := NewRemote(RemoteConfig{URL: srv.URL, Interval: time.Minute})
+
+	// A caller giving up does not fail the fetch shared with the others
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.Flags(ctx, Subject{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the canceled caller to stop waiting, got %v", err)
+	}
+	errs := make(chan error, 10)
+	for range 10 {
+		go func() {
+			_, err := p.Flags(context.Background(), Subject{})
+			errs <- err
+		}()
+	}
+	close(release)
+	for range 10 {
+		if err := <-errs; err == nil {
+			t.Fatal("expected an error before the rules are loaded")
+		}
+	}
+
+	// Failed first fetches are retried after a backoff, not per request
+	for range 10 {
+		if _, err := p.Flags(context.Background(), Subject{}); err == nil {
+			t.Fatal("expected an error during the backoff")
+		}
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Fatalf("expected 1 fetch, got %d", n)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	if got, err := p.Flags(context.Background(), Subject{}); err != nil || !got["beta"] {
+		t.Fatalf("expected the retried fetch to load the rules, got %v %v", got, err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var loads atomic.Int32
+	provider := ProviderFunc(func(ctx context.Context, s Subject) (map[string]bool, error) {
+		loads.Add(1)
+		if s.ID == "broken" {
+			return nil, errors.New("flag service down")
+		}
+		return map[string]bool{"new-search": s.Tenant == "acme"}, nil
+	})
+
+	// Authentication runs after the flags middleware, on the route
+	handler := Middleware(provider)(middleware.APIKey(map[string]*middleware.Principal{
+		"acme-key":   {ID: "u1", Attrs: map[string]any{"tenant": "acme"}},
+		"globex-key": {ID: "u2", Attrs: map[string]any{"tenant": "globex"}},
+		"broken-key": {ID: "broken"},
+	})(Require("new-search")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !Enabled(r.Context(), "new-search") || Enabled(r.Context(), "other") {
+			t.Error("unexpected flags")
+		}
+	}))))
+
+	for key, want := range map[string]int{"acme-key": http.StatusOK, "globex-key": http.StatusNotFound, "broken-key": http.StatusNotFound} {
+		req := httptest.NewRequest(http.MethodGet, "/search", nil)
+		req.Header.Set("X-API-Key", key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("%s: expected %d, got %d", key, want, rec.Code)
+		}
+	}
+	if loads.Load() != 3 {
+		t.Errorf("expected one load per request, got %d", loads.Load())
+	}
+	if Enabled(context.Background(), "new-search") {
+		t.Error("expected flags to be off without the middleware")
+	}
+}
@@ -0,0 +1,120 @@
+package helix
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// flashCookie is the name of the cookie carrying flash messages.
+const flashCookie = "helix_flash"
+
+// Flash is a one-time message shown on the next page a user visits, such as
+// "Profile saved" after a form post redirects.
+type Flash struct {
+	// Kind classifies the message, such as "success" or "error".
+	Kind string `json:"kind"`
+
+	// Message is the text to show.
+	Message string `json:"message"`
+}
+
+// maxFlashSize bounds the flash cookie value, under the 4KB that browsers
+// keep of a cookie.
+const maxFlashSize = 4000
+
+// Flash queues a message for the next request that calls Flashes, typically
+// the page a form post redirects to (Post/Redirect/Get). Messages are kept in
+// a cookie signed with Options.CookieSecret, so they survive the redirect and
+// can't be forged. Messages queued by earlier requests that were not read
+// yet are kept; when the cookie would grow past about 4KB, the oldest
+// messages are dropped. Call Flash before the response is written.
+// Panics if no secret is configured.
+//
+// Example:
+//
+//	s.POST("/profile", helix.HandleCtx(func(c *helix.Ctx) error {
+//	    // ... save the profile
+//	    c.Flash("success", "Profile saved")
+//	    c.Redirect("/profile", http.StatusSeeOther)
+//	    return nil
+//	}))
+func (c *Ctx) Flash(kind, message string) *Ctx {
+	secret := c.cookieSecret()
+	c.flashes = append(c.flashes, Flash{Kind: kind, Message: message})
+	pending := c.flashes
+	if !c.flashesRead {
+		pending = append(c.incomingFlashes(secret), pending...)
+	}
+	return c.writeFlashes(secret, pending)
+}
+
+// Flashes returns the messages queued by Flash on earlier requests and
+// clears them, so each message is shown once. Messages with an invalid
+// signature are discarded. Panics if no secret is configured.
+func (c *Ctx) Flashes() []Flash {
+	secret := c.cookieSecret()
+	if _, err := c.Request.Cookie(flashCookie); err != nil {
+		return nil
+	}
+	c.flashesRead = true
+	// Messages queued by this request are kept for the next one
+	c.writeFlashes(secret, c.flashes)
+	return c.incomingFlashes(secret)
+}
+
+// incomingFlashes returns the messages of the request's flash cookie.
+func (c *Ctx) incomingFlashes(secret []byte) []Flash {
+	cookie, err := c.Request.Cookie(flashCookie)
+	if err != nil {
+		return nil
+	}
+	value, err := verifyCookie(secret, flashCookie, cookie.Value)
+	if err != nil {
+		return nil
+	}
+	var flashes []Flash
+	if json.Unmarshal([]byte(value), &flashes) != nil {
+		return nil
+	}
+	return flashes
+}
+
+// writeFlashes sets the flash cookie to flashes, dropping the oldest ones
+// until it fits maxFlashSize, or clears it when none are left.
+func (c *Ctx) writeFlashes(secret []byte, flashes []Flash) *Ctx {
+	for len(flashes) > 0 {
+		b, _ := json.Marshal(flashes)
+		if value := signCookie(secret, flashCookie, string(b)); len(value) <= maxFlashSize {
+			return c.setFlashCookie(&http.Cookie{
+				Name:     flashCookie,
+				Value:    value,
+				Path:     "/",
+				HttpOnly: true,
+				Secure:   c.Request.TLS != nil,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+		flashes = flashes[1:]
+	}
+	return c.setFlashCookie(&http.Cookie{Name: flashCookie, Path: "/", MaxAge: -1})
+}
+
+// setFlashCookie sets cookie, replacing any flash cookie set earlier in the
+// request so only the latest state reaches the client.
+func (c *Ctx) setFlashCookie(cookie *http.Cookie) *Ctx {
+	h := c.Response.Header()
+	cookies := h["Set-Cookie"]
+	kept := cookies[:0]
+	for _, v := range cookies {
+		if !strings.HasPrefix(v, flashCookie+"=") {
+			kept = append(kept, v)
+		}
+	}
+	if len(kept) == 0 {
+		h.Del("Set-Cookie")
+	} else {
+		h["Set-Cookie"] = kept
+	}
+	return c.SetCookie(cookie)
+}
@@ -0,0 +1,147 @@
+// Package form binds and validates HTML form posts and re-renders the form
+// with field errors and the submitted values when validation fails.
+//
+// Example:
+//
+//	var signupPage = template.Must(template.New("signup").Parse(`
+//	<form method="post">
+//	  <input name="email" value="{{.Form.Value "email"}}">
+//	  {{with .Form.Error "email"}}<p class="error">{{.}}</p>{{end}}
+//	  <button>Sign up</button>
+//	</form>`))
+//
+//	s.GET("/signup", helix.HandleCtx(func(c *helix.Ctx) error {
+//	    return form.Render(c.Response, signupPage, "signup", form.New(), nil)
+//	}))
+//
+//	s.POST("/signup", helix.HandleCtx(func(c *helix.Ctx) error {
+//	    input, f, err := form.Bind[SignupInput](c.Request)
+//	    if f.HasErrors() {
+//	        return form.Render(c.Response, signupPage, "signup", f, nil)
+//	    }
+//	    if err != nil {
+//	        return err
+//	    }
+//	    // ... create the account
+//	    c.Redirect("/welcome", http.StatusSeeOther)
+//	    return nil
+//	}))
+package form
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"github.com/kolosys/helix"
+)
+
+// Form is the state of a submitted HTML form: the values as entered and the
+// errors of each field.
+type Form struct {
+	// Values are the submitted values, used to repopulate the inputs.
+	Values url.Values
+
+	// Errors holds the error messages of each field, in the order reported.
+	Errors map[string][]string
+}
+
+// New returns an empty Form, for rendering a form before it is submitted.
+func New() *Form {
+	return &Form{Values: url.Values{}, Errors: map[string][]string{}}
+}
+
+// Bind binds the form post to T with helix.BindForm and, if T implements
+// helix.Validatable, validates it. Binding and validation errors are
+// collected into the returned Form along with the submitted values, and the
+// error is the *helix.ValidationErrors. Other errors, such as a malformed
+// body, are returned as is.
+func Bind[T any](r *http.Request) (T, *Form, error) {
+	result, err := helix.BindForm[T](r)
+
+	f := New()
+	if r.Form != nil {
+		f.Values = r.Form
+	}
+
+	verrs := helix.NewValidationErrors()
+	if err != nil && !errors.As(err, &verrs) {
+		return result, f, err
+	}
+	if v, ok := any(&result).(helix.Validatable); ok {
+		if err := v.Validate(); err != nil {
+			var more *helix.ValidationErrors
+			if !errors.As(err, &more) {
+				return result, f, err
+			}
+			for _, fe := range more.Errors() {
+				verrs.Add(fe.Field, fe.Message)
+			}
+		}
+	}
+
+	for _, fe := range verrs.Errors() {
+		f.AddError(fe.Field, fe.Message)
+	}
+	return result, f, verrs.Err()
+}
+
+// Value returns the submitted value of field, or "" if none.
+func (f *Form) Value(field string) string {
+	return f.Values.Get(field)
+}
+
+// AddError records an error for field, such as one found when saving
+// ("email is already taken").
+func (f *Form) AddError(field, message string) {
+	f.Errors[field] = append(f.Errors[field], message)
+}
+
+// Error returns the first error of field, or "" if it has none.
+func (f *Form) Error(field string) string {
+	if msgs := f.Errors[field]; len(msgs) > 0 {
+		return msgs[0]
+	}
+	return ""
+}
+
+// HasError reports whether field has an error.
+func (f *Form) HasError(field string) bool {
+	return len(f.Errors[field]) > 0
+}
+
+// HasErrors reports whether any field has an error.
+func (f *Form) HasErrors() bool {
+	return f != nil && len(f.Errors) > 0
+}
+
+// View is the data passed to templates by Render.
+type View struct {
+	// Form is the form being rendered.
+	Form *Form
+
+	// Data is the page's own data.
+	Data any
+}
+
+// Render executes the template name of tmpl with a View of f and data. The
+// status is 422 Unprocessable Entity when the form has errors and 200 OK
+// otherwise. The template is executed before anything is written, so
+// template errors are returned without sending a partial page.
+func Render(w http.ResponseWriter, tmpl *template.Template, name string, f *Form, data any) error {
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, name, View{Form: f, Data: data}); err != nil {
+		return err
+	}
+
+	status := http.StatusOK
+	if f.HasErrors() {
+		status = http.StatusUnprocessableEntity
+	}
+	w.Header().Set("Content-Type", helix.MIMETextHTMLCharsetUTF8)
+	w.WriteHeader(status)
+	_, err := buf.WriteTo(w)
+	return err
+}
@@ -0,0 +1,111 @@
+package form_test
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kolosys/helix"
+	. "github.com/kolosys/helix/form"
+)
+
+type signup struct {
+	Email string `form:"email,required"`
+	Age   int    `form:"age"`
+	Terms bool   `form:"terms"`
+}
+
+func (s *signup) Validate() error {
+	v := helix.NewValidationErrors()
+	if s.Email != "" && !strings.Contains(s.Email, "@") {
+		v.Add("email", "must be an email address")
+	}
+	if !s.Terms {
+		v.Add("terms", "must be accepted")
+	}
+	return v.Err()
+}
+
+var page = template.Must(template.New("signup").Parse(
+	`<input name="email" value="{{.Form.Value "email"}}">{{with .Form.Error "email"}}<p>{{.}}</p>{{end}}` +
+		`{{if .Form.HasError "age"}}<p>age {{.Form.Error "age"}}</p>{{end}}{{.Data}}`))
+
+func post(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(body))
+	req.Header.Set("Content-Type", helix.MIMEApplicationForm)
+	return req
+}
+
+func TestBind(t *testing.T) {
+	input, f, err := Bind[signup](post("email=a@example.com&age=30&terms=true"))
+	if err != nil || f.HasErrors() {
+		t.Fatalf("unexpected errors: %v %v", err, f.Errors)
+	}
+	if input.Email != "a@example.com" || input.Age != 30 || !input.Terms {
+		t.Errorf("unexpected input %+v", input)
+	}
+
+	_, f, err = Bind[signup](post("email=<b>nope</b>&age=old"))
+	var verrs *helix.ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+	want := map[string]string{
+		"email": "must be an email address",
+		"age":   "is invalid",
+		"terms": "must be accepted",
+	}
+	for field, msg := range want {
+		if got := f.Error(field); got != msg {
+			t.Errorf("%s: expected %q, got %q", field, msg, got)
+		}
+	}
+
+	_, f, _ = Bind[signup](post("terms=true"))
+	if f.Error("email") != "is required" {
+		t.Errorf("expected required error, got %q", f.Error("email"))
+	}
+}
+
+func TestRender(t *testing.T) {
+	_, f, _ := Bind[signup](post("email=<b>nope</b>&age=old"))
+
+	rec := httptest.NewRecorder()
+	if err := Render(rec, page, "signup", f, "footer"); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`value="&lt;b&gt;nope&lt;/b&gt;"`,
+		"<p>must be an email address</p>",
+		"<p>age is invalid</p>",
+		"footer",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected body to contain %q, got %q", want, body)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	if err := Render(rec, page, "signup", New(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "<p>") {
+		t.Errorf("expected a clean form, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	// Template errors don't send a partial page
+	rec = httptest.NewRecorder()
+	if err := Render(rec, page, "missing", f, nil); err == nil {
+		t.Error("expected template error")
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("expected no output, got %q", rec.Body.String())
+	}
+}
@@ -0,0 +1,207 @@
+package geoip
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/big"
+)
+
+// errCorrupt reports a malformed data section.
+var errCorrupt = errors.New("helix/geoip: corrupt database")
+
+// MaxMind DB data section types.
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// maxDepth bounds the nesting of decoded values, so a malicious database
+// cannot exhaust the stack with pointer cycles.
+const maxDepth = 32
+
+// decoder decodes values from a MaxMind DB data section.
+type decoder struct {
+	buf []byte
+}
+
+// decode decodes the value at offset and returns it with the offset of the
+// value that follows. Maps decode as map[string]any, arrays as []any,
+// integers as uint64 or int64 (uint128 as *big.Int), and floats as float64.
+func (d decoder) decode(offset uint, depth int) (any, uint, error) {
+	if depth > maxDepth {
+		return nil, 0, errCorrupt
+	}
+	typ, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if typ == typePointer {
+		target, next, err := d.pointer(size, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		v, _, err := d.decode(target, depth+1)
+		return v, next, err
+	}
+
+	switch typ {
+	case typeMap:
+		m := make(map[string]any, min(size, 64))
+		for range size {
+			key, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			k, ok := key.(string)
+			if !ok {
+				return nil, 0, errCorrupt
+			}
+			v, next, err := d.decode(next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[k] = v
+			offset = next
+		}
+		return m, offset, nil
+	case typeArray:
+		a := make([]any, 0, min(size, 64))
+		for range size {
+			v, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, v)
+			offset = next
+		}
+		return a, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	}
+
+	end := offset + size
+	if end > uint(len(d.buf)) || end < offset {
+		return nil, 0, errCorrupt
+	}
+	b := d.buf[offset:end]
+	switch typ {
+	case typeString:
+		return string(b), end, nil
+	case typeBytes:
+		return append([]byte(nil), b...), end, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, errCorrupt
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), end, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, errCorrupt
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), end, nil
+	case typeUint16, typeUint32, typeUint64:
+		if size > 8 {
+			return nil, 0, errCorrupt
+		}
+		return beUint(b), end, nil
+	case typeInt32:
+		if size > 4 {
+			return nil, 0, errCorrupt
+		}
+		return int64(int32(beUint(b))), end, nil
+	case typeUint128:
+		if size > 16 {
+			return nil, 0, errCorrupt
+		}
+		return new(big.Int).SetBytes(b), end, nil
+	}
+	return nil, 0, errCorrupt
+}
+
+// control decodes a control byte, with its extended type and size bytes,
+// and returns the type, the payload size and the payload offset. For
+// pointers the size is the control byte's low five bits.
+func (d decoder) control(offset uint) (typ int, size uint, next uint, err error) {
+	if offset >= uint(len(d.buf)) {
+		return 0, 0, 0, errCorrupt
+	}
+	ctrl := d.buf[offset]
+	offset++
+	typ = int(ctrl >> 5)
+	if typ == typePointer {
+		return typ, uint(ctrl & 0x1f), offset, nil
+	}
+	if typ == typeExtended {
+		if offset >= uint(len(d.buf)) {
+			return 0, 0, 0, errCorrupt
+		}
+		typ = 7 + int(d.buf[offset])
+		offset++
+	}
+
+	size = uint(ctrl & 0x1f)
+	if size < 29 {
+		return typ, size, offset, nil
+	}
+	n := size - 28
+	if offset+n > uint(len(d.buf)) {
+		return 0, 0, 0, errCorrupt
+	}
+	extra := beUint(d.buf[offset : offset+n])
+	switch n {
+	case 1:
+		size = 29 + uint(extra)
+	case 2:
+		size = 285 + uint(extra)
+	default:
+		size = 65821 + uint(extra)
+	}
+	return typ, size, offset + n, nil
+}
+
+// pointer decodes a pointer whose control byte had the low bits ctrl and
+// returns its target and the offset after it.
+func (d decoder) pointer(ctrl, offset uint) (target, next uint, err error) {
+	n := (ctrl>>3)&0x3 + 1
+	if offset+n > uint(len(d.buf)) {
+		return 0, 0, errCorrupt
+	}
+	b := uint(beUint(d.buf[offset : offset+n]))
+	vvv := ctrl & 0x7
+	switch n {
+	case 1:
+		target = vvv<<8 | b
+	case 2:
+		target = (vvv<<16 | b) + 2048
+	case 3:
+		target = (vvv<<24 | b) + 526336
+	default:
+		target = b
+	}
+	return target, offset + n, nil
+}
+
+// beUint decodes a big-endian unsigned integer of up to eight bytes.
+func beUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
@@ -0,0 +1,232 @@
+// Package geoip resolves client addresses to locations with MaxMind DB
+// (.mmdb) files, such as GeoLite2-City and GeoIP2-City, for the GeoIP and
+// Logger middleware.
+//
+// It lives in its own module so the core framework stays free of database
+// files and their licensing; the reader itself uses only the standard
+// library.
+//
+// Example:
+//
+//	db, err := geoip.Open("GeoLite2-City.mmdb")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	s.Use(middleware.RealIP(), middleware.GeoIP(db))
+//
+//	s.GET("/", helix.HandleCtx(func(c *helix.Ctx) error {
+//	    if g := c.Geo(); g != nil && g.Country == "DE" {
+//	        c.Redirect("/de/", http.StatusFound)
+//	        return nil
+//	    }
+//	    return c.OK(home)
+//	}))
+package geoip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"time"
+
+	"github.com/kolosys/helix/middleware"
+)
+
+// metadataMarker precedes the metadata at the end of a database.
+var metadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// Metadata describes a database.
+type Metadata struct {
+	// DatabaseType is the database's type, such as "GeoLite2-City".
+	DatabaseType string
+
+	// IPVersion is 4 for IPv4-only databases and 6 for those that also
+	// hold IPv4 addresses.
+	IPVersion int
+
+	// Languages lists the locales of the names in the database.
+	Languages []string
+
+	// BuildTime is when the database was built.
+	BuildTime time.Time
+
+	nodeCount  uint
+	recordSize uint
+}
+
+// Reader looks up addresses in a MaxMind DB. It is safe for concurrent use.
+type Reader struct {
+	meta      Metadata
+	tree      []byte
+	data      decoder
+	nodeBytes uint
+	ipv4Start uint
+}
+
+// Open reads the database file at path into memory.
+func Open(path string) (*Reader, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("helix/geoip: %w", err)
+	}
+	return FromBytes(b)
+}
+
+// FromBytes returns a Reader for the database b, which it retains.
+func FromBytes(b []byte) (*Reader, error) {
+	i := bytes.LastIndex(b, metadataMarker)
+	if i < 0 {
+		return nil, errors.New("helix/geoip: not a MaxMind DB")
+	}
+	raw, _, err := decoder{buf: b[i+len(metadataMarker):]}.decode(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := raw.(map[string]any)
+	if !ok {
+		return nil, errCorrupt
+	}
+
+	meta := Metadata{
+		DatabaseType: stringAt(m, "database_type"),
+		IPVersion:    int(uintAt(m, "ip_version")),
+		BuildTime:    time.Unix(int64(uintAt(m, "build_epoch")), 0).UTC(),
+		nodeCount:    uint(uintAt(m, "node_count")),
+		recordSize:   uint(uintAt(m, "record_size")),
+	}
+	if langs, ok := m["languages"].([]any); ok {
+		for _, l := range langs {
+			if s, ok := l.(string); ok {
+				meta.Languages = append(meta.Languages, s)
+			}
+		}
+	}
+	if meta.recordSize != 24 && meta.recordSize != 28 && meta.recordSize != 32 {
+		return nil, fmt.Errorf("helix/geoip: unsupported record size %d", meta.recordSize)
+	}
+	if meta.IPVersion != 4 && meta.IPVersion != 6 {
+		return nil, fmt.Errorf("helix/geoip: unsupported IP version %d", meta.IPVersion)
+	}
+
+	r := &Reader{meta: meta, nodeBytes: meta.recordSize / 4}
+	treeSize := meta.nodeCount * r.nodeBytes
+	if treeSize+16 > uint(i) {
+		return nil, errCorrupt
+	}
+	r.tree = b[:treeSize]
+	r.data = decoder{buf: b[treeSize+16 : i]}
+
+	if meta.IPVersion == 6 {
+		// IPv4 addresses live under ::/96
+		for range 96 {
+			if r.ipv4Start >= meta.nodeCount {
+				break
+			}
+			r.ipv4Start = r.record(r.ipv4Start, 0)
+		}
+	}
+	return r, nil
+}
+
+// Metadata returns the database's metadata.
+func (r *Reader) Metadata() Metadata {
+	return r.meta
+}
+
+// Lookup returns the record for addr, decoded as map[string]any, or nil if
+// the database has none.
+func (r *Reader) Lookup(addr netip.Addr) (any, error) {
+	addr = addr.Unmap()
+	if addr.Is6() && r.meta.IPVersion == 4 {
+		return nil, nil
+	}
+
+	node := uint(0)
+	if addr.Is4() {
+		node = r.ipv4Start
+	}
+	ip := addr.AsSlice()
+	for i := 0; i < len(ip)*8 && node < r.meta.nodeCount; i++ {
+		bit := uint(ip[i/8]>>(7-i%8)) & 1
+		node = r.record(node, bit)
+	}
+
+	switch {
+	case node == r.meta.nodeCount:
+		return nil, nil
+	case node < r.meta.nodeCount+16:
+		return nil, errCorrupt
+	}
+	v, _, err := r.data.decode(node-r.meta.nodeCount-16, 0)
+	return v, err
+}
+
+// ResolveGeo implements middleware.GeoResolver with the country, region,
+// city and location of the English names in a City or Country database.
+func (r *Reader) ResolveGeo(addr netip.Addr) (*middleware.Geo, error) {
+	v, err := r.Lookup(addr)
+	if err != nil {
+		return nil, err
+	}
+	rec, ok := v.(map[string]any)
+	if !ok {
+		return nil, nil
+	}
+
+	country, _ := rec["country"].(map[string]any)
+	if country == nil {
+		country, _ = rec["registered_country"].(map[string]any)
+	}
+	city, _ := rec["city"].(map[string]any)
+	loc, _ := rec["location"].(map[string]any)
+	g := &middleware.Geo{
+		Country:     stringAt(country, "iso_code"),
+		CountryName: englishName(country),
+		City:        englishName(city),
+		TimeZone:    stringAt(loc, "time_zone"),
+	}
+	g.Latitude, _ = loc["latitude"].(float64)
+	g.Longitude, _ = loc["longitude"].(float64)
+	if subs, ok := rec["subdivisions"].([]any); ok && len(subs) > 0 {
+		sub, _ := subs[0].(map[string]any)
+		g.Region = stringAt(sub, "iso_code")
+	}
+	return g, nil
+}
+
+// record returns the left (bit 0) or right (bit 1) record of node.
+func (r *Reader) record(node, bit uint) uint {
+	off := node * r.nodeBytes
+	b := r.tree[off : off+r.nodeBytes]
+	switch r.meta.recordSize {
+	case 24:
+		return uint(beUint(b[bit*3 : bit*3+3]))
+	case 28:
+		if bit == 0 {
+			return uint(b[3]>>4)<<24 | uint(beUint(b[0:3]))
+		}
+		return uint(b[3]&0x0f)<<24 | uint(beUint(b[4:7]))
+	default:
+		return uint(beUint(b[bit*4 : bit*4+4]))
+	}
+}
+
+// stringAt returns the string m[key], or "".
+func stringAt(m map[string]any, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+// uintAt returns the unsigned integer m[key], or 0.
+func uintAt(m map[string]any, key string) uint64 {
+	n, _ := m[key].(uint64)
+	return n
+}
+
+// englishName returns the English name of a record, such as a city.
+func englishName(m map[string]any) string {
+	names, _ := m["names"].(map[string]any)
+	return stringAt(names, "en")
+}
@@ -0,0 +1,187 @@
+package geoip_test
+
+import (
+	"encoding/binary"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/kolosys/helix/geoip"
+	"github.com/kolosys/helix/middleware"
+)
+
+// encode encodes v in the MaxMind DB data format.
+func encode(v any) []byte {
+	ctrl := func(typ, size int) []byte {
+		if typ > 7 {
+			return []byte{byte(size), byte(typ - 7)}
+		}
+		return []byte{byte(typ<<5 | size)}
+	}
+	switch v := v.(type) {
+	case string:
+		return append(ctrl(2, len(v)), v...)
+	case float64:
+		b := binary.BigEndian.AppendUint64(nil, math.Float64bits(v))
+		return append(ctrl(3, 8), b...)
+	case uint64:
+		b := binary.BigEndian.AppendUint32(nil, uint32(v))
+		return append(ctrl(6, 4), b...)
+	case []any:
+		b := ctrl(11, len(v))
+		for _, e := range v {
+			b = append(b, encode(e)...)
+		}
+		return b
+	case map[string]any:
+		b := ctrl(7, len(v))
+		for k, e := range v {
+			b = append(b, encode(k)...)
+			b = append(b, encode(e)...)
+		}
+		return b
+	}
+	panic("unsupported type")
+}
+
+// buildDB returns a database mapping network to record.
+func buildDB(ipVersion, recordSize int, network netip.Prefix, record map[string]any) []byte {
+	bits := network.Bits()
+	if ipVersion == 6 && network.Addr().Is4() {
+		bits += 96
+	}
+	ip := network.Addr().AsSlice()
+	if ipVersion == 6 && network.Addr().Is4() {
+		ip = append(make([]byte, 12), ip...) // ::81.2.69.0, not ::ffff:81.2.69.0
+	}
+
+	nodeCount := bits
+	put := func(b []byte, left, right int) []byte {
+		switch recordSize {
+		case 24:
+			return append(b, byte(left>>16), byte(left>>8), byte(left), byte(right>>16), byte(right>>8), byte(right))
+		case 28:
+			return append(b, byte(left>>16), byte(left>>8), byte(left), byte(left>>24<<4|right>>24&0x0f), byte(right>>16), byte(right>>8), byte(right))
+		}
+		b = binary.BigEndian.AppendUint32(b, uint32(left))
+		return binary.BigEndian.AppendUint32(b, uint32(right))
+	}
+
+	var db []byte
+	for i := range bits {
+		next := i + 1
+		if i == bits-1 {
+			next = nodeCount + 16 // data offset 0
+		}
+		if ip[i/8]>>(7-i%8)&1 == 0 {
+			db = put(db, next, nodeCount)
+		} else {
+			db = put(db, nodeCount, next)
+		}
+	}
+	db = append(db, make([]byte, 16)...)
+	db = append(db, encode(record)...)
+	db = append(db, "\xab\xcd\xefMaxMind.com"...)
+	return append(db, encode(map[string]any{
+		"node_count":    uint64(nodeCount),
+		"record_size":   uint64(recordSize),
+		"ip_version":    uint64(ipVersion),
+		"database_type": "GeoLite2-City",
+		"languages":     []any{"en"},
+		"build_epoch":   uint64(1700000000),
+	})...)
+}
+
+var cityRecord = map[string]any{
+	"country":      map[string]any{"iso_code": "GB", "names": map[string]any{"en": "United Kingdom"}},
+	"city":         map[string]any{"names": map[string]any{"en": "London"}},
+	"subdivisions": []any{map[string]any{"iso_code": "ENG"}},
+	"location":     map[string]any{"latitude": 51.5142, "longitude": -0.0931, "time_zone": "Europe/London"},
+}
+
+func TestReader(t *testing.T) {
+	network := netip.MustParsePrefix("81.2.69.0/24")
+	for _, tc := range []struct {
+		ipVersion, recordSize int
+	}{{4, 24}, {6, 28}, {6, 32}} {
+		db, err := geoip.FromBytes(buildDB(tc.ipVersion, tc.recordSize, network, cityRecord))
+		if err != nil {
+			t.Fatalf("IPv%d/%d: %v", tc.ipVersion, tc.recordSize, err)
+		}
+		if m := db.Metadata(); m.DatabaseType != "GeoLite2-City" || m.IPVersion != tc.ipVersion {
+			t.Errorf("unexpected metadata %+v", m)
+		}
+
+		g, err := db.ResolveGeo(netip.MustParseAddr("81.2.69.160"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := middleware.Geo{
+			Country: "GB", CountryName: "United Kingdom", Region: "ENG", City: "London",
+			Latitude: 51.5142, Longitude: -0.0931, TimeZone: "Europe/London",
+		}
+		if g == nil || *g != want {
+			t.Errorf("IPv%d/%d: expected %+v, got %+v", tc.ipVersion, tc.recordSize, want, g)
+		}
+
+		for _, addr := range []string{"81.2.70.1", "2001:db8::1"} {
+			if g, err := db.ResolveGeo(netip.MustParseAddr(addr)); err != nil || g != nil {
+				t.Errorf("IPv%d/%d: expected %s to be unknown, got %+v, %v", tc.ipVersion, tc.recordSize, addr, g, err)
+			}
+		}
+	}
+
+	if _, err := geoip.FromBytes([]byte("not a database")); err == nil {
+		t.Error("expected an error for invalid data")
+	}
+}
+
+func TestGeoIPMiddleware(t *testing.T) {
+	db, err := geoip.FromBytes(buildDB(4, 24, netip.MustParsePrefix("81.2.69.0/24"), cityRecord))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lookups := 0
+	resolver := middleware.GeoResolverFunc(func(addr netip.Addr) (*middleware.Geo, error) {
+		lookups++
+		return db.ResolveGeo(addr)
+	})
+
+	var logged middleware.LogValues
+	var city string
+	handler := middleware.LoggerWithConfig(middleware.LoggerConfig{
+		Output: func(v middleware.LogValues) { logged = v },
+		Geo:    resolver,
+	})(middleware.GeoIP(resolver)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		city = ""
+		if g := middleware.GeoFrom(r.Context()); g != nil {
+			city = g.City
+		}
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "81.2.69.160:4711"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if city != "London" {
+		t.Errorf("expected the handler to see London, got %q", city)
+	}
+	if logged.Country != "GB" || logged.City != "London" {
+		t.Errorf("expected GB/London to be logged, got %q/%q", logged.Country, logged.City)
+	}
+	if lookups != 1 {
+		t.Errorf("expected the Logger to reuse the GeoIP location, got %d lookups", lookups)
+	}
+
+	// Forwarding headers are not trusted without RealIP
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "203.0.113.9:4711"
+	req.Header.Set("X-Forwarded-For", "81.2.69.160")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if city != "" || logged.Country != "" {
+		t.Errorf("expected a forged X-Forwarded-For to be ignored, got %q/%q", logged.Country, city)
+	}
+}
@@ -0,0 +1,7 @@
+module github.com/kolosys/helix/geoip
+
+go 1.24
+
+replace github.com/kolosys/helix => ../
+
+require github.com/kolosys/helix v0.0.0-00010101000000-000000000000
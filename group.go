@@ -1,6 +1,10 @@
 package helix
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/kolosys/helix/middleware"
+)
 
 // Group represents a group of routes with a common prefix and middleware.
 type Group struct {
@@ -8,6 +12,7 @@ type Group struct {
 	middleware []Middleware
 	server     *Server
 	parent     *Group
+	cors       *middleware.CORSConfig
 }
 
 // toMiddleware converts any middleware type to Middleware.
@@ -25,7 +30,7 @@ func toMiddleware(mw []any) []Middleware {
 
 // Group creates a new route group with the given prefix.
 // The prefix is prepended to all routes registered on the group.
-// Accepts Middleware (helix.Middleware is an alias for middleware.Middleware) or func(http.Handler) http.Handler.
+// Accepts Middleware (helix.Middleware is an alias for middleware.Middleware), func(http.Handler) http.Handler, or CtxMiddleware.
 func (s *Server) Group(prefix string, mw ...any) *Group {
 	return &Group{
 		prefix:     prefix,
@@ -36,7 +41,7 @@ func (s *Server) Group(prefix string, mw ...any) *Group {
 
 // Group creates a nested group with the given prefix.
 // The prefix is appended to the parent group's prefix.
-// Accepts Middleware (helix.Middleware is an alias for middleware.Middleware) or func(http.Handler) http.Handler.
+// Accepts Middleware (helix.Middleware is an alias for middleware.Middleware), func(http.Handler) http.Handler, or CtxMiddleware.
 func (g *Group) Group(prefix string, mw ...any) *Group {
 	return &Group{
 		prefix:     g.fullPrefix() + prefix,
@@ -48,11 +53,35 @@ func (g *Group) Group(prefix string, mw ...any) *Group {
 
 // Use adds middleware to the group.
 // Middleware is applied to all routes registered on this group.
-// Accepts Middleware (helix.Middleware is an alias for middleware.Middleware) or func(http.Handler) http.Handler.
+// Accepts Middleware (helix.Middleware is an alias for middleware.Middleware), func(http.Handler) http.Handler, or CtxMiddleware.
 func (g *Group) Use(mw ...any) {
 	g.middleware = append(g.middleware, toMiddleware(mw)...)
 }
 
+// CORS gives the routes registered on the group and its nested groups a
+// CORS policy of their own, replacing the server's CORS middleware as
+// WithRouteCORS does. Routes can still set their own with WithRouteCORS,
+// and nested groups with CORS.
+//
+// Example:
+//
+//	public := s.Group("/public")
+//	public.CORS(middleware.DefaultCORSConfig())
+func (g *Group) CORS(config middleware.CORSConfig) {
+	g.cors = &config
+}
+
+// corsConfig returns the CORS policy of the group or its nearest parent
+// with one, or nil.
+func (g *Group) corsConfig() *middleware.CORSConfig {
+	for ; g != nil; g = g.parent {
+		if g.cors != nil {
+			return g.cors
+		}
+	}
+	return nil
+}
+
 // fullPrefix returns the complete prefix including parent prefixes.
 func (g *Group) fullPrefix() string {
 	return g.prefix
@@ -73,69 +102,55 @@ func (g *Group) allMiddleware() []Middleware {
 	return all
 }
 
-// wrapHandler wraps a handler with the group's middleware.
-func (g *Group) wrapHandler(handler http.HandlerFunc) http.HandlerFunc {
-	middleware := g.allMiddleware()
-	if len(middleware) == 0 {
-		return handler
-	}
-
-	// Build the handler chain
-	var h http.Handler = handler
-	for i := len(middleware) - 1; i >= 0; i-- {
-		h = middleware[i](h)
-	}
-
-	return func(w http.ResponseWriter, r *http.Request) {
-		h.ServeHTTP(w, r)
-	}
-}
-
 // Handle registers a handler for the given method and pattern.
-func (g *Group) Handle(method, pattern string, handler http.HandlerFunc) {
+// Route options, such as WithRouteTimeout, apply to this route only.
+func (g *Group) Handle(method, pattern string, handler http.HandlerFunc, opts ...RouteOption) {
 	fullPattern := g.fullPrefix() + pattern
 	// Prepend base path if set
 	fullPattern = g.server.prependBasePath(fullPattern)
-	g.server.router.Handle(method, fullPattern, g.wrapHandler(handler))
+	if cors := g.corsConfig(); cors != nil {
+		opts = append([]RouteOption{WithRouteCORS(*cors)}, opts...)
+	}
+	g.server.router.handleRoute(newRoute(method, fullPattern, handler, opts, g.allMiddleware()))
 }
 
 // GET registers a handler for GET requests.
-func (g *Group) GET(pattern string, handler http.HandlerFunc) {
-	g.Handle(http.MethodGet, pattern, handler)
+func (g *Group) GET(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	g.Handle(http.MethodGet, pattern, handler, opts...)
 }
 
 // POST registers a handler for POST requests.
-func (g *Group) POST(pattern string, handler http.HandlerFunc) {
-	g.Handle(http.MethodPost, pattern, handler)
+func (g *Group) POST(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	g.Handle(http.MethodPost, pattern, handler, opts...)
 }
 
 // PUT registers a handler for PUT requests.
-func (g *Group) PUT(pattern string, handler http.HandlerFunc) {
-	g.Handle(http.MethodPut, pattern, handler)
+func (g *Group) PUT(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	g.Handle(http.MethodPut, pattern, handler, opts...)
 }
 
 // PATCH registers a handler for PATCH requests.
-func (g *Group) PATCH(pattern string, handler http.HandlerFunc) {
-	g.Handle(http.MethodPatch, pattern, handler)
+func (g *Group) PATCH(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	g.Handle(http.MethodPatch, pattern, handler, opts...)
 }
 
 // DELETE registers a handler for DELETE requests.
-func (g *Group) DELETE(pattern string, handler http.HandlerFunc) {
-	g.Handle(http.MethodDelete, pattern, handler)
+func (g *Group) DELETE(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	g.Handle(http.MethodDelete, pattern, handler, opts...)
 }
 
 // OPTIONS registers a handler for OPTIONS requests.
-func (g *Group) OPTIONS(pattern string, handler http.HandlerFunc) {
-	g.Handle(http.MethodOptions, pattern, handler)
+func (g *Group) OPTIONS(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	g.Handle(http.MethodOptions, pattern, handler, opts...)
 }
 
 // HEAD registers a handler for HEAD requests.
-func (g *Group) HEAD(pattern string, handler http.HandlerFunc) {
-	g.Handle(http.MethodHead, pattern, handler)
+func (g *Group) HEAD(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	g.Handle(http.MethodHead, pattern, handler, opts...)
 }
 
 // Any registers a handler for all HTTP methods.
-func (g *Group) Any(pattern string, handler http.HandlerFunc) {
+func (g *Group) Any(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
 	methods := []string{
 		http.MethodGet,
 		http.MethodPost,
@@ -146,7 +161,7 @@ func (g *Group) Any(pattern string, handler http.HandlerFunc) {
 		http.MethodHead,
 	}
 	for _, method := range methods {
-		g.Handle(method, pattern, handler)
+		g.Handle(method, pattern, handler, opts...)
 	}
 }
 
@@ -0,0 +1,67 @@
+package helix
+
+import (
+	"net/http"
+	"strings"
+)
+
+// MountGRPC serves gRPC and gRPC-Web requests with handler, on the same port
+// as the REST routes. Requests are dispatched by Content-Type: POST requests
+// with an "application/grpc" content type (including "application/grpc+proto"
+// and "application/grpc-web") go to handler, all others to the router.
+// gRPC requests pass through the server middleware, so authentication,
+// logging and metrics are shared, but bypass the base path.
+// HTTP/2 over cleartext (h2c) is enabled, since gRPC clients without TLS
+// require it. Must be called before the server starts.
+//
+// Example:
+//
+//	grpcServer := grpc.NewServer()
+//	pb.RegisterGreeterServer(grpcServer, &greeter{})
+//	s.MountGRPC(grpcServer)
+func (s *Server) MountGRPC(handler http.Handler) {
+	if handler == nil {
+		panic("helix: gRPC handler must not be nil")
+	}
+	s.grpcHandler = handler
+	s.h2c = true
+}
+
+// MountRPC serves every request under prefix with handler, for RPC
+// frameworks that route by path such as connect-go. The arguments match the
+// path and handler returned by generated connect-go constructors.
+// Requests pass through the server middleware and the base path applies.
+// HTTP/2 over cleartext (h2c) is enabled for streaming RPCs without TLS.
+//
+// Example:
+//
+//	s.MountRPC(greetv1connect.NewGreetServiceHandler(&greeter{}))
+func (s *Server) MountRPC(prefix string, handler http.Handler) {
+	if handler == nil {
+		panic("helix: RPC handler must not be nil")
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		s.Handle(method, prefix+"{rpc...}", handler.ServeHTTP)
+	}
+	s.h2c = true
+}
+
+// grpcMiddleware dispatches gRPC requests to the mounted gRPC handler.
+func (s *Server) grpcMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isGRPCRequest(r) {
+			s.grpcHandler.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isGRPCRequest reports whether r is a gRPC or gRPC-Web request.
+func isGRPCRequest(r *http.Request) bool {
+	return r.Method == http.MethodPost &&
+		strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
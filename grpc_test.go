@@ -0,0 +1,73 @@
+package helix_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/kolosys/helix"
+)
+
+func TestMountGRPC(t *testing.T) {
+	s := New(&Options{BasePath: "/api"})
+
+	var middlewareCalls int
+	s.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			middlewareCalls++
+			next.ServeHTTP(w, r)
+		})
+	})
+	s.MountGRPC(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Write([]byte("grpc " + r.URL.Path))
+	}))
+	s.Handle(http.MethodPost, "/users", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("rest"))
+	})
+
+	tests := []struct {
+		path        string
+		contentType string
+		expected    string
+	}{
+		{"/greeter.Greeter/SayHello", "application/grpc", "grpc /greeter.Greeter/SayHello"},
+		{"/greeter.Greeter/SayHello", "application/grpc-web+proto", "grpc /greeter.Greeter/SayHello"},
+		{"/api/users", "application/json", "rest"},
+	}
+
+	for _, tc := range tests {
+		req := httptest.NewRequest(http.MethodPost, tc.path, nil)
+		req.Header.Set("Content-Type", tc.contentType)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		if rec.Body.String() != tc.expected {
+			t.Errorf("%s (%s): expected %q, got %q", tc.path, tc.contentType, tc.expected, rec.Body.String())
+		}
+	}
+
+	if middlewareCalls != len(tests) {
+		t.Errorf("expected middleware to run for every request, ran %d times", middlewareCalls)
+	}
+	if s.NewHTTPServer().Protocols == nil || !s.NewHTTPServer().Protocols.UnencryptedHTTP2() {
+		t.Error("expected MountGRPC to enable h2c")
+	}
+}
+
+func TestMountRPC(t *testing.T) {
+	s := New(nil)
+	s.MountRPC("/greet.v1.GreetService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method + " " + r.URL.Path))
+	}))
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(method, "/greet.v1.GreetService/Greet", nil))
+
+		expected := method + " /greet.v1.GreetService/Greet"
+		if rec.Body.String() != expected {
+			t.Errorf("expected %q, got %q", expected, rec.Body.String())
+		}
+	}
+}
@@ -2,7 +2,16 @@ package helix
 
 import (
 	"context"
+	"errors"
+	"io"
 	"net/http"
+	"os"
+	"reflect"
+	"runtime/debug"
+	"strconv"
+
+	"github.com/kolosys/helix/logs"
+	"github.com/kolosys/helix/middleware"
 )
 
 // ErrorHandler is a function that handles errors from handlers.
@@ -10,18 +19,38 @@ import (
 // for writing an appropriate error response.
 type ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
 
-// errorHandlerKey is the context key for storing the error handler.
-type errorHandlerKey struct{}
+// ErrorHook is called for every error returned from a handler, before the
+// error response is written.
+type ErrorHook func(r *http.Request, err error)
+
+// errorConfig holds server-level error handling settings.
+// It is stored in the request context when any setting is configured.
+type errorConfig struct {
+	handler  ErrorHandler
+	hooks    []ErrorHook
+	mappers  []ErrorMapper
+	debug    bool
+	typeBase string
+	messages MessageCatalog
+}
+
+// active reports whether any setting requires the config in the request context.
+func (c *errorConfig) active() bool {
+	return c.handler != nil || len(c.hooks) > 0 || len(c.mappers) > 0 || c.debug || c.typeBase != "" || c.messages != nil
+}
+
+// errorConfigKey is the context key for storing the error configuration.
+type errorConfigKey struct{}
 
-// withErrorHandler stores the error handler in the request context.
-func withErrorHandler(r *http.Request, handler ErrorHandler) *http.Request {
-	return r.WithContext(context.WithValue(r.Context(), errorHandlerKey{}, handler))
+// withErrorConfig stores the error configuration in the request context.
+func withErrorConfig(r *http.Request, config *errorConfig) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), errorConfigKey{}, config))
 }
 
-// getErrorHandler retrieves the error handler from the request context.
-func getErrorHandler(r *http.Request) (ErrorHandler, bool) {
-	handler, ok := r.Context().Value(errorHandlerKey{}).(ErrorHandler)
-	return handler, ok
+// getErrorConfig retrieves the error configuration from the request context.
+func getErrorConfig(r *http.Request) *errorConfig {
+	config, _ := r.Context().Value(errorConfigKey{}).(*errorConfig)
+	return config
 }
 
 // Handler is a generic handler function that accepts a typed request and returns a typed response.
@@ -60,7 +89,7 @@ func Handle[Req, Res any](h Handler[Req, Res]) http.HandlerFunc {
 		}
 
 		// Encode response
-		if err := JSON(w, http.StatusOK, res); err != nil {
+		if err := writeResponse(w, r, http.StatusOK, res); err != nil {
 			handleError(w, r, err)
 			return
 		}
@@ -93,7 +122,7 @@ func HandleWithStatus[Req, Res any](status int, h Handler[Req, Res]) http.Handle
 		}
 
 		// Encode response
-		if err := JSON(w, status, res); err != nil {
+		if err := writeResponse(w, r, status, res); err != nil {
 			handleError(w, r, err)
 			return
 		}
@@ -112,6 +141,72 @@ func HandleAccepted[Req, Res any](h Handler[Req, Res]) http.HandlerFunc {
 	return HandleWithStatus(http.StatusAccepted, h)
 }
 
+// DepsHandler is a Handler that also receives its dependencies: a struct
+// whose exported fields are resolved by type for each request, as by
+// Resolve. Fields tagged `di:"-"` are left alone.
+type DepsHandler[Req, Deps, Res any] func(ctx context.Context, req Req, deps Deps) (Res, error)
+
+// HandleDeps wraps a DepsHandler into an http.HandlerFunc. It binds and
+// validates the request like Handle and fails with the error of a
+// dependency that cannot be resolved. It panics if Deps is not a struct.
+//
+// Example:
+//
+//	s.GET("/users/{id}", helix.HandleDeps(func(ctx context.Context, req GetUserRequest, deps struct {
+//	    Repo UserRepo
+//	}) (User, error) {
+//	    return deps.Repo.Find(ctx, req.ID)
+//	}))
+func HandleDeps[Req, Deps, Res any](h DepsHandler[Req, Deps, Res]) http.HandlerFunc {
+	t := reflect.TypeFor[Deps]()
+	if t.Kind() != reflect.Struct {
+		panic("helix: HandleDeps dependencies must be a struct, got " + t.String())
+	}
+	var fields []int
+	for i := range t.NumField() {
+		if f := t.Field(i); f.IsExported() && f.Tag.Get("di") != "-" {
+			fields = append(fields, i)
+		}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, err := Bind[Req](r)
+		if err != nil {
+			handleError(w, r, err)
+			return
+		}
+
+		if v, ok := any(&req).(Validatable); ok {
+			if err := v.Validate(); err != nil {
+				handleError(w, r, err)
+				return
+			}
+		}
+
+		var deps Deps
+		dv := reflect.ValueOf(&deps).Elem()
+		for _, i := range fields {
+			v, err := resolveType(r.Context(), t.Field(i).Type)
+			if err != nil {
+				handleError(w, r, err)
+				return
+			}
+			dv.Field(i).Set(v)
+		}
+
+		res, err := h(r.Context(), req, deps)
+		if err != nil {
+			handleError(w, r, err)
+			return
+		}
+
+		if err := writeResponse(w, r, http.StatusOK, res); err != nil {
+			handleError(w, r, err)
+			return
+		}
+	}
+}
+
 // NoRequestHandler is a handler that takes no request body, only context.
 type NoRequestHandler[Res any] func(ctx context.Context) (Res, error)
 
@@ -125,7 +220,7 @@ func HandleNoRequest[Res any](h NoRequestHandler[Res]) http.HandlerFunc {
 			return
 		}
 
-		if err := JSON(w, http.StatusOK, res); err != nil {
+		if err := writeResponse(w, r, http.StatusOK, res); err != nil {
 			handleError(w, r, err)
 			return
 		}
@@ -177,16 +272,124 @@ func HandleEmpty(h EmptyHandler) http.HandlerFunc {
 	}
 }
 
+// StreamHandler is a handler that returns a streamed response body and its
+// content type.
+type StreamHandler[Req any] func(ctx context.Context, req Req) (body io.ReadCloser, contentType string, err error)
+
+// HandleStream wraps a StreamHandler into an http.HandlerFunc for downloads
+// and proxied streams. The request is bound and validated like Handle, and
+// errors returned by the handler become RFC 7807 responses. The body is
+// always closed.
+//
+// The content type defaults to application/octet-stream. Content-Length is
+// set when the body's size is known: regular files and bodies with a
+// Len() int method, such as a type embedding *bytes.Reader (io.NopCloser
+// hides it). Otherwise the response is chunked and flushed as data arrives.
+// Errors while copying the body can no longer change the response; they are
+// reported to OnError hooks and logged.
+func HandleStream[Req any](h StreamHandler[Req]) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, err := Bind[Req](r)
+		if err != nil {
+			handleError(w, r, err)
+			return
+		}
+
+		if v, ok := any(&req).(Validatable); ok {
+			if err := v.Validate(); err != nil {
+				handleError(w, r, err)
+				return
+			}
+		}
+
+		body, contentType, err := h(r.Context(), req)
+		if err != nil {
+			if body != nil {
+				body.Close()
+			}
+			handleError(w, r, err)
+			return
+		}
+		if body == nil {
+			NoContent(w)
+			return
+		}
+		defer body.Close()
+
+		if contentType == "" {
+			contentType = MIMEApplicationOctetStream
+		}
+		w.Header().Set("Content-Type", contentType)
+
+		size, known := streamSize(body)
+		if known {
+			w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		}
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodHead {
+			return
+		}
+
+		var dst io.Writer = w
+		if !known {
+			dst = flushWriter{w: w, rc: http.NewResponseController(w)}
+		}
+		if _, err := io.Copy(dst, body); err != nil && r.Context().Err() == nil {
+			reportCommittedError(r, err, http.StatusOK)
+		}
+	}
+}
+
+// streamSize returns the remaining size of body if it can be determined
+// without reading it.
+func streamSize(body io.Reader) (int64, bool) {
+	switch b := body.(type) {
+	case interface{ Len() int }:
+		return int64(b.Len()), true
+	case *os.File:
+		info, err := b.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return 0, false
+		}
+		offset, err := b.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, false
+		}
+		return info.Size() - offset, true
+	}
+	return 0, false
+}
+
+// flushWriter flushes the response after every write so streamed data
+// reaches the client as it is produced.
+type flushWriter struct {
+	w  io.Writer
+	rc *http.ResponseController
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if err == nil {
+		f.rc.Flush()
+	}
+	return n, err
+}
+
 // handleError handles errors from handlers.
+// Domain errors are first translated by the registered error mappers.
 // If a custom error handler is set in the request context, it is used.
 // Otherwise, the default error handling is used:
 //   - If the error is a Problem, it is encoded as RFC 7807.
 //   - If the error is ValidationErrors, it is encoded with field-level errors.
 //   - Otherwise, a generic 500 Internal Server Error is returned.
 func handleError(w http.ResponseWriter, r *http.Request, err error) {
+	config := getErrorConfig(r)
+	err = mapError(config, err)
+	reportError(r, config, err)
+
 	// Check for custom error handler in context
-	if handler, ok := getErrorHandler(r); ok {
-		handler(w, r, err)
+	if config != nil && config.handler != nil {
+		config.handler(w, r, err)
 		return
 	}
 
@@ -198,8 +401,10 @@ func handleError(w http.ResponseWriter, r *http.Request, err error) {
 // This can be called from custom error handlers to fall back to default behavior.
 func HandleErrorDefault(w http.ResponseWriter, r *http.Request, err error) {
 	// Check if it's a ValidationErrors
-	if verrs, ok := err.(*ValidationErrors); ok {
+	var verrs *ValidationErrors
+	if errors.As(err, &verrs) {
 		p := verrs.ToProblem()
+		p = prepareValidationProblem(w, r, p)
 		p.Instance = r.URL.RequestURI()
 		w.Header().Set("Content-Type", MIMEApplicationProblemJSON)
 		w.WriteHeader(p.Status)
@@ -207,30 +412,107 @@ func HandleErrorDefault(w http.ResponseWriter, r *http.Request, err error) {
 		return
 	}
 
-	// Check if it's a Problem error
-	if problem, ok := err.(Problem); ok {
-		// Set the instance to the request URI if not set
-		if problem.Instance == "" {
-			problem.Instance = r.URL.RequestURI()
+	// Check if it's a Problem error, possibly wrapped
+	problem, ok := ProblemFrom(err)
+	var tooLarge *http.MaxBytesError
+	if !ok {
+		if errors.As(err, &tooLarge) {
+			problem = ErrPayloadTooLarge.WithDetailf("request body exceeds %d bytes", tooLarge.Limit).WithErr(err)
+		} else if isBindingError(err) {
+			// Binding errors are client errors
+			problem = ErrBadRequest.WithErr(err)
+		} else {
+			// Default to internal server error
+			problem = ErrInternal.WithErr(err)
 		}
-		WriteProblem(w, problem)
-		return
 	}
 
-	// Check for binding errors
-	if isBindingError(err) {
-		problem := ErrBadRequest.WithErr(err)
+	problem = prepareProblem(w, r, problem)
+
+	// Set the instance to the request URI if not set
+	if problem.Instance == "" {
 		problem.Instance = r.URL.RequestURI()
-		WriteProblem(w, problem)
+	}
+
+	// In debug mode, expose the underlying error and stack
+	if config := getErrorConfig(r); config != nil && config.debug {
+		writeDebugProblem(w, r, problem, err, debug.Stack(), nil)
 		return
 	}
 
-	// Default to internal server error
-	problem := ErrInternal.WithErr(err)
-	problem.Instance = r.URL.RequestURI()
 	WriteProblem(w, problem)
 }
 
+// reportError runs the server's error hooks and logs server errors.
+// Errors resulting in a 5xx status are always logged with the request ID
+// and a stack trace, since the response body hides the cause.
+func reportError(r *http.Request, config *errorConfig, err error) {
+	runErrorHooks(r, config, err)
+
+	status := errorStatus(err)
+	if status < http.StatusInternalServerError {
+		return
+	}
+
+	fields := logs.Fields{
+		"error":  err.Error(),
+		"status": status,
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"stack":  string(debug.Stack()),
+	}
+	if id := middleware.GetRequestID(r.Context()); id != "" {
+		fields["request_id"] = id
+	}
+	logs.FromContext(r.Context()).Error("request failed", fields)
+}
+
+// reportCommittedError reports an error returned after the response was
+// written, when it can no longer be turned into an error response.
+func reportCommittedError(r *http.Request, err error, status int) {
+	runErrorHooks(r, getErrorConfig(r), err)
+
+	fields := logs.Fields{
+		"error":  err.Error(),
+		"status": status,
+		"method": r.Method,
+		"path":   r.URL.Path,
+	}
+	if id := middleware.GetRequestID(r.Context()); id != "" {
+		fields["request_id"] = id
+	}
+	logs.FromContext(r.Context()).Warn("handler returned an error after the response was written", fields)
+}
+
+// runErrorHooks calls the server's error hooks.
+func runErrorHooks(r *http.Request, config *errorConfig, err error) {
+	if config == nil {
+		return
+	}
+	for _, hook := range config.hooks {
+		hook(r, err)
+	}
+}
+
+// errorStatus returns the status code the default error handling uses for err.
+func errorStatus(err error) int {
+	var verrs *ValidationErrors
+	if errors.As(err, &verrs) {
+		return http.StatusUnprocessableEntity
+	}
+	if problem, ok := ProblemFrom(err); ok {
+		return problem.Status
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	if isBindingError(err) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
 // isBindingError checks if an error is a binding error.
 func isBindingError(err error) bool {
 	switch err {
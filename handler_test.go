@@ -1,13 +1,21 @@
 package helix_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 
 	. "github.com/kolosys/helix"
+	"github.com/kolosys/helix/logs"
+	"github.com/kolosys/helix/middleware"
 )
 
 func TestHandleWithStatus(t *testing.T) {
@@ -116,6 +124,42 @@ func TestHandleBindingError(t *testing.T) {
 	}
 }
 
+func TestHandleUnsupportedMediaType(t *testing.T) {
+	type Request struct {
+		Name string `json:"name"`
+	}
+
+	s := New(nil)
+	s.POST("/items", Handle(func(ctx context.Context, req Request) (Request, error) {
+		return req, nil
+	}))
+
+	tests := []struct {
+		contentType string
+		want        int
+	}{
+		{"application/json", http.StatusOK},
+		{"application/merge-patch+json", http.StatusOK},
+		{"", http.StatusOK},
+		{"text/plain", http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(`{"name":"a"}`))
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("%q: expected status %d, got %d", tt.contentType, tt.want, rec.Code)
+		}
+		if tt.want == http.StatusUnsupportedMediaType && !strings.Contains(rec.Body.String(), "supported: application/json") {
+			t.Errorf("expected the supported types in the problem, got %s", rec.Body)
+		}
+	}
+}
+
 func TestHandleGenericError(t *testing.T) {
 	type Request struct{}
 
@@ -204,6 +248,128 @@ func TestHandleContextCancellation(t *testing.T) {
 	}
 }
 
+func TestHandleStream(t *testing.T) {
+	type Request struct {
+		Name string `path:"name"`
+	}
+
+	var closed bool
+	s := New(nil)
+	s.GET("/files/{name}", HandleStream(func(ctx context.Context, req Request) (io.ReadCloser, string, error) {
+		switch req.Name {
+		case "report.csv":
+			return stringBody{strings.NewReader("a,b\n1,2\n")}, "text/csv", nil
+		case "live":
+			pr, pw := io.Pipe()
+			go func() {
+				pw.Write([]byte("chunk1"))
+				pw.Write([]byte("chunk2"))
+				pw.Close()
+			}()
+			return closeTracker{pr, &closed}, "", nil
+		}
+		return nil, "", ErrNotFound.WithDetailf("file %s not found", req.Name)
+	}))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/files/report.csv", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "a,b\n1,2\n" {
+		t.Errorf("unexpected response %d %q", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Content-Type") != "text/csv" || rec.Header().Get("Content-Length") != "8" {
+		t.Errorf("unexpected headers %v", rec.Header())
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/files/live", nil))
+	if rec.Body.String() != "chunk1chunk2" {
+		t.Errorf("unexpected body %q", rec.Body.String())
+	}
+	if rec.Header().Get("Content-Type") != MIMEApplicationOctetStream || rec.Header().Get("Content-Length") != "" {
+		t.Errorf("expected octet-stream without length, got %v", rec.Header())
+	}
+	if !rec.Flushed {
+		t.Error("expected unknown-length stream to be flushed")
+	}
+	if !closed {
+		t.Error("expected body to be closed")
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/files/missing", nil))
+	if rec.Code != http.StatusNotFound || rec.Header().Get("Content-Type") != MIMEApplicationProblemJSON {
+		t.Errorf("expected 404 problem, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
+
+type stringBody struct{ *strings.Reader }
+
+func (stringBody) Close() error { return nil }
+
+type closeTracker struct {
+	io.Reader
+	closed *bool
+}
+
+func (c closeTracker) Close() error {
+	*c.closed = true
+	return nil
+}
+
+func TestHandleResult(t *testing.T) {
+	type Request struct {
+		Name string `json:"name"`
+	}
+	type User struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+
+	s := New(nil)
+	s.POST("/users", Handle(func(ctx context.Context, req Request) (Result[User], error) {
+		return Result[User]{
+			Value:   User{ID: "42", Name: req.Name},
+			Status:  http.StatusCreated,
+			Headers: http.Header{"Location": {"/users/42"}},
+			Cookies: []*http.Cookie{{Name: "last_user", Value: "42"}},
+		}, nil
+	}))
+	s.GET("/users", HandleNoRequest(func(ctx context.Context) (Result[[]User], error) {
+		return Result[[]User]{Value: []User{}, Headers: http.Header{"X-Total-Count": {"0"}}}, nil
+	}))
+	s.DELETE("/users/{id}", HandleWithStatus(http.StatusOK, func(ctx context.Context, req struct{}) (Result[any], error) {
+		return Result[any]{Status: http.StatusNoContent}, nil
+	}))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name":"ann"}`)))
+	if rec.Code != http.StatusCreated {
+		t.Errorf("expected 201, got %d", rec.Code)
+	}
+	if rec.Header().Get("Location") != "/users/42" {
+		t.Errorf("expected Location header, got %q", rec.Header().Get("Location"))
+	}
+	if !strings.Contains(rec.Header().Get("Set-Cookie"), "last_user=42") {
+		t.Errorf("expected cookie, got %q", rec.Header().Get("Set-Cookie"))
+	}
+	var user User
+	if err := json.Unmarshal(rec.Body.Bytes(), &user); err != nil || user.Name != "ann" {
+		t.Errorf("expected only the value to be encoded, got %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Total-Count") != "0" || strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Errorf("expected default status with header, got %d %v %q", rec.Code, rec.Header(), rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/users/42", nil))
+	if rec.Code != http.StatusNoContent || rec.Body.Len() != 0 {
+		t.Errorf("expected empty 204, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
 func BenchmarkHandle(b *testing.B) {
 	type Request struct {
 		ID int `path:"id"`
@@ -271,3 +437,220 @@ func BenchmarkHandleNoRequest(b *testing.B) {
 		s.ServeHTTP(rec, req)
 	}
 }
+
+func TestServerOnError(t *testing.T) {
+	var hookErrs []error
+	var hookPath string
+
+	s := New(nil)
+	s.OnError(func(r *http.Request, err error) {
+		hookErrs = append(hookErrs, err)
+		hookPath = r.URL.Path
+	})
+	s.GET("/missing", HandleNoRequest(func(ctx context.Context) (any, error) {
+		return nil, ErrNotFound
+	}))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+	if len(hookErrs) != 1 || hookPath != "/missing" {
+		t.Errorf("expected hook to be called once for /missing, got %v at %q", hookErrs, hookPath)
+	}
+}
+
+func TestHandleErrorLogsServerErrors(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logs.New(logs.WithOutput(&buf), logs.WithFormatter(&logs.JSONFormatter{}))
+
+	s := New(nil)
+	s.Use(middleware.RequestID(), middleware.ContextLogger(logger))
+	s.GET("/fail", HandleNoRequest(func(ctx context.Context) (any, error) {
+		return nil, errors.New("db connection refused")
+	}))
+	s.GET("/missing", HandleNoRequest(func(ctx context.Context) (any, error) {
+		return nil, ErrNotFound
+	}))
+
+	// Client errors are not logged
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if buf.Len() != 0 {
+		t.Fatalf("expected no log for 4xx, got %q", buf.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-1")
+	s.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to decode log line %q: %v", buf.String(), err)
+	}
+	if entry["level"] != "error" || entry["error"] != "db connection refused" {
+		t.Errorf("unexpected entry: %v", entry)
+	}
+	if entry["request_id"] != "req-1" || entry["status"] != float64(500) {
+		t.Errorf("expected request ID and status, got %v", entry)
+	}
+	if stack, _ := entry["stack"].(string); !strings.Contains(stack, "goroutine") {
+		t.Errorf("expected stack trace, got %q", stack)
+	}
+}
+
+type duplicateError struct {
+	Field string
+}
+
+func (e *duplicateError) Error() string { return e.Field + " already exists" }
+
+func TestServerMapError(t *testing.T) {
+	errNoRows := errors.New("sql: no rows in result set")
+
+	s := New(nil)
+	s.MapError(errNoRows, ErrNotFound.WithDetail("record not found"))
+	s.MapError((*duplicateError)(nil), ErrConflict)
+
+	s.GET("/missing", HandleNoRequest(func(ctx context.Context) (any, error) {
+		return nil, fmt.Errorf("loading user: %w", errNoRows)
+	}))
+	s.GET("/duplicate", HandleNoRequest(func(ctx context.Context) (any, error) {
+		return nil, fmt.Errorf("creating user: %w", &duplicateError{Field: "email"})
+	}))
+	s.GET("/other", HandleNoRequest(func(ctx context.Context) (any, error) {
+		return nil, errors.New("unmapped")
+	}))
+
+	tests := map[string]int{
+		"/missing":   http.StatusNotFound,
+		"/duplicate": http.StatusConflict,
+		"/other":     http.StatusInternalServerError,
+	}
+	for path, want := range tests {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("%s: expected status %d, got %d", path, want, rec.Code)
+		}
+	}
+}
+
+func TestServerMapError_InvalidTarget(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for non-error target")
+		}
+	}()
+	New(nil).MapError("not an error", ErrNotFound)
+}
+
+func TestRegisterErrorMapper(t *testing.T) {
+	errQuota := errors.New("quota exceeded")
+	RegisterErrorMapper(func(err error) (Problem, bool) {
+		if errors.Is(err, errQuota) {
+			return ErrTooManyRequests, true
+		}
+		return Problem{}, false
+	})
+
+	var hookErr error
+	s := New(nil)
+	s.OnError(func(r *http.Request, err error) { hookErr = err })
+	s.GET("/", HandleNoRequest(func(ctx context.Context) (any, error) {
+		return nil, errQuota
+	}))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status 429, got %d", rec.Code)
+	}
+	if p, ok := hookErr.(Problem); !ok || p.Err != errQuota {
+		t.Errorf("expected hook to receive mapped problem with cause, got %#v", hookErr)
+	}
+}
+
+type depsGreeter interface{ Greet(name string) string }
+
+type depsPrefix string
+
+type depsHello struct{ prefix depsPrefix }
+
+func (h depsHello) Greet(name string) string { return string(h.prefix) + name }
+
+// provideGreeter registers the constructors of TestHandleDeps once, as the
+// container is global.
+var provideGreeter = sync.OnceFunc(func() {
+	Provide(func(p depsPrefix) depsGreeter { return depsHello{prefix: p} })
+})
+
+func TestHandleDeps(t *testing.T) {
+	Register(depsPrefix("hello, "))
+	provideGreeter()
+	if err := Container().Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	type Request struct {
+		Name string `query:"name"`
+	}
+	h := HandleDeps(func(ctx context.Context, req Request, deps struct {
+		Greeter depsGreeter
+		Prefix  depsPrefix
+		Skipped *strings.Builder `di:"-"`
+	}) (map[string]string, error) {
+		return map[string]string{"greeting": deps.Greeter.Greet(req.Name), "prefix": string(deps.Prefix)}, nil
+	})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?name=ada", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"greeting":"hello, ada"`) {
+		t.Fatalf("unexpected response %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Request-scoped services take precedence
+	req := httptest.NewRequest(http.MethodGet, "/?name=ada", nil)
+	req = req.WithContext(WithService[depsPrefix](req.Context(), "scoped "))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if !strings.Contains(rec.Body.String(), `"prefix":"scoped "`) {
+		t.Fatalf("expected request-scoped service, got %s", rec.Body.String())
+	}
+
+	// Missing dependencies fail the request
+	type missing struct{}
+	rec = httptest.NewRecorder()
+	HandleDeps(func(ctx context.Context, req struct{}, deps struct{ M *missing }) (string, error) {
+		return "", nil
+	}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rec.Code)
+	}
+}
+
+type resolveClock struct{ zone string }
+
+var provideClock = sync.OnceFunc(func() {
+	Provide(func() *resolveClock { return &resolveClock{zone: "UTC"} })
+})
+
+func TestResolve(t *testing.T) {
+	provideClock()
+
+	s := New(nil)
+	s.GET("/zone", HandleCtx(func(c *Ctx) error {
+		clk, err := Resolve[*resolveClock](c)
+		if err != nil {
+			return err
+		}
+		return c.OK(map[string]string{"zone": clk.zone})
+	}))
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/zone", nil))
+	if !strings.Contains(rec.Body.String(), `"zone":"UTC"`) {
+		t.Fatalf("unexpected response %s", rec.Body.String())
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -14,6 +15,7 @@ const (
 	HealthStatusUp       HealthStatus = "up"
 	HealthStatusDown     HealthStatus = "down"
 	HealthStatusDegraded HealthStatus = "degraded"
+	HealthStatusDraining HealthStatus = "draining"
 )
 
 // HealthCheck is a function that checks the health of a component.
@@ -169,3 +171,272 @@ func ReadinessHandler(checks ...func(ctx context.Context) error) http.HandlerFun
 		})
 	}
 }
+
+// -----------------------------------------------------------------------------
+// Server Health Registry
+// -----------------------------------------------------------------------------
+
+// HealthProbe identifies which probe endpoints a check participates in.
+// Probes can be combined with bitwise OR.
+type HealthProbe int
+
+const (
+	// ProbeReadiness includes the check in /readyz.
+	ProbeReadiness HealthProbe = 1 << iota
+
+	// ProbeLiveness includes the check in /livez.
+	ProbeLiveness
+
+	// ProbeStartup includes the check in /startupz.
+	ProbeStartup
+)
+
+// HealthCheckConfig configures a check registered with a HealthRegistry.
+type HealthCheckConfig struct {
+	// Check reports an error if the component is unhealthy. Required.
+	Check func(ctx context.Context) error
+
+	// Timeout is the maximum duration for a single run of the check.
+	// Default: the registry timeout (5 seconds).
+	Timeout time.Duration
+
+	// CacheTTL is how long a result is reused before the check runs again.
+	// Default: 0 (run on every request)
+	CacheTTL time.Duration
+
+	// Probes selects the endpoints the check participates in.
+	// All checks are included in /healthz.
+	// Default: ProbeReadiness
+	Probes HealthProbe
+}
+
+// HealthRegistry collects component health checks for a server and exposes
+// Kubernetes-style probe endpoints. Readiness automatically reports
+// not ready once the server begins graceful shutdown.
+type HealthRegistry struct {
+	mu       sync.RWMutex
+	checks   map[string]*registeredCheck
+	version  string
+	timeout  time.Duration
+	draining atomic.Bool
+	started  atomic.Bool
+}
+
+// registeredCheck is a check with its configuration and cached result.
+type registeredCheck struct {
+	config HealthCheckConfig
+
+	mu       sync.Mutex
+	result   HealthCheckResult
+	checked  time.Time
+	hasCache bool
+}
+
+// newHealthRegistry creates an empty HealthRegistry.
+func newHealthRegistry() *HealthRegistry {
+	return &HealthRegistry{
+		checks:  make(map[string]*registeredCheck),
+		timeout: 5 * time.Second,
+	}
+}
+
+// Health returns the server's health registry.
+// On first call, it registers the probe endpoints:
+//   - GET /healthz  - runs all checks
+//   - GET /livez    - runs liveness checks
+//   - GET /readyz   - runs readiness checks, not ready while draining
+//   - GET /startupz - runs startup checks until they first succeed
+func (s *Server) Health() *HealthRegistry {
+	if s.health == nil {
+		s.health = newHealthRegistry()
+		s.GET("/healthz", s.health.HealthzHandler())
+		s.GET("/livez", s.health.LivezHandler())
+		s.GET("/readyz", s.health.ReadyzHandler())
+		s.GET("/startupz", s.health.StartupzHandler())
+	}
+	return s.health
+}
+
+// Version sets the application version shown in probe responses.
+func (h *HealthRegistry) Version(v string) *HealthRegistry {
+	h.version = v
+	return h
+}
+
+// Timeout sets the default timeout for checks that don't set their own.
+func (h *HealthRegistry) Timeout(d time.Duration) *HealthRegistry {
+	h.timeout = d
+	return h
+}
+
+// Register adds a readiness check for a named component.
+func (h *HealthRegistry) Register(name string, check func(ctx context.Context) error) *HealthRegistry {
+	return h.RegisterWithConfig(name, HealthCheckConfig{Check: check})
+}
+
+// RegisterWithConfig adds a check for a named component with the given configuration.
+func (h *HealthRegistry) RegisterWithConfig(name string, config HealthCheckConfig) *HealthRegistry {
+	if config.Check == nil {
+		panic("helix: health check must not be nil")
+	}
+	if config.Probes == 0 {
+		config.Probes = ProbeReadiness
+	}
+
+	h.mu.Lock()
+	h.checks[name] = &registeredCheck{config: config}
+	h.mu.Unlock()
+	return h
+}
+
+// Draining reports whether the server is shutting down.
+func (h *HealthRegistry) Draining() bool {
+	return h.draining.Load()
+}
+
+// SetDraining marks the server as draining, causing /readyz to report not ready.
+// This is called automatically when the server begins graceful shutdown.
+func (h *HealthRegistry) SetDraining(draining bool) {
+	h.draining.Store(draining)
+}
+
+// HealthzHandler returns a handler that runs every registered check.
+func (h *HealthRegistry) HealthzHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.respond(w, h.run(r.Context(), 0))
+	}
+}
+
+// LivezHandler returns a handler that runs liveness checks.
+func (h *HealthRegistry) LivezHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.respond(w, h.run(r.Context(), ProbeLiveness))
+	}
+}
+
+// ReadyzHandler returns a handler that runs readiness checks.
+// It reports not ready while the server is draining.
+func (h *HealthRegistry) ReadyzHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.Draining() {
+			h.respond(w, HealthResponse{
+				Status:    HealthStatusDraining,
+				Timestamp: time.Now().UTC(),
+				Version:   h.version,
+			})
+			return
+		}
+		h.respond(w, h.run(r.Context(), ProbeReadiness))
+	}
+}
+
+// StartupzHandler returns a handler that runs startup checks.
+// Once all startup checks have passed, it reports up without running them again.
+func (h *HealthRegistry) StartupzHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.started.Load() {
+			h.respond(w, HealthResponse{
+				Status:    HealthStatusUp,
+				Timestamp: time.Now().UTC(),
+				Version:   h.version,
+			})
+			return
+		}
+
+		response := h.run(r.Context(), ProbeStartup)
+		if response.Status != HealthStatusDown {
+			h.started.Store(true)
+		}
+		h.respond(w, response)
+	}
+}
+
+// run executes the checks for the given probe concurrently.
+// A probe of 0 runs all checks.
+func (h *HealthRegistry) run(ctx context.Context, probe HealthProbe) HealthResponse {
+	response := HealthResponse{
+		Status:     HealthStatusUp,
+		Timestamp:  time.Now().UTC(),
+		Version:    h.version,
+		Components: make(map[string]HealthCheckResult),
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	for name, check := range h.checks {
+		if probe != 0 && check.config.Probes&probe == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		go func(name string, check *registeredCheck) {
+			defer wg.Done()
+
+			result := check.run(ctx, h.timeout)
+
+			mu.Lock()
+			response.Components[name] = result
+			if result.Status == HealthStatusDown {
+				response.Status = HealthStatusDown
+			}
+			mu.Unlock()
+		}(name, check)
+	}
+
+	wg.Wait()
+	return response
+}
+
+// respond writes the health response with a status code matching its state.
+func (h *HealthRegistry) respond(w http.ResponseWriter, response HealthResponse) {
+	status := http.StatusOK
+	if response.Status == HealthStatusDown || response.Status == HealthStatusDraining {
+		status = http.StatusServiceUnavailable
+	}
+	JSON(w, status, response)
+}
+
+// run executes the check, returning a cached result if it is still fresh.
+func (c *registeredCheck) run(ctx context.Context, defaultTimeout time.Duration) HealthCheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.hasCache && c.config.CacheTTL > 0 && time.Since(c.checked) < c.config.CacheTTL {
+		return c.result
+	}
+
+	timeout := c.config.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- c.config.Check(ctx) }()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// The check ignored its context; report it down without waiting
+		err = ctx.Err()
+	}
+	result := HealthCheckResult{
+		Status:  HealthStatusUp,
+		Latency: time.Since(start),
+	}
+	if err != nil {
+		result.Status = HealthStatusDown
+		result.Message = err.Error()
+	}
+
+	c.result = result
+	c.checked = time.Now()
+	c.hasCache = true
+	return result
+}
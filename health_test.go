@@ -0,0 +1,262 @@
+package helix_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/helix"
+)
+
+func probe(t *testing.T, s *Server, path string) (int, HealthResponse) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var resp HealthResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode %s response: %v", path, err)
+	}
+	return rec.Code, resp
+}
+
+func TestServerHealth_Endpoints(t *testing.T) {
+	s := New(nil)
+	s.Health().
+		Version("1.2.3").
+		Register("db", func(ctx context.Context) error { return nil })
+
+	for _, path := range []string{"/healthz", "/livez", "/readyz", "/startupz"} {
+		code, resp := probe(t, s, path)
+		if code != http.StatusOK {
+			t.Errorf("%s: expected status 200, got %d", path, code)
+		}
+		if resp.Status != HealthStatusUp {
+			t.Errorf("%s: expected status up, got %s", path, resp.Status)
+		}
+		if resp.Version != "1.2.3" {
+			t.Errorf("%s: expected version 1.2.3, got %s", path, resp.Version)
+		}
+	}
+}
+
+func TestServerHealth_SameRegistry(t *testing.T) {
+	s := New(nil)
+	if s.Health() != s.Health() {
+		t.Error("expected Health to return the same registry")
+	}
+}
+
+func TestServerHealth_FailingCheck(t *testing.T) {
+	s := New(nil)
+	s.Health().Register("db", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})
+
+	code, resp := probe(t, s, "/readyz")
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", code)
+	}
+	if resp.Components["db"].Message != "connection refused" {
+		t.Errorf("expected failure message, got %q", resp.Components["db"].Message)
+	}
+
+	// Readiness checks don't affect liveness
+	code, _ = probe(t, s, "/livez")
+	if code != http.StatusOK {
+		t.Errorf("expected livez status 200, got %d", code)
+	}
+}
+
+func TestServerHealth_Probes(t *testing.T) {
+	s := New(nil)
+	s.Health().
+		RegisterWithConfig("deadlock", HealthCheckConfig{
+			Check:  func(ctx context.Context) error { return errors.New("stuck") },
+			Probes: ProbeLiveness,
+		}).
+		Register("cache", func(ctx context.Context) error { return nil })
+
+	code, resp := probe(t, s, "/livez")
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("expected livez status 503, got %d", code)
+	}
+	if _, ok := resp.Components["cache"]; ok {
+		t.Error("expected readiness check to be excluded from livez")
+	}
+
+	code, _ = probe(t, s, "/readyz")
+	if code != http.StatusOK {
+		t.Errorf("expected readyz status 200, got %d", code)
+	}
+
+	code, resp = probe(t, s, "/healthz")
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("expected healthz status 503, got %d", code)
+	}
+	if len(resp.Components) != 2 {
+		t.Errorf("expected 2 components in healthz, got %d", len(resp.Components))
+	}
+}
+
+func TestServerHealth_Timeout(t *testing.T) {
+	s := New(nil)
+	s.Health().RegisterWithConfig("slow", HealthCheckConfig{
+		Check: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+		Timeout: 10 * time.Millisecond,
+	})
+
+	start := time.Now()
+	code, _ := probe(t, s, "/readyz")
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", code)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("expected check to be bounded by its timeout")
+	}
+}
+
+func TestServerHealth_CheckIgnoresTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	s := New(nil)
+	s.Health().RegisterWithConfig("stuck", HealthCheckConfig{
+		Check: func(ctx context.Context) error {
+			<-release
+			return nil
+		},
+		Timeout: 10 * time.Millisecond,
+	})
+
+	start := time.Now()
+	code, resp := probe(t, s, "/readyz")
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", code)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("expected probe to return when the check times out")
+	}
+	if got := resp.Components["stuck"].Message; got != context.DeadlineExceeded.Error() {
+		t.Errorf("expected deadline message, got %q", got)
+	}
+}
+
+func TestServerHealth_CacheTTL(t *testing.T) {
+	var calls atomic.Int32
+
+	s := New(nil)
+	s.Health().RegisterWithConfig("db", HealthCheckConfig{
+		Check: func(ctx context.Context) error {
+			calls.Add(1)
+			return nil
+		},
+		CacheTTL: time.Minute,
+	})
+
+	probe(t, s, "/readyz")
+	probe(t, s, "/readyz")
+
+	if calls.Load() != 1 {
+		t.Errorf("expected check to run once, ran %d times", calls.Load())
+	}
+}
+
+func TestServerHealth_Startup(t *testing.T) {
+	var ready atomic.Bool
+
+	s := New(nil)
+	s.Health().RegisterWithConfig("migrations", HealthCheckConfig{
+		Check: func(ctx context.Context) error {
+			if !ready.Load() {
+				return errors.New("pending")
+			}
+			return nil
+		},
+		Probes: ProbeStartup,
+	})
+
+	code, _ := probe(t, s, "/startupz")
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 before startup, got %d", code)
+	}
+
+	ready.Store(true)
+	code, _ = probe(t, s, "/startupz")
+	if code != http.StatusOK {
+		t.Errorf("expected status 200 after startup, got %d", code)
+	}
+
+	// Once started, the probe stays up
+	ready.Store(false)
+	code, _ = probe(t, s, "/startupz")
+	if code != http.StatusOK {
+		t.Errorf("expected startup to remain up, got %d", code)
+	}
+}
+
+func TestServerHealth_DrainingOnShutdown(t *testing.T) {
+	s := New(nil)
+	health := s.Health()
+
+	var readyDuringStop int
+	s.OnStop(func(ctx context.Context, s *Server) {
+		readyDuringStop, _ = probe(t, s, "/readyz")
+	})
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !health.Draining() {
+		t.Error("expected registry to be draining after shutdown")
+	}
+	if readyDuringStop != http.StatusServiceUnavailable {
+		t.Errorf("expected readyz status 503 during shutdown, got %d", readyDuringStop)
+	}
+
+	code, resp := probe(t, s, "/readyz")
+	if code != http.StatusServiceUnavailable || resp.Status != HealthStatusDraining {
+		t.Errorf("expected draining 503, got %d %s", code, resp.Status)
+	}
+}
+
+func TestServerHealth_ShutdownDelay(t *testing.T) {
+	s := New(&Options{ShutdownDelay: 50 * time.Millisecond})
+	s.Health()
+
+	var waited time.Duration
+	start := time.Now()
+	s.OnStop(func(ctx context.Context, s *Server) {
+		waited = time.Since(start)
+	})
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if waited < 50*time.Millisecond {
+		t.Errorf("expected shutdown to wait for the delay, waited %v", waited)
+	}
+}
+
+func TestServerHealth_ShutdownDelayBoundedByContext(t *testing.T) {
+	s := New(&Options{ShutdownDelay: time.Minute})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	s.Shutdown(ctx)
+	if time.Since(start) > time.Second {
+		t.Error("expected the delay to end with the shutdown context")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -16,6 +17,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/kolosys/helix/logs"
 	"github.com/kolosys/helix/middleware"
 )
 
@@ -38,6 +40,21 @@ ______________________________________
 // This is an alias to middleware.Middleware for convenience.
 type Middleware = middleware.Middleware
 
+// Principal is the authenticated identity of a request, as set by the
+// BasicAuth, APIKey and JWT middleware and the auth/oidc package. This is
+// an alias to middleware.Principal for convenience.
+type Principal = middleware.Principal
+
+// Transaction is a database transaction opened by middleware.Tx. This is an
+// alias to middleware.Transaction for convenience.
+type Transaction = middleware.Transaction
+
+// TxFrom returns the request's transaction opened by middleware.Tx, or nil
+// if it has none. Assert it to the driver's type, such as *sql.Tx.
+func TxFrom(ctx context.Context) Transaction {
+	return middleware.TxFrom(ctx)
+}
+
 // Server is the main HTTP server for the Helix framework.
 type Server struct {
 	router     *Router
@@ -50,10 +67,19 @@ type Server struct {
 	writeTimeout    time.Duration
 	idleTimeout     time.Duration
 	gracePeriod     time.Duration
+	shutdownDelay   time.Duration
 	maxHeaderBytes  int
+	maxConnections  int
+	maxConnsPerIP   int
 	tlsCertFile     string
 	tlsKeyFile      string
+	enableUpgrade   bool
+	logLevelToggle  bool
 	tlsConfig       *tls.Config
+	h2c             bool
+	http2           *http.HTTP2Config
+	baseContext     func(ln net.Listener) context.Context
+	connContext     func(ctx context.Context, c net.Conn) context.Context
 	hideBanner      bool
 	banner          string
 	autoPort        bool
@@ -65,20 +91,40 @@ type Server struct {
 	// Lifecycle hooks
 	onStart []func(s *Server)
 	onStop  []func(ctx context.Context, s *Server)
+	waiters []func(ctx context.Context) error
+	modules []Module // registered with Register
+	started []Module // modules started by Run
+
+	// Cookies
+	cookieSecret []byte
+
+	// Responses
+	envelope EnvelopeFunc
+
+	// Background tasks started with Go
+	tasks *taskGroup
 
 	// Error handling
-	errorHandler ErrorHandler
+	errorConfig  *errorConfig
+	problemTypes []ProblemType
+
+	// Health checks
+	health *HealthRegistry
 
 	// Routing
-	basePath string // Base path prefix for all routes
+	basePath    string       // Base path prefix for all routes
+	grpcHandler http.Handler // Handler for gRPC requests, see MountGRPC
 
 	// State
-	once    sync.Once
-	handler http.Handler // Pre-compiled middleware chain
-	built   bool         // Whether the handler chain has been built
-
-	// Object pools for zero-allocation hot path
-	ctxPool sync.Pool
+	once        sync.Once
+	listener    net.Listener
+	connLimiter *limitListener
+	upgraded    chan struct{}
+	upgrade     sync.Once
+	logLevelMu  sync.Mutex
+	logLevel    logs.Level   // level restored by the next toggle
+	handler     http.Handler // Pre-compiled middleware chain
+	built       bool         // Whether the handler chain has been built
 }
 
 // New creates a new Server with the provided options.
@@ -98,19 +144,34 @@ func New(opts *Options) *Server {
 		writeTimeout:    opts.WriteTimeout,
 		idleTimeout:     opts.IdleTimeout,
 		gracePeriod:     opts.GracePeriod,
+		shutdownDelay:   opts.ShutdownDelay,
 		maxHeaderBytes:  opts.MaxHeaderBytes,
+		maxConnections:  opts.MaxConnections,
+		maxConnsPerIP:   opts.MaxConnectionsPerIP,
 		tlsCertFile:     opts.TLSCertFile,
 		tlsKeyFile:      opts.TLSKeyFile,
+		enableUpgrade:   opts.EnableUpgrade,
+		logLevelToggle:  opts.EnableLogLevelToggle,
 		tlsConfig:       opts.TLSConfig,
+		h2c:             opts.H2C,
+		http2:           opts.HTTP2,
+		baseContext:     opts.BaseContext,
+		connContext:     opts.ConnContext,
 		hideBanner:      opts.HideBanner,
 		banner:          opts.Banner,
-		errorHandler:    opts.ErrorHandler,
+		cookieSecret:    opts.CookieSecret,
+		envelope:        opts.ResponseEnvelope,
+		errorConfig:     &errorConfig{handler: opts.ErrorHandler, debug: opts.Debug},
 		basePath:        opts.BasePath,
 		autoPort:        opts.AutoPort,
 		maxPortAttempts: opts.MaxPortAttempts,
 		logOutput:       opts.LogOutput,
+		upgraded:        make(chan struct{}),
+		tasks:           newTaskGroup(),
 	}
 
+	s.router.warnOnly = opts.WarnRouteConflicts
+
 	if s.banner == "" && !s.hideBanner {
 		s.banner = fmt.Sprintf(banner, Version, website)
 	}
@@ -158,14 +219,18 @@ func convertToMiddleware(m any) (Middleware, error) {
 		return v, nil
 	case func(http.Handler) http.Handler:
 		return v, nil
+	case CtxMiddleware:
+		return AdaptCtx(v), nil
+	case func(*Ctx) error:
+		return AdaptCtx(v), nil
 	default:
-		return nil, fmt.Errorf("helix: middleware must be Middleware or func(http.Handler) http.Handler, got %T", m)
+		return nil, fmt.Errorf("helix: middleware must be Middleware, func(http.Handler) http.Handler or CtxMiddleware, got %T", m)
 	}
 }
 
 // Use adds middleware to the server's middleware chain.
 // Middleware is executed in the order it is added.
-// Accepts Middleware (helix.Middleware is an alias for middleware.Middleware) or func(http.Handler) http.Handler.
+// Accepts Middleware (helix.Middleware is an alias for middleware.Middleware), func(http.Handler) http.Handler, or CtxMiddleware.
 func (s *Server) Use(mw ...any) {
 	for _, m := range mw {
 		converted, err := convertToMiddleware(m)
@@ -193,10 +258,14 @@ func (s *Server) Build() {
 		handler = s.basePathMiddleware(handler)
 	}
 
-	// If a custom error handler is set, inject it into the request context
-	// This must be done before other middleware so handlers can access it
-	if s.errorHandler != nil {
-		handler = s.errorHandlerMiddleware(handler)
+	// Dispatch gRPC requests ahead of the base path check and the router
+	if s.grpcHandler != nil {
+		handler = s.grpcMiddleware(handler)
+	}
+
+	// In debug mode, render panics with source snippets
+	if s.errorConfig.debug {
+		handler = s.debugRecoverMiddleware(handler)
 	}
 
 	// Apply middleware in reverse order so first added is outermost
@@ -204,15 +273,47 @@ func (s *Server) Build() {
 		handler = s.middleware[i](handler)
 	}
 
+	// Tell CORS middleware about the routes of preflights and those with
+	// their own policy
+	handler = s.corsRoutesMiddleware(handler)
+
+	// If error handling is customized, inject the settings into the request context
+	// This must wrap all other middleware so Ctx middleware errors use them too
+	if s.errorConfig.active() {
+		handler = s.errorConfigMiddleware(handler)
+	}
+
+	// Make the response envelope available to typed handlers
+	if s.envelope != nil {
+		handler = WithEnvelope(s.envelope)(handler)
+	}
+
+	// Make the signed cookie secret available to Ctx helpers
+	if len(s.cookieSecret) > 0 {
+		handler = s.cookieSecretMiddleware(handler)
+	}
+
 	s.handler = handler
 	s.built = true
+}
 
-	// Initialize context pool
-	s.ctxPool = sync.Pool{
-		New: func() any {
-			return &Ctx{}
-		},
-	}
+// corsRoutesMiddleware tells CORS middleware the methods registered for
+// the paths of preflights and which cross-origin requests are for routes
+// with their own CORS policy, see WithRouteCORS.
+func (s *Server) corsRoutesMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			ctx := r.Context()
+			if r.Method == http.MethodOptions {
+				ctx = middleware.WithRouteMethods(ctx, s.router.allowedMethods)
+			}
+			if s.router.corsRoutes.Load() {
+				ctx = middleware.WithCORSOverride(ctx, s.router.ownsCORS)
+			}
+			r = r.WithContext(ctx)
+		}
+		next.ServeHTTP(w, r)
+	})
 }
 
 // basePathMiddleware validates that incoming requests start with the base path.
@@ -243,10 +344,10 @@ func (s *Server) basePathMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// errorHandlerMiddleware injects the error handler into the request context.
-func (s *Server) errorHandlerMiddleware(next http.Handler) http.Handler {
+// errorConfigMiddleware injects the error handling settings into the request context.
+func (s *Server) errorConfigMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		r = withErrorHandler(r, s.errorHandler)
+		r = withErrorConfig(r, s.errorConfig)
 		next.ServeHTTP(w, r)
 	})
 }
@@ -270,7 +371,8 @@ func (s *Server) Run(ctx context.Context) error {
 	}
 
 	// If auto port is enabled, find an available port
-	if s.autoPort {
+	// Inherited listeners already have their address bound
+	if s.autoPort && !hasInheritedListener() {
 		addr, err := findAvailableAddr(s.addr, s.maxPortAttempts)
 		if err != nil {
 			return fmt.Errorf("helix: failed to find available port: %w", err)
@@ -278,20 +380,28 @@ func (s *Server) Run(ctx context.Context) error {
 		s.addr = addr
 	}
 
-	s.httpServer = &http.Server{
-		Addr:           s.addr,
-		Handler:        s,
-		ReadTimeout:    s.readTimeout,
-		WriteTimeout:   s.writeTimeout,
-		IdleTimeout:    s.idleTimeout,
-		MaxHeaderBytes: s.maxHeaderBytes,
-		TLSConfig:      s.tlsConfig,
-	}
+	s.httpServer = s.newHTTPServer()
 
 	// Call onStart hooks
 	for _, fn := range s.onStart {
 		fn(s)
 	}
+	if err := s.startModules(ctx); err != nil {
+		return err
+	}
+
+	ln, err := s.listen()
+	if err != nil {
+		return errors.Join(err, s.stopModules(ctx))
+	}
+	s.listener = ln
+
+	// Enforce connection limits. The unwrapped listener is kept for upgrades.
+	if s.maxConnections > 0 || s.maxConnsPerIP > 0 {
+		tlsEnabled := s.tlsConfig != nil || (s.tlsCertFile != "" && s.tlsKeyFile != "")
+		s.connLimiter = newLimitListener(ln, s.maxConnections, s.maxConnsPerIP, !tlsEnabled)
+		ln = s.connLimiter
+	}
 
 	// Channel to receive server errors
 	errCh := make(chan error, 1)
@@ -300,11 +410,11 @@ func (s *Server) Run(ctx context.Context) error {
 	go func() {
 		var err error
 		if s.tlsCertFile != "" && s.tlsKeyFile != "" {
-			err = s.httpServer.ListenAndServeTLS(s.tlsCertFile, s.tlsKeyFile)
+			err = s.httpServer.ServeTLS(ln, s.tlsCertFile, s.tlsKeyFile)
 		} else if s.tlsConfig != nil {
-			err = s.httpServer.ListenAndServeTLS("", "")
+			err = s.httpServer.ServeTLS(ln, "", "")
 		} else {
-			err = s.httpServer.ListenAndServe()
+			err = s.httpServer.Serve(ln)
 		}
 
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -313,25 +423,86 @@ func (s *Server) Run(ctx context.Context) error {
 		close(errCh)
 	}()
 
+	// A process started by Upgrade tells its parent it can stop serving
+	notifyUpgradeReady()
+
 	// Wait for shutdown signal or context cancellation
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
 
-	select {
-	case err := <-errCh:
-		return err
-	case <-sigCh:
-		// Received shutdown signal
-	case <-ctx.Done():
-		// Context canceled
+	// Listen for the upgrade signal if enabled
+	upgradeCh := make(chan os.Signal, 1)
+	if s.enableUpgrade && upgradeSignal != nil {
+		signal.Notify(upgradeCh, upgradeSignal)
+		defer signal.Stop(upgradeCh)
+	}
+
+	// Listen for the log level toggle signal if enabled
+	toggleCh := make(chan os.Signal, 1)
+	if s.logLevelToggle {
+		signal.Notify(toggleCh, syscall.SIGHUP)
+		defer signal.Stop(toggleCh)
+	}
+
+wait:
+	for {
+		select {
+		case err := <-errCh:
+			return err
+		case <-sigCh:
+			// Received shutdown signal
+			break wait
+		case <-upgradeCh:
+			if err := s.Upgrade(); err != nil {
+				log.Printf("helix: upgrade failed: %v", err)
+			}
+		case <-toggleCh:
+			level := s.ToggleLogLevel()
+			log.Printf("helix: log level set to %s", level)
+		case <-s.upgraded:
+			// A new process has taken over the listener, drain this one
+			break wait
+		case <-ctx.Done():
+			// Context canceled
+			break wait
+		}
 	}
 
 	// Perform graceful shutdown
 	return s.Shutdown(context.Background())
 }
 
+// newHTTPServer creates the underlying http.Server from the server configuration.
+func (s *Server) newHTTPServer() *http.Server {
+	srv := &http.Server{
+		Addr:           s.addr,
+		Handler:        s,
+		ReadTimeout:    s.readTimeout,
+		WriteTimeout:   s.writeTimeout,
+		IdleTimeout:    s.idleTimeout,
+		MaxHeaderBytes: s.maxHeaderBytes,
+		TLSConfig:      s.tlsConfig,
+		HTTP2:          s.http2,
+		BaseContext:    s.taskBaseContext(s.baseContext),
+		ConnContext:    s.connContext,
+	}
+
+	// Serve HTTP/2 over cleartext connections in addition to the defaults
+	if s.h2c {
+		var protocols http.Protocols
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+		srv.Protocols = &protocols
+	}
+
+	return srv
+}
+
 // Shutdown gracefully shuts down the server without interrupting active connections.
-// It waits for the grace period for active connections to finish.
+// It waits for the grace period for active connections, background tasks
+// started with Go and shutdown waiters to finish.
 func (s *Server) Shutdown(ctx context.Context) error {
 	var err error
 	s.once.Do(func() {
@@ -339,20 +510,75 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		shutdownCtx, cancel := context.WithTimeout(ctx, s.gracePeriod)
 		defer cancel()
 
+		// Flip readiness so load balancers stop routing new traffic
+		if s.health != nil {
+			s.health.SetDraining(true)
+		}
+
+		// Keep serving while load balancers notice the server is draining
+		if s.shutdownDelay > 0 {
+			t := time.NewTimer(s.shutdownDelay)
+			select {
+			case <-t.C:
+			case <-shutdownCtx.Done():
+				t.Stop()
+			}
+		}
+
 		// Call onStop hooks
 		for _, fn := range s.onStop {
 			fn(shutdownCtx, s)
 		}
 
-		if s.httpServer == nil {
-			return
+		if s.httpServer != nil {
+			err = s.httpServer.Shutdown(shutdownCtx)
 		}
 
-		err = s.httpServer.Shutdown(shutdownCtx)
+		// Background work may still be running after the last response
+		err = errors.Join(err, s.drain(shutdownCtx))
+
+		// Modules release their resources last
+		err = errors.Join(err, s.stopModules(shutdownCtx))
 	})
 	return err
 }
 
+// drain waits for background tasks and shutdown waiters concurrently, up to
+// the deadline of ctx.
+func (s *Server) drain(ctx context.Context) error {
+	errs := make([]error, len(s.waiters)+1)
+	var wg sync.WaitGroup
+	wg.Add(len(errs))
+	go func() {
+		defer wg.Done()
+		errs[0] = s.tasks.wait(ctx)
+	}()
+	for i, fn := range s.waiters {
+		go func() {
+			defer wg.Done()
+			errs[i+1] = callWaiter(ctx, fn)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// callWaiter runs a shutdown waiter, giving up once ctx is done even if the
+// waiter ignores it.
+func callWaiter(ctx context.Context, fn func(ctx context.Context) error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- runTask(ctx, fn)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("helix: shutdown waiter did not finish: %w", ctx.Err())
+	}
+}
+
 // Addr returns the address the server is configured to listen on.
 func (s *Server) Addr() string {
 	return s.addr
@@ -370,3 +596,24 @@ func (s *Server) OnStart(fn func(s *Server)) {
 func (s *Server) OnStop(fn func(ctx context.Context, s *Server)) {
 	s.onStop = append(s.onStop, fn)
 }
+
+// RegisterShutdownWaiter registers a function that Shutdown waits on after
+// the server stops accepting requests, such as draining a job queue or
+// closing websocket hubs and SSE broadcasters. Waiters run concurrently with
+// a context whose deadline is the end of the grace period, and their errors
+// are returned from Shutdown and Run. Must be called before the server stops.
+//
+// Example:
+//
+//	s.RegisterShutdownWaiter(queue.Stop)
+func (s *Server) RegisterShutdownWaiter(fn func(ctx context.Context) error) {
+	s.waiters = append(s.waiters, fn)
+}
+
+// OnError registers a hook called for every error returned from a handler,
+// e.g. to report errors to an error tracker. Hooks run before the error
+// response is written. Server errors (5xx) are also logged through the
+// request's logger regardless of hooks. Must be called before the server starts.
+func (s *Server) OnError(hook ErrorHook) {
+	s.errorConfig.hooks = append(s.errorConfig.hooks, hook)
+}
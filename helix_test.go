@@ -2,10 +2,14 @@ package helix_test
 
 import (
 	"context"
+	"errors"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -85,7 +89,7 @@ func TestRouteRegistration(t *testing.T) {
 	methods := []struct {
 		name   string
 		method string
-		fn     func(s *Server, pattern string, handler http.HandlerFunc)
+		fn     func(s *Server, pattern string, handler http.HandlerFunc, opts ...RouteOption)
 	}{
 		{"GET", http.MethodGet, (*Server).GET},
 		{"POST", http.MethodPost, (*Server).POST},
@@ -414,6 +418,48 @@ func TestServerShutdown(t *testing.T) {
 	}
 }
 
+func TestRegisterShutdownWaiter(t *testing.T) {
+	s := New(&Options{GracePeriod: 100 * time.Millisecond})
+
+	var mu sync.Mutex
+	var drained []string
+	s.RegisterShutdownWaiter(func(ctx context.Context) error {
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		drained = append(drained, "queue")
+		mu.Unlock()
+		return nil
+	})
+	s.RegisterShutdownWaiter(func(ctx context.Context) error {
+		mu.Lock()
+		drained = append(drained, "hub")
+		mu.Unlock()
+		return errors.New("hub: close failed")
+	})
+	stuck := make(chan struct{})
+	defer close(stuck)
+	s.RegisterShutdownWaiter(func(ctx context.Context) error {
+		// Ignores ctx; Shutdown gives up at the end of the grace period
+		<-stuck
+		return nil
+	})
+
+	start := time.Now()
+	err := s.Shutdown(context.Background())
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected shutdown to stop waiting after the grace period, took %v", elapsed)
+	}
+	if err == nil || !strings.Contains(err.Error(), "hub: close failed") || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected waiter errors to be joined, got %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(drained) != 2 {
+		t.Errorf("expected both waiters to finish, got %v", drained)
+	}
+}
+
 func TestServerAddr(t *testing.T) {
 	s := New(&Options{Addr: ":9999"})
 	if s.Addr() != ":9999" {
@@ -1206,6 +1252,86 @@ func TestWithMaxHeaderBytes(t *testing.T) {
 	}
 }
 
+// Test for H2C option
+func TestWithH2C(t *testing.T) {
+	s := New(&Options{H2C: true})
+	s.GET("/proto", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	})
+
+	ts := httptest.NewUnstartedServer(nil)
+	ts.Config = s.NewHTTPServer()
+	ts.Start()
+	defer ts.Close()
+
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: &protocols}}
+
+	resp, err := client.Get(ts.URL + "/proto")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "HTTP/2.0" {
+		t.Errorf("expected HTTP/2.0, got %s", body)
+	}
+}
+
+// Test for HTTP2 option
+func TestWithHTTP2Config(t *testing.T) {
+	cfg := &http.HTTP2Config{
+		MaxConcurrentStreams: 500,
+		MaxReadFrameSize:     1 << 20,
+	}
+	s := New(&Options{HTTP2: cfg})
+
+	srv := s.NewHTTPServer()
+	if srv.HTTP2 != cfg {
+		t.Error("expected HTTP2 config to be passed to http.Server")
+	}
+	if srv.Protocols != nil {
+		t.Error("expected default protocols when H2C is disabled")
+	}
+}
+
+// Test for BaseContext and ConnContext options
+func TestWithBaseAndConnContext(t *testing.T) {
+	type ctxKey string
+
+	s := New(&Options{
+		BaseContext: func(ln net.Listener) context.Context {
+			return context.WithValue(context.Background(), ctxKey("db"), "pool")
+		},
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			return context.WithValue(ctx, ctxKey("tenant"), "acme")
+		},
+	})
+	s.GET("/values", func(w http.ResponseWriter, r *http.Request) {
+		db, _ := r.Context().Value(ctxKey("db")).(string)
+		tenant, _ := r.Context().Value(ctxKey("tenant")).(string)
+		w.Write([]byte(db + "," + tenant))
+	})
+
+	ts := httptest.NewUnstartedServer(nil)
+	ts.Config = s.NewHTTPServer()
+	ts.Start()
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/values")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "pool,acme" {
+		t.Errorf("expected 'pool,acme', got %q", body)
+	}
+}
+
 // Test static file serving pattern
 func TestStaticRoutePattern(t *testing.T) {
 	s := New(nil)
@@ -1512,3 +1638,87 @@ func TestStreamingResponse(t *testing.T) {
 		t.Errorf("expected 3 events, got body: %s", body)
 	}
 }
+
+// lifecycleModule records its lifecycle into events.
+type lifecycleModule struct {
+	name     string
+	events   *[]string
+	startErr error
+}
+
+func (m *lifecycleModule) Register(r RouteRegistrar) {
+	r.GET("/"+m.name, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(m.name))
+	})
+}
+
+func (m *lifecycleModule) OnStart(ctx context.Context) error {
+	*m.events = append(*m.events, "start "+m.name)
+	return m.startErr
+}
+
+func (m *lifecycleModule) OnStop(ctx context.Context) error {
+	*m.events = append(*m.events, "stop "+m.name)
+	return nil
+}
+
+// headerModule adds server-wide middleware.
+type headerModule struct{ ModuleFunc }
+
+func (headerModule) Middleware() []Middleware {
+	return []Middleware{func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Module", "auth")
+			next.ServeHTTP(w, r)
+		})
+	}}
+}
+
+func TestServerRegister(t *testing.T) {
+	var events []string
+	s := New(&Options{Addr: "127.0.0.1:0", HideBanner: true})
+	s.Register(
+		headerModule{func(r RouteRegistrar) {}},
+		&lifecycleModule{name: "billing", events: &events},
+		&lifecycleModule{name: "search", events: &events},
+	)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/billing", nil))
+	if rec.Body.String() != "billing" || rec.Header().Get("X-Module") != "auth" {
+		t.Fatalf("unexpected response %q %v", rec.Body.String(), rec.Header())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.OnStart(func(*Server) { events = append(events, "hook") })
+	runErr := make(chan error, 1)
+	go func() { runErr <- s.Run(ctx) }()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := <-runErr; err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"hook", "start billing", "start search", "stop search", "stop billing"}
+	if !slices.Equal(events, want) {
+		t.Fatalf("expected %v, got %v", want, events)
+	}
+}
+
+func TestServerRegisterStartFailure(t *testing.T) {
+	var events []string
+	s := New(&Options{Addr: "127.0.0.1:0", HideBanner: true})
+	s.Register(
+		&lifecycleModule{name: "billing", events: &events},
+		&lifecycleModule{name: "search", events: &events, startErr: errors.New("index missing")},
+	)
+
+	err := s.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "index missing") {
+		t.Fatalf("expected start error, got %v", err)
+	}
+	want := []string{"start billing", "start search", "stop billing"}
+	if !slices.Equal(events, want) {
+		t.Fatalf("expected %v, got %v", want, events)
+	}
+}
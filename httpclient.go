@@ -0,0 +1,23 @@
+package helix
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/kolosys/helix/middleware"
+)
+
+// HTTPClient returns an http.Client that adds the request ID and trace
+// headers of ctx to outgoing requests, so calls to other services can be
+// correlated with the incoming request. Requests carrying their own context
+// from a handler use that instead. Requires the RequestID middleware.
+//
+// Example:
+//
+//	s.GET("/orders/{id}", helix.HandleCtx(func(c *helix.Ctx) error {
+//	    resp, err := helix.HTTPClient(c.Context()).Get(inventoryURL)
+//	    ...
+//	}))
+func HTTPClient(ctx context.Context) *http.Client {
+	return &http.Client{Transport: middleware.ContextTransport(ctx, nil)}
+}
@@ -0,0 +1,42 @@
+package helix_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/kolosys/helix"
+	"github.com/kolosys/helix/middleware"
+)
+
+func TestHTTPClient(t *testing.T) {
+	var got string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(middleware.RequestIDHeader)
+	}))
+	defer upstream.Close()
+
+	s := New(nil)
+	s.Use(middleware.RequestID())
+	s.GET("/", HandleCtx(func(c *Ctx) error {
+		// Requests built without the handler's context still propagate
+		resp, err := HTTPClient(c.Context()).Get(upstream.URL)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return c.NoContent()
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-42")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got != "req-42" {
+		t.Errorf("expected request ID to propagate, got %q", got)
+	}
+}
@@ -0,0 +1,231 @@
+package helix
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// MessageCatalog translates user-facing messages.
+// Keys are problem type codes (e.g. "not_found") for problem titles, and the
+// untranslated text or format string for details and validation messages.
+type MessageCatalog interface {
+	// Message returns the translation of key for the language tag lang
+	// (e.g. "de" or "pt-BR"), or false if the catalog has none.
+	Message(lang, key string) (string, bool)
+}
+
+// Messages is a map-based MessageCatalog, keyed by language tag and then by
+// message key. Message matches tags exactly; the regional fallback is done
+// by the server's lookups (see SetMessageCatalog).
+type Messages map[string]map[string]string
+
+// Message implements MessageCatalog.
+func (m Messages) Message(lang, key string) (string, bool) {
+	msg, ok := m[lang][key]
+	return msg, ok
+}
+
+// DefaultMessages is the default English catalog. It lists every message
+// helix produces and serves as the template for other languages.
+var DefaultMessages = Messages{
+	"en": {
+		"bad_request":            "Bad Request",
+		"unauthorized":           "Unauthorized",
+		"forbidden":              "Forbidden",
+		"not_found":              "Not Found",
+		"method_not_allowed":     "Method Not Allowed",
+		"conflict":               "Conflict",
+		"gone":                   "Gone",
+		"payload_too_large":      "Payload Too Large",
+		"unsupported_media_type": "Unsupported Media Type",
+		"unprocessable_entity":   "Unprocessable Entity",
+		"too_many_requests":      "Too Many Requests",
+		"internal_error":         "Internal Server Error",
+		"not_implemented":        "Not Implemented",
+		"bad_gateway":            "Bad Gateway",
+		"service_unavailable":    "Service Unavailable",
+		"gateway_timeout":        "Gateway Timeout",
+
+		validationDetail: validationDetail,
+	},
+}
+
+// validationDetail is the detail of problems built from ValidationErrors.
+const validationDetail = "One or more validation errors occurred"
+
+// SetMessageCatalog localizes problem responses using catalog. The title,
+// detail and validation messages of each problem are translated into the
+// best language from the request's Accept-Language header that the
+// catalog or DefaultMessages knows, and Content-Language is set accordingly.
+// Regional tags fall back to their base language, so "de-AT" uses the "de"
+// messages if the catalog has no "de-AT" translation.
+// Messages without a translation are left unchanged.
+// Must be called before the server starts.
+//
+// Example:
+//
+//	s.SetMessageCatalog(helix.Messages{
+//	    "de": {
+//	        "not_found":      "Nicht gefunden",
+//	        "user not found": "Benutzer nicht gefunden",
+//	        "is required":    "ist erforderlich",
+//	    },
+//	})
+func (s *Server) SetMessageCatalog(catalog MessageCatalog) {
+	s.errorConfig.messages = catalog
+}
+
+// Translate translates key into the request's preferred language using the
+// server's message catalog, formatting the result with args if any.
+// It returns key, formatted with args, if no translation is available.
+func Translate(r *http.Request, key string, args ...any) string {
+	msg := key
+	if config := getErrorConfig(r); config != nil && config.messages != nil {
+		if m, _, ok := lookupMessage(config.messages, AcceptLanguages(r), key); ok {
+			msg = m
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// AcceptLanguages returns the language tags from the request's
+// Accept-Language header, ordered by preference. Tags with q=0 and the
+// wildcard "*" are omitted.
+func AcceptLanguages(r *http.Request) []string {
+	var tags []string
+	for _, spec := range parseAccept(r.Header.Get("Accept-Language")) {
+		if spec.q > 0 && spec.value != "*" {
+			tags = append(tags, spec.value)
+		}
+	}
+	return tags
+}
+
+// lookupMessage finds key in catalog for the first matching language,
+// trying each tag and then its base language, so catalogs only need exact
+// matches. It falls back to
+// DefaultMessages for each language before moving to the next.
+func lookupMessage(catalog MessageCatalog, langs []string, key string) (string, string, bool) {
+	for _, lang := range langs {
+		for _, tag := range languageCandidates(lang) {
+			if msg, ok := catalog.Message(tag, key); ok {
+				return msg, tag, true
+			}
+			if msg, ok := DefaultMessages.Message(tag, key); ok {
+				return msg, tag, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// languageCandidates returns lang followed by its base language, if any.
+func languageCandidates(lang string) []string {
+	if base, _, ok := strings.Cut(lang, "-"); ok {
+		return []string{lang, base}
+	}
+	return []string{lang}
+}
+
+// localizer translates the messages of a single response.
+type localizer struct {
+	catalog MessageCatalog
+	langs   []string
+	lang    string // language of the first translated message
+}
+
+// newLocalizer returns a localizer for r, or nil if no catalog is configured
+// or the request states no language preference.
+func newLocalizer(r *http.Request) *localizer {
+	config := getErrorConfig(r)
+	if config == nil || config.messages == nil {
+		return nil
+	}
+	langs := AcceptLanguages(r)
+	if len(langs) == 0 {
+		return nil
+	}
+	return &localizer{catalog: config.messages, langs: langs}
+}
+
+// translate returns the translation of key, or fallback if there is none.
+func (l *localizer) translate(key, fallback string) string {
+	msg, lang, ok := lookupMessage(l.catalog, l.langs, key)
+	if !ok {
+		return fallback
+	}
+	if l.lang == "" {
+		l.lang = lang
+	}
+	return msg
+}
+
+// problem translates the title and detail of p.
+func (l *localizer) problem(p Problem) Problem {
+	if code, ok := strings.CutPrefix(p.Type, problemTypePrefix); ok && code != "" {
+		p.Title = l.translate(code, l.translate(p.Title, p.Title))
+	} else {
+		p.Title = l.translate(p.Title, p.Title)
+	}
+	if p.Detail != "" {
+		p.Detail = l.translate(p.Detail, p.Detail)
+	}
+	return p
+}
+
+// fieldErrors translates validation messages. Messages added with Addf, as
+// recorded in formats, are looked up by their format string and formatted
+// after translation.
+func (l *localizer) fieldErrors(errs []FieldError, formats []fieldFormat) []FieldError {
+	result := make([]FieldError, len(errs))
+	for i, fe := range errs {
+		result[i] = fe
+		if i >= len(formats) || formats[i].format == "" {
+			result[i].Message = l.translate(fe.Message, fe.Message)
+			continue
+		}
+		format := l.translate(formats[i].format, formats[i].format)
+		result[i].Message = fmt.Sprintf(format, formats[i].args...)
+	}
+	return result
+}
+
+// setContentLanguage sets Content-Language if any message was translated.
+func (l *localizer) setContentLanguage(w http.ResponseWriter) {
+	if l.lang != "" {
+		w.Header().Set("Content-Language", l.lang)
+	}
+}
+
+// prepareProblem localizes p for the request and applies the problem type base.
+func prepareProblem(w http.ResponseWriter, r *http.Request, p Problem) Problem {
+	config := getErrorConfig(r)
+	if config == nil {
+		return p
+	}
+	if l := newLocalizer(r); l != nil {
+		p = l.problem(p)
+		l.setContentLanguage(w)
+	}
+	p.Type = resolveProblemType(config.typeBase, p.Type)
+	return p
+}
+
+// prepareValidationProblem is prepareProblem for validation problems.
+func prepareValidationProblem(w http.ResponseWriter, r *http.Request, p ValidationProblem) ValidationProblem {
+	config := getErrorConfig(r)
+	if config == nil {
+		return p
+	}
+	if l := newLocalizer(r); l != nil {
+		p.Problem = l.problem(p.Problem)
+		p.Errors = l.fieldErrors(p.Errors, p.formats)
+		l.setContentLanguage(w)
+	}
+	p.Type = resolveProblemType(config.typeBase, p.Type)
+	return p
+}
@@ -0,0 +1,135 @@
+package helix_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	. "github.com/kolosys/helix"
+)
+
+func TestAcceptLanguages(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{"", nil},
+		{"de", []string{"de"}},
+		{"fr;q=0.5, de-AT, en;q=0.8", []string{"de-AT", "en", "fr"}},
+		{"*, es;q=0, it", []string{"it"}},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Language", tt.header)
+		got := AcceptLanguages(req)
+		if len(got) == 0 && len(tt.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("AcceptLanguages(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func newLocalizedServer() *Server {
+	s := New(nil)
+	s.SetMessageCatalog(Messages{
+		"de": {
+			"not_found":                              "Nicht gefunden",
+			"unprocessable_entity":                   "Nicht verarbeitbar",
+			"user not found":                         "Benutzer nicht gefunden",
+			"is required":                            "ist erforderlich",
+			"must be at least %d":                    "muss mindestens %d sein",
+			"One or more validation errors occurred": "Validierung fehlgeschlagen",
+		},
+	})
+	s.GET("/missing", HandleCtx(func(c *Ctx) error {
+		return NotFoundf("user not found")
+	}))
+	s.GET("/invalid", HandleCtx(func(c *Ctx) error {
+		v := NewValidationErrors()
+		v.Add("name", "is required")
+		v.Addf("age", "must be at least %d", 18)
+		return v
+	}))
+	s.GET("/greet", func(w http.ResponseWriter, r *http.Request) {
+		Text(w, http.StatusOK, Translate(r, "is required"))
+	})
+	return s
+}
+
+func TestSetMessageCatalog(t *testing.T) {
+	s := newLocalizedServer()
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req.Header.Set("Accept-Language", "de-AT, en;q=0.5")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	var p Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatalf("failed to decode problem: %v", err)
+	}
+	if p.Title != "Nicht gefunden" || p.Detail != "Benutzer nicht gefunden" {
+		t.Errorf("expected German problem, got %+v", p)
+	}
+	if got := rec.Header().Get("Content-Language"); got != "de" {
+		t.Errorf("expected Content-Language de, got %q", got)
+	}
+
+	// Unknown languages keep the original messages
+	req = httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req.Header.Set("Accept-Language", "ja")
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	p = Problem{}
+	json.Unmarshal(rec.Body.Bytes(), &p)
+	if p.Title != "Not Found" || p.Detail != "user not found" {
+		t.Errorf("expected untranslated problem, got %+v", p)
+	}
+	if got := rec.Header().Get("Content-Language"); got != "" {
+		t.Errorf("expected no Content-Language, got %q", got)
+	}
+}
+
+func TestSetMessageCatalog_Validation(t *testing.T) {
+	s := newLocalizedServer()
+
+	req := httptest.NewRequest(http.MethodGet, "/invalid", nil)
+	req.Header.Set("Accept-Language", "de")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	var p ValidationProblem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatalf("failed to decode problem: %v", err)
+	}
+	if p.Title != "Nicht verarbeitbar" || p.Detail != "Validierung fehlgeschlagen" {
+		t.Errorf("expected German problem, got %+v", p.Problem)
+	}
+	if len(p.Errors) != 2 || p.Errors[0].Message != "ist erforderlich" || p.Errors[1].Message != "muss mindestens 18 sein" {
+		t.Errorf("expected German field errors, got %+v", p.Errors)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	s := newLocalizedServer()
+
+	req := httptest.NewRequest(http.MethodGet, "/greet", nil)
+	req.Header.Set("Accept-Language", "de")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	if rec.Body.String() != "ist erforderlich" {
+		t.Errorf("expected translated text, got %q", rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	if got := Translate(req, "must be at least %d", 3); got != "must be at least 3" {
+		t.Errorf("expected formatted key without catalog, got %q", got)
+	}
+}
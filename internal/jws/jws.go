@@ -0,0 +1,100 @@
+// Package jws verifies the signatures of JSON Web Signatures, shared by
+// the JWT middleware and the OIDC client.
+package jws
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"strings"
+)
+
+// Verify checks sig over signed for the JWS algorithm alg (RFC 7518): a
+// []byte secret for HS256, HS384 and HS512, an *rsa.PublicKey for RS* and
+// PS*, an *ecdsa.PublicKey for ES* and an ed25519.PublicKey for EdDSA. The
+// key must be of the algorithm's type, so an RSA public key cannot be used
+// as an HMAC secret.
+func Verify(alg string, key any, signed string, sig []byte) error {
+	if alg == "EdDSA" {
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(pub, []byte(signed), sig) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+	if len(alg) != 5 {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+
+	var h crypto.Hash
+	var newHash func() hash.Hash
+	switch alg[2:] {
+	case "256":
+		h, newHash = crypto.SHA256, sha256.New
+	case "384":
+		h, newHash = crypto.SHA384, sha512.New384
+	case "512":
+		h, newHash = crypto.SHA512, sha512.New
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+
+	if strings.HasPrefix(alg, "HS") {
+		secret, ok := key.([]byte)
+		if !ok {
+			return fmt.Errorf("key does not match algorithm %q", alg)
+		}
+		mac := hmac.New(newHash, secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), sig) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+
+	d := newHash()
+	d.Write([]byte(signed))
+	digest := d.Sum(nil)
+
+	switch alg[:2] {
+	case "RS", "PS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key does not match algorithm %q", alg)
+		}
+		var err error
+		if alg[0] == 'R' {
+			err = rsa.VerifyPKCS1v15(pub, h, digest, sig)
+		} else {
+			err = rsa.VerifyPSS(pub, h, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+		if err != nil {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case "ES":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key does not match algorithm %q", alg)
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return errors.New("invalid signature")
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported algorithm %q", alg)
+}
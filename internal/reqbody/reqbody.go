@@ -0,0 +1,30 @@
+// Package reqbody reads request bodies without consuming them, shared by the
+// mirror middleware and the replay recorder.
+package reqbody
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// Tee reads the body of r, up to limit bytes, and replaces it with one
+// replaying what was read. It reports false, after restoring the body, if
+// the body is larger than limit or fails to read.
+func Tee(r *http.Request, limit int64) ([]byte, bool) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, true
+	}
+	if r.ContentLength > limit {
+		return nil, false
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+	if err != nil || int64(len(body)) > limit {
+		return nil, false
+	}
+	return body, true
+}
@@ -0,0 +1,456 @@
+// Package jobs provides an in-process background job queue for the Helix
+// framework, with a worker pool, retries, panic recovery and graceful drain
+// on server shutdown.
+//
+// Jobs are plain structs. Handlers are registered per job type, and jobs are
+// serialized to JSON so that the queue can be backed by a persistent Store
+// such as Redis or SQL.
+//
+// Example:
+//
+//	type SendEmail struct {
+//	    To      string `json:"to"`
+//	    Subject string `json:"subject"`
+//	}
+//
+//	q := jobs.New(jobs.DefaultConfig())
+//	jobs.Handle(q, func(ctx context.Context, job SendEmail) error {
+//	    return mailer.Send(ctx, job.To, job.Subject)
+//	})
+//	jobs.Attach(s, q)
+//
+//	s.POST("/signup", func(w http.ResponseWriter, r *http.Request) {
+//	    // ...
+//	    jobs.Enqueue(r.Context(), SendEmail{To: user.Email, Subject: "Welcome"})
+//	})
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/kolosys/helix"
+)
+
+var (
+	// ErrNoQueue is returned by Enqueue when the context carries no queue.
+	ErrNoQueue = errors.New("helix/jobs: no queue in context")
+
+	// ErrStopped is returned when enqueueing to a stopped queue.
+	ErrStopped = errors.New("helix/jobs: queue stopped")
+
+	// ErrNoHandler is returned when enqueueing a job type without a handler.
+	ErrNoHandler = errors.New("helix/jobs: no handler for job type")
+)
+
+// Named can be implemented by jobs to set their type name. By default the
+// name is the Go type name, e.g. "main.SendEmail".
+type Named interface {
+	JobName() string
+}
+
+// Envelope is a serialized job as held by a Store.
+type Envelope struct {
+	// ID uniquely identifies the job.
+	ID string `json:"id"`
+
+	// Type is the job type name.
+	Type string `json:"type"`
+
+	// Payload is the JSON-encoded job.
+	Payload json.RawMessage `json:"payload"`
+
+	// Attempt is the number of times the job has been run.
+	Attempt int `json:"attempt"`
+
+	// EnqueuedAt is when the job was first enqueued.
+	EnqueuedAt time.Time `json:"enqueued_at"`
+
+	// RunAt is the earliest time the job may run.
+	RunAt time.Time `json:"run_at"`
+}
+
+// Config configures a Queue.
+type Config struct {
+	// Workers is the number of jobs run concurrently.
+	// Default: 4
+	Workers int
+
+	// MaxAttempts is the number of times a failing job is run before it is
+	// given up on.
+	// Default: 3
+	MaxAttempts int
+
+	// Backoff returns the delay before retrying a job that failed its
+	// attempt-th run.
+	// Default: exponential, starting at 1s
+	Backoff func(attempt int) time.Duration
+
+	// Timeout limits each run of a job. Zero means no limit.
+	// Default: 0
+	Timeout time.Duration
+
+	// Store holds pending jobs.
+	// Default: NewMemoryStore()
+	Store Store
+
+	// PollInterval is how often idle workers check the store for jobs
+	// pushed by other processes or becoming due.
+	// Default: 1s
+	PollInterval time.Duration
+
+	// OnError is called when a job fails its last attempt.
+	// Default: logs the failure
+	OnError func(e Envelope, err error)
+}
+
+// DefaultConfig returns the default queue configuration.
+func DefaultConfig() Config {
+	return Config{
+		Workers:      4,
+		MaxAttempts:  3,
+		Backoff:      exponentialBackoff,
+		PollInterval: time.Second,
+	}
+}
+
+// exponentialBackoff waits 1s, 2s, 4s, ... between attempts.
+func exponentialBackoff(attempt int) time.Duration {
+	return time.Second << min(attempt-1, 10)
+}
+
+// handlerFunc runs a serialized job.
+type handlerFunc func(ctx context.Context, payload json.RawMessage) error
+
+// Queue runs jobs on a pool of workers.
+type Queue struct {
+	config Config
+
+	mu       sync.RWMutex
+	handlers map[string]handlerFunc
+	started  bool
+	stopped  bool
+
+	wake    chan struct{}
+	stop    chan struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+}
+
+// New creates a queue. Register handlers with Handle and start it with
+// Start, or attach it to a server with Attach.
+func New(config Config) *Queue {
+	if config.Workers <= 0 {
+		config.Workers = 4
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 3
+	}
+	if config.Backoff == nil {
+		config.Backoff = exponentialBackoff
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryStore()
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+	if config.OnError == nil {
+		config.OnError = func(e Envelope, err error) {
+			log.Printf("helix/jobs: %s job %s failed after %d attempts: %v", e.Type, e.ID, e.Attempt, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Queue{
+		config:   config,
+		handlers: make(map[string]handlerFunc),
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Handle registers the handler for jobs of type T, which must be concrete:
+// jobs are enqueued by their own types and decoded into T.
+// Panics if T is an interface or a handler for T is already registered.
+func Handle[T any](q *Queue, fn func(ctx context.Context, job T) error) {
+	if t := reflect.TypeFor[T](); t.Kind() == reflect.Interface {
+		panic("helix/jobs: job type must be concrete, got " + t.String())
+	}
+	var zero T
+	name := jobName(zero)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.handlers[name]; ok {
+		panic("helix/jobs: handler already registered for " + name)
+	}
+	q.handlers[name] = func(ctx context.Context, payload json.RawMessage) error {
+		var job T
+		if err := json.Unmarshal(payload, &job); err != nil {
+			return fmt.Errorf("helix/jobs: decode %s: %w", name, err)
+		}
+		return fn(ctx, job)
+	}
+}
+
+// Enqueue adds a job to the queue. The job must be JSON-serializable and
+// have a registered handler.
+func (q *Queue) Enqueue(ctx context.Context, job any) error {
+	return q.EnqueueAt(ctx, job, time.Time{})
+}
+
+// EnqueueIn adds a job that runs no earlier than delay from now.
+func (q *Queue) EnqueueIn(ctx context.Context, job any, delay time.Duration) error {
+	return q.EnqueueAt(ctx, job, time.Now().Add(delay))
+}
+
+// EnqueueAt adds a job that runs no earlier than at.
+func (q *Queue) EnqueueAt(ctx context.Context, job any, at time.Time) error {
+	name := jobName(job)
+
+	q.mu.RLock()
+	_, ok := q.handlers[name]
+	stopped := q.stopped
+	q.mu.RUnlock()
+
+	if stopped {
+		return ErrStopped
+	}
+	if !ok {
+		return fmt.Errorf("%w %s", ErrNoHandler, name)
+	}
+
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("helix/jobs: encode %s: %w", name, err)
+	}
+
+	now := time.Now()
+	if at.IsZero() {
+		at = now
+	}
+	e := Envelope{
+		ID:         newID(),
+		Type:       name,
+		Payload:    payload,
+		EnqueuedAt: now,
+		RunAt:      at,
+	}
+	if err := q.config.Store.Push(ctx, e); err != nil {
+		return err
+	}
+	q.notify()
+	return nil
+}
+
+// Start starts the workers. Calling Start more than once has no effect.
+func (q *Queue) Start() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.started || q.stopped {
+		return
+	}
+	q.started = true
+
+	for i := 0; i < q.config.Workers; i++ {
+		q.workers.Add(1)
+		go q.work()
+	}
+}
+
+// Stop stops accepting jobs and waits for the workers to finish the jobs
+// that are due. If ctx expires first, running jobs are canceled and
+// ctx.Err() is returned. Jobs left in the store, such as scheduled retries,
+// are not run.
+func (q *Queue) Stop(ctx context.Context) error {
+	q.mu.Lock()
+	if q.stopped {
+		q.mu.Unlock()
+		return nil
+	}
+	q.stopped = true
+	close(q.stop)
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.cancel()
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Middleware returns middleware that makes the queue available to the
+// package-level Enqueue through the request context.
+func (q *Queue) Middleware() helix.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(WithQueue(r.Context(), q)))
+		})
+	}
+}
+
+// Attach ties q to the server's lifecycle: the workers start with the
+// server, pending jobs are drained during graceful shutdown once the
+// in-flight requests are done, so they can still enqueue, and handlers can
+// call Enqueue with the request context. Shutdown errors are returned from
+// Server.Shutdown.
+func Attach(s *helix.Server, q *Queue) {
+	s.Use(q.Middleware())
+	s.OnStart(func(s *helix.Server) {
+		q.Start()
+	})
+	s.RegisterShutdownWaiter(q.Stop)
+}
+
+type queueKey struct{}
+
+// WithQueue returns a copy of ctx carrying q.
+func WithQueue(ctx context.Context, q *Queue) context.Context {
+	return context.WithValue(ctx, queueKey{}, q)
+}
+
+// FromContext returns the queue carried by ctx, or nil.
+func FromContext(ctx context.Context) *Queue {
+	q, _ := ctx.Value(queueKey{}).(*Queue)
+	return q
+}
+
+// Enqueue adds a job to the queue carried by ctx.
+// Returns ErrNoQueue if ctx carries no queue.
+func Enqueue(ctx context.Context, job any) error {
+	q := FromContext(ctx)
+	if q == nil {
+		return ErrNoQueue
+	}
+	return q.Enqueue(ctx, job)
+}
+
+// notify wakes an idle worker.
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// work runs jobs until the queue is stopped and no due jobs remain.
+func (q *Queue) work() {
+	defer q.workers.Done()
+
+	ticker := time.NewTicker(q.config.PollInterval)
+	defer ticker.Stop()
+
+	for q.ctx.Err() == nil {
+		e, ok, err := q.config.Store.Pop(q.ctx)
+		if err != nil && q.ctx.Err() == nil {
+			log.Printf("helix/jobs: store error: %v", err)
+		}
+		if ok {
+			q.run(e)
+			continue
+		}
+
+		select {
+		case <-q.stop:
+			return
+		default:
+		}
+
+		select {
+		case <-q.wake:
+		case <-ticker.C:
+		case <-q.stop:
+		}
+	}
+}
+
+// run executes a job and schedules a retry or reports the failure.
+func (q *Queue) run(e Envelope) {
+	e.Attempt++
+
+	q.mu.RLock()
+	handler, ok := q.handlers[e.Type]
+	q.mu.RUnlock()
+
+	var err error
+	if !ok {
+		err = fmt.Errorf("%w %s", ErrNoHandler, e.Type)
+	} else {
+		err = q.call(handler, e)
+	}
+	if err == nil {
+		return
+	}
+
+	if e.Attempt >= q.config.MaxAttempts || q.ctx.Err() != nil {
+		q.config.OnError(e, err)
+		return
+	}
+
+	e.RunAt = time.Now().Add(q.config.Backoff(e.Attempt))
+	if err := q.config.Store.Push(q.ctx, e); err != nil {
+		q.config.OnError(e, err)
+	}
+}
+
+// call runs handler with panic recovery and the configured timeout.
+func (q *Queue) call(handler handlerFunc, e Envelope) (err error) {
+	ctx := q.ctx
+	if q.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, q.config.Timeout)
+		defer cancel()
+	}
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("helix/jobs: panic: %v\n%s", rec, debug.Stack())
+		}
+	}()
+
+	return handler(ctx, e.Payload)
+}
+
+// jobName returns the type name of job, or "<nil>" for nil.
+func jobName(job any) string {
+	if n, ok := job.(Named); ok {
+		return n.JobName()
+	}
+	if job == nil {
+		return "<nil>"
+	}
+	return reflect.TypeOf(job).String()
+}
+
+// newID returns a random job ID.
+func newID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,253 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kolosys/helix"
+	. "github.com/kolosys/helix/jobs"
+)
+
+type sendEmail struct {
+	To string `json:"to"`
+}
+
+type renamedJob struct{}
+
+func (renamedJob) JobName() string { return "renamed" }
+
+func testConfig() Config {
+	config := DefaultConfig()
+	config.PollInterval = 5 * time.Millisecond
+	config.Backoff = func(int) time.Duration { return time.Millisecond }
+	return config
+}
+
+func TestQueue_RunsJobs(t *testing.T) {
+	q := New(testConfig())
+
+	var mu sync.Mutex
+	var got []string
+	Handle(q, func(ctx context.Context, job sendEmail) error {
+		mu.Lock()
+		got = append(got, job.To)
+		mu.Unlock()
+		return nil
+	})
+
+	q.Start()
+	for _, to := range []string{"a@example.com", "b@example.com"} {
+		if err := q.Enqueue(context.Background(), sendEmail{To: to}); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+	if err := q.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	if len(got) != 2 {
+		t.Errorf("expected 2 jobs to run before Stop returned, got %v", got)
+	}
+}
+
+func TestQueue_RetriesAndRecoversPanics(t *testing.T) {
+	config := testConfig()
+	config.MaxAttempts = 3
+
+	var failed atomic.Value
+	config.OnError = func(e Envelope, err error) { failed.Store(e) }
+	q := New(config)
+
+	var runs atomic.Int32
+	Handle(q, func(ctx context.Context, job sendEmail) error {
+		if runs.Add(1) == 1 {
+			panic("boom")
+		}
+		return errors.New("smtp down")
+	})
+
+	q.Start()
+	defer q.Stop(context.Background())
+	q.Enqueue(context.Background(), sendEmail{To: "a@example.com"})
+
+	deadline := time.Now().Add(time.Second)
+	for failed.Load() == nil && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	e, ok := failed.Load().(Envelope)
+	if !ok {
+		t.Fatal("expected OnError to be called")
+	}
+	if e.Attempt != 3 || runs.Load() != 3 {
+		t.Errorf("expected 3 attempts, got envelope attempt %d and %d runs", e.Attempt, runs.Load())
+	}
+}
+
+func TestQueue_EnqueueErrors(t *testing.T) {
+	q := New(testConfig())
+	Handle(q, func(ctx context.Context, job renamedJob) error { return nil })
+
+	if err := q.Enqueue(context.Background(), sendEmail{}); !errors.Is(err, ErrNoHandler) {
+		t.Errorf("expected ErrNoHandler, got %v", err)
+	}
+	if err := Enqueue(context.Background(), renamedJob{}); !errors.Is(err, ErrNoQueue) {
+		t.Errorf("expected ErrNoQueue, got %v", err)
+	}
+
+	// Nil jobs have no handler, and interface types cannot have one
+	if err := q.Enqueue(context.Background(), nil); !errors.Is(err, ErrNoHandler) {
+		t.Errorf("expected ErrNoHandler for a nil job, got %v", err)
+	}
+	func() {
+		defer func() {
+			if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "must be concrete") {
+				t.Errorf("expected a panic for an interface job type, got %v", r)
+			}
+		}()
+		Handle(q, func(ctx context.Context, job fmt.Stringer) error { return nil })
+	}()
+
+	q.Stop(context.Background())
+	if err := q.Enqueue(context.Background(), renamedJob{}); !errors.Is(err, ErrStopped) {
+		t.Errorf("expected ErrStopped, got %v", err)
+	}
+}
+
+func TestQueue_StopDeadlineCancelsJobs(t *testing.T) {
+	q := New(testConfig())
+
+	started := make(chan struct{})
+	Handle(q, func(ctx context.Context, job sendEmail) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	q.Start()
+	q.Enqueue(context.Background(), sendEmail{})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := q.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestAttach(t *testing.T) {
+	s := helix.New(nil)
+	q := New(testConfig())
+
+	done := make(chan string, 1)
+	Handle(q, func(ctx context.Context, job sendEmail) error {
+		done <- job.To
+		return nil
+	})
+	Attach(s, q)
+	q.Start()
+	defer q.Stop(context.Background())
+
+	s.POST("/signup", func(w http.ResponseWriter, r *http.Request) {
+		if err := Enqueue(r.Context(), sendEmail{To: "new@example.com"}); err != nil {
+			t.Errorf("Enqueue: %v", err)
+		}
+	})
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/signup", nil))
+
+	select {
+	case to := <-done:
+		if to != "new@example.com" {
+			t.Errorf("unexpected job %q", to)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("job did not run")
+	}
+}
+
+func TestAttach_Shutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	s := helix.New(&helix.Options{Addr: addr, HideBanner: true})
+	q := New(testConfig())
+	ran := make(chan struct{}, 1)
+	Handle(q, func(ctx context.Context, job sendEmail) error {
+		ran <- struct{}{}
+		return nil
+	})
+	Attach(s, q)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	enqueued := make(chan error, 1)
+	s.POST("/signup", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		enqueued <- Enqueue(r.Context(), sendEmail{To: "late@example.com"})
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- s.Run(ctx) }()
+	go func() {
+		for {
+			resp, err := http.Post("http://"+addr+"/signup", "", nil)
+			if err == nil {
+				resp.Body.Close()
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	<-started
+
+	// Requests in flight during shutdown can still enqueue, and their jobs run
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	if err := <-enqueued; err != nil {
+		t.Fatalf("expected an in-flight request to enqueue, got %v", err)
+	}
+	if err := <-runErr; err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+	select {
+	case <-ran:
+	default:
+		t.Error("expected the job to be drained")
+	}
+}
+
+func TestMemoryStore_OrdersByRunAt(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	store.Push(context.Background(), Envelope{ID: "later", RunAt: now.Add(-time.Second)})
+	store.Push(context.Background(), Envelope{ID: "first", RunAt: now.Add(-time.Minute)})
+	store.Push(context.Background(), Envelope{ID: "future", RunAt: now.Add(time.Hour)})
+
+	for _, want := range []string{"first", "later"} {
+		e, ok, _ := store.Pop(context.Background())
+		if !ok || e.ID != want {
+			t.Errorf("expected %s, got %s (ok=%v)", want, e.ID, ok)
+		}
+	}
+	if _, ok, _ := store.Pop(context.Background()); ok {
+		t.Error("expected job scheduled in the future not to be due")
+	}
+	if store.Len() != 1 {
+		t.Errorf("expected 1 pending job, got %d", store.Len())
+	}
+}
@@ -0,0 +1,71 @@
+package jobs
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// Store holds pending jobs. Implementations backed by Redis or SQL allow
+// jobs to survive restarts and be shared between processes.
+type Store interface {
+	// Push adds a job to the store.
+	Push(ctx context.Context, e Envelope) error
+
+	// Pop removes and returns the job with the earliest RunAt that is due.
+	// It returns false if no job is due, without blocking.
+	Pop(ctx context.Context) (Envelope, bool, error)
+}
+
+// MemoryStore is an in-process Store. Jobs are lost when the process exits.
+type MemoryStore struct {
+	mu   sync.Mutex
+	jobs envelopeHeap
+}
+
+// NewMemoryStore creates an empty in-process store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Push implements Store.
+func (m *MemoryStore) Push(ctx context.Context, e Envelope) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	heap.Push(&m.jobs, e)
+	return nil
+}
+
+// Pop implements Store.
+func (m *MemoryStore) Pop(ctx context.Context) (Envelope, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.jobs) == 0 || m.jobs[0].RunAt.After(time.Now()) {
+		return Envelope{}, false, nil
+	}
+	return heap.Pop(&m.jobs).(Envelope), true, nil
+}
+
+// Len returns the number of pending jobs, including jobs not yet due.
+func (m *MemoryStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.jobs)
+}
+
+// envelopeHeap orders envelopes by RunAt.
+type envelopeHeap []Envelope
+
+func (h envelopeHeap) Len() int           { return len(h) }
+func (h envelopeHeap) Less(i, j int) bool { return h[i].RunAt.Before(h[j].RunAt) }
+func (h envelopeHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *envelopeHeap) Push(x any)        { *h = append(*h, x.(Envelope)) }
+
+func (h *envelopeHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
@@ -0,0 +1,4 @@
+package jwe
+
+// KeyWrap exports keyWrap for testing.
+var KeyWrap = keyWrap
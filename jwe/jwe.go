@@ -0,0 +1,503 @@
+// Package jwe encrypts and decrypts messages in the compact JSON Web
+// Encryption format (RFC 7516), for integrations that require message-level
+// encryption on top of TLS, such as payment and health-data APIs.
+//
+// Supported key management algorithms are "dir", "A128KW", "A192KW",
+// "A256KW", "RSA-OAEP" and "RSA-OAEP-256"; supported content encryption
+// algorithms are "A128GCM", "A192GCM" and "A256GCM". Compressed ("zip") and
+// critical ("crit") headers are rejected.
+//
+// Middleware decrypts request bodies and encrypts response bodies on the
+// routes that need it:
+//
+//	s.POST("/payments", createPayment, helix.WithRouteMiddleware(jwe.Middleware(jwe.Config{
+//	    DecryptionKey: func(ctx context.Context, h jwe.Header) (any, error) {
+//	        return keys.Private(ctx, h.KeyID) // *rsa.PrivateKey
+//	    },
+//	    EncryptionKey: func(r *http.Request) (jwe.Recipient, error) {
+//	        return jwe.Recipient{Alg: "RSA-OAEP-256", Key: partnerKey, KeyID: "partner-2024"}, nil
+//	    },
+//	    Required: true,
+//	})))
+package jwe
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kolosys/helix"
+)
+
+// ContentType is the media type of compact JWE messages.
+const ContentType = "application/jose"
+
+// ErrDecrypt is returned by Decrypt for a message that is malformed, was
+// tampered with or was not encrypted for the key. The cause is not told
+// apart, so that callers cannot be used as a decryption oracle.
+var ErrDecrypt = errors.New("helix/jwe: message cannot be decrypted")
+
+// Header is the protected header of a JWE message.
+type Header struct {
+	// Alg is the key management algorithm, such as "RSA-OAEP-256".
+	Alg string `json:"alg"`
+
+	// Enc is the content encryption algorithm, such as "A256GCM".
+	Enc string `json:"enc"`
+
+	// KeyID identifies the recipient's key.
+	KeyID string `json:"kid,omitempty"`
+
+	// ContentType is the media type of the plaintext.
+	ContentType string `json:"cty,omitempty"`
+}
+
+// Recipient is the key a message is encrypted for.
+type Recipient struct {
+	// Alg is the key management algorithm.
+	// Default: "dir" for a []byte key, "RSA-OAEP-256" for an RSA key
+	Alg string
+
+	// Enc is the content encryption algorithm.
+	// Default: "A256GCM"
+	Enc string
+
+	// Key is a []byte for "dir", of the content encryption key's size, and
+	// for "A128KW", "A192KW" and "A256KW", of 16, 24 and 32 bytes; or an
+	// *rsa.PublicKey for "RSA-OAEP" and "RSA-OAEP-256".
+	Key any
+
+	// KeyID, if set, is sent as the "kid" header, so the recipient can
+	// pick its key.
+	KeyID string
+}
+
+// Encrypt encrypts plaintext for to and returns the compact JWE message.
+// contentType, if set, is sent as the "cty" header.
+func Encrypt(plaintext []byte, to Recipient, contentType string) (string, error) {
+	if to.Alg == "" {
+		to.Alg = "dir"
+		if _, ok := to.Key.(*rsa.PublicKey); ok {
+			to.Alg = "RSA-OAEP-256"
+		}
+	}
+	if to.Enc == "" {
+		to.Enc = "A256GCM"
+	}
+	size, ok := contentKeySize(to.Enc)
+	if !ok {
+		return "", fmt.Errorf("helix/jwe: unsupported content encryption %q", to.Enc)
+	}
+
+	var cek, encryptedKey []byte
+	switch to.Alg {
+	case "dir":
+		secret, ok := to.Key.([]byte)
+		if !ok || len(secret) != size {
+			return "", fmt.Errorf("helix/jwe: %s needs a %d-byte key", to.Enc, size)
+		}
+		cek = secret
+	case "A128KW", "A192KW", "A256KW":
+		kek, ok := to.Key.([]byte)
+		if !ok || len(kek) != wrapKeySize(to.Alg) {
+			return "", fmt.Errorf("helix/jwe: %s needs a %d-byte key", to.Alg, wrapKeySize(to.Alg))
+		}
+		cek = randomBytes(size)
+		var err error
+		if encryptedKey, err = keyWrap(kek, cek); err != nil {
+			return "", err
+		}
+	case "RSA-OAEP", "RSA-OAEP-256":
+		pub, ok := to.Key.(*rsa.PublicKey)
+		if !ok {
+			return "", fmt.Errorf("helix/jwe: %s needs an *rsa.PublicKey", to.Alg)
+		}
+		cek = randomBytes(size)
+		var err error
+		if encryptedKey, err = rsa.EncryptOAEP(oaepHash(to.Alg), rand.Reader, pub, cek, nil); err != nil {
+			return "", fmt.Errorf("helix/jwe: %w", err)
+		}
+	default:
+		return "", fmt.Errorf("helix/jwe: unsupported key management %q", to.Alg)
+	}
+
+	header, err := json.Marshal(Header{Alg: to.Alg, Enc: to.Enc, KeyID: to.KeyID, ContentType: contentType})
+	if err != nil {
+		return "", err
+	}
+	protected := base64.RawURLEncoding.EncodeToString(header)
+
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return "", err
+	}
+	iv := randomBytes(gcm.NonceSize())
+	sealed := gcm.Seal(nil, iv, plaintext, []byte(protected))
+	ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+
+	enc := base64.RawURLEncoding
+	return protected + "." + enc.EncodeToString(encryptedKey) + "." + enc.EncodeToString(iv) + "." +
+		enc.EncodeToString(ciphertext) + "." + enc.EncodeToString(tag), nil
+}
+
+// Decrypt decrypts a compact JWE message with the key returned by key for
+// its header: a []byte for "dir" and the "A*KW" algorithms, or an
+// *rsa.PrivateKey for "RSA-OAEP" and "RSA-OAEP-256". key should return an
+// error for algorithms or key IDs it does not expect; its error is returned
+// as is. Any other failure returns ErrDecrypt.
+func Decrypt(token string, key func(Header) (any, error)) ([]byte, Header, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 5 {
+		return nil, Header{}, ErrDecrypt
+	}
+	var segs [5][]byte
+	for i, part := range parts {
+		var err error
+		if segs[i], err = base64.RawURLEncoding.DecodeString(part); err != nil {
+			return nil, Header{}, ErrDecrypt
+		}
+	}
+
+	var header struct {
+		Header
+		Zip  string   `json:"zip"`
+		Crit []string `json:"crit"`
+	}
+	if err := json.Unmarshal(segs[0], &header); err != nil || header.Zip != "" || len(header.Crit) > 0 {
+		return nil, Header{}, ErrDecrypt
+	}
+	size, ok := contentKeySize(header.Enc)
+	if !ok {
+		return nil, header.Header, ErrDecrypt
+	}
+
+	k, err := key(header.Header)
+	if err != nil {
+		return nil, header.Header, err
+	}
+
+	var cek []byte
+	switch header.Alg {
+	case "dir":
+		secret, ok := k.([]byte)
+		if !ok || len(segs[1]) != 0 {
+			return nil, header.Header, ErrDecrypt
+		}
+		cek = secret
+	case "A128KW", "A192KW", "A256KW":
+		kek, ok := k.([]byte)
+		if !ok || len(kek) != wrapKeySize(header.Alg) {
+			return nil, header.Header, ErrDecrypt
+		}
+		cek, _ = keyUnwrap(kek, segs[1])
+	case "RSA-OAEP", "RSA-OAEP-256":
+		priv, ok := k.(*rsa.PrivateKey)
+		if !ok {
+			return nil, header.Header, ErrDecrypt
+		}
+		cek, _ = rsa.DecryptOAEP(oaepHash(header.Alg), nil, priv, segs[1], nil)
+	}
+	if len(cek) != size {
+		return nil, header.Header, ErrDecrypt
+	}
+
+	gcm, err := newGCM(cek)
+	if err != nil || len(segs[2]) != gcm.NonceSize() || len(segs[4]) != gcm.Overhead() {
+		return nil, header.Header, ErrDecrypt
+	}
+	plaintext, err := gcm.Open(nil, segs[2], append(segs[3], segs[4]...), []byte(parts[0]))
+	if err != nil {
+		return nil, header.Header, ErrDecrypt
+	}
+	return plaintext, header.Header, nil
+}
+
+// Config configures Middleware.
+type Config struct {
+	// DecryptionKey returns the key decrypting a request body encrypted
+	// with the given header (see Decrypt). A non-nil error fails the
+	// request with a 500. If nil, request bodies are passed on as they are.
+	DecryptionKey func(ctx context.Context, h Header) (any, error)
+
+	// EncryptionKey returns the recipient a response to r is encrypted
+	// for. A non-nil error fails the request with a 500. If nil, responses
+	// are sent as they are.
+	EncryptionKey func(r *http.Request) (Recipient, error)
+
+	// Required rejects request bodies that are not encrypted with a 415.
+	Required bool
+
+	// MaxBodySize limits the encrypted request body read; larger bodies
+	// get a 413.
+	// Default: 1MB
+	MaxBodySize int64
+
+	// SkipFunc determines if encryption should be skipped.
+	SkipFunc func(r *http.Request) bool
+}
+
+// Middleware returns a middleware that decrypts "application/jose" request
+// bodies, replacing them with the plaintext and its content type ("cty",
+// default "application/json"), and encrypts non-empty response bodies for
+// the recipient returned by EncryptionKey. Bodies that do not decrypt get a
+// 400 problem. Responses are buffered to be encrypted, so do not use it for
+// streaming routes.
+func Middleware(config Config) func(http.Handler) http.Handler {
+	if config.DecryptionKey == nil && config.EncryptionKey == nil {
+		panic("helix/jwe: decryption or encryption key is required")
+	}
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = 1 << 20
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if config.SkipFunc != nil && config.SkipFunc(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if config.DecryptionKey != nil && !decryptRequest(w, r, config) {
+				return
+			}
+			if config.EncryptionKey == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			buf := &bufferedWriter{ResponseWriter: w, header: make(http.Header)}
+			next.ServeHTTP(buf, r)
+			encryptResponse(w, r, buf, config)
+		})
+	}
+}
+
+// decryptRequest replaces an encrypted request body with its plaintext. It
+// reports false if it has answered the request.
+func decryptRequest(w http.ResponseWriter, r *http.Request, config Config) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != ContentType {
+		if config.Required && r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0 {
+			helix.WriteProblem(w, helix.ErrUnsupportedMediaType.WithDetail("request body must be encrypted as "+ContentType))
+			return false
+		}
+		return true
+	}
+
+	token, err := io.ReadAll(http.MaxBytesReader(w, r.Body, config.MaxBodySize))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			helix.WriteProblem(w, helix.ErrPayloadTooLarge.WithDetailf("encrypted body exceeds %d bytes", config.MaxBodySize))
+		} else {
+			helix.WriteProblem(w, helix.ErrBadRequest.WithDetail("failed to read request body"))
+		}
+		return false
+	}
+
+	var keyErr error
+	plaintext, header, err := Decrypt(string(bytes.TrimSpace(token)), func(h Header) (any, error) {
+		k, err := config.DecryptionKey(r.Context(), h)
+		keyErr = err
+		return k, err
+	})
+	if keyErr != nil {
+		helix.WriteProblem(w, helix.ErrInternal.WithDetail("decryption key lookup failed"))
+		return false
+	}
+	if err != nil {
+		helix.WriteProblem(w, helix.ErrBadRequest.WithDetail("request body cannot be decrypted"))
+		return false
+	}
+
+	contentType := header.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	r.Header.Set("Content-Type", contentType)
+	r.Header.Del("Content-Length")
+	r.ContentLength = int64(len(plaintext))
+	r.Body = io.NopCloser(bytes.NewReader(plaintext))
+	return true
+}
+
+// encryptResponse writes the buffered response, encrypted if it has a body.
+func encryptResponse(w http.ResponseWriter, r *http.Request, buf *bufferedWriter, config Config) {
+	status := buf.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if buf.body.Len() == 0 {
+		copyHeader(w.Header(), buf.header)
+		w.WriteHeader(status)
+		return
+	}
+
+	to, err := config.EncryptionKey(r)
+	if err != nil {
+		helix.WriteProblem(w, helix.ErrInternal.WithDetail("encryption key lookup failed"))
+		return
+	}
+	token, err := Encrypt(buf.body.Bytes(), to, buf.header.Get("Content-Type"))
+	if err != nil {
+		helix.WriteProblem(w, helix.ErrInternal.WithDetail("response encryption failed"))
+		return
+	}
+
+	copyHeader(w.Header(), buf.header)
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(token)))
+	w.WriteHeader(status)
+	io.WriteString(w, token)
+}
+
+// bufferedWriter holds a response until it is encrypted.
+type bufferedWriter struct {
+	http.ResponseWriter
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedWriter) Header() http.Header { return b.header }
+
+func (b *bufferedWriter) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *bufferedWriter) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+// copyHeader copies src into dst, replacing existing values.
+func copyHeader(dst, src http.Header) {
+	for name, values := range src {
+		dst[name] = values
+	}
+}
+
+// contentKeySize returns the key size of a content encryption algorithm.
+func contentKeySize(enc string) (int, bool) {
+	switch enc {
+	case "A128GCM":
+		return 16, true
+	case "A192GCM":
+		return 24, true
+	case "A256GCM":
+		return 32, true
+	}
+	return 0, false
+}
+
+// wrapKeySize returns the key size of an AES key wrap algorithm.
+func wrapKeySize(alg string) int {
+	n, _ := strconv.Atoi(alg[1:4])
+	return n / 8
+}
+
+// oaepHash returns the hash of an RSA-OAEP algorithm.
+func oaepHash(alg string) hash.Hash {
+	if alg == "RSA-OAEP-256" {
+		return sha256.New()
+	}
+	return sha1.New()
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	rand.Read(b)
+	return b
+}
+
+// keyWrapIV is the initial value of RFC 3394 key wrapping.
+var keyWrapIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+// keyWrap wraps key with kek using the AES key wrap of RFC 3394.
+func keyWrap(kek, key []byte) ([]byte, error) {
+	if len(key)%8 != 0 || len(key) < 16 {
+		return nil, errors.New("helix/jwe: wrapped key must be a multiple of 8 bytes")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(key) / 8
+	out := make([]byte, 8+len(key))
+	copy(out, keyWrapIV)
+	copy(out[8:], key)
+
+	var b [16]byte
+	for j := range 6 {
+		for i := 1; i <= n; i++ {
+			copy(b[:8], out[:8])
+			copy(b[8:], out[8*i:8*i+8])
+			block.Encrypt(b[:], b[:])
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(out[:8], binary.BigEndian.Uint64(b[:8])^t)
+			copy(out[8*i:], b[8:])
+		}
+	}
+	return out, nil
+}
+
+// keyUnwrap reverses keyWrap, checking the integrity of the wrapped key.
+func keyUnwrap(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped)%8 != 0 || len(wrapped) < 24 {
+		return nil, ErrDecrypt
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(wrapped)/8 - 1
+	out := make([]byte, len(wrapped))
+	copy(out, wrapped)
+
+	var b [16]byte
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(b[:8], binary.BigEndian.Uint64(out[:8])^t)
+			copy(b[8:], out[8*i:8*i+8])
+			block.Decrypt(b[:], b[:])
+			copy(out[:8], b[:8])
+			copy(out[8*i:], b[8:])
+		}
+	}
+	if subtle.ConstantTimeCompare(out[:8], keyWrapIV) != 1 {
+		return nil, ErrDecrypt
+	}
+	return out[8:], nil
+}
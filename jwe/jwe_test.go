@@ -0,0 +1,170 @@
+package jwe_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kolosys/helix"
+	. "github.com/kolosys/helix/jwe"
+)
+
+func TestKeyWrap(t *testing.T) {
+	// RFC 3394, section 4.1
+	kek, _ := hex.DecodeString("000102030405060708090A0B0C0D0E0F")
+	key, _ := hex.DecodeString("00112233445566778899AABBCCDDEEFF")
+	wrapped, err := KeyWrap(kek, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(wrapped); got != "1fa68b0a8112b447aef34bd8fb5a7b829d3e862371d2cfe5" {
+		t.Fatalf("unexpected wrapped key %s", got)
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := make([]byte, 32)
+	rand.Read(secret)
+
+	tests := []struct {
+		name string
+		to   Recipient
+		key  any
+	}{
+		{"dir", Recipient{Key: secret}, secret},
+		{"A128KW", Recipient{Alg: "A128KW", Enc: "A128GCM", Key: secret[:16]}, secret[:16]},
+		{"A256KW", Recipient{Alg: "A256KW", Key: secret}, secret},
+		{"RSA-OAEP", Recipient{Alg: "RSA-OAEP", Key: &rsaKey.PublicKey}, rsaKey},
+		{"RSA-OAEP-256", Recipient{Key: &rsaKey.PublicKey, KeyID: "k1"}, rsaKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := Encrypt([]byte(`{"amount":100}`), tt.to, "application/json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := strings.Count(token, "."); n != 4 {
+				t.Fatalf("expected 5 segments, got %d", n+1)
+			}
+
+			plaintext, h, err := Decrypt(token, func(Header) (any, error) { return tt.key, nil })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(plaintext) != `{"amount":100}` || h.ContentType != "application/json" || h.KeyID != tt.to.KeyID {
+				t.Fatalf("unexpected result %q %+v", plaintext, h)
+			}
+
+			// Tampering with any segment must fail
+			parts := strings.Split(token, ".")
+			for i := range parts {
+				if parts[i] == "" {
+					continue
+				}
+				tampered := append([]string(nil), parts...)
+				b := []byte(tampered[i])
+				b[len(b)/2] ^= 1
+				tampered[i] = string(b)
+				if _, _, err := Decrypt(strings.Join(tampered, "."), func(Header) (any, error) { return tt.key, nil }); err == nil {
+					t.Errorf("segment %d: expected tampered message to fail", i)
+				}
+			}
+		})
+	}
+
+	t.Run("wrong key type", func(t *testing.T) {
+		token, _ := Encrypt([]byte("x"), Recipient{Key: secret}, "")
+		if _, _, err := Decrypt(token, func(Header) (any, error) { return rsaKey, nil }); !errors.Is(err, ErrDecrypt) {
+			t.Fatalf("expected ErrDecrypt, got %v", err)
+		}
+	})
+
+	t.Run("invalid recipient", func(t *testing.T) {
+		if _, err := Encrypt([]byte("x"), Recipient{Key: secret[:16]}, ""); err == nil {
+			t.Fatal("expected error for a short dir key")
+		}
+		if _, err := Encrypt([]byte("x"), Recipient{Alg: "RSA1_5", Key: &rsaKey.PublicKey}, ""); err == nil {
+			t.Fatal("expected error for an unsupported algorithm")
+		}
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	serverKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	clientKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	s := helix.New(nil)
+	s.POST("/payments", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, r.Header.Get("Content-Type")+" "+string(body))
+	}, helix.WithRouteMiddleware(Middleware(Config{
+		DecryptionKey: func(_ context.Context, h Header) (any, error) {
+			if h.KeyID != "server" {
+				return nil, errors.New("unknown key")
+			}
+			return serverKey, nil
+		},
+		EncryptionKey: func(*http.Request) (Recipient, error) {
+			return Recipient{Key: &clientKey.PublicKey}, nil
+		},
+		Required: true,
+	})))
+
+	token, _ := Encrypt([]byte(`{"amount":100}`), Recipient{Key: &serverKey.PublicKey, KeyID: "server"}, "")
+	req := httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(token))
+	req.Header.Set("Content-Type", ContentType)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated || rec.Header().Get("Content-Type") != ContentType {
+		t.Fatalf("unexpected response %d %v: %s", rec.Code, rec.Header(), rec.Body.String())
+	}
+	plaintext, h, err := Decrypt(rec.Body.String(), func(Header) (any, error) { return clientKey, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plaintext) != `application/json {"amount":100}` || h.ContentType != "text/plain" {
+		t.Fatalf("unexpected response %q %+v", plaintext, h)
+	}
+
+	// Plaintext bodies are rejected
+	req = httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(`{"amount":100}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected 415, got %d", rec.Code)
+	}
+
+	// Bodies that do not decrypt are rejected
+	req = httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(token[:len(token)-4]+"AAAA"))
+	req.Header.Set("Content-Type", ContentType)
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+
+	// Key lookup errors fail the request
+	other, _ := Encrypt([]byte("x"), Recipient{Key: &serverKey.PublicKey, KeyID: "other"}, "")
+	req = httptest.NewRequest(http.MethodPost, "/payments", bytes.NewBufferString(other))
+	req.Header.Set("Content-Type", ContentType)
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rec.Code)
+	}
+}
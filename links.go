@@ -0,0 +1,216 @@
+package helix
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Link is a hypermedia link to a related resource.
+type Link struct {
+	// Href is the target URI, absolute or relative to the request.
+	Href string `json:"href"`
+
+	// Title labels the target for humans.
+	Title string `json:"title,omitempty"`
+}
+
+// Links maps link relation types, such as "self", "next" and "prev", to
+// links. Responses carry them as a HAL-style "_links" member and as an RFC
+// 8288 Link header.
+type Links map[string]Link
+
+// linkRelOrder orders the common relations first in Link headers.
+var linkRelOrder = []string{"self", "first", "prev", "next", "last"}
+
+// rels returns the relations of l, common ones first and the rest sorted.
+func (l Links) rels() []string {
+	rels := make([]string, 0, len(l))
+	for _, rel := range linkRelOrder {
+		if _, ok := l[rel]; ok {
+			rels = append(rels, rel)
+		}
+	}
+	var rest []string
+	for rel := range l {
+		if !slices.Contains(linkRelOrder, rel) {
+			rest = append(rest, rel)
+		}
+	}
+	slices.Sort(rest)
+	return append(rels, rest...)
+}
+
+// Header formats l as an RFC 8288 Link header value, as in
+// `</posts?page=3>; rel="next", </posts?page=1>; rel="prev"`.
+func (l Links) Header() string {
+	var b strings.Builder
+	for i, rel := range l.rels() {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		link := l[rel]
+		b.WriteString("<" + link.Href + `>; rel="` + rel + `"`)
+		if link.Title != "" {
+			b.WriteString("; title=" + strconv.Quote(link.Title))
+		}
+	}
+	return b.String()
+}
+
+// merge returns l with the links of other whose relations l lacks.
+func (l Links) merge(other Links) Links {
+	if len(other) == 0 {
+		return l
+	}
+	merged := make(Links, len(l)+len(other))
+	for rel, link := range other {
+		merged[rel] = link
+	}
+	for rel, link := range l {
+		merged[rel] = link
+	}
+	return merged
+}
+
+// PageLinks returns the self, first, prev, next and last links of page of a
+// page-numbered collection, built from the request URL with the "page" and
+// "limit" query parameters replaced. prev and next are omitted at the ends.
+func PageLinks(r *http.Request, page, limit, totalPages int) Links {
+	href := func(p int) string {
+		q := r.URL.Query()
+		q.Set("page", strconv.Itoa(p))
+		q.Set("limit", strconv.Itoa(limit))
+		return (&url.URL{Path: r.URL.Path, RawQuery: q.Encode()}).RequestURI()
+	}
+
+	links := Links{"self": {Href: href(page)}, "first": {Href: href(1)}}
+	if totalPages > 0 {
+		links["last"] = Link{Href: href(totalPages)}
+	}
+	if page > 1 {
+		links["prev"] = Link{Href: href(min(page-1, max(totalPages, 1)))}
+	}
+	if page < totalPages {
+		links["next"] = Link{Href: href(page + 1)}
+	}
+	return links
+}
+
+// CursorLinks returns the self and, when next is not empty, next links of a
+// cursor-paginated collection, built from the request URL with the "cursor"
+// query parameter replaced.
+func CursorLinks(r *http.Request, next string) Links {
+	links := Links{"self": {Href: r.URL.RequestURI()}}
+	if next != "" {
+		q := r.URL.Query()
+		q.Set("cursor", next)
+		links["next"] = Link{Href: (&url.URL{Path: r.URL.Path, RawQuery: q.Encode()}).RequestURI()}
+	}
+	return links
+}
+
+// linkedValue is a response value with links added as its "_links" member.
+type linkedValue struct {
+	value any
+	links Links
+}
+
+// MarshalJSON implements json.Marshaler. Values that are not JSON objects
+// are encoded unchanged.
+func (v linkedValue) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(v.value)
+	if err != nil || len(b) < 2 || b[0] != '{' {
+		return b, err
+	}
+	links, err := json.Marshal(v.links)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"_links":`)
+	buf.Write(links)
+	if rest := b[1:]; !bytes.Equal(bytes.TrimSpace(rest), []byte("}")) {
+		buf.WriteByte(',')
+		buf.Write(rest)
+	} else {
+		buf.WriteByte('}')
+	}
+	return buf.Bytes(), nil
+}
+
+// WithLinks adds links to the response: as an RFC 8288 Link header right
+// away, and as the "_links" member of the JSON object written by JSON, OK,
+// Created, Accepted or Paginated. Later calls add further links.
+//
+// Example:
+//
+//	return c.WithLinks(helix.Links{
+//	    "self":   {Href: "/orders/" + id},
+//	    "cancel": {Href: "/orders/" + id + "/cancel"},
+//	}).OK(order)
+func (c *Ctx) WithLinks(links Links) *Ctx {
+	if len(links) == 0 {
+		return c
+	}
+	c.Response.Header().Add("Link", links.Header())
+	c.links = links.merge(c.links)
+	return c
+}
+
+// withLinks adds the links set with WithLinks to a response value.
+func (c *Ctx) withLinks(v any) any {
+	if len(c.links) == 0 {
+		return v
+	}
+	return linkedValue{value: v, links: c.links}
+}
+
+// pageLinker is implemented by collection responses that link to their
+// neighboring pages.
+type pageLinker interface {
+	withPageLinks(r *http.Request) (any, Links)
+}
+
+// addPageLinks fills in the page links of a typed handler's collection
+// response, keeping links the handler set, and sets the Link header.
+func addPageLinks(w http.ResponseWriter, r *http.Request, res any) any {
+	l, ok := res.(pageLinker)
+	if !ok {
+		return res
+	}
+	res, links := l.withPageLinks(r)
+	if len(links) > 0 {
+		w.Header().Add("Link", links.Header())
+	}
+	return res
+}
+
+// withPageLinks implements pageLinker.
+func (p PaginatedResponse[T]) withPageLinks(r *http.Request) (any, Links) {
+	var links Links
+	if p.NextCursor != "" || p.Limit == 0 {
+		links = CursorLinks(r, p.NextCursor)
+	} else {
+		links = PageLinks(r, p.Page, p.Limit, p.TotalPages)
+	}
+	p.Links = p.Links.merge(links)
+	return p, p.Links
+}
+
+// withPageLinks implements pageLinker.
+func (l ListResponse[Entity]) withPageLinks(r *http.Request) (any, Links) {
+	if l.Limit <= 0 {
+		l.Links = l.Links.merge(Links{"self": {Href: r.URL.RequestURI()}})
+		return l, l.Links
+	}
+	page := max(l.Page, 1)
+	totalPages := (l.Total + l.Limit - 1) / l.Limit
+	l.Links = l.Links.merge(PageLinks(r, page, l.Limit, totalPages))
+	return l, l.Links
+}
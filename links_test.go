@@ -0,0 +1,111 @@
+package helix_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/kolosys/helix"
+)
+
+func TestLinks_Header(t *testing.T) {
+	links := Links{
+		"next":    {Href: "/posts?page=3"},
+		"author":  {Href: "/users/1", Title: "Ann"},
+		"prev":    {Href: "/posts?page=1"},
+		"self":    {Href: "/posts?page=2"},
+		"archive": {Href: "/archive"},
+	}
+	want := `</posts?page=2>; rel="self", </posts?page=1>; rel="prev", </posts?page=3>; rel="next", ` +
+		`</archive>; rel="archive", </users/1>; rel="author"; title="Ann"`
+	if got := links.Header(); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestPageLinks(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/posts?page=2&status=open", nil)
+	links := PageLinks(r, 2, 10, 3)
+
+	want := map[string]string{
+		"self":  "/posts?limit=10&page=2&status=open",
+		"first": "/posts?limit=10&page=1&status=open",
+		"prev":  "/posts?limit=10&page=1&status=open",
+		"next":  "/posts?limit=10&page=3&status=open",
+		"last":  "/posts?limit=10&page=3&status=open",
+	}
+	if len(links) != len(want) {
+		t.Fatalf("expected %d links, got %v", len(want), links)
+	}
+	for rel, href := range want {
+		if links[rel].Href != href {
+			t.Errorf("%s: expected %s, got %s", rel, href, links[rel].Href)
+		}
+	}
+
+	if links := PageLinks(r, 3, 10, 3); links["next"].Href != "" {
+		t.Errorf("expected no next link on the last page, got %v", links["next"])
+	}
+	if links := CursorLinks(r, "abc"); links["next"].Href != "/posts?cursor=abc&page=2&status=open" {
+		t.Errorf("unexpected cursor next link %v", links["next"])
+	}
+}
+
+func TestCtx_WithLinks(t *testing.T) {
+	s := New(nil)
+	s.GET("/orders/1", HandleCtx(func(c *Ctx) error {
+		return c.WithLinks(Links{"self": {Href: "/orders/1"}}).OK(map[string]int{"id": 1})
+	}))
+	s.GET("/orders", HandleCtx(func(c *Ctx) error {
+		return c.WithLinks(Links{"self": {Href: "/orders"}}).Paginated([]int{1, 2}, 4, 1, 2)
+	}))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+	if got := rec.Body.String(); got != `{"_links":{"self":{"href":"/orders/1"}},"id":1}`+"\n" {
+		t.Errorf("unexpected body %s", got)
+	}
+	if got := rec.Header().Get("Link"); got != `</orders/1>; rel="self"` {
+		t.Errorf("unexpected Link header %q", got)
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders", nil))
+	var body struct {
+		Links Links `json:"_links"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Links["self"].Href != "/orders" || body.Links["next"].Href != "/orders?limit=2&page=2" {
+		t.Errorf("unexpected links %v", body.Links)
+	}
+	if len(rec.Header().Values("Link")) != 2 {
+		t.Errorf("expected the handler's and the page links in Link headers, got %v", rec.Header().Values("Link"))
+	}
+}
+
+func TestListResponse_Links(t *testing.T) {
+	s := New(nil)
+	s.GET("/items", HandleNoRequest(func(ctx context.Context) (ListResponse[int], error) {
+		return ListResponse[int]{Items: []int{3, 4}, Total: 5, Page: 2, Limit: 2}, nil
+	}))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items?page=2&limit=2", nil))
+
+	want := `</items?limit=2&page=2>; rel="self", </items?limit=2&page=1>; rel="first", ` +
+		`</items?limit=2&page=1>; rel="prev", </items?limit=2&page=3>; rel="next", </items?limit=2&page=3>; rel="last"`
+	if got := rec.Header().Get("Link"); got != want {
+		t.Errorf("expected Link %s, got %s", want, got)
+	}
+	var body ListResponse[int]
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Links["next"].Href != "/items?limit=2&page=3" {
+		t.Errorf("unexpected links %v", body.Links)
+	}
+}
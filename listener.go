@@ -0,0 +1,165 @@
+package helix
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConnStats contains connection counters for a server with connection limits.
+type ConnStats struct {
+	// Active is the number of currently open connections.
+	Active int64 `json:"active"`
+
+	// Accepted is the total number of connections accepted.
+	Accepted int64 `json:"accepted"`
+
+	// Rejected is the total number of connections rejected due to limits.
+	Rejected int64 `json:"rejected"`
+}
+
+// serviceUnavailableResponse is written to plaintext connections that exceed a limit.
+const serviceUnavailableResponse = "HTTP/1.1 503 Service Unavailable\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Connection: close\r\n" +
+	"Content-Length: 19\r\n" +
+	"\r\n" +
+	"Service Unavailable"
+
+// limitListener wraps a net.Listener to enforce global and per-IP connection limits.
+type limitListener struct {
+	net.Listener
+	max      int
+	maxPerIP int
+	respond  bool // write a 503 before closing rejected connections
+
+	mu    sync.Mutex
+	total int
+	perIP map[string]int
+
+	active   atomic.Int64
+	accepted atomic.Int64
+	rejected atomic.Int64
+}
+
+// newLimitListener wraps ln with the given limits. A limit of 0 disables it.
+// If respond is true, rejected connections receive a 503 response,
+// otherwise they are reset.
+func newLimitListener(ln net.Listener, max, maxPerIP int, respond bool) *limitListener {
+	return &limitListener{
+		Listener: ln,
+		max:      max,
+		maxPerIP: maxPerIP,
+		respond:  respond,
+		perIP:    make(map[string]int),
+	}
+}
+
+// Accept waits for and returns the next connection within the limits.
+// Connections exceeding a limit are rejected without being returned.
+func (l *limitListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		ip := connIP(conn)
+		if !l.acquire(ip) {
+			l.rejected.Add(1)
+			go l.reject(conn)
+			continue
+		}
+
+		l.accepted.Add(1)
+		l.active.Add(1)
+		return &limitConn{Conn: conn, listener: l, ip: ip}, nil
+	}
+}
+
+// acquire reserves a connection slot for the IP.
+func (l *limitListener) acquire(ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.max > 0 && l.total >= l.max {
+		return false
+	}
+	if l.maxPerIP > 0 && l.perIP[ip] >= l.maxPerIP {
+		return false
+	}
+
+	l.total++
+	l.perIP[ip]++
+	return true
+}
+
+// release frees a connection slot for the IP.
+func (l *limitListener) release(ip string) {
+	l.mu.Lock()
+	l.total--
+	if l.perIP[ip]--; l.perIP[ip] <= 0 {
+		delete(l.perIP, ip)
+	}
+	l.mu.Unlock()
+
+	l.active.Add(-1)
+}
+
+// reject closes a connection that exceeded a limit.
+func (l *limitListener) reject(conn net.Conn) {
+	if l.respond {
+		conn.SetWriteDeadline(time.Now().Add(time.Second))
+		conn.Write([]byte(serviceUnavailableResponse))
+	} else if tc, ok := conn.(*net.TCPConn); ok {
+		// Discard unsent data so the close sends a RST
+		tc.SetLinger(0)
+	}
+	conn.Close()
+}
+
+// stats returns a snapshot of the connection counters.
+func (l *limitListener) stats() ConnStats {
+	return ConnStats{
+		Active:   l.active.Load(),
+		Accepted: l.accepted.Load(),
+		Rejected: l.rejected.Load(),
+	}
+}
+
+// limitConn releases its slot in the limitListener when closed.
+type limitConn struct {
+	net.Conn
+	listener *limitListener
+	ip       string
+	once     sync.Once
+}
+
+// Close closes the connection and releases its slot.
+func (c *limitConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(func() {
+		c.listener.release(c.ip)
+	})
+	return err
+}
+
+// connIP returns the IP address of the connection's remote peer.
+func connIP(conn net.Conn) string {
+	addr := conn.RemoteAddr().String()
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// ConnStats returns connection counters for the server's listener.
+// Counters are only tracked when MaxConnections or MaxConnectionsPerIP is set.
+func (s *Server) ConnStats() ConnStats {
+	if s.connLimiter == nil {
+		return ConnStats{}
+	}
+	return s.connLimiter.stats()
+}
@@ -0,0 +1,135 @@
+package helix_test
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/helix"
+)
+
+// serveLimited starts an HTTP server on a limited listener whose handler
+// blocks until release is closed.
+func serveLimited(t *testing.T, max, maxPerIP int, respond bool) (string, chan struct{}, func() ConnStats) {
+	t.Helper()
+
+	raw, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, stats := NewLimitListener(raw, max, maxPerIP, respond)
+
+	release := make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte("ok"))
+	})}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+
+	return raw.Addr().String(), release, stats
+}
+
+// openRequest dials the server and sends a request without waiting for the response.
+func openRequest(t *testing.T, addr string) net.Conn {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(conn, "GET / HTTP/1.1\r\nHost: test\r\n\r\n")
+	return conn
+}
+
+func waitForStats(stats func() ConnStats, cond func(ConnStats) bool) ConnStats {
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond(stats()) && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	return stats()
+}
+
+func TestLimitListener_MaxConnections(t *testing.T) {
+	addr, release, stats := serveLimited(t, 1, 0, true)
+	defer close(release)
+
+	first := openRequest(t, addr)
+	defer first.Close()
+	waitForStats(stats, func(s ConnStats) bool { return s.Active == 1 })
+
+	second := openRequest(t, addr)
+	defer second.Close()
+	second.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	resp, err := http.ReadResponse(bufio.NewReader(second), nil)
+	if err != nil {
+		t.Fatalf("failed to read rejection: %v", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", resp.StatusCode)
+	}
+
+	got := waitForStats(stats, func(s ConnStats) bool { return s.Rejected == 1 })
+	if got.Active != 1 || got.Accepted != 1 || got.Rejected != 1 {
+		t.Errorf("unexpected stats: %+v", got)
+	}
+}
+
+func TestLimitListener_ReleaseOnClose(t *testing.T) {
+	addr, release, stats := serveLimited(t, 1, 0, true)
+	close(release)
+
+	for i := 0; i < 3; i++ {
+		conn := openRequest(t, addr)
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("request %d: expected status 200, got %d", i, resp.StatusCode)
+		}
+		conn.Close()
+		waitForStats(stats, func(s ConnStats) bool { return s.Active == 0 })
+	}
+
+	if got := stats(); got.Accepted != 3 || got.Rejected != 0 {
+		t.Errorf("unexpected stats: %+v", got)
+	}
+}
+
+func TestLimitListener_MaxConnectionsPerIP(t *testing.T) {
+	addr, release, stats := serveLimited(t, 0, 2, false)
+	defer close(release)
+
+	for i := 0; i < 2; i++ {
+		conn := openRequest(t, addr)
+		defer conn.Close()
+	}
+	waitForStats(stats, func(s ConnStats) bool { return s.Active == 2 })
+
+	// Without a response, the excess connection is reset
+	third := openRequest(t, addr)
+	defer third.Close()
+	third.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	_, err := third.Read(make([]byte, 1))
+	if err == nil {
+		t.Fatal("expected rejected connection to be closed")
+	}
+	if strings.Contains(err.Error(), "timeout") {
+		t.Fatalf("expected connection reset, got %v", err)
+	}
+}
+
+func TestServer_ConnStatsWithoutLimits(t *testing.T) {
+	s := New(nil)
+	if got := s.ConnStats(); got != (ConnStats{}) {
+		t.Errorf("expected zero stats, got %+v", got)
+	}
+}
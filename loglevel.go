@@ -0,0 +1,113 @@
+package helix
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/kolosys/helix/logs"
+)
+
+// LogLevelRequest is the body accepted by LogLevelHandler.
+// Level "reset" clears a module level so it follows the root level again.
+type LogLevelRequest struct {
+	Level  string `json:"level"`
+	Module string `json:"module,omitempty"`
+}
+
+// LogLevelResponse reports the current log levels.
+type LogLevelResponse struct {
+	Level   string            `json:"level"`
+	Modules map[string]string `json:"modules,omitempty"`
+}
+
+// LogLevelHandler returns a handler that reads and changes the level of the
+// default logs logger at runtime. GET reports the levels; PUT or POST sets
+// them from a JSON LogLevelRequest body or the level and module query
+// parameters. Protect it with authentication middleware.
+//
+// Example:
+//
+//	admin := s.Group("/internal", middleware.BasicAuth("admin", secret))
+//	admin.GET("/loglevel", s.LogLevelHandler())
+//	admin.PUT("/loglevel", s.LogLevelHandler())
+//
+//	// curl -X PUT -d '{"level":"debug","module":"db"}' .../internal/loglevel
+func (s *Server) LogLevelHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logs.Default()
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPut, http.MethodPost:
+			req := LogLevelRequest{
+				Level:  r.URL.Query().Get("level"),
+				Module: r.URL.Query().Get("module"),
+			}
+			if r.ContentLength != 0 && r.Body != nil {
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					WriteProblem(w, ErrBadRequest.WithDetailf("invalid JSON: %v", err))
+					return
+				}
+			}
+			if err := setLogLevel(logger, req); err != nil {
+				WriteProblem(w, ErrBadRequest.WithDetail(err.Error()))
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST")
+			WriteProblem(w, ErrMethodNotAllowed)
+			return
+		}
+
+		resp := LogLevelResponse{Level: logger.Level().String()}
+		if modules := logger.ModuleLevels(); len(modules) > 0 {
+			resp.Modules = make(map[string]string, len(modules))
+			for name, level := range modules {
+				resp.Modules[name] = level.String()
+			}
+		}
+		JSON(w, http.StatusOK, resp)
+	}
+}
+
+// ToggleLogLevel switches the default logs logger to debug, or back to the
+// level it had before the previous toggle. It returns the new level.
+// With Options.EnableLogLevelToggle, SIGHUP calls it while the server runs.
+func (s *Server) ToggleLogLevel() logs.Level {
+	s.logLevelMu.Lock()
+	defer s.logLevelMu.Unlock()
+
+	logger := logs.Default()
+	if current := logger.Level(); current != logs.DebugLevel {
+		s.logLevel = current
+		logger.SetLevel(logs.DebugLevel)
+		return logs.DebugLevel
+	}
+
+	restore := s.logLevel
+	if restore == logs.DebugLevel {
+		restore = logs.InfoLevel
+	}
+	logger.SetLevel(restore)
+	return restore
+}
+
+// setLogLevel applies a level change request to the logger.
+func setLogLevel(logger *logs.Logger, req LogLevelRequest) error {
+	if req.Module != "" && strings.EqualFold(req.Level, "reset") {
+		logger.ResetModuleLevel(req.Module)
+		return nil
+	}
+
+	level, err := logs.ParseLevel(req.Level)
+	if err != nil {
+		return err
+	}
+	if req.Module != "" {
+		logger.SetModuleLevel(req.Module, level)
+		return nil
+	}
+	logger.SetLevel(level)
+	return nil
+}
@@ -0,0 +1,93 @@
+package helix_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/kolosys/helix"
+	"github.com/kolosys/helix/logs"
+)
+
+func useTestDefaultLogger(t *testing.T) *logs.Logger {
+	t.Helper()
+
+	prev := logs.Default()
+	l := logs.New()
+	logs.SetDefault(l)
+	t.Cleanup(func() { logs.SetDefault(prev) })
+	return l
+}
+
+func TestLogLevelHandler(t *testing.T) {
+	logger := useTestDefaultLogger(t)
+
+	s := New(nil)
+	s.GET("/internal/loglevel", s.LogLevelHandler())
+	s.PUT("/internal/loglevel", s.LogLevelHandler())
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/internal/loglevel", strings.NewReader(`{"level":"debug"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if logger.Level() != logs.DebugLevel {
+		t.Errorf("expected debug level, got %s", logger.Level())
+	}
+
+	// Per-module level via query parameters
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/internal/loglevel?level=error&module=db", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if logger.Named("db").Level() != logs.ErrorLevel {
+		t.Errorf("expected db module at error level, got %s", logger.Named("db").Level())
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/internal/loglevel", nil))
+
+	var resp LogLevelResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Level != "debug" || resp.Modules["db"] != "error" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	// Reset the module
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/internal/loglevel", strings.NewReader(`{"level":"reset","module":"db"}`)))
+	if logger.Named("db").Level() != logs.DebugLevel {
+		t.Errorf("expected db module to follow root level after reset, got %s", logger.Named("db").Level())
+	}
+}
+
+func TestLogLevelHandler_InvalidLevel(t *testing.T) {
+	useTestDefaultLogger(t)
+
+	s := New(nil)
+	s.PUT("/internal/loglevel", s.LogLevelHandler())
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/internal/loglevel", strings.NewReader(`{"level":"loud"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestToggleLogLevel(t *testing.T) {
+	logger := useTestDefaultLogger(t)
+	logger.SetLevel(logs.WarnLevel)
+
+	s := New(nil)
+	if level := s.ToggleLogLevel(); level != logs.DebugLevel || logger.Level() != logs.DebugLevel {
+		t.Errorf("expected toggle to debug, got %s", level)
+	}
+	if level := s.ToggleLogLevel(); level != logs.WarnLevel || logger.Level() != logs.WarnLevel {
+		t.Errorf("expected toggle back to warn, got %s", level)
+	}
+}
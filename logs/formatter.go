@@ -0,0 +1,253 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TextFormatter formats entries as human-readable key=value lines:
+//
+//	2024-01-02T15:04:05Z INFO server started addr=:8080
+type TextFormatter struct {
+	// TimeFormat is the layout used for timestamps.
+	// Default: time.RFC3339
+	TimeFormat string
+
+	// DisableTimestamp omits the timestamp.
+	// Default: false
+	DisableTimestamp bool
+}
+
+// Format implements Formatter.
+func (f *TextFormatter) Format(entry *Entry) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if !f.DisableTimestamp {
+		buf.WriteString(entry.Time.Format(timeFormat(f.TimeFormat)))
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(strings.ToUpper(entry.Level.String()))
+	buf.WriteByte(' ')
+	buf.WriteString(entry.Message)
+
+	for _, k := range sortedKeys(entry.Fields) {
+		buf.WriteByte(' ')
+		buf.WriteString(k)
+		buf.WriteByte('=')
+		buf.WriteString(quoteValue(entry.Fields[k]))
+	}
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
+
+// JSONFormatter formats entries as one JSON object per line.
+// Fields are written at the top level alongside "time", "level", and "msg".
+type JSONFormatter struct {
+	// TimeFormat is the layout used for timestamps.
+	// Default: time.RFC3339
+	TimeFormat string
+
+	// DisableTimestamp omits the "time" key.
+	// Default: false
+	DisableTimestamp bool
+}
+
+// Format implements Formatter.
+func (f *JSONFormatter) Format(entry *Entry) ([]byte, error) {
+	data := make(map[string]any, len(entry.Fields)+3)
+	for k, v := range entry.Fields {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		data[k] = v
+	}
+	if !f.DisableTimestamp {
+		data["time"] = entry.Time.Format(timeFormat(f.TimeFormat))
+	}
+	data["level"] = entry.Level.String()
+	data["msg"] = entry.Message
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// timeFormat returns the layout, defaulting to RFC 3339.
+func timeFormat(layout string) string {
+	if layout == "" {
+		return time.RFC3339
+	}
+	return layout
+}
+
+// sortedKeys returns the field keys in lexical order.
+func sortedKeys(fields Fields) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// quoteValue formats a field value, quoting it if it contains spaces,
+// quotes, or an equals sign.
+func quoteValue(v any) string {
+	var s string
+	switch val := v.(type) {
+	case string:
+		s = val
+	case error:
+		s = val.Error()
+	case fmt.Stringer:
+		s = val.String()
+	default:
+		s = fmt.Sprint(val)
+	}
+
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// LogfmtFormatter formats entries as logfmt lines, suitable for Loki and Grafana:
+//
+//	time=2024-01-02T15:04:05Z level=info msg="server started" addr=:8080
+type LogfmtFormatter struct {
+	// TimeFormat is the layout used for timestamps.
+	// Default: time.RFC3339
+	TimeFormat string
+
+	// DisableTimestamp omits the time key.
+	// Default: false
+	DisableTimestamp bool
+}
+
+// Format implements Formatter.
+func (f *LogfmtFormatter) Format(entry *Entry) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if !f.DisableTimestamp {
+		buf.WriteString("time=")
+		buf.WriteString(entry.Time.Format(timeFormat(f.TimeFormat)))
+		buf.WriteByte(' ')
+	}
+	buf.WriteString("level=")
+	buf.WriteString(entry.Level.String())
+	buf.WriteString(" msg=")
+	buf.WriteString(quoteValue(entry.Message))
+
+	for _, k := range sortedKeys(entry.Fields) {
+		buf.WriteByte(' ')
+		buf.WriteString(k)
+		buf.WriteByte('=')
+		buf.WriteString(quoteValue(entry.Fields[k]))
+	}
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
+
+// ECSVersion is the Elastic Common Schema version reported by ECSFormatter.
+const ECSVersion = "8.11.0"
+
+// DefaultECSFieldMap maps common Helix field names to their ECS equivalents.
+var DefaultECSFieldMap = map[string]string{
+	"request_id": "http.request.id",
+	"method":     "http.request.method",
+	"path":       "url.path",
+	"route":      "http.route",
+	"query":      "url.query",
+	"status":     "http.response.status_code",
+	"bytes":      "http.response.body.bytes",
+	"ip":         "client.ip",
+	"client_ip":  "client.ip",
+	"user_agent": "user_agent.original",
+	"referer":    "http.request.referrer",
+	"trace_id":   "trace.id",
+	"span_id":    "span.id",
+	"error":      "error.message",
+	"service":    "service.name",
+	"user_id":    "user.id",
+}
+
+// ECSFormatter formats entries as Elastic Common Schema JSON, ready for
+// ingestion by Elasticsearch without a transformation pipeline.
+// Dotted field names are written as nested objects.
+type ECSFormatter struct {
+	// FieldMap renames fields to ECS names, merged over DefaultECSFieldMap.
+	FieldMap map[string]string
+
+	// ServiceName sets service.name on every entry.
+	ServiceName string
+}
+
+// Format implements Formatter.
+func (f *ECSFormatter) Format(entry *Entry) ([]byte, error) {
+	doc := map[string]any{
+		"@timestamp": entry.Time.UTC().Format(time.RFC3339Nano),
+		"message":    entry.Message,
+	}
+	setNested(doc, "log.level", entry.Level.String())
+	setNested(doc, "ecs.version", ECSVersion)
+	if f.ServiceName != "" {
+		setNested(doc, "service.name", f.ServiceName)
+	}
+
+	for _, k := range sortedKeys(entry.Fields) {
+		v := entry.Fields[k]
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		setNested(doc, f.fieldName(k), v)
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// fieldName returns the ECS name for a field.
+func (f *ECSFormatter) fieldName(key string) string {
+	if name, ok := f.FieldMap[key]; ok {
+		return name
+	}
+	if name, ok := DefaultECSFieldMap[key]; ok {
+		return name
+	}
+	return key
+}
+
+// setNested stores value under a dotted key as nested objects.
+// If a path segment is already a non-object value, the full dotted key is used.
+func setNested(doc map[string]any, key string, value any) {
+	parts := strings.Split(key, ".")
+	m := doc
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := m[p]
+		if !ok {
+			child := make(map[string]any)
+			m[p] = child
+			m = child
+			continue
+		}
+		child, ok := next.(map[string]any)
+		if !ok {
+			doc[key] = value
+			return
+		}
+		m = child
+	}
+	m[parts[len(parts)-1]] = value
+}
@@ -0,0 +1,543 @@
+// Package logs provides a zero-dependency structured logger for the Helix framework.
+//
+// Loggers are immutable: With and WithFields return child loggers that share
+// the parent's output, formatter, hooks, sampler, and level.
+//
+// Example:
+//
+//	logger := logs.New(logs.WithFormatter(&logs.JSONFormatter{}))
+//	logger.Info("server started", logs.Fields{"addr": ":8080"})
+//
+//	reqLogger := logger.With("request_id", id)
+//	reqLogger.Warn("slow query")
+package logs
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Level is the severity of a log entry.
+type Level int8
+
+// Log levels in increasing order of severity.
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+// String returns the lowercase name of the level.
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	default:
+		return fmt.Sprintf("level(%d)", l)
+	}
+}
+
+// ParseLevel parses a level name such as "debug" or "WARN".
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return DebugLevel, nil
+	case "info":
+		return InfoLevel, nil
+	case "warn", "warning":
+		return WarnLevel, nil
+	case "error":
+		return ErrorLevel, nil
+	default:
+		return InfoLevel, fmt.Errorf("logs: unknown level %q", s)
+	}
+}
+
+// Fields is a set of structured key-value pairs attached to an entry.
+type Fields map[string]any
+
+// Entry is a single log record passed to samplers, hooks, and formatters.
+// Entries must not be modified or retained after the call returns.
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	Fields  Fields
+}
+
+// Formatter serializes an entry into bytes, including the trailing newline.
+type Formatter interface {
+	Format(entry *Entry) ([]byte, error)
+}
+
+// Hook is called for every entry at one of its levels before it is written.
+type Hook interface {
+	// Levels returns the levels the hook fires for.
+	Levels() []Level
+
+	// Fire processes the entry. Errors are reported to stderr.
+	Fire(entry *Entry) error
+}
+
+// Sampler decides whether an entry is logged.
+type Sampler interface {
+	// Sample returns true if the entry should be logged.
+	Sample(entry *Entry) bool
+}
+
+// Option configures a Logger.
+type Option func(*Logger)
+
+// WithOutput sets the writer entries are written to.
+// Default: os.Stdout
+func WithOutput(w io.Writer) Option {
+	return func(l *Logger) {
+		l.core.out = w
+	}
+}
+
+// WithFormatter sets the entry formatter.
+// Default: &TextFormatter{}
+func WithFormatter(f Formatter) Option {
+	return func(l *Logger) {
+		l.core.formatter = f
+	}
+}
+
+// WithLevel sets the minimum level that is logged.
+// Default: InfoLevel
+func WithLevel(level Level) Option {
+	return func(l *Logger) {
+		l.level.Store(int32(level))
+	}
+}
+
+// WithHooks adds hooks to the logger.
+func WithHooks(hooks ...Hook) Option {
+	return func(l *Logger) {
+		l.core.hooks = append(l.core.hooks, hooks...)
+	}
+}
+
+// WithLevelOutput routes entries at the level and above to w instead of the
+// default output. An optional formatter overrides the logger's formatter for
+// this output. When several level outputs apply, the highest level wins.
+//
+// Example:
+//
+//	logs.New(
+//	    logs.WithOutput(os.Stdout),
+//	    logs.WithLevelOutput(logs.ErrorLevel, os.Stderr, &logs.JSONFormatter{}),
+//	)
+func WithLevelOutput(level Level, w io.Writer, formatter ...Formatter) Option {
+	return func(l *Logger) {
+		out := levelOutput{level: level, out: w}
+		if len(formatter) > 0 {
+			out.formatter = formatter[0]
+		}
+
+		// Keep outputs ordered from highest to lowest level
+		i := sort.Search(len(l.core.levelOutputs), func(i int) bool {
+			return l.core.levelOutputs[i].level < level
+		})
+		l.core.levelOutputs = append(l.core.levelOutputs, levelOutput{})
+		copy(l.core.levelOutputs[i+1:], l.core.levelOutputs[i:])
+		l.core.levelOutputs[i] = out
+	}
+}
+
+// WithSampler sets the sampler used to drop entries.
+func WithSampler(s Sampler) Option {
+	return func(l *Logger) {
+		l.core.sampler = s
+	}
+}
+
+// WithFields sets fields included in every entry.
+func WithFields(fields Fields) Option {
+	return func(l *Logger) {
+		l.fields = mergeFields(l.fields, fields)
+	}
+}
+
+// core is the output state shared by a logger and its children.
+type core struct {
+	mu        sync.Mutex
+	out       io.Writer
+	formatter Formatter
+	hooks     []Hook
+	sampler   Sampler
+
+	// levelOutputs override out for entries at or above their level,
+	// ordered from highest to lowest level
+	levelOutputs []levelOutput
+
+	// redactor masks sensitive fields before hooks and formatters run
+	redactor *Redactor
+
+	// modules holds per-module level overrides, see Named
+	modMu   sync.RWMutex
+	modules map[string]*atomic.Int32
+
+	// sink replaces formatting and output when set (see FromSlog)
+	sink func(*Entry)
+}
+
+// levelOutput is an output used for entries at or above a level.
+type levelOutput struct {
+	level     Level
+	out       io.Writer
+	formatter Formatter
+}
+
+// unsetLevel marks a module without its own level.
+const unsetLevel = math.MinInt32
+
+// moduleLevel returns the level override for a module, creating it if needed.
+func (c *core) moduleLevel(name string) *atomic.Int32 {
+	c.modMu.RLock()
+	lvl, ok := c.modules[name]
+	c.modMu.RUnlock()
+	if ok {
+		return lvl
+	}
+
+	c.modMu.Lock()
+	defer c.modMu.Unlock()
+	if lvl, ok := c.modules[name]; ok {
+		return lvl
+	}
+	if c.modules == nil {
+		c.modules = make(map[string]*atomic.Int32)
+	}
+	lvl = new(atomic.Int32)
+	lvl.Store(unsetLevel)
+	c.modules[name] = lvl
+	return lvl
+}
+
+// Logger is a structured, leveled logger.
+type Logger struct {
+	core   *core
+	level  *atomic.Int32
+	fields Fields
+
+	// name and module are set on loggers created by Named
+	name   string
+	module *atomic.Int32
+}
+
+// New creates a Logger with the given options.
+func New(opts ...Option) *Logger {
+	l := &Logger{
+		core: &core{
+			out:       os.Stdout,
+			formatter: &TextFormatter{},
+		},
+		level: new(atomic.Int32),
+	}
+	l.level.Store(int32(InfoLevel))
+
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// With returns a child logger that includes the key-value pair in every entry.
+func (l *Logger) With(key string, value any) *Logger {
+	return l.WithFields(Fields{key: value})
+}
+
+// WithFields returns a child logger that includes the fields in every entry.
+func (l *Logger) WithFields(fields Fields) *Logger {
+	return &Logger{
+		core:   l.core,
+		level:  l.level,
+		fields: mergeFields(l.fields, fields),
+		name:   l.name,
+		module: l.module,
+	}
+}
+
+// Named returns a child logger for a module, with a "module" field and a level
+// that can be changed independently via SetModuleLevel. Nested names are
+// joined with dots ("db.pool"). Until a module level is set, the logger
+// follows the root level.
+func (l *Logger) Named(name string) *Logger {
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+	child := l.With("module", name)
+	child.name = name
+	child.module = l.core.moduleLevel(name)
+	return child
+}
+
+// Name returns the module name set by Named.
+func (l *Logger) Name() string {
+	return l.name
+}
+
+// SetModuleLevel sets the level for loggers created by Named(name).
+func (l *Logger) SetModuleLevel(name string, level Level) {
+	l.core.moduleLevel(name).Store(int32(level))
+}
+
+// ResetModuleLevel makes loggers created by Named(name) follow the root level again.
+func (l *Logger) ResetModuleLevel(name string) {
+	l.core.moduleLevel(name).Store(unsetLevel)
+}
+
+// ModuleLevels returns the modules with their own level.
+func (l *Logger) ModuleLevels() map[string]Level {
+	l.core.modMu.RLock()
+	defer l.core.modMu.RUnlock()
+
+	levels := make(map[string]Level)
+	for name, lvl := range l.core.modules {
+		if v := lvl.Load(); v != unsetLevel {
+			levels[name] = Level(v)
+		}
+	}
+	return levels
+}
+
+// WithError returns a child logger with the error message in the "error" field.
+func (l *Logger) WithError(err error) *Logger {
+	if err == nil {
+		return l
+	}
+	return l.With("error", err.Error())
+}
+
+// Fields returns a copy of the logger's fields.
+func (l *Logger) Fields() Fields {
+	return mergeFields(nil, l.fields)
+}
+
+// Level returns the minimum level that is logged.
+func (l *Logger) Level() Level {
+	if l.module != nil {
+		if v := l.module.Load(); v != unsetLevel {
+			return Level(v)
+		}
+	}
+	return Level(l.level.Load())
+}
+
+// SetLevel changes the minimum level for the logger and its children.
+// On a logger created by Named, it sets the module level instead.
+func (l *Logger) SetLevel(level Level) {
+	if l.module != nil {
+		l.module.Store(int32(level))
+		return
+	}
+	l.level.Store(int32(level))
+}
+
+// Enabled reports whether entries at the level are logged.
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.Level()
+}
+
+// Log writes an entry at the given level.
+func (l *Logger) Log(level Level, msg string, fields ...Fields) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	entry := &Entry{
+		Time:    time.Now(),
+		Level:   level,
+		Message: msg,
+		Fields:  l.fields,
+	}
+	for _, f := range fields {
+		entry.Fields = mergeFields(entry.Fields, f)
+	}
+
+	l.write(entry)
+}
+
+// Debug logs a message at DebugLevel.
+func (l *Logger) Debug(msg string, fields ...Fields) {
+	l.Log(DebugLevel, msg, fields...)
+}
+
+// Info logs a message at InfoLevel.
+func (l *Logger) Info(msg string, fields ...Fields) {
+	l.Log(InfoLevel, msg, fields...)
+}
+
+// Warn logs a message at WarnLevel.
+func (l *Logger) Warn(msg string, fields ...Fields) {
+	l.Log(WarnLevel, msg, fields...)
+}
+
+// Error logs a message at ErrorLevel.
+func (l *Logger) Error(msg string, fields ...Fields) {
+	l.Log(ErrorLevel, msg, fields...)
+}
+
+// Debugf logs a formatted message at DebugLevel.
+func (l *Logger) Debugf(format string, args ...any) {
+	if l.Enabled(DebugLevel) {
+		l.Log(DebugLevel, fmt.Sprintf(format, args...))
+	}
+}
+
+// Infof logs a formatted message at InfoLevel.
+func (l *Logger) Infof(format string, args ...any) {
+	if l.Enabled(InfoLevel) {
+		l.Log(InfoLevel, fmt.Sprintf(format, args...))
+	}
+}
+
+// Warnf logs a formatted message at WarnLevel.
+func (l *Logger) Warnf(format string, args ...any) {
+	if l.Enabled(WarnLevel) {
+		l.Log(WarnLevel, fmt.Sprintf(format, args...))
+	}
+}
+
+// Errorf logs a formatted message at ErrorLevel.
+func (l *Logger) Errorf(format string, args ...any) {
+	if l.Enabled(ErrorLevel) {
+		l.Log(ErrorLevel, fmt.Sprintf(format, args...))
+	}
+}
+
+// write samples, fires hooks for, formats, and outputs an entry.
+func (l *Logger) write(entry *Entry) {
+	c := l.core
+
+	if c.sampler != nil && !c.sampler.Sample(entry) {
+		return
+	}
+
+	if c.redactor != nil {
+		entry.Message = c.redactor.RedactString("", entry.Message)
+		entry.Fields = c.redactor.Redact(entry.Fields)
+	}
+
+	for _, h := range c.hooks {
+		if !hookFires(h, entry.Level) {
+			continue
+		}
+		if err := h.Fire(entry); err != nil {
+			fmt.Fprintf(os.Stderr, "logs: hook error: %v\n", err)
+		}
+	}
+
+	if c.sink != nil {
+		c.sink(entry)
+		return
+	}
+
+	out, formatter := c.output(entry.Level)
+
+	b, err := formatter.Format(entry)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "logs: format error: %v\n", err)
+		return
+	}
+
+	c.mu.Lock()
+	out.Write(b)
+	c.mu.Unlock()
+}
+
+// output returns the writer and formatter for entries at the level.
+func (c *core) output(level Level) (io.Writer, Formatter) {
+	for _, lo := range c.levelOutputs {
+		if level < lo.level {
+			continue
+		}
+		if lo.formatter != nil {
+			return lo.out, lo.formatter
+		}
+		return lo.out, c.formatter
+	}
+	return c.out, c.formatter
+}
+
+// hookFires reports whether the hook is registered for the level.
+func hookFires(h Hook, level Level) bool {
+	for _, l := range h.Levels() {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeFields returns a new map with the fields of b layered over a.
+// If b is empty, a is returned unchanged.
+func mergeFields(a, b Fields) Fields {
+	if len(b) == 0 {
+		return a
+	}
+	merged := make(Fields, len(a)+len(b))
+	for k, v := range a {
+		merged[k] = v
+	}
+	for k, v := range b {
+		merged[k] = v
+	}
+	return merged
+}
+
+// AllLevels lists every level, for hooks that fire on all entries.
+var AllLevels = []Level{DebugLevel, InfoLevel, WarnLevel, ErrorLevel}
+
+// defaultLogger is the package-level logger.
+var defaultLogger atomic.Pointer[Logger]
+
+func init() {
+	defaultLogger.Store(New())
+}
+
+// Default returns the package-level logger.
+func Default() *Logger {
+	return defaultLogger.Load()
+}
+
+// SetDefault replaces the package-level logger.
+func SetDefault(l *Logger) {
+	defaultLogger.Store(l)
+}
+
+// Debug logs a message at DebugLevel using the default logger.
+func Debug(msg string, fields ...Fields) {
+	Default().Debug(msg, fields...)
+}
+
+// Info logs a message at InfoLevel using the default logger.
+func Info(msg string, fields ...Fields) {
+	Default().Info(msg, fields...)
+}
+
+// Warn logs a message at WarnLevel using the default logger.
+func Warn(msg string, fields ...Fields) {
+	Default().Warn(msg, fields...)
+}
+
+// Error logs a message at ErrorLevel using the default logger.
+func Error(msg string, fields ...Fields) {
+	Default().Error(msg, fields...)
+}
@@ -0,0 +1,414 @@
+package logs_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	. "github.com/kolosys/helix/logs"
+)
+
+func newTestLogger(buf *bytes.Buffer, opts ...Option) *Logger {
+	opts = append([]Option{
+		WithOutput(buf),
+		WithFormatter(&TextFormatter{DisableTimestamp: true}),
+	}, opts...)
+	return New(opts...)
+}
+
+func TestLogger_Levels(t *testing.T) {
+	var buf bytes.Buffer
+	l := newTestLogger(&buf, WithLevel(WarnLevel))
+
+	l.Debug("debug")
+	l.Info("info")
+	l.Warn("warn")
+	l.Error("error")
+
+	out := buf.String()
+	if strings.Contains(out, "debug") || strings.Contains(out, "INFO") {
+		t.Errorf("expected debug and info to be filtered, got %q", out)
+	}
+	if !strings.Contains(out, "WARN warn") || !strings.Contains(out, "ERROR error") {
+		t.Errorf("expected warn and error lines, got %q", out)
+	}
+}
+
+func TestLogger_SetLevelSharedWithChildren(t *testing.T) {
+	var buf bytes.Buffer
+	l := newTestLogger(&buf)
+	child := l.With("component", "db")
+
+	l.SetLevel(DebugLevel)
+	child.Debug("query")
+
+	if !strings.Contains(buf.String(), "DEBUG query component=db") {
+		t.Errorf("expected child to follow parent level, got %q", buf.String())
+	}
+}
+
+func TestLogger_Fields(t *testing.T) {
+	var buf bytes.Buffer
+	l := newTestLogger(&buf).With("service", "api")
+
+	l.Info("started", Fields{"addr": ":8080", "note": "hello world"})
+
+	want := `INFO started addr=:8080 note="hello world" service=api` + "\n"
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+
+	// Per-call fields don't leak into the logger
+	if _, ok := l.Fields()["addr"]; ok {
+		t.Error("expected per-call fields not to be retained")
+	}
+}
+
+func TestLogger_WithError(t *testing.T) {
+	var buf bytes.Buffer
+	l := newTestLogger(&buf)
+
+	l.WithError(errors.New("boom")).Error("failed")
+
+	if !strings.Contains(buf.String(), "error=boom") {
+		t.Errorf("expected error field, got %q", buf.String())
+	}
+	if l.WithError(nil) != l {
+		t.Error("expected WithError(nil) to return the same logger")
+	}
+}
+
+func TestJSONFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(WithOutput(&buf), WithFormatter(&JSONFormatter{}))
+
+	l.Infof("user %d created", 42)
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if entry["msg"] != "user 42 created" || entry["level"] != "info" {
+		t.Errorf("unexpected entry: %v", entry)
+	}
+	if _, ok := entry["time"]; !ok {
+		t.Error("expected time key")
+	}
+}
+
+type recordingHook struct {
+	entries []string
+}
+
+func (h *recordingHook) Levels() []Level { return []Level{ErrorLevel} }
+
+func (h *recordingHook) Fire(e *Entry) error {
+	h.entries = append(h.entries, e.Message)
+	return nil
+}
+
+func TestLogger_Hooks(t *testing.T) {
+	var buf bytes.Buffer
+	hook := &recordingHook{}
+	l := newTestLogger(&buf, WithHooks(hook))
+
+	l.Info("ignored")
+	l.Error("reported")
+
+	if len(hook.entries) != 1 || hook.entries[0] != "reported" {
+		t.Errorf("expected hook to fire for error only, got %v", hook.entries)
+	}
+}
+
+type dropSampler struct{}
+
+func (dropSampler) Sample(e *Entry) bool { return e.Level != InfoLevel }
+
+func TestLogger_Sampler(t *testing.T) {
+	var buf bytes.Buffer
+	l := newTestLogger(&buf, WithSampler(dropSampler{}))
+
+	l.Info("dropped")
+	l.Warn("kept")
+
+	if strings.Contains(buf.String(), "dropped") || !strings.Contains(buf.String(), "kept") {
+		t.Errorf("expected sampler to drop info, got %q", buf.String())
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]Level{
+		"debug":   DebugLevel,
+		"INFO":    InfoLevel,
+		"warning": WarnLevel,
+		"Error":   ErrorLevel,
+	}
+	for s, want := range tests {
+		got, err := ParseLevel(s)
+		if err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", s, got, err, want)
+		}
+	}
+
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("expected error for unknown level")
+	}
+}
+
+func TestContext(t *testing.T) {
+	if FromContext(context.Background()) != Default() {
+		t.Error("expected default logger for empty context")
+	}
+
+	l := New()
+	ctx := NewContext(context.Background(), l)
+	if FromContext(ctx) != l {
+		t.Error("expected logger from context")
+	}
+}
+
+func TestLogfmtFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(WithOutput(&buf), WithFormatter(&LogfmtFormatter{DisableTimestamp: true}))
+
+	l.Info("server started", Fields{"addr": ":8080", "quote": `say "hi"`})
+
+	want := `level=info msg="server started" addr=:8080 quote="say \"hi\""` + "\n"
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestECSFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(WithOutput(&buf), WithFormatter(&ECSFormatter{
+		ServiceName: "api",
+		FieldMap:    map[string]string{"tenant": "organization.id"},
+	}))
+
+	l.Error("request failed", Fields{
+		"method":   "GET",
+		"trace_id": "abc123",
+		"tenant":   "acme",
+		"error":    errors.New("timeout"),
+		"custom":   1,
+	})
+
+	var doc map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+
+	get := func(path ...string) any {
+		var v any = doc
+		for _, p := range path {
+			m, ok := v.(map[string]any)
+			if !ok {
+				return nil
+			}
+			v = m[p]
+		}
+		return v
+	}
+
+	checks := []struct {
+		path []string
+		want any
+	}{
+		{[]string{"message"}, "request failed"},
+		{[]string{"log", "level"}, "error"},
+		{[]string{"ecs", "version"}, ECSVersion},
+		{[]string{"service", "name"}, "api"},
+		{[]string{"http", "request", "method"}, "GET"},
+		{[]string{"trace", "id"}, "abc123"},
+		{[]string{"organization", "id"}, "acme"},
+		{[]string{"error", "message"}, "timeout"},
+		{[]string{"custom"}, float64(1)},
+	}
+	for _, c := range checks {
+		if got := get(c.path...); got != c.want {
+			t.Errorf("expected %s = %v, got %v", strings.Join(c.path, "."), c.want, got)
+		}
+	}
+	if _, ok := doc["@timestamp"]; !ok {
+		t.Error("expected @timestamp")
+	}
+}
+
+func TestWithLevelOutput(t *testing.T) {
+	var stdout, warnings, errs bytes.Buffer
+	l := New(
+		WithOutput(&stdout),
+		WithFormatter(&TextFormatter{DisableTimestamp: true}),
+		WithLevelOutput(ErrorLevel, &errs, &JSONFormatter{DisableTimestamp: true}),
+		WithLevelOutput(WarnLevel, &warnings),
+	)
+
+	l.Info("info")
+	l.Warn("warn")
+	l.Error("error")
+
+	if stdout.String() != "INFO info\n" {
+		t.Errorf("expected only info on default output, got %q", stdout.String())
+	}
+	if warnings.String() != "WARN warn\n" {
+		t.Errorf("expected warn with default formatter, got %q", warnings.String())
+	}
+	if errs.String() != `{"level":"error","msg":"error"}`+"\n" {
+		t.Errorf("expected error with JSON formatter, got %q", errs.String())
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestMultiWriter(t *testing.T) {
+	var a, b bytes.Buffer
+	w := MultiWriter(&a, failingWriter{}, &b)
+
+	n, err := w.Write([]byte("line\n"))
+	if n != 5 {
+		t.Errorf("expected 5 bytes written, got %d", n)
+	}
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("expected joined error, got %v", err)
+	}
+	if a.String() != "line\n" || b.String() != "line\n" {
+		t.Errorf("expected both writers to receive the line, got %q and %q", a.String(), b.String())
+	}
+}
+
+func TestRedactor(t *testing.T) {
+	r := NewRedactor(RedactConfig{
+		KeyPatterns:   []*regexp.Regexp{regexp.MustCompile(`^ssn$`)},
+		ValuePatterns: []*regexp.Regexp{regexp.MustCompile(`Bearer \S+`)},
+	})
+
+	got := r.Redact(Fields{
+		"user":        "ann",
+		"Password":    "hunter2",
+		"X-API-Key":   "k",
+		"ssn":         "123",
+		"session_id":  "s",
+		"note":        "sent Bearer abc.def",
+		"credentials": Fields{"refresh_token": "r", "kind": "oauth"},
+	})
+
+	expected := map[string]any{
+		"user":        "ann",
+		"Password":    DefaultRedactMask,
+		"X-API-Key":   DefaultRedactMask,
+		"ssn":         DefaultRedactMask,
+		"session_id":  DefaultRedactMask,
+		"note":        "sent " + DefaultRedactMask,
+		"credentials": DefaultRedactMask,
+	}
+	for k, v := range expected {
+		if got[k] != v {
+			t.Errorf("expected %s = %v, got %v", k, v, got[k])
+		}
+	}
+
+	nested := r.RedactValue("data", map[string]any{"token": "t", "id": 1})
+	if m := nested.(map[string]any); m["token"] != DefaultRedactMask || m["id"] != 1 {
+		t.Errorf("expected nested redaction, got %v", m)
+	}
+}
+
+func TestRedactor_JSONAndQuery(t *testing.T) {
+	r := DefaultRedactor()
+
+	out := string(r.RedactJSON([]byte(`{"user":"ann","auth":{"password":"x"}}`)))
+	if strings.Contains(out, `"x"`) || !strings.Contains(out, `"ann"`) {
+		t.Errorf("unexpected redacted JSON: %s", out)
+	}
+
+	q := r.RedactQuery("page=2&access_token=abc")
+	if strings.Contains(q, "abc") || !strings.Contains(q, "page=2") {
+		t.Errorf("unexpected redacted query: %s", q)
+	}
+	if r.RedactQuery("page=2") != "page=2" {
+		t.Error("expected query without secrets to be unchanged")
+	}
+}
+
+func TestLogger_WithRedactor(t *testing.T) {
+	var buf bytes.Buffer
+	hook := &recordingHook{}
+	l := newTestLogger(&buf, WithRedactor(DefaultRedactor()), WithHooks(hook))
+
+	l.With("password", "hunter2").Error("login failed", Fields{"user": "ann"})
+
+	if strings.Contains(buf.String(), "hunter2") {
+		t.Errorf("expected password to be redacted, got %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), "password="+DefaultRedactMask) {
+		t.Errorf("expected mask in output, got %q", buf.String())
+	}
+}
+
+func TestLogger_Named(t *testing.T) {
+	var buf bytes.Buffer
+	root := newTestLogger(&buf)
+	db := root.Named("db")
+	pool := db.Named("pool")
+
+	if pool.Name() != "db.pool" {
+		t.Errorf("expected nested name db.pool, got %s", pool.Name())
+	}
+
+	root.SetModuleLevel("db", DebugLevel)
+	db.Debug("query")
+	root.Debug("hidden")
+	pool.Debug("hidden")
+
+	out := buf.String()
+	if !strings.Contains(out, "DEBUG query module=db") {
+		t.Errorf("expected db debug line, got %q", out)
+	}
+	if strings.Contains(out, "hidden") {
+		t.Errorf("expected other modules to keep the root level, got %q", out)
+	}
+
+	// Children of a named logger share its module level
+	if db.With("k", "v").Level() != DebugLevel {
+		t.Error("expected child to share the module level")
+	}
+
+	root.ResetModuleLevel("db")
+	if db.Level() != InfoLevel {
+		t.Errorf("expected db to follow root after reset, got %s", db.Level())
+	}
+	if len(root.ModuleLevels()) != 0 {
+		t.Errorf("expected no module levels, got %v", root.ModuleLevels())
+	}
+}
+
+func TestRingHook(t *testing.T) {
+	ring := NewRingHook(3)
+	l := New(WithOutput(&bytes.Buffer{}), WithHooks(ring))
+
+	for _, msg := range []string{"a", "b", "c", "d"} {
+		l.Info(msg)
+	}
+
+	entries := ring.Entries()
+	if len(entries) != 3 || ring.Len() != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	for i, want := range []string{"b", "c", "d"} {
+		if entries[i].Message != want {
+			t.Errorf("entry %d: expected %q, got %q", i, want, entries[i].Message)
+		}
+	}
+
+	ring.Reset()
+	if ring.Len() != 0 {
+		t.Errorf("expected empty ring after reset, got %d", ring.Len())
+	}
+}
@@ -0,0 +1,556 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrHookClosed is returned when flushing a closed hook.
+var ErrHookClosed = errors.New("logs: hook closed")
+
+// OTLPConfig configures the OTLP log export hook.
+type OTLPConfig struct {
+	// Endpoint is the OTLP/HTTP logs endpoint of the collector.
+	// Default: "http://localhost:4318/v1/logs"
+	Endpoint string
+
+	// Headers are added to every export request (e.g. authentication).
+	Headers map[string]string
+
+	// ServiceName sets the service.name resource attribute.
+	ServiceName string
+
+	// Resource holds additional resource attributes
+	// (e.g. "deployment.environment", "service.version").
+	Resource Fields
+
+	// Levels are the levels exported.
+	// Default: AllLevels
+	Levels []Level
+
+	// BatchSize is the maximum number of entries per export request.
+	// Default: 512
+	BatchSize int
+
+	// FlushInterval is how often a partial batch is exported.
+	// Default: 5s
+	FlushInterval time.Duration
+
+	// QueueSize is the number of entries buffered before new entries are dropped.
+	// Default: 2048
+	QueueSize int
+
+	// MaxRetries is the number of retries for a failed export.
+	// A negative value disables retries.
+	// Default: 3
+	MaxRetries int
+
+	// RetryBackoff is the initial delay between retries, doubled after each attempt.
+	// Default: 500ms
+	RetryBackoff time.Duration
+
+	// Client is the HTTP client used for exports.
+	// Default: a client with a 10s timeout
+	Client *http.Client
+}
+
+// DefaultOTLPConfig returns the default configuration for the OTLP hook.
+func DefaultOTLPConfig() OTLPConfig {
+	return OTLPConfig{
+		Endpoint:      "http://localhost:4318/v1/logs",
+		Levels:        AllLevels,
+		BatchSize:     512,
+		FlushInterval: 5 * time.Second,
+		QueueSize:     2048,
+		MaxRetries:    3,
+		RetryBackoff:  500 * time.Millisecond,
+		Client:        &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// OTLPHook batches entries and exports them to an OpenTelemetry collector
+// using OTLP/HTTP with JSON encoding. Fields become log record attributes;
+// "trace_id" and "span_id" fields populate the record's trace context.
+//
+// Example:
+//
+//	hook := logs.NewOTLPHook(logs.OTLPConfig{
+//	    Endpoint:    "http://otel-collector:4318/v1/logs",
+//	    ServiceName: "api",
+//	})
+//	defer hook.Close(context.Background())
+//
+//	logger := logs.New(logs.WithHooks(hook))
+type OTLPHook struct {
+	config   OTLPConfig
+	resource []otlpKeyValue
+
+	queue   chan Entry
+	flushCh chan chan error
+	done    chan struct{}
+	stopped chan struct{}
+	close   sync.Once
+	dropped atomic.Uint64
+}
+
+// NewOTLPHook creates an OTLP hook and starts its export loop.
+// Call Close to flush remaining entries and stop the loop.
+func NewOTLPHook(config OTLPConfig) *OTLPHook {
+	defaults := DefaultOTLPConfig()
+	if config.Endpoint == "" {
+		config.Endpoint = defaults.Endpoint
+	}
+	if config.Levels == nil {
+		config.Levels = defaults.Levels
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaults.BatchSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = defaults.FlushInterval
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaults.QueueSize
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = defaults.MaxRetries
+	} else if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = defaults.RetryBackoff
+	}
+	if config.Client == nil {
+		config.Client = defaults.Client
+	}
+
+	resource := mergeFields(nil, config.Resource)
+	if config.ServiceName != "" {
+		resource = mergeFields(resource, Fields{"service.name": config.ServiceName})
+	}
+
+	h := &OTLPHook{
+		config:   config,
+		resource: otlpAttributes(resource),
+		queue:    make(chan Entry, config.QueueSize),
+		flushCh:  make(chan chan error),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go h.run()
+	return h
+}
+
+// Levels implements Hook.
+func (h *OTLPHook) Levels() []Level {
+	return h.config.Levels
+}
+
+// Fire implements Hook. Entries are queued without blocking; when the
+// queue is full the entry is dropped and counted in Dropped.
+func (h *OTLPHook) Fire(entry *Entry) error {
+	select {
+	case <-h.done:
+		return nil
+	default:
+	}
+
+	select {
+	case h.queue <- *entry:
+	default:
+		h.dropped.Add(1)
+	}
+	return nil
+}
+
+// Dropped returns the number of entries dropped because the queue was full.
+func (h *OTLPHook) Dropped() uint64 {
+	return h.dropped.Load()
+}
+
+// Flush exports all queued entries and waits for the export to finish.
+func (h *OTLPHook) Flush(ctx context.Context) error {
+	result := make(chan error, 1)
+	select {
+	case h.flushCh <- result:
+	case <-h.stopped:
+		return ErrHookClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close flushes queued entries and stops the export loop.
+func (h *OTLPHook) Close(ctx context.Context) error {
+	h.close.Do(func() {
+		close(h.done)
+	})
+
+	select {
+	case <-h.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run collects entries into batches and exports them.
+func (h *OTLPHook) run() {
+	defer close(h.stopped)
+
+	ticker := time.NewTicker(h.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Entry, 0, h.config.BatchSize)
+	export := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := h.export(batch)
+		batch = batch[:0]
+		return err
+	}
+	drain := func() error {
+		var errs []error
+		for {
+			select {
+			case e := <-h.queue:
+				batch = append(batch, e)
+				if len(batch) >= h.config.BatchSize {
+					errs = append(errs, export())
+				}
+			default:
+				errs = append(errs, export())
+				return errors.Join(errs...)
+			}
+		}
+	}
+
+	for {
+		select {
+		case e := <-h.queue:
+			batch = append(batch, e)
+			if len(batch) >= h.config.BatchSize {
+				h.report(export())
+			}
+		case <-ticker.C:
+			h.report(export())
+		case result := <-h.flushCh:
+			result <- drain()
+		case <-h.done:
+			h.report(drain())
+			return
+		}
+	}
+}
+
+// report writes background export errors to stderr.
+func (h *OTLPHook) report(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "logs: OTLP export failed: %v\n", err)
+	}
+}
+
+// export sends a batch to the collector, retrying with exponential backoff.
+func (h *OTLPHook) export(batch []Entry) error {
+	body, err := json.Marshal(h.payload(batch))
+	if err != nil {
+		return err
+	}
+
+	backoff := h.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := h.send(body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= h.config.MaxRetries {
+			return err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-h.done:
+			// Shutting down: retry without waiting
+		}
+		backoff *= 2
+	}
+}
+
+// send performs a single export request and reports whether it can be retried.
+func (h *OTLPHook) send(body []byte) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, h.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range h.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := h.config.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusBadGateway,
+		resp.StatusCode == http.StatusServiceUnavailable,
+		resp.StatusCode == http.StatusGatewayTimeout:
+		return true, fmt.Errorf("logs: OTLP collector returned %s", resp.Status)
+	default:
+		return false, fmt.Errorf("logs: OTLP collector returned %s", resp.Status)
+	}
+}
+
+// --- OTLP JSON encoding ---
+
+type otlpPayload struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int            `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 otlpAnyValue   `json:"body"`
+	Attributes           []otlpKeyValue `json:"attributes,omitempty"`
+	TraceID              string         `json:"traceId,omitempty"`
+	SpanID               string         `json:"spanId,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string         `json:"stringValue,omitempty"`
+	BoolValue   *bool           `json:"boolValue,omitempty"`
+	IntValue    *string         `json:"intValue,omitempty"`
+	DoubleValue *float64        `json:"doubleValue,omitempty"`
+	ArrayValue  *otlpArrayValue `json:"arrayValue,omitempty"`
+	KvlistValue *otlpKvlist     `json:"kvlistValue,omitempty"`
+}
+
+type otlpArrayValue struct {
+	Values []otlpAnyValue `json:"values"`
+}
+
+type otlpKvlist struct {
+	Values []otlpKeyValue `json:"values"`
+}
+
+// payload builds the OTLP request body for a batch.
+func (h *OTLPHook) payload(batch []Entry) otlpPayload {
+	observed := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	records := make([]otlpLogRecord, len(batch))
+	for i, e := range batch {
+		fields := e.Fields
+		rec := otlpLogRecord{
+			TimeUnixNano:         strconv.FormatInt(e.Time.UnixNano(), 10),
+			ObservedTimeUnixNano: observed,
+			SeverityNumber:       otlpSeverity(e.Level),
+			SeverityText:         otlpSeverityText(e.Level),
+			Body:                 otlpValue(e.Message),
+		}
+
+		if id, ok := fields["trace_id"].(string); ok {
+			rec.TraceID = id
+		}
+		if id, ok := fields["span_id"].(string); ok {
+			rec.SpanID = id
+		}
+		if rec.TraceID != "" || rec.SpanID != "" {
+			fields = mergeFields(nil, fields)
+			delete(fields, "trace_id")
+			delete(fields, "span_id")
+		}
+		rec.Attributes = otlpAttributes(fields)
+
+		records[i] = rec
+	}
+
+	return otlpPayload{ResourceLogs: []otlpResourceLogs{{
+		Resource: otlpResource{Attributes: h.resource},
+		ScopeLogs: []otlpScopeLogs{{
+			Scope:      otlpScope{Name: "github.com/kolosys/helix/logs"},
+			LogRecords: records,
+		}},
+	}}}
+}
+
+// otlpSeverity maps a level to an OTel severity number.
+func otlpSeverity(level Level) int {
+	switch level {
+	case DebugLevel:
+		return 5
+	case WarnLevel:
+		return 13
+	case ErrorLevel:
+		return 17
+	default:
+		return 9
+	}
+}
+
+// otlpSeverityText maps a level to an OTel severity text.
+func otlpSeverityText(level Level) string {
+	switch level {
+	case DebugLevel:
+		return "DEBUG"
+	case WarnLevel:
+		return "WARN"
+	case ErrorLevel:
+		return "ERROR"
+	default:
+		return "INFO"
+	}
+}
+
+// otlpAttributes converts fields to OTel attributes, sorted by key.
+func otlpAttributes(fields Fields) []otlpKeyValue {
+	if len(fields) == 0 {
+		return nil
+	}
+	attrs := make([]otlpKeyValue, 0, len(fields))
+	for _, k := range sortedKeys(fields) {
+		attrs = append(attrs, otlpKeyValue{Key: k, Value: otlpValue(fields[k])})
+	}
+	return attrs
+}
+
+// otlpValue converts a field value to an OTel AnyValue.
+func otlpValue(v any) otlpAnyValue {
+	switch val := v.(type) {
+	case nil:
+		return otlpAnyValue{}
+	case string:
+		return otlpAnyValue{StringValue: &val}
+	case bool:
+		return otlpAnyValue{BoolValue: &val}
+	case int:
+		return otlpInt(int64(val))
+	case int8:
+		return otlpInt(int64(val))
+	case int16:
+		return otlpInt(int64(val))
+	case int32:
+		return otlpInt(int64(val))
+	case int64:
+		return otlpInt(val)
+	case uint:
+		return otlpUint(uint64(val))
+	case uint8:
+		return otlpInt(int64(val))
+	case uint16:
+		return otlpInt(int64(val))
+	case uint32:
+		return otlpInt(int64(val))
+	case uint64:
+		return otlpUint(val)
+	case float32:
+		f := float64(val)
+		return otlpAnyValue{DoubleValue: &f}
+	case float64:
+		return otlpAnyValue{DoubleValue: &val}
+	case time.Duration:
+		return otlpInt(int64(val))
+	case time.Time:
+		s := val.Format(time.RFC3339Nano)
+		return otlpAnyValue{StringValue: &s}
+	case error:
+		s := val.Error()
+		return otlpAnyValue{StringValue: &s}
+	case Fields:
+		return otlpAnyValue{KvlistValue: &otlpKvlist{Values: otlpAttributes(val)}}
+	case map[string]any:
+		return otlpAnyValue{KvlistValue: &otlpKvlist{Values: otlpAttributes(val)}}
+	case map[string]string:
+		kv := make([]otlpKeyValue, 0, len(val))
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			kv = append(kv, otlpKeyValue{Key: k, Value: otlpValue(val[k])})
+		}
+		return otlpAnyValue{KvlistValue: &otlpKvlist{Values: kv}}
+	case []any:
+		values := make([]otlpAnyValue, len(val))
+		for i, item := range val {
+			values[i] = otlpValue(item)
+		}
+		return otlpAnyValue{ArrayValue: &otlpArrayValue{Values: values}}
+	case []string:
+		values := make([]otlpAnyValue, len(val))
+		for i, item := range val {
+			values[i] = otlpValue(item)
+		}
+		return otlpAnyValue{ArrayValue: &otlpArrayValue{Values: values}}
+	case fmt.Stringer:
+		s := val.String()
+		return otlpAnyValue{StringValue: &s}
+	default:
+		s := fmt.Sprint(val)
+		return otlpAnyValue{StringValue: &s}
+	}
+}
+
+func otlpInt(v int64) otlpAnyValue {
+	s := strconv.FormatInt(v, 10)
+	return otlpAnyValue{IntValue: &s}
+}
+
+func otlpUint(v uint64) otlpAnyValue {
+	if v > math.MaxInt64 {
+		s := strconv.FormatUint(v, 10)
+		return otlpAnyValue{StringValue: &s}
+	}
+	return otlpInt(int64(v))
+}
@@ -0,0 +1,211 @@
+package logs_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/helix/logs"
+)
+
+type otlpCollector struct {
+	mu       sync.Mutex
+	payloads []map[string]any
+	failures atomic.Int32
+	server   *httptest.Server
+}
+
+func newOTLPCollector(t *testing.T, failures int32) *otlpCollector {
+	t.Helper()
+
+	c := &otlpCollector{}
+	c.failures.Store(failures)
+	c.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c.failures.Add(-1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var payload map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		c.mu.Lock()
+		c.payloads = append(c.payloads, payload)
+		c.mu.Unlock()
+	}))
+	t.Cleanup(c.server.Close)
+	return c
+}
+
+// records returns the log records of every received payload.
+func (c *otlpCollector) records() []map[string]any {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var records []map[string]any
+	for _, p := range c.payloads {
+		for _, rl := range p["resourceLogs"].([]any) {
+			for _, sl := range rl.(map[string]any)["scopeLogs"].([]any) {
+				for _, rec := range sl.(map[string]any)["logRecords"].([]any) {
+					records = append(records, rec.(map[string]any))
+				}
+			}
+		}
+	}
+	return records
+}
+
+func attribute(rec map[string]any, key string) map[string]any {
+	attrs, _ := rec["attributes"].([]any)
+	for _, a := range attrs {
+		kv := a.(map[string]any)
+		if kv["key"] == key {
+			return kv["value"].(map[string]any)
+		}
+	}
+	return nil
+}
+
+func TestOTLPHook_Export(t *testing.T) {
+	collector := newOTLPCollector(t, 0)
+
+	hook := NewOTLPHook(OTLPConfig{
+		Endpoint:    collector.server.URL,
+		Headers:     map[string]string{"Authorization": "Bearer key"},
+		ServiceName: "api",
+		Resource:    Fields{"deployment.environment": "test"},
+	})
+	defer hook.Close(context.Background())
+
+	logger := New(WithOutput(&discard{}), WithHooks(hook))
+	logger.Warn("slow query", Fields{
+		"duration_ms": 250,
+		"ok":          false,
+		"ratio":       0.5,
+		"trace_id":    "4bf92f3577b34da6a3ce929d0e0e4736",
+		"tags":        []string{"db"},
+	})
+
+	if err := hook.Flush(context.Background()); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+
+	records := collector.records()
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+	rec := records[0]
+
+	if rec["severityText"] != "WARN" || rec["severityNumber"] != float64(13) {
+		t.Errorf("unexpected severity: %v %v", rec["severityText"], rec["severityNumber"])
+	}
+	if rec["body"].(map[string]any)["stringValue"] != "slow query" {
+		t.Errorf("unexpected body: %v", rec["body"])
+	}
+	if rec["traceId"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected trace ID, got %v", rec["traceId"])
+	}
+	if attribute(rec, "trace_id") != nil {
+		t.Error("expected trace_id to be moved out of attributes")
+	}
+	if v := attribute(rec, "duration_ms"); v == nil || v["intValue"] != "250" {
+		t.Errorf("expected int attribute, got %v", v)
+	}
+	if v := attribute(rec, "ok"); v == nil || v["boolValue"] != false {
+		t.Errorf("expected bool attribute, got %v", v)
+	}
+	if v := attribute(rec, "ratio"); v == nil || v["doubleValue"] != 0.5 {
+		t.Errorf("expected double attribute, got %v", v)
+	}
+	if v := attribute(rec, "tags"); v == nil || v["arrayValue"] == nil {
+		t.Errorf("expected array attribute, got %v", v)
+	}
+
+	collector.mu.Lock()
+	resource := collector.payloads[0]["resourceLogs"].([]any)[0].(map[string]any)["resource"].(map[string]any)
+	collector.mu.Unlock()
+	if attribute(resource, "service.name")["stringValue"] != "api" {
+		t.Errorf("expected service.name resource attribute, got %v", resource)
+	}
+	if attribute(resource, "deployment.environment")["stringValue"] != "test" {
+		t.Errorf("expected custom resource attribute, got %v", resource)
+	}
+}
+
+func TestOTLPHook_Retry(t *testing.T) {
+	collector := newOTLPCollector(t, 2)
+
+	hook := NewOTLPHook(OTLPConfig{
+		Endpoint:     collector.server.URL,
+		Headers:      map[string]string{"Authorization": "Bearer key"},
+		RetryBackoff: time.Millisecond,
+	})
+	defer hook.Close(context.Background())
+
+	New(WithOutput(&discard{}), WithHooks(hook)).Info("hello")
+
+	if err := hook.Flush(context.Background()); err != nil {
+		t.Fatalf("expected retries to succeed, got %v", err)
+	}
+	if len(collector.records()) != 1 {
+		t.Errorf("expected 1 record after retries, got %d", len(collector.records()))
+	}
+}
+
+func TestOTLPHook_RetriesExhausted(t *testing.T) {
+	collector := newOTLPCollector(t, 10)
+
+	hook := NewOTLPHook(OTLPConfig{
+		Endpoint:     collector.server.URL,
+		MaxRetries:   1,
+		RetryBackoff: time.Millisecond,
+	})
+	defer hook.Close(context.Background())
+
+	New(WithOutput(&discard{}), WithHooks(hook)).Info("hello")
+
+	if err := hook.Flush(context.Background()); err == nil {
+		t.Error("expected export error after retries are exhausted")
+	}
+}
+
+func TestOTLPHook_CloseFlushes(t *testing.T) {
+	collector := newOTLPCollector(t, 0)
+
+	hook := NewOTLPHook(OTLPConfig{
+		Endpoint:      collector.server.URL,
+		Headers:       map[string]string{"Authorization": "Bearer key"},
+		FlushInterval: time.Hour,
+	})
+
+	logger := New(WithOutput(&discard{}), WithHooks(hook))
+	logger.Info("one")
+	logger.Info("two")
+
+	if err := hook.Close(context.Background()); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if len(collector.records()) != 2 {
+		t.Errorf("expected 2 records after close, got %d", len(collector.records()))
+	}
+	if err := hook.Flush(context.Background()); !errors.Is(err, ErrHookClosed) {
+		t.Errorf("expected ErrHookClosed, got %v", err)
+	}
+}
+
+type discard struct{}
+
+func (*discard) Write(p []byte) (int, error) { return len(p), nil }
@@ -0,0 +1,230 @@
+package logs
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// DefaultRedactMask replaces redacted values.
+const DefaultRedactMask = "[REDACTED]"
+
+// DefaultRedactKeys are field name fragments treated as sensitive.
+var DefaultRedactKeys = []string{
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"authorization",
+	"apikey",
+	"cookie",
+	"credential",
+	"privatekey",
+	"session",
+}
+
+// RedactConfig configures a Redactor.
+type RedactConfig struct {
+	// Keys are field name fragments whose values are masked. Matching is
+	// case-insensitive and ignores '-' and '_', so "api_key" matches
+	// "X-API-Key" and "apikey".
+	// Default: DefaultRedactKeys
+	Keys []string
+
+	// KeyPatterns mask fields whose names match any pattern.
+	KeyPatterns []*regexp.Regexp
+
+	// ValuePatterns mask matching substrings in string values of any field,
+	// e.g. bearer tokens or card numbers embedded in messages.
+	ValuePatterns []*regexp.Regexp
+
+	// Mask replaces redacted values.
+	// Default: "[REDACTED]"
+	Mask string
+}
+
+// DefaultRedactConfig returns the default redaction configuration.
+func DefaultRedactConfig() RedactConfig {
+	return RedactConfig{
+		Keys: DefaultRedactKeys,
+		Mask: DefaultRedactMask,
+	}
+}
+
+// Redactor masks sensitive values before they are logged.
+type Redactor struct {
+	keys          []string
+	keyPatterns   []*regexp.Regexp
+	valuePatterns []*regexp.Regexp
+	mask          string
+}
+
+// NewRedactor creates a Redactor with the given configuration.
+func NewRedactor(config RedactConfig) *Redactor {
+	if config.Keys == nil {
+		config.Keys = DefaultRedactKeys
+	}
+	if config.Mask == "" {
+		config.Mask = DefaultRedactMask
+	}
+
+	keys := make([]string, 0, len(config.Keys))
+	for _, k := range config.Keys {
+		if k = normalizeKey(k); k != "" {
+			keys = append(keys, k)
+		}
+	}
+
+	return &Redactor{
+		keys:          keys,
+		keyPatterns:   config.KeyPatterns,
+		valuePatterns: config.ValuePatterns,
+		mask:          config.Mask,
+	}
+}
+
+// DefaultRedactor returns a Redactor using DefaultRedactConfig.
+func DefaultRedactor() *Redactor {
+	return NewRedactor(DefaultRedactConfig())
+}
+
+// WithRedactor masks sensitive fields before entries reach hooks and formatters.
+func WithRedactor(r *Redactor) Option {
+	return func(l *Logger) {
+		l.core.redactor = r
+	}
+}
+
+// IsSensitive reports whether values of the named field are masked.
+func (r *Redactor) IsSensitive(key string) bool {
+	norm := normalizeKey(key)
+	for _, k := range r.keys {
+		if strings.Contains(norm, k) {
+			return true
+		}
+	}
+	for _, p := range r.keyPatterns {
+		if p.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactString masks a string value for the named field.
+func (r *Redactor) RedactString(key, value string) string {
+	if value == "" {
+		return value
+	}
+	if r.IsSensitive(key) {
+		return r.mask
+	}
+	for _, p := range r.valuePatterns {
+		value = p.ReplaceAllString(value, r.mask)
+	}
+	return value
+}
+
+// RedactValue masks a value for the named field.
+// Nested maps and slices are redacted recursively.
+func (r *Redactor) RedactValue(key string, value any) any {
+	if r.IsSensitive(key) {
+		return r.mask
+	}
+
+	switch v := value.(type) {
+	case string:
+		return r.RedactString(key, v)
+	case error:
+		return r.RedactString(key, v.Error())
+	case Fields:
+		return r.Redact(v)
+	case map[string]any:
+		return map[string]any(r.Redact(v))
+	case map[string]string:
+		return r.RedactStrings(v)
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = r.RedactValue(key, item)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+// Redact returns a copy of fields with sensitive values masked.
+func (r *Redactor) Redact(fields Fields) Fields {
+	if len(fields) == 0 {
+		return fields
+	}
+	out := make(Fields, len(fields))
+	for k, v := range fields {
+		out[k] = r.RedactValue(k, v)
+	}
+	return out
+}
+
+// RedactStrings returns a copy of the map with sensitive values masked.
+func (r *Redactor) RedactStrings(values map[string]string) map[string]string {
+	if len(values) == 0 {
+		return values
+	}
+	out := make(map[string]string, len(values))
+	for k, v := range values {
+		out[k] = r.RedactString(k, v)
+	}
+	return out
+}
+
+// RedactQuery returns the raw query string with sensitive parameters masked.
+func (r *Redactor) RedactQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return rawQuery
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return rawQuery
+	}
+
+	changed := false
+	for k, vals := range query {
+		for i, v := range vals {
+			if masked := r.RedactString(k, v); masked != v {
+				vals[i] = masked
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return rawQuery
+	}
+	return query.Encode()
+}
+
+// RedactJSON masks sensitive keys in a JSON document.
+// Invalid JSON is returned unchanged apart from value pattern masking.
+func (r *Redactor) RedactJSON(body []byte) []byte {
+	if len(body) == 0 {
+		return body
+	}
+
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return []byte(r.RedactString("", string(body)))
+	}
+
+	out, err := json.Marshal(r.RedactValue("", doc))
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// normalizeKey lowercases a key and strips separators.
+func normalizeKey(key string) string {
+	key = strings.ToLower(key)
+	return strings.NewReplacer("-", "", "_", "", ".", "", " ", "").Replace(key)
+}
@@ -0,0 +1,87 @@
+package logs
+
+import "sync"
+
+// RingHook retains the most recent entries in memory, for quick triage
+// when centralized logging is lagging.
+//
+// Example:
+//
+//	ring := logs.NewRingHook(500)
+//	logger := logs.New(logs.WithHooks(ring))
+type RingHook struct {
+	mu      sync.Mutex
+	levels  []Level
+	entries []Entry
+	next    int
+	full    bool
+}
+
+// NewRingHook creates a hook that keeps the last n entries at the given
+// levels. If no levels are given, entries at all levels are kept.
+func NewRingHook(n int, levels ...Level) *RingHook {
+	if n <= 0 {
+		n = 100
+	}
+	if len(levels) == 0 {
+		levels = AllLevels
+	}
+	return &RingHook{
+		levels:  levels,
+		entries: make([]Entry, n),
+	}
+}
+
+// Levels implements Hook.
+func (h *RingHook) Levels() []Level {
+	return h.levels
+}
+
+// Fire implements Hook.
+func (h *RingHook) Fire(entry *Entry) error {
+	h.mu.Lock()
+	h.entries[h.next] = *entry
+	h.next = (h.next + 1) % len(h.entries)
+	if h.next == 0 {
+		h.full = true
+	}
+	h.mu.Unlock()
+	return nil
+}
+
+// Entries returns the retained entries, oldest first.
+func (h *RingHook) Entries() []Entry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.full {
+		out := make([]Entry, h.next)
+		copy(out, h.entries[:h.next])
+		return out
+	}
+
+	out := make([]Entry, 0, len(h.entries))
+	out = append(out, h.entries[h.next:]...)
+	out = append(out, h.entries[:h.next]...)
+	return out
+}
+
+// Len returns the number of retained entries.
+func (h *RingHook) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.full {
+		return len(h.entries)
+	}
+	return h.next
+}
+
+// Reset discards all retained entries.
+func (h *RingHook) Reset() {
+	h.mu.Lock()
+	clear(h.entries)
+	h.next = 0
+	h.full = false
+	h.mu.Unlock()
+}
@@ -0,0 +1,159 @@
+package logs
+
+import (
+	"hash/fnv"
+	"sync/atomic"
+	"time"
+)
+
+// SamplerFunc adapts a function to the Sampler interface.
+type SamplerFunc func(entry *Entry) bool
+
+// Sample implements Sampler.
+func (f SamplerFunc) Sample(entry *Entry) bool {
+	return f(entry)
+}
+
+// samplerBuckets is the number of counters shared by all sampling keys.
+// Keys that hash to the same bucket share a counter, bounding memory.
+const samplerBuckets = 4096
+
+// SamplerConfig configures a burst sampler.
+type SamplerConfig struct {
+	// Tick is the window after which counters reset.
+	// Default: 1s
+	Tick time.Duration
+
+	// First is the number of entries per key logged in each tick.
+	// Default: 100
+	First int
+
+	// Thereafter logs every Mth entry per key after First within a tick.
+	// Zero drops all entries after First.
+	// Default: 0
+	Thereafter int
+
+	// Key groups entries that are counted together.
+	// Default: the entry's level and message
+	Key func(entry *Entry) string
+}
+
+// DefaultSamplerConfig returns the default burst sampler configuration.
+func DefaultSamplerConfig() SamplerConfig {
+	return SamplerConfig{
+		Tick:  time.Second,
+		First: 100,
+	}
+}
+
+// burstSampler logs the first N entries per key per tick, then 1 in M.
+type burstSampler struct {
+	tick       int64
+	first      uint64
+	thereafter uint64
+	key        func(entry *Entry) string
+	counters   [samplerBuckets]sampleCounter
+}
+
+// sampleCounter counts entries for a bucket within the current tick.
+type sampleCounter struct {
+	resetAt atomic.Int64
+	count   atomic.Uint64
+}
+
+// NewSampler returns a sampler with zap's semantics: within each tick, the
+// first First entries per key are logged, then every Thereafter-th entry.
+//
+// Example:
+//
+//	// Per message: 100 per second, then 1 in 100
+//	logs.New(logs.WithSampler(logs.NewSampler(logs.SamplerConfig{
+//	    First:      100,
+//	    Thereafter: 100,
+//	})))
+func NewSampler(config SamplerConfig) Sampler {
+	if config.Tick <= 0 {
+		config.Tick = time.Second
+	}
+	if config.First <= 0 {
+		config.First = DefaultSamplerConfig().First
+	}
+	if config.Thereafter < 0 {
+		config.Thereafter = 0
+	}
+	if config.Key == nil {
+		config.Key = levelMessageKey
+	}
+
+	return &burstSampler{
+		tick:       int64(config.Tick),
+		first:      uint64(config.First),
+		thereafter: uint64(config.Thereafter),
+		key:        config.Key,
+	}
+}
+
+// NewKeySampler returns a burst sampler that counts entries by the value
+// of a field, e.g. "route" or "user_id". Entries without the field are
+// counted together.
+func NewKeySampler(field string, tick time.Duration, first, thereafter int) Sampler {
+	return NewSampler(SamplerConfig{
+		Tick:       tick,
+		First:      first,
+		Thereafter: thereafter,
+		Key: func(e *Entry) string {
+			return quoteValue(e.Fields[field])
+		},
+	})
+}
+
+// Sample implements Sampler.
+func (s *burstSampler) Sample(entry *Entry) bool {
+	h := fnv.New32a()
+	h.Write([]byte(s.key(entry)))
+	c := &s.counters[h.Sum32()%samplerBuckets]
+
+	n := c.inc(entry.Time.UnixNano(), s.tick)
+	if n <= s.first {
+		return true
+	}
+	return s.thereafter > 0 && (n-s.first)%s.thereafter == 0
+}
+
+// inc increments the counter, resetting it when the tick has elapsed.
+func (c *sampleCounter) inc(now, tick int64) uint64 {
+	resetAt := c.resetAt.Load()
+	if resetAt > now {
+		return c.count.Add(1)
+	}
+
+	c.count.Store(1)
+	if !c.resetAt.CompareAndSwap(resetAt, now+tick) {
+		// Another goroutine reset the counter first
+		return c.count.Add(1)
+	}
+	return 1
+}
+
+// levelMessageKey is the default sampling key.
+func levelMessageKey(e *Entry) string {
+	return e.Level.String() + "\x00" + e.Message
+}
+
+// LevelSampler applies a sampler per level. Levels without a sampler
+// are always logged, so errors can bypass sampling entirely.
+//
+// Example:
+//
+//	logs.WithSampler(logs.LevelSampler(map[logs.Level]logs.Sampler{
+//	    logs.DebugLevel: logs.NewSampler(logs.SamplerConfig{First: 10}),
+//	    logs.InfoLevel:  logs.NewSampler(logs.SamplerConfig{First: 100, Thereafter: 10}),
+//	}))
+func LevelSampler(samplers map[Level]Sampler) Sampler {
+	return SamplerFunc(func(e *Entry) bool {
+		if s, ok := samplers[e.Level]; ok && s != nil {
+			return s.Sample(e)
+		}
+		return true
+	})
+}
@@ -0,0 +1,82 @@
+package logs_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/kolosys/helix/logs"
+)
+
+func sampled(s Sampler, entries ...*Entry) int {
+	n := 0
+	for _, e := range entries {
+		if s.Sample(e) {
+			n++
+		}
+	}
+	return n
+}
+
+func repeat(e Entry, n int) []*Entry {
+	entries := make([]*Entry, n)
+	for i := range entries {
+		c := e
+		entries[i] = &c
+	}
+	return entries
+}
+
+func TestSampler_BurstThenThereafter(t *testing.T) {
+	now := time.Now()
+	s := NewSampler(SamplerConfig{Tick: time.Second, First: 3, Thereafter: 5})
+
+	// 3 first + every 5th of the remaining 20
+	if n := sampled(s, repeat(Entry{Time: now, Message: "hit"}, 23)...); n != 7 {
+		t.Errorf("expected 7 sampled entries, got %d", n)
+	}
+
+	// A different message has its own budget
+	if n := sampled(s, repeat(Entry{Time: now, Message: "other"}, 3)...); n != 3 {
+		t.Errorf("expected separate budget per message, got %d", n)
+	}
+
+	// Counters reset after the tick
+	later := now.Add(2 * time.Second)
+	if n := sampled(s, repeat(Entry{Time: later, Message: "hit"}, 3)...); n != 3 {
+		t.Errorf("expected counter reset after tick, got %d", n)
+	}
+}
+
+func TestSampler_NoThereafter(t *testing.T) {
+	s := NewSampler(SamplerConfig{First: 2})
+
+	if n := sampled(s, repeat(Entry{Time: time.Now(), Message: "x"}, 10)...); n != 2 {
+		t.Errorf("expected only the first 2 entries, got %d", n)
+	}
+}
+
+func TestKeySampler(t *testing.T) {
+	now := time.Now()
+	s := NewKeySampler("route", time.Second, 1, 0)
+
+	a := repeat(Entry{Time: now, Message: "req", Fields: Fields{"route": "/a"}}, 5)
+	b := repeat(Entry{Time: now, Message: "req", Fields: Fields{"route": "/b"}}, 5)
+
+	if n := sampled(s, append(a, b...)...); n != 2 {
+		t.Errorf("expected one entry per route, got %d", n)
+	}
+}
+
+func TestLevelSampler(t *testing.T) {
+	now := time.Now()
+	s := LevelSampler(map[Level]Sampler{
+		InfoLevel: SamplerFunc(func(*Entry) bool { return false }),
+	})
+
+	if sampled(s, repeat(Entry{Time: now, Level: InfoLevel}, 3)...) != 0 {
+		t.Error("expected info entries to be dropped")
+	}
+	if sampled(s, repeat(Entry{Time: now, Level: ErrorLevel}, 3)...) != 3 {
+		t.Error("expected error entries to bypass sampling")
+	}
+}
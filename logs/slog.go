@@ -0,0 +1,135 @@
+package logs
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogHandler is a slog.Handler that routes records through a Logger,
+// applying its level, formatter, hooks, and sampler.
+//
+// Example:
+//
+//	slog.SetDefault(slog.New(logs.NewSlogHandler(logger)))
+//	slog.Info("user created", "id", 42)
+type SlogHandler struct {
+	logger *Logger
+	prefix string // dotted group prefix for attribute keys
+}
+
+// NewSlogHandler returns a slog.Handler that writes through the logger.
+func NewSlogHandler(l *Logger) *SlogHandler {
+	return &SlogHandler{logger: l}
+}
+
+// Enabled implements slog.Handler.
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.Enabled(fromSlogLevel(level))
+}
+
+// Handle implements slog.Handler.
+func (h *SlogHandler) Handle(_ context.Context, r slog.Record) error {
+	entry := &Entry{
+		Time:    r.Time,
+		Level:   fromSlogLevel(r.Level),
+		Message: r.Message,
+		Fields:  h.logger.fields,
+	}
+
+	if r.NumAttrs() > 0 {
+		fields := make(Fields, r.NumAttrs())
+		r.Attrs(func(a slog.Attr) bool {
+			addAttr(fields, h.prefix, a)
+			return true
+		})
+		entry.Fields = mergeFields(entry.Fields, fields)
+	}
+
+	h.logger.write(entry)
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make(Fields, len(attrs))
+	for _, a := range attrs {
+		addAttr(fields, h.prefix, a)
+	}
+	return &SlogHandler{logger: h.logger.WithFields(fields), prefix: h.prefix}
+}
+
+// WithGroup implements slog.Handler.
+// Attributes added afterwards are keyed as "group.key".
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &SlogHandler{logger: h.logger, prefix: h.prefix + name + "."}
+}
+
+// FromSlog returns a Logger that writes entries to the slog handler.
+// Level filtering is delegated to the handler; hooks and samplers passed
+// as options still apply.
+func FromSlog(h slog.Handler, opts ...Option) *Logger {
+	l := New(append([]Option{WithLevel(DebugLevel)}, opts...)...)
+	l.core.sink = func(e *Entry) {
+		level := toSlogLevel(e.Level)
+		if !h.Enabled(context.Background(), level) {
+			return
+		}
+
+		r := slog.NewRecord(e.Time, level, e.Message, 0)
+		for _, k := range sortedKeys(e.Fields) {
+			r.AddAttrs(slog.Any(k, e.Fields[k]))
+		}
+		_ = h.Handle(context.Background(), r)
+	}
+	return l
+}
+
+// addAttr flattens an attribute into fields, expanding groups into dotted keys.
+func addAttr(fields Fields, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if a.Key != "" {
+			groupPrefix = prefix + a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			addAttr(fields, groupPrefix, ga)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	fields[prefix+a.Key] = v.Any()
+}
+
+// fromSlogLevel maps a slog level to the nearest logs level.
+func fromSlogLevel(level slog.Level) Level {
+	switch {
+	case level >= slog.LevelError:
+		return ErrorLevel
+	case level >= slog.LevelWarn:
+		return WarnLevel
+	case level >= slog.LevelInfo:
+		return InfoLevel
+	default:
+		return DebugLevel
+	}
+}
+
+// toSlogLevel maps a logs level to the corresponding slog level.
+func toSlogLevel(level Level) slog.Level {
+	switch level {
+	case DebugLevel:
+		return slog.LevelDebug
+	case WarnLevel:
+		return slog.LevelWarn
+	case ErrorLevel:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
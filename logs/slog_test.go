@@ -0,0 +1,69 @@
+package logs_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	. "github.com/kolosys/helix/logs"
+)
+
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(WithOutput(&buf), WithFormatter(&JSONFormatter{}))
+
+	slogger := slog.New(NewSlogHandler(l)).With("service", "api").WithGroup("req")
+	slogger.Warn("slow request", "id", 42, slog.Group("db", "table", "users"))
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+
+	expected := map[string]any{
+		"msg":          "slow request",
+		"level":        "warn",
+		"service":      "api",
+		"req.id":       float64(42),
+		"req.db.table": "users",
+	}
+	for k, v := range expected {
+		if entry[k] != v {
+			t.Errorf("expected %s = %v, got %v", k, v, entry[k])
+		}
+	}
+}
+
+func TestSlogHandler_Enabled(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(WithOutput(&buf), WithLevel(WarnLevel))
+
+	slogger := slog.New(NewSlogHandler(l))
+	slogger.Info("dropped")
+	slogger.Debug("dropped")
+
+	if buf.Len() != 0 {
+		t.Errorf("expected records below the logger level to be dropped, got %q", buf.String())
+	}
+}
+
+func TestFromSlog(t *testing.T) {
+	var buf bytes.Buffer
+	h := slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})
+
+	l := FromSlog(h).With("component", "cache")
+	l.Debug("dropped")
+	l.Error("miss", Fields{"key": "user:1"})
+
+	out := buf.String()
+	if strings.Contains(out, "dropped") {
+		t.Errorf("expected slog handler level to apply, got %q", out)
+	}
+	for _, want := range []string{"level=ERROR", "msg=miss", "component=cache", "key=user:1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got %q", want, out)
+		}
+	}
+}
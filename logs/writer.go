@@ -0,0 +1,39 @@
+package logs
+
+import (
+	"errors"
+	"io"
+)
+
+// multiWriter duplicates writes to every writer, continuing past failures.
+type multiWriter struct {
+	writers []io.Writer
+}
+
+// MultiWriter returns a writer that duplicates each entry to all writers.
+// Unlike io.MultiWriter, a failing writer does not stop the others from
+// receiving the entry; the errors are joined and returned.
+func MultiWriter(writers ...io.Writer) io.Writer {
+	w := make([]io.Writer, 0, len(writers))
+	for _, writer := range writers {
+		if writer != nil {
+			w = append(w, writer)
+		}
+	}
+	return &multiWriter{writers: w}
+}
+
+// Write implements io.Writer.
+func (m *multiWriter) Write(p []byte) (int, error) {
+	var errs []error
+	for _, w := range m.writers {
+		n, err := w.Write(p)
+		if err == nil && n < len(p) {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return len(p), errors.Join(errs...)
+}
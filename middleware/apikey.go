@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"net/http"
+)
+
+// APIKeyConfig configures the APIKey middleware.
+type APIKeyConfig struct {
+	// Lookup returns the principal owning key, or nil if the key is
+	// unknown. A non-nil error means the key could not be checked, and the
+	// request is failed rather than denied. Required.
+	Lookup func(ctx context.Context, key string) (*Principal, error)
+
+	// Header is the request header carrying the key.
+	// Default: "X-API-Key"
+	Header string
+
+	// Query, if set, is a query parameter also accepted for the key, for
+	// clients that cannot set headers. Keys in URLs end up in access logs
+	// and browser history, so prefer the header.
+	Query string
+
+	// SkipFunc determines if authentication should be skipped.
+	SkipFunc func(r *http.Request) bool
+}
+
+// APIKey returns a middleware that authenticates requests by the API key in
+// the X-API-Key header against keys, a map of keys to their principals.
+// Keys are looked up by their SHA-256 digest, so lookups take the same time
+// whatever the key. See APIKeyWithConfig.
+//
+// Example:
+//
+//	api := s.Group("/api", middleware.APIKey(map[string]*middleware.Principal{
+//	    os.Getenv("BILLING_KEY"): {ID: "billing", Scopes: []string{"invoices:read"}},
+//	}))
+func APIKey(keys map[string]*Principal) Middleware {
+	digests := make(map[[sha256.Size]byte]*Principal, len(keys))
+	for key, p := range keys {
+		digests[sha256.Sum256([]byte(key))] = p
+	}
+	return APIKeyWithConfig(APIKeyConfig{
+		Lookup: func(_ context.Context, key string) (*Principal, error) {
+			return digests[sha256.Sum256([]byte(key))], nil
+		},
+	})
+}
+
+// APIKeyWithConfig returns an APIKey middleware with the given
+// configuration. Requests without a key, or with an unknown one, get a 401
+// problem; authenticated requests carry the key's Principal.
+func APIKeyWithConfig(config APIKeyConfig) Middleware {
+	if config.Lookup == nil {
+		panic("helix: APIKey lookup is required")
+	}
+	if config.Header == "" {
+		config.Header = "X-API-Key"
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if config.SkipFunc != nil && config.SkipFunc(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := r.Header.Get(config.Header)
+			if key == "" && config.Query != "" {
+				key = r.URL.Query().Get(config.Query)
+			}
+			if key == "" {
+				writeProblem(w, r, http.StatusUnauthorized, "API key required", nil)
+				return
+			}
+
+			p, err := config.Lookup(r.Context(), key)
+			if err != nil {
+				writeProblem(w, r, http.StatusInternalServerError, "authentication check failed", nil)
+				return
+			}
+			if p == nil {
+				writeProblem(w, r, http.StatusUnauthorized, "invalid API key", nil)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/kolosys/helix/logs"
+)
+
+// Principal is the authenticated identity of a request.
+type Principal struct {
+	// ID identifies the principal, such as a username or user ID.
+	ID string
+
+	// Roles are the roles granted to the principal, such as "admin".
+	Roles []string
+
+	// Scopes are permissions granted to the principal directly, such as
+	// OAuth scopes ("articles:write").
+	Scopes []string
+
+	// Attrs holds further attributes of the principal, such as the claims
+	// of a token ("email", "tenant").
+	Attrs map[string]any
+}
+
+// HasRole reports whether the principal has role.
+func (p *Principal) HasRole(role string) bool {
+	return p != nil && slices.Contains(p.Roles, role)
+}
+
+// Attr returns the string attribute name, or "" if it is missing or not a
+// string.
+func (p *Principal) Attr(name string) string {
+	if p == nil {
+		return ""
+	}
+	v, _ := p.Attrs[name].(string)
+	return v
+}
+
+// PrincipalFromClaims returns the principal described by the claims of a
+// token: the "sub" claim is the ID, "roles" the roles, and "scope" or "scp"
+// the scopes, as space-separated strings or lists. All claims are kept in
+// Attrs.
+func PrincipalFromClaims(claims map[string]any) *Principal {
+	p := &Principal{Attrs: claims}
+	p.ID, _ = claims["sub"].(string)
+	p.Roles = claimList(claims["roles"])
+	p.Scopes = claimList(claims["scope"])
+	if p.Scopes == nil {
+		p.Scopes = claimList(claims["scp"])
+	}
+	return p
+}
+
+// claimList returns the strings of a space-separated or list claim.
+func claimList(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		var list []string
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	case []string:
+		return v
+	}
+	return nil
+}
+
+// principalKey is the context key for the principal.
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p. Authentication middleware
+// calls it once the credentials are verified. It also reports p to the
+// Logger wrapping the request, which logs its ID, and adds the ID as the
+// "principal" field of the request logger of ContextLogger.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	if h, ok := ctx.Value(routeKey{}).(*routeHolder); ok {
+		h.principal = p
+		if h.contextLogger && p != nil && p.ID != "" {
+			ctx = logs.NewContext(ctx, logs.FromContext(ctx).With("principal", p.ID))
+		}
+	}
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal stored in ctx, or nil if the request
+// is not authenticated.
+func PrincipalFrom(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// PolicyProvider decides whether a principal holds a permission.
+type PolicyProvider interface {
+	// Allowed reports whether p holds permission. A non-nil error means the
+	// decision could not be made, and the request is failed rather than denied.
+	Allowed(ctx context.Context, p *Principal, permission string) (bool, error)
+}
+
+// PolicyFunc adapts a function to a PolicyProvider.
+type PolicyFunc func(ctx context.Context, p *Principal, permission string) (bool, error)
+
+// Allowed implements PolicyProvider.
+func (f PolicyFunc) Allowed(ctx context.Context, p *Principal, permission string) (bool, error) {
+	return f(ctx, p, permission)
+}
+
+// RolePolicy maps role names to the permissions they grant. A principal holds
+// a permission if one of its scopes or one of its roles grants it. Granted
+// permissions may end in "*" to match any suffix, so "articles:*" grants
+// "articles:write" and "*" grants everything.
+//
+// Example:
+//
+//	policy := middleware.RolePolicy{
+//	    "admin":  {"*"},
+//	    "editor": {"articles:*"},
+//	    "viewer": {"articles:read"},
+//	}
+type RolePolicy map[string][]string
+
+// Allowed implements PolicyProvider.
+func (rp RolePolicy) Allowed(_ context.Context, p *Principal, permission string) (bool, error) {
+	if grants(p.Scopes, permission) {
+		return true, nil
+	}
+	for _, role := range p.Roles {
+		if grants(rp[role], permission) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// grants reports whether one of granted matches permission.
+func grants(granted []string, permission string) bool {
+	for _, g := range granted {
+		if g == permission {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(g, "*"); ok && strings.HasPrefix(permission, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// policyKey is the context key for the policy installed by Authorization.
+type policyKey struct{}
+
+// Authorization returns a middleware that makes policy the PolicyProvider
+// for RequirePermission and Can in the requests it handles. Without it,
+// permissions are checked against the principal's scopes only.
+func Authorization(policy PolicyProvider) Middleware {
+	if policy == nil {
+		panic("helix: Authorization policy is required")
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), policyKey{}, policy)))
+		})
+	}
+}
+
+// Can reports whether the principal of ctx holds permission under the
+// policy installed by Authorization. It reports false for unauthenticated
+// requests.
+func Can(ctx context.Context, permission string) (bool, error) {
+	p := PrincipalFrom(ctx)
+	if p == nil {
+		return false, nil
+	}
+	policy, ok := ctx.Value(policyKey{}).(PolicyProvider)
+	if !ok {
+		return grants(p.Scopes, permission), nil
+	}
+	return policy.Allowed(ctx, p, permission)
+}
+
+// RequireRole returns a middleware that allows principals having at least one
+// of roles. Unauthenticated requests get a 401 and others a 403 problem
+// response. Place it after the authentication middleware.
+func RequireRole(roles ...string) Middleware {
+	if len(roles) == 0 {
+		panic("helix: RequireRole requires at least one role")
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := PrincipalFrom(r.Context())
+			if p == nil {
+				writeProblem(w, r, http.StatusUnauthorized, "authentication required", nil)
+				return
+			}
+			if !slices.ContainsFunc(roles, p.HasRole) {
+				writeProblem(w, r, http.StatusForbidden, "missing role "+strings.Join(quoteAll(roles), " or "), nil)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequirePermission returns a middleware that allows principals holding all
+// of permissions. Unauthenticated requests get a 401 and others a 403
+// problem response. Place it after the authentication middleware and
+// Authorization.
+//
+// Example:
+//
+//	admin := s.Group("/admin", auth, middleware.Authorization(policy))
+//	admin.POST("/articles", createArticle, middleware.RequirePermission("articles:write"))
+func RequirePermission(permissions ...string) Middleware {
+	if len(permissions) == 0 {
+		panic("helix: RequirePermission requires at least one permission")
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if PrincipalFrom(r.Context()) == nil {
+				writeProblem(w, r, http.StatusUnauthorized, "authentication required", nil)
+				return
+			}
+			for _, perm := range permissions {
+				ok, err := Can(r.Context(), perm)
+				if err != nil {
+					writeProblem(w, r, http.StatusInternalServerError, "authorization check failed", nil)
+					return
+				}
+				if !ok {
+					writeProblem(w, r, http.StatusForbidden, `missing permission "`+perm+`"`, nil)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// quoteAll returns values in double quotes.
+func quoteAll(values []string) []string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = `"` + v + `"`
+	}
+	return quoted
+}
@@ -1,17 +1,41 @@
 package middleware
 
 import (
+	"context"
+	"crypto/pbkdf2"
+	"crypto/rand"
 	"crypto/sha256"
 	"crypto/subtle"
+	"encoding/base64"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 // BasicAuthConfig configures the BasicAuth middleware.
 type BasicAuthConfig struct {
 	// Validator is a function that validates the username and password.
-	// Return true if the credentials are valid.
+	// Return true if the credentials are valid. Compare secrets in
+	// constant time, as with crypto/subtle. If set, Store is ignored.
 	Validator func(username, password string) bool
 
+	// Store looks up the password hashes that credentials are checked
+	// against with Verify. One of Validator and Store is required.
+	Store CredentialStore
+
+	// Verify checks a password against a hash from Store.
+	// Default: VerifyPassword
+	Verify func(hash, password string) bool
+
+	// DummyHash is the hash Verify checks the passwords of unknown users
+	// against, so they take as long as wrong passwords. It should be of the
+	// scheme and cost of the Store's hashes.
+	// Default: the hash of the last known user checked, or a HashPassword
+	// hash before the first
+	DummyHash string
+
 	// Realm is the authentication realm displayed in the browser.
 	// Default: "Restricted"
 	Realm string
@@ -20,12 +44,42 @@ type BasicAuthConfig struct {
 	SkipFunc func(r *http.Request) bool
 }
 
+// CredentialStore looks up stored password hashes, such as in a users
+// table, for BasicAuth.
+type CredentialStore interface {
+	// PasswordHash returns the stored hash of username's password, or ""
+	// if there is no such user. A non-nil error means the credentials could
+	// not be checked, and the request is failed rather than denied.
+	PasswordHash(ctx context.Context, username string) (string, error)
+}
+
+// CredentialStoreFunc adapts a function to a CredentialStore.
+type CredentialStoreFunc func(ctx context.Context, username string) (string, error)
+
+// PasswordHash implements CredentialStore.
+func (f CredentialStoreFunc) PasswordHash(ctx context.Context, username string) (string, error) {
+	return f(ctx, username)
+}
+
+// CredentialMap is a CredentialStore of usernames to password hashes, as
+// made by HashPassword.
+type CredentialMap map[string]string
+
+// PasswordHash implements CredentialStore.
+func (m CredentialMap) PasswordHash(_ context.Context, username string) (string, error) {
+	return m[username], nil
+}
+
 // BasicAuth returns a BasicAuth middleware with the given username and password.
 // Uses constant-time comparison to prevent timing attacks.
 func BasicAuth(username, password string) Middleware {
 	return BasicAuthWithConfig(BasicAuthConfig{
 		Validator: func(u, p string) bool {
-			return secureCompare(u, username) && secureCompare(p, password)
+			// Compare both, so the time taken does not tell a wrong
+			// username from a wrong password
+			userOK := secureCompare(u, username)
+			passOK := secureCompare(p, password)
+			return userOK && passOK
 		},
 		Realm: "Restricted",
 	})
@@ -40,13 +94,21 @@ func BasicAuthWithValidator(validator func(username, password string) bool) Midd
 }
 
 // BasicAuthWithConfig returns a BasicAuth middleware with the given configuration.
+// Authenticated requests carry a Principal whose ID is the username.
 func BasicAuthWithConfig(config BasicAuthConfig) Middleware {
-	if config.Validator == nil {
-		panic("helix: BasicAuth validator is required")
+	if config.Validator == nil && config.Store == nil {
+		panic("helix: BasicAuth validator or store is required")
 	}
 	if config.Realm == "" {
 		config.Realm = "Restricted"
 	}
+	if config.Verify == nil {
+		config.Verify = VerifyPassword
+	}
+	var lastHash atomic.Pointer[string]
+	if config.DummyHash != "" {
+		lastHash.Store(&config.DummyHash)
+	}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -64,12 +126,36 @@ func BasicAuthWithConfig(config BasicAuthConfig) Middleware {
 			}
 
 			// Validate credentials
-			if !config.Validator(username, password) {
+			var valid bool
+			if config.Validator != nil {
+				valid = config.Validator(username, password)
+			} else {
+				hash, err := config.Store.PasswordHash(r.Context(), username)
+				if err != nil {
+					writeProblem(w, r, http.StatusInternalServerError, "authentication check failed", nil)
+					return
+				}
+				// Check unknown users against a dummy hash of the store's
+				// scheme, so they take as long as wrong passwords
+				known := hash != ""
+				switch {
+				case known && config.DummyHash == "":
+					lastHash.Store(&hash)
+				case !known:
+					if last := lastHash.Load(); last != nil {
+						hash = *last
+					} else {
+						hash = dummyHash()
+					}
+				}
+				valid = config.Verify(hash, password) && known
+			}
+			if !valid {
 				unauthorized(w, config.Realm)
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), &Principal{ID: username})))
 		})
 	}
 }
@@ -79,11 +165,11 @@ func BasicAuthWithConfig(config BasicAuthConfig) Middleware {
 func BasicAuthUsers(users map[string]string) Middleware {
 	return BasicAuthWithConfig(BasicAuthConfig{
 		Validator: func(username, password string) bool {
+			// Unknown users are compared too, so they take as long as
+			// wrong passwords
 			expectedPassword, ok := users[username]
-			if !ok {
-				return false
-			}
-			return secureCompare(password, expectedPassword)
+			match := secureCompare(password, expectedPassword)
+			return ok && match
 		},
 		Realm: "Restricted",
 	})
@@ -104,3 +190,101 @@ func secureCompare(a, b string) bool {
 	bHash := sha256.Sum256([]byte(b))
 	return subtle.ConstantTimeCompare(aHash[:], bHash[:]) == 1
 }
+
+// pbkdf2Prefix marks the PBKDF2-SHA256 hashes made by HashPassword.
+const pbkdf2Prefix = "$pbkdf2-sha256$"
+
+// pbkdf2Iterations is the PBKDF2-SHA256 work factor recommended by OWASP.
+const pbkdf2Iterations = 600000
+
+var (
+	passwordSchemesMu sync.RWMutex
+	passwordSchemes   = map[string]func(hash, password string) bool{
+		pbkdf2Prefix: verifyPBKDF2,
+	}
+)
+
+// RegisterPasswordHash registers verify for the password hashes starting
+// with prefix, for VerifyPassword. It is safe to call concurrently, but is
+// typically called at init. helix has no dependencies, so bcrypt and
+// Argon2 hashes are verified by registering golang.org/x/crypto:
+//
+//	verify := func(hash, password string) bool {
+//	    return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+//	}
+//	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+//	    middleware.RegisterPasswordHash(prefix, verify)
+//	}
+func RegisterPasswordHash(prefix string, verify func(hash, password string) bool) {
+	if prefix == "" || verify == nil {
+		panic("helix: password hash prefix and verifier are required")
+	}
+	passwordSchemesMu.Lock()
+	defer passwordSchemesMu.Unlock()
+	passwordSchemes[prefix] = verify
+}
+
+// VerifyPassword reports whether password matches hash, by the scheme
+// registered for the hash's prefix. The PBKDF2-SHA256 hashes of
+// HashPassword are built in; hashes of unknown schemes never match.
+func VerifyPassword(hash, password string) bool {
+	var verify func(hash, password string) bool
+	longest := 0
+	passwordSchemesMu.RLock()
+	for prefix, v := range passwordSchemes {
+		if len(prefix) > longest && strings.HasPrefix(hash, prefix) {
+			verify, longest = v, len(prefix)
+		}
+	}
+	passwordSchemesMu.RUnlock()
+	return verify != nil && verify(hash, password)
+}
+
+// HashPassword returns a salted PBKDF2-SHA256 hash of password for a
+// CredentialStore, in the PHC string format:
+//
+//	$pbkdf2-sha256$i=600000$<salt>$<key>
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, 16)
+	rand.Read(salt)
+	key, err := pbkdf2.Key(sha256.New, password, salt, pbkdf2Iterations, sha256.Size)
+	if err != nil {
+		return "", err
+	}
+	return pbkdf2Prefix + "i=" + strconv.Itoa(pbkdf2Iterations) + "$" +
+		base64.RawStdEncoding.EncodeToString(salt) + "$" +
+		base64.RawStdEncoding.EncodeToString(key), nil
+}
+
+// verifyPBKDF2 verifies a hash made by HashPassword.
+func verifyPBKDF2(hash, password string) bool {
+	params, salt64, ok := strings.Cut(strings.TrimPrefix(hash, pbkdf2Prefix), "$")
+	if !ok {
+		return false
+	}
+	salt64, key64, ok := strings.Cut(salt64, "$")
+	if !ok {
+		return false
+	}
+	iter, err := strconv.Atoi(strings.TrimPrefix(params, "i="))
+	if err != nil || iter <= 0 {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(salt64)
+	if err != nil {
+		return false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(key64)
+	if err != nil || len(want) < 16 {
+		return false
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, iter, len(want))
+	return err == nil && subtle.ConstantTimeCompare(key, want) == 1
+}
+
+// dummyHash returns the hash that unknown users are checked against until
+// a known user's hash is seen.
+var dummyHash = sync.OnceValue(func() string {
+	hash, _ := HashPassword(rand.Text())
+	return hash
+})
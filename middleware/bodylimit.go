@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// BodyLimit returns a middleware that limits request bodies to n bytes.
+// Requests declaring a larger Content-Length are rejected with a 413
+// problem response before the handler runs; reading past the limit fails
+// with an *http.MaxBytesError, which helix also reports as 413.
+//
+// Example:
+//
+//	s.Use(middleware.BodyLimit(1 << 20)) // 1 MiB
+func BodyLimit(n int64) Middleware {
+	if n <= 0 {
+		panic("helix: body limit must be positive")
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				writeProblem(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", n), nil)
+				return
+			}
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = http.MaxBytesReader(w, r.Body, n)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
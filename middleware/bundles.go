@@ -1,5 +1,11 @@
 package middleware
 
+import (
+	"io"
+	"os"
+	"time"
+)
+
 // API returns a middleware bundle suitable for JSON API servers.
 // Includes: RequestID, Recover, and CORS.
 // Add logging via helix.LoggerMiddleware with your preferred RequestLogger.
@@ -77,3 +83,89 @@ func Secure(rate float64, burst int) []Middleware {
 		RateLimit(rate, burst),
 	}
 }
+
+// PresetConfig configures the APIDefaults and WebDefaults presets.
+type PresetConfig struct {
+	// Timeout is the maximum duration of a request.
+	// Default: 30 seconds
+	Timeout time.Duration
+
+	// BodyLimit is the maximum request body size in bytes.
+	// Default: 4 MiB
+	BodyLimit int64
+
+	// LogOutput receives the JSON access log.
+	// Default: os.Stdout
+	LogOutput io.Writer
+
+	// TrustedProxies lists the proxies whose forwarding headers RealIP
+	// believes.
+	// Default: loopback and private networks
+	TrustedProxies []string
+
+	// TrustedOrigins lists other origins allowed to send unsafe requests
+	// past CSRF. WebDefaults only.
+	TrustedOrigins []string
+
+	// SecureHeaders configures the security headers; zero fields use the
+	// defaults. WebDefaults only.
+	SecureHeaders SecureHeadersConfig
+
+	// Sessions is session middleware to run last, such as a store-backed
+	// session loader; this package has none built in. WebDefaults only.
+	// Default: nil (no sessions)
+	Sessions Middleware
+}
+
+// APIDefaults returns the recommended middleware for a JSON API service.
+// Includes: RequestID, RealIP, Logger (JSON), Recover, Timeout, BodyLimit.
+//
+// Example:
+//
+//	s.Use(middleware.Chain(middleware.APIDefaults()...))
+func APIDefaults() []Middleware {
+	return APIDefaultsWithConfig(PresetConfig{})
+}
+
+// APIDefaultsWithConfig returns the APIDefaults preset with the given
+// configuration.
+func APIDefaultsWithConfig(config PresetConfig) []Middleware {
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	if config.BodyLimit <= 0 {
+		config.BodyLimit = 4 << 20
+	}
+	if config.LogOutput == nil {
+		config.LogOutput = os.Stdout
+	}
+
+	return []Middleware{
+		RequestID(),
+		RealIPWithConfig(RealIPConfig{TrustedProxies: config.TrustedProxies}),
+		LoggerWithConfig(LoggerConfig{Output: TextOutput(config.LogOutput, LogFormatJSON)}),
+		Recover(),
+		Timeout(config.Timeout),
+		BodyLimit(config.BodyLimit),
+	}
+}
+
+// WebDefaults returns the recommended middleware for a browser-facing web
+// application: APIDefaults plus SecureHeaders, CSRF and, when configured,
+// Sessions.
+func WebDefaults() []Middleware {
+	return WebDefaultsWithConfig(PresetConfig{})
+}
+
+// WebDefaultsWithConfig returns the WebDefaults preset with the given
+// configuration.
+func WebDefaultsWithConfig(config PresetConfig) []Middleware {
+	mws := append(APIDefaultsWithConfig(config),
+		SecureHeadersWithConfig(config.SecureHeaders),
+		CSRFWithConfig(CSRFConfig{TrustedOrigins: config.TrustedOrigins}),
+	)
+	if config.Sessions != nil {
+		mws = append(mws, config.Sessions)
+	}
+	return mws
+}
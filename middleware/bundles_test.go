@@ -1,8 +1,10 @@
 package middleware_test
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	. "github.com/kolosys/helix/middleware"
@@ -233,3 +235,53 @@ func TestBundleComposition(t *testing.T) {
 		t.Errorf("expected status 200, got %d", rec.Code)
 	}
 }
+
+func TestAPIDefaults(t *testing.T) {
+	var logs strings.Builder
+	sessions := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Session", "1")
+			next.ServeHTTP(w, r)
+		})
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		w.Write([]byte(r.RemoteAddr))
+	})
+
+	api := Chain(APIDefaultsWithConfig(PresetConfig{LogOutput: &logs, BodyLimit: 8})...)(handler)
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("too large body"))
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	rec := httptest.NewRecorder()
+	api.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected the body limit to apply, got %d", rec.Code)
+	}
+	if rec.Header().Get("X-Request-ID") == "" {
+		t.Error("expected a request ID")
+	}
+	if !strings.Contains(logs.String(), `"remote_ip":"203.0.113.7"`) {
+		t.Errorf("expected a JSON access log with the real client IP, got %q", logs.String())
+	}
+
+	web := Chain(WebDefaultsWithConfig(PresetConfig{LogOutput: io.Discard, Sessions: sessions})...)(handler)
+	req = httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Sec-Fetch-Site", "cross-site")
+	rec = httptest.NewRecorder()
+	web.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected CSRF protection, got %d", rec.Code)
+	}
+	if rec.Header().Get("X-Frame-Options") != "DENY" {
+		t.Error("expected security headers")
+	}
+
+	rec = httptest.NewRecorder()
+	web.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Header().Get("X-Session") != "1" {
+		t.Error("expected the session middleware to run")
+	}
+}
@@ -138,7 +138,7 @@ func CacheWithConfig(config CacheConfig) Middleware {
 
 			// Set Vary header
 			if len(config.VaryHeaders) > 0 {
-				w.Header().Set("Vary", strings.Join(config.VaryHeaders, ", "))
+				AddVary(w.Header(), config.VaryHeaders...)
 			}
 
 			// Set Expires header if MaxAge is set
@@ -1,10 +1,13 @@
 package middleware
 
 import (
+	"bufio"
 	"compress/flate"
 	"compress/gzip"
 	"io"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 )
@@ -16,14 +19,21 @@ type CompressConfig struct {
 	// Default: -1 (gzip.DefaultCompression)
 	Level int
 
-	// MinSize is the minimum size in bytes to trigger compression.
+	// MinSize is the minimum size in bytes to trigger compression. At most
+	// MinSize bytes are buffered to decide; the rest of the response is
+	// streamed. Responses declaring a Content-Length are decided at once.
 	// Default: 1024 (1KB)
 	MinSize int
 
-	// Types is a list of content types to compress.
+	// Types is a list of content type prefixes to compress.
 	// Default: text/*, application/json, application/javascript, application/xml
 	Types []string
 
+	// ExcludedTypes is a list of content type prefixes never to compress,
+	// even if they match Types, because they are compressed already.
+	// Default: common image, video, audio, font and archive formats
+	ExcludedTypes []string
+
 	// SkipFunc is a function that determines if compression should be skipped.
 	SkipFunc func(r *http.Request) bool
 }
@@ -41,7 +51,52 @@ func DefaultCompressConfig() CompressConfig {
 			"application/xhtml+xml",
 			"image/svg+xml",
 		},
+		ExcludedTypes: []string{
+			"image/png",
+			"image/jpeg",
+			"image/gif",
+			"image/webp",
+			"image/avif",
+			"video/",
+			"audio/",
+			"font/woff",
+			"application/zip",
+			"application/gzip",
+			"application/x-gzip",
+			"application/zstd",
+			"application/x-bzip2",
+			"application/x-xz",
+			"application/x-7z-compressed",
+			"application/x-rar-compressed",
+		},
+	}
+}
+
+// gzipPools and flatePools hold idle writers, indexed by compression level
+// plus one, shared by all Compress middleware.
+var (
+	gzipPools  [gzip.BestCompression + 2]sync.Pool
+	flatePools [flate.BestCompression + 2]sync.Pool
+)
+
+// getGzipWriter returns a pooled gzip writer at level writing to w.
+func getGzipWriter(level int, w io.Writer) *gzip.Writer {
+	if gw, ok := gzipPools[level+1].Get().(*gzip.Writer); ok {
+		gw.Reset(w)
+		return gw
 	}
+	gw, _ := gzip.NewWriterLevel(w, level)
+	return gw
+}
+
+// getFlateWriter returns a pooled deflate writer at level writing to w.
+func getFlateWriter(level int, w io.Writer) *flate.Writer {
+	if fw, ok := flatePools[level+1].Get().(*flate.Writer); ok {
+		fw.Reset(w)
+		return fw
+	}
+	fw, _ := flate.NewWriter(w, level)
+	return fw
 }
 
 // Compress returns a middleware that compresses responses using gzip or deflate.
@@ -67,20 +122,8 @@ func CompressWithConfig(config CompressConfig) Middleware {
 	if len(config.Types) == 0 {
 		config.Types = DefaultCompressConfig().Types
 	}
-
-	// Create pools for gzip and deflate writers
-	gzipPool := &sync.Pool{
-		New: func() any {
-			w, _ := gzip.NewWriterLevel(io.Discard, config.Level)
-			return w
-		},
-	}
-
-	flatePool := &sync.Pool{
-		New: func() any {
-			w, _ := flate.NewWriter(io.Discard, config.Level)
-			return w
-		},
+	if len(config.ExcludedTypes) == 0 {
+		config.ExcludedTypes = DefaultCompressConfig().ExcludedTypes
 	}
 
 	return func(next http.Handler) http.Handler {
@@ -91,22 +134,16 @@ func CompressWithConfig(config CompressConfig) Middleware {
 				return
 			}
 
-			// Check Accept-Encoding header
-			acceptEncoding := r.Header.Get("Accept-Encoding")
-			if acceptEncoding == "" {
-				next.ServeHTTP(w, r)
-				return
-			}
-
-			// Determine encoding
+			// Determine encoding. Clients accepting neither still get
+			// Vary: Accept-Encoding on compressible responses, so caches
+			// do not serve them the uncompressed copy of others or the
+			// other way around.
 			var encoding string
+			acceptEncoding := r.Header.Get("Accept-Encoding")
 			if strings.Contains(acceptEncoding, "gzip") {
 				encoding = "gzip"
 			} else if strings.Contains(acceptEncoding, "deflate") {
 				encoding = "deflate"
-			} else {
-				next.ServeHTTP(w, r)
-				return
 			}
 
 			// Create compress writer
@@ -114,8 +151,6 @@ func CompressWithConfig(config CompressConfig) Middleware {
 				ResponseWriter: w,
 				encoding:       encoding,
 				config:         config,
-				gzipPool:       gzipPool,
-				flatePool:      flatePool,
 			}
 
 			defer cw.Close()
@@ -130,8 +165,6 @@ type compressWriter struct {
 	http.ResponseWriter
 	encoding      string
 	config        CompressConfig
-	gzipPool      *sync.Pool
-	flatePool     *sync.Pool
 	writer        io.Writer
 	gzipWriter    *gzip.Writer
 	flateWriter   *flate.Writer
@@ -142,31 +175,71 @@ type compressWriter struct {
 }
 
 func (cw *compressWriter) WriteHeader(code int) {
+	if informational(code) {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
 	cw.statusCode = code
 	// Don't write header yet - we need to check content type
 }
 
 func (cw *compressWriter) Write(b []byte) (int, error) {
-	// Buffer until we have enough data
-	cw.buffer = append(cw.buffer, b...)
+	// Once the encoding is decided, write through
+	if cw.headerWritten {
+		return cw.output().Write(b)
+	}
 
-	// Check if we should start compression
-	if !cw.headerWritten && len(cw.buffer) >= cw.config.MinSize {
-		cw.finalize()
+	// A declared length decides the encoding without buffering
+	if n, err := strconv.Atoi(cw.Header().Get("Content-Length")); err == nil {
+		cw.finalize(n >= cw.config.MinSize)
+		return cw.output().Write(b)
 	}
 
-	return len(b), nil
+	// Buffer until we have enough data to decide
+	if len(cw.buffer)+len(b) < cw.config.MinSize {
+		cw.buffer = append(cw.buffer, b...)
+		return len(b), nil
+	}
+
+	// Decide, then stream the buffered data and the rest through
+	cw.finalize(true)
+	if err := cw.writeBuffer(); err != nil {
+		return 0, err
+	}
+	return cw.output().Write(b)
+}
+
+// output returns the writer for response data after finalize.
+func (cw *compressWriter) output() io.Writer {
+	if cw.compressed && cw.writer != nil {
+		return cw.writer
+	}
+	return cw.ResponseWriter
+}
+
+// writeBuffer writes and clears the buffered data.
+func (cw *compressWriter) writeBuffer() error {
+	if len(cw.buffer) == 0 {
+		return nil
+	}
+	_, err := cw.output().Write(cw.buffer)
+	cw.buffer = cw.buffer[:0]
+	return err
 }
 
-func (cw *compressWriter) finalize() {
+// finalize decides the encoding and writes the header. large reports
+// whether the response reaches MinSize.
+func (cw *compressWriter) finalize(large bool) {
 	if cw.headerWritten {
 		return
 	}
 	cw.headerWritten = true
 
-	contentType := cw.Header().Get("Content-Type")
-	if contentType != "" && cw.shouldCompress(contentType) && len(cw.buffer) >= cw.config.MinSize {
-		cw.startCompression()
+	if large && cw.shouldCompress() {
+		AddVary(cw.Header(), "Accept-Encoding")
+		if cw.encoding != "" {
+			cw.startCompression()
+		}
 	}
 
 	if cw.statusCode == 0 {
@@ -175,18 +248,24 @@ func (cw *compressWriter) finalize() {
 	cw.ResponseWriter.WriteHeader(cw.statusCode)
 }
 
-func (cw *compressWriter) shouldCompress(contentType string) bool {
-	for _, t := range cw.config.Types {
-		if strings.HasSuffix(t, "/") {
-			// Prefix match (e.g., "text/")
-			if strings.HasPrefix(contentType, t) {
-				return true
-			}
-		} else {
-			// Starts with match
-			if strings.HasPrefix(contentType, t) {
-				return true
-			}
+// shouldCompress reports whether the response's content type is compressed.
+// Responses the handler encoded itself are left alone.
+func (cw *compressWriter) shouldCompress() bool {
+	if cw.Header().Get("Content-Encoding") != "" {
+		return false
+	}
+	contentType := strings.ToLower(cw.Header().Get("Content-Type"))
+	if contentType == "" {
+		return false
+	}
+	return hasTypePrefix(cw.config.Types, contentType) && !hasTypePrefix(cw.config.ExcludedTypes, contentType)
+}
+
+// hasTypePrefix reports whether contentType starts with one of prefixes.
+func hasTypePrefix(prefixes []string, contentType string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(contentType, p) {
+			return true
 		}
 	}
 	return false
@@ -196,51 +275,44 @@ func (cw *compressWriter) startCompression() {
 	cw.compressed = true
 	cw.Header().Set("Content-Encoding", cw.encoding)
 	cw.Header().Del("Content-Length")
-	cw.Header().Add("Vary", "Accept-Encoding")
 
 	switch cw.encoding {
 	case "gzip":
-		gw := cw.gzipPool.Get().(*gzip.Writer)
-		gw.Reset(cw.ResponseWriter)
-		cw.gzipWriter = gw
-		cw.writer = gw
+		cw.gzipWriter = getGzipWriter(cw.config.Level, cw.ResponseWriter)
+		cw.writer = cw.gzipWriter
 	case "deflate":
-		fw := cw.flatePool.Get().(*flate.Writer)
-		fw.Reset(cw.ResponseWriter)
-		cw.flateWriter = fw
-		cw.writer = fw
+		cw.flateWriter = getFlateWriter(cw.config.Level, cw.ResponseWriter)
+		cw.writer = cw.flateWriter
 	}
 }
 
 func (cw *compressWriter) Close() error {
 	// Finalize if not yet done
-	if !cw.headerWritten {
-		cw.finalize()
-	}
+	// Anything still buffered is below MinSize
+	cw.finalize(false)
 
 	// Write buffered data
-	if len(cw.buffer) > 0 {
-		if cw.compressed && cw.writer != nil {
-			cw.writer.Write(cw.buffer)
-		} else {
-			cw.ResponseWriter.Write(cw.buffer)
-		}
-	}
+	cw.writeBuffer()
 
 	// Close compression writers and return to pool
 	if cw.gzipWriter != nil {
 		cw.gzipWriter.Close()
-		cw.gzipPool.Put(cw.gzipWriter)
+		gzipPools[cw.config.Level+1].Put(cw.gzipWriter)
 	}
 	if cw.flateWriter != nil {
 		cw.flateWriter.Close()
-		cw.flatePool.Put(cw.flateWriter)
+		flatePools[cw.config.Level+1].Put(cw.flateWriter)
 	}
 
 	return nil
 }
 
+// Flush sends buffered data to the client, deciding the encoding early if
+// needed, so streaming responses such as SSE work through Compress.
 func (cw *compressWriter) Flush() {
+	cw.finalize(false)
+	cw.writeBuffer()
+
 	if cw.gzipWriter != nil {
 		cw.gzipWriter.Flush()
 	}
@@ -251,3 +323,21 @@ func (cw *compressWriter) Flush() {
 		f.Flush()
 	}
 }
+
+// Hijack implements http.Hijacker if the underlying writer supports it.
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(cw.ResponseWriter).Hijack()
+}
+
+// Push implements http.Pusher if the underlying writer supports it.
+func (cw *compressWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := cw.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// RequireContentType returns a middleware that rejects requests with a body
+// whose Content-Type is not one of types with a 415 Unsupported Media Type
+// problem response. The response's Accept header lists the supported types,
+// as RFC 9110 suggests. A type may end in "/*" to accept any subtype, as in
+// "image/*". Requests without a body, such as most GETs, pass through.
+//
+// Example:
+//
+//	api.Use(middleware.RequireContentType("application/json"))
+func RequireContentType(types ...string) Middleware {
+	if len(types) == 0 {
+		panic("helix: RequireContentType requires at least one content type")
+	}
+	supported := make([]string, len(types))
+	for i, t := range types {
+		supported[i] = strings.ToLower(t)
+	}
+	accept := strings.Join(types, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength == 0 || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			header := r.Header.Get("Content-Type")
+			mediaType, _, err := mime.ParseMediaType(header)
+			if err == nil && matchContentType(mediaType, supported) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			detail := "missing Content-Type; supported: " + accept
+			if header != "" {
+				detail = fmt.Sprintf("unsupported content type %q; supported: %s", header, accept)
+			}
+			w.Header().Set("Accept", accept)
+			writeProblem(w, r, http.StatusUnsupportedMediaType, detail, nil)
+		})
+	}
+}
+
+// matchContentType reports whether mediaType, which is lowercase, matches
+// one of supported.
+func matchContentType(mediaType string, supported []string) bool {
+	for _, s := range supported {
+		if s == mediaType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(s, "*"); ok && strings.HasSuffix(prefix, "/") && strings.HasPrefix(mediaType, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/kolosys/helix/logs"
+)
+
+// routeKey is the context key for the matched route holder.
+type routeKey struct{}
+
+// routeHolder receives the matched route pattern once routing completes,
+// the principal once authentication middleware sets one, whether
+// ContextLogger holds a request logger to add the principal to, the client
+// location once GeoIP resolves it, and the variants of the experiments the
+// request takes part in. It is placed
+// in the context before routing so middleware wrapping the router can
+// observe the route after the fact.
+type routeHolder struct {
+	pattern       string
+	principal     *Principal
+	contextLogger bool
+	geo           *Geo
+	variants      map[string]string
+}
+
+// String implements fmt.Stringer so the holder can be used as a log field.
+func (h *routeHolder) String() string {
+	return h.pattern
+}
+
+// MarshalJSON implements json.Marshaler so the holder can be used as a log field.
+func (h *routeHolder) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.pattern)
+}
+
+// trackRoute returns a request whose context carries a route holder.
+// An existing holder from outer middleware is reused.
+func trackRoute(r *http.Request) (*http.Request, *routeHolder) {
+	if h, ok := r.Context().Value(routeKey{}).(*routeHolder); ok {
+		return r, h
+	}
+	h := &routeHolder{}
+	return r.WithContext(context.WithValue(r.Context(), routeKey{}, h)), h
+}
+
+// SetRoutePattern records the matched route pattern for middleware tracking it.
+// It is called by the router and is a no-op when no middleware tracks routes.
+func SetRoutePattern(r *http.Request, pattern string) {
+	if h, ok := r.Context().Value(routeKey{}).(*routeHolder); ok {
+		h.pattern = pattern
+	}
+}
+
+// SetVariant records that the request was served by variant of experiment,
+// an A/B test or canary split, for the Logger wrapping the request. It is a
+// no-op when no middleware tracks routes.
+func SetVariant(r *http.Request, experiment, variant string) {
+	if h, ok := r.Context().Value(routeKey{}).(*routeHolder); ok {
+		if h.variants == nil {
+			h.variants = make(map[string]string)
+		}
+		h.variants[experiment] = variant
+	}
+}
+
+// RoutePattern returns the matched route pattern (e.g. "/users/{id}").
+// Returns an empty string if the route is not matched yet or no middleware
+// tracks routes.
+func RoutePattern(r *http.Request) string {
+	if h, ok := r.Context().Value(routeKey{}).(*routeHolder); ok {
+		return h.pattern
+	}
+	return ""
+}
+
+// ContextLogger returns a middleware that stores a request-scoped logger in the context.
+// The child logger includes request_id, method, path, and route fields, so every
+// line logged through logs.FromContext or Ctx.Logger is correlated with the request.
+// The route field is filled in once the router matches the request, and
+// authentication middleware add a principal field with the principal's ID.
+// Place it after RequestID so the request ID is available.
+func ContextLogger(logger *logs.Logger) Middleware {
+	if logger == nil {
+		logger = logs.Default()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r, route := trackRoute(r)
+			route.contextLogger = true
+
+			fields := logs.Fields{
+				"method": r.Method,
+				"path":   r.URL.Path,
+				"route":  route,
+			}
+			if id := GetRequestID(r.Context()); id != "" {
+				fields["request_id"] = id
+			}
+
+			ctx := logs.NewContext(r.Context(), logger.WithFields(fields))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
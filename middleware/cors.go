@@ -1,7 +1,9 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -9,15 +11,21 @@ import (
 // CORSConfig configures the CORS middleware.
 type CORSConfig struct {
 	// AllowOrigins is a list of origins that are allowed.
-	// Use "*" to allow all origins.
+	// Use "*" to allow all origins, or a "*" label to allow the subdomains
+	// of a site, as "https://*.example.com" allows
+	// "https://app.example.com" and "https://eu.app.example.com" but not
+	// "https://example.com".
 	// Default: []
 	AllowOrigins []string
 
-	// AllowOriginFunc is a custom function to validate the origin.
-	// If set, AllowOrigins is ignored.
+	// AllowOriginFunc is a custom function to validate the origin, such
+	// as against the tenants in a database. If set, AllowOrigins is
+	// ignored.
 	AllowOriginFunc func(origin string) bool
 
-	// AllowMethods is a list of methods that are allowed.
+	// AllowMethods is a list of methods that are allowed. Use "*" to allow
+	// all. Where the router reports the methods registered for a path (see
+	// WithRouteMethods), preflights allow only those of them.
 	// Default: GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS
 	AllowMethods []string
 
@@ -33,9 +41,18 @@ type CORSConfig struct {
 	// Default: false
 	AllowCredentials bool
 
-	// MaxAge is the maximum age (in seconds) of the preflight cache.
-	// Default: 0 (no caching)
+	// MaxAge is how long (in seconds) browsers may cache preflight
+	// results, sent as Access-Control-Max-Age. A negative value sends 0, so
+	// browsers do not cache them at all; zero leaves the browser default,
+	// 5 seconds in most. Browsers cap it, Chrome at 2 hours.
+	// Default: 0
 	MaxAge int
+
+	// AllowPrivateNetwork answers Private Network Access preflights, which
+	// browsers send before a public site may call a server on a private
+	// network or localhost, with Access-Control-Allow-Private-Network.
+	// Default: false
+	AllowPrivateNetwork bool
 }
 
 // DefaultCORSConfig returns the default CORS configuration.
@@ -80,42 +97,64 @@ func CORSWithConfig(config CORSConfig) Middleware {
 	allowMethods := strings.Join(config.AllowMethods, ", ")
 	allowHeaders := strings.Join(config.AllowHeaders, ", ")
 	exposeHeaders := strings.Join(config.ExposeHeaders, ", ")
-	maxAge := strconv.Itoa(config.MaxAge)
+	maxAge := strconv.Itoa(max(config.MaxAge, 0))
+	allowedMethods := make(map[string]bool, len(config.AllowMethods))
+	for _, m := range config.AllowMethods {
+		allowedMethods[m] = true
+	}
+	wildcard := len(config.AllowOrigins) == 1 && config.AllowOrigins[0] == "*" &&
+		!config.AllowCredentials && config.AllowOriginFunc == nil
+	allowOrigin := config.AllowOriginFunc
+	if allowOrigin == nil {
+		allowOrigin = originMatcher(config.AllowOrigins)
+	}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			origin := r.Header.Get("Origin")
 
+			// Unless every origin gets "*", the response depends on the
+			// Origin, even when it is missing or not allowed
+			if !wildcard {
+				AddVary(w.Header(), "Origin")
+			}
+
 			// If no origin header, not a CORS request
 			if origin == "" {
 				next.ServeHTTP(w, r)
 				return
 			}
 
-			// Check if origin is allowed
-			allowed := false
-			if config.AllowOriginFunc != nil {
-				allowed = config.AllowOriginFunc(origin)
-			} else {
-				for _, o := range config.AllowOrigins {
-					if o == "*" || o == origin {
-						allowed = true
-						break
-					}
-				}
+			if override, _ := r.Context().Value(corsOverrideKey{}).(func(*http.Request) bool); override != nil && override(r) {
+				next.ServeHTTP(w, r)
+				return
 			}
 
-			if !allowed {
+			if !allowOrigin(origin) {
 				next.ServeHTTP(w, r)
 				return
 			}
 
+			// Preflights allow the methods registered for the path, and
+			// paths without routes are left to the router
+			methods := allowMethods
+			if routeMethods, _ := r.Context().Value(routeMethodsKey{}).(func(*http.Request) []string); routeMethods != nil && r.Method == http.MethodOptions {
+				registered := routeMethods(r)
+				if len(registered) == 0 {
+					next.ServeHTTP(w, r)
+					return
+				}
+				if !allowedMethods["*"] {
+					registered = slices.DeleteFunc(slices.Clone(registered), func(m string) bool { return !allowedMethods[m] })
+				}
+				methods = strings.Join(registered, ", ")
+			}
+
 			// Set CORS headers
-			if len(config.AllowOrigins) == 1 && config.AllowOrigins[0] == "*" && !config.AllowCredentials {
+			if wildcard {
 				w.Header().Set("Access-Control-Allow-Origin", "*")
 			} else {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
-				w.Header().Set("Vary", "Origin")
 			}
 
 			if config.AllowCredentials {
@@ -128,10 +167,18 @@ func CORSWithConfig(config CORSConfig) Middleware {
 
 			// Handle preflight request
 			if r.Method == http.MethodOptions {
-				w.Header().Set("Access-Control-Allow-Methods", allowMethods)
+				AddVary(w.Header(), "Access-Control-Request-Method", "Access-Control-Request-Headers")
+				w.Header().Set("Access-Control-Allow-Methods", methods)
 				w.Header().Set("Access-Control-Allow-Headers", allowHeaders)
 
-				if config.MaxAge > 0 {
+				if config.AllowPrivateNetwork {
+					AddVary(w.Header(), "Access-Control-Request-Private-Network")
+					if r.Header.Get("Access-Control-Request-Private-Network") == "true" {
+						w.Header().Set("Access-Control-Allow-Private-Network", "true")
+					}
+				}
+
+				if config.MaxAge != 0 {
 					w.Header().Set("Access-Control-Max-Age", maxAge)
 				}
 
@@ -144,6 +191,67 @@ func CORSWithConfig(config CORSConfig) Middleware {
 	}
 }
 
+// corsOverrideKey is the context key for the CORS override check.
+type corsOverrideKey struct{}
+
+// WithCORSOverride returns a copy of ctx in which CORS middleware passes
+// the requests for which override reports true on untouched, leaving them
+// to a policy of their own further in, such as a route's. A nil override
+// clears the check, so that the inner policy applies. helix servers install
+// it for the routes registered with helix.WithRouteCORS.
+func WithCORSOverride(ctx context.Context, override func(r *http.Request) bool) context.Context {
+	return context.WithValue(ctx, corsOverrideKey{}, override)
+}
+
+// routeMethodsKey is the context key for the route methods lookup.
+type routeMethodsKey struct{}
+
+// WithRouteMethods returns a copy of ctx telling CORS middleware which
+// methods are registered for a request's path, so that preflights allow
+// only those and are left to the router for paths without routes. helix
+// servers install it for preflights.
+func WithRouteMethods(ctx context.Context, methods func(r *http.Request) []string) context.Context {
+	return context.WithValue(ctx, routeMethodsKey{}, methods)
+}
+
+// originMatcher returns a function reporting whether an origin is in
+// origins, which may contain "*" and subdomain wildcards.
+func originMatcher(origins []string) func(origin string) bool {
+	exact := make(map[string]bool, len(origins))
+	var all bool
+	var wildcards [][2]string // prefix and suffix around "*"
+	for _, o := range origins {
+		switch {
+		case o == "*":
+			all = true
+		case strings.Contains(o, "*"):
+			prefix, suffix, _ := strings.Cut(strings.ToLower(o), "*")
+			if !strings.HasSuffix(prefix, "://") || !strings.HasPrefix(suffix, ".") {
+				panic("helix: CORS origin " + o + ": * must be the first label of the host")
+			}
+			wildcards = append(wildcards, [2]string{prefix, suffix})
+		default:
+			exact[strings.ToLower(o)] = true
+		}
+	}
+
+	return func(origin string) bool {
+		origin = strings.ToLower(origin)
+		if all || exact[origin] {
+			return true
+		}
+		for _, w := range wildcards {
+			if len(origin) > len(w[0])+len(w[1]) && strings.HasPrefix(origin, w[0]) && strings.HasSuffix(origin, w[1]) {
+				sub := origin[len(w[0]) : len(origin)-len(w[1])]
+				if !strings.ContainsAny(sub, "/:@?#") {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
 // CORSAllowAll returns a CORS middleware that allows all origins, methods, and headers.
 func CORSAllowAll() Middleware {
 	return CORSWithConfig(CORSConfig{
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RequestTimeoutHeader is the header carrying a request's time budget.
+const RequestTimeoutHeader = "X-Request-Timeout"
+
+// DeadlineConfig configures the Deadline middleware.
+type DeadlineConfig struct {
+	// Default is the budget of requests that don't ask for one.
+	// Default: 30 seconds
+	Default time.Duration
+
+	// Max caps the budget clients may ask for.
+	// Default: Default
+	Max time.Duration
+
+	// Headers are the inbound headers read for a budget, in order. "grpc-timeout"
+	// uses the gRPC format ("100m", "5S"); other headers take a Go duration
+	// ("2.5s") or whole milliseconds ("2500").
+	// Default: X-Request-Timeout, grpc-timeout
+	Headers []string
+
+	// SkipFunc determines if the deadline should be skipped.
+	SkipFunc func(r *http.Request) bool
+}
+
+// DefaultDeadlineConfig returns the default Deadline configuration.
+func DefaultDeadlineConfig() DeadlineConfig {
+	return DeadlineConfig{
+		Default: 30 * time.Second,
+		Headers: []string{RequestTimeoutHeader, "grpc-timeout"},
+	}
+}
+
+// deadlineKey marks contexts whose deadline was set by Deadline, so
+// Transport passes the remaining budget on.
+type deadlineKey struct{}
+
+// Deadline returns a middleware that sets a context deadline on each request
+// from the budget the client asks for in X-Request-Timeout or grpc-timeout,
+// capped at defaultBudget, or defaultBudget if it asks for none. Handlers and
+// the calls they make with the request context then give up once the client
+// stops waiting. Unlike Timeout, no response is written when the deadline
+// passes. Transport sends the remaining budget on outgoing requests.
+func Deadline(defaultBudget time.Duration) Middleware {
+	config := DefaultDeadlineConfig()
+	config.Default = defaultBudget
+	return DeadlineWithConfig(config)
+}
+
+// DeadlineWithConfig returns a Deadline middleware with the given configuration.
+func DeadlineWithConfig(config DeadlineConfig) Middleware {
+	if config.Default <= 0 {
+		config.Default = 30 * time.Second
+	}
+	if config.Max <= 0 {
+		config.Max = config.Default
+	}
+	if config.Headers == nil {
+		config.Headers = []string{RequestTimeoutHeader, "grpc-timeout"}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if config.SkipFunc != nil && config.SkipFunc(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			budget := config.Default
+			for _, name := range config.Headers {
+				if d, ok := parseBudget(name, r.Header.Get(name)); ok {
+					budget = min(d, config.Max)
+					break
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.WithValue(r.Context(), deadlineKey{}, true), budget)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// parseBudget parses the budget in header name, reporting false if it is
+// missing or invalid.
+func parseBudget(name, value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	var d time.Duration
+	if http.CanonicalHeaderKey(name) == "Grpc-Timeout" {
+		d = parseGRPCTimeout(value)
+	} else if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		d = time.Duration(ms) * time.Millisecond
+	} else {
+		d, _ = time.ParseDuration(value)
+	}
+	return d, d > 0
+}
+
+// grpcTimeoutUnits maps grpc-timeout unit suffixes to durations.
+var grpcTimeoutUnits = map[byte]time.Duration{
+	'H': time.Hour,
+	'M': time.Minute,
+	'S': time.Second,
+	'm': time.Millisecond,
+	'u': time.Microsecond,
+	'n': time.Nanosecond,
+}
+
+// parseGRPCTimeout parses a grpc-timeout value of at most 8 digits and a
+// unit, returning 0 if it is invalid.
+func parseGRPCTimeout(value string) time.Duration {
+	if len(value) < 2 || len(value) > 9 {
+		return 0
+	}
+	unit, ok := grpcTimeoutUnits[value[len(value)-1]]
+	if !ok {
+		return 0
+	}
+	n, err := strconv.ParseUint(value[:len(value)-1], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(n) * unit
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// preloadTypes maps asset extensions to the "as" value of preload links.
+var preloadTypes = map[string]string{
+	".css":   "style",
+	".js":    "script",
+	".mjs":   "script",
+	".woff":  "font",
+	".woff2": "font",
+	".ttf":   "font",
+	".otf":   "font",
+	".png":   "image",
+	".jpg":   "image",
+	".jpeg":  "image",
+	".gif":   "image",
+	".webp":  "image",
+	".avif":  "image",
+	".svg":   "image",
+	".json":  "fetch",
+}
+
+// PreloadLink returns a Link header value preloading the asset at target,
+// inferring the destination from its extension:
+// PreloadLink("/app.css") is `</app.css>; rel=preload; as=style`.
+// Fonts are marked crossorigin, as browsers require. Values already in
+// Link form (starting with "<") are returned as is.
+func PreloadLink(target string) string {
+	if strings.HasPrefix(target, "<") {
+		return target
+	}
+	as, ok := preloadTypes[strings.ToLower(path.Ext(strings.SplitN(target, "?", 2)[0]))]
+	if !ok {
+		as = "fetch"
+	}
+	link := "<" + target + ">; rel=preload; as=" + as
+	if as == "font" || as == "fetch" {
+		link += "; crossorigin"
+	}
+	return link
+}
+
+// WriteEarlyHints adds a Link header for each of links, formatted with
+// PreloadLink, and sends them in a 103 Early Hints response so the browser
+// can start fetching critical assets while the page is generated. The hints
+// are only sent over HTTP/2 and later, since some HTTP/1.1 clients mishandle
+// interim responses; the Link headers still reach the final response.
+// Must be called before the response is written.
+func WriteEarlyHints(w http.ResponseWriter, r *http.Request, links ...string) {
+	for _, link := range links {
+		w.Header().Add("Link", PreloadLink(link))
+	}
+	if len(links) > 0 && r.ProtoMajor >= 2 {
+		w.WriteHeader(http.StatusEarlyHints)
+	}
+}
+
+// EarlyHints returns a middleware that sends links as 103 Early Hints for
+// GET requests accepting HTML, such as page routes rendering templates.
+//
+// Example:
+//
+//	pages := s.Group("/", middleware.EarlyHints("/static/app.css", "/static/app.js"))
+func EarlyHints(links ...string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/html") {
+				WriteEarlyHints(w, r, links...)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -104,6 +104,10 @@ type etagWriter struct {
 }
 
 func (ew *etagWriter) WriteHeader(code int) {
+	if informational(code) {
+		ew.ResponseWriter.WriteHeader(code)
+		return
+	}
 	ew.status = code
 	// Don't write header yet - we need to compute ETag first
 }
@@ -115,6 +119,17 @@ func (ew *etagWriter) Write(b []byte) (int, error) {
 	return ew.buffer.Write(b)
 }
 
+// FlushError reports http.ErrNotSupported: the body is buffered until the
+// ETag is computed, so it can't be flushed early.
+func (ew *etagWriter) FlushError() error {
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (ew *etagWriter) Unwrap() http.ResponseWriter {
+	return ew.ResponseWriter
+}
+
 // matchETag checks if an ETag matches the If-None-Match header.
 func matchETag(ifNoneMatch, etag string) bool {
 	// Handle wildcard
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+)
+
+// Geo is the geographic location of a client address.
+type Geo struct {
+	// Country is the ISO 3166-1 alpha-2 country code, such as "US".
+	Country string
+
+	// CountryName is the English name of the country.
+	CountryName string
+
+	// Region is the ISO 3166-2 code of the subdivision, such as "CA".
+	Region string
+
+	// City is the English name of the city.
+	City string
+
+	// Latitude and Longitude locate the address, approximately.
+	Latitude  float64
+	Longitude float64
+
+	// TimeZone is the IANA time zone of the location, such as
+	// "America/Los_Angeles".
+	TimeZone string
+}
+
+// GeoResolver resolves client addresses to locations. The geoip module
+// provides one backed by MaxMind DB files; wrap other services with
+// GeoResolverFunc.
+type GeoResolver interface {
+	// ResolveGeo returns the location of addr, or nil if it is unknown.
+	ResolveGeo(addr netip.Addr) (*Geo, error)
+}
+
+// GeoResolverFunc adapts a function to GeoResolver.
+type GeoResolverFunc func(addr netip.Addr) (*Geo, error)
+
+// ResolveGeo implements GeoResolver.
+func (f GeoResolverFunc) ResolveGeo(addr netip.Addr) (*Geo, error) {
+	return f(addr)
+}
+
+// geoKey is the context key for the client location.
+type geoKey struct{}
+
+// WithGeo returns a copy of ctx carrying g. It also reports g to the
+// Logger wrapping the request, which logs its country and city.
+func WithGeo(ctx context.Context, g *Geo) context.Context {
+	if h, ok := ctx.Value(routeKey{}).(*routeHolder); ok {
+		h.geo = g
+	}
+	return context.WithValue(ctx, geoKey{}, g)
+}
+
+// GeoFrom returns the client location stored in ctx by GeoIP, or nil if it
+// is unknown.
+func GeoFrom(ctx context.Context) *Geo {
+	g, _ := ctx.Value(geoKey{}).(*Geo)
+	return g
+}
+
+// GeoIP returns a middleware that resolves each request's client address
+// with resolver and stores the location in the request context, for
+// handlers (see GeoFrom), later middleware and the Logger. The address is
+// r.RemoteAddr, never a forwarding header a client can forge: place GeoIP
+// after RealIP when behind a proxy. Addresses the resolver fails on are
+// treated as unknown.
+//
+// Example:
+//
+//	db, err := geoip.Open("GeoLite2-City.mmdb")
+//	...
+//	s.Use(middleware.RealIP(), middleware.GeoIP(db))
+func GeoIP(resolver GeoResolver) Middleware {
+	if resolver == nil {
+		panic("helix: GeoIP requires a resolver")
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if g := resolveGeo(resolver, r); g != nil {
+				r = r.WithContext(WithGeo(r.Context(), g))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// resolveGeo returns the location of the request's peer address, as
+// rewritten by RealIP behind trusted proxies, or nil if it is unknown.
+func resolveGeo(resolver GeoResolver, r *http.Request) *Geo {
+	addr, ok := peerAddr(r)
+	if !ok {
+		return nil
+	}
+	g, err := resolver.ResolveGeo(addr)
+	if err != nil {
+		return nil
+	}
+	return g
+}
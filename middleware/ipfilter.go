@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// IPList is a set of addresses and networks, safe for concurrent use.
+// Addresses added at runtime can expire, so one list can be shared by
+// IPFilter and middleware that ban clients, such as Tarpit.
+type IPList struct {
+	mu       sync.RWMutex
+	prefixes []netip.Prefix
+	addrs    map[netip.Addr]time.Time // expiry, zero for never
+}
+
+// NewIPList returns a list of the given addresses and CIDR ranges, such as
+// "203.0.113.7" and "198.51.100.0/24". Panics on invalid entries.
+func NewIPList(entries ...string) *IPList {
+	l := &IPList{addrs: make(map[netip.Addr]time.Time)}
+	for _, e := range entries {
+		p, err := parsePrefix(e)
+		if err != nil {
+			panic("helix: invalid IP list entry " + e + ": " + err.Error())
+		}
+		l.prefixes = append(l.prefixes, p)
+	}
+	return l
+}
+
+// Add adds addr to the list for ttl, or for good if ttl is zero.
+func (l *IPList) Add(addr netip.Addr, ttl time.Duration) {
+	var expiry time.Time
+	if ttl > 0 {
+		expiry = time.Now().Add(ttl)
+	}
+	l.mu.Lock()
+	if l.addrs == nil {
+		l.addrs = make(map[netip.Addr]time.Time)
+	}
+	l.addrs[addr.Unmap()] = expiry
+	l.mu.Unlock()
+}
+
+// Remove removes addr, previously added with Add, from the list.
+func (l *IPList) Remove(addr netip.Addr) {
+	l.mu.Lock()
+	delete(l.addrs, addr.Unmap())
+	l.mu.Unlock()
+}
+
+// Contains reports whether addr is in the list.
+func (l *IPList) Contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	l.mu.RLock()
+	expiry, ok := l.addrs[addr]
+	prefixes := l.prefixes
+	l.mu.RUnlock()
+
+	if ok {
+		if expiry.IsZero() || time.Now().Before(expiry) {
+			return true
+		}
+		l.mu.Lock()
+		if e, ok := l.addrs[addr]; ok && e.Equal(expiry) {
+			delete(l.addrs, addr)
+		}
+		l.mu.Unlock()
+	}
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// IPFilterConfig configures the IPFilter middleware.
+type IPFilterConfig struct {
+	// Allow, if set, admits only clients in the list.
+	Allow *IPList
+
+	// Deny rejects clients in the list.
+	Deny *IPList
+
+	// Handler is called for rejected requests.
+	// If nil, a 403 problem response is sent.
+	Handler http.HandlerFunc
+}
+
+// IPFilter returns a middleware that rejects clients in deny with a 403
+// problem. See IPFilterWithConfig.
+func IPFilter(deny *IPList) Middleware {
+	return IPFilterWithConfig(IPFilterConfig{Deny: deny})
+}
+
+// IPFilterWithConfig returns an IPFilter middleware with the given
+// configuration. The client is the address in r.RemoteAddr, so place it
+// after RealIP when behind a proxy; forwarding headers are not trusted.
+func IPFilterWithConfig(config IPFilterConfig) Middleware {
+	if config.Handler == nil {
+		config.Handler = func(w http.ResponseWriter, r *http.Request) {
+			writeProblem(w, r, http.StatusForbidden, "access denied", nil)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			addr, ok := peerAddr(r)
+			if (config.Allow != nil && (!ok || !config.Allow.Contains(addr))) ||
+				(config.Deny != nil && ok && config.Deny.Contains(addr)) {
+				config.Handler(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// peerAddr returns the address of r.RemoteAddr.
+func peerAddr(r *http.Request) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	return addr.Unmap(), err == nil
+}
@@ -0,0 +1,227 @@
+package middleware
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kolosys/helix/internal/jws"
+	"github.com/kolosys/helix/logs"
+)
+
+// JWTConfig configures the JWT middleware.
+type JWTConfig struct {
+	// Key returns the key verifying a token signed with alg ("HS256",
+	// "RS256", ...) under the key ID kid: a []byte secret for HS256, HS384
+	// and HS512, an *rsa.PublicKey for RS* and PS*, an *ecdsa.PublicKey for
+	// ES* and an ed25519.PublicKey for EdDSA. Tokens whose algorithm does
+	// not fit the key are rejected, as are tokens Key returns a nil key
+	// for; an error fails the request with a 500 problem and is logged.
+	// One of Key and Secret is required.
+	Key func(ctx context.Context, alg, kid string) (any, error)
+
+	// Secret verifies HS256 tokens, when Key is nil.
+	Secret []byte
+
+	// Issuer, if set, must match the "iss" claim.
+	Issuer string
+
+	// Audience, if set, must be one of the "aud" claim.
+	Audience string
+
+	// Leeway allows for clock skew when checking the "exp" and "nbf"
+	// claims.
+	// Default: 1 minute
+	Leeway time.Duration
+
+	// Cookie, if set, is a cookie also accepted for the token, for browser
+	// clients. The Authorization header takes precedence.
+	Cookie string
+
+	// Principal maps the verified claims to the request's principal.
+	// Default: PrincipalFromClaims
+	Principal func(claims map[string]any) *Principal
+
+	// SkipFunc determines if authentication should be skipped.
+	SkipFunc func(r *http.Request) bool
+}
+
+// JWT returns a middleware that authenticates requests by the HS256 bearer
+// token in the Authorization header, signed with secret. See
+// JWTWithConfig.
+func JWT(secret []byte) Middleware {
+	return JWTWithConfig(JWTConfig{Secret: secret})
+}
+
+// JWTWithConfig returns a JWT middleware with the given configuration.
+// Requests without a valid token get a 401 problem with a
+// WWW-Authenticate challenge; authenticated requests carry the Principal of
+// the token's claims.
+//
+// Example:
+//
+//	s.Use(middleware.JWTWithConfig(middleware.JWTConfig{
+//	    Key: func(ctx context.Context, alg, kid string) (any, error) {
+//	        return keys.Public(kid)
+//	    },
+//	    Issuer:   "https://auth.example.com",
+//	    Audience: "api",
+//	}))
+func JWTWithConfig(config JWTConfig) Middleware {
+	if config.Key == nil {
+		if len(config.Secret) == 0 {
+			panic("helix: JWT key or secret is required")
+		}
+		secret := config.Secret
+		config.Key = func(_ context.Context, alg, _ string) (any, error) {
+			if alg != "HS256" {
+				return nil, nil
+			}
+			return secret, nil
+		}
+	}
+	if config.Leeway == 0 {
+		config.Leeway = time.Minute
+	}
+	if config.Principal == nil {
+		config.Principal = PrincipalFromClaims
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if config.SkipFunc != nil && config.SkipFunc(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, ok := bearerToken(r)
+			if !ok && config.Cookie != "" {
+				if c, err := r.Cookie(config.Cookie); err == nil {
+					token, ok = c.Value, c.Value != ""
+				}
+			}
+			if !ok {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeProblem(w, r, http.StatusUnauthorized, "bearer token required", nil)
+				return
+			}
+
+			claims, err := verifyJWT(r.Context(), config, token)
+			var keyErr *jwtKeyError
+			if errors.As(err, &keyErr) {
+				logs.FromContext(r.Context()).Error("helix: JWT key lookup failed", logs.Fields{"error": keyErr.err})
+				writeProblem(w, r, http.StatusInternalServerError, "authentication check failed", nil)
+				return
+			}
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				writeProblem(w, r, http.StatusUnauthorized, "invalid token", nil)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), config.Principal(claims))))
+		})
+	}
+}
+
+// SignJWT returns an HS256 token of claims signed with secret, such as for
+// the tokens verified by JWT. Set "exp" to limit its lifetime.
+func SignJWT(secret []byte, claims map[string]any) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// jwtKeyError is returned by verifyJWT when the configured Key fails,
+// which is the server's fault rather than the token's.
+type jwtKeyError struct{ err error }
+
+func (e *jwtKeyError) Error() string { return "key lookup: " + e.err.Error() }
+
+// verifyJWT checks the signature and registered claims of a compact JWS
+// token and returns its claims.
+func verifyJWT(ctx context.Context, config JWTConfig, token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg  string   `json:"alg"`
+		Kid  string   `json:"kid"`
+		Crit []string `json:"crit"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, errors.New("malformed header")
+	}
+	if len(header.Crit) > 0 {
+		return nil, errors.New("unsupported critical header")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed signature")
+	}
+	key, err := config.Key(ctx, header.Alg, header.Kid)
+	if err != nil {
+		return nil, &jwtKeyError{err}
+	}
+	if key == nil {
+		return nil, errors.New("unknown key")
+	}
+	if err := jws.Verify(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]any
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return nil, errors.New("malformed claims")
+	}
+
+	now := time.Now()
+	if exp, ok := claims["exp"].(float64); ok && now.Add(-config.Leeway).After(time.Unix(int64(exp), 0)) {
+		return nil, errors.New("token has expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(config.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return nil, errors.New("token is not valid yet")
+	}
+	if config.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != config.Issuer {
+			return nil, errors.New("unexpected issuer")
+		}
+	}
+	if config.Audience != "" && !slices.Contains(claimList(claims["aud"]), config.Audience) {
+		return nil, errors.New("token was not issued for this audience")
+	}
+	return claims, nil
+}
+
+// decodeJWTSegment decodes a base64url JSON segment of a JWT.
+func decodeJWTSegment(seg string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
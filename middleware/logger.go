@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
 	"os"
 	"regexp"
@@ -12,6 +13,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/kolosys/helix/logs"
 )
 
 // LogFormat represents a predefined log format.
@@ -27,10 +30,26 @@ const (
 	LogFormatJSON     LogFormat = "json"
 )
 
+// Timestamp layouts for TextOutputOptions.TimeFormat, besides the layouts
+// of the time package.
+const (
+	// TimeFormatCLF is the Common Log Format timestamp layout, as in
+	// "10/Oct/2000:13:55:36 -0700".
+	TimeFormatCLF = "02/Jan/2006:15:04:05 -0700"
+
+	// TimeFormatUnix formats timestamps as seconds since the Unix epoch.
+	TimeFormatUnix = "unix"
+
+	// TimeFormatUnixMilli formats timestamps as milliseconds since the Unix
+	// epoch, a JSON number in JSON output.
+	TimeFormatUnixMilli = "unixmilli"
+)
+
 // LogValues contains all extracted request/response data for logging.
 type LogValues struct {
 	Method        string
 	Path          string
+	Route         string
 	URI           string
 	Host          string
 	Protocol      string
@@ -49,6 +68,27 @@ type LogValues struct {
 	QueryParams   map[string]string
 	FormValues    map[string]string
 	CustomFields  map[string]string
+
+	// Principal is the ID of the authenticated principal, as set by
+	// authentication middleware with WithPrincipal.
+	Principal string
+
+	// Variants maps the experiments the request takes part in to the
+	// variant that served it, as recorded with SetVariant.
+	Variants map[string]string
+
+	// Country and City locate the client, as resolved by the GeoIP
+	// middleware or LoggerConfig.Geo.
+	Country string
+	City    string
+
+	// Client classifies the User-Agent, when LoggerConfig.ParseUserAgent
+	// is set.
+	Client *UserAgent
+
+	// ResponseBody is the start of the response body, redacted, when
+	// LoggerConfig.CaptureResponseBody is set.
+	ResponseBody []byte
 }
 
 // LogOutputFunc is a callback that receives log values and outputs them.
@@ -62,7 +102,7 @@ type TokenExtractor func(r *http.Request, body []byte) string
 type LoggerConfig struct {
 	// Output is the callback that receives log values. Required.
 	// Use TextOutput() for Morgan.js-style formatting.
-	// Use helix.StructuredOutput() for logs package integration.
+	// Use LogsOutput() for logs package integration.
 	// Or provide your own function for custom logging.
 	Output LogOutputFunc
 
@@ -90,6 +130,58 @@ type LoggerConfig struct {
 
 	// MaxBodySize limits captured body size. Default: 64KB.
 	MaxBodySize int64
+
+	// CaptureResponseBody enables response body capture for ResponseTokens
+	// and LogValues.ResponseBody, for debugging integrations.
+	CaptureResponseBody bool
+
+	// MaxResponseBodySize limits the captured response body size; the rest
+	// of the body is still sent, just not logged.
+	// Default: 64KB
+	MaxResponseBodySize int
+
+	// ResponseBodyTypes lists the content type prefixes of response bodies
+	// to capture, as binary bodies make for useless logs.
+	// Default: application/json, application/problem+json, application/xml,
+	// application/x-www-form-urlencoded, text/
+	ResponseBodyTypes []string
+
+	// ResponseTokens maps names to extractors, such as
+	// JSONBodyExtractor("error.code"), that read custom fields from the
+	// captured response body. Requires CaptureResponseBody.
+	ResponseTokens map[string]TokenExtractor
+
+	// SuccessSampleRate enables adaptive logging: every 4xx and 5xx
+	// response is logged, but only this fraction of the others, such as
+	// 0.01 for 1%, cutting the volume of high-traffic services while
+	// keeping errors visible.
+	// Default: 0 (every request is logged)
+	SuccessSampleRate float64
+
+	// RouteSampleRates overrides SuccessSampleRate for routes, keyed by
+	// pattern ("/users/{id}") or method and pattern ("GET /users/{id}").
+	// A rate of 0 logs none of the route's successes, 1 logs all of them.
+	RouteSampleRates map[string]float64
+
+	// Redactor masks sensitive values (passwords, tokens, Authorization)
+	// in the URI, extracted headers, query params, form values, custom
+	// fields, and the body passed to CustomTokens.
+	// Default: logs.DefaultRedactor()
+	Redactor *logs.Redactor
+
+	// Geo resolves the client address of logged requests into
+	// LogValues.Country and City, for requests the GeoIP middleware has not
+	// located. The address is r.RemoteAddr, as rewritten by RealIP.
+	Geo GeoResolver
+
+	// ParseUserAgent classifies the User-Agent of logged requests into
+	// LogValues.Client, logged as browser, os, device and bot fields.
+	ParseUserAgent bool
+
+	// TimeZone is the location of LogValues.StartTime, and so of the
+	// timestamps written by TextOutput. Use time.Local for server time.
+	// Default: time.UTC
+	TimeZone *time.Location
 }
 
 // Logger returns a middleware with dev format text output.
@@ -99,6 +191,15 @@ func Logger() Middleware {
 	})
 }
 
+// LoggerTo returns a middleware that writes access logs through logger, so
+// they share its formatter, hooks, sampling and outputs with application
+// logs. See LogsOutput for the entry layout.
+func LoggerTo(logger *logs.Logger) Middleware {
+	return LoggerWithConfig(LoggerConfig{
+		Output: LogsOutput(logger),
+	})
+}
+
 // LoggerWithConfig returns a Logger middleware with the given configuration.
 func LoggerWithConfig(config LoggerConfig) Middleware {
 	if config.Output == nil {
@@ -107,6 +208,25 @@ func LoggerWithConfig(config LoggerConfig) Middleware {
 	if config.MaxBodySize == 0 {
 		config.MaxBodySize = 64 << 10
 	}
+	if config.MaxResponseBodySize <= 0 {
+		config.MaxResponseBodySize = 64 << 10
+	}
+	if len(config.ResponseBodyTypes) == 0 {
+		config.ResponseBodyTypes = []string{
+			"application/json",
+			"application/problem+json",
+			"application/xml",
+			"application/x-www-form-urlencoded",
+			"text/",
+		}
+	}
+	if config.Redactor == nil {
+		config.Redactor = logs.DefaultRedactor()
+	}
+	if config.TimeZone == nil {
+		config.TimeZone = time.UTC
+	}
+	redactor := config.Redactor
 
 	// Precompile field extractors
 	fieldExtractors := make(map[string]fieldExtractor)
@@ -123,18 +243,27 @@ func LoggerWithConfig(config LoggerConfig) Middleware {
 
 			var capturedBody []byte
 			if config.CaptureBody && r.Body != nil && r.ContentLength > 0 {
-				capturedBody = captureRequestBody(r, config.MaxBodySize)
+				capturedBody = redactBody(redactor, r, captureRequestBody(r, config.MaxBodySize))
 			}
 
+			r, route := trackRoute(r)
 			start := time.Now()
 			rw := newResponseWriter(w)
+			if config.CaptureResponseBody {
+				rw.capture = &bodyCapture{limit: config.MaxResponseBodySize, types: config.ResponseBodyTypes}
+			}
 
 			next.ServeHTTP(rw, r)
 
+			if !config.sampled(r.Method, route.pattern, rw.Status()) {
+				return
+			}
+
 			v := LogValues{
 				Method:        r.Method,
 				Path:          r.URL.Path,
-				URI:           r.URL.RequestURI(),
+				Route:         route.pattern,
+				URI:           redactURI(redactor, r),
 				Host:          r.Host,
 				Protocol:      r.Proto,
 				RemoteIP:      getRemoteAddr(r),
@@ -145,15 +274,33 @@ func LoggerWithConfig(config LoggerConfig) Middleware {
 				Status:        rw.Status(),
 				ResponseSize:  rw.Size(),
 				Latency:       time.Since(start),
-				RequestID:     r.Header.Get(RequestIDHeader),
-				StartTime:     start,
+				RequestID:     GetRequestID(r.Context()),
+				StartTime:     start.In(config.TimeZone),
+			}
+			if v.RequestID == "" {
+				v.RequestID = r.Header.Get(RequestIDHeader)
+			}
+			if route.principal != nil {
+				v.Principal = route.principal.ID
+			}
+			v.Variants = route.variants
+			g := route.geo
+			if g == nil && config.Geo != nil {
+				g = resolveGeo(config.Geo, r)
+			}
+			if g != nil {
+				v.Country, v.City = g.Country, g.City
+			}
+			if config.ParseUserAgent {
+				ua := ParseUserAgent(r.UserAgent())
+				v.Client = &ua
 			}
 
 			// Extract headers
 			if len(config.LogHeaders) > 0 {
 				v.Headers = make(map[string]string, len(config.LogHeaders))
 				for _, h := range config.LogHeaders {
-					v.Headers[h] = r.Header.Get(h)
+					v.Headers[h] = redactor.RedactString(h, r.Header.Get(h))
 				}
 			}
 
@@ -162,7 +309,7 @@ func LoggerWithConfig(config LoggerConfig) Middleware {
 				v.QueryParams = make(map[string]string, len(config.LogQueryParams))
 				query := r.URL.Query()
 				for _, p := range config.LogQueryParams {
-					v.QueryParams[p] = query.Get(p)
+					v.QueryParams[p] = redactor.RedactString(p, query.Get(p))
 				}
 			}
 
@@ -170,21 +317,30 @@ func LoggerWithConfig(config LoggerConfig) Middleware {
 			if len(config.LogFormValues) > 0 {
 				v.FormValues = make(map[string]string, len(config.LogFormValues))
 				for _, f := range config.LogFormValues {
-					v.FormValues[f] = r.FormValue(f)
+					v.FormValues[f] = redactor.RedactString(f, r.FormValue(f))
 				}
 			}
 
+			if rw.capture != nil && len(rw.capture.body) > 0 {
+				v.ResponseBody = redactContent(redactor, rw.Header().Get("Content-Type"), rw.capture.body)
+			}
+
 			// Extract custom fields
-			if len(fieldExtractors) > 0 || len(config.CustomTokens) > 0 {
+			if len(fieldExtractors) > 0 || len(config.CustomTokens) > 0 || len(config.ResponseTokens) > 0 {
 				v.CustomFields = make(map[string]string)
 				for name, ext := range fieldExtractors {
 					if val := ext.extract(r); val != "" {
-						v.CustomFields[name] = val
+						v.CustomFields[name] = redactor.RedactString(name, redactor.RedactString(ext.key, val))
 					}
 				}
 				for name, ext := range config.CustomTokens {
 					if val := ext(r, capturedBody); val != "" {
-						v.CustomFields[name] = val
+						v.CustomFields[name] = redactor.RedactString(name, val)
+					}
+				}
+				for name, ext := range config.ResponseTokens {
+					if val := ext(r, v.ResponseBody); val != "" {
+						v.CustomFields[name] = redactor.RedactString(name, val)
 					}
 				}
 			}
@@ -194,11 +350,42 @@ func LoggerWithConfig(config LoggerConfig) Middleware {
 	}
 }
 
+// sampled reports whether a response with status to a request for the
+// route is logged under the sample rates.
+func (config *LoggerConfig) sampled(method, route string, status int) bool {
+	if status >= 400 || (config.SuccessSampleRate <= 0 && len(config.RouteSampleRates) == 0) {
+		return true
+	}
+
+	rate := 1.0
+	if config.SuccessSampleRate > 0 {
+		rate = config.SuccessSampleRate
+	}
+	if route != "" {
+		if override, ok := config.RouteSampleRates[method+" "+route]; ok {
+			rate = override
+		} else if override, ok := config.RouteSampleRates[route]; ok {
+			rate = override
+		}
+	}
+	return rate >= 1 || (rate > 0 && rand.Float64() < rate)
+}
+
 // --- Text Output Helpers (Morgan.js style) ---
 
 // TextOutputOptions configures text output formatting.
 type TextOutputOptions struct {
-	TimeFormat    string
+	// TimeFormat is the timestamp layout, a time package layout or one of
+	// TimeFormatCLF, TimeFormatUnix and TimeFormatUnixMilli.
+	// Default: TimeFormatCLF for LogFormatCommon and LogFormatCombined,
+	// time.RFC3339 for LogFormatJSON, time.RFC1123 otherwise
+	TimeFormat string
+
+	// TimeField names the timestamp field of LogFormatJSON entries, such
+	// as "@timestamp" or "time".
+	// Default: "timestamp"
+	TimeField string
+
 	DisableColors bool
 	JSONPretty    bool // for LogFormatJSON
 }
@@ -223,9 +410,19 @@ func TextOutputCustom(w io.Writer, format string, opts ...TextOutputOptions) Log
 // TextOutputWithOptions returns a LogOutputFunc with custom options.
 func TextOutputWithOptions(w io.Writer, format LogFormat, opts TextOutputOptions) LogOutputFunc {
 	if opts.TimeFormat == "" {
-		opts.TimeFormat = time.RFC1123
+		switch format {
+		case LogFormatJSON:
+			opts.TimeFormat = time.RFC3339
+		case LogFormatCommon, LogFormatCombined:
+			opts.TimeFormat = TimeFormatCLF
+		default:
+			opts.TimeFormat = time.RFC1123
+		}
 	}
 	if format == LogFormatJSON {
+		if opts.TimeField == "" {
+			opts.TimeField = "timestamp"
+		}
 		return jsonOutputFunc(w, opts)
 	}
 	return textOutputFunc(w, getFormatString(format), opts)
@@ -244,21 +441,33 @@ func textOutputFunc(w io.Writer, formatStr string, opts TextOutputOptions) LogOu
 			method = colorizeMethod(v.Method)
 		}
 
+		remoteUser := "-"
+		if v.Principal != "" {
+			remoteUser = v.Principal
+		}
+
 		replacements := map[string]string{
 			":method":         method,
 			":url":            v.URI,
 			":path":           v.Path,
+			":route":          v.Route,
 			":status":         status,
 			":response-time":  formatDuration(v.Latency),
 			":latency":        formatDuration(v.Latency),
 			":res-length":     formatSize(v.ResponseSize),
 			":remote-addr":    v.RemoteIP,
-			":remote-user":    "-",
-			":date":           v.StartTime.Format(opts.TimeFormat),
+			":remote-user":    remoteUser,
+			":date":           fmt.Sprint(formatTime(v.StartTime, opts.TimeFormat)),
 			":referrer":       v.Referer,
 			":user-agent":     v.UserAgent,
 			":http-version":   formatHTTPVersion(v.Protocol),
 			":request-id":     v.RequestID,
+			":country":        v.Country,
+			":city":           v.City,
+			":browser":        clientField(v.Client, "browser"),
+			":os":             clientField(v.Client, "os"),
+			":device":         clientField(v.Client, "device"),
+			":bot":            clientField(v.Client, "bot"),
 			":content-type":   v.ContentType,
 			":content-length": strconv.FormatInt(v.ContentLength, 10),
 		}
@@ -298,7 +507,6 @@ func jsonOutputFunc(w io.Writer, opts TextOutputOptions) LogOutputFunc {
 
 	return func(v LogValues) {
 		entry := map[string]any{
-			"timestamp":  v.StartTime.Format(time.RFC3339),
 			"method":     v.Method,
 			"path":       v.Path,
 			"status":     v.Status,
@@ -307,15 +515,39 @@ func jsonOutputFunc(w io.Writer, opts TextOutputOptions) LogOutputFunc {
 			"size":       v.ResponseSize,
 			"remote_ip":  v.RemoteIP,
 		}
+		if v.Route != "" {
+			entry["route"] = v.Route
+		}
 		if v.RequestID != "" {
 			entry["request_id"] = v.RequestID
 		}
 		if v.UserAgent != "" {
 			entry["user_agent"] = v.UserAgent
 		}
+		if v.Principal != "" {
+			entry["principal"] = v.Principal
+		}
+		if len(v.Variants) > 0 {
+			entry["variants"] = v.Variants
+		}
+		if v.Country != "" {
+			entry["country"] = v.Country
+		}
+		if v.City != "" {
+			entry["city"] = v.City
+		}
+		for _, name := range clientFields {
+			if val := clientField(v.Client, name); val != "" {
+				entry[name] = val
+			}
+		}
 		if len(v.CustomFields) > 0 {
 			entry["custom"] = v.CustomFields
 		}
+		if len(v.ResponseBody) > 0 {
+			entry["response_body"] = string(v.ResponseBody)
+		}
+		entry[opts.TimeField] = formatTime(v.StartTime, opts.TimeFormat)
 
 		buf := bufPool.Get().(*bytes.Buffer)
 		buf.Reset()
@@ -334,8 +566,125 @@ func jsonOutputFunc(w io.Writer, opts TextOutputOptions) LogOutputFunc {
 	}
 }
 
+// --- logs Package Output ---
+
+// LogsOutput returns a LogOutputFunc that emits each request as a structured
+// "http request" entry on logger. The level follows the status: error for
+// 5xx, warn for 4xx and info otherwise. If logger is nil, logs.Default() is
+// used.
+func LogsOutput(logger *logs.Logger) LogOutputFunc {
+	if logger == nil {
+		logger = logs.Default()
+	}
+
+	return func(v LogValues) {
+		level := logs.InfoLevel
+		switch {
+		case v.Status >= 500:
+			level = logs.ErrorLevel
+		case v.Status >= 400:
+			level = logs.WarnLevel
+		}
+
+		fields := logs.Fields{
+			"method":     v.Method,
+			"path":       v.Path,
+			"status":     v.Status,
+			"latency_ms": float64(v.Latency.Microseconds()) / 1000.0,
+			"size":       v.ResponseSize,
+			"remote_ip":  v.RemoteIP,
+		}
+		if v.Route != "" {
+			fields["route"] = v.Route
+		}
+		if v.RequestID != "" {
+			fields["request_id"] = v.RequestID
+		}
+		if v.UserAgent != "" {
+			fields["user_agent"] = v.UserAgent
+		}
+		if v.Principal != "" {
+			fields["principal"] = v.Principal
+		}
+		if len(v.Variants) > 0 {
+			fields["variants"] = v.Variants
+		}
+		if v.Country != "" {
+			fields["country"] = v.Country
+		}
+		if v.City != "" {
+			fields["city"] = v.City
+		}
+		for _, name := range clientFields {
+			if val := clientField(v.Client, name); val != "" {
+				fields[name] = val
+			}
+		}
+		if v.Error != nil {
+			fields["error"] = v.Error.Error()
+		}
+		if len(v.Headers) > 0 {
+			fields["headers"] = v.Headers
+		}
+		if len(v.QueryParams) > 0 {
+			fields["query"] = v.QueryParams
+		}
+		if len(v.FormValues) > 0 {
+			fields["form"] = v.FormValues
+		}
+		if len(v.ResponseBody) > 0 {
+			fields["response_body"] = string(v.ResponseBody)
+		}
+		for name, val := range v.CustomFields {
+			if _, exists := fields[name]; !exists {
+				fields[name] = val
+			}
+		}
+
+		logger.Log(level, "http request", fields)
+	}
+}
+
 // --- Format Helpers ---
 
+// clientFields are the User-Agent classification fields of structured
+// output.
+var clientFields = []string{"browser", "os", "device", "bot"}
+
+// clientField returns a field of the User-Agent classification ua, or ""
+// if it is nil or the field is unset.
+func clientField(ua *UserAgent, name string) string {
+	if ua == nil {
+		return ""
+	}
+	switch name {
+	case "browser":
+		if ua.BrowserVersion != "" {
+			return ua.Browser + " " + ua.BrowserVersion
+		}
+		return ua.Browser
+	case "os":
+		return ua.OS
+	case "device":
+		return ua.Device
+	case "bot":
+		return ua.BotName
+	}
+	return ""
+}
+
+// formatTime formats t with layout, returning an int64 for the epoch
+// layouts so JSON output encodes them as numbers.
+func formatTime(t time.Time, layout string) any {
+	switch layout {
+	case TimeFormatUnix:
+		return t.Unix()
+	case TimeFormatUnixMilli:
+		return t.UnixMilli()
+	}
+	return t.Format(layout)
+}
+
 func getFormatString(format LogFormat) string {
 	switch format {
 	case LogFormatCombined:
@@ -465,6 +814,36 @@ func captureRequestBody(r *http.Request, maxSize int64) []byte {
 	return body
 }
 
+// --- Redaction Helpers ---
+
+// redactURI returns the request URI with sensitive query parameters masked.
+func redactURI(redactor *logs.Redactor, r *http.Request) string {
+	if r.URL.RawQuery == "" {
+		return r.URL.RequestURI()
+	}
+	u := *r.URL
+	u.RawQuery = redactor.RedactQuery(u.RawQuery)
+	return u.RequestURI()
+}
+
+// redactBody masks sensitive values in a captured JSON or form body.
+func redactBody(redactor *logs.Redactor, r *http.Request, body []byte) []byte {
+	return redactContent(redactor, r.Header.Get("Content-Type"), body)
+}
+
+// redactContent masks sensitive values in a JSON or form body of
+// contentType.
+func redactContent(redactor *logs.Redactor, contentType string, body []byte) []byte {
+	switch {
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		return []byte(redactor.RedactQuery(string(body)))
+	case strings.Contains(contentType, "json"):
+		return redactor.RedactJSON(body)
+	default:
+		return body
+	}
+}
+
 // --- Request Helpers ---
 
 func getRemoteAddr(r *http.Request) string {
@@ -1,7 +1,13 @@
 // Package middleware provides HTTP middleware for the Helix framework.
 package middleware
 
-import "net/http"
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
 
 // Middleware is a function that wraps an http.Handler to provide additional functionality.
 type Middleware func(next http.Handler) http.Handler
@@ -18,12 +24,53 @@ func Chain(middlewares ...Middleware) Middleware {
 	}
 }
 
+// When returns a middleware that applies mw only to requests for which
+// predicate returns true; other requests skip straight to the next handler.
+//
+// Example:
+//
+//	s.Use(middleware.When(func(r *http.Request) bool {
+//	    return r.Method != http.MethodOptions
+//	}, middleware.RateLimit(10, 20)))
+func When(predicate func(r *http.Request) bool, mw Middleware) Middleware {
+	if predicate == nil || mw == nil {
+		panic("helix: When requires a predicate and a middleware")
+	}
+	return func(next http.Handler) http.Handler {
+		wrapped := mw(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if predicate(r) {
+				wrapped.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Unless returns a middleware that applies mw to every request except
+// those under pathPrefix, such as health checks, metrics or static assets.
+// The prefix matches whole path segments: "/health" skips "/health" and
+// "/health/live" but not "/healthz".
+//
+// Example:
+//
+//	s.Use(middleware.Unless("/metrics", middleware.Logger()))
+func Unless(pathPrefix string, mw Middleware) Middleware {
+	prefix := strings.TrimSuffix(pathPrefix, "/")
+	return When(func(r *http.Request) bool {
+		path := r.URL.Path
+		return path != prefix && !strings.HasPrefix(path, prefix+"/")
+	}, mw)
+}
+
 // responseWriter wraps http.ResponseWriter to capture response information.
 type responseWriter struct {
 	http.ResponseWriter
 	status      int
 	size        int
 	wroteHeader bool
+	capture     *bodyCapture // records the body, if set
 }
 
 // newResponseWriter creates a new responseWriter.
@@ -36,6 +83,10 @@ func newResponseWriter(w http.ResponseWriter) *responseWriter {
 
 // WriteHeader implements http.ResponseWriter.
 func (rw *responseWriter) WriteHeader(code int) {
+	if informational(code) {
+		rw.ResponseWriter.WriteHeader(code)
+		return
+	}
 	if rw.wroteHeader {
 		return
 	}
@@ -51,9 +102,19 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	}
 	n, err := rw.ResponseWriter.Write(b)
 	rw.size += n
+	if rw.capture != nil {
+		rw.capture.write(rw.Header(), b[:n])
+	}
 	return n, err
 }
 
+// informational reports whether code is an interim 1xx response, such as
+// 103 Early Hints, that is followed by the final response. 101 Switching
+// Protocols ends the response and is not informational here.
+func informational(code int) bool {
+	return code >= 100 && code < 200 && code != http.StatusSwitchingProtocols
+}
+
 // Status returns the HTTP status code of the response.
 func (rw *responseWriter) Status() int {
 	return rw.status
@@ -64,19 +125,49 @@ func (rw *responseWriter) Size() int {
 	return rw.size
 }
 
+// ReadFrom implements io.ReaderFrom, using the underlying implementation
+// if available so sendfile optimizations are preserved.
+func (rw *responseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if rw.capture != nil && rw.capture.wants(rw.Header()) {
+		// Copy through Write to record the body
+		return io.Copy(writerOnly{rw}, r)
+	}
+	var n int64
+	var err error
+	if rf, ok := rw.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = io.Copy(writerOnly{rw.ResponseWriter}, r)
+	}
+	rw.size += int(n)
+	return n, err
+}
+
 // Flush implements http.Flusher.
 func (rw *responseWriter) Flush() {
-	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
-		flusher.Flush()
+	rw.FlushError()
+}
+
+// FlushError flushes the response, returning http.ErrNotSupported if the
+// underlying writer can't flush. It is used by http.ResponseController.
+func (rw *responseWriter) FlushError() error {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
 	}
+	return http.NewResponseController(rw.ResponseWriter).Flush()
 }
 
 // Hijack implements http.Hijacker.
-func (rw *responseWriter) Hijack() (c any, rw2 any, err error) {
-	if hijacker, ok := rw.ResponseWriter.(http.Hijacker); ok {
-		return hijacker.Hijack()
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(rw.ResponseWriter).Hijack()
+	if err == nil && !rw.wroteHeader {
+		rw.status = http.StatusSwitchingProtocols
+		rw.wroteHeader = true
 	}
-	return nil, nil, http.ErrNotSupported
+	return conn, brw, err
 }
 
 // Push implements http.Pusher.
@@ -86,3 +177,44 @@ func (rw *responseWriter) Push(target string, opts *http.PushOptions) error {
 	}
 	return http.ErrNotSupported
 }
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// bodyCapture records the first bytes of a response body of the content
+// types it is limited to.
+type bodyCapture struct {
+	limit   int
+	types   []string
+	decided bool
+	active  bool
+	body    []byte
+}
+
+// wants reports whether a body with the headers h is recorded.
+func (c *bodyCapture) wants(h http.Header) bool {
+	if !c.decided {
+		c.decided = true
+		contentType := strings.ToLower(h.Get("Content-Type"))
+		c.active = contentType != "" && hasTypePrefix(c.types, contentType)
+	}
+	return c.active
+}
+
+// write records b, up to the limit.
+func (c *bodyCapture) write(h http.Header, b []byte) {
+	if !c.wants(h) {
+		return
+	}
+	if room := c.limit - len(c.body); room > 0 {
+		c.body = append(c.body, b[:min(room, len(b))]...)
+	}
+}
+
+// writerOnly hides any io.ReaderFrom implementation so io.Copy doesn't
+// call back into the wrapping writer.
+type writerOnly struct {
+	io.Writer
+}
@@ -1,15 +1,34 @@
 package middleware_test
 
 import (
+	"bufio"
 	"bytes"
 	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"maps"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/kolosys/helix/logs"
 	. "github.com/kolosys/helix/middleware"
 )
 
@@ -54,6 +73,38 @@ func TestChain(t *testing.T) {
 	}
 }
 
+func TestWhenUnless(t *testing.T) {
+	tag := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Tagged", "1")
+			next.ServeHTTP(w, r)
+		})
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	when := When(func(r *http.Request) bool { return r.Method == http.MethodPost }, tag)(ok)
+	unless := Unless("/health/", tag)(ok)
+
+	for _, tc := range []struct {
+		handler      http.Handler
+		method, path string
+		tagged       bool
+	}{
+		{when, http.MethodPost, "/", true},
+		{when, http.MethodGet, "/", false},
+		{unless, http.MethodGet, "/health", false},
+		{unless, http.MethodGet, "/health/live", false},
+		{unless, http.MethodGet, "/healthz", true},
+		{unless, http.MethodGet, "/users", true},
+	} {
+		rec := httptest.NewRecorder()
+		tc.handler.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		if tagged := rec.Header().Get("X-Tagged") != ""; tagged != tc.tagged {
+			t.Errorf("%s %s: expected tagged=%v", tc.method, tc.path, tc.tagged)
+		}
+	}
+}
+
 func TestResponseWriter(t *testing.T) {
 	rec := httptest.NewRecorder()
 	rw := NewResponseWriter(rec)
@@ -95,6 +146,74 @@ func TestResponseWriterDefaultStatus(t *testing.T) {
 	}
 }
 
+// hijackableRecorder is a ResponseRecorder that supports hijacking.
+type hijackableRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (h *hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.hijacked = true
+	server, client := net.Pipe()
+	client.Close()
+	return server, nil, nil
+}
+
+func TestResponseWriterPassthrough(t *testing.T) {
+	rec := httptest.NewRecorder()
+	var w http.ResponseWriter = NewResponseWriter(rec)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		t.Fatal("expected http.Flusher")
+	}
+	flusher.Flush()
+	if !rec.Flushed {
+		t.Error("expected flush to reach the underlying writer")
+	}
+
+	n, err := w.(io.ReaderFrom).ReadFrom(strings.NewReader("hello"))
+	if err != nil || n != 5 || rec.Body.String() != "hello" {
+		t.Errorf("expected ReadFrom to copy body, got %d %v %q", n, err, rec.Body.String())
+	}
+
+	if _, _, err := w.(http.Hijacker).Hijack(); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+	if err := w.(http.Pusher).Push("/app.js", nil); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+
+	hr := &hijackableRecorder{ResponseRecorder: httptest.NewRecorder()}
+	conn, _, err := http.NewResponseController(NewResponseWriter(hr)).Hijack()
+	if err != nil || !hr.hijacked {
+		t.Fatalf("expected hijack to reach the underlying writer, got %v", err)
+	}
+	conn.Close()
+}
+
+func TestLoggerPreservesHijacker(t *testing.T) {
+	var hijackErr error
+	handler := LoggerWithConfig(LoggerConfig{Output: func(LogValues) {}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			t.Fatal("expected logger writer to implement http.Hijacker")
+		}
+		var conn net.Conn
+		conn, _, hijackErr = hj.Hijack()
+		if conn != nil {
+			conn.Close()
+		}
+	}))
+
+	hr := &hijackableRecorder{ResponseRecorder: httptest.NewRecorder()}
+	handler.ServeHTTP(hr, httptest.NewRequest(http.MethodGet, "/ws", nil))
+
+	if hijackErr != nil || !hr.hijacked {
+		t.Errorf("expected hijack through logger, got %v", hijackErr)
+	}
+}
+
 func TestRecover(t *testing.T) {
 	output := &bytes.Buffer{}
 	mw := RecoverWithConfig(RecoverConfig{
@@ -258,6 +377,68 @@ func TestRequestIDCustomGenerator(t *testing.T) {
 	}
 }
 
+func TestRequestIDTrustIncoming(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   RequestIDConfig
+		incoming string
+		reused   bool
+	}{
+		{"trusted", DefaultRequestIDConfig(), "abc-123_x.y:z", true},
+		{"invalid charset", DefaultRequestIDConfig(), "abc 123\"", false},
+		{"too long", DefaultRequestIDConfig(), strings.Repeat("a", 129), false},
+		{"zero config", RequestIDConfig{Header: RequestIDHeader}, "abc-123", true},
+		{"ignored", RequestIDConfig{IgnoreIncoming: true}, "abc-123", false},
+		{"custom validator", RequestIDConfig{Validator: func(id string) bool { return len(id) == 36 }}, "abc-123", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var id string
+			handler := RequestIDWithConfig(tt.config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				id = GetRequestIDFromRequest(r)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(RequestIDHeader, tt.incoming)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if (id == tt.incoming) != tt.reused {
+				t.Errorf("expected reused=%v, got ID %q", tt.reused, id)
+			}
+			if id == "" {
+				t.Error("expected a request ID")
+			}
+		})
+	}
+}
+
+func TestRequestIDGenerators(t *testing.T) {
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	ulidPattern := regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+
+	for name, tt := range map[string]struct {
+		gen     func() string
+		pattern *regexp.Regexp
+	}{
+		"UUIDv7": {UUIDv7, uuidPattern},
+		"ULID":   {ULID, ulidPattern},
+	} {
+		first := tt.gen()
+		time.Sleep(2 * time.Millisecond)
+		second := tt.gen()
+
+		if !tt.pattern.MatchString(first) {
+			t.Errorf("%s: malformed ID %q", name, first)
+		}
+		if first >= second {
+			t.Errorf("%s: expected IDs to sort by time, got %q then %q", name, first, second)
+		}
+		if !ValidRequestID(first) {
+			t.Errorf("%s: expected generated ID to pass validation", name)
+		}
+	}
+}
+
 func TestCORS(t *testing.T) {
 	mw := CORS()
 
@@ -300,6 +481,68 @@ func TestCORSPreflight(t *testing.T) {
 	}
 }
 
+func TestCORSMaxAge(t *testing.T) {
+	for _, tc := range []struct {
+		maxAge int
+		want   string
+	}{{600, "600"}, {0, ""}, {-1, "0"}} {
+		handler := CORSWithConfig(CORSConfig{AllowOrigins: []string{"*"}, MaxAge: tc.maxAge})(http.NotFoundHandler())
+
+		req := httptest.NewRequest(http.MethodOptions, "/", nil)
+		req.Header.Set("Origin", "http://example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get("Access-Control-Max-Age"); got != tc.want {
+			t.Errorf("MaxAge %d: expected Access-Control-Max-Age %q, got %q", tc.maxAge, tc.want, got)
+		}
+		if _, ok := rec.Header()["Access-Control-Max-Age"]; ok != (tc.want != "") {
+			t.Errorf("MaxAge %d: unexpected header presence", tc.maxAge)
+		}
+	}
+}
+
+func TestCORSRouteMethods(t *testing.T) {
+	var nextCalled bool
+	handler := CORSWithConfig(CORSConfig{
+		AllowOrigins: []string{"*"},
+		AllowMethods: []string{http.MethodGet, http.MethodPost, http.MethodOptions},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nextCalled = true
+		http.NotFound(w, r)
+	}))
+	methods := func(r *http.Request) []string {
+		if r.URL.Path == "/items" {
+			return []string{http.MethodDelete, http.MethodGet, http.MethodOptions}
+		}
+		return nil
+	}
+
+	preflight := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, path, nil)
+		req.Header.Set("Origin", "http://example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		req = req.WithContext(WithRouteMethods(req.Context(), methods))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := preflight("/items")
+	if got := rec.Header().Get("Access-Control-Allow-Methods"); got != "GET, OPTIONS" {
+		t.Errorf("expected the registered methods the config allows, got %q", got)
+	}
+	if nextCalled {
+		t.Error("expected the preflight to be answered")
+	}
+
+	rec = preflight("/missing")
+	if !nextCalled || rec.Code != http.StatusNotFound || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("expected preflights for unknown paths to be passed on, got %d %v", rec.Code, rec.Header())
+	}
+}
+
 func TestCORSWithConfig(t *testing.T) {
 	mw := CORSWithConfig(CORSConfig{
 		AllowOrigins:     []string{"http://allowed.com"},
@@ -381,6 +624,58 @@ func TestCORSAllowAll(t *testing.T) {
 	}
 }
 
+func TestCORSWildcardOrigins(t *testing.T) {
+	handler := CORSWithConfig(CORSConfig{
+		AllowOrigins:     []string{"https://*.example.com", "http://localhost:3000"},
+		AllowCredentials: true,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := map[string]bool{
+		"https://app.example.com":    true,
+		"https://eu.app.example.com": true,
+		"HTTP://localhost:3000":      true,
+		"https://example.com":        false,
+		"https://evilexample.com":    false,
+		"http://app.example.com":     false,
+		"https://a.b@example.com":    false,
+		"https://app.example.com.io": false,
+	}
+	for origin, want := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Access-Control-Allow-Origin") == origin; got != want {
+			t.Errorf("%s: expected allowed=%v, got %v", origin, want, got)
+		}
+	}
+}
+
+func TestCORSPrivateNetwork(t *testing.T) {
+	handler := CORSWithConfig(CORSConfig{
+		AllowOriginFunc:     func(origin string) bool { return strings.HasSuffix(origin, ".tenant.example") },
+		AllowPrivateNetwork: true,
+		MaxAge:              600,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodOptions, "/", nil)
+	req.Header.Set("Origin", "https://acme.tenant.example")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Private-Network", "true")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Private-Network"); got != "true" {
+		t.Errorf("expected private network access to be allowed, got %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://acme.tenant.example" {
+		t.Errorf("expected the origin to be echoed, got %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Max-Age"); got != "600" {
+		t.Errorf("expected Max-Age 600, got %q", got)
+	}
+}
+
 func TestTimeout(t *testing.T) {
 	mw := Timeout(100 * time.Millisecond)
 
@@ -417,6 +712,41 @@ func TestTimeoutNoTimeout(t *testing.T) {
 	}
 }
 
+// deadlineRecorder is a ResponseRecorder that records write deadlines set
+// through http.ResponseController.
+type deadlineRecorder struct {
+	*httptest.ResponseRecorder
+	deadline time.Time
+}
+
+func (d *deadlineRecorder) SetWriteDeadline(t time.Time) error {
+	d.deadline = t
+	return nil
+}
+
+func TestTimeoutResponseController(t *testing.T) {
+	deadline := time.Now().Add(time.Minute)
+	handler := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+		if err := rc.SetWriteDeadline(deadline); err != nil {
+			t.Errorf("expected the write deadline to reach the connection, got %v", err)
+		}
+		w.Write([]byte("event"))
+		if err := rc.Flush(); err != nil {
+			t.Errorf("expected flush to succeed, got %v", err)
+		}
+	}))
+
+	rec := &deadlineRecorder{ResponseRecorder: httptest.NewRecorder()}
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !rec.deadline.Equal(deadline) {
+		t.Errorf("expected deadline %v, got %v", deadline, rec.deadline)
+	}
+	if !rec.Flushed {
+		t.Error("expected the response to be flushed")
+	}
+}
+
 func TestTimeoutSkip(t *testing.T) {
 	mw := TimeoutWithConfig(TimeoutConfig{
 		Timeout: 10 * time.Millisecond,
@@ -524,6 +854,120 @@ func TestCompressSmallResponse(t *testing.T) {
 	}
 }
 
+func TestCompressFlushStreams(t *testing.T) {
+	mw := CompressWithConfig(CompressConfig{MinSize: 1024})
+
+	var flushedBeforeEnd string
+	var rec *httptest.ResponseRecorder
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: one\n\n"))
+		w.(http.Flusher).Flush()
+		flushedBeforeEnd = rec.Body.String()
+		w.Write([]byte("data: two\n\n"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if flushedBeforeEnd != "data: one\n\n" {
+		t.Errorf("expected first event to be flushed, got %q", flushedBeforeEnd)
+	}
+	if rec.Body.String() != "data: one\n\ndata: two\n\n" {
+		t.Errorf("unexpected body %q", rec.Body.String())
+	}
+	if !rec.Flushed {
+		t.Error("expected flush to reach the underlying writer")
+	}
+}
+
+func TestCompressSkipsCompressedTypes(t *testing.T) {
+	mw := CompressWithConfig(CompressConfig{MinSize: 16, Types: []string{"image/", "application/"}})
+	data := bytes.Repeat([]byte("a"), 64)
+
+	for _, tc := range []struct {
+		contentType, encoding string
+		compressed            bool
+	}{
+		{contentType: "image/png"},
+		{contentType: "application/zip"},
+		{contentType: "application/json", encoding: "br"},
+		{contentType: "image/bmp", compressed: true},
+	} {
+		handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", tc.contentType)
+			if tc.encoding != "" {
+				w.Header().Set("Content-Encoding", tc.encoding)
+			}
+			w.Write(data)
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		gzipped := rec.Header().Get("Content-Encoding") == "gzip"
+		if gzipped != tc.compressed {
+			t.Errorf("%s: expected compressed=%v, got encoding %q", tc.contentType, tc.compressed, rec.Header().Get("Content-Encoding"))
+		}
+		if !tc.compressed && !bytes.Equal(rec.Body.Bytes(), data) {
+			t.Errorf("%s: expected the body unchanged", tc.contentType)
+		}
+	}
+}
+
+func TestCompressStreamsPastMinSize(t *testing.T) {
+	mw := CompressWithConfig(CompressConfig{MinSize: 100, Level: 1})
+	chunk := strings.Repeat("x", 60)
+
+	var afterFirst, afterSecond int
+	var rec *httptest.ResponseRecorder
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(chunk))
+		afterFirst = len(rec.Header().Get("Content-Encoding"))
+		w.Write([]byte(chunk))
+		afterSecond = len(rec.Header().Get("Content-Encoding"))
+		for range 10 {
+			w.Write([]byte(chunk))
+		}
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if afterFirst != 0 || afterSecond == 0 {
+		t.Errorf("expected the encoding decided once MinSize was reached (%d, %d)", afterFirst, afterSecond)
+	}
+	reader, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(reader)
+	if string(body) != strings.Repeat(chunk, 12) {
+		t.Errorf("unexpected decompressed body of %d bytes", len(body))
+	}
+
+	// A declared Content-Length decides without buffering
+	handler = mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Length", "120")
+		w.Write([]byte(chunk))
+		afterFirst = len(rec.Header().Get("Content-Encoding"))
+		w.Write([]byte(chunk))
+	}))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if afterFirst == 0 || rec.Header().Get("Content-Length") != "" {
+		t.Errorf("expected compression to start with the first write, got headers %v", rec.Header())
+	}
+}
+
 func TestRateLimit(t *testing.T) {
 	mw := RateLimit(2, 2) // 2 requests per second, burst of 2
 
@@ -562,6 +1006,93 @@ func TestRateLimit(t *testing.T) {
 	}
 }
 
+func TestRateLimitProblem(t *testing.T) {
+	handler := RateLimit(0.5, 1)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/items", nil)
+		req.RemoteAddr = "192.168.1.2:12345"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve(); rec.Header().Get("X-RateLimit-Reset") == "" {
+		t.Error("expected X-RateLimit-Reset on allowed requests")
+	}
+
+	rec := serve()
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("expected a problem response, got %q", ct)
+	}
+	// A token takes two seconds to refill at 0.5/s
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("expected Retry-After 2, got %q", got)
+	}
+
+	var body struct {
+		Type       string  `json:"type"`
+		Status     int     `json:"status"`
+		Limit      float64 `json:"limit"`
+		Remaining  int     `json:"remaining"`
+		RetryAfter int64   `json:"retry_after"`
+		Reset      int64   `json:"reset"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Type != "about:blank#too_many_requests" || body.Status != http.StatusTooManyRequests {
+		t.Errorf("unexpected problem %+v", body)
+	}
+	if body.Limit != 0.5 || body.Remaining != 0 || body.RetryAfter != 2 {
+		t.Errorf("unexpected extensions %+v", body)
+	}
+	if reset := strconv.FormatInt(body.Reset, 10); reset != rec.Header().Get("X-RateLimit-Reset") {
+		t.Errorf("expected reset %s to match the header %s", reset, rec.Header().Get("X-RateLimit-Reset"))
+	}
+}
+
+func TestRateLimitDraftHeaders(t *testing.T) {
+	tests := []struct {
+		headers    RateLimitHeaders
+		wantLegacy bool
+		wantDraft  bool
+	}{
+		{RateLimitHeadersLegacy, true, false},
+		{RateLimitHeadersDraft, false, true},
+		{RateLimitHeadersBoth, true, true},
+	}
+	for _, tt := range tests {
+		handler := RateLimitWithConfig(RateLimitConfig{Rate: 1, Burst: 5, Headers: tt.headers})(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		h := rec.Header()
+		if got := h.Get("X-RateLimit-Limit") != ""; got != tt.wantLegacy {
+			t.Errorf("headers %d: X-RateLimit-Limit present = %v", tt.headers, got)
+		}
+		if !tt.wantDraft {
+			if h.Get("RateLimit") != "" {
+				t.Errorf("headers %d: unexpected RateLimit header", tt.headers)
+			}
+			continue
+		}
+		// One of five tokens is used and refills in a second
+		if h.Get("RateLimit-Limit") != "5" || h.Get("RateLimit-Remaining") != "4" || h.Get("RateLimit-Reset") != "1" {
+			t.Errorf("headers %d: unexpected fields %v", tt.headers, h)
+		}
+		if got := h.Get("RateLimit"); got != "limit=5, remaining=4, reset=1" {
+			t.Errorf("headers %d: expected combined RateLimit header, got %q", tt.headers, got)
+		}
+	}
+}
+
 func TestRateLimitDifferentClients(t *testing.T) {
 	mw := RateLimit(1, 1) // 1 request per second, burst of 1
 
@@ -617,6 +1148,61 @@ func TestRateLimitSkip(t *testing.T) {
 	}
 }
 
+func TestRateLimitClasses(t *testing.T) {
+	type userKey struct{}
+	mw := RateLimitWithConfig(RateLimitConfig{
+		Rate:  1,
+		Burst: 1,
+		Classes: []RateLimitClass{
+			{Name: "user", Key: ContextKey(userKey{}), Burst: 3},
+			{Name: "api_key", Key: HeaderKey("X-API-Key"), Rate: 5, Burst: 2},
+		},
+	})
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	// All clients share one IP, as behind a corporate NAT
+	allowed := func(user, apiKey string, n int) int {
+		ok := 0
+		for i := 0; i < n; i++ {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "10.0.0.1:1234"
+			if user != "" {
+				req = req.WithContext(context.WithValue(req.Context(), userKey{}, user))
+			}
+			if apiKey != "" {
+				req.Header.Set("X-API-Key", apiKey)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code == http.StatusOK {
+				ok++
+			}
+		}
+		return ok
+	}
+
+	if got := allowed("ann", "", 5); got != 3 {
+		t.Errorf("expected 3 requests for ann, got %d", got)
+	}
+	if got := allowed("bob", "", 5); got != 3 {
+		t.Errorf("expected bob to have his own bucket, got %d", got)
+	}
+	if got := allowed("", "key-1", 5); got != 2 {
+		t.Errorf("expected 2 requests for the API key, got %d", got)
+	}
+	if got := allowed("", "", 5); got != 1 {
+		t.Errorf("expected 1 anonymous request, got %d", got)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-API-Key", "key-2")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if limit := rec.Header().Get("X-RateLimit-Limit"); limit != "5" {
+		t.Errorf("expected the class rate in X-RateLimit-Limit, got %q", limit)
+	}
+}
+
 func TestBasicAuth(t *testing.T) {
 	mw := BasicAuth("admin", "secret")
 
@@ -724,32 +1310,434 @@ func TestBasicAuthSkip(t *testing.T) {
 	}
 }
 
-func TestETag(t *testing.T) {
-	mw := ETag()
+func TestBasicAuthStore(t *testing.T) {
+	hash, err := HashPassword("s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$pbkdf2-sha256$i=600000$") {
+		t.Errorf("unexpected hash format %q", hash)
+	}
+	if other, _ := HashPassword("s3cret"); other == hash {
+		t.Error("expected hashes to be salted")
+	}
+	if VerifyPassword(strings.Replace(hash, "i=600000", "i=x", 1), "s3cret") || VerifyPassword("$unknown$x", "s3cret") {
+		t.Error("expected malformed and unknown hashes not to match")
+	}
 
-	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.Write([]byte(`{"data":"test"}`))
+	RegisterPasswordHash("$test-plain$", func(hash, password string) bool {
+		return strings.TrimPrefix(hash, "$test-plain$") == password
+	})
+
+	var storeErr error
+	handler := BasicAuthWithConfig(BasicAuthConfig{
+		Store: CredentialStoreFunc(func(ctx context.Context, username string) (string, error) {
+			return CredentialMap{"alice": hash, "bob": "$test-plain$hunter2"}[username], storeErr
+		}),
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(PrincipalFrom(r.Context()).ID))
 	}))
 
+	for _, tc := range []struct {
+		user, pass string
+		expected   int
+	}{
+		{"alice", "s3cret", http.StatusOK},
+		{"alice", "wrong", http.StatusUnauthorized},
+		{"bob", "hunter2", http.StatusOK},
+		{"mallory", "s3cret", http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(tc.user, tc.pass)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.expected {
+			t.Errorf("%s/%s: expected status %d, got %d", tc.user, tc.pass, tc.expected, rec.Code)
+		}
+		if rec.Code == http.StatusOK && rec.Body.String() != tc.user {
+			t.Errorf("expected principal %q, got %q", tc.user, rec.Body.String())
+		}
+	}
+
+	storeErr = errors.New("database down")
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth("alice", "s3cret")
 	rec := httptest.NewRecorder()
-
 	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected store errors to fail the request, got %d", rec.Code)
+	}
+}
 
-	etag := rec.Header().Get("ETag")
-	if etag == "" {
-		t.Error("expected ETag header")
+func TestBasicAuthDummyHash(t *testing.T) {
+	users := CredentialMap{"alice": "$custom$alice-pass"}
+	serve := func(handler http.Handler, user string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(user, "alice-pass")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
 	}
-	if !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) {
-		t.Errorf("ETag should be quoted, got '%s'", etag)
+	var verified []string
+	verify := func(hash, password string) bool {
+		verified = append(verified, hash)
+		return hash == "$custom$"+password
 	}
-}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 
-func TestETagNotModified(t *testing.T) {
-	mw := ETag()
+	// Unknown users are checked against a hash of the store's scheme
+	handler := BasicAuthWithConfig(BasicAuthConfig{Store: users, Verify: verify})(ok)
+	serve(handler, "alice")
+	verified = nil
+	if code := serve(handler, "mallory"); code != http.StatusUnauthorized {
+		t.Errorf("expected an unknown user to be rejected, got %d", code)
+	}
+	if !slices.Equal(verified, []string{"$custom$alice-pass"}) {
+		t.Errorf("expected the unknown user to be checked against the last known hash, got %v", verified)
+	}
 
-	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler = BasicAuthWithConfig(BasicAuthConfig{Store: users, Verify: verify, DummyHash: "$custom$dummy"})(ok)
+	verified = nil
+	if code := serve(handler, "mallory"); code != http.StatusUnauthorized {
+		t.Errorf("expected an unknown user to be rejected, got %d", code)
+	}
+	if !slices.Equal(verified, []string{"$custom$dummy"}) {
+		t.Errorf("expected the unknown user to be checked against DummyHash, got %v", verified)
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	var logged LogValues
+	var got *Principal
+	handler := LoggerWithConfig(LoggerConfig{Output: func(v LogValues) { logged = v }})(
+		APIKey(map[string]*Principal{"k-123": {ID: "billing", Scopes: []string{"invoices:read"}}})(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = PrincipalFrom(r.Context())
+			})))
+
+	for _, tc := range []struct {
+		key      string
+		expected int
+	}{{"k-123", http.StatusOK}, {"k-124", http.StatusUnauthorized}, {"", http.StatusUnauthorized}} {
+		got, logged = nil, LogValues{}
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tc.key != "" {
+			req.Header.Set("X-API-Key", tc.key)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.expected {
+			t.Errorf("key %q: expected status %d, got %d", tc.key, tc.expected, rec.Code)
+		}
+		if tc.expected == http.StatusOK && (got == nil || got.ID != "billing" || logged.Principal != "billing") {
+			t.Errorf("expected the billing principal to be set and logged, got %+v, %q", got, logged.Principal)
+		}
+	}
+
+	handler = APIKeyWithConfig(APIKeyConfig{
+		Query: "api_key",
+		Lookup: func(ctx context.Context, key string) (*Principal, error) {
+			return nil, errors.New("database down")
+		},
+	})(http.NotFoundHandler())
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?api_key=abc", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected lookup errors to fail the request, got %d", rec.Code)
+	}
+}
+
+func TestJWT(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	var got *Principal
+	handler := JWTWithConfig(JWTConfig{Secret: secret, Issuer: "https://auth.example.com", Audience: "api"})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = PrincipalFrom(r.Context())
+		}))
+	sign := func(claims map[string]any) string {
+		token, err := SignJWT(secret, claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	valid := map[string]any{
+		"sub": "u1", "iss": "https://auth.example.com", "aud": []string{"api", "web"},
+		"exp": time.Now().Add(time.Hour).Unix(), "roles": []string{"admin"}, "scope": "read write", "tenant": "acme",
+	}
+	with := func(name string, v any) map[string]any {
+		claims := maps.Clone(valid)
+		claims[name] = v
+		return claims
+	}
+	serve := func(token string) *httptest.ResponseRecorder {
+		got = nil
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve(sign(valid)); rec.Code != http.StatusOK {
+		t.Fatalf("expected a valid token to pass, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got.ID != "u1" || !got.HasRole("admin") || !slices.Equal(got.Scopes, []string{"read", "write"}) || got.Attr("tenant") != "acme" {
+		t.Errorf("unexpected principal %+v", got)
+	}
+
+	noneHeader := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	payload := strings.Split(sign(valid), ".")[1]
+	other, _ := SignJWT([]byte("another secret, also 32 bytes ok"), valid)
+	for name, token := range map[string]string{
+		"missing":      "",
+		"expired":      sign(with("exp", time.Now().Add(-time.Hour).Unix())),
+		"not yet":      sign(with("nbf", time.Now().Add(time.Hour).Unix())),
+		"issuer":       sign(with("iss", "https://evil.example")),
+		"audience":     sign(with("aud", "web")),
+		"wrong secret": other,
+		"alg none":     noneHeader + "." + payload + ".",
+		"malformed":    "a.b",
+	} {
+		rec := serve(token)
+		if rec.Code != http.StatusUnauthorized || got != nil {
+			t.Errorf("%s: expected 401, got %d", name, rec.Code)
+		}
+		if !strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), "Bearer") {
+			t.Errorf("%s: expected a Bearer challenge, got %q", name, rec.Header().Get("WWW-Authenticate"))
+		}
+	}
+
+	// Asymmetric keys must match the token's algorithm
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256","kid":"k1"}`)) + "." + payload
+	digest := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	es256 := signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+
+	handler = JWTWithConfig(JWTConfig{Key: func(ctx context.Context, alg, kid string) (any, error) {
+		return &key.PublicKey, nil
+	}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = PrincipalFrom(r.Context())
+	}))
+	if rec := serve(es256); rec.Code != http.StatusOK || got.ID != "u1" {
+		t.Errorf("expected an ES256 token to pass, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve(sign(valid)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected an HS256 token to fail against an ECDSA key, got %d", rec.Code)
+	}
+
+	// Unknown keys are rejected; failing key lookups are the server's error
+	var lookupErr error
+	handler = JWTWithConfig(JWTConfig{Key: func(ctx context.Context, alg, kid string) (any, error) {
+		return nil, lookupErr
+	}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	if rec := serve(es256); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected an unknown key to be rejected, got %d", rec.Code)
+	}
+	lookupErr = errors.New("key store down")
+	if rec := serve(es256); rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "key store down") {
+		t.Errorf("expected a failing key lookup to answer 500 without details, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestJWT_ProblemDetail(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	token, _ := SignJWT(secret, map[string]any{"sub": "u1", "exp": time.Now().Add(-time.Hour).Unix()})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	JWT(secret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized || strings.Contains(rec.Body.String(), "expired") {
+		t.Errorf("expected a 401 that does not say why the token failed, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestJWT_ContextLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logs.New(logs.WithOutput(&buf), logs.WithFormatter(&logs.JSONFormatter{}))
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	handler := Chain(ContextLogger(logger), JWT(secret))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logs.FromContext(r.Context()).Info("loading orders")
+	}))
+
+	token, _ := SignJWT(secret, map[string]any{"sub": "u1"})
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to decode log line %q: %v", buf.String(), err)
+	}
+	if entry["principal"] != "u1" {
+		t.Errorf("expected the principal in the request logger, got %v", entry["principal"])
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	secret := []byte("webhook-secret")
+	var body string
+	handler := VerifySignature(SignatureConfig{
+		Key: func(ctx context.Context, keyID string) ([]byte, error) {
+			switch keyID {
+			case "k1":
+				return secret, nil
+			case "empty":
+				return []byte{}, nil
+			}
+			return nil, nil
+		},
+		SignedHeaders: []string{"Host", "Content-Type"},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	}))
+
+	signed := func(keyID string, key []byte) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/hooks/orders?b=2&a=1", strings.NewReader(`{"id":1}`))
+		req.Header.Set("Content-Type", "application/json")
+		if err := SignRequest(req, keyID, key, "Host", "Content-Type"); err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+	serve := func(req *http.Request) int {
+		body = ""
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	req := signed("k1", secret)
+	replay := req.Clone(context.Background())
+	replay.Body, _ = req.GetBody()
+	if code := serve(req); code != http.StatusOK || body != `{"id":1}` {
+		t.Fatalf("expected a signed request to pass with its body, got %d %q", code, body)
+	}
+	if code := serve(replay); code != http.StatusUnauthorized {
+		t.Errorf("expected a replayed request to be rejected, got %d", code)
+	}
+
+	tampered := signed("k1", secret)
+	tampered.Body = io.NopCloser(strings.NewReader(`{"id":2}`))
+	retyped := signed("k1", secret)
+	retyped.Header.Set("Content-Type", "text/plain")
+	requeried := signed("k1", secret)
+	requeried.URL.RawQuery = "a=1&b=3"
+	stale := signed("k1", secret)
+	stale.Header.Set("X-Signature", strings.Replace(stale.Header.Get("X-Signature"), "t=", "t=1", 1))
+	unsigned := httptest.NewRequest(http.MethodPost, "/hooks/orders", nil)
+	for name, req := range map[string]*http.Request{
+		"tampered body":   tampered,
+		"changed header":  retyped,
+		"changed query":   requeried,
+		"wrong secret":    signed("k1", []byte("guess")),
+		"unknown key":     signed("k2", secret),
+		"empty secret":    signed("empty", nil),
+		"stale timestamp": stale,
+		"unsigned":        unsigned,
+	} {
+		if code := serve(req); code != http.StatusUnauthorized || body != "" {
+			t.Errorf("%s: expected 401, got %d", name, code)
+		}
+	}
+
+	// Verifiers reading another header are signed with SignRequestHeader
+	custom := VerifySignature(SignatureConfig{
+		Header: "X-Hub-Signature",
+		Key:    func(ctx context.Context, keyID string) ([]byte, error) { return secret, nil },
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req = httptest.NewRequest(http.MethodPost, "/hooks", strings.NewReader(`{}`))
+	if err := SignRequestHeader(req, "X-Hub-Signature", "", secret); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	custom.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected a request signed in a custom header to pass, got %d", rec.Code)
+	}
+
+	// Other schemes plug in their canonical string, such as Stripe's
+	stripe := VerifySignature(SignatureConfig{
+		Header: "Stripe-Signature",
+		Key:    func(ctx context.Context, keyID string) ([]byte, error) { return secret, nil },
+		Canonical: func(r *http.Request, timestamp string, body []byte) string {
+			return timestamp + "." + string(body)
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ts + `.{"type":"charge.succeeded"}`))
+	req = httptest.NewRequest(http.MethodPost, "/stripe", strings.NewReader(`{"type":"charge.succeeded"}`))
+	req.Header.Set("Stripe-Signature", "t="+ts+",v1="+hex.EncodeToString(mac.Sum(nil)))
+	rec = httptest.NewRecorder()
+	stripe.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected a Stripe-style signature to pass, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestEarlyHints(t *testing.T) {
+	if got := PreloadLink("/static/app.js?v=2"); got != "</static/app.js?v=2>; rel=preload; as=script" {
+		t.Errorf("unexpected link %q", got)
+	}
+	if got := PreloadLink("<https://cdn.example.com>; rel=preconnect"); got != "<https://cdn.example.com>; rel=preconnect" {
+		t.Errorf("expected Link values to be kept, got %q", got)
+	}
+
+	// Interim responses must not be taken for the final status
+	var status int
+	handler := LoggerWithConfig(LoggerConfig{Output: func(v LogValues) { status = v.Status }})(
+		EarlyHints("/app.css")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		})))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.ProtoMajor = 2
+	req.Header.Set("Accept", "text/html")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if status != http.StatusCreated {
+		t.Errorf("expected logged status 201, got %d", status)
+	}
+}
+
+func TestETag(t *testing.T) {
+	mw := ETag()
+
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":"test"}`))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Error("expected ETag header")
+	}
+	if !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) {
+		t.Errorf("ETag should be quoted, got '%s'", etag)
+	}
+}
+
+func TestETagNotModified(t *testing.T) {
+	mw := ETag()
+
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`{"data":"test"}`))
 	}))
 
@@ -812,6 +1800,29 @@ func TestETagSkipNonGET(t *testing.T) {
 	}
 }
 
+func TestETagResponseController(t *testing.T) {
+	deadline := time.Now().Add(time.Minute)
+	handler := ETag()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+		if err := rc.SetWriteDeadline(deadline); err != nil {
+			t.Errorf("expected the write deadline to reach the connection, got %v", err)
+		}
+		w.Write([]byte(`{"data":"test"}`))
+		if err := rc.Flush(); !errors.Is(err, http.ErrNotSupported) {
+			t.Errorf("expected flushing a buffered response to be unsupported, got %v", err)
+		}
+	}))
+
+	rec := &deadlineRecorder{ResponseRecorder: httptest.NewRecorder()}
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !rec.deadline.Equal(deadline) {
+		t.Errorf("expected deadline %v, got %v", deadline, rec.deadline)
+	}
+	if rec.Header().Get("ETag") == "" || rec.Body.String() != `{"data":"test"}` {
+		t.Errorf("expected the buffered response with an ETag, got %q %q", rec.Header().Get("ETag"), rec.Body.String())
+	}
+}
+
 func TestETagHelpers(t *testing.T) {
 	// Test ETagFromContent
 	etag := ETagFromContent([]byte("test"), false)
@@ -987,6 +1998,570 @@ func TestCacheHelpers(t *testing.T) {
 	}
 }
 
+func TestContextLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logs.New(logs.WithOutput(&buf), logs.WithFormatter(&logs.JSONFormatter{}))
+
+	handler := Chain(RequestID(), ContextLogger(logger))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The router normally records the matched pattern
+		SetRoutePattern(r, "/users/{id}")
+		logs.FromContext(r.Context()).Info("loading user")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set(RequestIDHeader, "req-123")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to decode log line %q: %v", buf.String(), err)
+	}
+
+	expected := map[string]string{
+		"msg":        "loading user",
+		"request_id": "req-123",
+		"method":     "GET",
+		"path":       "/users/42",
+		"route":      "/users/{id}",
+	}
+	for k, v := range expected {
+		if entry[k] != v {
+			t.Errorf("expected %s %q, got %v", k, v, entry[k])
+		}
+	}
+}
+
+func TestContextLogger_RoutePattern(t *testing.T) {
+	var got string
+	handler := ContextLogger(logs.New(logs.WithOutput(io.Discard)))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetRoutePattern(r, "/items/{id}")
+		got = RoutePattern(r)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/1", nil))
+
+	if got != "/items/{id}" {
+		t.Errorf("expected route pattern, got %q", got)
+	}
+
+	// Without a tracking middleware there is nothing to record
+	req := httptest.NewRequest(http.MethodGet, "/items/1", nil)
+	SetRoutePattern(req, "/items/{id}")
+	if RoutePattern(req) != "" {
+		t.Error("expected empty route pattern without tracking middleware")
+	}
+}
+
+func TestLoggerRedaction(t *testing.T) {
+	var got LogValues
+	mw := LoggerWithConfig(LoggerConfig{
+		Output:         func(v LogValues) { got = v },
+		LogHeaders:     []string{"Authorization", "Accept"},
+		LogQueryParams: []string{"access_token", "page"},
+		Fields:         map[string]string{"auth": "header:Authorization"},
+		CaptureBody:    true,
+		CustomTokens: map[string]TokenExtractor{
+			"body":     func(r *http.Request, body []byte) string { return string(body) },
+			"password": JSONBodyExtractor("password"),
+		},
+	})
+
+	var handlerBody []byte
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerBody, _ = io.ReadAll(r.Body)
+	}))
+
+	body := `{"user":"ann","password":"hunter2"}`
+	req := httptest.NewRequest(http.MethodPost, "/login?access_token=abc&page=2", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer abc")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	const mask = "[REDACTED]"
+	if got.Headers["Authorization"] != mask || got.Headers["Accept"] != "application/json" {
+		t.Errorf("unexpected headers: %v", got.Headers)
+	}
+	if got.QueryParams["access_token"] != mask || got.QueryParams["page"] != "2" {
+		t.Errorf("unexpected query params: %v", got.QueryParams)
+	}
+	if strings.Contains(got.URI, "abc") {
+		t.Errorf("expected token to be masked in URI, got %q", got.URI)
+	}
+	if got.CustomFields["auth"] != mask || got.CustomFields["password"] != mask {
+		t.Errorf("unexpected custom fields: %v", got.CustomFields)
+	}
+	if strings.Contains(got.CustomFields["body"], "hunter2") {
+		t.Errorf("expected password to be masked in body, got %q", got.CustomFields["body"])
+	}
+	if string(handlerBody) != body {
+		t.Errorf("expected handler to receive the original body, got %q", handlerBody)
+	}
+}
+
+func TestLoggerSampling(t *testing.T) {
+	var logged []string
+	mw := LoggerWithConfig(LoggerConfig{
+		Output:            func(v LogValues) { logged = append(logged, fmt.Sprintf("%d %s", v.Status, v.Route)) },
+		SuccessSampleRate: 0.000001,
+		RouteSampleRates:  map[string]float64{"POST /orders": 1, "/healthz": 0},
+	})
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetRoutePattern(r, r.URL.Path)
+		if r.URL.Query().Has("fail") {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+
+	for _, target := range []string{"/users", "/healthz", "/healthz?fail", "/orders", "/users?fail"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/orders", nil))
+
+	want := "502 /healthz,502 /users,200 /orders"
+	if got := strings.Join(logged, ","); got != want {
+		t.Errorf("expected %q to be logged, got %q", want, got)
+	}
+}
+
+func TestLoggerTimestamps(t *testing.T) {
+	start := time.Date(2000, time.October, 10, 20, 55, 36, 0, time.UTC)
+	v := LogValues{Method: http.MethodGet, Path: "/", URI: "/", Status: 200, StartTime: start}
+
+	var buf bytes.Buffer
+	TextOutputWithOptions(&buf, LogFormatJSON, TextOutputOptions{TimeField: "@timestamp", TimeFormat: TimeFormatUnixMilli})(v)
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := entry["@timestamp"].(float64); !ok || int64(got) != start.UnixMilli() {
+		t.Errorf("expected @timestamp %d, got %v", start.UnixMilli(), entry["@timestamp"])
+	}
+	if _, ok := entry["timestamp"]; ok {
+		t.Error("expected no timestamp field")
+	}
+
+	buf.Reset()
+	TextOutputWithOptions(&buf, LogFormatCommon, TextOutputOptions{DisableColors: true})(v)
+	if !strings.Contains(buf.String(), "[10/Oct/2000:20:55:36 +0000]") {
+		t.Errorf("expected a CLF timestamp, got %q", buf.String())
+	}
+
+	var got LogValues
+	zone := time.FixedZone("UTC-7", -7*60*60)
+	for _, tz := range []*time.Location{nil, zone} {
+		mw := LoggerWithConfig(LoggerConfig{Output: func(v LogValues) { got = v }, TimeZone: tz})
+		mw(http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		want := time.UTC
+		if tz != nil {
+			want = tz
+		}
+		if got.StartTime.Location() != want {
+			t.Errorf("expected start time in %v, got %v", want, got.StartTime.Location())
+		}
+	}
+}
+
+func TestLoggerCaptureResponseBody(t *testing.T) {
+	var got LogValues
+	mw := LoggerWithConfig(LoggerConfig{
+		Output:              func(v LogValues) { got = v },
+		CaptureResponseBody: true,
+		MaxResponseBodySize: 64,
+		ResponseTokens:      map[string]TokenExtractor{"code": JSONBodyExtractor("error.code")},
+	})
+
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", r.URL.Query().Get("type"))
+		w.WriteHeader(http.StatusBadGateway)
+		io.WriteString(w, `{"error":{"code":"upstream_timeout"},"token":"s3cret"}`)
+		io.WriteString(w, strings.Repeat(" ", 100))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?type=application/json", nil))
+
+	if got.CustomFields["code"] != "upstream_timeout" {
+		t.Errorf("expected the response token, got %v", got.CustomFields)
+	}
+	if len(got.ResponseBody) == 0 || strings.Contains(string(got.ResponseBody), "s3cret") {
+		t.Errorf("expected a redacted response body, got %q", got.ResponseBody)
+	}
+	if rec.Body.Len() != 154 {
+		t.Errorf("expected the full body to be sent, got %d bytes", rec.Body.Len())
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/?type=image/png", nil))
+	if got.ResponseBody != nil {
+		t.Errorf("expected binary bodies to be skipped, got %q", got.ResponseBody)
+	}
+}
+
+func TestLoggerTo(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logs.New(logs.WithOutput(&buf), logs.WithFormatter(&logs.JSONFormatter{}))
+
+	handler := Chain(RequestID(), LoggerTo(logger))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetRoutePattern(r, "/status/{code}")
+		switch r.URL.Path {
+		case "/status/404":
+			w.WriteHeader(http.StatusNotFound)
+		case "/status/500":
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	tests := []struct {
+		path   string
+		status float64
+		level  string
+	}{
+		{"/status/200", 200, "info"},
+		{"/status/404", 404, "warn"},
+		{"/status/500", 500, "error"},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		var entry map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatalf("%s: failed to decode log line %q: %v", tt.path, buf.String(), err)
+		}
+		if entry["level"] != tt.level || entry["status"] != tt.status {
+			t.Errorf("%s: expected %s %v, got %v %v", tt.path, tt.level, tt.status, entry["level"], entry["status"])
+		}
+		if entry["msg"] != "http request" || entry["method"] != "GET" || entry["path"] != tt.path {
+			t.Errorf("%s: unexpected entry %v", tt.path, entry)
+		}
+		if entry["route"] != "/status/{code}" || entry["request_id"] == nil || entry["size"] != float64(2) {
+			t.Errorf("%s: missing request fields in %v", tt.path, entry)
+		}
+	}
+}
+
+func TestSlowRequest(t *testing.T) {
+	counter := NewSlowRequestCounter()
+	var got []SlowRequestInfo
+	handler := SlowRequestWithConfig(SlowRequestConfig{
+		Threshold: time.Hour,
+		Routes: map[string]time.Duration{
+			"/reports/{id}":      time.Nanosecond,
+			"POST /reports/{id}": time.Hour,
+		},
+		OnSlow: func(r *http.Request, info SlowRequestInfo) {
+			got = append(got, info)
+			counter.Record(r, info)
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			SetRoutePattern(r, "/reports/{id}")
+		}
+		time.Sleep(time.Millisecond)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/reports/1", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/reports/2", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/reports/3", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if len(got) != 2 {
+		t.Fatalf("expected 2 slow requests, got %d: %+v", len(got), got)
+	}
+	if got[0].Route != "/reports/{id}" || got[0].Status != http.StatusAccepted || got[0].Threshold != time.Nanosecond {
+		t.Errorf("unexpected slow request info %+v", got[0])
+	}
+	if counts := counter.Counts(); counts["GET /reports/{id}"] != 2 || len(counts) != 1 {
+		t.Errorf("unexpected counts %v", counts)
+	}
+
+	// Unrouted requests share one key, whatever their path
+	for _, path := range []string{"/wp-admin", "/.env", "/xmlrpc.php"} {
+		counter.Record(nil, SlowRequestInfo{Method: http.MethodGet, Path: path})
+	}
+	if counts := counter.Counts(); counts["GET <unmatched>"] != 3 || len(counts) != 2 {
+		t.Errorf("expected unrouted requests under one key, got %v", counts)
+	}
+}
+
+func TestSlowRequest_DefaultLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logs.New(logs.WithOutput(&buf), logs.WithFormatter(&logs.JSONFormatter{}))
+
+	handler := Chain(ContextLogger(logger), SlowRequest(time.Nanosecond, nil))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetRoutePattern(r, "/slow")
+		time.Sleep(time.Millisecond)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to decode log line %q: %v", buf.String(), err)
+	}
+	if entry["msg"] != "slow request" || entry["level"] != "warn" || entry["route"] != "/slow" {
+		t.Errorf("unexpected entry %v", entry)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var got http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer upstream.Close()
+
+	client := &http.Client{Transport: Transport(nil)}
+	handler := RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, upstream.URL, nil)
+		req.Header.Set("Tracestate", "vendor=override")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "req-123")
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("tracestate", "vendor=value")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got.Get(RequestIDHeader) != "req-123" {
+		t.Errorf("expected request ID to propagate, got %q", got.Get(RequestIDHeader))
+	}
+	if got.Get("traceparent") != req.Header.Get("traceparent") {
+		t.Errorf("expected traceparent to propagate, got %q", got.Get("traceparent"))
+	}
+	if got.Get("tracestate") != "vendor=override" {
+		t.Errorf("expected explicit header to be kept, got %q", got.Get("tracestate"))
+	}
+
+	// Without RequestID in the context nothing is added
+	got = nil
+	resp, err := client.Get(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got.Get(RequestIDHeader) != "" {
+		t.Errorf("expected no request ID, got %q", got.Get(RequestIDHeader))
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	policy := RolePolicy{
+		"admin":  {"*"},
+		"editor": {"articles:*"},
+	}
+	handler := Authorization(policy)(RequirePermission("articles:write")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	serve := func(p *Principal) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/articles", nil)
+		if p != nil {
+			req = req.WithContext(WithPrincipal(req.Context(), p))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, p := range []*Principal{
+		{ID: "a", Roles: []string{"admin"}},
+		{ID: "e", Roles: []string{"viewer", "editor"}},
+		{ID: "s", Scopes: []string{"articles:write"}},
+	} {
+		if rec := serve(p); rec.Code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d", p.ID, rec.Code)
+		}
+	}
+
+	if rec := serve(nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a principal, got %d", rec.Code)
+	}
+
+	rec := serve(&Principal{ID: "v", Roles: []string{"viewer"}})
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("expected problem content type, got %q", ct)
+	}
+	var problem struct {
+		Type   string `json:"type"`
+		Detail string `json:"detail"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatal(err)
+	}
+	if problem.Type != "about:blank#forbidden" || problem.Detail != `missing permission "articles:write"` {
+		t.Errorf("unexpected problem %+v", problem)
+	}
+
+	// Policy errors fail the request instead of denying it
+	failing := Authorization(PolicyFunc(func(context.Context, *Principal, string) (bool, error) {
+		return false, errors.New("policy store down")
+	}))(RequirePermission("articles:write")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/articles", nil)
+	failing.ServeHTTP(rec, req.WithContext(WithPrincipal(req.Context(), &Principal{ID: "a"})))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500 on policy error, got %d", rec.Code)
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	var got *Principal
+	handler := BasicAuthUsers(map[string]string{"alice": "secret"})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = PrincipalFrom(r.Context())
+		}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth("alice", "secret")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if got == nil || got.ID != "alice" {
+		t.Fatalf("expected BasicAuth to set the principal, got %+v", got)
+	}
+
+	guarded := RequireRole("admin", "owner")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, tc := range []struct {
+		roles []string
+		want  int
+	}{
+		{[]string{"owner"}, http.StatusOK},
+		{[]string{"viewer"}, http.StatusForbidden},
+		{nil, http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		guarded.ServeHTTP(rec, req.WithContext(WithPrincipal(req.Context(), &Principal{ID: "u", Roles: tc.roles})))
+		if rec.Code != tc.want {
+			t.Errorf("roles %v: expected %d, got %d", tc.roles, tc.want, rec.Code)
+		}
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	var budget time.Duration
+	handler := Deadline(10 * time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, ok := r.Context().Deadline()
+		if !ok {
+			t.Fatal("expected a context deadline")
+		}
+		budget = time.Until(deadline).Round(time.Second)
+	}))
+
+	for _, tc := range []struct {
+		header, value string
+		want          time.Duration
+	}{
+		{"", "", 10 * time.Second},
+		{RequestTimeoutHeader, "2s", 2 * time.Second},
+		{RequestTimeoutHeader, "3000", 3 * time.Second},
+		{RequestTimeoutHeader, "1h", 10 * time.Second},
+		{RequestTimeoutHeader, "soon", 10 * time.Second},
+		{"grpc-timeout", "4S", 4 * time.Second},
+		{"grpc-timeout", "5000m", 5 * time.Second},
+		{"grpc-timeout", "5x", 10 * time.Second},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tc.header != "" {
+			req.Header.Set(tc.header, tc.value)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		if budget != tc.want {
+			t.Errorf("%s %q: expected budget %v, got %v", tc.header, tc.value, tc.want, budget)
+		}
+	}
+
+	// Transport passes the remaining budget on
+	var got string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(RequestTimeoutHeader)
+	}))
+	defer upstream.Close()
+
+	client := &http.Client{Transport: Transport(nil)}
+	Deadline(time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, upstream.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	ms, err := strconv.Atoi(got)
+	if err != nil || ms <= 50_000 || ms > 60_000 {
+		t.Errorf("expected remaining budget in ms, got %q", got)
+	}
+}
+
+func TestAllowedQueryParams(t *testing.T) {
+	handler := AllowedQueryParamsWithConfig(AllowedQueryParamsConfig{
+		Allowed:         []string{"page", "limit", "sort"},
+		AllowedPrefixes: []string{"filter["},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, target := range []string{"/users", "/users?page=2&limit=10", "/users?filter[status]=active&sort=name"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d", target, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users?pgae=2&limit=10&debug=1", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("expected problem content type, got %q", ct)
+	}
+
+	var problem struct {
+		Status int    `json:"status"`
+		Detail string `json:"detail"`
+		Errors []struct {
+			Field string `json:"field"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatal(err)
+	}
+	if problem.Detail != "unknown query parameters: debug, pgae" {
+		t.Errorf("unexpected detail %q", problem.Detail)
+	}
+	if len(problem.Errors) != 2 || problem.Errors[0].Field != "debug" || problem.Errors[1].Field != "pgae" {
+		t.Errorf("unexpected errors %+v", problem.Errors)
+	}
+}
+
+func TestAllowedQueryParams_Handler(t *testing.T) {
+	var got []string
+	handler := AllowedQueryParamsWithConfig(AllowedQueryParamsConfig{
+		Allowed: []string{"q"},
+		Handler: func(w http.ResponseWriter, r *http.Request, unknown []string) {
+			got = unknown
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?q=a&x=1", nil))
+	if rec.Code != http.StatusUnprocessableEntity || len(got) != 1 || got[0] != "x" {
+		t.Errorf("expected custom handler with [x], got %d %v", rec.Code, got)
+	}
+}
+
 func BenchmarkRecoverMiddleware(b *testing.B) {
 	mw := Recover()
 	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -1091,3 +2666,599 @@ func BenchmarkCacheMiddleware(b *testing.B) {
 		handler.ServeHTTP(rec, req)
 	}
 }
+
+func TestRequireContentType(t *testing.T) {
+	handler := RequireContentType("application/json", "image/*")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		want        int
+	}{
+		{"json", `{}`, "application/json; charset=utf-8", http.StatusOK},
+		{"wildcard", "png", "image/png", http.StatusOK},
+		{"no body", "", "", http.StatusOK},
+		{"form", "a=1", "application/x-www-form-urlencoded", http.StatusUnsupportedMediaType},
+		{"missing", `{}`, "", http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, rec.Code)
+			}
+			if tt.want != http.StatusUnsupportedMediaType {
+				return
+			}
+			if got := rec.Header().Get("Accept"); got != "application/json, image/*" {
+				t.Errorf("expected the supported types in Accept, got %q", got)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+				t.Errorf("expected problem content type, got %q", ct)
+			}
+		})
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	handler := RealIP()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Remote", r.RemoteAddr)
+		w.Header().Set("X-Forwarded", r.Header.Get("X-Forwarded-For"))
+	}))
+
+	for _, tc := range []struct {
+		remote, forwarded, realIP, want string
+	}{
+		{"10.0.0.1:80", "203.0.113.7, 10.0.0.2", "", "203.0.113.7:80"},
+		{"10.0.0.1:80", "6.6.6.6, 203.0.113.7", "", "203.0.113.7:80"},
+		{"10.0.0.1:80", "", "203.0.113.8", "203.0.113.8:80"},
+		{"198.51.100.1:80", "203.0.113.7", "", "198.51.100.1:80"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tc.remote
+		if tc.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", tc.forwarded)
+		}
+		if tc.realIP != "" {
+			req.Header.Set("X-Real-IP", tc.realIP)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get("X-Remote"); got != tc.want {
+			t.Errorf("%s via %q: expected %s, got %s", tc.remote, tc.forwarded, tc.want, got)
+		}
+		if rec.Header().Get("X-Forwarded") != "" {
+			t.Error("expected X-Forwarded-For to be removed")
+		}
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	handler := BodyLimit(4)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		}
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("12345")))
+	var p struct{ Type string }
+	json.NewDecoder(rec.Body).Decode(&p)
+	if rec.Code != http.StatusRequestEntityTooLarge || p.Type != "about:blank#payload_too_large" {
+		t.Errorf("expected a 413 problem, got %d %q", rec.Code, p.Type)
+	}
+
+	// Bodies without a declared length are cut off while reading
+	req := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader("12345")))
+	req.ContentLength = -1
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected reading past the limit to fail, got %d", rec.Code)
+	}
+}
+
+func TestSecureHeaders(t *testing.T) {
+	handler := SecureHeadersWithConfig(SecureHeadersConfig{FrameOptions: "-", ContentSecurityPolicy: "default-src 'self'"})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	h := rec.Header()
+	if h.Get("X-Content-Type-Options") != "nosniff" || h.Get("Content-Security-Policy") != "default-src 'self'" {
+		t.Errorf("unexpected headers %v", h)
+	}
+	if _, ok := h["X-Frame-Options"]; ok {
+		t.Error("expected X-Frame-Options to be omitted")
+	}
+	if h.Get("Strict-Transport-Security") != "max-age=31536000" {
+		t.Errorf("unexpected HSTS %q", h.Get("Strict-Transport-Security"))
+	}
+}
+
+func TestCSRF(t *testing.T) {
+	handler := CSRFWithConfig(CSRFConfig{TrustedOrigins: []string{"https://app.example.com"}})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, tc := range []struct {
+		method, fetchSite, origin string
+		want                      int
+	}{
+		{http.MethodGet, "cross-site", "https://evil.example", http.StatusOK},
+		{http.MethodPost, "cross-site", "https://evil.example", http.StatusForbidden},
+		{http.MethodPost, "same-origin", "http://example.com", http.StatusOK},
+		{http.MethodPost, "", "https://evil.example", http.StatusForbidden},
+		{http.MethodPost, "", "http://example.com", http.StatusOK},
+		{http.MethodPost, "", "", http.StatusOK},
+		{http.MethodDelete, "cross-site", "https://app.example.com", http.StatusOK},
+	} {
+		req := httptest.NewRequest(tc.method, "http://example.com/", nil)
+		if tc.fetchSite != "" {
+			req.Header.Set("Sec-Fetch-Site", tc.fetchSite)
+		}
+		if tc.origin != "" {
+			req.Header.Set("Origin", tc.origin)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s %q from %q: expected %d, got %d", tc.method, tc.fetchSite, tc.origin, tc.want, rec.Code)
+		}
+	}
+}
+
+func TestParseUserAgent(t *testing.T) {
+	tests := []struct {
+		ua   string
+		want UserAgent
+	}{
+		{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36 Edg/126.0.2592.87",
+			UserAgent{Browser: "Edge", BrowserVersion: "126.0.2592.87", OS: "Windows", Device: "desktop"},
+		},
+		{
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+			UserAgent{Browser: "Safari", BrowserVersion: "17.5", OS: "iOS", Device: "mobile"},
+		},
+		{
+			"Mozilla/5.0 (Linux; Android 14; SM-X710) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36",
+			UserAgent{Browser: "Chrome", BrowserVersion: "126.0.0.0", OS: "Android", Device: "tablet"},
+		},
+		{
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 14.5; rv:127.0) Gecko/20100101 Firefox/127.0",
+			UserAgent{Browser: "Firefox", BrowserVersion: "127.0", OS: "macOS", Device: "desktop"},
+		},
+		{
+			"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			UserAgent{Device: "bot", Bot: true, BotName: "Googlebot", SearchEngine: true},
+		},
+		{"curl/8.7.1", UserAgent{Device: "bot", Bot: true, BotName: "curl"}},
+		{"Mozilla/5.0 (compatible; ExampleCrawler/1.0)", UserAgent{Device: "bot", Bot: true, BotName: "ExampleCrawler"}},
+		{"", UserAgent{Device: "bot", Bot: true}},
+		// Non-ASCII and invalid UTF-8, whose lowercase differs in length
+		{"\xff\xff\xffmybot", UserAgent{Device: "bot", Bot: true, BotName: "\xff\xff\xffmybot"}},
+		{"\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff Chrome/", UserAgent{Browser: "Chrome", Device: "desktop"}},
+		{"İİİİ ExampleBot/1.0", UserAgent{Device: "bot", Bot: true, BotName: "ExampleBot"}},
+		{"Mozilla/5.0 (ȺȺȺȺ) Firefox/127.0", UserAgent{Browser: "Firefox", BrowserVersion: "127.0", Device: "desktop"}},
+	}
+
+	for _, tt := range tests {
+		if got := ParseUserAgent(tt.ua); got != tt.want {
+			t.Errorf("ParseUserAgent(%q) = %+v, want %+v", tt.ua, got, tt.want)
+		}
+	}
+}
+
+func TestBlockBots(t *testing.T) {
+	var logged LogValues
+	handler := LoggerWithConfig(LoggerConfig{Output: func(v LogValues) { logged = v }, ParseUserAgent: true})(
+		BlockBots(BotPolicy{AllowSearchEngines: true, Allow: []string{"slackbot"}})(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		),
+	)
+
+	tests := []struct {
+		ua     string
+		status int
+	}{
+		{"Mozilla/5.0 (X11; Linux x86_64; rv:127.0) Gecko/20100101 Firefox/127.0", http.StatusOK},
+		{"Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)", http.StatusOK},
+		{"Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)", http.StatusOK},
+		{"python-requests/2.32.3", http.StatusForbidden},
+		{"", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("User-Agent", tt.ua)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%q: expected %d, got %d", tt.ua, tt.status, rec.Code)
+		}
+	}
+
+	if logged.Client == nil || logged.Client.Device != "bot" {
+		t.Errorf("expected the logged client to be a bot, got %+v", logged.Client)
+	}
+}
+
+func TestIPFilter(t *testing.T) {
+	deny := NewIPList("198.51.100.0/24")
+	deny.Add(netip.MustParseAddr("203.0.113.7"), 0)
+	deny.Add(netip.MustParseAddr("203.0.113.8"), time.Hour)
+	deny.Add(netip.MustParseAddr("203.0.113.9"), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	handler := IPFilter(deny)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	tests := map[string]int{
+		"198.51.100.20:1234":     http.StatusForbidden,
+		"203.0.113.7:1234":       http.StatusForbidden,
+		"203.0.113.8:1234":       http.StatusForbidden,
+		"203.0.113.9:1234":       http.StatusOK, // expired
+		"[::ffff:203.0.113.7]:1": http.StatusForbidden,
+		"192.0.2.1:1234":         http.StatusOK,
+	}
+	for addr, want := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("%s: expected %d, got %d", addr, want, rec.Code)
+		}
+	}
+
+	deny.Remove(netip.MustParseAddr("203.0.113.7"))
+	if deny.Contains(netip.MustParseAddr("203.0.113.7")) {
+		t.Error("expected the removed address not to be denied")
+	}
+}
+
+func TestTarpit(t *testing.T) {
+	banned := NewIPList()
+	var trapped []string
+	handler := IPFilter(banned)(TarpitWithConfig(TarpitConfig{
+		Delay:    20 * time.Millisecond,
+		Denylist: banned,
+		OnTrap:   func(r *http.Request) { trapped = append(trapped, r.URL.Path) },
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	serve := func(path string) (int, time.Duration) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		start := time.Now()
+		handler.ServeHTTP(rec, req)
+		return rec.Code, time.Since(start)
+	}
+
+	if code, _ := serve("/wp-login.phpx"); code != http.StatusOK {
+		t.Errorf("expected /wp-login.phpx to pass, got %d", code)
+	}
+	code, elapsed := serve("/.env.production")
+	if code != http.StatusNotFound || elapsed < 20*time.Millisecond {
+		t.Errorf("expected a delayed 404, got %d after %v", code, elapsed)
+	}
+	if code, _ := serve("/"); code != http.StatusForbidden {
+		t.Errorf("expected the trapped client to be banned, got %d", code)
+	}
+	if !slices.Equal(trapped, []string{"/.env.production"}) {
+		t.Errorf("expected one trapped request, got %v", trapped)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	var got string
+	handler := Rewrite(map[string]string{
+		"/api/v1/{path...}": "/api/{path...}",
+		"/api/v1/legacy":    "/legacy",
+		"/u/{name}":         "/users/{name}?view=profile",
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.RequestURI()
+	}))
+
+	tests := map[string]string{
+		"/api/v1/orders/7": "/api/orders/7",
+		"/api/v1/legacy":   "/legacy",
+		"/u/ada?tab=posts": "/users/ada?view=profile&tab=posts",
+		"/u/":              "/u/",
+		"/other":           "/other",
+	}
+	for target, want := range tests {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+		if got != want {
+			t.Errorf("%s: expected %s, got %s", target, want, got)
+		}
+	}
+}
+
+func TestRedirectRules(t *testing.T) {
+	rules, err := ParseRedirectRules(strings.NewReader(`
+# legacy blog
+/blog/{year}/{slug}  /posts/{slug}
+/docs/{path...}      https://docs.example.com/{path...}  302
+/go/{path...}        /{path...}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := RedirectRules(rules...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		target, location string
+		status           int
+	}{
+		{"/blog/2019/hello?ref=rss", "/posts/hello?ref=rss", http.StatusMovedPermanently},
+		{"/docs/guide/intro", "https://docs.example.com/guide/intro", http.StatusFound},
+		{"/go//evil.example", "/evil.example", http.StatusMovedPermanently},
+		{"/blog/2019", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if rec.Code != tt.status || rec.Header().Get("Location") != tt.location {
+			t.Errorf("%s: expected %d %q, got %d %q", tt.target, tt.status, tt.location, rec.Code, rec.Header().Get("Location"))
+		}
+	}
+
+	for _, bad := range []string{"/a /b 200", "/a/{id} /b/{slug}", "/a/{path...}/b /c", "/a"} {
+		if _, err := ParseRedirectRules(strings.NewReader(bad)); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	handler := Canonical(CanonicalConfig{HTTPS: true, WWW: "strip"})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		method, url, proto, location string
+		status                       int
+	}{
+		{http.MethodGet, "http://www.example.com/a?b=1", "", "https://example.com/a?b=1", http.StatusMovedPermanently},
+		{http.MethodPost, "http://example.com/form", "", "https://example.com/form", http.StatusPermanentRedirect},
+		{http.MethodGet, "http://example.com/", "https", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.url, nil)
+		if tt.proto != "" {
+			req.Header.Set("X-Forwarded-Proto", tt.proto)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.status || rec.Header().Get("Location") != tt.location {
+			t.Errorf("%s %s: expected %d %q, got %d %q", tt.method, tt.url, tt.status, tt.location, rec.Code, rec.Header().Get("Location"))
+		}
+	}
+}
+
+func TestAddVary(t *testing.T) {
+	h := http.Header{}
+	h.Add("Vary", "Accept")
+	h.Add("Vary", "accept-encoding, Origin")
+	AddVary(h, "Origin", "Accept-Encoding", "Accept-Language")
+	if got := h.Values("Vary"); len(got) != 1 || got[0] != "Accept, accept-encoding, Origin, Accept-Language" {
+		t.Errorf("unexpected Vary %q", got)
+	}
+
+	h = http.Header{"Vary": {"*"}}
+	AddVary(h, "Origin")
+	if got := h.Get("Vary"); got != "*" {
+		t.Errorf("expected Vary * to be kept, got %q", got)
+	}
+}
+
+// TestVaryCachePoisoning checks that responses which depend on request
+// headers say so, so a shared cache cannot serve one client's response to
+// another.
+func TestVaryCachePoisoning(t *testing.T) {
+	body := strings.Repeat("compressible ", 200)
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Add("Vary", "Accept-Language")
+		io.WriteString(w, body)
+	})
+	handler := CORSWithConfig(CORSConfig{AllowOrigins: []string{"https://app.example.com"}})(
+		Compress()(app),
+	)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		method  string
+		want    []string
+	}{
+		// A cached copy without ACAO must not be served to CORS requests
+		{"no origin", nil, http.MethodGet, []string{"Origin", "Accept-Language", "Accept-Encoding"}},
+		{"disallowed origin", map[string]string{"Origin": "https://evil.example"}, http.MethodGet, []string{"Origin"}},
+		// The uncompressed copy must not be served to gzip clients, nor
+		// the gzip one to clients that cannot decode it
+		{"gzip", map[string]string{"Origin": "https://app.example.com", "Accept-Encoding": "gzip"}, http.MethodGet, []string{"Origin", "Accept-Language", "Accept-Encoding"}},
+		{"preflight", map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "PUT"}, http.MethodOptions,
+			[]string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/", nil)
+		for k, v := range tt.headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		vary := strings.Join(rec.Header().Values("Vary"), ", ")
+		for _, name := range tt.want {
+			if strings.Count(vary, name) != 1 {
+				t.Errorf("%s: expected Vary to list %s once, got %q", tt.name, name, vary)
+			}
+		}
+	}
+}
+
+// fakeTx records how a transaction ended.
+type fakeTx struct {
+	ended     string
+	commitErr error
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.ended = "commit"
+	return tx.commitErr
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.ended = "rollback"
+	return nil
+}
+
+func TestTx(t *testing.T) {
+	run := func(tx *fakeTx, handler http.HandlerFunc) *httptest.ResponseRecorder {
+		db := TxBeginnerFunc(func(context.Context) (Transaction, error) { return tx, nil })
+		rec := httptest.NewRecorder()
+		Tx(db)(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+		return rec
+	}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		ended   string
+		status  int
+	}{
+		{"created", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusCreated) }, "commit", http.StatusCreated},
+		{"write", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) }, "commit", http.StatusOK},
+		{"no response", func(w http.ResponseWriter, r *http.Request) {}, "commit", http.StatusOK},
+		{"error", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusConflict) }, "rollback", http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := &fakeTx{}
+			rec := run(tx, func(w http.ResponseWriter, r *http.Request) {
+				if TxFrom(r.Context()) != tx {
+					t.Error("expected transaction in context")
+				}
+				tt.handler(w, r)
+			})
+			if tx.ended != tt.ended || rec.Code != tt.status {
+				t.Fatalf("expected %s with %d, got %q with %d", tt.ended, tt.status, tx.ended, rec.Code)
+			}
+		})
+	}
+
+	t.Run("panic", func(t *testing.T) {
+		tx := &fakeTx{}
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected panic to propagate")
+			}
+			if tx.ended != "rollback" {
+				t.Fatalf("expected rollback, got %q", tx.ended)
+			}
+		}()
+		run(tx, func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+	})
+
+	t.Run("commit failure", func(t *testing.T) {
+		tx := &fakeTx{commitErr: errors.New("serialization failure")}
+		rec := run(tx, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"id":1}`))
+		})
+		if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), `"id"`) {
+			t.Fatalf("expected 500 problem, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("begin failure", func(t *testing.T) {
+		db := TxBeginnerFunc(func(context.Context) (Transaction, error) { return nil, errors.New("pool exhausted") })
+		rec := httptest.NewRecorder()
+		Tx(db)(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatalf("expected 500, got %d", rec.Code)
+		}
+	})
+}
+
+func TestMirror(t *testing.T) {
+	mirrored := make(chan *http.Request, 1)
+	release := make(chan struct{})
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		mirrored <- r
+		<-release
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer shadow.Close()
+	defer close(release)
+
+	handler := Mirror(shadow.URL+"/shadow", 1)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(append([]byte("handled "), body...))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/orders?dry=1", strings.NewReader(`{"id":1}`))
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("Cookie", "session=secret")
+	req.Header.Set("Connection", "X-Hop")
+	req.Header.Set("X-Hop", "1")
+	req.Header.Set("X-Trace", "t1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != `handled {"id":1}` {
+		t.Fatalf("expected the original response, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	select {
+	case r := <-mirrored:
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || r.URL.Path != "/shadow/orders" || r.URL.RawQuery != "dry=1" || string(body) != `{"id":1}` {
+			t.Errorf("unexpected mirrored request %s %s %q", r.Method, r.URL, body)
+		}
+		if r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "" || r.Header.Get("X-Hop") != "" ||
+			r.Header.Get("X-Trace") != "t1" || r.Header.Get(MirrorHeader) != "1" {
+			t.Errorf("expected credentials and hop-by-hop headers to be removed, got %v", r.Header)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the request to be mirrored")
+	}
+
+	// Mirrored requests are not mirrored again
+	req = httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set(MirrorHeader, "1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	select {
+	case r := <-mirrored:
+		t.Errorf("expected no mirror of a mirrored request, got %s", r.URL)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Credentials are mirrored when asked for, minus the removed headers
+	handler = MirrorWithConfig(MirrorConfig{Target: shadow.URL, KeepCredentials: true, RemoveHeaders: []string{"X-Trace"}})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req = httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("X-Trace", "t1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	select {
+	case r := <-mirrored:
+		if r.Header.Get("Authorization") != "Bearer token" || r.Header.Get("X-Trace") != "" {
+			t.Errorf("unexpected mirrored headers %v", r.Header)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the request to be mirrored")
+	}
+}
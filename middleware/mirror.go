@@ -0,0 +1,213 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kolosys/helix/internal/reqbody"
+	"github.com/kolosys/helix/logs"
+)
+
+// MirrorHeader is set on mirrored requests, so the shadow environment can
+// tell them apart, such as to skip sending emails.
+const MirrorHeader = "X-Mirror"
+
+// mirrorHopHeaders are the hop-by-hop headers not copied onto mirrored
+// requests, besides those named by the Connection header.
+var mirrorHopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// mirrorCredentialHeaders are the headers carrying credentials, not copied
+// onto mirrored requests unless MirrorConfig.KeepCredentials is set.
+var mirrorCredentialHeaders = []string{
+	"Authorization", "Cookie", "X-API-Key", "X-Auth-Token",
+}
+
+// MirrorConfig configures the Mirror middleware.
+type MirrorConfig struct {
+	// Target is the base URL of the shadow environment, such as
+	// "https://shadow.internal". Request paths are appended to its path.
+	// Required.
+	Target string
+
+	// SampleRate is the fraction of the requests mirrored, from 0 to 1.
+	// Default: 1 (every request)
+	SampleRate float64
+
+	// Client sends the mirrored requests.
+	// Default: http.DefaultClient
+	Client *http.Client
+
+	// Timeout bounds each mirrored request.
+	// Default: 10 seconds
+	Timeout time.Duration
+
+	// MaxBodySize is the largest request body mirrored. Requests with
+	// larger bodies are served but not mirrored.
+	// Default: 1MB
+	MaxBodySize int64
+
+	// MaxInFlight is the most mirrored requests in flight at once. Further
+	// requests are not mirrored until some complete, so that a slow shadow
+	// environment cannot pile up goroutines.
+	// Default: 100
+	MaxInFlight int
+
+	// KeepCredentials copies the Authorization, Cookie, X-API-Key and
+	// X-Auth-Token headers onto mirrored requests. They are removed by
+	// default, so that the shadow environment cannot act as the users.
+	// Default: false
+	KeepCredentials bool
+
+	// RemoveHeaders are further headers removed from mirrored requests,
+	// such as custom credentials.
+	// Default: nil
+	RemoveHeaders []string
+
+	// SkipFunc determines if a request is not mirrored, such as for
+	// requests with side effects outside the shadow environment.
+	SkipFunc func(r *http.Request) bool
+
+	// OnError is called when a mirrored request fails. Its response status
+	// is not an error; the shadow environment is compared with its logs.
+	// If nil, the failure is logged as a warning.
+	OnError func(r *http.Request, err error)
+}
+
+// Mirror returns a middleware that copies a sampleRate fraction of the
+// requests to the shadow environment at targetURL. See MirrorWithConfig.
+//
+// Example:
+//
+//	s.Use(middleware.Mirror("https://shadow.internal", 0.05)) // 5% of the traffic
+func Mirror(targetURL string, sampleRate float64) Middleware {
+	return MirrorWithConfig(MirrorConfig{Target: targetURL, SampleRate: sampleRate})
+}
+
+// MirrorWithConfig returns a Mirror middleware with the given configuration.
+// Mirrored requests are sent in the background with the method, path,
+// query, headers and body of the original, plus the MirrorHeader; their
+// responses are discarded, and the original is served as if unmirrored.
+// Hop-by-hop and credential headers are not mirrored.
+// Upgrade requests, such as WebSockets, and requests that are mirrors
+// themselves are never mirrored.
+func MirrorWithConfig(config MirrorConfig) Middleware {
+	target, err := url.Parse(config.Target)
+	if err != nil || target.Scheme == "" || target.Host == "" {
+		panic("helix: Mirror target must be an absolute URL, got " + config.Target)
+	}
+	if config.SampleRate == 0 {
+		config.SampleRate = 1
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.MaxBodySize == 0 {
+		config.MaxBodySize = 1 << 20
+	}
+	if config.MaxInFlight == 0 {
+		config.MaxInFlight = 100
+	}
+	if config.OnError == nil {
+		config.OnError = func(r *http.Request, err error) {
+			logs.FromContext(r.Context()).Warn("helix: mirrored request failed", logs.Fields{
+				"method": r.Method,
+				"path":   r.URL.Path,
+				"error":  err,
+			})
+		}
+	}
+	removed := slices.Concat(mirrorHopHeaders, config.RemoveHeaders)
+	if !config.KeepCredentials {
+		removed = slices.Concat(removed, mirrorCredentialHeaders)
+	}
+	inFlight := make(chan struct{}, config.MaxInFlight)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if (config.SampleRate < 1 && rand.Float64() >= config.SampleRate) ||
+				r.Header.Get("Upgrade") != "" || r.Header.Get(MirrorHeader) != "" || r.Method == http.MethodConnect ||
+				(config.SkipFunc != nil && config.SkipFunc(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			select {
+			case inFlight <- struct{}{}:
+			default:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, ok := reqbody.Tee(r, config.MaxBodySize)
+			if !ok {
+				<-inFlight
+				next.ServeHTTP(w, r)
+				return
+			}
+			out, cancel, err := mirrorRequest(r, target, body, removed, config.Timeout)
+			if err != nil {
+				<-inFlight
+				config.OnError(r, err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			go func() {
+				defer func() { <-inFlight }()
+				defer cancel()
+				resp, err := config.Client.Do(out)
+				if err != nil {
+					config.OnError(out, err)
+					return
+				}
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// mirrorRequest returns a copy of r addressed to target, without the
+// headers removed and those named by its Connection header. Its context
+// keeps the values of r's, such as the logger, but not its cancellation, so
+// the copy outlives the response.
+func mirrorRequest(r *http.Request, target *url.URL, body []byte, removed []string, timeout time.Duration) (*http.Request, context.CancelFunc, error) {
+	u := *target
+	u.Path = strings.TrimSuffix(target.Path, "/") + r.URL.Path
+	u.RawPath = strings.TrimSuffix(target.EscapedPath(), "/") + r.URL.EscapedPath()
+	u.RawQuery = r.URL.RawQuery
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), timeout)
+	req, err := http.NewRequestWithContext(ctx, r.Method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	req.Header = r.Header.Clone()
+	for _, v := range r.Header.Values("Connection") {
+		for h := range strings.SplitSeq(v, ",") {
+			req.Header.Del(strings.TrimSpace(h))
+		}
+	}
+	for _, h := range removed {
+		req.Header.Del(h)
+	}
+	req.Header.Set(MirrorHeader, "1")
+	if body == nil {
+		req.Body, req.ContentLength = http.NoBody, 0
+	}
+	return req, cancel, nil
+}
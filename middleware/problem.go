@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// fieldError is a field entry of a problem response.
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// problem mirrors helix.Problem and helix.ValidationProblem, which this
+// package cannot import.
+type problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []fieldError `json:"errors,omitempty"`
+
+	// Extensions are extra members written alongside the standard ones.
+	Extensions map[string]any `json:"-"`
+}
+
+// MarshalJSON implements json.Marshaler, inlining the extension members.
+func (p problem) MarshalJSON() ([]byte, error) {
+	type plain problem
+	b, err := json.Marshal(plain(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return b, err
+	}
+	ext, err := json.Marshal(p.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	// Both are non-empty objects: join them as {standard,extensions}
+	return append(append(b[:len(b)-1], ','), ext[1:]...), nil
+}
+
+// newProblem creates a problem whose type and title are derived from status
+// the way helix derives them for its built-in errors.
+func newProblem(r *http.Request, status int, detail string) problem {
+	title := http.StatusText(status)
+	if status == http.StatusRequestEntityTooLarge {
+		title = "Payload Too Large"
+	}
+	slug := strings.ReplaceAll(strings.ToLower(title), " ", "_")
+	if status == http.StatusInternalServerError {
+		slug = "internal_error"
+	}
+	return problem{
+		Type:     "about:blank#" + slug,
+		Title:    title,
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.RequestURI(),
+	}
+}
+
+// write sends p as an RFC 7807 problem response.
+func (p problem) write(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// writeProblem sends an RFC 7807 problem response for status.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string, errs []fieldError) {
+	p := newProblem(r, status, detail)
+	p.Errors = errs
+	p.write(w)
+}
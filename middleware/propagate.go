@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultPropagateHeaders are the W3C trace context headers.
+var defaultPropagateHeaders = []string{"traceparent", "tracestate", "baggage"}
+
+// propagationKey is the context key for outbound propagation headers.
+type propagationKey struct{}
+
+// propagation holds the headers copied onto outgoing requests.
+type propagation struct {
+	header http.Header
+}
+
+// newPropagation captures the request ID and the named inbound headers.
+func newPropagation(r *http.Request, idHeader, id string, names []string) *propagation {
+	p := &propagation{header: make(http.Header, len(names)+1)}
+	p.header.Set(idHeader, id)
+	for _, name := range names {
+		if values := r.Header.Values(name); len(values) > 0 {
+			p.header[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	return p
+}
+
+// PropagationHeaders returns the headers Transport adds to outgoing requests
+// made with ctx: the request ID and the inbound trace headers captured by
+// RequestID. Returns nil if ctx has none.
+func PropagationHeaders(ctx context.Context) http.Header {
+	p, ok := ctx.Value(propagationKey{}).(*propagation)
+	if !ok {
+		return nil
+	}
+	return p.header.Clone()
+}
+
+// Transport returns an http.RoundTripper that adds the request ID and trace
+// headers from each outgoing request's context, so correlation survives
+// service hops. Within Deadline, the remaining budget is sent as
+// X-Request-Timeout. Headers already set on the request are kept. If base is nil,
+// http.DefaultTransport is used.
+//
+// Example:
+//
+//	client := &http.Client{Transport: middleware.Transport(nil)}
+//	req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, url, nil)
+//	resp, err := client.Do(req)
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &propagatingTransport{base: base}
+}
+
+// ContextTransport is like Transport but falls back to ctx for requests whose
+// own context carries no request ID, for clients that build requests without
+// the incoming context.
+func ContextTransport(ctx context.Context, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &propagatingTransport{base: base, ctx: ctx}
+}
+
+// propagatingTransport injects propagation headers into outgoing requests.
+type propagatingTransport struct {
+	base http.RoundTripper
+	ctx  context.Context
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *propagatingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	p, ok := req.Context().Value(propagationKey{}).(*propagation)
+	if !ok && t.ctx != nil {
+		p, ok = t.ctx.Value(propagationKey{}).(*propagation)
+	}
+	budgetCtx := req.Context()
+	if budgetCtx.Value(deadlineKey{}) == nil && t.ctx != nil {
+		budgetCtx = t.ctx
+	}
+	deadline, hasBudget := budgetCtx.Deadline()
+	hasBudget = hasBudget && budgetCtx.Value(deadlineKey{}) != nil
+	if !ok && !hasBudget {
+		return t.base.RoundTrip(req)
+	}
+
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(req.Context())
+	if ok {
+		for name, values := range p.header {
+			if _, exists := req.Header[name]; !exists {
+				req.Header[name] = append([]string(nil), values...)
+			}
+		}
+	}
+	if hasBudget && req.Header.Get(RequestTimeoutHeader) == "" {
+		if remaining := time.Until(deadline).Milliseconds(); remaining > 0 {
+			req.Header.Set(RequestTimeoutHeader, strconv.FormatInt(remaining, 10))
+		}
+	}
+	return t.base.RoundTrip(req)
+}
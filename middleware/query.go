@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// AllowedQueryParamsConfig configures the AllowedQueryParams middleware.
+type AllowedQueryParamsConfig struct {
+	// Allowed is the list of accepted query parameter names.
+	// Default: [] (no query parameters are accepted)
+	Allowed []string
+
+	// AllowedPrefixes accepts any parameter starting with one of these
+	// prefixes, e.g. "filter[" for "filter[status]".
+	// Default: []
+	AllowedPrefixes []string
+
+	// Handler is called with the unknown parameter names, sorted, when a
+	// request is rejected.
+	// If nil, a 400 Bad Request problem response listing them is sent.
+	Handler func(w http.ResponseWriter, r *http.Request, unknown []string)
+
+	// SkipFunc determines if the check should be skipped.
+	SkipFunc func(r *http.Request) bool
+}
+
+// AllowedQueryParams returns a middleware that rejects requests carrying
+// query parameters other than names, catching client typos such as
+// "?pgae=2".
+func AllowedQueryParams(names ...string) Middleware {
+	return AllowedQueryParamsWithConfig(AllowedQueryParamsConfig{Allowed: names})
+}
+
+// AllowedQueryParamsWithConfig returns an AllowedQueryParams middleware with
+// the given configuration.
+func AllowedQueryParamsWithConfig(config AllowedQueryParamsConfig) Middleware {
+	allowed := make(map[string]bool, len(config.Allowed))
+	for _, name := range config.Allowed {
+		allowed[name] = true
+	}
+	if config.Handler == nil {
+		config.Handler = unknownQueryParams
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.RawQuery == "" || (config.SkipFunc != nil && config.SkipFunc(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var unknown []string
+			for name := range r.URL.Query() {
+				if allowed[name] || hasAnyPrefix(name, config.AllowedPrefixes) {
+					continue
+				}
+				unknown = append(unknown, name)
+			}
+			if len(unknown) > 0 {
+				slices.Sort(unknown)
+				config.Handler(w, r, unknown)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// hasAnyPrefix reports whether s starts with one of prefixes.
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// unknownQueryParams sends an RFC 7807 problem listing the unknown parameters.
+func unknownQueryParams(w http.ResponseWriter, r *http.Request, unknown []string) {
+	errs := make([]fieldError, len(unknown))
+	for i, name := range unknown {
+		errs[i] = fieldError{Field: name, Message: "unknown query parameter"}
+	}
+	writeProblem(w, r, http.StatusBadRequest, "unknown query parameters: "+strings.Join(unknown, ", "), errs)
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 	"sync"
@@ -22,8 +23,17 @@ type RateLimitConfig struct {
 	// Default: uses client IP address
 	KeyFunc func(r *http.Request) string
 
-	// Handler is called when the rate limit is exceeded.
-	// If nil, a default 429 Too Many Requests response is sent.
+	// Classes give requests identified by other keys, such as an
+	// authenticated user, API key or tenant, their own limits. They are
+	// tried in order and the first returning a non-empty key applies;
+	// requests matching none are limited by KeyFunc, Rate and Burst.
+	// Default: []
+	Classes []RateLimitClass
+
+	// Handler is called when the rate limit is exceeded, after the
+	// Retry-After and X-RateLimit-* headers are set.
+	// If nil, a 429 problem response is sent with the limit, remaining,
+	// retry_after and reset values as extension members.
 	Handler http.HandlerFunc
 
 	// SkipFunc determines if rate limiting should be skipped.
@@ -36,6 +46,76 @@ type RateLimitConfig struct {
 	// ExpirationTime is how long to keep entries after last access.
 	// Default: 5 minutes
 	ExpirationTime time.Duration
+
+	// Headers selects the rate limit header fields sent with responses.
+	// Default: RateLimitHeadersLegacy
+	Headers RateLimitHeaders
+}
+
+// RateLimitHeaders selects the rate limit header fields RateLimit sends.
+// Retry-After is sent with every rejected request regardless.
+type RateLimitHeaders int
+
+const (
+	// RateLimitHeadersLegacy sends X-RateLimit-Limit (the rate per second),
+	// X-RateLimit-Remaining and X-RateLimit-Reset (a Unix time).
+	RateLimitHeadersLegacy RateLimitHeaders = iota
+
+	// RateLimitHeadersDraft sends the IETF RateLimit header fields draft:
+	// RateLimit-Limit (the burst), RateLimit-Remaining, RateLimit-Reset
+	// (seconds until the bucket is full again) and the combined RateLimit
+	// field, as in "limit=10, remaining=4, reset=3".
+	RateLimitHeadersDraft
+
+	// RateLimitHeadersBoth sends the legacy and the draft header fields, for
+	// migrating clients.
+	RateLimitHeadersBoth
+)
+
+// RateLimitClass is a class of rate limit keys with its own limits.
+type RateLimitClass struct {
+	// Name identifies the class. Keys of different classes never share a
+	// bucket.
+	Name string
+
+	// Key extracts the key from the request, returning "" if the class does
+	// not apply. See ContextKey and HeaderKey.
+	Key func(r *http.Request) string
+
+	// Rate is the number of requests allowed per second for each key.
+	// Default: RateLimitConfig.Rate
+	Rate float64
+
+	// Burst is the maximum number of requests allowed in a burst.
+	// Default: RateLimitConfig.Burst
+	Burst int
+}
+
+// ContextKey returns a rate limit key extractor reading the request context
+// value for key, such as the user ID or tenant set by an authentication
+// middleware. Strings and fmt.Stringer values are used as is; missing or
+// empty values yield "".
+func ContextKey(key any) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		switch v := r.Context().Value(key).(type) {
+		case nil:
+			return ""
+		case string:
+			return v
+		case fmt.Stringer:
+			return v.String()
+		default:
+			return fmt.Sprint(v)
+		}
+	}
+}
+
+// HeaderKey returns a rate limit key extractor reading the named request
+// header, such as "X-API-Key" or "X-Tenant-ID".
+func HeaderKey(name string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
 }
 
 // DefaultRateLimitConfig returns the default RateLimit configuration.
@@ -74,6 +154,19 @@ func RateLimitWithConfig(config RateLimitConfig) Middleware {
 	if config.ExpirationTime <= 0 {
 		config.ExpirationTime = 5 * time.Minute
 	}
+	classes := make([]RateLimitClass, len(config.Classes))
+	for i, class := range config.Classes {
+		if class.Name == "" || class.Key == nil {
+			panic("helix: RateLimit classes require a Name and Key")
+		}
+		if class.Rate <= 0 {
+			class.Rate = config.Rate
+		}
+		if class.Burst <= 0 {
+			class.Burst = config.Burst
+		}
+		classes[i] = class
+	}
 
 	store := newRateLimitStore(config)
 
@@ -88,31 +181,44 @@ func RateLimitWithConfig(config RateLimitConfig) Middleware {
 				return
 			}
 
-			key := config.KeyFunc(r)
-			limiter := store.get(key, config.Rate, config.Burst)
+			rate, burst := config.Rate, config.Burst
+			var key string
+			for _, class := range classes {
+				if k := class.Key(r); k != "" {
+					key = class.Name + ":" + k
+					rate, burst = class.Rate, class.Burst
+					break
+				}
+			}
+			if key == "" {
+				key = config.KeyFunc(r)
+			}
+			limiter := store.get(key, rate, burst)
 
-			if !limiter.Allow() {
-				// Rate limit exceeded
-				retryAfter := limiter.RetryAfter()
+			allowed, state := limiter.take()
+			reset := time.Now().Add(state.reset).Unix()
+			setRateLimitHeaders(w.Header(), config.Headers, rate, burst, state, reset)
 
-				w.Header().Set("X-RateLimit-Limit", strconv.FormatFloat(config.Rate, 'f', 0, 64))
-				w.Header().Set("X-RateLimit-Remaining", "0")
-				w.Header().Set("Retry-After", strconv.FormatInt(int64(retryAfter.Seconds()), 10))
+			if !allowed {
+				retryAfter := ceilSeconds(state.retryAfter)
+				w.Header().Set("Retry-After", strconv.FormatInt(retryAfter, 10))
 
 				if config.Handler != nil {
 					config.Handler(w, r)
-				} else {
-					w.WriteHeader(http.StatusTooManyRequests)
-					w.Write([]byte("Too Many Requests"))
+					return
+				}
+				p := newProblem(r, http.StatusTooManyRequests,
+					fmt.Sprintf("rate limit exceeded, retry in %d seconds", retryAfter))
+				p.Extensions = map[string]any{
+					"limit":       rate,
+					"remaining":   0,
+					"retry_after": retryAfter,
+					"reset":       reset,
 				}
+				p.write(w)
 				return
 			}
 
-			// Set rate limit headers
-			remaining := limiter.Remaining()
-			w.Header().Set("X-RateLimit-Limit", strconv.FormatFloat(config.Rate, 'f', 0, 64))
-			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
-
 			next.ServeHTTP(w, r)
 		})
 	}
@@ -198,7 +304,16 @@ func newTokenBucket(rate float64, burst int) *tokenBucket {
 	return tb
 }
 
-func (tb *tokenBucket) Allow() bool {
+// bucketState describes a token bucket after a request was counted.
+type bucketState struct {
+	remaining  int           // whole tokens left
+	retryAfter time.Duration // until the next token, if none is left
+	reset      time.Duration // until the bucket is full again
+}
+
+// take consumes a token if one is available and reports whether it did,
+// along with the bucket's resulting state.
+func (tb *tokenBucket) take() (bool, bucketState) {
 	tb.mu.Lock()
 	defer tb.mu.Unlock()
 
@@ -212,40 +327,28 @@ func (tb *tokenBucket) Allow() bool {
 		tb.tokens = float64(tb.burst)
 	}
 
-	if tb.tokens >= 1 {
+	allowed := tb.tokens >= 1
+	if allowed {
 		tb.tokens--
-		return true
 	}
 
-	return false
-}
-
-func (tb *tokenBucket) Remaining() int {
-	tb.mu.Lock()
-	defer tb.mu.Unlock()
-
-	now := time.Now()
-	elapsed := now.Sub(tb.lastUpdate).Seconds()
-
-	tokens := tb.tokens + elapsed*tb.rate
-	if tokens > float64(tb.burst) {
-		tokens = float64(tb.burst)
+	state := bucketState{
+		remaining: int(tb.tokens),
+		reset:     tb.until(float64(tb.burst)),
 	}
-
-	return int(tokens)
+	if !allowed {
+		state.retryAfter = tb.until(1)
+	}
+	return allowed, state
 }
 
-func (tb *tokenBucket) RetryAfter() time.Duration {
-	tb.mu.Lock()
-	defer tb.mu.Unlock()
-
-	if tb.tokens >= 1 {
+// until returns how long the bucket takes to refill to tokens. tb.mu must
+// be held.
+func (tb *tokenBucket) until(tokens float64) time.Duration {
+	if tb.tokens >= tokens {
 		return 0
 	}
-
-	// Calculate time until next token
-	needed := 1.0 - tb.tokens
-	return time.Duration(needed/tb.rate) * time.Second
+	return time.Duration((tokens - tb.tokens) / tb.rate * float64(time.Second))
 }
 
 func (tb *tokenBucket) touch() {
@@ -256,6 +359,33 @@ func (tb *tokenBucket) lastAccess() time.Time {
 	return tb.lastTouch.Load().(time.Time)
 }
 
+// setRateLimitHeaders sets the header fields selected by style for a bucket
+// of rate and burst in state, which is full again at the Unix time reset.
+func setRateLimitHeaders(h http.Header, style RateLimitHeaders, rate float64, burst int, state bucketState, reset int64) {
+	if style != RateLimitHeadersDraft {
+		h.Set("X-RateLimit-Limit", strconv.FormatFloat(rate, 'f', -1, 64))
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(state.remaining))
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+	}
+	if style != RateLimitHeadersLegacy {
+		var resetIn int64
+		if state.reset > 0 {
+			resetIn = ceilSeconds(state.reset)
+		}
+		h.Set("RateLimit-Limit", strconv.Itoa(burst))
+		h.Set("RateLimit-Remaining", strconv.Itoa(state.remaining))
+		h.Set("RateLimit-Reset", strconv.FormatInt(resetIn, 10))
+		h.Set("RateLimit", fmt.Sprintf("limit=%d, remaining=%d, reset=%d", burst, state.remaining, resetIn))
+	}
+}
+
+// ceilSeconds rounds d up to whole seconds, with a minimum of one, as
+// Retry-After must not tell clients to retry immediately.
+func ceilSeconds(d time.Duration) int64 {
+	secs := int64((d + time.Second - 1) / time.Second)
+	return max(secs, 1)
+}
+
 // getClientIP extracts the client IP from the request.
 func getClientIP(r *http.Request) string {
 	// Check X-Forwarded-For
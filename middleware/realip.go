@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// RealIPConfig configures the RealIP middleware.
+type RealIPConfig struct {
+	// TrustedProxies lists the addresses or CIDR ranges of the proxies in
+	// front of the server, whose X-Forwarded-For and X-Real-IP headers are
+	// believed. Such headers from any other peer are dropped.
+	// Default: loopback and private networks
+	TrustedProxies []string
+}
+
+// DefaultRealIPConfig returns the default RealIP configuration.
+func DefaultRealIPConfig() RealIPConfig {
+	return RealIPConfig{
+		TrustedProxies: []string{
+			"127.0.0.0/8",
+			"::1/128",
+			"10.0.0.0/8",
+			"172.16.0.0/12",
+			"192.168.0.0/16",
+			"fc00::/7",
+		},
+	}
+}
+
+// RealIP returns a middleware that sets r.RemoteAddr to the client's address
+// as reported by trusted proxies. See RealIPWithConfig.
+func RealIP() Middleware {
+	return RealIPWithConfig(DefaultRealIPConfig())
+}
+
+// RealIPWithConfig returns a RealIP middleware with the given configuration.
+// The client is the rightmost X-Forwarded-For address that is not a trusted
+// proxy, else X-Real-IP. Afterwards X-Forwarded-For is removed and X-Real-IP
+// holds the client address, so later middleware such as Logger and RateLimit
+// cannot be fooled by spoofed headers.
+func RealIPWithConfig(config RealIPConfig) Middleware {
+	if len(config.TrustedProxies) == 0 {
+		config.TrustedProxies = DefaultRealIPConfig().TrustedProxies
+	}
+	trusted := make([]netip.Prefix, len(config.TrustedProxies))
+	for i, p := range config.TrustedProxies {
+		prefix, err := parsePrefix(p)
+		if err != nil {
+			panic("helix: invalid trusted proxy " + p + ": " + err.Error())
+		}
+		trusted[i] = prefix
+	}
+	isTrusted := func(addr netip.Addr) bool {
+		addr = addr.Unmap()
+		for _, p := range trusted {
+			if p.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host, port, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				host = r.RemoteAddr
+			}
+			forwarded := r.Header.Values("X-Forwarded-For")
+			realIP := r.Header.Get("X-Real-IP")
+			r.Header.Del("X-Forwarded-For")
+			r.Header.Del("X-Real-IP")
+
+			peer, err := netip.ParseAddr(host)
+			if err != nil || !isTrusted(peer) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			client := peer
+			found := false
+			hops := strings.Split(strings.Join(forwarded, ","), ",")
+			for i := len(hops) - 1; i >= 0; i-- {
+				addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+				if err != nil {
+					break
+				}
+				client, found = addr, true
+				if !isTrusted(addr) {
+					break
+				}
+			}
+			if !found {
+				if addr, err := netip.ParseAddr(strings.TrimSpace(realIP)); err == nil {
+					client = addr
+				}
+			}
+
+			if port == "" {
+				port = "0"
+			}
+			r.RemoteAddr = net.JoinHostPort(client.Unmap().String(), port)
+			r.Header.Set("X-Real-IP", client.Unmap().String())
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// parsePrefix parses a CIDR range or a single address.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		return netip.ParsePrefix(s)
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
@@ -3,8 +3,10 @@ package middleware
 import (
 	"context"
 	"crypto/rand"
+	"encoding/binary"
 	"encoding/hex"
 	"net/http"
+	"time"
 )
 
 // RequestIDHeader is the default header name for the request ID.
@@ -20,20 +22,37 @@ type RequestIDConfig struct {
 	Header string
 
 	// Generator is a function that generates a new request ID.
+	// UUIDv7 and ULID produce time-sortable IDs.
 	// Default: generates a random 16-byte hex string
 	Generator func() string
 
+	// IgnoreIncoming always generates a new request ID. By default a
+	// request ID sent by the client, or a gateway in front, is reused when
+	// it passes Validator.
+	IgnoreIncoming bool
+
+	// Validator reports whether an inbound request ID may be reused.
+	// Default: ValidRequestID
+	Validator func(id string) bool
+
 	// TargetHeader is the header name to set on the response.
 	// Default: same as Header
 	TargetHeader string
+
+	// PropagateHeaders lists inbound headers, such as W3C trace context,
+	// that Transport copies onto outgoing requests along with the request ID.
+	// Default: ["traceparent", "tracestate", "baggage"]
+	PropagateHeaders []string
 }
 
 // DefaultRequestIDConfig returns the default configuration for RequestID.
 func DefaultRequestIDConfig() RequestIDConfig {
 	return RequestIDConfig{
-		Header:       RequestIDHeader,
-		Generator:    generateRequestID,
-		TargetHeader: RequestIDHeader,
+		Header:           RequestIDHeader,
+		Generator:        generateRequestID,
+		TargetHeader:     RequestIDHeader,
+		PropagateHeaders: defaultPropagateHeaders,
+		Validator:        ValidRequestID,
 	}
 }
 
@@ -54,11 +73,22 @@ func RequestIDWithConfig(config RequestIDConfig) Middleware {
 	if config.TargetHeader == "" {
 		config.TargetHeader = config.Header
 	}
+	if config.PropagateHeaders == nil {
+		config.PropagateHeaders = defaultPropagateHeaders
+	}
+	if config.Validator == nil {
+		config.Validator = ValidRequestID
+	}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Check for existing request ID
-			id := r.Header.Get(config.Header)
+			// Reuse the client's request ID only if trusted and well-formed
+			var id string
+			if !config.IgnoreIncoming {
+				if incoming := r.Header.Get(config.Header); incoming != "" && config.Validator(incoming) {
+					id = incoming
+				}
+			}
 			if id == "" {
 				id = config.Generator()
 			}
@@ -68,6 +98,7 @@ func RequestIDWithConfig(config RequestIDConfig) Middleware {
 
 			// Store in context
 			ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+			ctx = context.WithValue(ctx, propagationKey{}, newPropagation(r, config.Header, id, config.PropagateHeaders))
 			r = r.WithContext(ctx)
 
 			next.ServeHTTP(w, r)
@@ -97,3 +128,80 @@ func generateRequestID() string {
 	}
 	return hex.EncodeToString(b)
 }
+
+// maxRequestIDLength is the longest inbound request ID ValidRequestID accepts.
+const maxRequestIDLength = 128
+
+// ValidRequestID reports whether id is at most 128 characters of ASCII
+// letters, digits, '-', '_', '.' and ':'. It keeps client-supplied IDs from
+// injecting spaces, quotes or control characters into logs.
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// UUIDv7 generates an RFC 9562 version 7 UUID, which sorts by creation time.
+// It can be used as RequestIDConfig.Generator.
+func UUIDv7() string {
+	var b [16]byte
+	rand.Read(b[6:])
+	putMillis(b[:], time.Now())
+	b[6] = b[6]&0x0f | 0x70 // version 7
+	b[8] = b[8]&0x3f | 0x80 // RFC 9562 variant
+
+	var out [36]byte
+	hex.Encode(out[0:8], b[0:4])
+	out[8] = '-'
+	hex.Encode(out[9:13], b[4:6])
+	out[13] = '-'
+	hex.Encode(out[14:18], b[6:8])
+	out[18] = '-'
+	hex.Encode(out[19:23], b[8:10])
+	out[23] = '-'
+	hex.Encode(out[24:], b[10:])
+	return string(out[:])
+}
+
+// crockford is the Crockford base32 alphabet used by ULIDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID generates a 26-character ULID, which sorts by creation time.
+// It can be used as RequestIDConfig.Generator.
+func ULID() string {
+	var b [16]byte
+	rand.Read(b[6:])
+	putMillis(b[:], time.Now())
+
+	// Encode the 128 bits as 26 base32 digits, least significant first
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+	var out [26]byte
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// putMillis writes the 48-bit Unix millisecond timestamp of t into b[0:6].
+func putMillis(b []byte, t time.Time) {
+	ms := uint64(t.UnixMilli())
+	b[0] = byte(ms >> 40)
+	b[1] = byte(ms >> 32)
+	b[2] = byte(ms >> 24)
+	b[3] = byte(ms >> 16)
+	b[4] = byte(ms >> 8)
+	b[5] = byte(ms)
+}
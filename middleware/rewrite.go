@@ -0,0 +1,429 @@
+package middleware
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// pathPattern is a parsed rewrite or redirect pattern such as
+// "/old/{id}" or "/docs/{path...}".
+type pathPattern struct {
+	raw      string
+	segments []patternSegment
+}
+
+// patternSegment is a segment of a pathPattern.
+type patternSegment struct {
+	value string // literal text, or the parameter name
+	param bool   // {name}: one segment
+	rest  bool   // {name...}: the remaining segments
+}
+
+// parsePathPattern parses a pattern. Parameters match whole segments, and a
+// {name...} parameter must be last.
+func parsePathPattern(pattern string) (pathPattern, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		return pathPattern{}, fmt.Errorf("pattern %q must start with /", pattern)
+	}
+	p := pathPattern{raw: pattern}
+	parts := strings.Split(pattern[1:], "/")
+	for i, part := range parts {
+		if !strings.HasPrefix(part, "{") || !strings.HasSuffix(part, "}") {
+			if strings.ContainsAny(part, "{}") {
+				return pathPattern{}, fmt.Errorf("pattern %q: parameters must be whole segments", pattern)
+			}
+			p.segments = append(p.segments, patternSegment{value: part})
+			continue
+		}
+		name, rest := strings.CutSuffix(part[1:len(part)-1], "...")
+		if name == "" {
+			return pathPattern{}, fmt.Errorf("pattern %q: empty parameter name", pattern)
+		}
+		if rest && i != len(parts)-1 {
+			return pathPattern{}, fmt.Errorf("pattern %q: {%s...} must be last", pattern, name)
+		}
+		p.segments = append(p.segments, patternSegment{value: name, param: !rest, rest: rest})
+	}
+	return p, nil
+}
+
+// match matches the escaped path against the pattern and returns the
+// captured parameters.
+func (p pathPattern) match(path string) (map[string]string, bool) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, false
+	}
+	parts := strings.Split(path[1:], "/")
+	var params map[string]string
+	for i, seg := range p.segments {
+		if seg.rest {
+			if params == nil {
+				params = make(map[string]string)
+			}
+			params[seg.value] = strings.Join(parts[i:], "/")
+			return params, true
+		}
+		if i == len(parts) {
+			return nil, false
+		}
+		if !seg.param {
+			if parts[i] != seg.value {
+				return nil, false
+			}
+			continue
+		}
+		if parts[i] == "" {
+			return nil, false
+		}
+		if params == nil {
+			params = make(map[string]string)
+		}
+		params[seg.value] = parts[i]
+	}
+	return params, len(parts) == len(p.segments)
+}
+
+// statics returns the number of literal segments, to try more specific
+// patterns first.
+func (p pathPattern) statics() int {
+	n := 0
+	for _, seg := range p.segments {
+		if !seg.param && !seg.rest {
+			n++
+		}
+	}
+	return n
+}
+
+// expand replaces the {name} and {name...} references in target with the
+// captured parameters.
+func expand(target string, params map[string]string) string {
+	if len(params) == 0 || !strings.Contains(target, "{") {
+		return target
+	}
+	pairs := make([]string, 0, 4*len(params))
+	for name, value := range params {
+		pairs = append(pairs, "{"+name+"...}", value, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(target)
+}
+
+// checkTarget reports target references to parameters the pattern lacks.
+func checkTarget(p pathPattern, target string) error {
+	for rest := target; ; {
+		start := strings.Index(rest, "{")
+		if start < 0 {
+			return nil
+		}
+		end := strings.Index(rest[start:], "}")
+		if end < 0 {
+			return fmt.Errorf("target %q: unclosed parameter", target)
+		}
+		name := strings.TrimSuffix(rest[start+1:start+end], "...")
+		if !slices.ContainsFunc(p.segments, func(seg patternSegment) bool {
+			return (seg.param || seg.rest) && seg.value == name
+		}) {
+			return fmt.Errorf("target %q: pattern %q has no parameter {%s}", target, p.raw, name)
+		}
+		rest = rest[start+end+1:]
+	}
+}
+
+// rewriteRule is a compiled Rewrite rule.
+type rewriteRule struct {
+	from pathPattern
+	to   string
+}
+
+// Rewrite returns a middleware that rewrites request paths before routing,
+// mapping patterns to targets with {name} and {name...} captures, as in
+// "/old/{id}": "/new/{id}". The client is not redirected and sees the
+// original URL. Query strings in targets are merged with the request's.
+// More specific patterns, with more literal segments, are tried first.
+// Panics on invalid patterns.
+//
+// Example:
+//
+//	s.Use(middleware.Rewrite(map[string]string{
+//	    "/api/v1/{path...}": "/api/{path...}",
+//	    "/u/{name}":         "/users/{name}?view=profile",
+//	}))
+func Rewrite(rules map[string]string) Middleware {
+	compiled := make([]rewriteRule, 0, len(rules))
+	for from, to := range rules {
+		p, err := parsePathPattern(from)
+		if err == nil {
+			err = checkTarget(p, to)
+		}
+		if err != nil {
+			panic("helix: rewrite: " + err.Error())
+		}
+		compiled = append(compiled, rewriteRule{from: p, to: to})
+	}
+	slices.SortFunc(compiled, func(a, b rewriteRule) int {
+		if d := b.from.statics() - a.from.statics(); d != 0 {
+			return d
+		}
+		if d := len(b.from.segments) - len(a.from.segments); d != 0 {
+			return d
+		}
+		return strings.Compare(a.from.raw, b.from.raw)
+	})
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, rule := range compiled {
+				params, ok := rule.from.match(r.URL.EscapedPath())
+				if !ok {
+					continue
+				}
+				target, err := url.Parse(expand(rule.to, params))
+				if err != nil {
+					break
+				}
+				r2 := r.Clone(r.Context())
+				r2.URL.Path, r2.URL.RawPath = target.Path, target.RawPath
+				r2.URL.RawQuery = mergeQuery(target.RawQuery, r.URL.RawQuery)
+				r2.RequestURI = r2.URL.RequestURI()
+				r = r2
+				break
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// mergeQuery joins two raw query strings.
+func mergeQuery(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	}
+	return a + "&" + b
+}
+
+// RedirectRule redirects requests matching a pattern.
+type RedirectRule struct {
+	// From is the path pattern, with {name} and {name...} captures, such as
+	// "/blog/{year}/{slug}".
+	From string
+
+	// To is the target path or URL, referencing the captures, such as
+	// "/posts/{slug}" or "https://docs.example.com/{path...}". The request's
+	// query string is kept unless To has one.
+	To string
+
+	// Status is the redirect status code.
+	// Default: 301 (Moved Permanently)
+	Status int
+}
+
+// redirectRule is a compiled RedirectRule.
+type redirectRule struct {
+	RedirectRule
+	from pathPattern
+}
+
+// RedirectRules returns a middleware that redirects requests matching the
+// rules, tried in order, so legacy URLs need no handler each. Panics on
+// invalid rules; see LoadRedirectRules to read them from a file.
+//
+// Example:
+//
+//	s.Use(middleware.RedirectRules(
+//	    middleware.RedirectRule{From: "/old/{id}", To: "/new/{id}"},
+//	    middleware.RedirectRule{From: "/docs/{path...}", To: "https://docs.example.com/{path...}", Status: http.StatusFound},
+//	))
+func RedirectRules(rules ...RedirectRule) Middleware {
+	compiled := make([]redirectRule, len(rules))
+	for i, rule := range rules {
+		c, err := compileRedirect(rule)
+		if err != nil {
+			panic("helix: " + err.Error())
+		}
+		compiled[i] = c
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, rule := range compiled {
+				params, ok := rule.from.match(r.URL.EscapedPath())
+				if !ok {
+					continue
+				}
+				target := expand(rule.To, params)
+				if strings.HasPrefix(rule.To, "/") && !strings.HasPrefix(rule.To, "//") {
+					// A capture of "//evil.example" must not make the
+					// redirect leave the site
+					target = "/" + strings.TrimLeft(target, `/\`)
+				}
+				if r.URL.RawQuery != "" && !strings.Contains(target, "?") {
+					target += "?" + r.URL.RawQuery
+				}
+				http.Redirect(w, r, target, rule.Status)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// compileRedirect validates rule and parses its pattern.
+func compileRedirect(rule RedirectRule) (redirectRule, error) {
+	if rule.Status == 0 {
+		rule.Status = http.StatusMovedPermanently
+	}
+	if rule.Status < 300 || rule.Status > 399 {
+		return redirectRule{}, fmt.Errorf("redirect %s: status %d is not a redirect", rule.From, rule.Status)
+	}
+	p, err := parsePathPattern(rule.From)
+	if err == nil {
+		err = checkTarget(p, rule.To)
+	}
+	if err != nil {
+		return redirectRule{}, err
+	}
+	return redirectRule{RedirectRule: rule, from: p}, nil
+}
+
+// ParseRedirectRules reads redirect rules, one per line as
+// "FROM TO [STATUS]", in the style of a _redirects file. Blank lines and
+// lines starting with # are ignored.
+//
+//	# legacy blog
+//	/blog/{year}/{slug}  /posts/{slug}
+//	/docs/{path...}      https://docs.example.com/{path...}  302
+func ParseRedirectRules(r io.Reader) ([]RedirectRule, error) {
+	var rules []RedirectRule
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 && len(fields) != 3 {
+			return nil, fmt.Errorf("helix: redirect rules line %d: expected FROM TO [STATUS]", line)
+		}
+		rule := RedirectRule{From: fields[0], To: fields[1]}
+		if len(fields) == 3 {
+			status, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("helix: redirect rules line %d: invalid status %q", line, fields[2])
+			}
+			rule.Status = status
+		}
+		if _, err := compileRedirect(rule); err != nil {
+			return nil, fmt.Errorf("helix: redirect rules line %d: %w", line, err)
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("helix: redirect rules: %w", err)
+	}
+	return rules, nil
+}
+
+// LoadRedirectRules reads redirect rules from the file at path; see
+// ParseRedirectRules for the format.
+//
+// Example:
+//
+//	rules, err := middleware.LoadRedirectRules("redirects.txt")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	s.Use(middleware.RedirectRules(rules...))
+func LoadRedirectRules(path string) ([]RedirectRule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("helix: %w", err)
+	}
+	defer f.Close()
+	return ParseRedirectRules(f)
+}
+
+// CanonicalConfig configures the Canonical middleware.
+type CanonicalConfig struct {
+	// HTTPS redirects plain HTTP requests to HTTPS. Requests a proxy marks
+	// with X-Forwarded-Proto: https count as HTTPS.
+	HTTPS bool
+
+	// Host is the canonical host, such as "example.com"; requests for any
+	// other host are redirected to it.
+	Host string
+
+	// WWW, when Host is empty, adds ("add") or strips ("strip") the "www."
+	// prefix of the request's host.
+	WWW string
+
+	// Status is the redirect status code for GET and HEAD requests. Other
+	// methods get 308 (Permanent Redirect) so clients keep the method and
+	// body.
+	// Default: 301 (Moved Permanently)
+	Status int
+}
+
+// Canonical returns a middleware that redirects requests to the canonical
+// scheme and host, so every page has one URL for caches and search
+// engines. Panics if WWW is not "", "add" or "strip".
+//
+// Example:
+//
+//	s.Use(middleware.Canonical(middleware.CanonicalConfig{HTTPS: true, WWW: "strip"}))
+func Canonical(config CanonicalConfig) Middleware {
+	if config.WWW != "" && config.WWW != "add" && config.WWW != "strip" {
+		panic(`helix: Canonical WWW must be "add" or "strip"`)
+	}
+	if config.Status == 0 {
+		config.Status = http.StatusMovedPermanently
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scheme := "http"
+			if isHTTPS(r) {
+				scheme = "https"
+			}
+			host := r.Host
+
+			wantScheme, wantHost := scheme, host
+			if config.HTTPS {
+				wantScheme = "https"
+			}
+			switch {
+			case config.Host != "":
+				wantHost = config.Host
+			case config.WWW == "add" && !strings.HasPrefix(host, "www."):
+				wantHost = "www." + host
+			case config.WWW == "strip":
+				wantHost = strings.TrimPrefix(host, "www.")
+			}
+
+			if wantScheme == scheme && strings.EqualFold(wantHost, host) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			status := config.Status
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				status = http.StatusPermanentRedirect
+			}
+			http.Redirect(w, r, wantScheme+"://"+wantHost+r.URL.RequestURI(), status)
+		})
+	}
+}
+
+// isHTTPS reports whether the request arrived over TLS, directly or at a
+// proxy that says so with X-Forwarded-Proto.
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
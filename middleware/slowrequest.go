@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"cmp"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kolosys/helix/logs"
+)
+
+// SlowRequestInfo describes a request that exceeded its latency threshold.
+type SlowRequestInfo struct {
+	Method    string
+	Path      string
+	Route     string // matched route pattern, empty if the request was not routed
+	Status    int
+	Latency   time.Duration
+	Threshold time.Duration
+}
+
+// SlowRequestConfig configures the SlowRequest middleware.
+type SlowRequestConfig struct {
+	// Threshold is the latency above which a request is reported.
+	// Default: 1 second
+	Threshold time.Duration
+
+	// Routes sets per-route latency objectives keyed by route pattern
+	// ("/users/{id}") or method and pattern ("GET /users/{id}"). The method
+	// form takes precedence. Routes not listed use Threshold.
+	// Default: {}
+	Routes map[string]time.Duration
+
+	// OnSlow is called after a request exceeds its threshold.
+	// If nil, a warning is logged through the request's logger
+	// (see ContextLogger).
+	OnSlow func(r *http.Request, info SlowRequestInfo)
+
+	// SkipFunc determines if the request should not be measured.
+	SkipFunc func(r *http.Request) bool
+}
+
+// DefaultSlowRequestConfig returns the default SlowRequest configuration.
+func DefaultSlowRequestConfig() SlowRequestConfig {
+	return SlowRequestConfig{
+		Threshold: time.Second,
+	}
+}
+
+// SlowRequest returns a middleware that calls onSlow for requests taking
+// longer than threshold. A nil onSlow logs a warning.
+func SlowRequest(threshold time.Duration, onSlow func(r *http.Request, info SlowRequestInfo)) Middleware {
+	config := DefaultSlowRequestConfig()
+	config.Threshold = threshold
+	config.OnSlow = onSlow
+	return SlowRequestWithConfig(config)
+}
+
+// SlowRequestWithConfig returns a SlowRequest middleware with the given configuration.
+func SlowRequestWithConfig(config SlowRequestConfig) Middleware {
+	if config.Threshold <= 0 {
+		config.Threshold = time.Second
+	}
+	if config.OnSlow == nil {
+		config.OnSlow = logSlowRequest
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if config.SkipFunc != nil && config.SkipFunc(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r, route := trackRoute(r)
+			start := time.Now()
+			rw := newResponseWriter(w)
+
+			next.ServeHTTP(rw, r)
+
+			latency := time.Since(start)
+			threshold := config.Threshold
+			if len(config.Routes) > 0 && route.pattern != "" {
+				if t, ok := config.Routes[r.Method+" "+route.pattern]; ok {
+					threshold = t
+				} else if t, ok := config.Routes[route.pattern]; ok {
+					threshold = t
+				}
+			}
+			if latency <= threshold {
+				return
+			}
+
+			config.OnSlow(r, SlowRequestInfo{
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Route:     route.pattern,
+				Status:    rw.Status(),
+				Latency:   latency,
+				Threshold: threshold,
+			})
+		})
+	}
+}
+
+// logSlowRequest is the default OnSlow handler.
+func logSlowRequest(r *http.Request, info SlowRequestInfo) {
+	fields := logs.Fields{
+		"method":       info.Method,
+		"path":         info.Path,
+		"status":       info.Status,
+		"latency_ms":   float64(info.Latency.Microseconds()) / 1000.0,
+		"threshold_ms": float64(info.Threshold.Microseconds()) / 1000.0,
+	}
+	if info.Route != "" {
+		fields["route"] = info.Route
+	}
+	logs.FromContext(r.Context()).Warn("slow request", fields)
+}
+
+// SlowRequestCounter counts SLO breaches per route. Its Record method can
+// be used as SlowRequestConfig.OnSlow, alone or from a custom handler.
+type SlowRequestCounter struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+// NewSlowRequestCounter creates an empty SlowRequestCounter.
+func NewSlowRequestCounter() *SlowRequestCounter {
+	return &SlowRequestCounter{counts: make(map[string]int64)}
+}
+
+// Record counts a breach under "METHOD pattern", or "METHOD <unmatched>"
+// for unrouted requests, so that scans of random paths cannot grow the
+// counts without bound.
+func (c *SlowRequestCounter) Record(r *http.Request, info SlowRequestInfo) {
+	key := info.Method + " " + cmp.Or(info.Route, "<unmatched>")
+
+	c.mu.Lock()
+	c.counts[key]++
+	c.mu.Unlock()
+}
+
+// Counts returns a snapshot of the breach counts.
+func (c *SlowRequestCounter) Counts() map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := make(map[string]int64, len(c.counts))
+	for k, v := range c.counts {
+		counts[k] = v
+	}
+	return counts
+}
+
+// Routes returns the keys with recorded breaches, most breached first.
+func (c *SlowRequestCounter) Routes() []string {
+	counts := c.Counts()
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
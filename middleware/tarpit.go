@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kolosys/helix/logs"
+)
+
+// TarpitConfig configures the Tarpit middleware.
+type TarpitConfig struct {
+	// Paths are the honey-pot paths that only scanners request. A path
+	// matches itself and the paths below it; a trailing "*" matches any
+	// path starting with the rest, as "/.env*" matches "/.env.local".
+	// Default: DefaultTarpitPaths
+	Paths []string
+
+	// Delay is how long trapped requests are held before a 404 is sent,
+	// tying up the scanner's connection.
+	// Default: 10 seconds
+	Delay time.Duration
+
+	// MaxConcurrent caps the requests held at once, so a flood of scanner
+	// requests cannot exhaust the server. Requests beyond it get the 404
+	// right away.
+	// Default: 100
+	MaxConcurrent int
+
+	// Denylist, if set, receives the address of every trapped client, for
+	// DenyFor. Share it with IPFilter to block offenders on all paths.
+	Denylist *IPList
+
+	// DenyFor is how long trapped clients stay on the Denylist.
+	// Default: 1 hour
+	DenyFor time.Duration
+
+	// OnTrap is called for each trapped request.
+	// If nil, a warning is logged through the request's logger
+	// (see ContextLogger).
+	OnTrap func(r *http.Request)
+}
+
+// DefaultTarpitPaths are paths commonly probed by vulnerability scanners.
+var DefaultTarpitPaths = []string{
+	"/wp-login.php",
+	"/wp-admin",
+	"/xmlrpc.php",
+	"/.env*",
+	"/.git",
+	"/.aws",
+	"/phpmyadmin",
+	"/config.php",
+	"/server-status",
+	"/cgi-bin",
+}
+
+// Tarpit returns a middleware that traps requests to the given scanner
+// paths, holding each for delay before answering 404. Empty paths use
+// DefaultTarpitPaths. See TarpitWithConfig.
+func Tarpit(paths []string, delay time.Duration) Middleware {
+	return TarpitWithConfig(TarpitConfig{Paths: paths, Delay: delay})
+}
+
+// TarpitWithConfig returns a Tarpit middleware with the given configuration.
+//
+// Example:
+//
+//	banned := middleware.NewIPList()
+//	s.Use(
+//	    middleware.RealIP(),
+//	    middleware.IPFilter(banned),
+//	    middleware.TarpitWithConfig(middleware.TarpitConfig{Denylist: banned}),
+//	)
+func TarpitWithConfig(config TarpitConfig) Middleware {
+	if len(config.Paths) == 0 {
+		config.Paths = DefaultTarpitPaths
+	}
+	if config.Delay <= 0 {
+		config.Delay = 10 * time.Second
+	}
+	if config.MaxConcurrent <= 0 {
+		config.MaxConcurrent = 100
+	}
+	if config.DenyFor <= 0 {
+		config.DenyFor = time.Hour
+	}
+	if config.OnTrap == nil {
+		config.OnTrap = logTrap
+	}
+	held := make(chan struct{}, config.MaxConcurrent)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !trapped(config.Paths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			config.OnTrap(r)
+			if config.Denylist != nil {
+				if addr, ok := peerAddr(r); ok {
+					config.Denylist.Add(addr, config.DenyFor)
+				}
+			}
+
+			select {
+			case held <- struct{}{}:
+				timer := time.NewTimer(config.Delay)
+				select {
+				case <-timer.C:
+				case <-r.Context().Done():
+					timer.Stop()
+				}
+				<-held
+			default:
+			}
+			http.NotFound(w, r)
+		})
+	}
+}
+
+// trapped reports whether path matches one of the honey-pot paths.
+func trapped(paths []string, path string) bool {
+	for _, p := range paths {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+			continue
+		}
+		p = strings.TrimSuffix(p, "/")
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// logTrap is the default OnTrap callback.
+func logTrap(r *http.Request) {
+	logs.FromContext(r.Context()).Warn("tarpit trapped request", logs.Fields{
+		"method":     r.Method,
+		"path":       r.URL.Path,
+		"remote_ip":  getRemoteAddr(r),
+		"user_agent": r.UserAgent(),
+	})
+}
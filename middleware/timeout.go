@@ -116,7 +116,9 @@ func (tw *timeoutWriter) WriteHeader(code int) {
 	if tw.timedOut {
 		return
 	}
-	tw.written = true
+	if !informational(code) {
+		tw.written = true
+	}
 	tw.ResponseWriter.WriteHeader(code)
 }
 
@@ -130,3 +132,27 @@ func (tw *timeoutWriter) Write(b []byte) (int, error) {
 	tw.written = true
 	return tw.ResponseWriter.Write(b)
 }
+
+// Flush flushes the response unless the request has timed out.
+func (tw *timeoutWriter) Flush() {
+	tw.FlushError()
+}
+
+// FlushError is like Flush, returning an error if the request has timed out
+// or the underlying writer can't flush. It is used by
+// http.ResponseController.
+func (tw *timeoutWriter) FlushError() error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return context.DeadlineExceeded
+	}
+	tw.written = true
+	return http.NewResponseController(tw.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
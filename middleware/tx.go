@@ -0,0 +1,209 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/kolosys/helix/logs"
+)
+
+// Transaction is a database transaction opened by Tx. *sql.Tx satisfies
+// it; wrap other drivers' transactions, such as pgx's, to match.
+type Transaction interface {
+	Commit() error
+	Rollback() error
+}
+
+// TxBeginner begins the transactions of Tx.
+type TxBeginner interface {
+	Begin(ctx context.Context) (Transaction, error)
+}
+
+// TxBeginnerFunc adapts a function to a TxBeginner.
+type TxBeginnerFunc func(ctx context.Context) (Transaction, error)
+
+// Begin implements TxBeginner.
+func (f TxBeginnerFunc) Begin(ctx context.Context) (Transaction, error) {
+	return f(ctx)
+}
+
+// SQLDB returns a TxBeginner of database/sql transactions with opts, which
+// may be nil. Handlers get the *sql.Tx with TxFrom(ctx).(*sql.Tx).
+func SQLDB(db *sql.DB, opts *sql.TxOptions) TxBeginner {
+	return TxBeginnerFunc(func(ctx context.Context) (Transaction, error) {
+		return db.BeginTx(ctx, opts)
+	})
+}
+
+// TxConfig configures the Tx middleware.
+type TxConfig struct {
+	// DB begins the transactions. Required.
+	DB TxBeginner
+
+	// Commit reports whether the transaction of a response with the given
+	// status is committed; it is rolled back otherwise.
+	// Default: 2xx statuses
+	Commit func(status int) bool
+
+	// SkipFunc determines if a request runs without a transaction, such as
+	// for read-only endpoints.
+	SkipFunc func(r *http.Request) bool
+}
+
+// txKey is the context key of the request's transaction.
+type txKey struct{}
+
+// WithTx returns a copy of ctx carrying tx.
+func WithTx(ctx context.Context, tx Transaction) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFrom returns the transaction of the request, or nil if it has none.
+func TxFrom(ctx context.Context) Transaction {
+	tx, _ := ctx.Value(txKey{}).(Transaction)
+	return tx
+}
+
+// Tx returns a middleware that runs each request in a transaction of db.
+// See TxWithConfig.
+//
+// Example:
+//
+//	s.Use(middleware.Tx(middleware.SQLDB(db, nil)))
+//
+//	s.POST("/orders", helix.HandleCtx(func(c *helix.Ctx) error {
+//	    tx := helix.TxFrom(c.Context()).(*sql.Tx)
+//	    _, err := tx.ExecContext(c.Context(), "INSERT INTO orders ...")
+//	    return err
+//	}))
+func Tx(db TxBeginner) Middleware {
+	return TxWithConfig(TxConfig{DB: db})
+}
+
+// TxWithConfig returns a Tx middleware with the given configuration. The
+// transaction is committed when the handler sends a 2xx status, before the
+// status is sent, so that a failed commit still gets a 500 problem; any
+// other status, or a panic, rolls it back. Handlers returning an error roll
+// back through the error's status.
+func TxWithConfig(config TxConfig) Middleware {
+	if config.DB == nil {
+		panic("helix: Tx database is required")
+	}
+	if config.Commit == nil {
+		config.Commit = func(status int) bool {
+			return status >= 200 && status < 300
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if config.SkipFunc != nil && config.SkipFunc(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			tx, err := config.DB.Begin(r.Context())
+			if err != nil {
+				logs.FromContext(r.Context()).Error("helix: failed to begin transaction", logs.Fields{"error": err})
+				writeProblem(w, r, http.StatusInternalServerError, "failed to begin transaction", nil)
+				return
+			}
+
+			tw := &txWriter{ResponseWriter: w, r: r, tx: tx, commit: config.Commit}
+			defer func() {
+				if p := recover(); p != nil {
+					if !tw.done {
+						tw.done = true
+						tw.rollback()
+					}
+					panic(p)
+				}
+				if !tw.done {
+					// Nothing was written, and net/http sends a 200.
+					tw.finish(http.StatusOK)
+				}
+			}()
+			next.ServeHTTP(tw, r.WithContext(WithTx(r.Context(), tx)))
+		})
+	}
+}
+
+// txWriter ends the transaction when the response status is sent.
+type txWriter struct {
+	http.ResponseWriter
+	r      *http.Request
+	tx     Transaction
+	commit func(status int) bool
+	done   bool // the transaction has ended
+	failed bool // the commit failed, and the response was replaced
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (tw *txWriter) WriteHeader(code int) {
+	if informational(code) {
+		tw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if tw.done {
+		if !tw.failed {
+			tw.ResponseWriter.WriteHeader(code)
+		}
+		return
+	}
+	if tw.finish(code) {
+		tw.ResponseWriter.WriteHeader(code)
+	}
+}
+
+// Write implements http.ResponseWriter.
+func (tw *txWriter) Write(b []byte) (int, error) {
+	if !tw.done {
+		tw.WriteHeader(http.StatusOK)
+	}
+	if tw.failed {
+		return len(b), nil
+	}
+	return tw.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher.
+func (tw *txWriter) Flush() {
+	if !tw.done {
+		tw.WriteHeader(http.StatusOK)
+	}
+	if !tw.failed {
+		http.NewResponseController(tw.ResponseWriter).Flush()
+	}
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (tw *txWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+// finish commits or rolls back the transaction for a response with status.
+// If the commit fails, it answers with a 500 problem and reports false.
+func (tw *txWriter) finish(status int) bool {
+	tw.done = true
+	if !tw.commit(status) {
+		tw.rollback()
+		return true
+	}
+	if err := tw.tx.Commit(); err != nil {
+		logs.FromContext(tw.r.Context()).Error("helix: failed to commit transaction", logs.Fields{"error": err})
+		tw.failed = true
+		tw.Header().Del("Content-Length")
+		writeProblem(tw.ResponseWriter, tw.r, http.StatusInternalServerError, "failed to commit transaction", nil)
+		return false
+	}
+	return true
+}
+
+// rollback rolls back the transaction, logging a failure.
+func (tw *txWriter) rollback() {
+	if err := tw.tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		logs.FromContext(tw.r.Context()).Error("helix: failed to roll back transaction", logs.Fields{"error": err})
+	}
+}
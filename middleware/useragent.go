@@ -0,0 +1,231 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// UserAgent is the classification of a User-Agent header.
+type UserAgent struct {
+	// Browser and BrowserVersion name the browser, such as "Chrome" and
+	// "126.0.6478.127". Empty for bots and unknown clients.
+	Browser        string
+	BrowserVersion string
+
+	// OS is the operating system, such as "Windows", "macOS", "iOS",
+	// "Android", "ChromeOS" or "Linux".
+	OS string
+
+	// Device is "desktop", "mobile", "tablet" or "bot".
+	Device string
+
+	// Bot reports a crawler, monitoring service, HTTP library or command
+	// line tool, or a request without a User-Agent. BotName names it, such
+	// as "Googlebot" or "curl".
+	Bot     bool
+	BotName string
+
+	// SearchEngine reports a well-known search engine crawler.
+	SearchEngine bool
+}
+
+// knownBots lists well-known automated clients by a lowercased token of
+// their User-Agent.
+var knownBots = []struct {
+	token, name string
+	search      bool
+}{
+	{"googlebot", "Googlebot", true},
+	{"bingbot", "Bingbot", true},
+	{"duckduckbot", "DuckDuckBot", true},
+	{"baiduspider", "Baiduspider", true},
+	{"yandexbot", "YandexBot", true},
+	{"applebot", "Applebot", true},
+	{"slurp", "Yahoo! Slurp", true},
+	{"facebookexternalhit", "facebookexternalhit", false},
+	{"twitterbot", "Twitterbot", false},
+	{"linkedinbot", "LinkedInBot", false},
+	{"slackbot", "Slackbot", false},
+	{"discordbot", "Discordbot", false},
+	{"gptbot", "GPTBot", false},
+	{"ccbot", "CCBot", false},
+	{"ahrefsbot", "AhrefsBot", false},
+	{"semrushbot", "SemrushBot", false},
+	{"headlesschrome", "HeadlessChrome", false},
+	{"curl/", "curl", false},
+	{"wget/", "Wget", false},
+	{"python-requests", "python-requests", false},
+	{"python-urllib", "Python-urllib", false},
+	{"go-http-client", "Go-http-client", false},
+	{"okhttp", "okhttp", false},
+	{"postmanruntime", "PostmanRuntime", false},
+	{"httpie", "HTTPie", false},
+	{"scrapy", "Scrapy", false},
+}
+
+// botWords mark unlisted automated clients.
+var botWords = []string{"bot", "crawler", "spider", "scraper"}
+
+// browsers lists browser tokens, most specific first since most browsers
+// also claim to be Chrome, Safari or Mozilla.
+var browsers = []struct {
+	token, name string
+}{
+	{"edg/", "Edge"},
+	{"edga/", "Edge"},
+	{"edgios/", "Edge"},
+	{"opr/", "Opera"},
+	{"samsungbrowser/", "Samsung Internet"},
+	{"firefox/", "Firefox"},
+	{"fxios/", "Firefox"},
+	{"crios/", "Chrome"},
+	{"chrome/", "Chrome"},
+	{"version/", "Safari"},
+	{"msie ", "Internet Explorer"},
+	{"rv:", "Internet Explorer"},
+}
+
+// ParseUserAgent classifies a User-Agent header. It recognizes the common
+// browsers, operating systems and bots; use it for analytics and coarse
+// policies, not security decisions, as clients can send any header.
+func ParseUserAgent(s string) UserAgent {
+	lower := asciiLower(s)
+	if strings.TrimSpace(lower) == "" {
+		return UserAgent{Device: "bot", Bot: true}
+	}
+
+	for _, b := range knownBots {
+		if strings.Contains(lower, b.token) {
+			return UserAgent{Device: "bot", Bot: true, BotName: b.name, SearchEngine: b.search}
+		}
+	}
+	for _, word := range botWords {
+		if strings.Contains(lower, word) {
+			return UserAgent{Device: "bot", Bot: true, BotName: botProduct(s, lower, word)}
+		}
+	}
+
+	var ua UserAgent
+	switch {
+	case strings.Contains(lower, "windows"):
+		ua.OS = "Windows"
+	case strings.Contains(lower, "iphone"), strings.Contains(lower, "ipad"), strings.Contains(lower, "ipod"):
+		ua.OS = "iOS"
+	case strings.Contains(lower, "android"):
+		ua.OS = "Android"
+	case strings.Contains(lower, "mac os x"), strings.Contains(lower, "macintosh"):
+		ua.OS = "macOS"
+	case strings.Contains(lower, "cros "):
+		ua.OS = "ChromeOS"
+	case strings.Contains(lower, "linux"):
+		ua.OS = "Linux"
+	}
+
+	switch {
+	case strings.Contains(lower, "ipad"), strings.Contains(lower, "tablet"),
+		ua.OS == "Android" && !strings.Contains(lower, "mobile"):
+		ua.Device = "tablet"
+	case strings.Contains(lower, "mobi"), strings.Contains(lower, "iphone"), strings.Contains(lower, "ipod"):
+		ua.Device = "mobile"
+	default:
+		ua.Device = "desktop"
+	}
+
+	for _, b := range browsers {
+		i := strings.Index(lower, b.token)
+		if i < 0 || (b.token == "rv:" && !strings.Contains(lower, "trident/")) {
+			continue
+		}
+		if b.name == "Safari" && !strings.Contains(lower, "safari/") {
+			continue
+		}
+		ua.Browser = b.name
+		version := s[i+len(b.token):]
+		if end := strings.IndexAny(version, " ;)"); end >= 0 {
+			version = version[:end]
+		}
+		ua.BrowserVersion = version
+		break
+	}
+	return ua
+}
+
+// asciiLower lowercases the ASCII letters of s. Unlike strings.ToLower it
+// keeps the byte length of any input, including invalid UTF-8, so offsets
+// in the result are valid in s.
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+// botProduct returns the product token of a User-Agent containing word,
+// such as "ExampleBot" from "Mozilla/5.0 (compatible; ExampleBot/2.1)".
+func botProduct(s, lower, word string) string {
+	i := strings.Index(lower, word)
+	start := strings.LastIndexAny(s[:i], " ;(+") + 1
+	end := i + len(word)
+	if n := strings.IndexAny(s[end:], "/ ;)"); n >= 0 {
+		end += n
+	} else {
+		end = len(s)
+	}
+	return s[start:end]
+}
+
+// BotPolicy configures the BlockBots middleware.
+type BotPolicy struct {
+	// Allow lists the names of bots let through, such as "Slackbot",
+	// matched case-insensitively against UserAgent.BotName.
+	Allow []string
+
+	// AllowSearchEngines lets well-known search engine crawlers through.
+	AllowSearchEngines bool
+
+	// AllowEmpty lets requests without a User-Agent through, such as those
+	// of internal health checks.
+	AllowEmpty bool
+
+	// Skip exempts requests from the policy, such as those to /robots.txt.
+	Skip func(r *http.Request) bool
+}
+
+// BlockBots returns a middleware that rejects requests from bots, by
+// ParseUserAgent's classification, with a 403 problem unless the policy
+// allows them. It keeps honest scrapers out; clients that fake a browser
+// User-Agent need rate limiting instead.
+//
+// Example:
+//
+//	s.Use(middleware.BlockBots(middleware.BotPolicy{
+//	    AllowSearchEngines: true,
+//	    Allow:              []string{"Slackbot", "Twitterbot"},
+//	}))
+func BlockBots(policy BotPolicy) Middleware {
+	allowed := make(map[string]bool, len(policy.Allow))
+	for _, name := range policy.Allow {
+		allowed[strings.ToLower(name)] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if policy.Skip != nil && policy.Skip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ua := ParseUserAgent(r.UserAgent())
+			if !ua.Bot ||
+				(policy.AllowEmpty && strings.TrimSpace(r.UserAgent()) == "") ||
+				(ua.SearchEngine && policy.AllowSearchEngines) ||
+				allowed[strings.ToLower(ua.BotName)] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			writeProblem(w, r, http.StatusForbidden, "automated clients are not allowed", nil)
+		})
+	}
+}
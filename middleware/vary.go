@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// AddVary adds header names to the Vary header of h, keeping the names
+// already listed, by other middleware or the handler, and skipping
+// duplicates. A Vary of "*" is left alone. Caches key responses by the
+// request headers in Vary, so a response that depends on a header but
+// omits it can be served to the wrong client.
+func AddVary(h http.Header, names ...string) {
+	var listed []string
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name == "*" {
+				return
+			} else if name != "" {
+				listed = append(listed, name)
+			}
+		}
+	}
+
+	n := len(listed)
+	for _, name := range names {
+		if !containsFold(listed, name) {
+			listed = append(listed, name)
+		}
+	}
+	if len(listed) > n || len(h.Values("Vary")) > 1 {
+		h.Set("Vary", strings.Join(listed, ", "))
+	}
+}
+
+// containsFold reports whether list contains s, ignoring case.
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
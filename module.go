@@ -1,6 +1,12 @@
 package helix
 
-import "net/http"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+)
 
 // Module is an interface for modular route definitions.
 // Modules allow you to organize routes into separate files or packages
@@ -24,6 +30,31 @@ type Module interface {
 	Register(r RouteRegistrar)
 }
 
+// MiddlewareModule is implemented by modules that add server-wide
+// middleware when registered with Server.Register, such as an auth module
+// loading sessions. Middleware for the module's own routes belongs on a
+// group in Register.
+type MiddlewareModule interface {
+	Module
+	Middleware() []Middleware
+}
+
+// StartModule is implemented by modules with work to do before the server
+// accepts requests, such as running migrations or warming caches. An error
+// aborts the start.
+type StartModule interface {
+	Module
+	OnStart(ctx context.Context) error
+}
+
+// StopModule is implemented by modules with resources to release after the
+// server has stopped serving requests, such as connection pools. ctx has
+// the grace period as its deadline.
+type StopModule interface {
+	Module
+	OnStop(ctx context.Context) error
+}
+
 // ModuleFunc is a function that implements Module.
 type ModuleFunc func(r RouteRegistrar)
 
@@ -35,14 +66,14 @@ func (f ModuleFunc) Register(r RouteRegistrar) {
 // RouteRegistrar is an interface for registering routes.
 // Both Server and Group implement this interface.
 type RouteRegistrar interface {
-	GET(pattern string, handler http.HandlerFunc)
-	POST(pattern string, handler http.HandlerFunc)
-	PUT(pattern string, handler http.HandlerFunc)
-	PATCH(pattern string, handler http.HandlerFunc)
-	DELETE(pattern string, handler http.HandlerFunc)
-	OPTIONS(pattern string, handler http.HandlerFunc)
-	HEAD(pattern string, handler http.HandlerFunc)
-	Handle(method, pattern string, handler http.HandlerFunc)
+	GET(pattern string, handler http.HandlerFunc, opts ...RouteOption)
+	POST(pattern string, handler http.HandlerFunc, opts ...RouteOption)
+	PUT(pattern string, handler http.HandlerFunc, opts ...RouteOption)
+	PATCH(pattern string, handler http.HandlerFunc, opts ...RouteOption)
+	DELETE(pattern string, handler http.HandlerFunc, opts ...RouteOption)
+	OPTIONS(pattern string, handler http.HandlerFunc, opts ...RouteOption)
+	HEAD(pattern string, handler http.HandlerFunc, opts ...RouteOption)
+	Handle(method, pattern string, handler http.HandlerFunc, opts ...RouteOption)
 	Group(prefix string, mw ...any) *Group
 	Resource(pattern string, mw ...any) *ResourceBuilder
 }
@@ -69,3 +100,67 @@ func (s *Server) MountFunc(prefix string, fn func(r RouteRegistrar), mw ...any)
 func (g *Group) MountFunc(prefix string, fn func(r RouteRegistrar), mw ...any) {
 	g.Mount(prefix, ModuleFunc(fn), mw...)
 }
+
+// Register registers self-contained modules: their routes, added at the
+// root (the modules choose their own prefixes), the middleware of each
+// MiddlewareModule, and the lifecycle hooks of each StartModule and
+// StopModule. Modules are started in order when the server runs, after
+// the OnStart hooks, and stopped in reverse order at shutdown, after
+// requests and background tasks have finished. Must be called before the
+// server starts.
+//
+// Example:
+//
+//	type BillingModule struct{ db *sql.DB }
+//
+//	func (m *BillingModule) Register(r helix.RouteRegistrar) {
+//	    invoices := r.Group("/invoices")
+//	    invoices.GET("/", m.list)
+//	}
+//
+//	func (m *BillingModule) OnStart(ctx context.Context) error { return m.migrate(ctx) }
+//	func (m *BillingModule) OnStop(ctx context.Context) error  { return m.db.Close() }
+//
+//	s.Register(auth.NewModule(cfg), &BillingModule{db: db})
+func (s *Server) Register(modules ...Module) {
+	for _, m := range modules {
+		if mm, ok := m.(MiddlewareModule); ok {
+			for _, mw := range mm.Middleware() {
+				s.Use(mw)
+			}
+		}
+		m.Register(s)
+		s.modules = append(s.modules, m)
+	}
+}
+
+// startModules starts the registered modules in order. If one fails, the
+// modules already started are stopped.
+func (s *Server) startModules(ctx context.Context) error {
+	for _, m := range s.modules {
+		if sm, ok := m.(StartModule); ok {
+			if err := sm.OnStart(ctx); err != nil {
+				return errors.Join(
+					fmt.Errorf("helix: module %T failed to start: %w", m, err),
+					s.stopModules(ctx),
+				)
+			}
+		}
+		s.started = append(s.started, m)
+	}
+	return nil
+}
+
+// stopModules stops the started modules in reverse order.
+func (s *Server) stopModules(ctx context.Context) error {
+	var errs []error
+	for _, m := range slices.Backward(s.started) {
+		if sm, ok := m.(StopModule); ok {
+			if err := sm.OnStop(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("helix: module %T failed to stop: %w", m, err))
+			}
+		}
+	}
+	s.started = nil
+	return errors.Join(errs...)
+}
@@ -0,0 +1,334 @@
+package helix
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// MsgPackCodec encodes MessagePack in the application/msgpack and
+// application/x-msgpack content types. Values are converted through their
+// JSON representation, so json struct tags and json.Marshaler
+// implementations apply as they do for JSON bodies, and binary data
+// decodes into []byte fields.
+var MsgPackCodec Codec = msgpackCodec{}
+
+// msgpackCodec is MsgPackCodec.
+type msgpackCodec struct{}
+
+// ContentTypes implements Codec.
+func (msgpackCodec) ContentTypes() []string {
+	return []string{MIMEApplicationMsgPack, "application/x-msgpack"}
+}
+
+// Marshal implements Codec.
+func (msgpackCodec) Marshal(v any) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	generic, err := decodeJSONValue(b)
+	if err != nil {
+		return nil, err
+	}
+	return appendMsgPack(nil, generic)
+}
+
+// Unmarshal implements Codec.
+func (msgpackCodec) Unmarshal(data []byte, v any) error {
+	d := msgpackDecoder{data: data}
+	generic, err := d.value(0)
+	if err != nil {
+		return err
+	}
+	if d.pos != len(data) {
+		return errors.New("unexpected data after the MessagePack value")
+	}
+	b, err := json.Marshal(generic)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// appendMsgPack appends the MessagePack encoding of a generic JSON value.
+func appendMsgPack(b []byte, v any) ([]byte, error) {
+	switch x := v.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+	case bool:
+		if x {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case json.Number:
+		if i, err := strconv.ParseInt(string(x), 10, 64); err == nil {
+			return appendMsgPackInt(b, i), nil
+		}
+		if u, err := strconv.ParseUint(string(x), 10, 64); err == nil {
+			return binary.BigEndian.AppendUint64(append(b, 0xcf), u), nil
+		}
+		f, err := x.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(f)), nil
+	case string:
+		b = appendMsgPackHeader(b, len(x), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		return append(b, x...), nil
+	case []any:
+		b = appendMsgPackHeader(b, len(x), 0x90, 16, 0, 0xdc, 0xdd)
+		var err error
+		for _, elem := range x {
+			if b, err = appendMsgPack(b, elem); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]any:
+		b = appendMsgPackHeader(b, len(x), 0x80, 16, 0, 0xde, 0xdf)
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		var err error
+		for _, k := range keys {
+			b, _ = appendMsgPack(b, k)
+			if b, err = appendMsgPack(b, x[k]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("cannot encode %T as MessagePack", v)
+}
+
+// appendMsgPackInt appends the smallest encoding of i.
+func appendMsgPackInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0 && i < 128:
+		return append(b, byte(i))
+	case i >= -32 && i < 0:
+		return append(b, byte(i))
+	case i >= 0 && i <= math.MaxUint8:
+		return append(b, 0xcc, byte(i))
+	case i >= 0 && i <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(i))
+	case i >= 0 && i <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(i))
+	case i >= 0:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), uint64(i))
+	case i >= math.MinInt8:
+		return append(b, 0xd0, byte(i))
+	case i >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(i))
+	case i >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(i))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(i))
+}
+
+// appendMsgPackHeader appends the header of a string, array or map of n
+// elements: a fix format below fixMax, else the 8, 16 or 32-bit format. A
+// zero code8 means the type has no 8-bit format.
+func appendMsgPackHeader(b []byte, n int, fix byte, fixMax int, code8, code16, code32 byte) []byte {
+	switch {
+	case n < fixMax:
+		return append(b, fix|byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		return append(b, code8, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, code16), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, code32), uint32(n))
+}
+
+// msgpackMaxDepth bounds the nesting of decoded values.
+const msgpackMaxDepth = 1000
+
+// msgpackDecoder decodes MessagePack into generic JSON values.
+type msgpackDecoder struct {
+	data []byte
+	pos  int
+}
+
+// errMsgPackShort is returned for truncated MessagePack data.
+var errMsgPackShort = errors.New("unexpected end of MessagePack data")
+
+// next returns the next n bytes.
+func (d *msgpackDecoder) next(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.pos < n {
+		return nil, errMsgPackShort
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// uint reads a big-endian unsigned integer of size bytes.
+func (d *msgpackDecoder) uint(size int) (uint64, error) {
+	b, err := d.next(size)
+	if err != nil {
+		return 0, err
+	}
+	var u uint64
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+	return u, nil
+}
+
+// value decodes the next value.
+func (d *msgpackDecoder) value(depth int) (any, error) {
+	if depth > msgpackMaxDepth {
+		return nil, errors.New("MessagePack data is nested too deeply")
+	}
+	b, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	code := b[0]
+
+	switch {
+	case code <= 0x7f:
+		return json.Number(strconv.Itoa(int(code))), nil
+	case code >= 0xe0:
+		return json.Number(strconv.Itoa(int(int8(code)))), nil
+	case code&0xe0 == 0xa0:
+		return d.str(int(code & 0x1f))
+	case code&0xf0 == 0x90:
+		return d.array(int(code&0x0f), depth)
+	case code&0xf0 == 0x80:
+		return d.object(int(code&0x0f), depth)
+	}
+
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := d.uint(1 << (code - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		return json.Number(strconv.FormatUint(u, 10)), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (code - 0xd0)
+		u, err := d.uint(size)
+		if err != nil {
+			return nil, err
+		}
+		// Sign-extend from size bytes
+		shift := 64 - 8*size
+		return json.Number(strconv.FormatInt(int64(u<<shift)>>shift, 10)), nil
+	case 0xca:
+		u, err := d.uint(4)
+		if err != nil {
+			return nil, err
+		}
+		return floatNumber(float64(math.Float32frombits(uint32(u))))
+	case 0xcb:
+		u, err := d.uint(8)
+		if err != nil {
+			return nil, err
+		}
+		return floatNumber(math.Float64frombits(u))
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (code - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.str(int(n))
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.uint(1 << (code - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		raw, err := d.next(int(n))
+		if err != nil {
+			return nil, err
+		}
+		// Binary data decodes into []byte fields, which JSON encodes as base64
+		return base64.StdEncoding.EncodeToString(raw), nil
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (code - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.array(int(n), depth)
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (code - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.object(int(n), depth)
+	}
+	return nil, fmt.Errorf("unsupported MessagePack type 0x%02x", code)
+}
+
+// str decodes a string of n bytes.
+func (d *msgpackDecoder) str(n int) (any, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// array decodes an array of n elements.
+func (d *msgpackDecoder) array(n, depth int) (any, error) {
+	if n > len(d.data)-d.pos {
+		return nil, errMsgPackShort // each element takes at least a byte
+	}
+	arr := make([]any, n)
+	for i := range arr {
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		arr[i] = v
+	}
+	return arr, nil
+}
+
+// object decodes a map of n entries. Keys that are not strings are
+// converted to their JSON text.
+func (d *msgpackDecoder) object(n, depth int) (any, error) {
+	if n > (len(d.data)-d.pos)/2 {
+		return nil, errMsgPackShort // each entry takes at least two bytes
+	}
+	obj := make(map[string]any, n)
+	for range n {
+		k, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			key = fmt.Sprint(k)
+		}
+		obj[key] = v
+	}
+	return obj, nil
+}
+
+// floatNumber converts a decoded float to a JSON number.
+func floatNumber(f float64) (any, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, errors.New("MessagePack float is not representable in JSON")
+	}
+	return json.Number(strconv.FormatFloat(f, 'g', -1, 64)), nil
+}
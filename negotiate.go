@@ -0,0 +1,225 @@
+package helix
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// acceptSpec is a single entry of an Accept-style header.
+type acceptSpec struct {
+	value string
+	q     float64
+}
+
+// parseAccept parses an Accept-style header into its entries, ordered by
+// descending quality. Entries keep their header order on equal quality.
+// Entries with an invalid q-value are skipped.
+func parseAccept(header string) []acceptSpec {
+	if header == "" {
+		return nil
+	}
+
+	var specs []acceptSpec
+	for _, part := range strings.Split(header, ",") {
+		value, params, _ := strings.Cut(part, ";")
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		q := 1.0
+		valid := true
+		for _, param := range strings.Split(params, ";") {
+			name, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if !strings.EqualFold(name, "q") {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				valid = false
+				break
+			}
+			q = parsed
+		}
+		if valid {
+			specs = append(specs, acceptSpec{value, q})
+		}
+	}
+
+	sort.SliceStable(specs, func(i, j int) bool { return specs[i].q > specs[j].q })
+	return specs
+}
+
+// negotiate returns the offer with the highest quality according to specs.
+// match returns the specificity of a spec for an offer, or -1 if the spec
+// does not apply; the most specific spec determines an offer's quality.
+// Ties go to the earlier offer. If header is empty, the first offer wins.
+func negotiate(header string, offers []string, normalize func(string) string, match func(spec, offer string) int) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	if header == "" {
+		return offers[0]
+	}
+
+	specs := parseAccept(header)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		normalized := normalize(offer)
+		q, specificity := 0.0, -1
+		for _, spec := range specs {
+			if s := match(strings.ToLower(spec.value), normalized); s > specificity {
+				q, specificity = spec.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// mimeShorthands maps common format names to media types for Accepts.
+var mimeShorthands = map[string]string{
+	"json":    MIMEApplicationJSON,
+	"html":    MIMETextHTML,
+	"xml":     MIMEApplicationXML,
+	"text":    MIMETextPlain,
+	"csv":     MIMETextCSV,
+	"js":      MIMEApplicationJavaScript,
+	"css":     MIMETextCSS,
+	"problem": MIMEApplicationProblemJSON,
+	"msgpack": MIMEApplicationMsgPack,
+	"form":    MIMEApplicationForm,
+}
+
+// normalizeMediaType expands a shorthand like "json" to its media type.
+func normalizeMediaType(offer string) string {
+	offer = strings.ToLower(offer)
+	if strings.Contains(offer, "/") {
+		return offer
+	}
+	if t, ok := mimeShorthands[offer]; ok {
+		return t
+	}
+	if t := mime.TypeByExtension("." + offer); t != "" {
+		t, _, _ = strings.Cut(t, ";")
+		return t
+	}
+	return offer
+}
+
+// matchMediaType matches media ranges such as "*/*" and "text/*".
+func matchMediaType(spec, offer string) int {
+	switch {
+	case spec == offer:
+		return 2
+	case spec == "*/*":
+		return 0
+	case strings.HasSuffix(spec, "/*"):
+		if strings.HasPrefix(offer, strings.TrimSuffix(spec, "*")) {
+			return 1
+		}
+	}
+	return -1
+}
+
+// matchEncoding matches content codings, including "*".
+func matchEncoding(spec, offer string) int {
+	switch spec {
+	case offer:
+		return 1
+	case "*":
+		return 0
+	}
+	return -1
+}
+
+// matchLanguage matches language ranges by prefix, so "en" matches "en-US".
+// A regional range also matches its base language, with lower precedence.
+func matchLanguage(spec, offer string) int {
+	switch {
+	case spec == offer:
+		return 3
+	case strings.HasPrefix(offer, spec+"-"):
+		return 2
+	case strings.HasPrefix(spec, offer+"-"):
+		return 1
+	case spec == "*":
+		return 0
+	}
+	return -1
+}
+
+// Accepts returns the best of the offered content types for the request's
+// Accept header, or "" if none is acceptable. Offers may be media types or
+// shorthands such as "json", "html", "xml" and "text". If the request has
+// no Accept header, the first offer is returned.
+//
+// Example:
+//
+//	switch helix.Accepts(r, "json", "html") {
+//	case "html":
+//	    helix.HTML(w, http.StatusOK, page)
+//	default:
+//	    helix.OK(w, data)
+//	}
+func Accepts(r *http.Request, offers ...string) string {
+	return negotiate(r.Header.Get("Accept"), offers, normalizeMediaType, matchMediaType)
+}
+
+// AcceptsEncodings returns the best of the offered content codings for the
+// request's Accept-Encoding header, or "" if none is acceptable.
+// "identity" is acceptable unless explicitly refused.
+func AcceptsEncodings(r *http.Request, offers ...string) string {
+	header := r.Header.Get("Accept-Encoding")
+	if best := negotiate(header, offers, strings.ToLower, matchEncoding); best != "" {
+		return best
+	}
+	for _, offer := range offers {
+		if strings.EqualFold(offer, "identity") && !identityRefused(header) {
+			return offer
+		}
+	}
+	return ""
+}
+
+// identityRefused reports whether an Accept-Encoding header refuses the
+// identity coding with "identity;q=0", or with "*;q=0" and no identity entry.
+func identityRefused(header string) bool {
+	refused := false
+	for _, spec := range parseAccept(header) {
+		switch strings.ToLower(spec.value) {
+		case "identity":
+			return spec.q == 0
+		case "*":
+			refused = spec.q == 0
+		}
+	}
+	return refused
+}
+
+// AcceptsLanguages returns the best of the offered language tags for the
+// request's Accept-Language header, or "" if none is acceptable.
+func AcceptsLanguages(r *http.Request, offers ...string) string {
+	return negotiate(r.Header.Get("Accept-Language"), offers, strings.ToLower, matchLanguage)
+}
+
+// ContentType returns the media type of the request body without
+// parameters, lowercased, e.g. "application/json".
+// Returns "" if the request has no Content-Type header.
+func ContentType(r *http.Request) string {
+	header := r.Header.Get("Content-Type")
+	if header == "" {
+		return ""
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		mediaType, _, _ = strings.Cut(header, ";")
+		return strings.ToLower(strings.TrimSpace(mediaType))
+	}
+	return mediaType
+}
@@ -0,0 +1,121 @@
+package helix_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/kolosys/helix"
+)
+
+func requestWithHeader(name, value string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if value != "" {
+		req.Header.Set(name, value)
+	}
+	return req
+}
+
+func TestAccepts(t *testing.T) {
+	tests := []struct {
+		accept string
+		offers []string
+		want   string
+	}{
+		{"", []string{"json", "html"}, "json"},
+		{"text/html,application/xhtml+xml,*/*;q=0.8", []string{"json", "html"}, "html"},
+		{"application/json", []string{"html", "json"}, "json"},
+		{"text/*;q=0.5, application/json;q=0.9", []string{"text", "json"}, "json"},
+		{"text/*", []string{"json", "text/csv"}, "text/csv"},
+		{"image/png", []string{"json", "html"}, ""},
+		{"*/*;q=0.1, text/html;q=0", []string{"html", "json"}, "json"},
+		{"APPLICATION/JSON", []string{"application/json"}, "application/json"},
+	}
+
+	for _, tt := range tests {
+		got := Accepts(requestWithHeader("Accept", tt.accept), tt.offers...)
+		if got != tt.want {
+			t.Errorf("Accepts(%q, %v) = %q, want %q", tt.accept, tt.offers, got, tt.want)
+		}
+	}
+}
+
+func TestAcceptsEncodings(t *testing.T) {
+	tests := []struct {
+		header string
+		offers []string
+		want   string
+	}{
+		{"", []string{"gzip", "identity"}, "gzip"},
+		{"gzip, br;q=0.9", []string{"br", "gzip"}, "gzip"},
+		{"deflate", []string{"gzip", "identity"}, "identity"},
+		{"deflate, identity;q=0", []string{"gzip", "identity"}, ""},
+		{"*;q=0", []string{"gzip", "identity"}, ""},
+		{"*", []string{"br"}, "br"},
+	}
+
+	for _, tt := range tests {
+		got := AcceptsEncodings(requestWithHeader("Accept-Encoding", tt.header), tt.offers...)
+		if got != tt.want {
+			t.Errorf("AcceptsEncodings(%q, %v) = %q, want %q", tt.header, tt.offers, got, tt.want)
+		}
+	}
+}
+
+func TestAcceptsLanguages(t *testing.T) {
+	tests := []struct {
+		header string
+		offers []string
+		want   string
+	}{
+		{"", []string{"en", "de"}, "en"},
+		{"de-DE, en;q=0.5", []string{"en", "de"}, "de"},
+		{"en", []string{"de", "en-US"}, "en-US"},
+		{"fr;q=0.8, de;q=0.9", []string{"fr", "de"}, "de"},
+		{"ja", []string{"en", "de"}, ""},
+	}
+
+	for _, tt := range tests {
+		got := AcceptsLanguages(requestWithHeader("Accept-Language", tt.header), tt.offers...)
+		if got != tt.want {
+			t.Errorf("AcceptsLanguages(%q, %v) = %q, want %q", tt.header, tt.offers, got, tt.want)
+		}
+	}
+}
+
+func TestContentType(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"application/json", "application/json"},
+		{"Application/JSON; charset=utf-8", "application/json"},
+		{"multipart/form-data; boundary=abc", "multipart/form-data"},
+	}
+
+	for _, tt := range tests {
+		if got := ContentType(requestWithHeader("Content-Type", tt.header)); got != tt.want {
+			t.Errorf("ContentType(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCtx_Accepts(t *testing.T) {
+	s := New(nil)
+	s.GET("/report", HandleCtx(func(c *Ctx) error {
+		if c.Accepts("json", "html") == "html" {
+			return c.HTML(http.StatusOK, "<p>report</p>")
+		}
+		return c.OK(map[string]string{"report": "ok"})
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/report", nil)
+	req.Header.Set("Accept", "text/html")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	if rec.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Errorf("expected HTML response, got %q", rec.Header().Get("Content-Type"))
+	}
+}
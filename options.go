@@ -1,8 +1,10 @@
 package helix
 
 import (
+	"context"
 	"crypto/tls"
 	"net"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
@@ -35,6 +37,12 @@ type Options struct {
 	// Default is 30 seconds.
 	GracePeriod time.Duration
 
+	// ShutdownDelay is how long Shutdown waits after marking the server as
+	// draining before it stops accepting connections, giving load balancers
+	// time to observe the failing /readyz probe. It counts against GracePeriod.
+	// Default is 0 (no delay).
+	ShutdownDelay time.Duration
+
 	// TLSCertFile is the path to the TLS certificate file.
 	// If set along with TLSKeyFile, the server will use TLS.
 	TLSCertFile string
@@ -47,10 +55,74 @@ type Options struct {
 	// If set, TLSCertFile and TLSKeyFile are ignored.
 	TLSConfig *tls.Config
 
+	// H2C enables HTTP/2 over cleartext TCP (prior knowledge, no TLS).
+	// This is useful for gRPC-style internal traffic behind load balancers
+	// that don't terminate TLS. HTTP/1.1 continues to be served alongside it.
+	// Default is false.
+	H2C bool
+
+	// HTTP2 configures HTTP/2 settings such as max concurrent streams and
+	// frame sizes. It applies to both TLS (h2) and cleartext (h2c) HTTP/2.
+	// If nil, the net/http defaults are used.
+	HTTP2 *http.HTTP2Config
+
+	// EnableUpgrade enables zero-downtime binary upgrades triggered by SIGUSR2.
+	// On the signal, the server starts a new copy of the executable that
+	// inherits the listening socket, then drains its own connections once
+	// the new process is serving. A new process that fails to start serving
+	// is killed, and the server keeps serving.
+	// Not supported on Windows.
+	// Default is false.
+	EnableUpgrade bool
+
+	// EnableLogLevelToggle makes SIGHUP toggle the default logs logger
+	// between its configured level and debug, for production debugging
+	// without a restart.
+	// Default is false.
+	EnableLogLevelToggle bool
+
+	// Debug enables development mode: problem responses include the
+	// underlying error and stack trace, HTML requests get a readable error
+	// page, and panics are rendered with source snippets. Never enable it
+	// in production, as it exposes internals.
+	// Default is false.
+	Debug bool
+
+	// WarnRouteConflicts logs conflicting route registrations, such as a
+	// pattern registered twice, and keeps the first route, instead of
+	// panicking. Overlaps resolved by precedence, such as "/users/new" and
+	// "/users/{id}", are logged too. See Server.RouteConflicts.
+	// Default is false.
+	WarnRouteConflicts bool
+
 	// MaxHeaderBytes is the maximum size of request headers.
 	// Default is 0 (no limit).
 	MaxHeaderBytes int
 
+	// MaxConnections is the maximum number of concurrent connections.
+	// Connections beyond the limit receive a 503 response, or are reset
+	// when TLS is enabled.
+	// Default is 0 (no limit).
+	MaxConnections int
+
+	// MaxConnectionsPerIP is the maximum number of concurrent connections
+	// from a single client IP. Excess connections are rejected the same
+	// way as MaxConnections.
+	// Default is 0 (no limit).
+	MaxConnectionsPerIP int
+
+	// BaseContext returns the base context for incoming requests on the listener.
+	// Use it to inject server-wide dependencies (DB pools, loggers) into every
+	// request context. Mirrors http.Server.BaseContext.
+	// If nil, context.Background() is used.
+	BaseContext func(ln net.Listener) context.Context
+
+	// ConnContext modifies the context used for a new connection.
+	// Use it to attach per-connection values such as tenant info derived
+	// from the connection. Mirrors http.Server.ConnContext.
+	// If nil, the base context is used unchanged.
+	ConnContext func(ctx context.Context, c net.Conn) context.Context
+
 	// HideBanner hides the banner on startup.
 	// Default is false.
 	HideBanner bool
@@ -59,6 +131,19 @@ type Options struct {
 	// If set, HideBanner is ignored.
 	Banner string
 
+	// CookieSecret is the HMAC key used by Ctx.SetSignedCookie and
+	// Ctx.GetSignedCookie. It should be at least 32 random bytes.
+	// If not set, signed cookie helpers panic.
+	CookieSecret []byte
+
+	// ResponseEnvelope wraps the successful responses of typed handlers
+	// (Handle, HandleWithStatus, HandleNoRequest and resources), e.g. in
+	// {"data": ..., "meta": ...}. Groups can override it with
+	// Group.ResponseEnvelope, and handlers can opt out with Result.Raw.
+	// See DataEnvelope for a standard envelope.
+	// If nil, responses are encoded as returned.
+	ResponseEnvelope EnvelopeFunc
+
 	// ErrorHandler is a custom error handler for the server.
 	// If not set, the default error handling (RFC 7807 Problem Details) is used.
 	ErrorHandler ErrorHandler
@@ -78,6 +78,37 @@ type PaginatedResponse[T any] struct {
 	TotalPages int    `json:"total_pages"`
 	HasMore    bool   `json:"has_more"`
 	NextCursor string `json:"next_cursor,omitempty"`
+
+	// Links are the collection's page links. Typed handlers fill in self,
+	// first, prev, next and last from the request URL, keeping links set
+	// here, and send them as a Link header too.
+	Links Links `json:"_links,omitempty"`
+}
+
+// PageMeta is the pagination metadata of a PaginatedResponse, used as the
+// meta of DataEnvelope.
+type PageMeta struct {
+	Total      int    `json:"total"`
+	Page       int    `json:"page,omitempty"`
+	Limit      int    `json:"limit,omitempty"`
+	TotalPages int    `json:"total_pages,omitempty"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Links      Links  `json:"_links,omitempty"`
+}
+
+// EnvelopeData implements EnvelopeMeta, splitting the items from the
+// pagination metadata.
+func (p PaginatedResponse[T]) EnvelopeData() (data, meta any) {
+	return p.Items, PageMeta{
+		Total:      p.Total,
+		Page:       p.Page,
+		Limit:      p.Limit,
+		TotalPages: p.TotalPages,
+		HasMore:    p.HasMore,
+		NextCursor: p.NextCursor,
+		Links:      p.Links,
+	}
 }
 
 // NewPaginatedResponse creates a new paginated response.
@@ -0,0 +1,176 @@
+package helix
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ParamType constrains the values a path parameter matches. Use it in
+// patterns as {name:type}, e.g. "/users/{id:uuid}"; requests whose parameter
+// does not match fall through to other routes or a 404. Enumerations are
+// written inline as {name:enum(a|b|c)}; see Enum.
+type ParamType string
+
+// Built-in parameter types.
+const (
+	// Int matches decimal integers, e.g. "42" or "-7".
+	Int ParamType = "int"
+
+	// UUID matches UUIDs with or without hyphens.
+	UUID ParamType = "uuid"
+
+	// Alpha matches ASCII letters.
+	Alpha ParamType = "alpha"
+
+	// Alnum matches ASCII letters and digits.
+	Alnum ParamType = "alnum"
+
+	// Slug matches lowercase letters, digits and hyphens, e.g. "hello-world".
+	Slug ParamType = "slug"
+)
+
+var (
+	paramTypesMu sync.RWMutex
+	paramTypes   = map[ParamType]func(string) bool{
+		Int:   isInt,
+		UUID:  isValidUUID,
+		Alpha: isAlpha,
+		Alnum: isAlnum,
+		Slug:  isSlug,
+	}
+)
+
+// RegisterParamType registers a custom parameter type for use in route
+// patterns. Panics if name is empty or already registered.
+// Must be called before routes using the type are registered.
+//
+// Example:
+//
+//	helix.RegisterParamType("sku", func(s string) bool {
+//	    return len(s) == 8 && strings.HasPrefix(s, "SKU")
+//	})
+//	s.GET("/products/{sku:sku}", getProduct)
+func RegisterParamType(name ParamType, match func(string) bool) {
+	if name == "" {
+		panic("helix: parameter type name must not be empty")
+	}
+	if match == nil {
+		panic("helix: parameter type matcher must not be nil")
+	}
+
+	paramTypesMu.Lock()
+	defer paramTypesMu.Unlock()
+
+	if _, ok := paramTypes[name]; ok {
+		panic(fmt.Sprintf("helix: parameter type %q already registered", name))
+	}
+	paramTypes[name] = match
+}
+
+// Enum returns the parameter type matching exactly one of values, for
+// building patterns programmatically. It is equivalent to writing
+// {name:enum(a|b)} in the pattern. Panics if values is empty or a value
+// contains '|', '/', ')' or '}'.
+//
+// Example:
+//
+//	s.GET("/posts/{status:"+string(helix.Enum("draft", "published"))+"}", listPosts)
+func Enum(values ...string) ParamType {
+	if len(values) == 0 {
+		panic("helix: enum parameter type needs at least one value")
+	}
+	for _, v := range values {
+		if v == "" || strings.ContainsAny(v, "|/)}") {
+			panic(fmt.Sprintf("helix: invalid enum value %q", v))
+		}
+	}
+	return ParamType("enum(" + strings.Join(values, "|") + ")")
+}
+
+// EnumValues returns the values of an enum parameter type such as
+// "enum(a|b)", or nil if t is not an enum.
+func (t ParamType) EnumValues() []string {
+	s := string(t)
+	if !strings.HasPrefix(s, "enum(") || !strings.HasSuffix(s, ")") {
+		return nil
+	}
+	return strings.Split(s[len("enum("):len(s)-1], "|")
+}
+
+// enumMatcher returns a matcher accepting exactly the given values.
+func enumMatcher(values []string) func(string) bool {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		if v == "" {
+			panic("helix: enum parameter type has an empty value")
+		}
+		set[v] = struct{}{}
+	}
+	return func(s string) bool {
+		_, ok := set[s]
+		return ok
+	}
+}
+
+// lookupParamType returns the matcher for a parameter type, panicking if
+// the type is unknown.
+func lookupParamType(name ParamType) func(string) bool {
+	if values := name.EnumValues(); values != nil {
+		return enumMatcher(values)
+	}
+
+	paramTypesMu.RLock()
+	defer paramTypesMu.RUnlock()
+
+	match, ok := paramTypes[name]
+	if !ok {
+		panic(fmt.Sprintf("helix: unknown parameter type %q", name))
+	}
+	return match
+}
+
+func isInt(s string) bool {
+	if len(s) > 1 && s[0] == '-' {
+		s = s[1:]
+	}
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlpha(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isAlnum(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isSlug(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,513 @@
+package helix
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// PatchOperation is an operation of a JSON Patch document (RFC 6902).
+type PatchOperation struct {
+	// Op is one of "add", "remove", "replace", "move", "copy" or "test".
+	Op string `json:"op"`
+
+	// Path is the JSON Pointer (RFC 6901) the operation applies to.
+	Path string `json:"path"`
+
+	// From is the source JSON Pointer of "move" and "copy".
+	From string `json:"from,omitempty"`
+
+	// Value is the value of "add", "replace" and "test".
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// JSONPatch is a JSON Patch document (RFC 6902).
+type JSONPatch []PatchOperation
+
+// BindJSONPatch decodes and validates an application/json-patch+json
+// request body. Other content types fail with ErrUnsupportedMediaType and
+// malformed documents with ErrBadRequest.
+func BindJSONPatch(r *http.Request) (JSONPatch, error) {
+	if ct := ContentType(r); ct != MIMEApplicationJSONPatch {
+		return nil, ErrUnsupportedMediaType.WithDetailf("unsupported content type %q; supported: %s", ct, MIMEApplicationJSONPatch)
+	}
+	if r.Body == nil {
+		return nil, ErrInvalidJSON
+	}
+
+	var patch JSONPatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidJSON, err)
+	}
+	if err := patch.validate(); err != nil {
+		return nil, err
+	}
+	return patch, nil
+}
+
+// validate checks that each operation is well-formed.
+func (p JSONPatch) validate() error {
+	for i, op := range p {
+		invalid := func(format string, args ...any) error {
+			return ErrBadRequest.WithDetailf("patch operation %d: "+format, append([]any{i}, args...)...)
+		}
+		switch op.Op {
+		case "add", "replace", "test":
+			if op.Value == nil {
+				return invalid("%q requires a value", op.Op)
+			}
+		case "move", "copy":
+			if _, err := parsePointer(op.From); err != nil {
+				return invalid("from: %v", err)
+			}
+		case "remove":
+		default:
+			return invalid("unknown op %q", op.Op)
+		}
+		if _, err := parsePointer(op.Path); err != nil {
+			return invalid("path: %v", err)
+		}
+	}
+	return nil
+}
+
+// Apply applies the patch to the JSON document doc and returns the result.
+// The patch is atomic: if an operation fails, doc is left unchanged. A
+// failed "test" returns ErrConflict and a pointer to a missing value
+// ErrUnprocessableEntity.
+func (p JSONPatch) Apply(doc []byte) ([]byte, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	root, err := decodeJSONValue(doc)
+	if err != nil {
+		return nil, ErrUnprocessableEntity.WithDetailf("document is not valid JSON: %v", err)
+	}
+
+	for i, op := range p {
+		root, err = applyOperation(root, op)
+		if err != nil {
+			if problem, ok := ProblemFrom(err); ok {
+				return nil, problem.WithDetailf("patch operation %d (%s %s): %s", i, op.Op, op.Path, problem.Detail)
+			}
+			return nil, err
+		}
+	}
+	return json.Marshal(root)
+}
+
+// ApplyTo applies the patch to dst, a pointer to a value that round-trips
+// through JSON such as a struct, replacing its contents with the result.
+func (p JSONPatch) ApplyTo(dst any) error {
+	return applyToValue(dst, p.Apply)
+}
+
+// ApplyMergePatch applies the JSON Merge Patch (RFC 7396) patch to dst, a
+// pointer to a value that round-trips through JSON such as a struct. Members
+// set to null in patch are removed, that is, reset to their zero value.
+//
+// Example:
+//
+//	user := store.Get(id)
+//	body, _ := io.ReadAll(r.Body)
+//	if err := helix.ApplyMergePatch(&user, body); err != nil {
+//	    return err
+//	}
+func ApplyMergePatch(dst any, patch []byte) error {
+	patchValue, err := decodeJSONValue(patch)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidJSON, err)
+	}
+	return applyToValue(dst, func(doc []byte) ([]byte, error) {
+		target, err := decodeJSONValue(doc)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(mergePatch(target, patchValue))
+	})
+}
+
+// ApplyPatch applies the PATCH request body to dst according to its
+// Content-Type: a JSON Patch for application/json-patch+json, and a JSON
+// Merge Patch for application/merge-patch+json or application/json. Other
+// types fail with ErrUnsupportedMediaType.
+//
+// Example:
+//
+//	s.PATCH("/users/{id}", helix.HandleCtx(func(c *helix.Ctx) error {
+//	    user, err := store.Get(c.Param("id"))
+//	    if err != nil {
+//	        return err
+//	    }
+//	    if err := helix.ApplyPatch(c.Request, &user); err != nil {
+//	        return err
+//	    }
+//	    return c.OK(store.Save(user))
+//	}))
+func ApplyPatch(r *http.Request, dst any) error {
+	switch ct := ContentType(r); ct {
+	case MIMEApplicationJSONPatch:
+		patch, err := BindJSONPatch(r)
+		if err != nil {
+			return err
+		}
+		return patch.ApplyTo(dst)
+	case MIMEApplicationMergePatch, MIMEApplicationJSON:
+		if r.Body == nil {
+			return ErrInvalidJSON
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		return ApplyMergePatch(dst, body)
+	default:
+		return ErrUnsupportedMediaType.WithDetailf("unsupported content type %q; supported: %s, %s, %s",
+			ct, MIMEApplicationJSONPatch, MIMEApplicationMergePatch, MIMEApplicationJSON)
+	}
+}
+
+// applyToValue round-trips dst through JSON, transforming the document
+// with apply. dst is only modified if every step succeeds.
+func applyToValue(dst any, apply func(doc []byte) ([]byte, error)) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		panic("helix: patch destination must be a non-nil pointer")
+	}
+
+	doc, err := json.Marshal(dst)
+	if err != nil {
+		return fmt.Errorf("encode patch target: %w", err)
+	}
+	patched, err := apply(doc)
+	if err != nil {
+		return err
+	}
+
+	result := reflect.New(v.Elem().Type())
+	if err := json.Unmarshal(patched, result.Interface()); err != nil {
+		return ErrUnprocessableEntity.WithDetailf("patched document is invalid: %v", err).WithErr(err)
+	}
+	v.Elem().Set(result.Elem())
+	return nil
+}
+
+// mergePatch implements the MergePatch algorithm of RFC 7396.
+func mergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = make(map[string]any, len(p))
+	}
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+		} else {
+			t[key] = mergePatch(t[key], value)
+		}
+	}
+	return t
+}
+
+// decodeJSONValue decodes data into the generic JSON representation,
+// keeping numbers exact.
+func decodeJSONValue(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return v, nil
+}
+
+// applyOperation applies op to root, returning the new root.
+func applyOperation(root any, op PatchOperation) (any, error) {
+	path, _ := parsePointer(op.Path)
+
+	switch op.Op {
+	case "add", "replace":
+		value, err := decodeJSONValue(op.Value)
+		if err != nil {
+			return nil, ErrBadRequest.WithDetailf("invalid value: %v", err)
+		}
+		if op.Op == "add" {
+			return addValue(root, path, value)
+		}
+		if len(path) == 0 {
+			return value, nil
+		}
+		return updateParent(root, path, func(parent any, key string) (any, error) {
+			switch n := parent.(type) {
+			case map[string]any:
+				if _, ok := n[key]; !ok {
+					return nil, errMissing(key)
+				}
+				n[key] = value
+				return n, nil
+			case []any:
+				i, err := arrayIndex(key, len(n)-1)
+				if err != nil {
+					return nil, err
+				}
+				n[i] = value
+				return n, nil
+			}
+			return nil, errNotContainer()
+		})
+
+	case "remove":
+		return removeValue(root, path)
+
+	case "move", "copy":
+		from, _ := parsePointer(op.From)
+		value, err := getValue(root, from)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "copy" {
+			return addValue(root, path, deepCopyJSON(value))
+		}
+		if op.Path == op.From {
+			return root, nil
+		}
+		if strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, ErrUnprocessableEntity.WithDetail("cannot move a value into itself")
+		}
+		root, err = removeValue(root, from)
+		if err != nil {
+			return nil, err
+		}
+		return addValue(root, path, value)
+
+	case "test":
+		expected, err := decodeJSONValue(op.Value)
+		if err != nil {
+			return nil, ErrBadRequest.WithDetailf("invalid value: %v", err)
+		}
+		actual, err := getValue(root, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(actual, expected) {
+			return nil, ErrConflict.WithDetail("test failed")
+		}
+		return root, nil
+	}
+	return nil, ErrBadRequest.WithDetailf("unknown op %q", op.Op)
+}
+
+// addValue implements "add", inserting into arrays and setting members.
+func addValue(root any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return updateParent(root, path, func(parent any, key string) (any, error) {
+		switch n := parent.(type) {
+		case map[string]any:
+			n[key] = value
+			return n, nil
+		case []any:
+			if key == "-" {
+				return append(n, value), nil
+			}
+			i, err := arrayIndex(key, len(n))
+			if err != nil {
+				return nil, err
+			}
+			n = append(n, nil)
+			copy(n[i+1:], n[i:])
+			n[i] = value
+			return n, nil
+		}
+		return nil, errNotContainer()
+	})
+}
+
+// removeValue implements "remove".
+func removeValue(root any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, ErrUnprocessableEntity.WithDetail("cannot remove the document root")
+	}
+	return updateParent(root, path, func(parent any, key string) (any, error) {
+		switch n := parent.(type) {
+		case map[string]any:
+			if _, ok := n[key]; !ok {
+				return nil, errMissing(key)
+			}
+			delete(n, key)
+			return n, nil
+		case []any:
+			i, err := arrayIndex(key, len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			return append(n[:i], n[i+1:]...), nil
+		}
+		return nil, errNotContainer()
+	})
+}
+
+// getValue returns the value path points to.
+func getValue(node any, path []string) (any, error) {
+	for _, key := range path {
+		switch n := node.(type) {
+		case map[string]any:
+			v, ok := n[key]
+			if !ok {
+				return nil, errMissing(key)
+			}
+			node = v
+		case []any:
+			i, err := arrayIndex(key, len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = n[i]
+		default:
+			return nil, errNotContainer()
+		}
+	}
+	return node, nil
+}
+
+// updateParent calls fn with the container holding the last token of path
+// and that token, replacing the container with fn's result. Containers are
+// returned rather than mutated in place because arrays may be reallocated.
+func updateParent(node any, path []string, fn func(parent any, key string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return fn(node, path[0])
+	}
+	key := path[0]
+	switch n := node.(type) {
+	case map[string]any:
+		child, ok := n[key]
+		if !ok {
+			return nil, errMissing(key)
+		}
+		child, err := updateParent(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		n[key] = child
+		return n, nil
+	case []any:
+		i, err := arrayIndex(key, len(n)-1)
+		if err != nil {
+			return nil, err
+		}
+		child, err := updateParent(n[i], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		n[i] = child
+		return n, nil
+	}
+	return nil, errNotContainer()
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into unescaped tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("pointer %q must start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// arrayIndex parses an array index token, which must be at most last.
+func arrayIndex(token string, last int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, ErrUnprocessableEntity.WithDetailf("invalid array index %q", token)
+	}
+	if i > last {
+		return 0, ErrUnprocessableEntity.WithDetailf("array index %d is out of range", i)
+	}
+	return i, nil
+}
+
+// errMissing reports a pointer to a missing member.
+func errMissing(key string) error {
+	return ErrUnprocessableEntity.WithDetailf("member %q does not exist", key)
+}
+
+// errNotContainer reports a pointer through a value that is no object or array.
+func errNotContainer() error {
+	return ErrUnprocessableEntity.WithDetail("path does not point into an object or array")
+}
+
+// deepCopyJSON copies a generic JSON value.
+func deepCopyJSON(v any) any {
+	switch n := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(n))
+		for k, child := range n {
+			m[k] = deepCopyJSON(child)
+		}
+		return m
+	case []any:
+		s := make([]any, len(n))
+		for i, child := range n {
+			s[i] = deepCopyJSON(child)
+		}
+		return s
+	}
+	return v
+}
+
+// jsonEqual compares generic JSON values, treating numbers by value.
+func jsonEqual(a, b any) bool {
+	switch x := a.(type) {
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fx, errX := x.Float64()
+		fy, errY := y.Float64()
+		return errX == nil && errY == nil && fx == fy
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !jsonEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !jsonEqual(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
@@ -0,0 +1,111 @@
+package helix_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/kolosys/helix"
+)
+
+func TestJSONPatch_Apply(t *testing.T) {
+	doc := `{"name":"a","tags":["x","y"],"meta":{"n":1}}`
+
+	tests := []struct {
+		name    string
+		patch   JSONPatch
+		want    string
+		wantErr error
+	}{
+		{"add member", JSONPatch{{Op: "add", Path: "/email", Value: []byte(`"a@b.c"`)}},
+			`{"email":"a@b.c","meta":{"n":1},"name":"a","tags":["x","y"]}`, nil},
+		{"insert and append", JSONPatch{{Op: "add", Path: "/tags/0", Value: []byte(`"w"`)}, {Op: "add", Path: "/tags/-", Value: []byte(`"z"`)}},
+			`{"meta":{"n":1},"name":"a","tags":["w","x","y","z"]}`, nil},
+		{"replace and remove", JSONPatch{{Op: "replace", Path: "/name", Value: []byte(`"b"`)}, {Op: "remove", Path: "/tags/1"}},
+			`{"meta":{"n":1},"name":"b","tags":["x"]}`, nil},
+		{"move and copy", JSONPatch{{Op: "move", From: "/meta/n", Path: "/n"}, {Op: "copy", From: "/tags", Path: "/meta/tags"}},
+			`{"meta":{"tags":["x","y"]},"n":1,"name":"a","tags":["x","y"]}`, nil},
+		{"passing test", JSONPatch{{Op: "test", Path: "/meta/n", Value: []byte(`1.0`)}},
+			`{"meta":{"n":1},"name":"a","tags":["x","y"]}`, nil},
+		{"failing test", JSONPatch{{Op: "test", Path: "/name", Value: []byte(`"b"`)}}, "", ErrConflict},
+		{"missing member", JSONPatch{{Op: "replace", Path: "/missing", Value: []byte(`1`)}}, "", ErrUnprocessableEntity},
+		{"index out of range", JSONPatch{{Op: "remove", Path: "/tags/2"}}, "", ErrUnprocessableEntity},
+		{"unknown op", JSONPatch{{Op: "merge", Path: "/name"}}, "", ErrBadRequest},
+		{"missing value", JSONPatch{{Op: "add", Path: "/name"}}, "", ErrBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.patch.Apply([]byte(doc))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+type patchUser struct {
+	Name  string   `json:"name"`
+	Email string   `json:"email,omitempty"`
+	Tags  []string `json:"tags"`
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	user := patchUser{Name: "a", Email: "a@b.c", Tags: []string{"x"}}
+	if err := ApplyMergePatch(&user, []byte(`{"name":"b","email":null}`)); err != nil {
+		t.Fatal(err)
+	}
+	want := patchUser{Name: "b", Tags: []string{"x"}}
+	if !reflect.DeepEqual(user, want) {
+		t.Errorf("expected %+v, got %+v", want, user)
+	}
+
+	if err := ApplyMergePatch(&user, []byte(`{"name":1}`)); !errors.Is(err, ErrUnprocessableEntity) {
+		t.Errorf("expected ErrUnprocessableEntity for a mistyped member, got %v", err)
+	}
+	if !reflect.DeepEqual(user, want) {
+		t.Errorf("expected a failed patch to leave the value unchanged, got %+v", user)
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+		want        string
+		wantErr     error
+	}{
+		{MIMEApplicationJSONPatch, `[{"op":"replace","path":"/name","value":"b"}]`, "b", nil},
+		{MIMEApplicationMergePatch, `{"name":"c"}`, "c", nil},
+		{MIMEApplicationJSON, `{"name":"d"}`, "d", nil},
+		{MIMETextPlain, `name=e`, "", ErrUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("PATCH", "/users/1", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+
+		user := patchUser{Name: "a"}
+		err := ApplyPatch(req, &user)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: expected %v, got %v", tt.contentType, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.contentType, err)
+		} else if user.Name != tt.want {
+			t.Errorf("%s: expected name %q, got %q", tt.contentType, tt.want, user.Name)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package helix
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 )
@@ -46,6 +47,30 @@ func (p Problem) Error() string {
 	return p.Title
 }
 
+// Unwrap returns the error that caused the problem, for errors.Is and errors.As.
+func (p Problem) Unwrap() error {
+	return p.Err
+}
+
+// Is reports whether target is a Problem with the same status and type,
+// so errors.Is(err, helix.ErrNotFound) matches any not-found problem
+// regardless of its detail or instance.
+func (p Problem) Is(target error) bool {
+	t, ok := target.(Problem)
+	return ok && p.Status == t.Status && p.Type == t.Type
+}
+
+// ProblemFrom returns the first Problem in err's chain.
+// Returns false if the chain contains no Problem.
+func ProblemFrom(err error) (Problem, bool) {
+	var p Problem
+	if errors.As(err, &p) {
+		return p, true
+	}
+	return Problem{}, false
+}
+
+// WithDetail returns a copy of the Problem with the given detail message.
 func (p Problem) WithDetail(detail string) Problem {
 	newProblem := p
 	newProblem.Detail = detail
@@ -77,7 +102,7 @@ func (p Problem) WithType(problemType string) Problem {
 	return newProblem
 }
 
-// WithStack returns a copy of the Problem with the given stack trace.
+// WithErr returns a copy of the Problem with the given underlying error.
 func (p Problem) WithErr(err error) Problem {
 	newProblem := p
 	newProblem.Err = err
@@ -107,6 +132,12 @@ var (
 	// ErrGone represents a 410 Gone error.
 	ErrGone = NewProblem(http.StatusGone, "gone", "Gone")
 
+	// ErrPayloadTooLarge represents a 413 Payload Too Large error.
+	ErrPayloadTooLarge = NewProblem(http.StatusRequestEntityTooLarge, "payload_too_large", "Payload Too Large")
+
+	// ErrUnsupportedMediaType represents a 415 Unsupported Media Type error.
+	ErrUnsupportedMediaType = NewProblem(http.StatusUnsupportedMediaType, "unsupported_media_type", "Unsupported Media Type")
+
 	// ErrUnprocessableEntity represents a 422 Unprocessable Entity error.
 	ErrUnprocessableEntity = NewProblem(http.StatusUnprocessableEntity, "unprocessable_entity", "Unprocessable Entity")
 
@@ -241,10 +272,20 @@ type FieldError struct {
 	Message string `json:"message"`
 }
 
+// fieldFormat is the format and arguments of a message added with Addf,
+// kept so the message can be localized.
+type fieldFormat struct {
+	format string
+	args   []any
+}
+
 // ValidationErrors collects multiple validation errors for RFC 7807 response.
 // Implements the error interface and can be returned from Validate() methods.
 type ValidationErrors struct {
 	errors []FieldError
+
+	// formats parallels errors, with a zero fieldFormat for Add
+	formats []fieldFormat
 }
 
 // NewValidationErrors creates a new empty ValidationErrors collector.
@@ -257,14 +298,13 @@ func NewValidationErrors() *ValidationErrors {
 // Add adds a validation error for a specific field.
 func (v *ValidationErrors) Add(field, message string) {
 	v.errors = append(v.errors, FieldError{Field: field, Message: message})
+	v.formats = append(v.formats, fieldFormat{})
 }
 
 // Addf adds a validation error for a specific field with a formatted message.
 func (v *ValidationErrors) Addf(field, format string, args ...any) {
-	v.errors = append(v.errors, FieldError{
-		Field:   field,
-		Message: fmt.Sprintf(format, args...),
-	})
+	v.errors = append(v.errors, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	v.formats = append(v.formats, fieldFormat{format: format, args: args})
 }
 
 // HasErrors returns true if there are any validation errors.
@@ -306,13 +346,17 @@ func (v *ValidationErrors) Err() error {
 type ValidationProblem struct {
 	Problem
 	Errors []FieldError `json:"errors,omitempty"`
+
+	// formats parallels Errors, as in ValidationErrors
+	formats []fieldFormat
 }
 
 // ToProblem converts ValidationErrors to a ValidationProblem for RFC 7807 response.
 func (v *ValidationErrors) ToProblem() ValidationProblem {
 	return ValidationProblem{
-		Problem: ErrUnprocessableEntity.WithDetail("One or more validation errors occurred"),
+		Problem: ErrUnprocessableEntity.WithDetail(validationDetail),
 		Errors:  v.errors,
+		formats: v.formats,
 	}
 }
 
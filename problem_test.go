@@ -1,6 +1,10 @@
 package helix_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
@@ -167,3 +171,65 @@ func BenchmarkWriteProblem(b *testing.B) {
 		WriteProblem(rec, p)
 	}
 }
+
+func TestProblemUnwrap(t *testing.T) {
+	cause := errors.New("connection refused")
+	p := ErrServiceUnavailable.WithErr(cause)
+
+	if !errors.Is(p, cause) {
+		t.Error("expected errors.Is to find the cause")
+	}
+	if errors.Unwrap(p) != cause {
+		t.Error("expected Unwrap to return Err")
+	}
+}
+
+func TestProblemIs(t *testing.T) {
+	err := fmt.Errorf("loading user: %w", ErrNotFound.WithDetail("user 1 not found"))
+
+	if !errors.Is(err, ErrNotFound) {
+		t.Error("expected wrapped problem to match ErrNotFound")
+	}
+	if errors.Is(err, ErrConflict) {
+		t.Error("expected wrapped problem not to match ErrConflict")
+	}
+}
+
+func TestProblemFrom(t *testing.T) {
+	err := fmt.Errorf("outer: %w", fmt.Errorf("inner: %w", ErrForbidden.WithDetail("no access")))
+
+	p, ok := ProblemFrom(err)
+	if !ok || p.Status != http.StatusForbidden || p.Detail != "no access" {
+		t.Errorf("expected forbidden problem, got %+v, %v", p, ok)
+	}
+
+	if _, ok := ProblemFrom(errors.New("plain")); ok {
+		t.Error("expected no problem in a plain error")
+	}
+}
+
+func TestHandleErrorWrappedProblem(t *testing.T) {
+	s := New(nil)
+	s.GET("/", HandleNoRequest(func(ctx context.Context) (any, error) {
+		return nil, fmt.Errorf("handler: %w", ErrNotFound.WithDetail("gone fishing"))
+	}))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "gone fishing") {
+		t.Errorf("expected problem detail in body, got %s", rec.Body.String())
+	}
+}
+
+func TestFieldErrorComparable(t *testing.T) {
+	v := NewValidationErrors()
+	v.Addf("age", "must be at least %d", 18)
+	seen := map[FieldError]bool{v.Errors()[0]: true}
+	if !seen[FieldError{Field: "age", Message: "must be at least 18"}] {
+		t.Errorf("expected field errors to compare by field and message, got %v", v.Errors())
+	}
+}
@@ -0,0 +1,171 @@
+package helix
+
+import (
+	"net/http"
+	"strings"
+)
+
+// problemTypePrefix is the type URI prefix used by NewProblem.
+const problemTypePrefix = "about:blank#"
+
+// ProblemType documents a problem type in the server's problem catalog.
+type ProblemType struct {
+	// Code is the problem type identifier, e.g. "not_found".
+	Code string `json:"code"`
+
+	// Type is the resolved type URI. It is filled in by the catalog.
+	Type string `json:"type"`
+
+	// Status is the HTTP status code used for this problem type.
+	Status int `json:"status"`
+
+	// Title is the short, human-readable summary shared by all occurrences.
+	Title string `json:"title"`
+
+	// Description documents when the problem occurs and how clients should react.
+	Description string `json:"description,omitempty"`
+}
+
+// builtinProblemTypes documents the sentinel problems defined by helix.
+var builtinProblemTypes = []ProblemType{
+	{Code: "bad_request", Status: http.StatusBadRequest, Title: "Bad Request",
+		Description: "The request is malformed, e.g. the body is not valid JSON or a parameter has the wrong type."},
+	{Code: "unauthorized", Status: http.StatusUnauthorized, Title: "Unauthorized",
+		Description: "The request lacks valid authentication credentials."},
+	{Code: "forbidden", Status: http.StatusForbidden, Title: "Forbidden",
+		Description: "The credentials are valid but do not grant access to the resource."},
+	{Code: "not_found", Status: http.StatusNotFound, Title: "Not Found",
+		Description: "The requested resource does not exist."},
+	{Code: "method_not_allowed", Status: http.StatusMethodNotAllowed, Title: "Method Not Allowed",
+		Description: "The resource does not support the request method. See the Allow header."},
+	{Code: "conflict", Status: http.StatusConflict, Title: "Conflict",
+		Description: "The request conflicts with the current state of the resource."},
+	{Code: "gone", Status: http.StatusGone, Title: "Gone",
+		Description: "The resource existed but has been permanently removed."},
+	{Code: "payload_too_large", Status: http.StatusRequestEntityTooLarge, Title: "Payload Too Large",
+		Description: "The request body, or a part of a multipart upload, exceeds the size limit."},
+	{Code: "unsupported_media_type", Status: http.StatusUnsupportedMediaType, Title: "Unsupported Media Type",
+		Description: "The request body's Content-Type is not one the endpoint accepts."},
+	{Code: "unprocessable_entity", Status: http.StatusUnprocessableEntity, Title: "Unprocessable Entity",
+		Description: "The request is well-formed but failed validation. The errors member lists the offending fields."},
+	{Code: "too_many_requests", Status: http.StatusTooManyRequests, Title: "Too Many Requests",
+		Description: "The client exceeded a rate limit. Retry after the period given by the Retry-After header."},
+	{Code: "internal_error", Status: http.StatusInternalServerError, Title: "Internal Server Error",
+		Description: "The server failed to handle the request. The request can be retried."},
+	{Code: "not_implemented", Status: http.StatusNotImplemented, Title: "Not Implemented",
+		Description: "The server does not support the requested functionality."},
+	{Code: "bad_gateway", Status: http.StatusBadGateway, Title: "Bad Gateway",
+		Description: "An upstream service returned an invalid response."},
+	{Code: "service_unavailable", Status: http.StatusServiceUnavailable, Title: "Service Unavailable",
+		Description: "The server is temporarily unable to handle the request, e.g. during maintenance or shutdown."},
+	{Code: "gateway_timeout", Status: http.StatusGatewayTimeout, Title: "Gateway Timeout",
+		Description: "An upstream service did not respond in time."},
+}
+
+// SetProblemTypeBase sets the base URI for problem types. Problems created
+// with NewProblem, including the built-in sentinels, are written with a type
+// of base + code instead of "about:blank#" + code, so clients can dereference
+// the type to its documentation. Must be called before the server starts.
+//
+// Example:
+//
+//	s.SetProblemTypeBase("https://api.example.com/errors/")
+//	// ErrNotFound is written with "type": "https://api.example.com/errors/not_found"
+func (s *Server) SetProblemTypeBase(base string) {
+	s.errorConfig.typeBase = base
+}
+
+// RegisterProblemType adds a problem type to the server's catalog and
+// returns a Problem of that type. Panics if the code is empty, the status is
+// not an error status, or the code is already registered.
+//
+// Example:
+//
+//	var ErrInsufficientFunds = s.RegisterProblemType(helix.ProblemType{
+//	    Code:        "insufficient_funds",
+//	    Status:      http.StatusPaymentRequired,
+//	    Title:       "Insufficient Funds",
+//	    Description: "The account balance does not cover the transfer amount.",
+//	})
+func (s *Server) RegisterProblemType(pt ProblemType) Problem {
+	if pt.Code == "" {
+		panic("helix: problem type code must not be empty")
+	}
+	if pt.Status < 400 || pt.Status > 599 {
+		panic("helix: problem type " + pt.Code + " must have a 4xx or 5xx status")
+	}
+	if _, ok := s.lookupProblemType(pt.Code); ok {
+		panic("helix: problem type " + pt.Code + " already registered")
+	}
+	if pt.Title == "" {
+		pt.Title = http.StatusText(pt.Status)
+	}
+
+	s.problemTypes = append(s.problemTypes, pt)
+	return NewProblem(pt.Status, pt.Code, pt.Title)
+}
+
+// ProblemTypes returns the built-in and registered problem types with their
+// type URIs resolved against the base set by SetProblemTypeBase.
+func (s *Server) ProblemTypes() []ProblemType {
+	types := make([]ProblemType, 0, len(builtinProblemTypes)+len(s.problemTypes))
+	types = append(types, builtinProblemTypes...)
+	types = append(types, s.problemTypes...)
+	for i := range types {
+		types[i].Type = resolveProblemType(s.errorConfig.typeBase, problemTypePrefix+types[i].Code)
+	}
+	return types
+}
+
+// ProblemCatalogHandler returns a handler that serves the problem catalog as
+// JSON. If the route has a {code} parameter, only that problem type is
+// served, or a 404 if it is unknown. Mount it under the type base so that
+// problem type URIs resolve to their documentation.
+//
+// Example:
+//
+//	s.SetProblemTypeBase("https://api.example.com/errors/")
+//	s.GET("/errors", s.ProblemCatalogHandler())
+//	s.GET("/errors/{code}", s.ProblemCatalogHandler())
+func (s *Server) ProblemCatalogHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		code := Param(r, "code")
+		if code == "" {
+			OK(w, s.ProblemTypes())
+			return
+		}
+
+		pt, ok := s.lookupProblemType(code)
+		if !ok {
+			WriteProblem(w, ErrNotFound.WithDetailf("unknown problem type %q", code))
+			return
+		}
+		pt.Type = resolveProblemType(s.errorConfig.typeBase, problemTypePrefix+pt.Code)
+		OK(w, pt)
+	}
+}
+
+// lookupProblemType finds a built-in or registered problem type by code.
+func (s *Server) lookupProblemType(code string) (ProblemType, bool) {
+	for _, types := range [][]ProblemType{builtinProblemTypes, s.problemTypes} {
+		for _, pt := range types {
+			if pt.Code == code {
+				return pt, true
+			}
+		}
+	}
+	return ProblemType{}, false
+}
+
+// resolveProblemType rewrites an "about:blank#code" type URI against base.
+// Other type URIs, and all types when base is empty, are returned unchanged.
+func resolveProblemType(base, problemType string) string {
+	if base == "" {
+		return problemType
+	}
+	code, ok := strings.CutPrefix(problemType, problemTypePrefix)
+	if !ok || code == "" {
+		return problemType
+	}
+	return base + code
+}
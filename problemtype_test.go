@@ -0,0 +1,115 @@
+package helix_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/kolosys/helix"
+)
+
+func TestSetProblemTypeBase(t *testing.T) {
+	s := New(nil)
+	s.SetProblemTypeBase("https://api.example.com/errors/")
+	s.GET("/missing", HandleCtx(func(c *Ctx) error {
+		return ErrNotFound
+	}))
+	s.GET("/custom", HandleCtx(func(c *Ctx) error {
+		return c.Problem(ErrConflict.WithType("https://other.example.com/conflict"))
+	}))
+	s.GET("/invalid", HandleCtx(func(c *Ctx) error {
+		v := NewValidationErrors()
+		v.Add("name", "is required")
+		return v
+	}))
+
+	tests := []struct {
+		path     string
+		wantType string
+	}{
+		{"/missing", "https://api.example.com/errors/not_found"},
+		{"/custom", "https://other.example.com/conflict"},
+		{"/invalid", "https://api.example.com/errors/unprocessable_entity"},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		var p Problem
+		if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+			t.Fatalf("%s: failed to decode problem: %v", tt.path, err)
+		}
+		if p.Type != tt.wantType {
+			t.Errorf("%s: expected type %q, got %q", tt.path, tt.wantType, p.Type)
+		}
+	}
+}
+
+func TestRegisterProblemType(t *testing.T) {
+	s := New(nil)
+	errFunds := s.RegisterProblemType(ProblemType{
+		Code:        "insufficient_funds",
+		Status:      http.StatusPaymentRequired,
+		Title:       "Insufficient Funds",
+		Description: "The balance does not cover the amount.",
+	})
+
+	if errFunds.Status != http.StatusPaymentRequired || errFunds.Type != "about:blank#insufficient_funds" {
+		t.Errorf("unexpected problem: %+v", errFunds)
+	}
+
+	var found bool
+	for _, pt := range s.ProblemTypes() {
+		if pt.Code == "insufficient_funds" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected registered type in catalog")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for duplicate code")
+		}
+	}()
+	s.RegisterProblemType(ProblemType{Code: "not_found", Status: http.StatusNotFound})
+}
+
+func TestProblemCatalogHandler(t *testing.T) {
+	s := New(nil)
+	s.SetProblemTypeBase("https://api.example.com/errors/")
+	s.RegisterProblemType(ProblemType{Code: "quota_exceeded", Status: http.StatusForbidden})
+	s.GET("/errors", s.ProblemCatalogHandler())
+	s.GET("/errors/{code}", s.ProblemCatalogHandler())
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/errors", nil))
+
+	var types []ProblemType
+	if err := json.Unmarshal(rec.Body.Bytes(), &types); err != nil {
+		t.Fatalf("failed to decode catalog: %v", err)
+	}
+	if len(types) < 2 || types[0].Type != "https://api.example.com/errors/"+types[0].Code {
+		t.Errorf("unexpected catalog: %+v", types)
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/errors/quota_exceeded", nil))
+
+	var pt ProblemType
+	if err := json.Unmarshal(rec.Body.Bytes(), &pt); err != nil {
+		t.Fatalf("failed to decode problem type: %v", err)
+	}
+	if pt.Title != "Forbidden" || pt.Type != "https://api.example.com/errors/quota_exceeded" {
+		t.Errorf("unexpected problem type: %+v", pt)
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/errors/unknown", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+}
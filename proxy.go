@@ -0,0 +1,326 @@
+package helix
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// errNoUpstream is returned by the proxy transport when every upstream's
+// circuit is open.
+var errNoUpstream = errors.New("helix: no upstream available")
+
+// ProxyConfig configures the reverse proxy.
+type ProxyConfig struct {
+	// Targets are the upstream base URLs, e.g. "http://users:8080/api".
+	// Requests are balanced over them round-robin. Required.
+	Targets []string
+
+	// StripPrefix is removed from the request path before it is forwarded,
+	// e.g. "/users" forwards /users/42 as /42.
+	// Default: ""
+	StripPrefix string
+
+	// Rewrite rewrites the request path after StripPrefix, before it is
+	// joined with the target's path. The path is decoded, so escapes such
+	// as %2F are not kept in rewritten paths.
+	// Default: nil
+	Rewrite func(path string) string
+
+	// PreserveHost forwards the inbound Host header instead of the target's host.
+	// Default: false
+	PreserveHost bool
+
+	// SetHeaders are set on every forwarded request.
+	// Default: nil
+	SetHeaders map[string]string
+
+	// RemoveHeaders are removed from every forwarded request, e.g. "Cookie".
+	// Default: nil
+	RemoveHeaders []string
+
+	// Retries is the number of times a failed request is retried on the next
+	// upstream. Only requests without a body and with an idempotent method
+	// (GET, HEAD, OPTIONS) are retried. A failure is a transport error or a
+	// 502, 503 or 504 response. Proxy and DefaultProxyConfig use 2.
+	// Default: 0
+	Retries int
+
+	// FailureThreshold is the number of consecutive failures after which an
+	// upstream's circuit opens and it stops receiving requests.
+	// Default: 5
+	FailureThreshold int
+
+	// Cooldown is how long an open circuit stays open before the upstream
+	// is tried again.
+	// Default: 30s
+	Cooldown time.Duration
+
+	// Transport performs the upstream requests.
+	// Default: http.DefaultTransport
+	Transport http.RoundTripper
+
+	// ModifyResponse, if set, modifies upstream responses.
+	// Default: nil
+	ModifyResponse func(*http.Response) error
+
+	// FlushInterval is the flush interval for streaming responses.
+	// A negative value flushes after every write.
+	// Default: 0
+	FlushInterval time.Duration
+}
+
+// DefaultProxyConfig returns the default proxy configuration.
+func DefaultProxyConfig() ProxyConfig {
+	return ProxyConfig{
+		Retries:          2,
+		FailureThreshold: 5,
+		Cooldown:         30 * time.Second,
+	}
+}
+
+// Proxy returns a handler that forwards requests to target using the
+// default configuration. Panics if target is not a valid URL.
+//
+// Example:
+//
+//	s.Any("/api/{path...}", helix.Proxy("http://backend:8080"))
+func Proxy(target string) http.HandlerFunc {
+	config := DefaultProxyConfig()
+	config.Targets = []string{target}
+	return ProxyWithConfig(config)
+}
+
+// ProxyWithConfig returns a reverse proxy handler built on
+// httputil.ReverseProxy, balancing requests over config.Targets with
+// retries and per-upstream circuit breaking. X-Forwarded-For,
+// X-Forwarded-Host and X-Forwarded-Proto are set on forwarded requests.
+// If no upstream is available the client receives a 503 problem, and if
+// the upstream fails a 502 problem. Panics if a target is not a valid URL.
+//
+// Example:
+//
+//	s.Any("/users/{path...}", helix.ProxyWithConfig(helix.ProxyConfig{
+//	    Targets:       []string{"http://users-1:8080", "http://users-2:8080"},
+//	    StripPrefix:   "/users",
+//	    RemoveHeaders: []string{"Cookie"},
+//	    Retries:       2,
+//	}))
+func ProxyWithConfig(config ProxyConfig) http.HandlerFunc {
+	if len(config.Targets) == 0 {
+		panic("helix: proxy requires at least one target")
+	}
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 5
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = 30 * time.Second
+	}
+	if config.Transport == nil {
+		config.Transport = http.DefaultTransport
+	}
+
+	transport := &proxyTransport{
+		base:      config.Transport,
+		retries:   config.Retries,
+		threshold: config.FailureThreshold,
+		cooldown:  config.Cooldown,
+	}
+	for _, target := range config.Targets {
+		u, err := url.Parse(target)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			panic("helix: invalid proxy target " + target)
+		}
+		transport.upstreams = append(transport.upstreams, &upstream{url: u})
+	}
+
+	stripPrefix := (&url.URL{Path: config.StripPrefix}).EscapedPath()
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			// Escaped paths are forwarded as received, so an escaped
+			// slash (%2F) is not turned into a path separator
+			rawPath := strings.TrimPrefix(pr.In.URL.EscapedPath(), stripPrefix)
+			if !strings.HasPrefix(rawPath, "/") {
+				rawPath = "/" + rawPath
+			}
+			path, err := url.PathUnescape(rawPath)
+			if err != nil {
+				path = rawPath
+			}
+			if config.Rewrite != nil {
+				path, rawPath = config.Rewrite(path), ""
+			}
+			pr.Out.URL.Path = path
+			pr.Out.URL.RawPath = rawPath
+
+			pr.SetXForwarded()
+			if config.PreserveHost {
+				pr.Out.Host = pr.In.Host
+			} else {
+				pr.Out.Host = ""
+			}
+			for name, value := range config.SetHeaders {
+				pr.Out.Header.Set(name, value)
+			}
+			for _, name := range config.RemoveHeaders {
+				pr.Out.Header.Del(name)
+			}
+		},
+		Transport:      transport,
+		ModifyResponse: config.ModifyResponse,
+		FlushInterval:  config.FlushInterval,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if errors.Is(err, errNoUpstream) {
+				handleError(w, r, ErrServiceUnavailable)
+				return
+			}
+			handleError(w, r, ErrBadGateway)
+		},
+	}
+
+	return proxy.ServeHTTP
+}
+
+// upstream is a proxy target with its circuit breaker state.
+type upstream struct {
+	url *url.URL
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+// available reports whether the upstream's circuit is closed, or open but
+// past its cooldown.
+func (u *upstream) available(now time.Time) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return !now.Before(u.openUntil)
+}
+
+// record updates the circuit breaker with the outcome of a request.
+func (u *upstream) record(failed bool, threshold int, cooldown time.Duration) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if !failed {
+		u.failures = 0
+		return
+	}
+	u.failures++
+	if u.failures >= threshold {
+		u.openUntil = time.Now().Add(cooldown)
+		u.failures = 0
+	}
+}
+
+// proxyTransport balances requests over upstreams with retries.
+type proxyTransport struct {
+	base      http.RoundTripper
+	upstreams []*upstream
+	next      atomic.Uint64
+	retries   int
+	threshold int
+	cooldown  time.Duration
+}
+
+// pick returns the next available upstream in round-robin order, or nil.
+func (t *proxyTransport) pick() *upstream {
+	now := time.Now()
+	start := t.next.Add(1) - 1
+	for i := range t.upstreams {
+		u := t.upstreams[(start+uint64(i))%uint64(len(t.upstreams))]
+		if u.available(now) {
+			return u
+		}
+	}
+	return nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *proxyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	attempts := 1
+	if retryable(req) {
+		attempts += t.retries
+	}
+
+	var lastErr error
+	for i := 0; i < attempts; i++ {
+		u := t.pick()
+		if u == nil {
+			if lastErr == nil {
+				lastErr = errNoUpstream
+			}
+			return nil, lastErr
+		}
+
+		resp, err := t.base.RoundTrip(upstreamRequest(req, u.url))
+		if err != nil {
+			u.record(true, t.threshold, t.cooldown)
+			lastErr = err
+			if req.Context().Err() != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		failed := resp.StatusCode == http.StatusBadGateway ||
+			resp.StatusCode == http.StatusServiceUnavailable ||
+			resp.StatusCode == http.StatusGatewayTimeout
+		u.record(failed, t.threshold, t.cooldown)
+		if failed && i < attempts-1 {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			continue
+		}
+		return resp, nil
+	}
+	return nil, lastErr
+}
+
+// retryable reports whether req can be sent again safely.
+func retryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return req.Body == nil || req.Body == http.NoBody
+	}
+	return false
+}
+
+// upstreamRequest returns a copy of req addressed to target.
+func upstreamRequest(req *http.Request, target *url.URL) *http.Request {
+	out := req.Clone(req.Context())
+	out.URL.Scheme = target.Scheme
+	out.URL.Host = target.Host
+	out.URL.Path = joinURLPath(target.Path, req.URL.Path)
+	if req.URL.RawPath != "" {
+		out.URL.RawPath = joinURLPath(target.EscapedPath(), req.URL.RawPath)
+	}
+	if target.RawQuery != "" {
+		if out.URL.RawQuery == "" {
+			out.URL.RawQuery = target.RawQuery
+		} else {
+			out.URL.RawQuery = target.RawQuery + "&" + out.URL.RawQuery
+		}
+	}
+	return out
+}
+
+// joinURLPath joins a target base path and a request path with one slash.
+func joinURLPath(base, path string) string {
+	switch {
+	case base == "":
+		return path
+	case strings.HasSuffix(base, "/") && strings.HasPrefix(path, "/"):
+		return base + path[1:]
+	case !strings.HasSuffix(base, "/") && !strings.HasPrefix(path, "/"):
+		return base + "/" + path
+	}
+	return base + path
+}
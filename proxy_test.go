@@ -0,0 +1,130 @@
+package helix_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/kolosys/helix"
+)
+
+func TestProxy(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Path", r.URL.Path)
+		w.Header().Set("X-Raw-Path", r.URL.EscapedPath())
+		w.Header().Set("X-Host", r.Host)
+		w.Header().Set("X-Forwarded", r.Header.Get("X-Forwarded-Host"))
+		w.Header().Set("X-Cookie", r.Header.Get("Cookie"))
+		w.Header().Set("X-Gateway", r.Header.Get("X-Gateway"))
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer backend.Close()
+
+	s := New(nil)
+	s.Any("/users/{path...}", ProxyWithConfig(ProxyConfig{
+		Targets:       []string{backend.URL + "/api"},
+		StripPrefix:   "/users",
+		SetHeaders:    map[string]string{"X-Gateway": "helix"},
+		RemoveHeaders: []string{"Cookie"},
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/users/42", nil)
+	req.Header.Set("Cookie", "session=secret")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusTeapot {
+		t.Fatalf("expected status 418, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("X-Path"); got != "/api/42" {
+		t.Errorf("expected path /api/42, got %q", got)
+	}
+	if got := rec.Header().Get("X-Host"); got == "example.com" {
+		t.Error("expected target host, got inbound host")
+	}
+	if got := rec.Header().Get("X-Forwarded"); got != "example.com" {
+		t.Errorf("expected X-Forwarded-Host example.com, got %q", got)
+	}
+	if got := rec.Header().Get("X-Cookie"); got != "" {
+		t.Errorf("expected Cookie to be removed, got %q", got)
+	}
+	if got := rec.Header().Get("X-Gateway"); got != "helix" {
+		t.Errorf("expected X-Gateway helix, got %q", got)
+	}
+
+	// Escaped slashes are forwarded escaped
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://example.com/users/files/a%2Fb", nil))
+	if got := rec.Header().Get("X-Raw-Path"); got != "/api/files/a%2Fb" {
+		t.Errorf("expected path /api/files/a%%2Fb, got %q", got)
+	}
+}
+
+func TestProxy_RetriesAndBalancing(t *testing.T) {
+	var healthyHits atomic.Int32
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		healthyHits.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer healthy.Close()
+
+	config := DefaultProxyConfig()
+	config.Targets = []string{failing.URL, healthy.URL}
+	handler := ProxyWithConfig(config)
+
+	for i := 0; i < 4; i++ {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("request %d: expected retry to reach healthy upstream, got %d", i, rec.Code)
+		}
+	}
+	if healthyHits.Load() != 4 {
+		t.Errorf("expected 4 hits on healthy upstream, got %d", healthyHits.Load())
+	}
+}
+
+func TestProxy_CircuitBreaker(t *testing.T) {
+	var hits atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer backend.Close()
+
+	handler := ProxyWithConfig(ProxyConfig{
+		Targets:          []string{backend.URL},
+		FailureThreshold: 2,
+	})
+
+	codes := make([]int, 3)
+	for i := range codes {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		codes[i] = rec.Code
+	}
+
+	if codes[0] != http.StatusBadGateway || codes[1] != http.StatusBadGateway {
+		t.Errorf("expected upstream 502s before the circuit opens, got %v", codes)
+	}
+	if codes[2] != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 once the circuit is open, got %d", codes[2])
+	}
+	if hits.Load() != 2 {
+		t.Errorf("expected 2 upstream hits, got %d", hits.Load())
+	}
+}
+
+func TestProxy_InvalidTargetPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for invalid target")
+		}
+	}()
+	Proxy("not a url")
+}
@@ -0,0 +1,198 @@
+// Package quic serves a Helix server over HTTP/3 (QUIC) alongside its TCP listeners.
+//
+// It lives in its own module so the core framework stays dependency-free.
+//
+// Example:
+//
+//	s := helix.New(&helix.Options{
+//	    Addr:        ":443",
+//	    TLSCertFile: "cert.pem",
+//	    TLSKeyFile:  "key.pem",
+//	})
+//
+//	quic.Enable(s, quic.Config{
+//	    CertFile: "cert.pem",
+//	    KeyFile:  "key.pem",
+//	})
+//
+//	s.Start()
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/kolosys/helix"
+	quicgo "github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// Config configures the HTTP/3 listener.
+type Config struct {
+	// Addr is the UDP address to listen on.
+	// Default: the server's TCP address.
+	Addr string
+
+	// TLSConfig is the TLS configuration for QUIC connections.
+	// If set, CertFile and KeyFile are ignored.
+	TLSConfig *tls.Config
+
+	// CertFile is the path to the TLS certificate file.
+	CertFile string
+
+	// KeyFile is the path to the TLS key file.
+	KeyFile string
+
+	// QUICConfig holds QUIC transport parameters.
+	// If nil, quic-go defaults are used.
+	QUICConfig *quicgo.Config
+
+	// Port overrides the port advertised in the Alt-Svc header.
+	// Useful when a firewall redirects UDP traffic to a different port.
+	// Default: the port the listener is bound to.
+	Port int
+
+	// DisableAltSvc disables the Alt-Svc header on responses.
+	// Default: false
+	DisableAltSvc bool
+}
+
+// Listener serves a Helix server over HTTP/3.
+type Listener struct {
+	config Config
+	server *http3.Server
+
+	mu   sync.Mutex
+	conn net.PacketConn
+	done chan struct{}
+}
+
+// Enable registers an HTTP/3 listener on the server.
+// The listener starts with the server, advertises itself via the Alt-Svc
+// header on every response, and is shut down gracefully with the server.
+// Enable must be called before the server starts.
+func Enable(s *helix.Server, config Config) *Listener {
+	l := &Listener{
+		config: config,
+		server: &http3.Server{
+			Handler:    s,
+			QUICConfig: config.QUICConfig,
+			Port:       config.Port,
+		},
+	}
+
+	if !config.DisableAltSvc {
+		s.Use(l.altSvc)
+	}
+
+	s.OnStart(func(s *helix.Server) {
+		addr := l.config.Addr
+		if addr == "" {
+			addr = s.Addr()
+		}
+		if err := l.listen(addr); err != nil {
+			log.Printf("helix/quic: failed to start HTTP/3 listener: %v", err)
+			return
+		}
+		go l.serve()
+	})
+
+	s.OnStop(func(ctx context.Context, s *helix.Server) {
+		if err := l.Shutdown(ctx); err != nil {
+			log.Printf("helix/quic: HTTP/3 shutdown error: %v", err)
+		}
+	})
+
+	return l
+}
+
+// Addr returns the UDP address the listener is bound to.
+// Returns nil if the listener has not started.
+func (l *Listener) Addr() net.Addr {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return nil
+	}
+	return l.conn.LocalAddr()
+}
+
+// Shutdown gracefully shuts down the HTTP/3 listener.
+// Clients receive a GOAWAY frame and in-flight requests are allowed to
+// complete until the context is done.
+func (l *Listener) Shutdown(ctx context.Context) error {
+	l.mu.Lock()
+	conn, done := l.conn, l.done
+	l.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+
+	err := l.server.Shutdown(ctx)
+	conn.Close()
+	<-done
+	return err
+}
+
+// listen binds the UDP socket and prepares the TLS configuration.
+func (l *Listener) listen(addr string) error {
+	tlsConfig, err := l.tlsConfig()
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.server.Addr = addr
+	l.server.TLSConfig = tlsConfig
+	l.conn = conn
+	l.done = make(chan struct{})
+	l.mu.Unlock()
+	return nil
+}
+
+// serve runs the HTTP/3 server until it is shut down.
+func (l *Listener) serve() {
+	defer close(l.done)
+
+	err := l.server.Serve(l.conn)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, quicgo.ErrServerClosed) {
+		log.Printf("helix/quic: HTTP/3 server error: %v", err)
+	}
+}
+
+// tlsConfig returns the TLS configuration for QUIC connections.
+func (l *Listener) tlsConfig() (*tls.Config, error) {
+	if l.config.TLSConfig != nil {
+		return l.config.TLSConfig, nil
+	}
+	if l.config.CertFile == "" || l.config.KeyFile == "" {
+		return nil, errors.New("helix/quic: TLSConfig or CertFile and KeyFile are required")
+	}
+
+	cert, err := tls.LoadX509KeyPair(l.config.CertFile, l.config.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
+
+// altSvc is middleware that advertises the HTTP/3 endpoint.
+func (l *Listener) altSvc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Errors mean the listener isn't bound yet; nothing to advertise
+		_ = l.server.SetQUICHeaders(w.Header())
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,130 @@
+package quic_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kolosys/helix"
+	. "github.com/kolosys/helix/quic"
+	"github.com/quic-go/quic-go/http3"
+)
+
+func selfSignedTLSConfig(t *testing.T) *tls.Config {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	}
+}
+
+func TestEnable_ServesHTTP3(t *testing.T) {
+	s := helix.New(&helix.Options{Addr: "127.0.0.1:0", HideBanner: true})
+	s.GET("/proto", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	})
+
+	l := Enable(s, Config{
+		Addr:      "127.0.0.1:0",
+		TLSConfig: selfSignedTLSConfig(t),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- s.Run(ctx)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for l.Addr() == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if l.Addr() == nil {
+		t.Fatal("HTTP/3 listener did not start")
+	}
+
+	tr := &http3.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	defer tr.Close()
+	client := &http.Client{Transport: tr, Timeout: 5 * time.Second}
+
+	resp, err := client.Get("https://" + l.Addr().String() + "/proto")
+	if err != nil {
+		t.Fatalf("HTTP/3 request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if string(body) != "HTTP/3.0" {
+		t.Errorf("expected HTTP/3.0, got %s", body)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Alt-Svc"), `h3=":`) {
+		t.Errorf("expected Alt-Svc header, got %q", resp.Header.Get("Alt-Svc"))
+	}
+
+	cancel()
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Errorf("unexpected run error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+}
+
+func TestEnable_NoAltSvcBeforeStart(t *testing.T) {
+	s := helix.New(&helix.Options{HideBanner: true})
+	s.GET("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	Enable(s, Config{})
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+	if rec.Header().Get("Alt-Svc") != "" {
+		t.Errorf("expected no Alt-Svc before the listener starts, got %q", rec.Header().Get("Alt-Svc"))
+	}
+}
+
+func TestListener_ShutdownBeforeStart(t *testing.T) {
+	s := helix.New(nil)
+	l := Enable(s, Config{})
+
+	if err := l.Shutdown(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if l.Addr() != nil {
+		t.Error("expected nil address before start")
+	}
+}
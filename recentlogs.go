@@ -0,0 +1,129 @@
+package helix
+
+import (
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kolosys/helix/logs"
+)
+
+// RecentLog is a log entry reported by RecentLogsHandler.
+type RecentLog struct {
+	Time    time.Time   `json:"time"`
+	Level   string      `json:"level"`
+	Message string      `json:"msg"`
+	Fields  logs.Fields `json:"fields,omitempty"`
+}
+
+// RecentLogsHandler returns a handler that serves the entries retained by a
+// ring hook, newest first, as JSON or, for browsers, as an HTML table.
+//
+// Query parameters:
+//   - level: minimum level to include (e.g. "warn")
+//   - limit: maximum number of entries
+//   - q: case-insensitive substring the message must contain
+//   - format: "json" or "html" (default: based on the Accept header)
+//
+// Example:
+//
+//	ring := logs.NewRingHook(500)
+//	logs.SetDefault(logs.New(logs.WithHooks(ring)))
+//
+//	admin := s.Group("/internal", middleware.BasicAuth("admin", secret))
+//	admin.GET("/logs", s.RecentLogsHandler(ring))
+func (s *Server) RecentLogsHandler(ring *logs.RingHook) http.HandlerFunc {
+	if ring == nil {
+		panic("helix: RecentLogsHandler requires a ring hook")
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		minLevel := logs.DebugLevel
+		if v := query.Get("level"); v != "" {
+			level, err := logs.ParseLevel(v)
+			if err != nil {
+				WriteProblem(w, ErrBadRequest.WithDetail(err.Error()))
+				return
+			}
+			minLevel = level
+		}
+
+		limit := 0
+		if v := query.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				WriteProblem(w, ErrBadRequest.WithDetailf("invalid limit %q", v))
+				return
+			}
+			limit = n
+		}
+
+		search := strings.ToLower(query.Get("q"))
+
+		entries := ring.Entries()
+		result := make([]RecentLog, 0, len(entries))
+		for i := len(entries) - 1; i >= 0; i-- {
+			e := entries[i]
+			if e.Level < minLevel {
+				continue
+			}
+			if search != "" && !strings.Contains(strings.ToLower(e.Message), search) {
+				continue
+			}
+			result = append(result, RecentLog{
+				Time:    e.Time,
+				Level:   e.Level.String(),
+				Message: e.Message,
+				Fields:  e.Fields,
+			})
+			if limit > 0 && len(result) == limit {
+				break
+			}
+		}
+
+		if wantsHTML(r) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			recentLogsTemplate.Execute(w, result)
+			return
+		}
+		JSON(w, http.StatusOK, result)
+	}
+}
+
+// wantsHTML reports whether the client asked for an HTML page.
+func wantsHTML(r *http.Request) bool {
+	switch r.URL.Query().Get("format") {
+	case "html":
+		return true
+	case "json":
+		return false
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+var recentLogsTemplate = template.Must(template.New("logs").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Recent logs</title>
+<style>
+body { font-family: ui-monospace, monospace; font-size: 13px; margin: 1em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 4px 8px; border-bottom: 1px solid #ddd; vertical-align: top; }
+.debug { color: #888; } .warn { color: #b58900; } .error { color: #dc322f; }
+</style>
+</head>
+<body>
+<h1>Recent logs</h1>
+<table>
+<tr><th>Time</th><th>Level</th><th>Message</th><th>Fields</th></tr>
+{{range .}}<tr class="{{.Level}}"><td>{{.Time.Format "2006-01-02 15:04:05.000"}}</td><td>{{.Level}}</td><td>{{.Message}}</td><td>{{range $k, $v := .Fields}}{{$k}}={{$v}} {{end}}</td></tr>
+{{else}}<tr><td colspan="4">No entries</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,65 @@
+package helix_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/kolosys/helix"
+	"github.com/kolosys/helix/logs"
+)
+
+func TestRecentLogsHandler(t *testing.T) {
+	ring := logs.NewRingHook(10)
+	logger := logs.New(logs.WithOutput(&strings.Builder{}), logs.WithHooks(ring))
+	logger.Info("started")
+	logger.Warn("slow query", logs.Fields{"ms": 900})
+	logger.Error("query failed")
+
+	s := New(nil)
+	s.GET("/internal/logs", s.RecentLogsHandler(ring))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/internal/logs?level=warn", nil))
+
+	var entries []RecentLog
+	if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries at warn and above, got %d", len(entries))
+	}
+	if entries[0].Message != "query failed" || entries[1].Message != "slow query" {
+		t.Errorf("expected newest first, got %q, %q", entries[0].Message, entries[1].Message)
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/internal/logs?q=QUERY&limit=1", nil))
+	entries = nil
+	json.Unmarshal(rec.Body.Bytes(), &entries)
+	if len(entries) != 1 || entries[0].Message != "query failed" {
+		t.Errorf("expected limited search result, got %+v", entries)
+	}
+}
+
+func TestRecentLogsHandler_HTML(t *testing.T) {
+	ring := logs.NewRingHook(10)
+	logs.New(logs.WithOutput(&strings.Builder{}), logs.WithHooks(ring)).Info("<script>")
+
+	s := New(nil)
+	s.GET("/internal/logs", s.RecentLogsHandler(ring))
+
+	req := httptest.NewRequest(http.MethodGet, "/internal/logs", nil)
+	req.Header.Set("Accept", "text/html")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("expected HTML content type, got %q", rec.Header().Get("Content-Type"))
+	}
+	if strings.Contains(rec.Body.String(), "<script>") || !strings.Contains(rec.Body.String(), "&lt;script&gt;") {
+		t.Error("expected message to be HTML-escaped")
+	}
+}
@@ -0,0 +1,411 @@
+// Package replay records sampled requests and replays them against another
+// server, to reproduce production bugs or generate realistic load.
+//
+// The Record middleware writes each sampled request (method, URL, headers
+// and body) to a Sink, such as a JSON lines file; Replay reads the requests
+// back and re-issues them. The helix command wraps Replay:
+//
+//	helix replay -target http://localhost:8080 requests.jsonl
+//
+// Example:
+//
+//	sink, err := replay.NewFileSink("requests.jsonl")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer sink.Close()
+//	s.Use(replay.Record(sink, 0.01)) // 1% of the requests
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kolosys/helix/internal/reqbody"
+	"github.com/kolosys/helix/logs"
+	"github.com/kolosys/helix/middleware"
+)
+
+// ReplayHeader is set on replayed requests. Record skips them, so a server
+// replayed against does not record its own traffic.
+const ReplayHeader = "X-Replay"
+
+// Request is a recorded request.
+type Request struct {
+	// Time is when the request was received.
+	Time time.Time `json:"time"`
+
+	// Method is the request method.
+	Method string `json:"method"`
+
+	// URL is the request URI: the path and query.
+	URL string `json:"url"`
+
+	// Host is the Host header of the request.
+	Host string `json:"host,omitempty"`
+
+	// Header holds the request headers.
+	Header http.Header `json:"header,omitempty"`
+
+	// Body is the request body, base64 encoded in JSON.
+	Body []byte `json:"body,omitempty"`
+
+	// RequestID is the ID of the request, as set by the RequestID
+	// middleware, to find it in the logs.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Sink stores recorded requests.
+type Sink interface {
+	Write(ctx context.Context, req Request) error
+}
+
+// SinkFunc adapts a function to a Sink, such as to publish the requests to a
+// queue.
+type SinkFunc func(ctx context.Context, req Request) error
+
+// Write implements Sink.
+func (f SinkFunc) Write(ctx context.Context, req Request) error {
+	return f(ctx, req)
+}
+
+// WriterSink returns a Sink writing the requests to w as JSON lines, the
+// format Replay reads. Writes are serialized.
+func WriterSink(w io.Writer) Sink {
+	var mu sync.Mutex
+	return SinkFunc(func(ctx context.Context, req Request) error {
+		line, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		_, err = w.Write(append(line, '\n'))
+		return err
+	})
+}
+
+// FileSink is a Sink appending the requests to a file as JSON lines.
+type FileSink struct {
+	Sink
+	f *os.File
+}
+
+// NewFileSink opens the file at path for appending, creating it if needed.
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{Sink: WriterSink(f), f: f}, nil
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	return s.f.Close()
+}
+
+// RecordConfig configures the Record middleware.
+type RecordConfig struct {
+	// Sink stores the recorded requests. Required.
+	Sink Sink
+
+	// SampleRate is the fraction of the requests recorded, from 0 to 1.
+	// Default: 1 (every request)
+	SampleRate float64
+
+	// MaxBodySize is the largest request body recorded. Requests with
+	// larger bodies are served but not recorded.
+	// Default: 1MB
+	MaxBodySize int64
+
+	// Redactor masks sensitive headers, query parameters and JSON or form
+	// body fields, such as Authorization. Use
+	// logs.NewRedactor(logs.RedactConfig{Keys: []string{}}) to record
+	// requests verbatim, and ReplayConfig.Header to put credentials back
+	// when replaying.
+	// Default: logs.DefaultRedactor()
+	Redactor *logs.Redactor
+
+	// SkipFunc determines if a request is not recorded.
+	SkipFunc func(r *http.Request) bool
+
+	// OnError is called when the Sink fails. The request is served anyway.
+	// If nil, the failure is logged as a warning.
+	OnError func(r *http.Request, err error)
+}
+
+// Record returns a middleware that records a sampleRate fraction of the
+// requests to sink. See RecordWithConfig.
+func Record(sink Sink, sampleRate float64) func(http.Handler) http.Handler {
+	return RecordWithConfig(RecordConfig{Sink: sink, SampleRate: sampleRate})
+}
+
+// RecordWithConfig returns a Record middleware with the given configuration.
+// Requests are recorded before the handler runs, so those crashing it are
+// recorded too. Upgrade requests, such as WebSockets, and replayed requests
+// are not recorded.
+func RecordWithConfig(config RecordConfig) func(http.Handler) http.Handler {
+	if config.Sink == nil {
+		panic("helix/replay: Record sink is required")
+	}
+	if config.SampleRate == 0 {
+		config.SampleRate = 1
+	}
+	if config.MaxBodySize == 0 {
+		config.MaxBodySize = 1 << 20
+	}
+	if config.Redactor == nil {
+		config.Redactor = logs.DefaultRedactor()
+	}
+	if config.OnError == nil {
+		config.OnError = func(r *http.Request, err error) {
+			logs.FromContext(r.Context()).Warn("helix/replay: failed to record request", logs.Fields{
+				"method": r.Method,
+				"path":   r.URL.Path,
+				"error":  err,
+			})
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if (config.SampleRate < 1 && rand.Float64() >= config.SampleRate) ||
+				r.Header.Get("Upgrade") != "" || r.Header.Get(ReplayHeader) != "" ||
+				(config.SkipFunc != nil && config.SkipFunc(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if body, ok := reqbody.Tee(r, config.MaxBodySize); ok {
+				if err := config.Sink.Write(r.Context(), newRequest(r, body, config.Redactor)); err != nil {
+					config.OnError(r, err)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// newRequest returns the record of r with body, masked by redactor.
+func newRequest(r *http.Request, body []byte, redactor *logs.Redactor) Request {
+	u := *r.URL
+	u.RawQuery = redactor.RedactQuery(u.RawQuery)
+	header := r.Header.Clone()
+	for name, values := range header {
+		for i, v := range values {
+			values[i] = redactor.RedactString(name, v)
+		}
+	}
+	contentType := r.Header.Get("Content-Type")
+	switch {
+	case len(body) == 0:
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		body = []byte(redactor.RedactQuery(string(body)))
+	case strings.Contains(contentType, "json"):
+		body = redactor.RedactJSON(body)
+	}
+	return Request{
+		Time:      time.Now().UTC(),
+		Method:    r.Method,
+		URL:       u.RequestURI(),
+		Host:      r.Host,
+		Header:    header,
+		Body:      body,
+		RequestID: middleware.GetRequestID(r.Context()),
+	}
+}
+
+// ReplayConfig configures Replay.
+type ReplayConfig struct {
+	// Target is the base URL of the server replayed against, such as
+	// "http://localhost:8080". Recorded URLs are appended to its path.
+	// Required.
+	Target string
+
+	// Client sends the requests.
+	// Default: http.DefaultClient
+	Client *http.Client
+
+	// Concurrency is the number of requests sent at once.
+	// Default: 1, replaying the requests in order
+	Concurrency int
+
+	// Speed paces the requests by their recorded times, sped up by Speed:
+	// 1 replays at the recorded pace, 2 twice as fast. If zero, requests are
+	// sent as fast as possible.
+	Speed float64
+
+	// Header is set on every request, replacing the recorded values, such
+	// as to put back a redacted Authorization header.
+	Header http.Header
+
+	// KeepHost sends the recorded Host header instead of the target's.
+	KeepHost bool
+
+	// OnResponse, if set, is called with the response to each request, or
+	// the error sending it. The response body is closed after it returns.
+	OnResponse func(req Request, resp *http.Response, err error)
+}
+
+// Result summarizes a Replay.
+type Result struct {
+	// Requests is the number of requests sent.
+	Requests int
+
+	// Errors is the number of requests that got no response.
+	Errors int
+
+	// Statuses counts the responses by status code.
+	Statuses map[int]int
+
+	// Elapsed is how long the replay took.
+	Elapsed time.Duration
+}
+
+// Replay re-issues the requests read from r, JSON lines as written by
+// WriterSink and FileSink, against the target server, each with the
+// ReplayHeader set. It stops at the end of r or when ctx is done, and
+// returns an error if the input is malformed or ctx ended early.
+//
+// Example:
+//
+//	f, _ := os.Open("requests.jsonl")
+//	res, err := replay.Replay(ctx, f, replay.ReplayConfig{
+//	    Target:      "http://localhost:8080",
+//	    Concurrency: 8,
+//	})
+func Replay(ctx context.Context, r io.Reader, config ReplayConfig) (Result, error) {
+	target, err := url.Parse(config.Target)
+	if err != nil || target.Scheme == "" || target.Host == "" {
+		return Result{}, fmt.Errorf("helix/replay: target must be an absolute URL, got %q", config.Target)
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	config.Concurrency = max(config.Concurrency, 1)
+
+	res := Result{Statuses: make(map[int]int)}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, config.Concurrency)
+	start := time.Now()
+	var first time.Time
+
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for line := 1; ; line++ {
+		var req Request
+		if err = dec.Decode(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = nil
+			} else {
+				err = fmt.Errorf("helix/replay: request %d: %w", line, err)
+			}
+			break
+		}
+
+		if config.Speed > 0 && !req.Time.IsZero() {
+			if first.IsZero() {
+				first = req.Time
+			}
+			due := start.Add(time.Duration(float64(req.Time.Sub(first)) / config.Speed))
+			if err = sleep(ctx, time.Until(due)); err != nil {
+				break
+			}
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		if err != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			status, sendErr := send(ctx, config, target, req)
+			mu.Lock()
+			defer mu.Unlock()
+			res.Requests++
+			if sendErr != nil {
+				res.Errors++
+			} else {
+				res.Statuses[status]++
+			}
+		}()
+	}
+
+	wg.Wait()
+	res.Elapsed = time.Since(start)
+	return res, err
+}
+
+// send re-issues req against target, returning the response status.
+func send(ctx context.Context, config ReplayConfig, target *url.URL, req Request) (int, error) {
+	ref, err := url.Parse(req.URL)
+	if err != nil {
+		return 0, err
+	}
+	u := *target
+	u.Path = strings.TrimSuffix(target.Path, "/") + ref.Path
+	u.RawPath = strings.TrimSuffix(target.EscapedPath(), "/") + ref.EscapedPath()
+	u.RawQuery = ref.RawQuery
+
+	out, err := http.NewRequestWithContext(ctx, req.Method, u.String(), bytes.NewReader(req.Body))
+	if err != nil {
+		return 0, err
+	}
+	out.Header = req.Header.Clone()
+	if out.Header == nil {
+		out.Header = make(http.Header)
+	}
+	for name, values := range config.Header {
+		out.Header[http.CanonicalHeaderKey(name)] = values
+	}
+	out.Header.Set(ReplayHeader, "1")
+	out.Header.Del("Content-Length")
+	if config.KeepHost && req.Host != "" {
+		out.Host = req.Host
+	}
+
+	resp, err := config.Client.Do(out)
+	if config.OnResponse != nil {
+		config.OnResponse(req, resp, err)
+	}
+	if err != nil {
+		return 0, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
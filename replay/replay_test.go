@@ -0,0 +1,97 @@
+package replay_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/kolosys/helix/replay"
+)
+
+func TestRecord(t *testing.T) {
+	var buf bytes.Buffer
+	handler := Record(WriterSink(&buf), 1)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/orders?sku=42&token=abc", strings.NewReader(`{"qty":2,"password":"hunter2"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer abc")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Body.String() != `{"qty":2,"password":"hunter2"}` {
+		t.Fatalf("expected the handler to read the body, got %q", rec.Body.String())
+	}
+
+	// Replayed requests are not recorded
+	req = httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set(ReplayHeader, "1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var got Request
+	dec := json.NewDecoder(&buf)
+	if err := dec.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if dec.More() {
+		t.Error("expected a single recorded request")
+	}
+	if got.Method != http.MethodPost || got.URL != "/orders?sku=42&token=%5BREDACTED%5D" || got.Time.IsZero() {
+		t.Errorf("unexpected request %s %s at %v", got.Method, got.URL, got.Time)
+	}
+	if got.Header.Get("Authorization") != "[REDACTED]" || got.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected headers %v", got.Header)
+	}
+	if !strings.Contains(string(got.Body), `"qty":2`) || strings.Contains(string(got.Body), "hunter2") {
+		t.Errorf("unexpected body %s", got.Body)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, r.Method+" "+r.URL.RequestURI()+" "+string(body)+" "+r.Header.Get("Authorization")+" "+r.Header.Get(ReplayHeader))
+		mu.Unlock()
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer target.Close()
+
+	var buf bytes.Buffer
+	sink := WriterSink(&buf)
+	sink.Write(context.Background(), Request{Method: http.MethodPost, URL: "/orders?sku=42", Body: []byte("qty=2")})
+	sink.Write(context.Background(), Request{Method: http.MethodDelete, URL: "/orders/7", Header: http.Header{"Authorization": {"[REDACTED]"}}})
+
+	res, err := Replay(context.Background(), &buf, ReplayConfig{
+		Target: target.URL + "/staging",
+		Header: http.Header{"Authorization": {"Bearer test"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Requests != 2 || res.Errors != 0 || res.Statuses[http.StatusOK] != 1 || res.Statuses[http.StatusNotFound] != 1 {
+		t.Errorf("unexpected result %+v", res)
+	}
+	want := []string{
+		"POST /staging/orders?sku=42 qty=2 Bearer test 1",
+		"DELETE /staging/orders/7  Bearer test 1",
+	}
+	if strings.Join(received, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected replayed requests\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(received, "\n"))
+	}
+
+	if _, err := Replay(context.Background(), strings.NewReader("{not json"), ReplayConfig{Target: target.URL}); err == nil {
+		t.Error("expected an error for malformed input")
+	}
+}
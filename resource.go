@@ -1,6 +1,105 @@
 package helix
 
-import "net/http"
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Resource action names, for UseFor, Only and Except.
+const (
+	ActionList   = "list"
+	ActionCreate = "create"
+	ActionGet    = "get"
+	ActionUpdate = "update"
+	ActionPatch  = "patch"
+	ActionDelete = "delete"
+)
+
+// resourceActions holds per-action middleware and action filters shared by
+// ResourceBuilder and TypedResourceBuilder.
+type resourceActions struct {
+	middleware map[string][]Middleware
+	only       map[string]bool
+	except     map[string]bool
+}
+
+// checkAction panics if action is not a known resource action.
+func checkAction(action string) {
+	switch action {
+	case ActionList, ActionCreate, ActionGet, ActionUpdate, ActionPatch, ActionDelete:
+	default:
+		panic(fmt.Sprintf("helix: unknown resource action %q", action))
+	}
+}
+
+// useFor adds middleware for actions. Leading strings are action names,
+// the remaining arguments are middleware.
+func (ra *resourceActions) useFor(args []any) {
+	var actions []string
+	i := 0
+	for ; i < len(args); i++ {
+		action, ok := args[i].(string)
+		if !ok {
+			break
+		}
+		checkAction(action)
+		actions = append(actions, action)
+	}
+	if len(actions) == 0 {
+		panic("helix: UseFor requires at least one action")
+	}
+
+	mw := toMiddleware(args[i:])
+	if ra.middleware == nil {
+		ra.middleware = make(map[string][]Middleware)
+	}
+	for _, action := range actions {
+		ra.middleware[action] = append(ra.middleware[action], mw...)
+	}
+}
+
+// setOnly restricts registration to actions.
+func (ra *resourceActions) setOnly(actions []string) {
+	ra.only = make(map[string]bool, len(actions))
+	for _, action := range actions {
+		checkAction(action)
+		ra.only[action] = true
+	}
+}
+
+// setExcept excludes actions from registration.
+func (ra *resourceActions) setExcept(actions []string) {
+	if ra.except == nil {
+		ra.except = make(map[string]bool, len(actions))
+	}
+	for _, action := range actions {
+		checkAction(action)
+		ra.except[action] = true
+	}
+}
+
+// allowed reports whether action passes the Only and Except filters.
+func (ra *resourceActions) allowed(action string) bool {
+	if ra.only != nil && !ra.only[action] {
+		return false
+	}
+	return !ra.except[action]
+}
+
+// wrap applies the action's middleware to handler.
+func (ra *resourceActions) wrap(action string, handler http.HandlerFunc) http.HandlerFunc {
+	mw := ra.middleware[action]
+	if len(mw) == 0 {
+		return handler
+	}
+
+	var h http.Handler = handler
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h.ServeHTTP
+}
 
 // ResourceBuilder provides a fluent interface for defining REST resource routes.
 type ResourceBuilder struct {
@@ -8,12 +107,20 @@ type ResourceBuilder struct {
 	group      *Group
 	pattern    string
 	middleware []Middleware
+	actions    resourceActions
+	idParam    string
+	idType     ParamType
+
+	// parent is set for resources created with Nest.
+	parent      *ResourceBuilder
+	nestPattern string
+	parentParam string
 }
 
 // Resource creates a new ResourceBuilder for the given pattern.
 // The pattern should be the base path for the resource (e.g., "/users").
 // Optional middleware can be applied to all routes in the resource.
-// Accepts Middleware (helix.Middleware is an alias for middleware.Middleware) or func(http.Handler) http.Handler.
+// Accepts Middleware (helix.Middleware is an alias for middleware.Middleware), func(http.Handler) http.Handler, or CtxMiddleware.
 func (s *Server) Resource(pattern string, mw ...any) *ResourceBuilder {
 	return &ResourceBuilder{
 		server:     s,
@@ -48,39 +155,204 @@ func (rb *ResourceBuilder) handle(method, pattern string, handler http.HandlerFu
 	}
 }
 
+// handleAction registers a standard action route, applying the action's
+// middleware and skipping actions excluded by Only or Except.
+func (rb *ResourceBuilder) handleAction(action, method, pattern string, handler http.HandlerFunc) {
+	if !rb.actions.allowed(action) {
+		return
+	}
+	rb.handle(method, pattern, rb.actions.wrap(action, handler))
+}
+
+// UseFor adds middleware to specific actions, e.g. authentication for
+// mutating actions only. Leading string arguments are action names ("list",
+// "create", "get", "update", "patch", "delete"); the rest are middleware.
+// Action middleware runs after the resource's middleware.
+// Must be called before the actions are registered.
+//
+// Example:
+//
+//	s.Resource("/posts").
+//	    UseFor("create", "update", "delete", requireAuth).
+//	    CRUD(list, create, get, update, delete)
+func (rb *ResourceBuilder) UseFor(args ...any) *ResourceBuilder {
+	rb.actions.useFor(args)
+	return rb
+}
+
+// Only restricts the resource to the given actions; registering any other
+// standard action is a no-op. Must be called before the actions are registered.
+func (rb *ResourceBuilder) Only(actions ...string) *ResourceBuilder {
+	rb.actions.setOnly(actions)
+	return rb
+}
+
+// Except excludes the given actions; registering them is a no-op.
+// Must be called before the actions are registered.
+func (rb *ResourceBuilder) Except(actions ...string) *ResourceBuilder {
+	rb.actions.setExcept(actions)
+	return rb
+}
+
+// IDParam sets the name of the ID parameter used by Get, Update, Patch and
+// Delete, which defaults to "id". Must be called before those routes are
+// registered.
+//
+// Example:
+//
+//	s.Resource("/users").IDParam("userID").Get(getUser) // GET /users/{userID}
+func (rb *ResourceBuilder) IDParam(name string) *ResourceBuilder {
+	rb.idParam = name
+	return rb
+}
+
+// IDType constrains the ID parameter to a parameter type, so requests with
+// malformed IDs are rejected by the router. Must be called before the
+// routes are registered.
+//
+// Example:
+//
+//	s.Resource("/users").IDType(helix.UUID).Get(getUser) // GET /users/{id:uuid}
+func (rb *ResourceBuilder) IDType(t ParamType) *ResourceBuilder {
+	lookupParamType(t)
+	rb.idType = t
+	return rb
+}
+
+// Nest returns a builder for a child resource whose routes are generated
+// under a single parent resource, e.g. Nest("/comments") on "/posts" yields
+// "/posts/{postID}/comments" and "/posts/{postID}/comments/{id}".
+// The parent ID parameter defaults to the parent's IDParam, if set, or else
+// the singular of the parent's last path segment followed by "ID"; use
+// ParentParam to change it. The parent's IDType applies to it as well.
+// The child inherits the parent's middleware, followed by mw.
+// Accepts the same middleware types as Resource.
+//
+// Example:
+//
+//	posts := s.Resource("/posts").CRUD(listPosts, createPost, getPost, updatePost, deletePost)
+//	posts.Nest("/comments").
+//	    List(listComments). // GET /posts/{postID}/comments
+//	    Get(getComment)     // GET /posts/{postID}/comments/{id}
+func (rb *ResourceBuilder) Nest(pattern string, mw ...any) *ResourceBuilder {
+	converted := toMiddleware(mw)
+	allMW := make([]Middleware, 0, len(rb.middleware)+len(converted))
+	allMW = append(allMW, rb.middleware...)
+	allMW = append(allMW, converted...)
+
+	child := &ResourceBuilder{
+		server:      rb.server,
+		group:       rb.group,
+		middleware:  allMW,
+		parent:      rb,
+		nestPattern: pattern,
+	}
+	param := rb.idParam
+	if param == "" {
+		param = defaultParentParam(rb.pattern)
+	}
+	child.setParentParam(param)
+	return child
+}
+
+// ParentParam sets the name of the parent ID parameter of a nested resource.
+// Must be called before any routes are registered on the nested resource.
+// Panics if the resource was not created with Nest.
+//
+// Example:
+//
+//	s.Resource("/posts").Nest("/comments").ParentParam("post")
+//	// routes under /posts/{post}/comments
+func (rb *ResourceBuilder) ParentParam(name string) *ResourceBuilder {
+	if rb.parent == nil {
+		panic("helix: ParentParam requires a resource created with Nest")
+	}
+	rb.setParentParam(name)
+	return rb
+}
+
+// setParentParam rebuilds the pattern of a nested resource.
+func (rb *ResourceBuilder) setParentParam(name string) {
+	rb.parentParam = name
+	rb.pattern = rb.parent.memberPattern(name) + rb.nestPattern
+}
+
+// memberPattern returns the pattern of a single resource, using param as
+// the name of the ID parameter.
+func (rb *ResourceBuilder) memberPattern(param string) string {
+	return rb.pattern + idSegment(param, rb.idType)
+}
+
+// itemPattern returns the pattern of a single resource.
+func (rb *ResourceBuilder) itemPattern() string {
+	param := rb.idParam
+	if param == "" {
+		param = "id"
+	}
+	return rb.memberPattern(param)
+}
+
+// idSegment returns the path segment for an ID parameter of type t.
+func idSegment(param string, t ParamType) string {
+	if t == "" {
+		return "/{" + param + "}"
+	}
+	return "/{" + param + ":" + string(t) + "}"
+}
+
+// defaultParentParam derives a parent ID parameter name from a resource
+// pattern, e.g. "postID" for "/posts" and "categoryID" for "/categories".
+func defaultParentParam(pattern string) string {
+	segment := strings.Trim(pattern, "/")
+	if i := strings.LastIndex(segment, "/"); i >= 0 {
+		segment = segment[i+1:]
+	}
+	switch {
+	case strings.HasSuffix(segment, "ies"):
+		segment = strings.TrimSuffix(segment, "ies") + "y"
+	case strings.HasSuffix(segment, "ss"):
+	case strings.HasSuffix(segment, "s"):
+		segment = strings.TrimSuffix(segment, "s")
+	}
+	if segment == "" || strings.HasPrefix(segment, "{") {
+		return "parentID"
+	}
+	return segment + "ID"
+}
+
 // List registers a GET handler for the collection (e.g., GET /users).
 func (rb *ResourceBuilder) List(handler http.HandlerFunc) *ResourceBuilder {
-	rb.handle(http.MethodGet, rb.pattern, handler)
+	rb.handleAction(ActionList, http.MethodGet, rb.pattern, handler)
 	return rb
 }
 
 // Create registers a POST handler for creating resources (e.g., POST /users).
 func (rb *ResourceBuilder) Create(handler http.HandlerFunc) *ResourceBuilder {
-	rb.handle(http.MethodPost, rb.pattern, handler)
+	rb.handleAction(ActionCreate, http.MethodPost, rb.pattern, handler)
 	return rb
 }
 
 // Get registers a GET handler for a single resource (e.g., GET /users/{id}).
 func (rb *ResourceBuilder) Get(handler http.HandlerFunc) *ResourceBuilder {
-	rb.handle(http.MethodGet, rb.pattern+"/{id}", handler)
+	rb.handleAction(ActionGet, http.MethodGet, rb.itemPattern(), handler)
 	return rb
 }
 
 // Update registers a PUT handler for updating a resource (e.g., PUT /users/{id}).
 func (rb *ResourceBuilder) Update(handler http.HandlerFunc) *ResourceBuilder {
-	rb.handle(http.MethodPut, rb.pattern+"/{id}", handler)
+	rb.handleAction(ActionUpdate, http.MethodPut, rb.itemPattern(), handler)
 	return rb
 }
 
 // Patch registers a PATCH handler for partial updates (e.g., PATCH /users/{id}).
 func (rb *ResourceBuilder) Patch(handler http.HandlerFunc) *ResourceBuilder {
-	rb.handle(http.MethodPatch, rb.pattern+"/{id}", handler)
+	rb.handleAction(ActionPatch, http.MethodPatch, rb.itemPattern(), handler)
 	return rb
 }
 
 // Delete registers a DELETE handler for deleting a resource (e.g., DELETE /users/{id}).
 func (rb *ResourceBuilder) Delete(handler http.HandlerFunc) *ResourceBuilder {
-	rb.handle(http.MethodDelete, rb.pattern+"/{id}", handler)
+	rb.handleAction(ActionDelete, http.MethodDelete, rb.itemPattern(), handler)
 	return rb
 }
 
@@ -173,6 +445,8 @@ type TypedResourceBuilder[Entity any] struct {
 	group      *Group
 	pattern    string
 	middleware []Middleware
+	actions    resourceActions
+	idType     ParamType
 }
 
 // wrapHandler wraps a handler with the resource's middleware.
@@ -201,6 +475,46 @@ func (rb *TypedResourceBuilder[Entity]) handle(method, pattern string, handler h
 	}
 }
 
+// handleAction registers a standard action route, applying the action's
+// middleware and skipping actions excluded by Only or Except.
+func (rb *TypedResourceBuilder[Entity]) handleAction(action, method, pattern string, handler http.HandlerFunc) {
+	if !rb.actions.allowed(action) {
+		return
+	}
+	rb.handle(method, pattern, rb.actions.wrap(action, handler))
+}
+
+// UseFor adds middleware to specific actions. See ResourceBuilder.UseFor.
+func (rb *TypedResourceBuilder[Entity]) UseFor(args ...any) *TypedResourceBuilder[Entity] {
+	rb.actions.useFor(args)
+	return rb
+}
+
+// Only restricts the resource to the given actions. See ResourceBuilder.Only.
+func (rb *TypedResourceBuilder[Entity]) Only(actions ...string) *TypedResourceBuilder[Entity] {
+	rb.actions.setOnly(actions)
+	return rb
+}
+
+// Except excludes the given actions. See ResourceBuilder.Except.
+func (rb *TypedResourceBuilder[Entity]) Except(actions ...string) *TypedResourceBuilder[Entity] {
+	rb.actions.setExcept(actions)
+	return rb
+}
+
+// IDType constrains the {id} parameter to a parameter type.
+// See ResourceBuilder.IDType.
+func (rb *TypedResourceBuilder[Entity]) IDType(t ParamType) *TypedResourceBuilder[Entity] {
+	lookupParamType(t)
+	rb.idType = t
+	return rb
+}
+
+// itemPattern returns the pattern of a single resource.
+func (rb *TypedResourceBuilder[Entity]) itemPattern() string {
+	return rb.pattern + idSegment("id", rb.idType)
+}
+
 // ListRequest is a common request type for list operations.
 type ListRequest struct {
 	Page   int    `query:"page"`
@@ -216,6 +530,10 @@ type ListResponse[Entity any] struct {
 	Total int      `json:"total"`
 	Page  int      `json:"page,omitempty"`
 	Limit int      `json:"limit,omitempty"`
+
+	// Links are the list's page links, filled in like those of
+	// PaginatedResponse.
+	Links Links `json:"_links,omitempty"`
 }
 
 // IDRequest is a common request type for single-entity operations.
@@ -226,41 +544,98 @@ type IDRequest struct {
 // List registers a typed GET handler for the collection.
 // Handler signature: func(ctx, ListReq) (ListResponse[Entity], error)
 func (rb *TypedResourceBuilder[Entity]) List(h Handler[ListRequest, ListResponse[Entity]]) *TypedResourceBuilder[Entity] {
-	rb.handle(http.MethodGet, rb.pattern, Handle(h))
+	rb.handleAction(ActionList, http.MethodGet, rb.pattern, Handle(h))
 	return rb
 }
 
 // Create registers a typed POST handler for creating resources.
-// Handler signature: func(ctx, CreateReq) (Entity, error)
+// Handler signature: func(ctx, Entity) (Entity, error)
+// Use CreateWith for a request type other than Entity.
 func (rb *TypedResourceBuilder[Entity]) Create(h Handler[Entity, Entity]) *TypedResourceBuilder[Entity] {
-	rb.handle(http.MethodPost, rb.pattern, HandleCreated(h))
+	rb.handleAction(ActionCreate, http.MethodPost, rb.pattern, HandleCreated(h))
 	return rb
 }
 
 // Get registers a typed GET handler for a single resource.
 // Handler signature: func(ctx, IDRequest) (Entity, error)
 func (rb *TypedResourceBuilder[Entity]) Get(h Handler[IDRequest, Entity]) *TypedResourceBuilder[Entity] {
-	rb.handle(http.MethodGet, rb.pattern+"/{id}", Handle(h))
+	rb.handleAction(ActionGet, http.MethodGet, rb.itemPattern(), Handle(h))
 	return rb
 }
 
 // Update registers a typed PUT handler for updating a resource.
-// The request type should include the ID from path and the update data.
+// Use UpdateWith for a request type that binds the ID from the path
+// alongside the update data.
 func (rb *TypedResourceBuilder[Entity]) Update(h Handler[Entity, Entity]) *TypedResourceBuilder[Entity] {
-	rb.handle(http.MethodPut, rb.pattern+"/{id}", Handle(h))
+	rb.handleAction(ActionUpdate, http.MethodPut, rb.itemPattern(), Handle(h))
 	return rb
 }
 
 // Patch registers a typed PATCH handler for partial updates.
+// Use PatchWith for a dedicated partial-update request type.
 func (rb *TypedResourceBuilder[Entity]) Patch(h Handler[Entity, Entity]) *TypedResourceBuilder[Entity] {
-	rb.handle(http.MethodPatch, rb.pattern+"/{id}", Handle(h))
+	rb.handleAction(ActionPatch, http.MethodPatch, rb.itemPattern(), Handle(h))
 	return rb
 }
 
 // Delete registers a typed DELETE handler for deleting a resource.
 // Handler signature: func(ctx, IDRequest) error
 func (rb *TypedResourceBuilder[Entity]) Delete(h NoResponseHandler[IDRequest]) *TypedResourceBuilder[Entity] {
-	rb.handle(http.MethodDelete, rb.pattern+"/{id}", HandleNoResponse(h))
+	rb.handleAction(ActionDelete, http.MethodDelete, rb.itemPattern(), HandleNoResponse(h))
+	return rb
+}
+
+// CreateWith registers a typed POST handler on rb whose request type differs
+// from the entity, e.g. a payload without server-assigned fields like the ID.
+// It returns rb so registration can continue.
+//
+// Example:
+//
+//	type CreateUserRequest struct {
+//	    Name  string `json:"name"`
+//	    Email string `json:"email"`
+//	}
+//
+//	users := helix.TypedResource[User](s, "/users").List(listUsers)
+//	helix.CreateWith(users, func(ctx context.Context, req CreateUserRequest) (User, error) {
+//	    return store.Create(ctx, req.Name, req.Email)
+//	})
+func CreateWith[Req, Entity any](rb *TypedResourceBuilder[Entity], h Handler[Req, Entity]) *TypedResourceBuilder[Entity] {
+	rb.handleAction(ActionCreate, http.MethodPost, rb.pattern, HandleCreated(h))
+	return rb
+}
+
+// UpdateWith registers a typed PUT handler on rb whose request type differs
+// from the entity. The request type typically binds the ID from the path
+// alongside the update data. It returns rb so registration can continue.
+//
+// Example:
+//
+//	type UpdateUserRequest struct {
+//	    ID   int    `path:"id"`
+//	    Name string `json:"name"`
+//	}
+//
+//	helix.UpdateWith(users, updateUser)
+func UpdateWith[Req, Entity any](rb *TypedResourceBuilder[Entity], h Handler[Req, Entity]) *TypedResourceBuilder[Entity] {
+	rb.handleAction(ActionUpdate, http.MethodPut, rb.itemPattern(), Handle(h))
+	return rb
+}
+
+// PatchWith registers a typed PATCH handler on rb whose request type differs
+// from the entity, e.g. a struct of pointer fields for partial updates.
+// It returns rb so registration can continue.
+//
+// Example:
+//
+//	type PatchUserRequest struct {
+//	    ID   int     `path:"id"`
+//	    Name *string `json:"name"`
+//	}
+//
+//	helix.PatchWith(users, patchUser)
+func PatchWith[Req, Entity any](rb *TypedResourceBuilder[Entity], h Handler[Req, Entity]) *TypedResourceBuilder[Entity] {
+	rb.handleAction(ActionPatch, http.MethodPatch, rb.itemPattern(), Handle(h))
 	return rb
 }
 
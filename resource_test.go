@@ -2,6 +2,7 @@ package helix_test
 
 import (
 	"bytes"
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -395,3 +396,229 @@ func TestServer_PrintRoutes(t *testing.T) {
 		t.Error("expected output to contain /users")
 	}
 }
+
+func TestResourceBuilder_UseFor(t *testing.T) {
+	s := New(nil)
+
+	requireAuth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	s.Resource("/posts").
+		UseFor(ActionCreate, ActionDelete, requireAuth).
+		CRUD(ok, ok, ok, ok, ok)
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, "/posts", http.StatusOK},
+		{http.MethodGet, "/posts/1", http.StatusOK},
+		{http.MethodPut, "/posts/1", http.StatusOK},
+		{http.MethodPost, "/posts", http.StatusUnauthorized},
+		{http.MethodDelete, "/posts/1", http.StatusUnauthorized},
+	}
+
+	for _, tc := range tests {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		if rec.Code != tc.want {
+			t.Errorf("%s %s: expected status %d, got %d", tc.method, tc.path, tc.want, rec.Code)
+		}
+	}
+}
+
+func TestResourceBuilder_OnlyExcept(t *testing.T) {
+	s := New(nil)
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	s.Resource("/tags").Only("list", "get").CRUD(ok, ok, ok, ok, ok)
+	s.Resource("/orders").Except("delete").CRUD(ok, ok, ok, ok, ok)
+
+	tests := []struct {
+		method string
+		path   string
+		routed bool
+	}{
+		{http.MethodGet, "/tags", true},
+		{http.MethodGet, "/tags/1", true},
+		{http.MethodPost, "/tags", false},
+		{http.MethodDelete, "/tags/1", false},
+		{http.MethodPost, "/orders", true},
+		{http.MethodPut, "/orders/1", true},
+		{http.MethodDelete, "/orders/1", false},
+	}
+
+	for _, tc := range tests {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		if routed := rec.Code == http.StatusOK; routed != tc.routed {
+			t.Errorf("%s %s: expected routed=%v, got status %d", tc.method, tc.path, tc.routed, rec.Code)
+		}
+	}
+}
+
+func TestResourceBuilder_UnknownActionPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for unknown action")
+		}
+	}()
+
+	New(nil).Resource("/users").Only("destroy")
+}
+
+func TestResourceBuilder_Nest(t *testing.T) {
+	s := New(nil)
+
+	posts := s.Resource("/posts")
+	posts.Nest("/comments").
+		List(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("list " + Param(r, "postID")))
+		}).
+		Get(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("get " + Param(r, "postID") + "/" + Param(r, "id")))
+		})
+	s.Resource("/categories").Nest("/items").ParentParam("cat").
+		List(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("items " + Param(r, "cat")))
+		})
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/posts/7/comments", "list 7"},
+		{"/posts/7/comments/3", "get 7/3"},
+		{"/categories/books/items", "items books"},
+	}
+
+	for _, tc := range tests {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rec.Body.String() != tc.want {
+			t.Errorf("GET %s: expected %q, got %q (status %d)", tc.path, tc.want, rec.Body.String(), rec.Code)
+		}
+	}
+}
+
+func TestResourceBuilder_NestInheritsMiddleware(t *testing.T) {
+	s := New(nil)
+	var trail []string
+
+	mark := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				trail = append(trail, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	s.Resource("/users", mark("parent")).
+		Nest("/keys", mark("child")).
+		Delete(func(w http.ResponseWriter, r *http.Request) { trail = append(trail, "handler") })
+
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/users/1/keys/2", nil))
+
+	if got := strings.Join(trail, ","); got != "parent,child,handler" {
+		t.Errorf("expected parent,child,handler, got %s", got)
+	}
+}
+
+func TestResourceBuilder_IDParamAndType(t *testing.T) {
+	s := New(nil)
+
+	users := s.Resource("/users").IDParam("userID").IDType(UUID).
+		Get(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(Param(r, "userID")))
+		})
+	users.Nest("/posts").List(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("posts " + Param(r, "userID")))
+	})
+
+	const id = "123e4567-e89b-12d3-a456-426614174000"
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/users/" + id, http.StatusOK, id},
+		{"/users/42", http.StatusNotFound, ""},
+		{"/users/" + id + "/posts", http.StatusOK, "posts " + id},
+		{"/users/42/posts", http.StatusNotFound, ""},
+	}
+
+	for _, tc := range tests {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rec.Code != tc.status {
+			t.Errorf("GET %s: expected status %d, got %d", tc.path, tc.status, rec.Code)
+		}
+		if tc.body != "" && rec.Body.String() != tc.body {
+			t.Errorf("GET %s: expected body %q, got %q", tc.path, tc.body, rec.Body.String())
+		}
+	}
+}
+
+type testArticle struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+}
+
+type createArticleRequest struct {
+	Title string `json:"title"`
+}
+
+type patchArticleRequest struct {
+	ID    int     `path:"id"`
+	Title *string `json:"title"`
+}
+
+func TestTypedResource_RequestDTOs(t *testing.T) {
+	s := New(nil)
+
+	articles := TypedResource[testArticle](s, "/articles")
+	CreateWith(articles, func(ctx context.Context, req createArticleRequest) (testArticle, error) {
+		return testArticle{ID: 1, Title: req.Title}, nil
+	})
+	PatchWith(articles, func(ctx context.Context, req patchArticleRequest) (testArticle, error) {
+		a := testArticle{ID: req.ID, Title: "unchanged"}
+		if req.Title != nil {
+			a.Title = *req.Title
+		}
+		return a, nil
+	})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/articles", strings.NewReader(`{"title":"Hello"}`))
+	req.Header.Set("Content-Type", "application/json")
+	s.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf("create: expected status 201, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"title":"Hello"`) {
+		t.Errorf("create: unexpected body %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPatch, "/articles/5", strings.NewReader(`{"title":"Patched"}`))
+	req.Header.Set("Content-Type", "application/json")
+	s.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("patch: expected status 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `{"id":5,"title":"Patched"}`) {
+		t.Errorf("patch: unexpected body %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,89 @@
+package helix
+
+import "net/http"
+
+// Result wraps a typed handler's response value with the status code,
+// headers and cookies to send with it. Return it from Handle,
+// HandleWithStatus or HandleNoRequest handlers to control the response
+// without switching to HandleCtx.
+//
+// Example:
+//
+//	s.POST("/users", helix.Handle(func(ctx context.Context, req CreateUserRequest) (helix.Result[User], error) {
+//	    user, err := users.Create(ctx, req)
+//	    if err != nil {
+//	        return helix.Result[User]{}, err
+//	    }
+//	    return helix.Result[User]{
+//	        Value:   user,
+//	        Status:  http.StatusCreated,
+//	        Headers: http.Header{"Location": {"/users/" + user.ID}},
+//	    }, nil
+//	}))
+type Result[T any] struct {
+	// Value is encoded as the response body.
+	Value T
+
+	// Status is the response status code. If zero, the handler's default
+	// status is used. Bodies are omitted for 204 and 304.
+	Status int
+
+	// Headers are added to the response.
+	Headers http.Header
+
+	// Cookies are set on the response.
+	Cookies []*http.Cookie
+
+	// Raw encodes Value as is, bypassing the response envelope.
+	Raw bool
+}
+
+// result is implemented by Result so typed handlers can detect it.
+type result interface {
+	apply(w http.ResponseWriter, status int) (int, any, bool)
+}
+
+// apply writes the headers and cookies and returns the status, the body and
+// whether the body bypasses the envelope.
+func (res Result[T]) apply(w http.ResponseWriter, status int) (int, any, bool) {
+	for key, values := range res.Headers {
+		for _, v := range values {
+			w.Header().Add(key, v)
+		}
+	}
+	for _, c := range res.Cookies {
+		http.SetCookie(w, c)
+	}
+	if res.Status != 0 {
+		status = res.Status
+	}
+	return status, res.Value, res.Raw
+}
+
+// writeResponse encodes a typed handler's response as JSON, or with a
+// negotiated codec, with the given default status, applying Result
+// metadata, collection page links, the "fields" selection of the request
+// under WithSparseFields and the response envelope.
+func writeResponse(w http.ResponseWriter, r *http.Request, status int, res any) error {
+	raw := false
+	if rr, ok := res.(result); ok {
+		status, res, raw = rr.apply(w, status)
+	}
+	if status == http.StatusNoContent || status == http.StatusNotModified {
+		w.WriteHeader(status)
+		return nil
+	}
+	if !raw && status < http.StatusMultipleChoices {
+		res = addPageLinks(w, r, res)
+	}
+	if fields := sparseFieldsFrom(r); fields != nil && !raw && status < http.StatusMultipleChoices {
+		var err error
+		if res, err = applyFields(res, fields); err != nil {
+			return err
+		}
+	}
+	if envelope := envelopeFrom(r); envelope != nil && !raw {
+		res = envelope(status, res)
+	}
+	return writeEncoded(w, r, status, res)
+}
@@ -0,0 +1,127 @@
+package helix
+
+import (
+	"cmp"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// RouteConflict describes two registered routes that can match the same
+// request.
+type RouteConflict struct {
+	// Method is the HTTP method of both routes.
+	Method string
+
+	// Pattern and Site are the pattern and registration call site, as
+	// "file:line", of the route registered later.
+	Pattern string
+	Site    string
+
+	// Existing and ExistingSite are the pattern and call site of the route
+	// registered first.
+	Existing     string
+	ExistingSite string
+
+	// Overlap reports a route that shadows part of the other, such as
+	// "/users/new" and "/users/{id}". The router resolves overlaps by
+	// precedence (static segments, then typed, then untyped parameters), so
+	// they are reported but allowed. Other conflicts, duplicate patterns,
+	// including ones differing only in parameter names, are errors.
+	Overlap bool
+
+	// Reason explains the conflict.
+	Reason string
+}
+
+// Error implements error.
+func (c RouteConflict) Error() string {
+	return fmt.Sprintf("helix: route %s %s (%s) %s %s (%s)",
+		c.Method, c.Pattern, c.Site, c.Reason, c.Existing, c.ExistingSite)
+}
+
+// routeEntry is a registered route with its parsed pattern.
+type routeEntry struct {
+	info     RouteInfo
+	segments []segment
+}
+
+// callSite returns the "file:line" of the first caller outside helix, the
+// code that registered a route.
+func callSite() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "github.com/kolosys/helix.") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// findConflict compares a new route with an existing one of the same
+// method and returns their conflict, if any.
+func findConflict(route, existing routeEntry) (RouteConflict, bool) {
+	c := RouteConflict{
+		Method:       route.info.Method,
+		Pattern:      route.info.Pattern,
+		Site:         route.info.Site,
+		Existing:     existing.info.Pattern,
+		ExistingSite: existing.info.Site,
+	}
+
+	a, b := route.segments, existing.segments
+	shadowed := false
+	renamed := ""
+	for i := 0; ; i++ {
+		if i == len(a) || i == len(b) {
+			if len(a) != len(b) {
+				return c, false
+			}
+			if shadowed {
+				c.Overlap, c.Reason = true, "overlaps"
+				return c, true
+			}
+			c.Reason = cmp.Or(renamed, "duplicates")
+			return c, true
+		}
+
+		x, y := a[i], b[i]
+		switch {
+		case x.catchAll || y.catchAll:
+			if x.catchAll && y.catchAll && !shadowed {
+				c.Reason = cmp.Or(renamed, "duplicates")
+				return c, true
+			}
+			c.Overlap, c.Reason = true, "overlaps"
+			return c, true
+		case !x.isParam && !y.isParam:
+			if x.value != y.value {
+				return c, false
+			}
+		case x.isParam && y.isParam:
+			if x.typ != y.typ {
+				shadowed = true
+				continue
+			}
+			if x.value != y.value && renamed == "" {
+				// Reported only if the routes have the same shape; names
+				// are kept per route, so other routes may differ
+				renamed = fmt.Sprintf("renames parameter {%s} to {%s} at the same position as", y.value, x.value)
+			}
+		default:
+			static, param := x, y
+			if x.isParam {
+				static, param = y, x
+			}
+			if param.typ != "" && !lookupParamType(param.typ)(static.value) {
+				return c, false
+			}
+			shadowed = true
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package helix
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// RoutesFormat is a format for ExportRoutes.
+type RoutesFormat int
+
+const (
+	// RoutesText is the aligned method and pattern list of PrintRoutes.
+	RoutesText RoutesFormat = iota
+
+	// RoutesJSON is a JSON array of RouteInfo objects.
+	RoutesJSON
+
+	// RoutesMarkdown is a Markdown table for documentation.
+	RoutesMarkdown
+
+	// RoutesOpenAPI is an OpenAPI 3.1 document skeleton with the paths,
+	// operations and path parameters of the routes, to be completed with
+	// schemas by a docs pipeline.
+	RoutesOpenAPI
+)
+
+// ExportRoutes writes the registered routes to w in format, sorted by
+// pattern, then by method, so exports can be compared in CI to catch
+// route drift.
+//
+// Example:
+//
+//	if err := s.ExportRoutes(os.Stdout, helix.RoutesJSON); err != nil {
+//	    log.Fatal(err)
+//	}
+func (s *Server) ExportRoutes(w io.Writer, format RoutesFormat) error {
+	routes := sortedRoutes(s.Routes())
+	switch format {
+	case RoutesText:
+		s.PrintRoutes(w)
+		return nil
+	case RoutesJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(routes)
+	case RoutesMarkdown:
+		return writeRoutesMarkdown(w, routes)
+	case RoutesOpenAPI:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(openAPIDocument(routes))
+	default:
+		return fmt.Errorf("helix: unknown routes format %d", format)
+	}
+}
+
+// sortedRoutes sorts routes by pattern, then by method.
+func sortedRoutes(routes []RouteInfo) []RouteInfo {
+	slices.SortStableFunc(routes, func(a, b RouteInfo) int {
+		return cmp.Or(strings.Compare(a.Pattern, b.Pattern), strings.Compare(a.Method, b.Method))
+	})
+	return routes
+}
+
+// writeRoutesMarkdown writes routes as a Markdown table.
+func writeRoutesMarkdown(w io.Writer, routes []RouteInfo) error {
+	cell := func(s string) string {
+		return strings.ReplaceAll(s, "|", `\|`)
+	}
+
+	var b strings.Builder
+	b.WriteString("| Method | Pattern | Handler | Middleware | Summary |\n")
+	b.WriteString("|--------|---------|---------|------------|---------|\n")
+	for _, r := range routes {
+		fmt.Fprintf(&b, "| %s | `%s` | %s | %s | %s |\n",
+			r.Method, cell(r.Pattern), cell(r.Handler), cell(strings.Join(r.Middleware, ", ")), cell(r.Metadata["summary"]))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// openAPIDocument builds an OpenAPI document skeleton for routes.
+func openAPIDocument(routes []RouteInfo) map[string]any {
+	paths := make(map[string]map[string]any)
+	for _, r := range routes {
+		path, params := openAPIPath(r.Pattern)
+		op := map[string]any{
+			"responses": map[string]any{"default": map[string]any{"description": "Response"}},
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+		if v := r.Metadata["summary"]; v != "" {
+			op["summary"] = v
+		}
+		if v := r.Metadata["description"]; v != "" {
+			op["description"] = v
+		}
+		if v := r.Metadata["tags"]; v != "" {
+			tags := strings.Split(v, ",")
+			for i := range tags {
+				tags[i] = strings.TrimSpace(tags[i])
+			}
+			op["tags"] = tags
+		}
+		if r.Handler != "" {
+			op["x-handler"] = r.Handler
+		}
+
+		if paths[path] == nil {
+			paths[path] = make(map[string]any)
+		}
+		paths[path][strings.ToLower(r.Method)] = op
+	}
+
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info":    map[string]any{"title": "API", "version": "1.0.0"},
+		"paths":   paths,
+	}
+}
+
+// openAPIPath converts a route pattern to an OpenAPI path template and its
+// path parameters. OpenAPI cannot express catch-all parameters, which
+// become single parameters.
+func openAPIPath(pattern string) (string, []map[string]any) {
+	segments := parsePattern(pattern)
+	if len(segments) == 0 {
+		return "/", nil
+	}
+
+	var path strings.Builder
+	var params []map[string]any
+	for _, seg := range segments {
+		path.WriteByte('/')
+		if !seg.isParam {
+			path.WriteString(seg.value)
+			continue
+		}
+		path.WriteString("{" + seg.value + "}")
+		params = append(params, map[string]any{
+			"name":     seg.value,
+			"in":       "path",
+			"required": true,
+			"schema":   openAPIParamSchema(seg.typ),
+		})
+	}
+	return path.String(), params
+}
+
+// openAPIParamSchema returns the schema of a path parameter of type typ.
+func openAPIParamSchema(typ ParamType) map[string]any {
+	if values := typ.EnumValues(); values != nil {
+		return map[string]any{"type": "string", "enum": values}
+	}
+	switch typ {
+	case Int:
+		return map[string]any{"type": "integer"}
+	case UUID:
+		return map[string]any{"type": "string", "format": "uuid"}
+	case Alpha:
+		return map[string]any{"type": "string", "pattern": "^[A-Za-z]+$"}
+	case Alnum:
+		return map[string]any{"type": "string", "pattern": "^[A-Za-z0-9]+$"}
+	case Slug:
+		return map[string]any{"type": "string", "pattern": "^[a-z0-9-]+$"}
+	}
+	return map[string]any{"type": "string"}
+}
+
+// funcName returns the package-qualified name of the function fn, as
+// "middleware.CORSWithConfig", without the suffixes of closures.
+func funcName(fn any) string {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return ""
+	}
+	f := runtime.FuncForPC(v.Pointer())
+	if f == nil {
+		return ""
+	}
+	name := f.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	for {
+		i := strings.LastIndex(name, ".")
+		if i < 0 || !isClosureSuffix(name[i+1:]) {
+			break
+		}
+		name = name[:i]
+	}
+	return strings.TrimSuffix(name, "-fm")
+}
+
+// isClosureSuffix reports whether s is a closure name element such as
+// "func1" or "2".
+func isClosureSuffix(s string) bool {
+	s = strings.TrimPrefix(s, "func")
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package helix_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/kolosys/helix"
+	"github.com/kolosys/helix/middleware"
+)
+
+func exportListUsers(w http.ResponseWriter, r *http.Request) {}
+
+func exportGetUser(w http.ResponseWriter, r *http.Request) {}
+
+func exportRoutesServer() *Server {
+	s := New(nil)
+	s.Use(middleware.RequestID())
+	api := s.Group("/api", middleware.Recover())
+	api.GET("/users/{id:int}", exportGetUser)
+	api.GET("/users", exportListUsers,
+		WithRouteMetadata("summary", "List users"),
+		WithRouteMetadata("tags", "users, admin"),
+		WithRouteMiddleware(middleware.ETag()))
+	return s
+}
+
+func TestExportRoutesJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := exportRoutesServer().ExportRoutes(&buf, RoutesJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	var routes []RouteInfo
+	if err := json.Unmarshal(buf.Bytes(), &routes); err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 2 || routes[0].Pattern != "/api/users" || routes[1].Pattern != "/api/users/{id:int}" {
+		t.Fatalf("unexpected routes %+v", routes)
+	}
+
+	list := routes[0]
+	if list.Handler != "helix_test.exportListUsers" {
+		t.Errorf("unexpected handler %q", list.Handler)
+	}
+	want := []string{"middleware.RequestIDWithConfig", "middleware.RecoverWithConfig", "middleware.ETagWithConfig"}
+	if strings.Join(list.Middleware, ",") != strings.Join(want, ",") {
+		t.Errorf("expected middleware %v, got %v", want, list.Middleware)
+	}
+	if list.Metadata["summary"] != "List users" {
+		t.Errorf("unexpected metadata %v", list.Metadata)
+	}
+	if strings.Contains(buf.String(), "routeexport_test.go") {
+		t.Error("expected call sites to be left out")
+	}
+}
+
+func TestExportRoutesMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := exportRoutesServer().ExportRoutes(&buf, RoutesMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	row := "| GET | `/api/users` | helix_test.exportListUsers | middleware.RequestIDWithConfig, middleware.RecoverWithConfig, middleware.ETagWithConfig | List users |"
+	if !strings.Contains(buf.String(), row) {
+		t.Errorf("expected row %q in\n%s", row, buf.String())
+	}
+}
+
+func TestExportRoutesOpenAPI(t *testing.T) {
+	var buf bytes.Buffer
+	if err := exportRoutesServer().ExportRoutes(&buf, RoutesOpenAPI); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			Summary    string   `json:"summary"`
+			Tags       []string `json:"tags"`
+			Parameters []struct {
+				Name   string         `json:"name"`
+				In     string         `json:"in"`
+				Schema map[string]any `json:"schema"`
+			} `json:"parameters"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	get := doc.Paths["/api/users/{id}"]["get"]
+	if len(get.Parameters) != 1 || get.Parameters[0].Name != "id" || get.Parameters[0].Schema["type"] != "integer" {
+		t.Errorf("unexpected parameters %+v", get.Parameters)
+	}
+	list := doc.Paths["/api/users"]["get"]
+	if list.Summary != "List users" || strings.Join(list.Tags, ",") != "users,admin" {
+		t.Errorf("unexpected operation %+v", list)
+	}
+}
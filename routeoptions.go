@@ -0,0 +1,180 @@
+package helix
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/kolosys/helix/middleware"
+)
+
+// RouteOption configures a single route. Pass route options after the
+// handler when registering it:
+//
+//	s.POST("/upload", upload, helix.WithRouteBodyLimit(100<<20), helix.WithRouteTimeout(5*time.Minute))
+type RouteOption func(*routeOptions)
+
+// routeOptions collects the options of a route.
+type routeOptions struct {
+	middleware []Middleware
+	metadata   map[string]string
+	cors       *middleware.CORSConfig
+}
+
+// newRoute wraps handler with the middleware of opts and then with outer,
+// the middleware of its groups, and describes the route for Routes. Route
+// middleware runs inside group and server middleware, in the order given;
+// a route's own CORS policy runs outside group middleware.
+func newRoute(method, pattern string, handler http.HandlerFunc, opts []RouteOption, outer []Middleware) (RouteInfo, http.HandlerFunc) {
+	var ro routeOptions
+	for _, opt := range opts {
+		opt(&ro)
+	}
+	middleware := append(slices.Clip(outer), ro.middleware...)
+	var preflight http.HandlerFunc
+	if ro.cors != nil {
+		cors := routeCORS(*ro.cors)
+		middleware = append([]Middleware{cors}, middleware...)
+		preflight = cors(http.HandlerFunc(noContent)).ServeHTTP
+	}
+
+	route := RouteInfo{
+		Method:     method,
+		Pattern:    pattern,
+		Handler:    funcName(handler),
+		Middleware: make([]string, len(middleware)),
+		Metadata:   ro.metadata,
+		preflight:  preflight,
+	}
+	for i, mw := range middleware {
+		route.Middleware[i] = funcName(mw)
+	}
+	if len(middleware) == 0 {
+		return route, handler
+	}
+
+	var h http.Handler = handler
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return route, h.ServeHTTP
+}
+
+// WithRouteMetadata attaches key and value to the route for introspection,
+// as reported by Server.Routes and the route exports. The "summary",
+// "description" and "tags" (comma-separated) keys are used by the OpenAPI
+// export.
+//
+// Example:
+//
+//	s.GET("/users", listUsers, helix.WithRouteMetadata("summary", "List users"))
+func WithRouteMetadata(key, value string) RouteOption {
+	return func(ro *routeOptions) {
+		if ro.metadata == nil {
+			ro.metadata = make(map[string]string)
+		}
+		ro.metadata[key] = value
+	}
+}
+
+// WithRouteMiddleware applies middleware to the route only.
+// Accepts the same middleware types as Server.Use.
+func WithRouteMiddleware(mw ...any) RouteOption {
+	converted := toMiddleware(mw)
+	return func(ro *routeOptions) {
+		ro.middleware = append(ro.middleware, converted...)
+	}
+}
+
+// WithRouteCORS gives the route a CORS policy of its own, replacing the
+// server's CORS middleware for its requests and preflights, as for a public
+// endpoint in an otherwise locked-down API. The policy runs ahead of group
+// middleware, so authentication does not reject preflights. See also
+// Group.CORS.
+//
+// Example:
+//
+//	s.Use(middleware.CORSWithConfig(middleware.CORSConfig{AllowOrigins: []string{"https://app.example.com"}}))
+//	s.GET("/widget.js", widget, helix.WithRouteCORS(middleware.DefaultCORSConfig()))
+func WithRouteCORS(config middleware.CORSConfig) RouteOption {
+	return func(ro *routeOptions) {
+		ro.cors = &config
+	}
+}
+
+// routeCORS returns the middleware applying a route's own CORS policy.
+// The router sends it the route's preflights, with noContent as the
+// handler for those it does not answer.
+func routeCORS(config middleware.CORSConfig) Middleware {
+	cors := middleware.CORSWithConfig(config)
+	return func(next http.Handler) http.Handler {
+		h := cors(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h.ServeHTTP(w, r.WithContext(middleware.WithCORSOverride(r.Context(), nil)))
+		})
+	}
+}
+
+// noContent answers with 204 No Content.
+func noContent(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// WithRouteBodyLimit limits the route's request body to n bytes. Requests
+// declaring a larger Content-Length are rejected with 413 before the handler
+// runs; reading past the limit fails with an *http.MaxBytesError, which the
+// default error handling also reports as 413.
+func WithRouteBodyLimit(n int64) RouteOption {
+	if n <= 0 {
+		panic("helix: route body limit must be positive")
+	}
+	return WithRouteMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				handleError(w, r, ErrPayloadTooLarge.WithDetailf("request body exceeds %d bytes", n))
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+			next.ServeHTTP(w, r)
+		})
+	})
+}
+
+// WithRouteTimeout gives the route d to complete, replacing the server's
+// ReadTimeout and WriteTimeout for its requests and setting a context
+// deadline, so slow endpoints such as uploads can run longer than the rest
+// of the API, or fast ones can be cut short.
+func WithRouteTimeout(d time.Duration) RouteOption {
+	if d <= 0 {
+		panic("helix: route timeout must be positive")
+	}
+	return WithRouteMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			deadline := time.Now().Add(d)
+			// Writers without deadline support, such as test recorders,
+			// only get the context deadline
+			rc := http.NewResponseController(w)
+			rc.SetReadDeadline(deadline)
+			rc.SetWriteDeadline(deadline)
+
+			ctx, cancel := context.WithDeadline(r.Context(), deadline)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+}
+
+// WithRouteReadTimeout gives the route d to read the request body,
+// replacing the server's ReadTimeout for its requests.
+func WithRouteReadTimeout(d time.Duration) RouteOption {
+	if d <= 0 {
+		panic("helix: route read timeout must be positive")
+	}
+	return WithRouteMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.NewResponseController(w).SetReadDeadline(time.Now().Add(d))
+			next.ServeHTTP(w, r)
+		})
+	})
+}
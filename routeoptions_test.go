@@ -0,0 +1,156 @@
+package helix_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/helix"
+	"github.com/kolosys/helix/middleware"
+)
+
+func TestWithRouteBodyLimit(t *testing.T) {
+	type upload struct {
+		Data string `json:"data"`
+	}
+
+	s := New(nil)
+	api := s.Group("/api")
+	api.POST("/small", Handle(func(ctx context.Context, req upload) (upload, error) {
+		return req, nil
+	}), WithRouteBodyLimit(16))
+	api.POST("/large", Handle(func(ctx context.Context, req upload) (upload, error) {
+		return req, nil
+	}))
+
+	body := `{"data":"` + strings.Repeat("x", 64) + `"}`
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/small", strings.NewReader(body)))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for a declared oversized body, got %d", rec.Code)
+	}
+
+	// Without Content-Length the limit applies while reading
+	req := httptest.NewRequest(http.MethodPost, "/api/small", strings.NewReader(body))
+	req.ContentLength = -1
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 when reading past the limit, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/large", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected other routes to be unaffected, got %d", rec.Code)
+	}
+}
+
+func TestWithRouteTimeout(t *testing.T) {
+	var deadline time.Time
+	var hasDeadline bool
+	var order []string
+
+	s := New(nil)
+	s.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			order = append(order, "server")
+			next.ServeHTTP(w, r)
+		})
+	})
+	s.GET("/export", func(w http.ResponseWriter, r *http.Request) {
+		deadline, hasDeadline = r.Context().Deadline()
+		order = append(order, "handler")
+	}, WithRouteTimeout(5*time.Minute), WithRouteMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			order = append(order, "route")
+			next.ServeHTTP(w, r)
+		})
+	}))
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	resp, err := http.Get(ts.URL + "/export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if !hasDeadline || time.Until(deadline) < 4*time.Minute {
+		t.Errorf("expected a 5 minute context deadline, got %v", deadline)
+	}
+	if strings.Join(order, ",") != "server,route,handler" {
+		t.Errorf("unexpected middleware order %v", order)
+	}
+}
+
+func TestWithRouteCORS(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	requireAuth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	s := New(nil)
+	s.Use(middleware.CORSWithConfig(middleware.CORSConfig{AllowOrigins: []string{"https://app.example.com"}}))
+	s.GET("/private", ok)
+	s.GET("/widget", ok, WithRouteCORS(middleware.DefaultCORSConfig()))
+	public := s.Group("/public", requireAuth)
+	public.CORS(middleware.CORSConfig{AllowOrigins: []string{"https://partner.example"}, MaxAge: 600})
+	public.POST("/events", ok)
+
+	get := func(path, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+	preflight := func(path, origin, method string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, path, nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if got := get("/private", "https://evil.example").Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("expected the global policy to reject the origin, got %q", got)
+	}
+	if got := get("/widget", "https://evil.example").Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("expected the route policy to allow any origin, got %q", got)
+	}
+	if got := get("/widget", "https://app.example.com").Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("expected only the route policy to apply, got %q", got)
+	}
+
+	rec := preflight("/widget", "https://evil.example", http.MethodGet)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("expected the route policy to answer the preflight, got %d %q", rec.Code, rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	rec = preflight("/public/events", "https://partner.example", http.MethodPost)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "https://partner.example" ||
+		rec.Header().Get("Access-Control-Max-Age") != "600" {
+		t.Errorf("expected the group policy to answer the preflight ahead of auth, got %d %v", rec.Code, rec.Header())
+	}
+	rec = preflight("/public/events", "https://app.example.com", http.MethodPost)
+	if rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("expected a preflight from an origin the group rejects to fail, got %d %v", rec.Code, rec.Header())
+	}
+
+	rec = preflight("/private", "https://app.example.com", http.MethodGet)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Errorf("expected the global policy to answer other preflights, got %d %v", rec.Code, rec.Header())
+	}
+}
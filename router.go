@@ -1,46 +1,82 @@
 package helix
 
 import (
+	"log"
 	"net/http"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
+
+	"github.com/kolosys/helix/middleware"
 )
 
 // RouteInfo contains information about a registered route.
 type RouteInfo struct {
-	Method  string
-	Pattern string
+	Method  string `json:"method"`
+	Pattern string `json:"pattern"`
+
+	// Handler identifies the handler function, as "main.listUsers".
+	// Handlers built by helix, such as typed handlers, report helix's
+	// wrapper.
+	Handler string `json:"handler,omitempty"`
+
+	// Middleware names the group and route middleware of the route,
+	// outermost first. Server.Routes adds the server's middleware.
+	Middleware []string `json:"middleware,omitempty"`
+
+	// Metadata holds the values set with WithRouteMetadata.
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Site is the "file:line" that registered the route. It is left out of
+	// route exports, as it changes with unrelated edits.
+	Site string `json:"-"`
+
+	preflight http.HandlerFunc // answers preflights with the route's own CORS policy
 }
 
 // Router handles HTTP request routing.
 type Router struct {
 	trees       map[string]*routeNode    // method -> root
-	routes      []RouteInfo              // registered routes for introspection
+	routes      []routeEntry             // registered routes for introspection
+	conflicts   []RouteConflict          // conflicts found on registration
+	warnOnly    bool                     // log conflicts instead of panicking
+	profiler    *routeProfiler           // per-route counters, if enabled
 	mu          sync.RWMutex             // For tree map access
 	methodLocks map[string]*sync.RWMutex // Per-method locks for reduced contention
 	methodMu    sync.Mutex               // For methodLocks map access
 	paramsPool  sync.Pool
+	corsRoutes  atomic.Bool // some route has its own CORS policy
 }
 
 // routeNode represents a node in the routing tree.
 type routeNode struct {
-	path     string           // static path segment
-	children []*routeNode     // child nodes
-	param    *routeNode       // parameter child node
-	paramKey string           // parameter name if this is a param node
-	catchAll *routeNode       // catch-all child node
-	handler  http.HandlerFunc // handler for this route
+	path      string            // static path segment
+	children  []*routeNode      // child nodes
+	params    []*routeNode      // parameter child nodes, typed before untyped
+	paramKey  string            // parameter name if this is a param node
+	paramKeys []string          // parameter names of the route ending here
+	typ       ParamType         // parameter type if this is a typed param node
+	match     func(string) bool // matcher for typ
+	catchAll  *routeNode        // catch-all child node
+	handler   http.HandlerFunc  // handler for this route
+	pattern   string            // registered pattern for this route
+	preflight http.HandlerFunc  // preflight handler of the route's own CORS policy
 }
 
 // params holds path parameters extracted from a route.
 type params struct {
-	keys   []string
-	values []string
+	keys      []string
+	values    []string
+	pattern   string           // matched route pattern
+	preflight http.HandlerFunc // preflight handler of the matched route
 }
 
 func (p *params) reset() {
 	p.keys = p.keys[:0]
 	p.values = p.values[:0]
+	p.pattern = ""
+	p.preflight = nil
 }
 
 func (p *params) add(key, value string) {
@@ -74,6 +110,12 @@ func newRouter() *Router {
 
 // Handle registers a new route with the given method and pattern.
 func (r *Router) Handle(method, pattern string, handler http.HandlerFunc) {
+	r.handleRoute(RouteInfo{Method: method, Pattern: pattern, Handler: funcName(handler)}, handler)
+}
+
+// handleRoute registers handler for the route described by info.
+func (r *Router) handleRoute(info RouteInfo, handler http.HandlerFunc) {
+	method, pattern := info.Method, info.Pattern
 	if pattern == "" {
 		panic("helix: pattern must not be empty")
 	}
@@ -98,17 +140,60 @@ func (r *Router) Handle(method, pattern string, handler http.HandlerFunc) {
 	}
 	r.mu.Unlock()
 
+	// Parse pattern into segments
+	segments := parsePattern(pattern)
+	info.Site = callSite()
+	route := routeEntry{info: info, segments: segments}
+
 	// Track the route for introspection (needs global lock)
 	r.mu.Lock()
-	r.routes = append(r.routes, RouteInfo{
-		Method:  method,
-		Pattern: pattern,
-	})
+	add, err := r.checkConflicts(route)
+	if add {
+		r.routes = append(r.routes, route)
+	}
 	r.mu.Unlock()
+	if err != nil {
+		panic(err.Error())
+	}
+	if !add {
+		return
+	}
 
-	// Parse pattern into segments
-	segments := parsePattern(pattern)
-	r.addRoute(root, segments, handler)
+	n := r.addRoute(root, segments, pattern, handler)
+	n.paramKeys = paramNames(segments)
+	if info.preflight != nil {
+		n.preflight = info.preflight
+		r.corsRoutes.Store(true)
+	}
+}
+
+// checkConflicts records the conflicts of route with the registered routes
+// and reports whether route may be added. Conflicts other than overlaps are
+// returned as an error, or logged and the route skipped if warnOnly is set.
+// r.mu must be held.
+func (r *Router) checkConflicts(route routeEntry) (bool, error) {
+	for _, existing := range r.routes {
+		if existing.info.Method != route.info.Method {
+			continue
+		}
+		c, found := findConflict(route, existing)
+		if !found {
+			continue
+		}
+		r.conflicts = append(r.conflicts, c)
+		switch {
+		case c.Overlap:
+			if r.warnOnly {
+				log.Printf("%s; first match by precedence", c.Error())
+			}
+		case r.warnOnly:
+			log.Printf("%s; ignoring the later route", c.Error())
+			return false, nil
+		default:
+			return false, c
+		}
+	}
+	return true, nil
 }
 
 // Routes returns all registered routes.
@@ -117,15 +202,29 @@ func (r *Router) Routes() []RouteInfo {
 	defer r.mu.RUnlock()
 
 	routes := make([]RouteInfo, len(r.routes))
-	copy(routes, r.routes)
+	for i, route := range r.routes {
+		routes[i] = route.info
+	}
 	return routes
 }
 
+// Conflicts returns the route conflicts found so far, including the
+// overlaps allowed by precedence.
+func (r *Router) Conflicts() []RouteConflict {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	conflicts := make([]RouteConflict, len(r.conflicts))
+	copy(conflicts, r.conflicts)
+	return conflicts
+}
+
 // segment represents a path segment.
 type segment struct {
-	value    string // segment value (static text or param name)
-	isParam  bool   // is this a parameter?
-	catchAll bool   // is this a catch-all?
+	value    string    // segment value (static text or param name)
+	typ      ParamType // parameter type, e.g. "uuid" for {id:uuid}
+	isParam  bool      // is this a parameter?
+	catchAll bool      // is this a catch-all?
 }
 
 // parsePattern parses a pattern into segments.
@@ -156,8 +255,10 @@ func parsePattern(pattern string) []segment {
 					catchAll: true,
 				})
 			} else {
+				name, typ, _ := strings.Cut(paramName, ":")
 				segments = append(segments, segment{
-					value:   paramName,
+					value:   name,
+					typ:     ParamType(typ),
 					isParam: true,
 				})
 			}
@@ -169,14 +270,28 @@ func parsePattern(pattern string) []segment {
 	return segments
 }
 
-// addRoute adds a route to the tree.
-func (r *Router) addRoute(n *routeNode, segments []segment, handler http.HandlerFunc) {
+// paramNames returns the parameter names of a route's segments in order.
+// Routes sharing a parameter node may name it differently, so the names
+// are kept on the route's final node and applied once it matches.
+func paramNames(segments []segment) []string {
+	var names []string
+	for _, seg := range segments {
+		if seg.isParam || seg.catchAll {
+			names = append(names, seg.value)
+		}
+	}
+	return names
+}
+
+// addRoute adds a route to the tree and returns its node.
+func (r *Router) addRoute(n *routeNode, segments []segment, pattern string, handler http.HandlerFunc) *routeNode {
 	if len(segments) == 0 {
 		if n.handler != nil {
 			panic("helix: route already registered")
 		}
 		n.handler = handler
-		return
+		n.pattern = pattern
+		return n
 	}
 
 	seg := segments[0]
@@ -187,27 +302,48 @@ func (r *Router) addRoute(n *routeNode, segments []segment, handler http.Handler
 			n.catchAll = &routeNode{paramKey: seg.value}
 		}
 		n.catchAll.handler = handler
-		return
+		n.catchAll.pattern = pattern
+		return n.catchAll
 	}
 
 	if seg.isParam {
-		if n.param == nil {
-			n.param = &routeNode{paramKey: seg.value}
-		}
-		r.addRoute(n.param, remaining, handler)
-		return
+		return r.addRoute(n.paramChild(seg), remaining, pattern, handler)
 	}
 
 	for _, child := range n.children {
 		if child.path == seg.value {
-			r.addRoute(child, remaining, handler)
-			return
+			return r.addRoute(child, remaining, pattern, handler)
 		}
 	}
 
 	child := &routeNode{path: seg.value}
 	n.children = append(n.children, child)
-	r.addRoute(child, remaining, handler)
+	return r.addRoute(child, remaining, pattern, handler)
+}
+
+// paramChild returns the parameter child for seg, creating it if needed.
+// Each parameter type has one child; typed children are kept ahead of the
+// untyped one so that they are tried first.
+func (n *routeNode) paramChild(seg segment) *routeNode {
+	for _, child := range n.params {
+		if child.typ == seg.typ {
+			return child
+		}
+	}
+
+	child := &routeNode{paramKey: seg.value, typ: seg.typ}
+	if seg.typ == "" {
+		n.params = append(n.params, child)
+		return child
+	}
+
+	child.match = lookupParamType(seg.typ)
+	i := len(n.params)
+	if i > 0 && n.params[i-1].typ == "" {
+		i--
+	}
+	n.params = append(n.params[:i], append([]*routeNode{child}, n.params[i:]...)...)
+	return child
 }
 
 // getMethodLock returns the RWMutex for the given HTTP method.
@@ -231,24 +367,17 @@ func (r *Router) getMethodLock(method string) *sync.RWMutex {
 
 // ServeHTTP implements http.Handler.
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	path := req.URL.Path
+	ps := r.paramsPool.Get().(*params)
+	ps.reset()
 
-	// Use per-method lock for reduced contention
-	methodLock := r.getMethodLock(req.Method)
-	methodLock.RLock()
-	root := r.trees[req.Method]
-	methodLock.RUnlock()
+	handler := r.find(req.Method, req.URL.Path, ps)
 
-	if root == nil {
-		http.NotFound(w, req)
+	if handler == nil && req.Method == http.MethodOptions {
+		r.paramsPool.Put(ps)
+		r.serveOptions(w, req)
 		return
 	}
 
-	ps := r.paramsPool.Get().(*params)
-	ps.reset()
-
-	handler := r.lookup(root, path, ps)
-
 	if handler == nil {
 		r.paramsPool.Put(ps)
 		http.NotFound(w, req)
@@ -260,11 +389,117 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		req = req.WithContext(ctx)
 	}
 
-	handler(w, req)
+	// Report the matched pattern to middleware tracking it (e.g. ContextLogger)
+	middleware.SetRoutePattern(req, ps.pattern)
+
+	if r.profiler != nil {
+		r.profiler.serve(req.Method, ps.pattern, handler, w, req)
+	} else {
+		handler(w, req)
+	}
 
 	r.paramsPool.Put(ps)
 }
 
+// find finds the handler of the route for method and path.
+func (r *Router) find(method, path string, ps *params) http.HandlerFunc {
+	// Use per-method lock for reduced contention
+	methodLock := r.getMethodLock(method)
+	methodLock.RLock()
+	root := r.trees[method]
+	methodLock.RUnlock()
+
+	if root == nil {
+		return nil
+	}
+	return r.lookup(root, path, ps)
+}
+
+// serveOptions answers OPTIONS requests for paths without an OPTIONS
+// route, listing the path's methods in the Allow header. Preflights for
+// routes with their own CORS policy are answered by that policy; the
+// server's CORS middleware answers the others before they get here.
+func (r *Router) serveOptions(w http.ResponseWriter, req *http.Request) {
+	methods := r.allowedMethods(req)
+	if len(methods) == 0 {
+		http.NotFound(w, req)
+		return
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+
+	if isPreflight(req) && r.corsRoutes.Load() {
+		ps := r.paramsPool.Get().(*params)
+		ps.reset()
+		r.find(req.Header.Get("Access-Control-Request-Method"), req.URL.Path, ps)
+		preflight := ps.preflight
+		r.paramsPool.Put(ps)
+		if preflight != nil {
+			preflight(w, req)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// allowedMethods returns the methods registered for the path of req,
+// sorted, with OPTIONS, which the router answers, or nil if there are
+// none.
+func (r *Router) allowedMethods(req *http.Request) []string {
+	r.mu.RLock()
+	registered := make([]string, 0, len(r.trees))
+	for method := range r.trees {
+		registered = append(registered, method)
+	}
+	r.mu.RUnlock()
+
+	ps := r.paramsPool.Get().(*params)
+	defer r.paramsPool.Put(ps)
+
+	var methods []string
+	for _, method := range registered {
+		ps.reset()
+		if r.find(method, req.URL.Path, ps) != nil {
+			methods = append(methods, method)
+		}
+	}
+	if len(methods) == 0 {
+		return nil
+	}
+	if !slices.Contains(methods, http.MethodOptions) {
+		methods = append(methods, http.MethodOptions)
+	}
+	slices.Sort(methods)
+	return methods
+}
+
+// ownsCORS reports whether the route for req, or for the method requested
+// by a preflight, has its own CORS policy.
+func (r *Router) ownsCORS(req *http.Request) bool {
+	if !r.corsRoutes.Load() {
+		return false
+	}
+	ps := r.paramsPool.Get().(*params)
+	defer r.paramsPool.Put(ps)
+
+	ps.reset()
+	if r.find(req.Method, req.URL.Path, ps) != nil {
+		return ps.preflight != nil
+	}
+	if isPreflight(req) {
+		ps.reset()
+		if r.find(req.Header.Get("Access-Control-Request-Method"), req.URL.Path, ps) != nil {
+			return ps.preflight != nil
+		}
+	}
+	return false
+}
+
+// isPreflight reports whether req is a CORS preflight request.
+func isPreflight(req *http.Request) bool {
+	return req.Method == http.MethodOptions && req.Header.Get("Origin") != "" &&
+		req.Header.Get("Access-Control-Request-Method") != ""
+}
+
 // lookup finds a handler for the given path.
 func (r *Router) lookup(n *routeNode, path string, ps *params) http.HandlerFunc {
 	// Remove leading slash
@@ -278,6 +513,11 @@ func (r *Router) lookup(n *routeNode, path string, ps *params) http.HandlerFunc
 // lookupRecursive recursively searches for a matching route.
 func (r *Router) lookupRecursive(n *routeNode, path string, ps *params) http.HandlerFunc {
 	if path == "" {
+		if n.handler != nil {
+			ps.pattern = n.pattern
+			ps.preflight = n.preflight
+			copy(ps.keys, n.paramKeys)
+		}
 		return n.handler
 	}
 
@@ -299,9 +539,12 @@ func (r *Router) lookupRecursive(n *routeNode, path string, ps *params) http.Han
 		}
 	}
 
-	if n.param != nil {
-		ps.add(n.param.paramKey, segment)
-		if handler := r.lookupRecursive(n.param, remaining, ps); handler != nil {
+	for _, param := range n.params {
+		if param.match != nil && !param.match(segment) {
+			continue
+		}
+		ps.add(param.paramKey, segment)
+		if handler := r.lookupRecursive(param, remaining, ps); handler != nil {
 			return handler
 		}
 		ps.keys = ps.keys[:len(ps.keys)-1]
@@ -314,6 +557,9 @@ func (r *Router) lookupRecursive(n *routeNode, path string, ps *params) http.Han
 			fullPath = segment + "/" + remaining
 		}
 		ps.add(n.catchAll.paramKey, fullPath)
+		ps.pattern = n.catchAll.pattern
+		ps.preflight = n.catchAll.preflight
+		copy(ps.keys, n.catchAll.paramKeys)
 		return n.catchAll.handler
 	}
 
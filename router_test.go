@@ -1,11 +1,17 @@
 package helix_test
 
 import (
+	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
 
 	. "github.com/kolosys/helix"
+	"github.com/kolosys/helix/middleware"
 )
 
 func TestRouterStaticRoutes(t *testing.T) {
@@ -182,6 +188,90 @@ func TestRouterMethodNotFound(t *testing.T) {
 	}
 }
 
+func TestRouterOptions(t *testing.T) {
+	r := NewRouter()
+	ok := func(w http.ResponseWriter, req *http.Request) {}
+	r.Handle(http.MethodGet, "/users/{id}", ok)
+	r.Handle(http.MethodDelete, "/users/{id}", ok)
+	r.Handle(http.MethodOptions, "/custom", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/users/1", nil))
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Allow") != "DELETE, GET, OPTIONS" {
+		t.Errorf("expected 204 with the path's methods, got %d %q", rec.Code, rec.Header().Get("Allow"))
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a path without routes, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/custom", nil))
+	if rec.Code != http.StatusTeapot {
+		t.Errorf("expected OPTIONS routes to take precedence, got %d", rec.Code)
+	}
+}
+
+func TestServerPreflights(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	s := New(nil)
+	s.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins: []string{"https://app.example.com"},
+		MaxAge:       3600,
+	}))
+	s.GET("/items", ok)
+	s.POST("/items", ok)
+	admin := s.Group("/admin")
+	admin.CORS(middleware.CORSConfig{AllowOrigins: []string{"https://admin.example.com"}, MaxAge: -1})
+	admin.GET("/items", ok)
+	admin.DELETE("/items", ok)
+	reports := s.Group("/reports")
+	reports.CORS(middleware.CORSConfig{AllowOrigins: []string{"https://reports.example.com"}})
+	reports.GET("/daily", ok)
+
+	preflight := func(path, origin, method string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, path, nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, tc := range []struct {
+		path, origin, method string
+		methods, maxAge      string
+	}{
+		{"/items", "https://app.example.com", http.MethodPost, "GET, OPTIONS, POST", "3600"},
+		{"/admin/items", "https://admin.example.com", http.MethodDelete, "DELETE, GET, OPTIONS", "0"},
+		{"/reports/daily", "https://reports.example.com", http.MethodGet, "GET, OPTIONS", ""},
+	} {
+		rec := preflight(tc.path, tc.origin, tc.method)
+		h := rec.Header()
+		if rec.Code != http.StatusNoContent || h.Get("Access-Control-Allow-Origin") != tc.origin {
+			t.Errorf("%s: expected the preflight to be allowed, got %d %v", tc.path, rec.Code, h)
+		}
+		if h.Get("Access-Control-Allow-Methods") != tc.methods {
+			t.Errorf("%s: expected methods %q, got %q", tc.path, tc.methods, h.Get("Access-Control-Allow-Methods"))
+		}
+		if v, ok := h["Access-Control-Max-Age"]; (tc.maxAge == "" && ok) || (tc.maxAge != "" && (len(v) != 1 || v[0] != tc.maxAge)) {
+			t.Errorf("%s: expected Access-Control-Max-Age %q, got %v", tc.path, tc.maxAge, v)
+		}
+	}
+
+	// Each group's policy applies to its own routes only
+	if rec := preflight("/reports/daily", "https://admin.example.com", http.MethodGet); rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("expected another group's origin to be rejected, got %v", rec.Header())
+	}
+	if rec := preflight("/missing", "https://app.example.com", http.MethodGet); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a preflight to an unknown path, got %d", rec.Code)
+	}
+}
+
 func TestRouterDifferentMethods(t *testing.T) {
 	r := NewRouter()
 
@@ -262,6 +352,97 @@ func TestRouterPanics(t *testing.T) {
 	})
 }
 
+func TestRouteConflicts(t *testing.T) {
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+
+	t.Run("duplicate reports both call sites", func(t *testing.T) {
+		defer func() {
+			msg := fmt.Sprint(recover())
+			if strings.Count(msg, "router_test.go:") != 2 || !strings.Contains(msg, "GET /users/{uid} (") {
+				t.Errorf("unexpected panic %q", msg)
+			}
+		}()
+		s := New(nil)
+		s.GET("/users/{id}", noop)
+		s.GET("/users/{uid}", noop)
+	})
+
+	t.Run("renamed parameter", func(t *testing.T) {
+		defer func() {
+			if msg := fmt.Sprint(recover()); !strings.Contains(msg, "renames parameter {id} to {uid}") {
+				t.Errorf("unexpected panic %q", msg)
+			}
+		}()
+		s := New(nil)
+		s.GET("/users/{id}/posts", noop)
+		s.GET("/users/{uid}/posts", noop)
+	})
+
+	t.Run("parameter names are per route", func(t *testing.T) {
+		for _, opts := range []*Options{nil, {WarnRouteConflicts: true}} {
+			s := New(opts)
+			s.GET("/users/{id}", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("user " + Param(r, "id"))) })
+			s.GET("/users/{userID}/posts", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("posts " + Param(r, "userID"))) })
+			s.GET("/users/{user_id}/files/{path...}", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("files " + Param(r, "user_id") + " " + Param(r, "path")))
+			})
+
+			for path, want := range map[string]string{
+				"/users/7":               "user 7",
+				"/users/7/posts":         "posts 7",
+				"/users/7/files/a/b.txt": "files 7 a/b.txt",
+			} {
+				rec := httptest.NewRecorder()
+				s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+				if rec.Body.String() != want {
+					t.Errorf("GET %s: expected %q, got %d %q", path, want, rec.Code, rec.Body.String())
+				}
+			}
+			if c := s.RouteConflicts(); len(c) != 0 {
+				t.Errorf("expected no conflicts, got %v", c)
+			}
+		}
+	})
+
+	t.Run("overlaps are allowed", func(t *testing.T) {
+		s := New(nil)
+		s.GET("/users/{id}", noop)
+		s.GET("/users/new", noop)
+		s.GET("/users/{id:int}", noop)
+		s.GET("/orders/{id:int}", noop)
+		s.GET("/orders/new", noop) // cannot match an int
+		s.POST("/users/new", noop)
+
+		conflicts := s.RouteConflicts()
+		if len(conflicts) != 2 {
+			t.Fatalf("expected 2 overlaps, got %v", conflicts)
+		}
+		for _, c := range conflicts {
+			if !c.Overlap || c.Method != http.MethodGet || !strings.HasPrefix(c.Existing, "/users/") {
+				t.Errorf("unexpected conflict %+v", c)
+			}
+		}
+	})
+
+	t.Run("warn instead of panic", func(t *testing.T) {
+		log.SetOutput(io.Discard)
+		defer log.SetOutput(os.Stderr)
+
+		s := New(&Options{WarnRouteConflicts: true})
+		s.GET("/users", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("first")) })
+		s.GET("/users", noop)
+
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", nil))
+		if rec.Body.String() != "first" {
+			t.Errorf("expected the first route to be kept, got %q", rec.Body.String())
+		}
+		if c := s.RouteConflicts(); len(c) != 1 || c[0].Overlap {
+			t.Errorf("expected the duplicate to be recorded, got %v", c)
+		}
+	})
+}
+
 func TestParsePattern(t *testing.T) {
 	tests := []struct {
 		pattern  string
@@ -354,3 +535,119 @@ func BenchmarkRouterMultiParam(b *testing.B) {
 		r.ServeHTTP(rec, req)
 	}
 }
+
+func TestRouterTypedParams(t *testing.T) {
+	r := NewRouter()
+
+	r.Handle(http.MethodGet, "/users/{id:int}", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("int " + Param(req, "id")))
+	})
+	r.Handle(http.MethodGet, "/users/{name}", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("name " + Param(req, "name")))
+	})
+	r.Handle(http.MethodGet, "/orders/{id:uuid}", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("uuid " + Param(req, "id")))
+	})
+
+	tests := []struct {
+		path     string
+		expected string
+		status   int
+	}{
+		{"/users/42", "int 42", http.StatusOK},
+		{"/users/alice", "name alice", http.StatusOK},
+		{"/orders/123e4567-e89b-12d3-a456-426614174000", "uuid 123e4567-e89b-12d3-a456-426614174000", http.StatusOK},
+		{"/orders/42", "", http.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tc.status {
+				t.Errorf("expected status %d, got %d", tc.status, rec.Code)
+			}
+			if tc.expected != "" && rec.Body.String() != tc.expected {
+				t.Errorf("expected body %q, got %q", tc.expected, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRouterEnumParams(t *testing.T) {
+	r := NewRouter()
+	r.Handle(http.MethodGet, "/posts/{status:enum(draft|published)}", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("status " + Param(req, "status")))
+	})
+	r.Handle(http.MethodGet, "/posts/{id:"+string(Enum("latest", "popular"))+"}", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("feed " + Param(req, "id")))
+	})
+
+	tests := []struct {
+		path     string
+		expected string
+		status   int
+	}{
+		{"/posts/draft", "status draft", http.StatusOK},
+		{"/posts/published", "status published", http.StatusOK},
+		{"/posts/popular", "feed popular", http.StatusOK},
+		{"/posts/archived", "", http.StatusNotFound},
+		{"/posts/Draft", "", http.StatusNotFound},
+	}
+	for _, tc := range tests {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d", tc.path, tc.status, rec.Code)
+		}
+		if tc.expected != "" && rec.Body.String() != tc.expected {
+			t.Errorf("%s: expected body %q, got %q", tc.path, tc.expected, rec.Body.String())
+		}
+	}
+
+	if got := Enum("a", "b").EnumValues(); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("unexpected enum values %v", got)
+	}
+	if Int.EnumValues() != nil {
+		t.Error("expected no enum values for int")
+	}
+
+	for _, pattern := range []string{"/x/{s:enum()}", "/x/{s:enum(a||b)}"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected panic", pattern)
+				}
+			}()
+			NewRouter().Handle(http.MethodGet, pattern, func(w http.ResponseWriter, req *http.Request) {})
+		}()
+	}
+}
+
+func TestRegisterParamType(t *testing.T) {
+	RegisterParamType("even", func(s string) bool {
+		return s != "" && (s[len(s)-1]-'0')%2 == 0
+	})
+
+	r := NewRouter()
+	r.Handle(http.MethodGet, "/n/{n:even}", func(w http.ResponseWriter, req *http.Request) {})
+
+	for path, status := range map[string]int{"/n/4": http.StatusOK, "/n/5": http.StatusNotFound} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != status {
+			t.Errorf("%s: expected status %d, got %d", path, status, rec.Code)
+		}
+	}
+
+	t.Run("unknown type", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("expected panic")
+			}
+		}()
+		NewRouter().Handle(http.MethodGet, "/x/{id:nope}", func(w http.ResponseWriter, req *http.Request) {})
+	})
+}
@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"sort"
+	"slices"
 	"strings"
 )
 
@@ -31,57 +31,58 @@ func (s *Server) prependBasePath(pattern string) string {
 }
 
 // Handle registers a handler for the given method and pattern.
-func (s *Server) Handle(method, pattern string, handler http.HandlerFunc) {
-	s.router.Handle(method, s.prependBasePath(pattern), handler)
+// Route options, such as WithRouteTimeout, apply to this route only.
+func (s *Server) Handle(method, pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	s.router.handleRoute(newRoute(method, s.prependBasePath(pattern), handler, opts, nil))
 }
 
 // GET registers a handler for GET requests.
-func (s *Server) GET(pattern string, handler http.HandlerFunc) {
-	s.router.Handle(http.MethodGet, pattern, handler)
+func (s *Server) GET(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	s.router.handleRoute(newRoute(http.MethodGet, pattern, handler, opts, nil))
 }
 
 // POST registers a handler for POST requests.
-func (s *Server) POST(pattern string, handler http.HandlerFunc) {
-	s.router.Handle(http.MethodPost, pattern, handler)
+func (s *Server) POST(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	s.router.handleRoute(newRoute(http.MethodPost, pattern, handler, opts, nil))
 }
 
 // PUT registers a handler for PUT requests.
-func (s *Server) PUT(pattern string, handler http.HandlerFunc) {
-	s.router.Handle(http.MethodPut, pattern, handler)
+func (s *Server) PUT(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	s.router.handleRoute(newRoute(http.MethodPut, pattern, handler, opts, nil))
 }
 
 // PATCH registers a handler for PATCH requests.
-func (s *Server) PATCH(pattern string, handler http.HandlerFunc) {
-	s.router.Handle(http.MethodPatch, pattern, handler)
+func (s *Server) PATCH(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	s.router.handleRoute(newRoute(http.MethodPatch, pattern, handler, opts, nil))
 }
 
 // DELETE registers a handler for DELETE requests.
-func (s *Server) DELETE(pattern string, handler http.HandlerFunc) {
-	s.router.Handle(http.MethodDelete, pattern, handler)
+func (s *Server) DELETE(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	s.router.handleRoute(newRoute(http.MethodDelete, pattern, handler, opts, nil))
 }
 
 // OPTIONS registers a handler for OPTIONS requests.
-func (s *Server) OPTIONS(pattern string, handler http.HandlerFunc) {
-	s.router.Handle(http.MethodOptions, pattern, handler)
+func (s *Server) OPTIONS(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	s.router.handleRoute(newRoute(http.MethodOptions, pattern, handler, opts, nil))
 }
 
 // HEAD registers a handler for HEAD requests.
-func (s *Server) HEAD(pattern string, handler http.HandlerFunc) {
-	s.router.Handle(http.MethodHead, pattern, handler)
+func (s *Server) HEAD(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	s.router.handleRoute(newRoute(http.MethodHead, pattern, handler, opts, nil))
 }
 
 // CONNECT registers a handler for CONNECT requests.
-func (s *Server) CONNECT(pattern string, handler http.HandlerFunc) {
-	s.router.Handle(http.MethodConnect, pattern, handler)
+func (s *Server) CONNECT(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	s.router.handleRoute(newRoute(http.MethodConnect, pattern, handler, opts, nil))
 }
 
 // TRACE registers a handler for TRACE requests.
-func (s *Server) TRACE(pattern string, handler http.HandlerFunc) {
-	s.router.Handle(http.MethodTrace, pattern, handler)
+func (s *Server) TRACE(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	s.router.handleRoute(newRoute(http.MethodTrace, pattern, handler, opts, nil))
 }
 
 // Any registers a handler for all HTTP methods.
-func (s *Server) Any(pattern string, handler http.HandlerFunc) {
+func (s *Server) Any(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
 	methods := []string{
 		http.MethodGet,
 		http.MethodPost,
@@ -92,7 +93,7 @@ func (s *Server) Any(pattern string, handler http.HandlerFunc) {
 		http.MethodHead,
 	}
 	for _, method := range methods {
-		s.router.Handle(method, pattern, handler)
+		s.router.handleRoute(newRoute(method, pattern, handler, opts, nil))
 	}
 }
 
@@ -117,23 +118,36 @@ func (s *Server) Static(pattern, root string) {
 	})
 }
 
-// Routes returns all registered routes.
+// Routes returns all registered routes. Their Middleware lists start with
+// the server's middleware.
 func (s *Server) Routes() []RouteInfo {
-	return s.router.Routes()
+	routes := s.router.Routes()
+	if len(s.middleware) == 0 {
+		return routes
+	}
+
+	names := make([]string, len(s.middleware))
+	for i, mw := range s.middleware {
+		names[i] = funcName(mw)
+	}
+	for i := range routes {
+		routes[i].Middleware = append(slices.Clip(names), routes[i].Middleware...)
+	}
+	return routes
+}
+
+// RouteConflicts returns the conflicts found between registered routes,
+// including overlaps the router resolves by precedence, for checks such as
+// failing a test when a new route shadows an existing one.
+func (s *Server) RouteConflicts() []RouteConflict {
+	return s.router.Conflicts()
 }
 
 // PrintRoutes prints all registered routes to the given writer.
-// Routes are sorted by pattern, then by method.
+// Routes are sorted by pattern, then by method. See ExportRoutes for
+// machine-readable formats.
 func (s *Server) PrintRoutes(w io.Writer) {
-	routes := s.Routes()
-
-	// Sort routes by pattern, then by method
-	sort.Slice(routes, func(i, j int) bool {
-		if routes[i].Pattern != routes[j].Pattern {
-			return routes[i].Pattern < routes[j].Pattern
-		}
-		return routes[i].Method < routes[j].Method
-	})
+	routes := sortedRoutes(s.Routes())
 
 	// Find max method length for alignment
 	maxMethodLen := 0
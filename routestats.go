@@ -0,0 +1,142 @@
+package helix
+
+import (
+	"cmp"
+	"net/http"
+	"runtime/metrics"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RouteStats holds the timing and allocation counters of a route collected
+// after Server.EnableProfiling.
+type RouteStats struct {
+	Method  string `json:"method"`
+	Pattern string `json:"pattern"`
+
+	// Count is the number of requests served.
+	Count uint64 `json:"count"`
+
+	// Total, Mean and Max are the durations of the handler, including route
+	// and group middleware.
+	Total time.Duration `json:"total_ns"`
+	Mean  time.Duration `json:"mean_ns"`
+	Max   time.Duration `json:"max_ns"`
+
+	// Bytes and Allocs are the heap bytes and objects allocated while the
+	// handler ran, totalled and per request. They are process-wide counts,
+	// so they include the allocations of concurrent requests: compare
+	// routes relative to each other rather than reading them as exact.
+	Bytes        uint64 `json:"bytes"`
+	Allocs       uint64 `json:"allocs"`
+	BytesPerReq  uint64 `json:"bytes_per_req"`
+	AllocsPerReq uint64 `json:"allocs_per_req"`
+}
+
+// routeProfiler collects RouteStats for every route.
+type routeProfiler struct {
+	counters sync.Map // method + " " + pattern -> *routeCounters
+}
+
+// routeCounters are the counters of one route.
+type routeCounters struct {
+	method, pattern string
+	count           atomic.Uint64
+	total           atomic.Int64
+	max             atomic.Int64
+	bytes           atomic.Uint64
+	allocs          atomic.Uint64
+}
+
+// heapAllocMetrics are the runtime metrics read around each handler.
+var heapAllocMetrics = [...]string{"/gc/heap/allocs:bytes", "/gc/heap/allocs:objects"}
+
+// readHeapAllocs returns the bytes and objects allocated by the process.
+func readHeapAllocs() (bytes, objects uint64) {
+	samples := [len(heapAllocMetrics)]metrics.Sample{
+		{Name: heapAllocMetrics[0]},
+		{Name: heapAllocMetrics[1]},
+	}
+	metrics.Read(samples[:])
+	return samples[0].Value.Uint64(), samples[1].Value.Uint64()
+}
+
+// serve runs handler for the route and records its counters.
+func (p *routeProfiler) serve(method, pattern string, handler http.HandlerFunc, w http.ResponseWriter, r *http.Request) {
+	bytesBefore, allocsBefore := readHeapAllocs()
+	start := time.Now()
+
+	handler(w, r)
+
+	elapsed := int64(time.Since(start))
+	bytesAfter, allocsAfter := readHeapAllocs()
+
+	key := method + " " + pattern
+	v, ok := p.counters.Load(key)
+	if !ok {
+		v, _ = p.counters.LoadOrStore(key, &routeCounters{method: method, pattern: pattern})
+	}
+	c := v.(*routeCounters)
+	c.count.Add(1)
+	c.total.Add(elapsed)
+	for {
+		prev := c.max.Load()
+		if elapsed <= prev || c.max.CompareAndSwap(prev, elapsed) {
+			break
+		}
+	}
+	c.bytes.Add(bytesAfter - bytesBefore)
+	c.allocs.Add(allocsAfter - allocsBefore)
+}
+
+// stats returns a snapshot of the counters, slowest total first.
+func (p *routeProfiler) stats() []RouteStats {
+	var stats []RouteStats
+	p.counters.Range(func(_, v any) bool {
+		c := v.(*routeCounters)
+		s := RouteStats{
+			Method:  c.method,
+			Pattern: c.pattern,
+			Count:   c.count.Load(),
+			Total:   time.Duration(c.total.Load()),
+			Max:     time.Duration(c.max.Load()),
+			Bytes:   c.bytes.Load(),
+			Allocs:  c.allocs.Load(),
+		}
+		if s.Count > 0 {
+			s.Mean = s.Total / time.Duration(s.Count)
+			s.BytesPerReq = s.Bytes / s.Count
+			s.AllocsPerReq = s.Allocs / s.Count
+		}
+		stats = append(stats, s)
+		return true
+	})
+	slices.SortFunc(stats, func(a, b RouteStats) int {
+		return cmp.Or(cmp.Compare(b.Total, a.Total), cmp.Compare(a.Pattern, b.Pattern), cmp.Compare(a.Method, b.Method))
+	})
+	return stats
+}
+
+// EnableProfiling makes the server count the requests, handler time and
+// allocations of every route, whenever it was registered, for finding hot
+// handlers without an APM agent. Read the counters with Stats, or from the
+// {prefix}/stats endpoint of EnableDebug. Profiling reads runtime metrics
+// twice per request, so it costs a little latency; enable it where needed.
+// Must be called before the server starts.
+func (s *Server) EnableProfiling() {
+	if s.router.profiler == nil {
+		s.router.profiler = &routeProfiler{}
+	}
+}
+
+// Stats returns the per-route counters collected since EnableProfiling, the
+// routes with the most total handler time first. Returns nil if profiling
+// is not enabled.
+func (s *Server) Stats() []RouteStats {
+	if s.router.profiler == nil {
+		return nil
+	}
+	return s.router.profiler.stats()
+}
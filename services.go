@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"reflect"
 	"sync"
+
+	"github.com/kolosys/helix/di"
 )
 
 // Services provides a type-safe service registry for dependency injection.
@@ -133,3 +135,84 @@ func ProvideMiddleware[T any](factory func(r *http.Request) T) Middleware {
 		})
 	}
 }
+
+// container holds the constructors registered with Provide. Types without
+// one fall back to the services registered with Register.
+var container = di.NewWithConfig(di.Config{
+	Fallback: func(t reflect.Type) (reflect.Value, bool) {
+		servicesMu.RLock()
+		defer servicesMu.RUnlock()
+		v, ok := services[t]
+		if !ok {
+			return reflect.Value{}, false
+		}
+		if v == nil {
+			return reflect.Zero(t), true
+		}
+		return reflect.ValueOf(v), true
+	},
+})
+
+// Provide registers a constructor in the global container, a function
+// returning a service, or a service and an error. It is called once, on
+// first use, with its parameters resolved from the other constructors and
+// the services registered with Register. Provide panics if constructor is
+// not such a function or if its type is already provided.
+//
+// Example:
+//
+//	helix.Register(db)                // *sql.DB
+//	helix.Provide(NewUserRepo)        // func(*sql.DB) *UserRepo
+//	helix.Provide(NewSignupService)   // func(*UserRepo, *Mailer) (*SignupService, error)
+//
+//	s.POST("/signup", helix.HandleCtx(func(c *helix.Ctx) error {
+//	    svc, err := helix.Resolve[*SignupService](c)
+//	    if err != nil {
+//	        return err
+//	    }
+//	    ...
+//	}))
+func Provide(constructor any) {
+	container.Provide(constructor)
+}
+
+// Container returns the global container of Provide, such as to check it
+// with Validate at startup.
+func Container() *di.Container {
+	return container
+}
+
+// Resolve returns the service of type T for the request: a request-scoped
+// service added with WithService, else one built by a constructor
+// registered with Provide, else one registered with Register.
+func Resolve[T any](c *Ctx) (T, error) {
+	return ResolveContext[T](c.Context())
+}
+
+// ResolveContext is like Resolve for a context, such as the one of a
+// typed handler.
+func ResolveContext[T any](ctx context.Context) (T, error) {
+	var zero T
+	v, err := resolveType(ctx, reflect.TypeFor[T]())
+	if err != nil {
+		return zero, err
+	}
+	svc, _ := v.Interface().(T) // nil interfaces
+	return svc, nil
+}
+
+// resolveType returns the service of type t for ctx. See Resolve.
+func resolveType(ctx context.Context, t reflect.Type) (reflect.Value, error) {
+	if cs := getContextServices(ctx); cs != nil {
+		cs.mu.RLock()
+		v, ok := cs.services[t]
+		cs.mu.RUnlock()
+		if ok {
+			if v == nil {
+				return reflect.Zero(t), nil
+			}
+			return reflect.ValueOf(v), nil
+		}
+	}
+	return container.Value(t)
+}
@@ -0,0 +1,165 @@
+package helix
+
+import (
+	"crypto/rand"
+	"expvar"
+	"hash/fnv"
+	"net/http"
+
+	"github.com/kolosys/helix/middleware"
+)
+
+// splitRequests counts the requests of split routes by experiment and
+// variant, published as the "helix_split_requests" expvar (see the
+// {prefix}/vars endpoint of EnableDebug).
+var splitRequests = expvar.NewMap("helix_split_requests")
+
+// DefaultSplitCookie is the cookie holding the visitor ID of anonymous
+// clients, shared by all experiments.
+const DefaultSplitCookie = "helix_visitor"
+
+// Variant is one of the handlers of a split route.
+type Variant struct {
+	// Name identifies the variant in logs and metrics, such as "control"
+	// or "canary".
+	Name string
+
+	// Weight is the share of the traffic the variant receives, relative to
+	// the weights of the other variants: 90 and 10 send 10% of the clients
+	// to the second variant.
+	Weight int
+
+	// Handler serves the requests of the variant.
+	Handler http.HandlerFunc
+}
+
+// SplitConfig configures a split handler.
+type SplitConfig struct {
+	// Name identifies the experiment in logs and metrics. Required.
+	Name string
+
+	// Variants are the handlers traffic is split between. Required.
+	Variants []Variant
+
+	// Key returns the identity a client is assigned a variant by. Clients
+	// with the same key get the same variant of an experiment.
+	// Default: the ID of the principal, or the visitor ID of the Cookie
+	Key func(r *http.Request) string
+
+	// Cookie is the cookie holding the visitor ID of clients without a
+	// Key, which is set on their first request.
+	// Default: DefaultSplitCookie
+	Cookie string
+}
+
+// Split returns a handler that sends each client to one of variants, for
+// A/B tests and canary releases. See SplitWithConfig.
+//
+// Example:
+//
+//	s.GET("/search", helix.Split("search-ranking",
+//	    helix.Variant{Name: "control", Weight: 90, Handler: searchV1},
+//	    helix.Variant{Name: "semantic", Weight: 10, Handler: searchV2},
+//	))
+func Split(name string, variants ...Variant) http.HandlerFunc {
+	return SplitWithConfig(SplitConfig{Name: name, Variants: variants})
+}
+
+// SplitWithConfig returns a split handler with the given configuration.
+// Clients are assigned a variant by the hash of their key, so they keep it
+// across requests, and raising the weight of the last variant, such as a
+// canary's, only moves clients to it. The variant is recorded for the Logger, which logs it
+// under "variants", and the requests are counted in the
+// "helix_split_requests" expvar by "experiment/variant".
+func SplitWithConfig(config SplitConfig) http.HandlerFunc {
+	if config.Name == "" {
+		panic("helix: Split experiment name is required")
+	}
+	total := 0
+	for _, v := range config.Variants {
+		if v.Name == "" || v.Handler == nil || v.Weight < 0 {
+			panic("helix: Split variants need a name, a handler and a non-negative weight")
+		}
+		total += v.Weight
+	}
+	if total == 0 {
+		panic("helix: Split needs a variant with a positive weight")
+	}
+	if config.Cookie == "" {
+		config.Cookie = DefaultSplitCookie
+	}
+	if config.Key == nil {
+		config.Key = func(r *http.Request) string {
+			if p := middleware.PrincipalFrom(r.Context()); p != nil && p.ID != "" {
+				return "principal:" + p.ID
+			}
+			if c, err := r.Cookie(config.Cookie); err == nil && c.Value != "" {
+				return "visitor:" + c.Value
+			}
+			return ""
+		}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := config.Key(r)
+		if key == "" {
+			id := rand.Text()
+			http.SetCookie(w, &http.Cookie{
+				Name:     config.Cookie,
+				Value:    id,
+				Path:     "/",
+				MaxAge:   365 * 24 * 60 * 60,
+				HttpOnly: true,
+				Secure:   r.TLS != nil,
+				SameSite: http.SameSiteLaxMode,
+			})
+			key = "visitor:" + id
+		}
+
+		v := pickVariant(config.Variants, splitBucket(config.Name, key, total))
+		middleware.SetVariant(r, config.Name, v.Name)
+		splitRequests.Add(config.Name+"/"+v.Name, 1)
+		v.Handler(w, r)
+	}
+}
+
+// splitBucket returns the bucket, from 0 to total-1, of key in experiment.
+// Keys hash to one of 10000 positions, scaled to total so that a client
+// keeps its position when the weights change.
+func splitBucket(experiment, key string, total int) int {
+	h := fnv.New32a()
+	h.Write([]byte(experiment))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	return int(h.Sum32()%10000) * total / 10000
+}
+
+// pickVariant returns the variant whose weight range holds bucket.
+func pickVariant(variants []Variant, bucket int) Variant {
+	for _, v := range variants {
+		if bucket < v.Weight {
+			return v
+		}
+		bucket -= v.Weight
+	}
+	return variants[len(variants)-1]
+}
+
+// Split registers a split handler for all HTTP methods at pattern, with the
+// pattern as the experiment name. See SplitWithConfig.
+//
+// Example:
+//
+//	s.Split("/search",
+//	    helix.Variant{Name: "stable", Weight: 95, Handler: search},
+//	    helix.Variant{Name: "canary", Weight: 5, Handler: searchCanary},
+//	)
+func (s *Server) Split(pattern string, variants ...Variant) {
+	s.Any(pattern, Split(pattern, variants...))
+}
+
+// Split registers a split handler for all HTTP methods at pattern, with the
+// full pattern as the experiment name. See SplitWithConfig.
+func (g *Group) Split(pattern string, variants ...Variant) {
+	g.Any(pattern, Split(g.fullPrefix()+pattern, variants...))
+}
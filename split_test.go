@@ -0,0 +1,116 @@
+package helix_test
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	. "github.com/kolosys/helix"
+	"github.com/kolosys/helix/middleware"
+)
+
+// splitUses returns the requests counted for variant of experiment.
+func splitUses(experiment, variant string) int64 {
+	if v, ok := expvar.Get("helix_split_requests").(*expvar.Map).Get(experiment + "/" + variant).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+// variantHandler writes name.
+func variantHandler(name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(name))
+	}
+}
+
+func TestSplit(t *testing.T) {
+	stableUses, canaryUses := splitUses("ranking", "stable"), splitUses("ranking", "canary")
+	var logged middleware.LogValues
+	s := New(nil)
+	s.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{Output: func(v middleware.LogValues) { logged = v }}))
+	s.GET("/search", SplitWithConfig(SplitConfig{
+		Name: "ranking",
+		Variants: []Variant{
+			{Name: "stable", Weight: 90, Handler: variantHandler("stable")},
+			{Name: "canary", Weight: 10, Handler: variantHandler("canary")},
+		},
+		Key: func(r *http.Request) string { return r.Header.Get("X-User") },
+	}))
+
+	served := map[string]string{}
+	canary := 0
+	for i := range 1000 {
+		user := strconv.Itoa(i)
+		req := httptest.NewRequest(http.MethodGet, "/search", nil)
+		req.Header.Set("X-User", user)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		served[user] = rec.Body.String()
+		if served[user] == "canary" {
+			canary++
+		}
+		if logged.Variants["ranking"] != served[user] {
+			t.Fatalf("expected variant %q to be logged, got %v", served[user], logged.Variants)
+		}
+	}
+	if canary < 50 || canary > 150 {
+		t.Errorf("expected about 100 of 1000 users on the canary, got %d", canary)
+	}
+	if got := splitUses("ranking", "canary") - canaryUses; got != int64(canary) {
+		t.Errorf("expected %d canary requests counted, got %d", canary, got)
+	}
+	if got := splitUses("ranking", "stable") - stableUses; got != int64(1000-canary) {
+		t.Errorf("expected %d stable requests counted, got %d", 1000-canary, got)
+	}
+
+	for user, variant := range served {
+		req := httptest.NewRequest(http.MethodGet, "/search", nil)
+		req.Header.Set("X-User", user)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		if rec.Body.String() != variant {
+			t.Fatalf("user %s: expected to stay on %q, got %q", user, variant, rec.Body.String())
+		}
+	}
+}
+
+func TestServerSplit(t *testing.T) {
+	s := New(nil)
+	s.Split("/checkout",
+		Variant{Name: "a", Weight: 1, Handler: variantHandler("a")},
+		Variant{Name: "b", Weight: 1, Handler: variantHandler("b")},
+	)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/checkout", nil))
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != DefaultSplitCookie || cookies[0].Value == "" {
+		t.Fatalf("expected a visitor cookie, got %v", cookies)
+	}
+	variant := rec.Body.String()
+
+	for range 20 {
+		req := httptest.NewRequest(http.MethodPost, "/checkout", nil)
+		req.AddCookie(cookies[0])
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		if rec.Body.String() != variant {
+			t.Fatalf("expected the visitor to stay on %q, got %q", variant, rec.Body.String())
+		}
+		if len(rec.Result().Cookies()) != 0 {
+			t.Fatal("expected no new visitor cookie")
+		}
+	}
+}
+
+func TestSplitInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for variants without weight")
+		}
+	}()
+	Split("empty", Variant{Name: "a", Handler: variantHandler("a")})
+}
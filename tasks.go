@@ -0,0 +1,132 @@
+package helix
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"runtime/debug"
+	"sync"
+
+	"github.com/kolosys/helix/logs"
+)
+
+// tasksCtxKey is the context key for the server's background task group.
+type tasksCtxKey struct{}
+
+// taskGroup tracks background tasks started with Go so Shutdown can wait
+// for them.
+type taskGroup struct {
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	closing bool
+
+	// ctx is canceled when the grace period for tasks runs out
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// newTaskGroup creates an empty taskGroup.
+func newTaskGroup() *taskGroup {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &taskGroup{ctx: ctx, cancel: cancel}
+}
+
+// add registers a task, reporting false once the group is closing.
+func (g *taskGroup) add() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closing {
+		return false
+	}
+	g.wg.Add(1)
+	return true
+}
+
+// wait waits for running tasks until ctx is done, then cancels the
+// contexts of any still running.
+func (g *taskGroup) wait(ctx context.Context) error {
+	g.mu.Lock()
+	g.closing = true
+	g.mu.Unlock()
+	defer g.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("helix: background tasks did not finish: %w", ctx.Err())
+	}
+}
+
+// taskBaseContext wraps base so every request context carries the task group.
+func (s *Server) taskBaseContext(base func(net.Listener) context.Context) func(net.Listener) context.Context {
+	return func(ln net.Listener) context.Context {
+		ctx := context.Background()
+		if base != nil {
+			ctx = base(ln)
+		}
+		return context.WithValue(ctx, tasksCtxKey{}, s.tasks)
+	}
+}
+
+// Go runs fn in a new goroutine with panic recovery. fn receives a context
+// that keeps the values of ctx, such as the request ID and logger, but is not
+// canceled when ctx is, so work can outlive the response. Errors and panics
+// are logged through logs.FromContext.
+//
+// When ctx comes from a request served by Run, Shutdown waits for the task
+// within the grace period and cancels its context once that runs out.
+//
+// Example:
+//
+//	s.POST("/signup", helix.HandleCtx(func(c *helix.Ctx) error {
+//	    helix.Go(c.Context(), func(ctx context.Context) error {
+//	        return mailer.SendWelcome(ctx, email)
+//	    })
+//	    return c.Accepted(nil)
+//	}))
+func Go(ctx context.Context, fn func(ctx context.Context) error) {
+	taskCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+
+	g, _ := ctx.Value(tasksCtxKey{}).(*taskGroup)
+	tracked := g != nil && g.add()
+	stop := func() bool { return false }
+	if g != nil {
+		stop = context.AfterFunc(g.ctx, cancel)
+	}
+
+	go func() {
+		defer func() {
+			stop()
+			cancel()
+			if tracked {
+				g.wg.Done()
+			}
+		}()
+
+		if err := runTask(taskCtx, fn); err != nil {
+			logs.FromContext(taskCtx).Error("helix: background task failed", logs.Fields{"error": err})
+		}
+	}()
+}
+
+// runTask calls fn, converting a panic into an error.
+func runTask(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("helix: panic: %v\n%s", rec, debug.Stack())
+		}
+	}()
+	return fn(ctx)
+}
+
+// Async runs fn in the background with Go, using the request's context.
+func (c *Ctx) Async(fn func(ctx context.Context) error) {
+	Go(c.Context(), fn)
+}
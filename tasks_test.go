@@ -0,0 +1,125 @@
+package helix_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/helix"
+	"github.com/kolosys/helix/logs"
+	"github.com/kolosys/helix/middleware"
+)
+
+func TestGo(t *testing.T) {
+	var mu sync.Mutex
+	var buf bytes.Buffer
+	logger := logs.New(logs.WithOutput(&syncWriter{mu: &mu, w: &buf}), logs.WithFormatter(&logs.JSONFormatter{}))
+
+	s := New(&Options{GracePeriod: 5 * time.Second})
+	s.Use(middleware.RequestID(), middleware.ContextLogger(logger))
+
+	release := make(chan struct{})
+	finished := make(chan string, 1)
+	s.POST("/work", HandleCtx(func(c *Ctx) error {
+		c.Async(func(ctx context.Context) error {
+			<-release
+			if ctx.Err() != nil {
+				t.Error("expected task context to outlive the request")
+			}
+			finished <- middleware.GetRequestID(ctx)
+			return nil
+		})
+		c.Async(func(ctx context.Context) error {
+			panic("boom")
+		})
+		return c.Accepted(nil)
+	}))
+
+	ts := httptest.NewUnstartedServer(nil)
+	ts.Config = s.NewHTTPServer()
+	ts.Start()
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/work", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-7")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", resp.StatusCode)
+	}
+
+	// Shutdown waits for the running task
+	time.AfterFunc(50*time.Millisecond, func() { close(release) })
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+	select {
+	case id := <-finished:
+		if id != "req-7" {
+			t.Errorf("expected task to keep the request ID, got %q", id)
+		}
+	default:
+		t.Fatal("expected shutdown to wait for the task")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !strings.Contains(buf.String(), "helix: panic: boom") || !strings.Contains(buf.String(), `"request_id":"req-7"`) {
+		t.Errorf("expected the panic to be logged with the request ID, got %s", buf.String())
+	}
+}
+
+func TestGo_GracePeriodExpires(t *testing.T) {
+	s := New(&Options{GracePeriod: 50 * time.Millisecond})
+
+	canceled := make(chan struct{})
+	s.GET("/work", HandleCtx(func(c *Ctx) error {
+		c.Async(func(ctx context.Context) error {
+			<-ctx.Done()
+			close(canceled)
+			return ctx.Err()
+		})
+		return c.NoContent()
+	}))
+
+	ts := httptest.NewUnstartedServer(nil)
+	ts.Config = s.NewHTTPServer()
+	ts.Start()
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if err := s.Shutdown(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline error, got %v", err)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("expected the task context to be canceled")
+	}
+}
+
+// syncWriter serializes writes from background goroutines.
+type syncWriter struct {
+	mu *sync.Mutex
+	w  *bytes.Buffer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
@@ -0,0 +1,42 @@
+package helix
+
+import "net/http"
+
+// trailer is a response trailer whose value is computed after the handler.
+type trailer struct {
+	key   string
+	value func() string
+}
+
+// SetTrailer sets an HTTP trailer named key, whose value is computed by fn
+// once the handler returns, so streaming handlers can send checksums or a
+// final status after the body. The trailer is announced in the Trailer
+// header when called before the response is written; later calls send it
+// unannounced, which HTTP/1.1 clients may ignore. Trailers are sent by
+// HandleCtx and CtxMiddleware.
+//
+// Example:
+//
+//	h := sha256.New()
+//	c.SetTrailer("Digest", func() string {
+//	    return "sha-256=" + base64.StdEncoding.EncodeToString(h.Sum(nil))
+//	})
+//	_, err := io.Copy(io.MultiWriter(c.Response, h), report)
+//	return err
+func (c *Ctx) SetTrailer(key string, fn func() string) *Ctx {
+	key = http.CanonicalHeaderKey(key)
+	if c.Written() {
+		key = http.TrailerPrefix + key
+	} else {
+		c.Response.Header().Add("Trailer", key)
+	}
+	c.trailers = append(c.trailers, trailer{key: key, value: fn})
+	return c
+}
+
+// writeTrailers computes and sets the trailers registered with SetTrailer.
+func (c *Ctx) writeTrailers() {
+	for _, t := range c.trailers {
+		c.Response.Header().Set(t.key, t.value())
+	}
+}
@@ -0,0 +1,8 @@
+package tus
+
+// LockedUploads returns the number of uploads locked by requests in flight.
+func LockedUploads(h *Handler) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.locked)
+}
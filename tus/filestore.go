@@ -0,0 +1,143 @@
+package tus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FileStore stores uploads as files in a directory: the bytes in ID and
+// the size and metadata in ID.info. The offset is the data file's size, so
+// it survives restarts without extra bookkeeping.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a FileStore in dir, creating the directory if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("helix/tus: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Path returns the path of the upload's data file, for moving completed
+// uploads into place.
+func (s *FileStore) Path(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+// Create implements Store.
+func (s *FileStore) Create(ctx context.Context, upload Upload) (string, error) {
+	b := make([]byte, 16)
+	rand.Read(b)
+	id := hex.EncodeToString(b)
+	upload.ID = id
+
+	info, err := json.Marshal(upload)
+	if err != nil {
+		return "", fmt.Errorf("helix/tus: %w", err)
+	}
+	if err := os.WriteFile(s.Path(id)+".info", info, 0o644); err != nil {
+		return "", fmt.Errorf("helix/tus: %w", err)
+	}
+	if err := os.WriteFile(s.Path(id), nil, 0o644); err != nil {
+		return "", fmt.Errorf("helix/tus: %w", err)
+	}
+	return id, nil
+}
+
+// Get implements Store.
+func (s *FileStore) Get(ctx context.Context, id string) (Upload, error) {
+	if !validID(id) {
+		return Upload{}, ErrNotFound
+	}
+	b, err := os.ReadFile(s.Path(id) + ".info")
+	if err != nil {
+		return Upload{}, notFound(err)
+	}
+	var upload Upload
+	if err := json.Unmarshal(b, &upload); err != nil {
+		return Upload{}, fmt.Errorf("helix/tus: upload %s: %w", id, err)
+	}
+	info, err := os.Stat(s.Path(id))
+	if err != nil {
+		return Upload{}, notFound(err)
+	}
+	upload.Offset = info.Size()
+	return upload, nil
+}
+
+// Append implements Store.
+func (s *FileStore) Append(ctx context.Context, id string, offset int64, src io.Reader) (int64, error) {
+	if !validID(id) {
+		return 0, ErrNotFound
+	}
+	f, err := os.OpenFile(s.Path(id), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return 0, notFound(err)
+	}
+	defer f.Close()
+
+	if info, err := f.Stat(); err != nil {
+		return 0, fmt.Errorf("helix/tus: %w", err)
+	} else if info.Size() != offset {
+		return 0, fmt.Errorf("helix/tus: upload %s is at offset %d, not %d", id, info.Size(), offset)
+	}
+	n, err := io.Copy(f, src)
+	if err != nil {
+		return n, err
+	}
+	return n, f.Sync()
+}
+
+// Open implements Store.
+func (s *FileStore) Open(ctx context.Context, id string) (io.ReadCloser, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+	f, err := os.Open(s.Path(id))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return f, nil
+}
+
+// Delete implements Store.
+func (s *FileStore) Delete(ctx context.Context, id string) error {
+	if !validID(id) {
+		return ErrNotFound
+	}
+	if err := os.Remove(s.Path(id) + ".info"); err != nil {
+		return notFound(err)
+	}
+	if err := os.Remove(s.Path(id)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("helix/tus: %w", err)
+	}
+	return nil
+}
+
+// notFound maps a missing file to ErrNotFound.
+func notFound(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return fmt.Errorf("helix/tus: %w", err)
+}
+
+// validID reports whether id could have been issued by Create, which keeps
+// client-supplied IDs from escaping the directory.
+func validID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
@@ -0,0 +1,426 @@
+// Package tus implements the tus resumable upload protocol (https://tus.io),
+// version 1.0.0 with the creation and termination extensions. Clients
+// create an upload, send its bytes in one or more PATCH requests and, after
+// a dropped connection, ask for the offset and continue from there.
+//
+// Example:
+//
+//	store, err := tus.NewFileStore("/var/uploads")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	s.Mount("/uploads", tus.NewWithConfig(tus.Config{
+//	    Store:   store,
+//	    MaxSize: 10 << 30,
+//	    OnComplete: func(ctx context.Context, u tus.Upload) error {
+//	        return media.Import(ctx, u.ID, u.Metadata["filename"])
+//	    },
+//	}))
+package tus
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kolosys/helix"
+)
+
+// Version is the tus protocol version implemented by Handler.
+const Version = "1.0.0"
+
+// extensions lists the supported protocol extensions.
+const extensions = "creation,termination"
+
+// offsetContentType is the required Content-Type of PATCH requests.
+const offsetContentType = "application/offset+octet-stream"
+
+// ErrNotFound is returned by stores for unknown upload IDs.
+var ErrNotFound = errors.New("helix/tus: upload not found")
+
+// Upload describes an upload.
+type Upload struct {
+	// ID identifies the upload within its store.
+	ID string `json:"id"`
+
+	// Size is the total length of the upload in bytes.
+	Size int64 `json:"size"`
+
+	// Offset is the number of bytes received so far.
+	Offset int64 `json:"-"`
+
+	// Metadata holds the client-supplied Upload-Metadata pairs, such as
+	// "filename" and "filetype".
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Complete reports whether all bytes have been received.
+func (u Upload) Complete() bool {
+	return u.Offset == u.Size
+}
+
+// Store persists uploads. Implementations must be safe for concurrent use;
+// Handler serializes writes to the same upload.
+type Store interface {
+	// Create stores a new, empty upload and returns its ID.
+	Create(ctx context.Context, upload Upload) (string, error)
+
+	// Get returns the upload with its current offset, or ErrNotFound.
+	Get(ctx context.Context, id string) (Upload, error)
+
+	// Append writes src to the upload starting at offset, which is the
+	// upload's current offset, and returns the number of bytes written.
+	// Bytes written before an error must count toward the offset so the
+	// client can resume after them.
+	Append(ctx context.Context, id string, offset int64, src io.Reader) (int64, error)
+
+	// Open returns a reader for the bytes of the upload.
+	Open(ctx context.Context, id string) (io.ReadCloser, error)
+
+	// Delete removes the upload, or returns ErrNotFound.
+	Delete(ctx context.Context, id string) error
+}
+
+// Config configures a Handler.
+type Config struct {
+	// Store persists the uploads. Required.
+	Store Store
+
+	// MaxSize limits the size of an upload in bytes. Zero means no limit.
+	// Default: 0
+	MaxSize int64
+
+	// OnCreate is called before an upload is created. Returning an error
+	// rejects the upload; return a helix.Problem to choose the status.
+	OnCreate func(ctx context.Context, upload Upload) error
+
+	// OnComplete is called once the last byte of an upload is received,
+	// before the final PATCH is answered. An error fails that request, but
+	// the upload stays complete.
+	OnComplete func(ctx context.Context, upload Upload) error
+}
+
+// Handler serves the tus protocol. Mount it with Server.Mount or
+// Group.Mount: creation requests go to the mount prefix and each upload is
+// addressed as prefix + "/" + ID.
+type Handler struct {
+	config Config
+
+	// locked holds the uploads being written by a PATCH or DELETE, which
+	// are rejected rather than queued, so it only grows with the requests
+	// in flight
+	mu     sync.Mutex
+	locked map[string]bool
+}
+
+// New creates a Handler storing uploads in store.
+func New(store Store) *Handler {
+	return NewWithConfig(Config{Store: store})
+}
+
+// NewWithConfig creates a Handler with the given configuration.
+func NewWithConfig(config Config) *Handler {
+	if config.Store == nil {
+		panic("helix/tus: Store is required")
+	}
+	return &Handler{config: config, locked: make(map[string]bool)}
+}
+
+// Register implements helix.Module.
+func (h *Handler) Register(r helix.RouteRegistrar) {
+	r.OPTIONS("", h.options)
+	r.POST("", h.create)
+	r.HEAD("/{id}", h.head)
+	r.PATCH("/{id}", h.patch)
+	r.DELETE("/{id}", h.terminate)
+	r.OPTIONS("/{id}", h.options)
+}
+
+// options advertises the server's capabilities.
+func (h *Handler) options(w http.ResponseWriter, r *http.Request) {
+	header := w.Header()
+	header.Set("Tus-Resumable", Version)
+	header.Set("Tus-Version", Version)
+	header.Set("Tus-Extension", extensions)
+	if h.config.MaxSize > 0 {
+		header.Set("Tus-Max-Size", strconv.FormatInt(h.config.MaxSize, 10))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkVersion rejects requests for other protocol versions.
+func checkVersion(w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Set("Tus-Resumable", Version)
+	if r.Header.Get("Tus-Resumable") != Version {
+		w.Header().Set("Tus-Version", Version)
+		helix.WriteProblem(w, helix.NewProblem(http.StatusPreconditionFailed, "precondition_failed", "Precondition Failed").
+			WithDetailf("unsupported Tus-Resumable version %q", r.Header.Get("Tus-Resumable")))
+		return false
+	}
+	return true
+}
+
+// create handles the creation extension.
+func (h *Handler) create(w http.ResponseWriter, r *http.Request) {
+	if !checkVersion(w, r) {
+		return
+	}
+
+	size, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || size < 0 {
+		helix.WriteProblem(w, helix.ErrBadRequest.WithDetail("missing or invalid Upload-Length"))
+		return
+	}
+	if h.config.MaxSize > 0 && size > h.config.MaxSize {
+		helix.WriteProblem(w, helix.ErrPayloadTooLarge.WithDetailf("upload exceeds the maximum size of %d bytes", h.config.MaxSize))
+		return
+	}
+	metadata, err := parseMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		helix.WriteProblem(w, helix.ErrBadRequest.WithDetailf("%v", err))
+		return
+	}
+
+	upload := Upload{Size: size, Metadata: metadata}
+	if h.config.OnCreate != nil {
+		if err := h.config.OnCreate(r.Context(), upload); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	id, err := h.config.Store.Create(r.Context(), upload)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	upload.ID = id
+
+	w.Header().Set("Location", strings.TrimSuffix(r.URL.Path, "/")+"/"+id)
+	if size == 0 {
+		// An empty upload is complete as soon as it exists
+		if err := h.complete(r.Context(), upload); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// head reports the offset of an upload.
+func (h *Handler) head(w http.ResponseWriter, r *http.Request) {
+	if !checkVersion(w, r) {
+		return
+	}
+
+	upload, err := h.config.Store.Get(r.Context(), helix.Param(r, "id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	header := w.Header()
+	header.Set("Cache-Control", "no-store")
+	header.Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	header.Set("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	if len(upload.Metadata) > 0 {
+		header.Set("Upload-Metadata", formatMetadata(upload.Metadata))
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// patch appends the request body to an upload.
+func (h *Handler) patch(w http.ResponseWriter, r *http.Request) {
+	if !checkVersion(w, r) {
+		return
+	}
+	if r.Header.Get("Content-Type") != offsetContentType {
+		helix.WriteProblem(w, helix.NewProblem(http.StatusUnsupportedMediaType, "unsupported_media_type", "Unsupported Media Type").
+			WithDetail("Content-Type must be "+offsetContentType))
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		helix.WriteProblem(w, helix.ErrBadRequest.WithDetail("missing or invalid Upload-Offset"))
+		return
+	}
+
+	id := helix.Param(r, "id")
+	upload, unlock, ok := h.lock(w, r, id)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	if offset != upload.Offset {
+		helix.WriteProblem(w, helix.ErrConflict.WithDetailf("Upload-Offset is %d, expected %d", offset, upload.Offset))
+		return
+	}
+
+	body := &limitedReader{r: r.Body, remaining: upload.Size - upload.Offset}
+	n, err := h.config.Store.Append(r.Context(), id, offset, body)
+	upload.Offset += n
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	if err != nil && !(body.overflow && upload.Complete()) {
+		writeError(w, err)
+		return
+	}
+
+	// A body running past the size still completes the upload, and is
+	// answered with 413 once OnComplete has run
+	if upload.Complete() {
+		if err := h.complete(r.Context(), upload); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// terminate handles the termination extension.
+func (h *Handler) terminate(w http.ResponseWriter, r *http.Request) {
+	if !checkVersion(w, r) {
+		return
+	}
+
+	id := helix.Param(r, "id")
+	_, unlock, ok := h.lock(w, r, id)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	if err := h.config.Store.Delete(r.Context(), id); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// complete runs the OnComplete hook.
+func (h *Handler) complete(ctx context.Context, upload Upload) error {
+	if h.config.OnComplete == nil {
+		return nil
+	}
+	return h.config.OnComplete(ctx, upload)
+}
+
+// lock takes the lock of an existing upload without waiting and returns
+// the upload as read under the lock. It answers the request and reports
+// false if the upload does not exist or another request holds its lock.
+func (h *Handler) lock(w http.ResponseWriter, r *http.Request, id string) (Upload, func(), bool) {
+	// Unknown IDs are rejected without taking a lock
+	if _, err := h.config.Store.Get(r.Context(), id); err != nil {
+		writeError(w, err)
+		return Upload{}, nil, false
+	}
+
+	h.mu.Lock()
+	if h.locked[id] {
+		h.mu.Unlock()
+		helix.WriteProblem(w, helix.NewProblem(http.StatusLocked, "locked", "Locked").
+			WithDetail("the upload is being written by another request"))
+		return Upload{}, nil, false
+	}
+	h.locked[id] = true
+	h.mu.Unlock()
+	unlock := func() {
+		h.mu.Lock()
+		delete(h.locked, id)
+		h.mu.Unlock()
+	}
+
+	upload, err := h.config.Store.Get(r.Context(), id)
+	if err != nil {
+		unlock()
+		writeError(w, err)
+		return Upload{}, nil, false
+	}
+	return upload, unlock, true
+}
+
+// writeError writes err as a problem: ErrNotFound as 404, problems as is
+// and anything else as 500.
+func writeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrNotFound) {
+		helix.WriteProblem(w, helix.ErrNotFound.WithDetail("upload not found"))
+		return
+	}
+	if p, ok := helix.ProblemFrom(err); ok {
+		helix.WriteProblem(w, p)
+		return
+	}
+	helix.WriteProblem(w, helix.ErrInternal.WithErr(err))
+}
+
+// errUploadTooLarge is returned when a PATCH body runs past the upload size.
+var errUploadTooLarge = helix.ErrPayloadTooLarge.WithDetail("request body exceeds the upload size")
+
+// limitedReader reads at most remaining bytes and fails if more follow.
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	overflow  bool // more bytes followed
+}
+
+// Read implements io.Reader.
+func (l *limitedReader) Read(b []byte) (int, error) {
+	if l.remaining <= 0 {
+		var one [1]byte
+		if n, err := l.r.Read(one[:]); n > 0 {
+			l.overflow = true
+			return 0, errUploadTooLarge
+		} else if err != nil {
+			return 0, err
+		}
+		return 0, nil
+	}
+	if int64(len(b)) > l.remaining {
+		b = b[:l.remaining]
+	}
+	n, err := l.r.Read(b)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// parseMetadata parses an Upload-Metadata header of comma-separated
+// "key base64value" pairs. The value may be omitted.
+func parseMetadata(header string) (map[string]string, error) {
+	if header == "" {
+		return nil, nil
+	}
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("malformed Upload-Metadata")
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("malformed Upload-Metadata value for %q", key)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+// formatMetadata formats metadata as an Upload-Metadata header.
+func formatMetadata(metadata map[string]string) string {
+	pairs := make([]string, 0, len(metadata))
+	for _, key := range slices.Sorted(maps.Keys(metadata)) {
+		pairs = append(pairs, key+" "+base64.StdEncoding.EncodeToString([]byte(metadata[key])))
+	}
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,223 @@
+package tus_test
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kolosys/helix"
+	. "github.com/kolosys/helix/tus"
+)
+
+// tusRequest builds a request with the Tus-Resumable header.
+func tusRequest(method, target string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	req.Header.Set("Tus-Resumable", Version)
+	return req
+}
+
+func newServer(t *testing.T, config Config) (*helix.Server, *FileStore) {
+	t.Helper()
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Store = store
+	s := helix.New(nil)
+	s.Mount("/uploads", NewWithConfig(config))
+	return s, store
+}
+
+func TestResumableUpload(t *testing.T) {
+	var completed Upload
+	s, store := newServer(t, Config{
+		MaxSize: 1 << 20,
+		OnComplete: func(ctx context.Context, u Upload) error {
+			completed = u
+			return nil
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, tusRequest(http.MethodOptions, "/uploads", nil))
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Tus-Version") != Version ||
+		rec.Header().Get("Tus-Max-Size") != "1048576" || rec.Header().Get("Tus-Extension") != "creation,termination" {
+		t.Fatalf("unexpected OPTIONS response %d %v", rec.Code, rec.Header())
+	}
+
+	// Create
+	req := tusRequest(http.MethodPost, "/uploads", nil)
+	req.Header.Set("Upload-Length", "11")
+	req.Header.Set("Upload-Metadata", "filename "+base64.StdEncoding.EncodeToString([]byte("hello.txt"))+",private")
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	location := rec.Header().Get("Location")
+	if !strings.HasPrefix(location, "/uploads/") {
+		t.Fatalf("unexpected Location %q", location)
+	}
+
+	patch := func(offset, body string) *httptest.ResponseRecorder {
+		req := tusRequest(http.MethodPatch, location, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/offset+octet-stream")
+		req.Header.Set("Upload-Offset", offset)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// First chunk, then resume from the offset reported by HEAD
+	if rec := patch("0", "hello"); rec.Code != http.StatusNoContent || rec.Header().Get("Upload-Offset") != "5" {
+		t.Fatalf("unexpected PATCH response %d %q", rec.Code, rec.Header().Get("Upload-Offset"))
+	}
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, tusRequest(http.MethodHead, location, nil))
+	if rec.Header().Get("Upload-Offset") != "5" || rec.Header().Get("Upload-Length") != "11" || rec.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("unexpected HEAD response %v", rec.Header())
+	}
+	if rec := patch("0", "again"); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a stale offset, got %d", rec.Code)
+	}
+	if completed.ID != "" {
+		t.Fatal("expected OnComplete not to run yet")
+	}
+	if rec := patch("5", " world"); rec.Code != http.StatusNoContent || rec.Header().Get("Upload-Offset") != "11" {
+		t.Fatalf("unexpected PATCH response %d %q", rec.Code, rec.Header().Get("Upload-Offset"))
+	}
+
+	if !completed.Complete() || completed.Metadata["filename"] != "hello.txt" || completed.Metadata["private"] != "" {
+		t.Errorf("unexpected completed upload %+v", completed)
+	}
+	rc, err := store.Open(context.Background(), completed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "hello world" {
+		t.Errorf("expected stored bytes, got %q", data)
+	}
+
+	if rec := patch("11", "!"); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 past the upload size, got %d", rec.Code)
+	}
+
+	// Terminate
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, tusRequest(http.MethodDelete, location, nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, tusRequest(http.MethodHead, location, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 after termination, got %d", rec.Code)
+	}
+}
+
+func TestProtocolErrors(t *testing.T) {
+	s, _ := newServer(t, Config{MaxSize: 10})
+
+	// Missing Tus-Resumable
+	req := httptest.NewRequest(http.MethodPost, "/uploads", nil)
+	req.Header.Set("Upload-Length", "5")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusPreconditionFailed || rec.Header().Get("Tus-Version") != Version {
+		t.Errorf("expected 412 with Tus-Version, got %d", rec.Code)
+	}
+
+	for _, tc := range []struct {
+		length string
+		want   int
+	}{
+		{"", http.StatusBadRequest},
+		{"-1", http.StatusBadRequest},
+		{"11", http.StatusRequestEntityTooLarge},
+	} {
+		req := tusRequest(http.MethodPost, "/uploads", nil)
+		req.Header.Set("Upload-Length", tc.length)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("Upload-Length %q: expected %d, got %d", tc.length, tc.want, rec.Code)
+		}
+	}
+
+	req = tusRequest(http.MethodPatch, "/uploads/0123456789abcdef0123456789abcdef", strings.NewReader("x"))
+	req.Header.Set("Upload-Offset", "0")
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected 415 without the offset content type, got %d", rec.Code)
+	}
+
+	req.Header.Set("Content-Type", "application/offset+octet-stream")
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown upload, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, tusRequest(http.MethodHead, "/uploads/..%2f..%2fetc", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an invalid ID, got %d", rec.Code)
+	}
+}
+
+func TestUploadOvershoot(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var completed Upload
+	h := NewWithConfig(Config{Store: store, OnComplete: func(ctx context.Context, u Upload) error {
+		completed = u
+		return nil
+	}})
+	s := helix.New(nil)
+	s.Mount("/uploads", h)
+
+	patch := func(location, offset, body string) *httptest.ResponseRecorder {
+		req := tusRequest(http.MethodPatch, location, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/offset+octet-stream")
+		req.Header.Set("Upload-Offset", offset)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Unknown uploads are not locked
+	for i := range 10 {
+		if rec := patch("/uploads/"+strings.Repeat(string(rune('a'+i)), 32), "0", "x"); rec.Code != http.StatusNotFound {
+			t.Fatalf("expected 404 for an unknown upload, got %d", rec.Code)
+		}
+	}
+	if n := LockedUploads(h); n != 0 {
+		t.Errorf("expected no locks left, got %d", n)
+	}
+
+	req := tusRequest(http.MethodPost, "/uploads", nil)
+	req.Header.Set("Upload-Length", "5")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	location := rec.Header().Get("Location")
+
+	rec = patch(location, "0", "hello world")
+	if rec.Code != http.StatusRequestEntityTooLarge || rec.Header().Get("Upload-Offset") != "5" {
+		t.Errorf("expected 413 at offset 5, got %d %q", rec.Code, rec.Header().Get("Upload-Offset"))
+	}
+	if !completed.Complete() || completed.Size != 5 {
+		t.Errorf("expected OnComplete for the overshooting PATCH, got %+v", completed)
+	}
+	if n := LockedUploads(h); n != 0 {
+		t.Errorf("expected no locks left, got %d", n)
+	}
+}
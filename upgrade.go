@@ -0,0 +1,40 @@
+package helix
+
+import (
+	"errors"
+	"net"
+	"os"
+	"time"
+)
+
+// upgradeEnvKey is the environment variable holding the file descriptor of
+// a listener inherited from a parent process during a binary upgrade.
+const upgradeEnvKey = "HELIX_UPGRADE_FD"
+
+// upgradeReadyEnvKey is the environment variable holding the file
+// descriptor of the pipe a process started by Upgrade reports readiness on.
+const upgradeReadyEnvKey = "HELIX_UPGRADE_READY_FD"
+
+// upgradeReadyTimeout is how long Upgrade waits for the new process to
+// serve on the inherited listener before killing it.
+var upgradeReadyTimeout = 30 * time.Second
+
+// Upgrade errors
+var (
+	ErrUpgradeNotSupported = errors.New("helix: binary upgrade not supported")
+	ErrServerNotRunning    = errors.New("helix: server is not running")
+)
+
+// hasInheritedListener reports whether a listener was passed by a parent process.
+func hasInheritedListener() bool {
+	return os.Getenv(upgradeEnvKey) != ""
+}
+
+// listen returns the server's listener.
+// If the process was started by Upgrade, the parent's listener is inherited.
+func (s *Server) listen() (net.Listener, error) {
+	if hasInheritedListener() {
+		return inheritListener()
+	}
+	return net.Listen("tcp", s.addr)
+}
@@ -0,0 +1,118 @@
+//go:build !windows
+
+package helix
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// upgradeSignal triggers a binary upgrade when Options.EnableUpgrade is set.
+var upgradeSignal os.Signal = syscall.SIGUSR2
+
+// inheritListener recreates the listener passed by the parent process.
+func inheritListener() (net.Listener, error) {
+	fd, err := strconv.Atoi(os.Getenv(upgradeEnvKey))
+	if err != nil {
+		return nil, fmt.Errorf("helix: invalid %s: %w", upgradeEnvKey, err)
+	}
+
+	f := os.NewFile(uintptr(fd), "helix-listener")
+	defer f.Close()
+
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("helix: failed to inherit listener: %w", err)
+	}
+
+	// Clear the variable so processes started later don't inherit it
+	os.Unsetenv(upgradeEnvKey)
+	return ln, nil
+}
+
+// notifyUpgradeReady tells the parent process, if this process was started
+// by Upgrade, that it is serving on the inherited listener.
+func notifyUpgradeReady() {
+	fd, err := strconv.Atoi(os.Getenv(upgradeReadyEnvKey))
+	os.Unsetenv(upgradeReadyEnvKey)
+	if err != nil {
+		return
+	}
+	f := os.NewFile(uintptr(fd), "helix-upgrade-ready")
+	f.Write([]byte{1})
+	f.Close()
+}
+
+// Upgrade starts a new copy of the running executable that inherits the
+// server's listener, then signals Run to drain active connections and exit.
+// The new process accepts connections as soon as it starts, so no connections
+// are dropped during the handover. Run keeps serving until the new process
+// reports that it is serving too; if it exits or does not report within 30
+// seconds, it is killed and an error is returned.
+func (s *Server) Upgrade() error {
+	if s.listener == nil {
+		return ErrServerNotRunning
+	}
+
+	fl, ok := s.listener.(interface{ File() (*os.File, error) })
+	if !ok {
+		return ErrUpgradeNotSupported
+	}
+	f, err := fl.File()
+	if err != nil {
+		return fmt.Errorf("helix: failed to get listener file: %w", err)
+	}
+	defer f.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("helix: failed to locate executable: %w", err)
+	}
+
+	ready, readyW, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("helix: failed to create readiness pipe: %w", err)
+	}
+	defer ready.Close()
+
+	// ExtraFiles[0] becomes file descriptor 3 in the child, and
+	// ExtraFiles[1] descriptor 4
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), upgradeEnvKey+"=3", upgradeReadyEnvKey+"=4")
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = []*os.File{f, readyW}
+
+	err = cmd.Start()
+	readyW.Close()
+	if err != nil {
+		return fmt.Errorf("helix: failed to start new process: %w", err)
+	}
+
+	// Keep serving until the child is serving, so a binary that fails to
+	// boot does not leave nothing listening
+	if err := awaitUpgradeReady(ready, upgradeReadyTimeout); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return fmt.Errorf("helix: new process did not become ready: %w", err)
+	}
+
+	s.upgrade.Do(func() {
+		close(s.upgraded)
+	})
+	return nil
+}
+
+// awaitUpgradeReady waits for the readiness byte on ready. The read fails
+// with io.EOF if the child exits first, or on timeout.
+func awaitUpgradeReady(ready *os.File, timeout time.Duration) error {
+	ready.SetReadDeadline(time.Now().Add(timeout))
+	_, err := ready.Read(make([]byte, 1))
+	return err
+}
@@ -0,0 +1,123 @@
+//go:build !windows
+
+package helix_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/helix"
+)
+
+func TestUpgrade_NotRunning(t *testing.T) {
+	s := New(nil)
+
+	if err := s.Upgrade(); !errors.Is(err, ErrServerNotRunning) {
+		t.Errorf("expected ErrServerNotRunning, got %v", err)
+	}
+}
+
+func TestRun_InheritedListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	f, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	t.Setenv(UpgradeEnvKey, strconv.Itoa(int(f.Fd())))
+
+	// The configured address is ignored in favor of the inherited listener
+	s := New(&Options{Addr: "127.0.0.1:1", HideBanner: true})
+	s.GET("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("pong"))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- s.Run(ctx)
+	}()
+
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get("http://" + ln.Addr().String() + "/ping")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if string(body) != "pong" {
+		t.Errorf("expected pong, got %s", body)
+	}
+
+	cancel()
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Errorf("unexpected run error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+}
+
+func TestRun_NotifiesUpgradeParent(t *testing.T) {
+	ready, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ready.Close()
+	fd, err := syscall.Dup(int(w.Fd()))
+	w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(UpgradeReadyEnvKey, strconv.Itoa(fd))
+
+	s := New(&Options{Addr: "127.0.0.1:0", HideBanner: true})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	if err := AwaitUpgradeReady(ready, 5*time.Second); err != nil {
+		t.Fatalf("expected the server to report readiness, got %v", err)
+	}
+}
+
+func TestAwaitUpgradeReady(t *testing.T) {
+	// A child exiting before it is ready closes the pipe
+	ready, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if err := AwaitUpgradeReady(ready, time.Second); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+	ready.Close()
+
+	// A child that never reports times out
+	ready, w, err = os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ready.Close()
+	defer w.Close()
+	if err := AwaitUpgradeReady(ready, 10*time.Millisecond); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package helix
+
+import (
+	"net"
+	"os"
+)
+
+// upgradeSignal is nil on Windows, which has no SIGUSR2.
+var upgradeSignal os.Signal
+
+// inheritListener is not supported on Windows.
+func inheritListener() (net.Listener, error) {
+	return nil, ErrUpgradeNotSupported
+}
+
+// notifyUpgradeReady is a no-op on Windows, which has no upgrades.
+func notifyUpgradeReady() {}
+
+// Upgrade is not supported on Windows and always returns ErrUpgradeNotSupported.
+func (s *Server) Upgrade() error {
+	return ErrUpgradeNotSupported
+}
@@ -0,0 +1,227 @@
+package helix
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+)
+
+// ErrPartTooLarge is returned when reading a multipart part beyond
+// UploadConfig.MaxPartSize.
+var ErrPartTooLarge = ErrPayloadTooLarge.WithDetail("multipart part exceeds the size limit")
+
+// ErrTooManyParts is returned by MultipartReader.NextPart when the request
+// has more parts than UploadConfig.MaxParts.
+var ErrTooManyParts = ErrPayloadTooLarge.WithDetail("too many multipart parts")
+
+// UploadConfig configures Ctx.MultipartReader.
+type UploadConfig struct {
+	// MaxPartSize limits the bytes read from each part. Zero means no limit.
+	// Default: 0
+	MaxPartSize int64
+
+	// MaxParts limits the number of parts. Zero means no limit.
+	// Default: 0
+	MaxParts int
+
+	// Hash creates the hash computing each part's checksum.
+	// Default: sha256.New
+	Hash func() hash.Hash
+}
+
+// MultipartReader streams the parts of a multipart/form-data request body
+// without buffering them to memory or disk, unlike ParseMultipartForm.
+type MultipartReader struct {
+	reader *multipart.Reader
+	config UploadConfig
+	parts  int
+	part   *Part
+}
+
+// Part is one part of a streamed multipart body. Reading it enforces the
+// size limit and updates its checksum.
+type Part struct {
+	// Name is the form field name.
+	Name string
+
+	// FileName is the client-supplied file name, or "" for a plain field.
+	// It is not sanitized; never use it as a path as is.
+	FileName string
+
+	// Header is the part's MIME header.
+	Header textproto.MIMEHeader
+
+	part  *multipart.Part
+	limit int64
+	size  int64
+	hash  hash.Hash
+}
+
+// MultipartReader returns a reader streaming the parts of a
+// multipart/form-data body, for uploads too large to buffer. Parts must be
+// consumed in order; reading the next part discards the rest of the current
+// one.
+//
+// Example:
+//
+//	s.POST("/uploads", helix.HandleCtx(func(c *helix.Ctx) error {
+//	    mr, err := c.MultipartReader(helix.UploadConfig{MaxPartSize: 10 << 30})
+//	    if err != nil {
+//	        return err
+//	    }
+//	    for {
+//	        part, err := mr.NextPart()
+//	        if err == io.EOF {
+//	            break
+//	        }
+//	        if err != nil {
+//	            return err
+//	        }
+//	        if _, err := bucket.Upload(c.Context(), part.FileName, part); err != nil {
+//	            return err
+//	        }
+//	        log.Printf("%s: %d bytes, sha256 %s", part.FileName, part.Size(), part.Checksum())
+//	    }
+//	    return c.Created(nil)
+//	}))
+func (c *Ctx) MultipartReader(config ...UploadConfig) (*MultipartReader, error) {
+	var cfg UploadConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Hash == nil {
+		cfg.Hash = sha256.New
+	}
+
+	mr, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, ErrBadRequest.WithDetailf("%v", err).WithErr(err)
+	}
+	return &MultipartReader{reader: mr, config: cfg}, nil
+}
+
+// NextPart returns the next part, or io.EOF when there are no more parts.
+// File and plain field parts are both returned; check Part.FileName to tell
+// them apart.
+func (m *MultipartReader) NextPart() (*Part, error) {
+	if m.part != nil {
+		m.part.part.Close()
+		m.part = nil
+	}
+	if m.config.MaxParts > 0 && m.parts >= m.config.MaxParts {
+		// Report the limit only if there is another part
+		if _, err := m.reader.NextPart(); err != nil {
+			return nil, m.partError(err)
+		}
+		return nil, ErrTooManyParts
+	}
+
+	p, err := m.reader.NextPart()
+	if err != nil {
+		return nil, m.partError(err)
+	}
+	m.parts++
+	m.part = &Part{
+		Name:     p.FormName(),
+		FileName: p.FileName(),
+		Header:   p.Header,
+		part:     p,
+		limit:    m.config.MaxPartSize,
+		hash:     m.config.Hash(),
+	}
+	return m.part, nil
+}
+
+// partError converts a multipart error to a bad request, keeping io.EOF.
+func (m *MultipartReader) partError(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.EOF
+	}
+	var maxBytes *http.MaxBytesError
+	if errors.As(err, &maxBytes) {
+		return ErrPayloadTooLarge.WithErr(err)
+	}
+	return ErrBadRequest.WithDetailf("malformed multipart body: %v", err).WithErr(err)
+}
+
+// ContentType returns the part's Content-Type header.
+func (p *Part) ContentType() string {
+	return p.Header.Get("Content-Type")
+}
+
+// Read implements io.Reader. It returns ErrPartTooLarge once the part
+// exceeds UploadConfig.MaxPartSize.
+func (p *Part) Read(b []byte) (int, error) {
+	if p.limit > 0 {
+		if p.size >= p.limit {
+			// Probe for data past the limit before failing
+			var one [1]byte
+			n, err := p.part.Read(one[:])
+			if n > 0 {
+				return 0, ErrPartTooLarge
+			}
+			return 0, err
+		}
+		if remaining := p.limit - p.size; int64(len(b)) > remaining {
+			b = b[:remaining]
+		}
+	}
+
+	n, err := p.part.Read(b)
+	p.size += int64(n)
+	p.hash.Write(b[:n])
+	return n, err
+}
+
+// Size returns the number of bytes read from the part so far.
+func (p *Part) Size() int64 {
+	return p.size
+}
+
+// Sum returns the checksum of the bytes read so far, which is the checksum
+// of the part once it has been read to the end.
+func (p *Part) Sum() []byte {
+	return p.hash.Sum(nil)
+}
+
+// Checksum returns Sum as a hex string.
+func (p *Part) Checksum() string {
+	return hex.EncodeToString(p.Sum())
+}
+
+// SaveTo copies the rest of the part to w, such as a file or an object
+// storage upload, and returns the number of bytes copied. Failures to read
+// the part are the client's, answered with 400 when returned from a
+// handler; failures to write to w, such as a full disk, with 500.
+func (p *Part) SaveTo(w io.Writer) (int64, error) {
+	sw := &saveWriter{w: w}
+	n, err := io.Copy(sw, p)
+	switch {
+	case err == nil:
+		return n, nil
+	case sw.err != nil:
+		return n, fmt.Errorf("save part %q: %w", p.Name, err)
+	}
+	return n, fmt.Errorf("helix: read part %q: %w", p.Name, err)
+}
+
+// saveWriter records the error of w, to tell write failures from read
+// failures in SaveTo.
+type saveWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (sw *saveWriter) Write(b []byte) (int, error) {
+	n, err := sw.w.Write(b)
+	if err != nil {
+		sw.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,162 @@
+package helix_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/kolosys/helix"
+)
+
+// multipartRequest builds a multipart/form-data request with a title field
+// and the given files.
+func multipartRequest(t *testing.T, files map[string]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("title", "holiday")
+	for name, content := range files {
+		fw, err := mw.CreateFormFile("file", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(fw, content)
+	}
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/uploads", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestCtx_MultipartReader(t *testing.T) {
+	content := strings.Repeat("x", 100_000)
+
+	type saved struct {
+		name, field, checksum string
+		size                  int64
+		data                  string
+	}
+	var got []saved
+
+	s := New(nil)
+	s.POST("/uploads", HandleCtx(func(c *Ctx) error {
+		mr, err := c.MultipartReader(UploadConfig{MaxPartSize: 200_000})
+		if err != nil {
+			return err
+		}
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			var buf bytes.Buffer
+			if _, err := part.SaveTo(&buf); err != nil {
+				return err
+			}
+			got = append(got, saved{part.FileName, part.Name, part.Checksum(), part.Size(), buf.String()})
+		}
+		return c.NoContent()
+	}))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, multipartRequest(t, map[string]string{"a.bin": content}))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(got) != 2 || got[0].field != "title" || got[0].data != "holiday" {
+		t.Fatalf("unexpected parts %+v", got)
+	}
+	sum := sha256.Sum256([]byte(content))
+	if got[1].name != "a.bin" || got[1].size != int64(len(content)) || got[1].checksum != hex.EncodeToString(sum[:]) {
+		t.Errorf("unexpected file part %s %d %s", got[1].name, got[1].size, got[1].checksum)
+	}
+}
+
+func TestCtx_MultipartReaderLimits(t *testing.T) {
+	var readErr error
+	s := New(nil)
+	s.POST("/uploads", HandleCtx(func(c *Ctx) error {
+		mr, err := c.MultipartReader(UploadConfig{MaxPartSize: 1024, MaxParts: 2})
+		if err != nil {
+			return err
+		}
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return c.NoContent()
+			}
+			if err != nil {
+				readErr = err
+				return err
+			}
+			if _, err := part.SaveTo(io.Discard); err != nil {
+				readErr = err
+				return err
+			}
+		}
+	}))
+
+	// Exactly at the limit is fine
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, multipartRequest(t, map[string]string{"ok.bin": strings.Repeat("x", 1024)}))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, multipartRequest(t, map[string]string{"big.bin": strings.Repeat("x", 1025)}))
+	if rec.Code != http.StatusRequestEntityTooLarge || !errors.Is(readErr, ErrPartTooLarge) {
+		t.Errorf("expected 413 part too large, got %d %v", rec.Code, readErr)
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, multipartRequest(t, map[string]string{"a": "1", "b": "2"}))
+	if rec.Code != http.StatusRequestEntityTooLarge || readErr.Error() != ErrTooManyParts.Error() {
+		t.Errorf("expected 413 too many parts, got %d %v", rec.Code, readErr)
+	}
+
+	// Not a multipart body
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/uploads", strings.NewReader("{}")))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
+
+// failingWriter fails every write, like a full disk.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("no space left on device") }
+
+func TestPart_SaveToWriteError(t *testing.T) {
+	s := New(nil)
+	s.POST("/uploads", HandleCtx(func(c *Ctx) error {
+		mr, err := c.MultipartReader(UploadConfig{})
+		if err != nil {
+			return err
+		}
+		part, err := mr.NextPart()
+		if err != nil {
+			return err
+		}
+		_, err = part.SaveTo(failingWriter{})
+		return err
+	}))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, multipartRequest(t, nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected a failing destination to be a server error, got %d: %s", rec.Code, rec.Body.String())
+	}
+}